@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/containrrr/watchtower/internal/flags"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCommand() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Print the effective value of every setting",
+		Long: `
+	Prints the value that each of the settings would have when starting watchtower with the same flags and
+	environment variables, with the passwords, tokens and webhooks masked. Using --show-origin also prints whether
+	each value came from a flag, an environment variable, a file or the default.
+	`,
+		Args: cobra.NoArgs,
+		Run:  runConfig,
+	}
+	configCmd.Flags().Bool("show-origin", false, "Print where the value of each setting came from")
+	return configCmd
+}
+
+func runConfig(c *cobra.Command, _ []string) {
+	showOrigin, _ := c.Flags().GetBool("show-origin")
+
+	f := rootCmd.PersistentFlags()
+	settings := flags.RecordSettings(f)
+	flags.ProcessFlagAliases(f)
+	settings.Refresh(f)
+
+	width := 0
+	origins := make([]string, len(settings))
+	for i, setting := range settings {
+		origins[i] = string(setting.Origin)
+		if setting.Source != "" {
+			origins[i] += ":" + setting.Source
+		}
+		if len(origins[i]) > width {
+			width = len(origins[i])
+		}
+	}
+
+	for i, setting := range settings {
+		if showOrigin {
+			fmt.Printf("%-*s  %s=%s\n", width, origins[i], setting.Name, setting.Value)
+		} else {
+			fmt.Printf("%s=%s\n", setting.Name, setting.Value)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/registry/diag"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newDiagCommand() *cobra.Command {
+	diagCmd := &cobra.Command{
+		Use:   "diag",
+		Short: "Diagnose issues with the services used by watchtower",
+	}
+
+	registryCmd := &cobra.Command{
+		Use:   "registry HOST|IMAGE",
+		Short: "Check each step of retrieving image digests from a registry",
+		Long: `
+	Resolves the registry host, connects to it and performs the TLS handshake, authentication and (when an
+	image reference such as ghcr.io/containrrr/watchtower:latest is given) the manifest HEAD request that
+	watchtower uses to check for updates, reporting the result of each step.
+	`,
+		Args:   cobra.ExactArgs(1),
+		PreRun: PreRunTool,
+		Run:    runDiagRegistry,
+	}
+	registryCmd.Flags().Duration("timeout", 30*time.Second, "Time limit for running all of the checks")
+
+	diagCmd.AddCommand(registryCmd)
+	return diagCmd
+}
+
+func runDiagRegistry(c *cobra.Command, args []string) {
+	target, err := diag.ParseTarget(args[0])
+	if err != nil {
+		log.Fatalf("Invalid registry or image %q: %v", args[0], err)
+	}
+
+	limit, _ := c.Flags().GetDuration("timeout")
+	ctx, cancel := context.WithTimeout(context.Background(), limit)
+	defer cancel()
+
+	steps := diag.Run(ctx, target)
+	for _, step := range steps {
+		if step.Skipped {
+			fmt.Printf("%-5s %-8s\n", step.Name, "skipped")
+			continue
+		}
+		status, detail := "ok", step.Detail
+		if step.Err != nil {
+			status, detail = "FAILED", step.Err.Error()
+		}
+		fmt.Printf("%-5s %-8s %8s  %s\n", step.Name, status, step.Duration.Round(time.Millisecond), detail)
+	}
+
+	if diag.Failed(steps) {
+		os.Exit(1)
+	}
+}
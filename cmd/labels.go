@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/containrrr/watchtower/internal/actions"
+	"github.com/containrrr/watchtower/pkg/filters"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newLabelsCommand() *cobra.Command {
+	labelsCmd := &cobra.Command{
+		Use:   "labels",
+		Short: "Snapshot and restore the watchtower labels of containers",
+		Long: `
+	Saves the watchtower labels of the running containers to a file, so that they can be re-applied
+	if the containers are recreated outside of watchtower and their configuration gets lost.
+	`,
+	}
+
+	snapshotCmd := &cobra.Command{
+		Use:    "snapshot FILE [CONTAINER...]",
+		Short:  "Write the watchtower labels of the running containers to FILE",
+		Args:   cobra.MinimumNArgs(1),
+		PreRun: PreRunTool,
+		Run:    runLabelsSnapshot,
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:    "restore FILE",
+		Short:  "Recreate the containers whose watchtower labels differ from the ones in FILE",
+		Args:   cobra.ExactArgs(1),
+		PreRun: PreRunTool,
+		Run:    runLabelsRestore,
+	}
+	restoreCmd.Flags().Bool("dry-run", false, "Only list the containers that would be recreated")
+
+	labelsCmd.AddCommand(snapshotCmd, restoreCmd)
+	return labelsCmd
+}
+
+func runLabelsSnapshot(_ *cobra.Command, args []string) {
+	path, names := args[0], args[1:]
+
+	filter := filters.FilterByNames(names, filters.NoFilter)
+	snapshot, err := actions.SnapshotLabels(client, filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := actions.WriteLabelSnapshot(file, snapshot); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Infof("Saved the labels of %d containers to %s", len(snapshot.Containers), path)
+}
+
+func runLabelsRestore(c *cobra.Command, args []string) {
+	dryRun, _ := c.Flags().GetBool("dry-run")
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	snapshot, err := actions.ReadLabelSnapshot(file)
+	if err != nil {
+		log.Fatalf("Failed to read label snapshot: %v", err)
+	}
+
+	restored, err := actions.RestoreLabels(client, snapshot, timeout, dryRun)
+	if len(restored) > 0 {
+		verb := "Restored"
+		if dryRun {
+			verb = "Would restore"
+		}
+		log.Infof("%s labels of: %s", verb, strings.Join(restored, ", "))
+	} else {
+		log.Info("All container labels already match the snapshot")
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
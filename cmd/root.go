@@ -1,30 +1,65 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"math"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/containrrr/watchtower/internal/actions"
 	"github.com/containrrr/watchtower/internal/flags"
 	"github.com/containrrr/watchtower/internal/meta"
+	"github.com/containrrr/watchtower/internal/systemd"
 	"github.com/containrrr/watchtower/pkg/api"
+	"github.com/containrrr/watchtower/pkg/api/approval"
+	"github.com/containrrr/watchtower/pkg/api/dashboard"
 	apiMetrics "github.com/containrrr/watchtower/pkg/api/metrics"
+	"github.com/containrrr/watchtower/pkg/api/quarantine"
+	"github.com/containrrr/watchtower/pkg/api/schedule"
 	"github.com/containrrr/watchtower/pkg/api/update"
+	"github.com/containrrr/watchtower/pkg/blackout"
+	"github.com/containrrr/watchtower/pkg/compose"
 	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/events"
 	"github.com/containrrr/watchtower/pkg/filters"
+	"github.com/containrrr/watchtower/pkg/heartbeat"
+	"github.com/containrrr/watchtower/pkg/hooks"
+	"github.com/containrrr/watchtower/pkg/kafka"
+	"github.com/containrrr/watchtower/pkg/leader"
+	"github.com/containrrr/watchtower/pkg/lock"
+	"github.com/containrrr/watchtower/pkg/logarchive"
+	"github.com/containrrr/watchtower/pkg/logging"
 	"github.com/containrrr/watchtower/pkg/metrics"
+	"github.com/containrrr/watchtower/pkg/mqtt"
+	"github.com/containrrr/watchtower/pkg/nats"
 	"github.com/containrrr/watchtower/pkg/notifications"
+	"github.com/containrrr/watchtower/pkg/registry/dnscache"
+	"github.com/containrrr/watchtower/pkg/registry/hosts"
+	"github.com/containrrr/watchtower/pkg/registry/replay"
+	"github.com/containrrr/watchtower/pkg/registry/transport"
+	"github.com/containrrr/watchtower/pkg/releases"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/state"
+	"github.com/containrrr/watchtower/pkg/strategy"
 	t "github.com/containrrr/watchtower/pkg/types"
+	"github.com/containrrr/watchtower/pkg/watchdog"
+	"github.com/docker/docker/api/types/versions"
+	units "github.com/docker/go-units"
 	"github.com/robfig/cron"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -34,11 +69,117 @@ var (
 	noRestart      bool
 	monitorOnly    bool
 	enableLabel    bool
+	disableLabel   bool
 	notifier       t.Notifier
 	timeout        time.Duration
 	lifecycleHooks bool
 	rollingRestart bool
 	scope          string
+	// filterPreset holds the filters of the preset selected using --filter-preset, combined with the filter flags
+	filterPreset filters.Preset
+	// selfUpdateTimeout is how long to wait for a new watchtower instance to take over after a self-update
+	selfUpdateTimeout time.Duration
+	// healthStartPeriodMultiplier scales the health check start period of new watchtower instances and recreated containers
+	healthStartPeriodMultiplier float64
+	// imageRetention decides which of the previous images are kept when cleaning up
+	imageRetention t.ImageRetention
+	// cleanupDelay is how long to wait before removing the previous images
+	cleanupDelay time.Duration
+	// networksDenied is set when the docker API does not permit connecting the recreated containers to networks
+	networksDenied bool
+	// stateStore keeps the state between sessions
+	stateStore t.StateStore
+	// logOutputs are the hooks shipping the logs to other destinations
+	logOutputs []*logging.OutputHook
+	// logFile writes the logs to a file, if enabled
+	logFile *logging.FileHook
+	// eventLog records the actions taken as structured events, if enabled
+	eventLog *events.Log
+	// errorBudget tracks the failed checks across sessions, if a check failure threshold has been set
+	errorBudget t.ErrorBudget
+	// updateRetries, updateRetryBackoff and updateRetrySessions configure how failed updates are retried
+	updateRetries       int
+	updateRetryBackoff  time.Duration
+	updateRetrySessions int
+	// quarantineAfter is after how many consecutive failed sessions containers are quarantined, if set
+	quarantineAfter int
+	// releaseResolver resolves the release links and notes of the new images, if enabled
+	releaseResolver t.ReleaseResolver
+	// sessionLabels enables labelling recreated containers with the update session
+	sessionLabels bool
+	// reportScheduleSpec is the cron expression for the additional report-only sessions, if any
+	reportScheduleSpec string
+	// checkScheduleSpec and cleanupScheduleSpec are the cron expressions for the separate check and cleanup sessions,
+	// if any
+	checkScheduleSpec   string
+	cleanupScheduleSpec string
+	// applyScheduleSpec is the cron expression for the sessions applying the staged updates, if any
+	applyScheduleSpec string
+	// stageOnly makes the sessions stage the new images, rather than applying them
+	stageOnly bool
+	// minImageAge defers the updates to new images until they are at least this old
+	minImageAge time.Duration
+	// failureLogLines is how many lines of the logs of the recreated containers that failed are reported, 0 to not
+	// check the recreated containers
+	failureLogLines int
+	// composeDeployer re-deploys the compose projects of the containers created by docker compose, if enabled
+	composeDeployer t.ComposeDeployer
+	// updateStrategy decides whether to update the stale containers, and may update them itself, if configured
+	updateStrategy t.UpdateStrategy
+	// transactionalGroups updates the containers of each group all or nothing
+	transactionalGroups bool
+	// requireApproval makes the sessions stage the new images until their updates are approved using the HTTP API
+	requireApproval bool
+	// approvalLinks creates the approval links included in the notifications, if the public URL of the API is set
+	approvalLinks *approval.Signer
+	// approvalLinksToken is the API token the approval links are signed with
+	approvalLinksToken string
+	// drainTimeout is how long to wait for the running update to finish when shutting down, 0 to wait until it has
+	drainTimeout time.Duration
+	// secretResolver resolves the secrets of secretsCmd that refer to a secret provider, like vault
+	secretResolver *flags.SecretResolver
+	secretsCmd     *cobra.Command
+	// secretsRefreshInterval is how often the secrets are resolved again, and secretsResolvedAt when they last were
+	secretsRefreshInterval time.Duration
+	secretsResolvedAt      time.Time
+	// influxMetrics writes the metrics of each session to InfluxDB, if enabled
+	influxMetrics *metrics.Influx
+	// mqttPublisher publishes the events and the status of the containers to an MQTT broker, if enabled
+	mqttPublisher *mqtt.Publisher
+	// kafkaProducer produces the events of the updates of the containers and of the sessions to Kafka, if enabled
+	kafkaProducer *kafka.Producer
+	// natsPublisher publishes the events and the reports of the sessions to a NATS server, if enabled
+	natsPublisher *nats.Publisher
+	// installUpdate runs a session updating the container, as requested by Home Assistant, once the HTTP API is set up
+	// to update the containers
+	installUpdate func(container string)
+	// heartbeatPinger pings the heartbeat check when the update sessions start and finish, if enabled
+	heartbeatPinger *heartbeat.Pinger
+	// sessionHooks runs the commands inside the watchtower container before and after the update sessions, and the
+	// one receiving their reports
+	sessionHooks *hooks.Session
+	// sessionWatchdog alerts when the scheduled update sessions stop completing, if enabled
+	sessionWatchdog *watchdog.Watchdog
+	// leaderElector decides whether this instance runs the sessions, if leader election is enabled
+	leaderElector leader.Elector
+	// leading is set while this instance is the elected leader
+	leading bool
+	// sessionLock serializes the update sessions with the other instances using it, if set, waiting for up to
+	// sessionLockTimeout to acquire it
+	sessionLock        lock.Lock
+	sessionLockTimeout time.Duration
+	// webDashboard records the sessions shown by the dashboard, if it is enabled
+	webDashboard *dashboard.Handler
+	// updatesPaused is set while the scheduled update sessions are paused from the dashboard
+	updatesPaused int32
+	// schedulerStarted is set once the scheduled sessions are running, which is when updates have a scheduled apply time
+	schedulerStarted int32
+	// pauseFile is the path of the file whose existence suspends applying updates, if set
+	pauseFile string
+	// blackouts are the days on which updates are not applied
+	blackouts blackout.Calendar
+	// shutdown is closed once watchtower has been asked to shut down
+	shutdown = make(chan struct{})
 )
 
 var rootCmd = NewRootCommand()
@@ -54,6 +195,8 @@ func NewRootCommand() *cobra.Command {
 	`,
 		Run:    Run,
 		PreRun: PreRun,
+		// Positional arguments are container names, not sub commands
+		Args: cobra.ArbitraryArgs,
 	}
 }
 
@@ -62,6 +205,11 @@ func init() {
 	flags.RegisterDockerFlags(rootCmd)
 	flags.RegisterSystemFlags(rootCmd)
 	flags.RegisterNotificationFlags(rootCmd)
+	rootCmd.AddCommand(newLabelsCommand())
+	rootCmd.AddCommand(newDiagCommand())
+	rootCmd.AddCommand(newValidateCommand())
+	rootCmd.AddCommand(newConfigCommand())
+	rootCmd.AddCommand(newTuiCommand())
 }
 
 // Execute the root func and exit in case of errors
@@ -76,6 +224,385 @@ func PreRun(cmd *cobra.Command, _ []string) {
 	f := cmd.PersistentFlags()
 	flags.ProcessFlagAliases(f)
 
+	configureLogging(f)
+
+	scheduleSpec, _ = f.GetString("schedule")
+	reportScheduleSpec, _ = f.GetString("report-schedule")
+	checkScheduleSpec, _ = f.GetString("check-schedule")
+	cleanupScheduleSpec, _ = f.GetString("cleanup-schedule")
+	applyScheduleSpec, _ = f.GetString("apply-schedule")
+	stageOnly, _ = f.GetBool("stage-only")
+	minImageAge, _ = f.GetDuration("min-image-age")
+	failureLogLines, _ = f.GetInt("failure-log-lines")
+	if redeploy, _ := f.GetBool("compose-redeploy"); redeploy {
+		binary, _ := f.GetString("compose-binary")
+		composeTimeout, _ := f.GetDuration("compose-timeout")
+		composeDeployer = compose.New(binary, composeTimeout)
+	}
+	requireApproval, _ = f.GetBool("require-approval")
+
+	flags.GetSecretsFromFiles(cmd)
+	secretsCmd = cmd
+	secretResolver = flags.NewSecretResolver(cmd)
+	if secretResolver.HasReferences() {
+		if _, err := secretResolver.Resolve(); err != nil {
+			log.Fatal(err)
+		}
+		secretsResolvedAt = time.Now()
+	}
+	configureApprovalLinks(f)
+	secretsRefreshInterval, _ = f.GetDuration("secrets-refresh-interval")
+	cleanup, noRestart, monitorOnly, timeout = flags.ReadFlags(cmd)
+
+	if timeout < 0 {
+		log.Fatal("Please specify a positive value for timeout value.")
+	}
+
+	preset, err := filterPresetFromFlags(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	filterPreset = preset
+	enableLabel, disableLabel, scope = preset.LabelEnable, preset.LabelDisable, preset.Scope
+	if updateStrategy, err = updateStrategyFromFlags(f); err != nil {
+		log.Fatal(err)
+	}
+	lifecycleHooks, _ = f.GetBool("enable-lifecycle-hooks")
+	rollingRestart, _ = f.GetBool("rolling-restart")
+	transactionalGroups, _ = f.GetBool("transactional-groups")
+	selfUpdateTimeout, _ = f.GetDuration("self-update-timeout")
+	drainTimeout, _ = f.GetDuration("drain-timeout")
+	pauseFile, _ = f.GetString("pause-file")
+	blackoutSpecs, _ := f.GetStringSlice("no-update-on")
+	calendar, err := blackout.Parse(blackoutSpecs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	blackouts = calendar
+	healthStartPeriodMultiplier, _ = f.GetFloat64("health-start-period-multiplier")
+	sessionLabels, _ = f.GetBool("session-labels")
+	imageRetention.Keep, _ = f.GetInt("cleanup-keep")
+	imageRetention.KeepYoungerThan, _ = f.GetDuration("cleanup-keep-younger-than")
+	if imageRetention.Keep < 0 || imageRetention.KeepYoungerThan < 0 {
+		log.Fatal("The number and age of the previous images to keep cannot be negative.")
+	}
+	cleanupDelay, _ = f.GetDuration("cleanup-delay")
+	updateRetries, _ = f.GetInt("update-retries")
+	updateRetryBackoff, _ = f.GetDuration("update-retry-backoff")
+	updateRetrySessions, _ = f.GetInt("update-retry-sessions")
+	if updateRetries < 0 || updateRetryBackoff < 0 || updateRetrySessions < 0 {
+		log.Fatal("The update retries, their backoff and the retry sessions cannot be negative.")
+	}
+	quarantineAfter, _ = f.GetInt("quarantine-after")
+	if quarantineAfter < 0 {
+		log.Fatal("The number of failed sessions before quarantining a container cannot be negative.")
+	}
+
+	configureReleaseResolver(f)
+	configureInfluxMetrics(f)
+	configureHeartbeat(f)
+	configureMQTT(f)
+	configureKafka(f)
+	configureNATS(f)
+	configureSessionHooks(f)
+	configureSessionLock(f)
+
+	stateFile, _ := f.GetString("state-file")
+	store, err := state.New(stateFile)
+	if err != nil {
+		log.Fatalf("Could not load the state file %s: %v", stateFile, err)
+	}
+	stateStore = store
+	if threshold, _ := f.GetInt("check-failure-threshold"); threshold > 1 {
+		errorBudget = session.NewErrorBudget(threshold, stateStore)
+	}
+
+	if scope != "" {
+		log.Debugf(`Using scope %q`, scope)
+	}
+
+	configureRegistryTraffic(f)
+
+	// configure environment vars for client
+	err = flags.EnvConfig(cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if noPull, _ := f.GetBool("no-pull"); monitorOnly && noPull {
+		log.Warn("Using `WATCHTOWER_NO_PULL` and `WATCHTOWER_MONITOR_ONLY` simultaneously might lead to no action being taken at all. If this is intentional, you may safely ignore this message.")
+	}
+
+	client = newClientFromFlags(f)
+	configureLeaderElection(f)
+
+	notifier = notifications.NewNotifier(cmd, eventRecorder())
+}
+
+// configureReleaseResolver sets up the resolver of the release links and notes, if enabled
+func configureReleaseResolver(f *pflag.FlagSet) {
+	releaseLinks, _ := f.GetBool("notification-release-links")
+	releaseNotes, _ := f.GetBool("notification-release-notes")
+	if releaseLinks || releaseNotes {
+		githubToken, _ := f.GetString("notification-github-token")
+		releaseResolver = releases.NewResolver(releaseNotes, githubToken)
+	}
+}
+
+// configureInfluxMetrics sets up the exporter writing the metrics of each session to InfluxDB, if enabled
+func configureInfluxMetrics(f *pflag.FlagSet) {
+	if url, _ := f.GetString("metrics-influx-url"); url != "" {
+		token, _ := f.GetString("metrics-influx-token")
+		influxMetrics = metrics.NewInflux(url, token)
+	}
+}
+
+// newLimiterFromFlags returns the limiter of the HTTP API requests, or nil if neither the rate nor the invalid tokens
+// are limited
+func newLimiterFromFlags(f *pflag.FlagSet) *api.Limiter {
+	rate, _ := f.GetInt("http-api-rate-limit")
+	attempts, _ := f.GetInt("http-api-lockout-attempts")
+	lockout, _ := f.GetDuration("http-api-lockout-duration")
+	trustProxy, _ := f.GetBool("http-api-trust-proxy")
+	limiter := api.NewLimiter(rate, attempts, lockout, trustProxy)
+	if !limiter.Enabled() {
+		return nil
+	}
+	return limiter
+}
+
+// apiCredentialsFromFlags returns the username and password of the HTTP API basic authentication, reading the
+// password from its file if one is set
+func apiCredentialsFromFlags(f *pflag.FlagSet) (username string, password string) {
+	username, _ = f.GetString("http-api-username")
+	password, _ = f.GetString("http-api-password")
+	if path, _ := f.GetString("http-api-password-file"); path != "" {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Could not read the HTTP API password: %v", err)
+		}
+		password = strings.TrimRight(string(content), "\r\n")
+	}
+	return username, password
+}
+
+// configureHeartbeat sets up the pinger of the heartbeat check, if enabled
+func configureHeartbeat(f *pflag.FlagSet) {
+	if url, _ := f.GetString("heartbeat-url"); url != "" {
+		heartbeatPinger = heartbeat.New(url)
+	}
+}
+
+// configureMQTT sets up the publisher of the events and the status of the containers to the MQTT broker, if enabled,
+// replacing the previous one
+func configureMQTT(f *pflag.FlagSet) {
+	if mqttPublisher != nil {
+		if err := mqttPublisher.Close(5 * time.Second); err != nil {
+			log.Warn(err)
+		}
+		mqttPublisher = nil
+	}
+	broker, _ := f.GetString("mqtt-url")
+	if broker == "" {
+		return
+	}
+	prefix, _ := f.GetString("mqtt-topic-prefix")
+	qos, _ := f.GetInt("mqtt-qos")
+	clientID, _ := f.GetString("mqtt-client-id")
+	var err error
+	if mqttPublisher, err = mqtt.New(broker, prefix, qos, clientID); err != nil {
+		log.Fatalf("Invalid MQTT configuration: %v", err)
+	}
+	if enabled, _ := f.GetBool("mqtt-homeassistant"); enabled {
+		discoveryPrefix, _ := f.GetString("mqtt-homeassistant-prefix")
+		var install func(string)
+		// the containers are only updated from Home Assistant when they can be updated using the HTTP API
+		if enableUpdateAPI, _ := f.GetBool("http-api-update"); enableUpdateAPI {
+			install = func(container string) {
+				if installUpdate != nil {
+					installUpdate(container)
+				}
+			}
+		}
+		mqttPublisher.EnableHomeAssistant(discoveryPrefix, install)
+	}
+}
+
+// configureKafka sets up the producer of the events of the updates of the containers and of the sessions to Kafka, if
+// enabled, replacing the previous one
+func configureKafka(f *pflag.FlagSet) {
+	if kafkaProducer != nil {
+		if err := kafkaProducer.Close(5 * time.Second); err != nil {
+			log.Warn(err)
+		}
+		kafkaProducer = nil
+	}
+	brokers, _ := f.GetStringSlice("kafka-brokers")
+	if len(brokers) == 0 {
+		return
+	}
+	config := kafka.Config{Brokers: brokers}
+	config.Topic, _ = f.GetString("kafka-topic")
+	config.TLS, _ = f.GetBool("kafka-tls")
+	config.TLSSkipVerify, _ = f.GetBool("kafka-tls-skip-verify")
+	config.Mechanism, _ = f.GetString("kafka-sasl-mechanism")
+	config.Username, _ = f.GetString("kafka-sasl-username")
+	config.Password, _ = f.GetString("kafka-sasl-password")
+	var err error
+	if kafkaProducer, err = kafka.New(config); err != nil {
+		log.Fatalf("Invalid Kafka configuration: %v", err)
+	}
+}
+
+// configureNATS sets up the publisher of the events and the reports of the sessions to the NATS server, if enabled,
+// replacing the previous one
+func configureNATS(f *pflag.FlagSet) {
+	if natsPublisher != nil {
+		if err := natsPublisher.Close(5 * time.Second); err != nil {
+			log.Warn(err)
+		}
+		natsPublisher = nil
+	}
+	server, _ := f.GetString("nats-url")
+	if server == "" {
+		return
+	}
+	prefix, _ := f.GetString("nats-subject-prefix")
+	var err error
+	if natsPublisher, err = nats.New(server, prefix); err != nil {
+		log.Fatalf("Invalid NATS configuration: %v", err)
+	}
+}
+
+// configureSessionHooks sets up the commands run before and after the update sessions, and the one receiving their
+// reports
+func configureSessionHooks(f *pflag.FlagSet) {
+	pre, _ := f.GetString("pre-session-command")
+	post, _ := f.GetString("post-session-command")
+	report, _ := f.GetString("report-command")
+	timeout, _ := f.GetDuration("session-command-timeout")
+	sessionHooks = hooks.New(pre, post, report, timeout)
+}
+
+// configureSessionLock sets up the lock serializing the update sessions across instances, if enabled
+func configureSessionLock(f *pflag.FlagSet) {
+	sessionLockTimeout, _ = f.GetDuration("session-lock-timeout")
+	if url, _ := f.GetString("session-lock"); url != "" {
+		var err error
+		if sessionLock, err = lock.New(url); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// acquireSessionLock waits for the session lock, if one is set, returning the function releasing it
+func acquireSessionLock() (func(), error) {
+	if sessionLock == nil {
+		return func() {}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionLockTimeout)
+	defer cancel()
+	return sessionLock.Acquire(ctx)
+}
+
+// configureLeaderElection sets up the election of the leader among the instances sharing the docker host, if enabled
+func configureLeaderElection(f *pflag.FlagSet) {
+	mode, _ := f.GetString("leader-election")
+	hostname, _ := os.Hostname()
+	switch mode {
+	case "":
+	case "label":
+		leaderElector = leader.NewLabelElector(client, scope, hostname)
+	case "file":
+		path, _ := f.GetString("leader-lease-file")
+		if path == "" {
+			log.Fatal("Electing the leader using a file requires the path of the lease file to be set")
+		}
+		duration, _ := f.GetDuration("leader-lease-duration")
+		if duration <= 0 {
+			log.Fatalf("Invalid leader lease duration %s, it must be positive", duration)
+		}
+		leaderElector = leader.NewFileLease(path, fmt.Sprintf("%s:%d", hostname, os.Getpid()), duration)
+	default:
+		log.Fatalf(`Unknown leader election %q. Possible values: "label" or "file"`, mode)
+	}
+}
+
+// isLeader returns whether this instance should run the sessions, which it always does without leader election
+func isLeader() bool {
+	if leaderElector == nil {
+		return true
+	}
+	isLeader, err := leaderElector.IsLeader()
+	if err != nil {
+		log.WithError(err).Warn("Could not determine the leader, skipping the session")
+		return false
+	}
+	if isLeader != leading {
+		leading = isLeader
+		if isLeader {
+			log.Info("This instance is now the leader, and runs the sessions")
+		} else {
+			log.Infof("%s has become the leader, this instance skips the sessions", leaderElector.Holder())
+		}
+	}
+	if !isLeader {
+		log.Debugf("Skipped the session, as %s is the leader", leaderElector.Holder())
+	}
+	return isLeader
+}
+
+// refreshSecrets resolves the secrets that refer to a secret provider again once the refresh interval has passed,
+// recreating the notifier, the release resolver, the influxdb exporter, the heartbeat pinger, the session lock and the
+// signer of the approval links if any of them changed.
+// The registry credentials are read from the environment for every pull, and need no further action.
+func refreshSecrets() {
+	if secretResolver == nil || !secretResolver.HasReferences() || secretsRefreshInterval <= 0 || time.Since(secretsResolvedAt) < secretsRefreshInterval {
+		return
+	}
+	secretsResolvedAt = time.Now()
+
+	changed, err := secretResolver.Resolve()
+	if err != nil {
+		log.WithError(err).Warn("Could not refresh the secrets, keeping the previous values")
+		return
+	}
+	if changed {
+		log.Debug("The secrets have changed, recreating the notifier")
+		configureReleaseResolver(secretsCmd.PersistentFlags())
+		configureInfluxMetrics(secretsCmd.PersistentFlags())
+		configureHeartbeat(secretsCmd.PersistentFlags())
+		configureMQTT(secretsCmd.PersistentFlags())
+		configureKafka(secretsCmd.PersistentFlags())
+		configureNATS(secretsCmd.PersistentFlags())
+		configureSessionLock(secretsCmd.PersistentFlags())
+		configureApprovalLinks(secretsCmd.PersistentFlags())
+		notifier.Close()
+		notifier = notifications.NewNotifier(secretsCmd, eventRecorder())
+	}
+}
+
+// configureApprovalLinks creates the signer of the approval links once the secrets have been resolved. The links are
+// signed using a key derived from the API token, which is why none are created without one, as anyone could forge
+// them otherwise. The signer is kept while the token is unchanged, so that the links redeemed already stay used.
+func configureApprovalLinks(f *pflag.FlagSet) {
+	publicURL, _ := f.GetString("http-api-public-url")
+	apiToken, _ := f.GetString("http-api-token")
+	if !requireApproval || publicURL == "" {
+		return
+	}
+	if apiToken == "" {
+		log.Warn("Not creating approval links, as they are signed using the HTTP API token, which is not set")
+		approvalLinks, approvalLinksToken = nil, ""
+		return
+	}
+	if approvalLinks == nil || apiToken != approvalLinksToken {
+		approvalLinks, approvalLinksToken = approval.NewSigner(apiToken, publicURL), apiToken
+	}
+}
+
+// configureLogging sets up the log formatter and level from the logging related flags
+func configureLogging(f *pflag.FlagSet) {
 	if enabled, _ := f.GetBool("no-color"); enabled {
 		log.SetFormatter(&log.TextFormatter{
 			DisableColors: true,
@@ -93,82 +620,330 @@ func PreRun(cmd *cobra.Command, _ []string) {
 	if enabled, _ := f.GetBool("trace"); enabled {
 		log.SetLevel(log.TraceLevel)
 	}
+	if spec, _ := f.GetString("log-level"); spec != "" {
+		levels, err := logging.ParseLevels(spec, log.GetLevel())
+		if err != nil {
+			log.Fatalf("Invalid log level: %v", err)
+		}
+		logging.ApplyLevels(levels)
+	}
 
-	scheduleSpec, _ = f.GetString("schedule")
+	outputs, _ := f.GetStringArray("log-output")
+	for _, output := range outputs {
+		hook, err := logging.NewOutputHook(output)
+		if err != nil {
+			log.Fatalf("Invalid log output: %v", err)
+		}
+		log.AddHook(hook)
+		logOutputs = append(logOutputs, hook)
+	}
 
-	flags.GetSecretsFromFiles(cmd)
-	cleanup, noRestart, monitorOnly, timeout = flags.ReadFlags(cmd)
+	if path, _ := f.GetString("log-file"); path != "" {
+		var maxSize int64
+		if value, _ := f.GetString("log-file-max-size"); value != "" {
+			var err error
+			if maxSize, err = units.FromHumanSize(value); err != nil {
+				log.Fatalf("Invalid log file max size %q: %v", value, err)
+			}
+		}
+		maxAge, _ := f.GetDuration("log-file-max-age")
+		maxBackups, _ := f.GetInt("log-file-max-backups")
+		var err error
+		if logFile, err = logging.NewFileHook(path, maxSize, maxAge, maxBackups); err != nil {
+			log.Fatal(err)
+		}
+		log.AddHook(logFile)
+	}
 
-	if timeout < 0 {
-		log.Fatal("Please specify a positive value for timeout value.")
+	if path, _ := f.GetString("event-log"); path != "" {
+		var maxSize int64
+		if value, _ := f.GetString("event-log-max-size"); value != "" {
+			var err error
+			if maxSize, err = units.FromHumanSize(value); err != nil {
+				log.Fatalf("Invalid event log max size %q: %v", value, err)
+			}
+		}
+		maxFiles, _ := f.GetInt("event-log-max-files")
+		var err error
+		if eventLog, err = events.Open(path, maxSize, maxFiles); err != nil {
+			log.Fatal(err)
+		}
 	}
+}
 
-	enableLabel, _ = f.GetBool("label-enable")
-	lifecycleHooks, _ = f.GetBool("enable-lifecycle-hooks")
-	rollingRestart, _ = f.GetBool("rolling-restart")
-	scope, _ = f.GetString("scope")
+// eventRecorder returns the recorder of the events, passing them to the event log, the MQTT broker, Kafka and the
+// NATS server, or nil if none of them is enabled
+func eventRecorder() t.EventRecorder {
+	var recorders []t.EventRecorder
+	if eventLog != nil {
+		recorders = append(recorders, eventLog)
+	}
+	if mqttPublisher != nil {
+		recorders = append(recorders, mqttPublisher)
+	}
+	if kafkaProducer != nil {
+		recorders = append(recorders, kafkaProducer)
+	}
+	if natsPublisher != nil {
+		recorders = append(recorders, natsPublisher)
+	}
+	return events.Combine(recorders...)
+}
 
-	if scope != "" {
-		log.Debugf(`Using scope %q`, scope)
+// closeLogOutputs ships the remaining log entries before exiting, and closes the log file, the event log and the
+// connections to the MQTT broker, Kafka and the NATS server
+func closeLogOutputs() {
+	for _, hook := range logOutputs {
+		hook.Close(5 * time.Second)
+	}
+	if logFile != nil {
+		_ = logFile.Close()
+	}
+	if eventLog != nil {
+		_ = eventLog.Close()
 	}
+	if mqttPublisher != nil {
+		if err := mqttPublisher.Close(5 * time.Second); err != nil {
+			log.Warn(err)
+		}
+	}
+	if kafkaProducer != nil {
+		if err := kafkaProducer.Close(5 * time.Second); err != nil {
+			log.Warn(err)
+		}
+	}
+	if natsPublisher != nil {
+		if err := natsPublisher.Close(5 * time.Second); err != nil {
+			log.Warn(err)
+		}
+	}
+}
 
-	// configure environment vars for client
-	err := flags.EnvConfig(cmd)
+// configureRegistryTraffic sets up how the requests made directly to registries are sent, resolved, recorded or replayed
+func configureRegistryTraffic(f *pflag.FlagSet) {
+	recordDir, _ := f.GetString("registry-record")
+	replayDir, _ := f.GetString("registry-replay")
+	if err := replay.Configure(recordDir, replayDir); err != nil {
+		log.Fatalf("Failed to set up registry traffic record/replay: %v", err)
+	}
+
+	dnsTTL, _ := f.GetDuration("registry-dns-ttl")
+	dnscache.Configure(dnsTTL)
+
+	configFile, err := flags.ReadConfigFile(f)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err = hosts.Configure(configFile.Registries); err != nil {
+		log.Fatalf("Failed to set up the registry settings: %v", err)
+	}
+
+	proxy, _ := f.GetString("registry-proxy")
+	noProxy, _ := f.GetString("registry-no-proxy")
+	connectTimeout, _ := f.GetDuration("registry-connect-timeout")
+	readTimeout, _ := f.GetDuration("registry-read-timeout")
+	requestTimeout, _ := f.GetDuration("registry-request-timeout")
+	maxRetries, _ := f.GetInt("registry-max-retries")
+	err = transport.Configure(transport.Options{
+		Proxy:          proxy,
+		NoProxy:        noProxy,
+		ConnectTimeout: connectTimeout,
+		ReadTimeout:    readTimeout,
+		RequestTimeout: requestTimeout,
+		MaxRetries:     maxRetries,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up the registry client: %v", err)
+	}
+}
+
+// filterPresetFromFlags returns the filters set using the flags, combined with the ones of the filter preset of the
+// config file selected using --filter-preset, if any
+func filterPresetFromFlags(f *pflag.FlagSet) (filters.Preset, error) {
+	var preset filters.Preset
+	preset.LabelEnable, _ = f.GetBool("label-enable")
+	preset.LabelDisable, _ = f.GetBool("label-disable")
+	preset.Scope, _ = f.GetString("scope")
+
+	name, _ := f.GetString("filter-preset")
+	if name == "" {
+		return preset, nil
+	}
+	configFile, err := flags.ReadConfigFile(f)
+	if err != nil {
+		return preset, err
+	}
+	selected, found := configFile.FilterPresets[name]
+	if !found {
+		return preset, fmt.Errorf("the filter preset %q is not defined in the config file", name)
+	}
+	merged, err := preset.Merge(selected)
+	if err != nil {
+		return preset, fmt.Errorf("invalid filter preset %q: %w", name, err)
+	}
+	return merged, nil
+}
 
+// updateStrategyFromFlags returns the update strategy combining the script and the plugin set using the flags, in that
+// order, or nil if neither is set
+func updateStrategyFromFlags(f *pflag.FlagSet) (t.UpdateStrategy, error) {
+	var strategies []t.UpdateStrategy
+	if path, _ := f.GetString("strategy-script"); path != "" {
+		script, err := strategy.LoadScript(path)
+		if err != nil {
+			return nil, err
+		}
+		strategies = append(strategies, script)
+	}
+	if plugin, _ := f.GetString("strategy-plugin"); plugin != "" {
+		timeout, _ := f.GetDuration("strategy-plugin-timeout")
+		strategies = append(strategies, strategy.NewPlugin(plugin, timeout))
+	}
+	return strategy.Chain(strategies...), nil
+}
+
+// newClientFromFlags creates a docker client wrapper using the client related flags
+func newClientFromFlags(f *pflag.FlagSet) container.Client {
 	noPull, _ := f.GetBool("no-pull")
 	includeStopped, _ := f.GetBool("include-stopped")
 	includeRestarting, _ := f.GetBool("include-restarting")
 	reviveStopped, _ := f.GetBool("revive-stopped")
+	ignoreOneshot, _ := f.GetBool("ignore-oneshot")
 	removeVolumes, _ := f.GetBool("remove-volumes")
+	cleanupVolumes, _ := f.GetBool("cleanup-volumes")
+	volumesDryRun, _ := f.GetBool("cleanup-volumes-dry-run")
 	warnOnHeadPullFailed, _ := f.GetString("warn-on-head-failure")
+	missingImageInfo, _ := f.GetString("missing-image-info")
+	diskSpacePath, _ := f.GetString("disk-space-path")
+	credentialsDir, _ := f.GetString("registry-credentials-dir")
 
-	if monitorOnly && noPull {
-		log.Warn("Using `WATCHTOWER_NO_PULL` and `WATCHTOWER_MONITOR_ONLY` simultaneously might lead to no action being taken at all. If this is intentional, you may safely ignore this message.")
+	var minFreeSpace int64
+	if value, _ := f.GetString("min-free-space"); value != "" {
+		var err error
+		if minFreeSpace, err = units.FromHumanSize(value); err != nil {
+			log.Fatalf("Invalid minimum free space %q: %v", value, err)
+		}
 	}
 
-	client = container.NewClient(container.ClientOptions{
+	var logArchive t.LogArchiver
+	if target, _ := f.GetString("archive-logs"); target != "" {
+		var err error
+		if logArchive, err = logarchive.New(target); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var volumeBackup *container.VolumeBackup
+	if target, _ := f.GetString("volume-backup-target"); target != "" {
+		volumeBackup = &container.VolumeBackup{Target: target}
+		volumeBackup.Image, _ = f.GetString("volume-backup-image")
+		volumeBackup.Command, _ = f.GetString("volume-backup-command")
+		volumeBackup.Timeout, _ = f.GetDuration("volume-backup-timeout")
+	}
+
+	return container.NewClient(container.ClientOptions{
 		PullImages:        !noPull,
 		IncludeStopped:    includeStopped,
 		ReviveStopped:     reviveStopped,
 		RemoveVolumes:     removeVolumes,
+		CleanupVolumes:    cleanupVolumes,
+		VolumesDryRun:     volumesDryRun,
 		IncludeRestarting: includeRestarting,
+		IgnoreOneshot:     ignoreOneshot,
 		WarnOnHeadFailed:  container.WarningStrategy(warnOnHeadPullFailed),
+		MissingImageInfo:  container.MissingImageInfoPolicy(missingImageInfo),
+		MinFreeSpace:      minFreeSpace,
+		DiskSpacePath:     diskSpacePath,
+		CredentialsDir:    credentialsDir,
+		LogArchive:        logArchive,
+		VolumeBackup:      volumeBackup,
 	})
+}
 
-	notifier = notifications.NewNotifier(cmd)
+// PreRunTool is the lifecycle hook used by the auxiliary tooling sub commands. It sets up logging and the docker
+// client from the (inherited) root flags, but does not set up notifications or scheduling.
+func PreRunTool(_ *cobra.Command, _ []string) {
+	f := rootCmd.PersistentFlags()
+
+	configureLogging(f)
+	configureRegistryTraffic(f)
+
+	flags.GetSecretsFromFiles(rootCmd)
+	if _, err := flags.NewSecretResolver(rootCmd).Resolve(); err != nil {
+		log.Fatal(err)
+	}
+	_, _, _, timeout = flags.ReadFlags(rootCmd)
+
+	if err := flags.EnvConfig(rootCmd); err != nil {
+		log.Fatal(err)
+	}
+
+	client = newClientFromFlags(f)
 }
 
 // Run is the main execution flow of the command
 func Run(c *cobra.Command, names []string) {
-	filter, filterDesc := filters.BuildFilter(names, enableLabel, scope)
+	filter, filterDesc := filters.BuildFilter(append(names, filterPreset.Names...), enableLabel, disableLabel, scope, filterPreset.Projects)
 	runOnce, _ := c.PersistentFlags().GetBool("run-once")
 	enableUpdateAPI, _ := c.PersistentFlags().GetBool("http-api-update")
 	enableMetricsAPI, _ := c.PersistentFlags().GetBool("http-api-metrics")
+	enableDashboard, _ := c.PersistentFlags().GetBool("http-api-dashboard")
 	unblockHTTPAPI, _ := c.PersistentFlags().GetBool("http-api-periodic-polls")
 	apiToken, _ := c.PersistentFlags().GetString("http-api-token")
 
+	if showSchedule, _ := c.PersistentFlags().GetBool("show-schedule"); showSchedule {
+		printSchedule(runOnce, enableUpdateAPI && !unblockHTTPAPI)
+		os.Exit(0)
+	}
+	handleShutdownSignals()
+
 	if rollingRestart && monitorOnly {
 		log.Fatal("Rolling restarts is not compatible with the global monitor only flag")
 	}
+	if rollingRestart && transactionalGroups {
+		log.Fatal("Rolling restarts are not compatible with transactional groups")
+	}
+	if enableLabel && disableLabel {
+		log.Fatal("The enable label and disable label modes cannot be used together")
+	}
 
 	awaitDockerClient()
+	applyCapabilities()
 
 	if err := actions.CheckForSanity(client, filter, rollingRestart); err != nil {
 		logNotifyExit(err)
 	}
 
+	failOnUpdate, _ := c.PersistentFlags().GetBool("fail-on-update")
 	if runOnce {
 		writeStartupMessage(c, time.Time{}, filterDesc)
-		runUpdatesWithNotifications(filter)
+		if !isLeader() {
+			notifier.Close()
+			closeLogOutputs()
+			os.Exit(session.ExitOK)
+		}
+		refreshSecrets()
+		report, err := runOnceUntilShutdown(newUpdateParams(filter, false))
+		exitCode := session.ExitCode(report, err, failOnUpdate)
+		pushMetrics(c, report, exitCode)
+		log.Debugf("Exiting with code %d", exitCode)
 		notifier.Close()
-		os.Exit(0)
+		closeLogOutputs()
+		os.Exit(exitCode)
 		return
 	}
+	if failOnUpdate {
+		log.Warn("Failing on updates only applies when running once, the fail on update flag has no effect")
+	}
+	if pushURL, _ := c.PersistentFlags().GetString("metrics-push-url"); pushURL != "" {
+		log.Warn("Pushing the metrics only applies when running once, use the metrics API to scrape them instead")
+	}
 
-	if err := actions.CheckForMultipleWatchtowerInstances(client, cleanup, scope); err != nil {
+	if leaderElector != nil {
+		if err := actions.CheckForDuplicateWatchtowerInstances(client, cleanup, scope); err != nil {
+			logNotifyExit(err)
+		}
+	} else if err := actions.CheckForMultipleWatchtowerInstances(client, cleanup, scope); err != nil {
 		logNotifyExit(err)
 	}
 
@@ -177,10 +952,25 @@ func Run(c *cobra.Command, names []string) {
 	updateLock <- true
 
 	httpAPI := api.New(apiToken)
+	httpAPI.Limiter = newLimiterFromFlags(c.PersistentFlags())
+	httpAPI.Username, httpAPI.Password = apiCredentialsFromFlags(c.PersistentFlags())
+	httpAPI.CORSOrigins, _ = c.PersistentFlags().GetStringSlice("http-api-cors-origin")
 
 	if enableUpdateAPI {
-		updateHandler := update.New(func(images []string) { runUpdatesWithNotifications(filters.FilterByImage(images, filter)) }, updateLock)
+		updateHandler := update.New(func(images []string, applyStaged bool) {
+			refreshSecrets()
+			params := newUpdateParams(filters.FilterByImage(images, filter), false)
+			// Updates pending approval are only applied once approved
+			params.ApplyStaged = applyStaged && !requireApproval
+			runSessionWithNotifications(params)
+		}, updateLock)
 		httpAPI.RegisterFunc(updateHandler.Path, updateHandler.Handle)
+		installUpdate = func(container string) {
+			chanValue := <-updateLock
+			defer func() { updateLock <- chanValue }()
+			refreshSecrets()
+			runSessionWithNotifications(newUpdateParams(filters.FilterByExactNames([]string{container}, filter), false))
+		}
 		// If polling isn't enabled the scheduler is never started and
 		// we need to trigger the startup messages manually.
 		if !unblockHTTPAPI {
@@ -188,11 +978,94 @@ func Run(c *cobra.Command, names []string) {
 		}
 	}
 
+	if enableUpdateAPI && quarantineAfter > 0 {
+		quarantineHandler := quarantine.New(
+			func() []t.QuarantinedContainer { return actions.ListQuarantined(stateStore) },
+			func(names []string) []string { return actions.ReleaseQuarantined(stateStore, names) },
+			updateLock)
+		httpAPI.RegisterFunc(quarantineHandler.Path, quarantineHandler.Handle)
+	}
+
+	if enableDashboard {
+		webDashboard = dashboard.New(
+			func() { metrics.RegisterScan(runUpdatesWithNotifications(filter, false)) },
+			func(paused bool) {
+				if paused {
+					atomic.StoreInt32(&updatesPaused, 1)
+				} else {
+					atomic.StoreInt32(&updatesPaused, 0)
+				}
+			},
+			func() bool { return atomic.LoadInt32(&updatesPaused) == 1 },
+			updateLock)
+		httpAPI.RegisterPublicFunc(webDashboard.PagePath, webDashboard.HandlePage)
+		httpAPI.RegisterFunc(webDashboard.Path, webDashboard.Handle)
+		httpAPI.RegisterFunc(webDashboard.Path+"/", webDashboard.Handle)
+	}
+
+	if requireApproval {
+		approvalHandler := approval.New(listPendingApprovals, func(containers []string) (t.Report, error) {
+			refreshSecrets()
+			params := newUpdateParams(filters.FilterByExactNames(containers, filter), false)
+			params.ApplyStaged = true
+			return runReportedSession(params)
+		}, updateLock)
+		httpAPI.RegisterFunc(approvalHandler.PendingPath, approvalHandler.HandlePending)
+		httpAPI.RegisterFunc(approvalHandler.ApprovePath, approvalHandler.HandleApprove)
+		if approvalLinks != nil {
+			// the signer is looked up for every request, as it is created again once the API token changes
+			httpAPI.RegisterPublicFunc(approval.TokenPath, func(w http.ResponseWriter, r *http.Request) {
+				signer := approvalLinks
+				if signer == nil {
+					http.NotFound(w, r)
+					return
+				}
+				approvalHandler.HandleApproveToken(signer)(w, r)
+			})
+		}
+	}
+
+	// The metrics alone do not require the API token, if they have their own
+	if enableUpdateAPI || enableDashboard || (enableMetricsAPI && (apiToken != "" || httpAPI.Username != "")) {
+		scheduleHandler := schedule.New(map[string]string{
+			"update":  scheduleSpec,
+			"report":  reportScheduleSpec,
+			"check":   checkScheduleSpec,
+			"cleanup": cleanupScheduleSpec,
+			"apply":   applyScheduleSpec,
+		})
+		httpAPI.RegisterFunc(scheduleHandler.Path, scheduleHandler.Handle)
+	}
+
 	if enableMetricsAPI {
 		metricsHandler := apiMetrics.New()
-		httpAPI.RegisterHandler(metricsHandler.Path, metricsHandler.Handle)
+		metricsToken, _ := c.PersistentFlags().GetString("http-api-metrics-token")
+		if metricsNoAuth, _ := c.PersistentFlags().GetBool("http-api-metrics-no-auth"); metricsNoAuth {
+			httpAPI.RegisterHandlerWithToken(metricsHandler.Path, "", metricsHandler.Handle)
+		} else if metricsToken != "" {
+			httpAPI.RegisterHandlerWithToken(metricsHandler.Path, metricsToken, metricsHandler.Handle)
+		} else {
+			httpAPI.RegisterHandler(metricsHandler.Path, metricsHandler.Handle)
+		}
 	}
 
+	if watchEvents, _ := c.PersistentFlags().GetBool("watch-events"); watchEvents {
+		checkNew, _ := c.PersistentFlags().GetBool("check-new-containers")
+		go actions.DiscoverNewContainers(client, filter, func(discovered container.Container) {
+			if checkNew {
+				// the session waits for the running one, which must not keep the docker events from being read
+				go checkNewContainer(discovered, filter, updateLock)
+			}
+		}, shutdown)
+	} else if checkNew, _ := c.PersistentFlags().GetBool("check-new-containers"); checkNew {
+		log.Warn("New containers are only discovered when watching the docker events, the check new containers flag has no effect")
+	}
+
+	if enableUpdateAPI && !unblockHTTPAPI {
+		// Without scheduled sessions, there is nothing but the HTTP server to watch
+		notifyServiceManager(func() bool { return true })
+		go exitOnShutdown(updateLock)
+	}
 	if err := httpAPI.Start(enableUpdateAPI && !unblockHTTPAPI); err != nil && err != http.ErrServerClosed {
 		log.Error("failed to start API", err)
 	}
@@ -201,12 +1074,14 @@ func Run(c *cobra.Command, names []string) {
 		log.Error(err)
 	}
 
+	closeLogOutputs()
 	os.Exit(1)
 }
 
 func logNotifyExit(err error) {
 	log.Error(err)
 	notifier.Close()
+	closeLogOutputs()
 	os.Exit(1)
 }
 
@@ -215,6 +1090,31 @@ func awaitDockerClient() {
 	time.Sleep(1 * time.Second)
 }
 
+// applyCapabilities logs the negotiated docker API version and disables the features relying on the parts of the docker API that watchtower is not permitted to
+// use, as is common behind a proxy restricting access to the docker socket, rather than having them fail mid-session
+func applyCapabilities() {
+	capabilities := client.ProbeCapabilities()
+	log.Debugf("Using docker API version %s", capabilities.APIVersion)
+	if versions.LessThan(capabilities.APIVersion, flags.DockerAPIMinVersion) {
+		log.Warnf("The docker API version %s is older than %s, the oldest version supported by watchtower. Features "+
+			"that the daemon does not support are left out, which might change the recreated containers.",
+			capabilities.APIVersion, flags.DockerAPIMinVersion)
+	}
+	if !capabilities.Exec && lifecycleHooks {
+		log.Warn("The docker API does not permit running commands in containers, the lifecycle hooks are disabled")
+		lifecycleHooks = false
+	}
+	if !capabilities.ImageRemoval && cleanup {
+		log.Warn("The docker API does not permit removing images, the previous images will not be cleaned up")
+		cleanup = false
+	}
+	if !capabilities.Networks {
+		log.Warn("The docker API does not permit connecting containers to networks, containers attached to several " +
+			"networks will not be updated")
+		networksDenied = true
+	}
+}
+
 func formatDuration(d time.Duration) string {
 	sb := strings.Builder{}
 
@@ -310,67 +1210,641 @@ func runUpgradesOnSchedule(c *cobra.Command, filter t.Filter, filtering string,
 	}
 
 	scheduler := cron.New()
-	err := scheduler.AddFunc(
-		scheduleSpec,
-		func() {
-			select {
-			case v := <-lock:
-				defer func() { lock <- v }()
-				metric := runUpdatesWithNotifications(filter)
-				metrics.RegisterScan(metric)
-			default:
-				// Update was skipped
+	// reporting is set while a report-only or check session is running. Update sessions wait for those to finish,
+	// rather than being skipped, as they usually run much less frequently. It is only changed along with taking or
+	// returning the lock while holding handoff, so that an update session never finds the lock taken by a report-only
+	// session that has not set it yet.
+	var handoff sync.Mutex
+	reporting := false
+	// tryReporting takes the lock for a report-only or check session, if it is free
+	tryReporting := func() (v bool, taken bool) {
+		handoff.Lock()
+		defer handoff.Unlock()
+		select {
+		case v = <-lock:
+			reporting = true
+			return v, true
+		default:
+			return false, false
+		}
+	}
+	// doneReporting returns the lock taken by tryReporting
+	doneReporting := func(v bool) {
+		handoff.Lock()
+		defer handoff.Unlock()
+		reporting = false
+		lock <- v
+	}
+	runSession := func(reportOnly bool) {
+		if !reportOnly && atomic.LoadInt32(&updatesPaused) == 1 {
+			log.Debug("Skipped the scheduled update, as updates are paused.")
+			sessionWatchdog.Completed(time.Now())
+			return
+		}
+		var v bool
+		if reportOnly {
+			var taken bool
+			if v, taken = tryReporting(); !taken {
 				metrics.RegisterScan(nil)
 				log.Debug("Skipped another update already running.")
+				return
+			}
+			defer doneReporting(v)
+		} else {
+			handoff.Lock()
+			select {
+			case v = <-lock:
+				handoff.Unlock()
+			default:
+				waiting := reporting
+				handoff.Unlock()
+				if !waiting {
+					// Update was skipped
+					metrics.RegisterScan(nil)
+					log.Debug("Skipped another update already running.")
+					return
+				}
+				log.Debug("Waiting for the running report-only session to finish.")
+				v = <-lock
 			}
+			defer func() { lock <- v }()
+		}
 
-			nextRuns := scheduler.Entries()
-			if len(nextRuns) > 0 {
-				log.Debug("Scheduled next run: " + nextRuns[0].Next.String())
+		refreshSecrets()
+		params := newUpdateParams(filter, reportOnly)
+		// The scheduled sessions only apply the updates that the check sessions found
+		params.PendingUpdates = checkScheduleSpec != ""
+		metric := runSessionWithNotifications(params)
+		metrics.RegisterScan(metric)
+		if !reportOnly {
+			sessionWatchdog.Completed(time.Now())
+		}
+	}
+	// checks are skipped while another session is running, like report-only sessions
+	runCheck := func() {
+		v, taken := tryReporting()
+		if !taken {
+			log.Debug("Skipped the check, as another session is running.")
+			return
+		}
+		defer doneReporting(v)
+		runChecks(filter)
+	}
+	// applying the staged updates waits for the running session to finish, like update sessions
+	runApply := func() {
+		if atomic.LoadInt32(&updatesPaused) == 1 {
+			log.Debug("Skipped applying the staged updates, as updates are paused.")
+			return
+		}
+		v := <-lock
+		defer func() { lock <- v }()
+		refreshSecrets()
+		params := newUpdateParams(filter, false)
+		params.ApplyStaged = true
+		metrics.RegisterScan(runSessionWithNotifications(params))
+	}
+	// cleanups wait for the running session to finish, as they usually run the least frequently
+	runCleanup := func() {
+		v := <-lock
+		defer func() { lock <- v }()
+		actions.RemoveDeferredImages(client, stateStore)
+	}
+
+	err := addScheduledFunc(
+		scheduler,
+		scheduleSpec,
+		func() {
+			runSession(false)
+
+			if nextRuns, err := schedule.NextRuns(scheduleSpec, time.Now(), 1); err == nil && len(nextRuns) > 0 {
+				log.Info("Scheduled next run: " + nextRuns[0].Format("2006-01-02 15:04:05 -0700 MST"))
 			}
 		})
 
 	if err != nil {
 		return err
 	}
+	updateSchedule := scheduler.Entries()[0].Schedule
+	firstRun := updateSchedule.Next(time.Now())
 
-	writeStartupMessage(c, scheduler.Entries()[0].Schedule.Next(time.Now()), filtering)
+	if reportScheduleSpec != "" {
+		if monitorOnly {
+			log.Warn("All sessions are report-only when monitor only is enabled, the report schedule has no effect")
+		}
+		if err := addScheduledFunc(scheduler, reportScheduleSpec, func() { runSession(true) }); err != nil {
+			return fmt.Errorf("invalid report schedule: %w", err)
+		}
+	}
+	if checkScheduleSpec != "" {
+		if err := addScheduledFunc(scheduler, checkScheduleSpec, runCheck); err != nil {
+			return fmt.Errorf("invalid check schedule: %w", err)
+		}
+	}
+	if cleanupScheduleSpec != "" {
+		if !cleanup {
+			log.Warn("The previous images are only removed when cleanup is enabled, the cleanup schedule has no effect")
+		}
+		if err := addScheduledFunc(scheduler, cleanupScheduleSpec, runCleanup); err != nil {
+			return fmt.Errorf("invalid cleanup schedule: %w", err)
+		}
+	}
 
+	if applyScheduleSpec != "" && requireApproval {
+		log.Warn("Updates pending approval are only applied once approved, the apply schedule has no effect")
+	} else if applyScheduleSpec != "" {
+		if err := addScheduledFunc(scheduler, applyScheduleSpec, runApply); err != nil {
+			return fmt.Errorf("invalid apply schedule: %w", err)
+		}
+	}
+
+	writeStartupMessage(c, firstRun, filtering)
+	logFirstRun("report-only", reportScheduleSpec)
+	logFirstRun("check", checkScheduleSpec)
+	logFirstRun("cleanup", cleanupScheduleSpec)
+	logFirstRun("apply", applyScheduleSpec)
+
+	startWatchdog(c, updateSchedule)
 	scheduler.Start()
+	atomic.StoreInt32(&schedulerStarted, 1)
+	notifyServiceManager(func() bool { return schedulerAlive(scheduler, time.Second) })
+
+	<-shutdown
+	if _, err := systemd.Notify(systemd.Stopping); err != nil {
+		log.Debug(err)
+	}
+	scheduler.Stop()
+	log.Info("Waiting for running update to be finished...")
+	drainRunningSession(lock)
+	return nil
+}
 
-	// Graceful shut-down on SIGINT/SIGTERM
+// handleShutdownSignals closes shutdown on SIGINT or SIGTERM, whichever mode watchtower runs in, as this is also how a
+// new watchtower instance taking over after a self-update asks this one to shut down
+func handleShutdownSignals() {
 	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
-	signal.Notify(interrupt, syscall.SIGTERM)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		close(shutdown)
+	}()
+}
 
-	<-interrupt
-	scheduler.Stop()
+// exitOnShutdown exits once watchtower has been asked to shut down and the running session has finished, when only
+// the HTTP API is served, as no scheduler handles the shutdown then
+func exitOnShutdown(lock chan bool) {
+	<-shutdown
 	log.Info("Waiting for running update to be finished...")
-	<-lock
+	drainRunningSession(lock)
+	notifier.Close()
+	closeLogOutputs()
+	os.Exit(0)
+}
+
+// listPendingApprovals returns the staged updates, which wait for approval
+func listPendingApprovals() []approval.Pending {
+	var pending []approval.Pending
+	for _, staged := range actions.ListStagedUpdates(stateStore) {
+		pending = append(pending, approval.Pending{
+			Container:   strings.TrimPrefix(staged.Container, "/"),
+			LatestImage: staged.LatestImage.ShortID(),
+			StagedAt:    staged.StagedAt,
+		})
+	}
+	return pending
+}
+
+// approvalLink returns the link approving the update of the container, if approval links are enabled
+func approvalLink(container string, image t.ImageID) string {
+	return approvalLinks.Link(strings.TrimPrefix(container, "/"), image.ShortID())
+}
+
+// checkNewContainer runs an update session for a container discovered by watching the docker events, once the
+// running session has finished. The session is report-only while the scheduled updates are paused.
+func checkNewContainer(discovered container.Container, filter t.Filter, lock chan bool) {
+	v := <-lock
+	defer func() { lock <- v }()
+
+	reportOnly := atomic.LoadInt32(&updatesPaused) == 1
+	runUpdatesWithNotifications(filters.FilterByExactNames([]string{discovered.Name()}, filter), reportOnly)
+}
+
+// notifyServiceManager tells systemd that watchtower is ready, if it was started using Type=notify, and pings its
+// watchdog for as long as alive reports that watchtower is working, if the watchdog is enabled
+func notifyServiceManager(alive func() bool) {
+	notified, err := systemd.Notify(systemd.Ready)
+	if err != nil {
+		log.WithError(err).Warn("Could not notify systemd that watchtower is ready")
+		return
+	}
+	if !notified {
+		return
+	}
+	log.Debug("Notified systemd that watchtower is ready")
+
+	interval, err := systemd.WatchdogInterval()
+	if err != nil {
+		log.WithError(err).Warn("Could not enable the systemd watchdog")
+		return
+	}
+	if interval == 0 {
+		return
+	}
+	log.Debugf("Pinging the systemd watchdog every %s", interval/2)
+	go systemd.Watchdog(interval, alive, shutdown, func(err error) {
+		log.WithError(err).Warn("Could not ping the systemd watchdog")
+	})
+}
+
+// schedulerAlive returns whether the run loop of the scheduler responds within the timeout, which it does not if it
+// is stuck
+func schedulerAlive(scheduler *cron.Cron, timeout time.Duration) bool {
+	responded := make(chan struct{})
+	go func() {
+		scheduler.Entries()
+		close(responded)
+	}()
+	select {
+	case <-responded:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// startWatchdog starts alerting the watchdog URLs when the sessions on the update schedule stop completing, if any
+// are set
+func startWatchdog(c *cobra.Command, updateSchedule cron.Schedule) {
+	alert := notifications.NewWatchdogAlerter(c)
+	if alert == nil {
+		return
+	}
+	grace, _ := c.PersistentFlags().GetFloat64("notification-watchdog-grace")
+	if grace < 1 {
+		log.Warnf("The watchdog grace must be at least 1 interval, using 1 instead of %g", grace)
+		grace = 1
+	}
+	sessionWatchdog = watchdog.New(updateSchedule, grace, alert, time.Now())
+	log.Debugf("Alerting the watchdog URLs if no update session completes by %s",
+		sessionWatchdog.Deadline().Format("2006-01-02 15:04:05 -0700 MST"))
+	go sessionWatchdog.Run(time.Minute, shutdown)
+}
+
+// pushMetrics pushes the metrics of the session to the Pushgateway, if one is configured
+func pushMetrics(c *cobra.Command, report t.Report, exitCode int) {
+	url, _ := c.PersistentFlags().GetString("metrics-push-url")
+	if url == "" {
+		return
+	}
+	job, _ := c.PersistentFlags().GetString("metrics-push-job")
+
+	var metric *metrics.Metric
+	if report != nil {
+		metric = metrics.NewMetric(report)
+	}
+	if err := metrics.Push(url, job, metric, exitCode); err != nil {
+		log.Warn(err)
+		return
+	}
+	log.Debugf("Pushed the metrics of the session as job %q", job)
+}
+
+// printSchedule prints the next run times of the update and report-only sessions
+func printSchedule(runOnce bool, apiOnly bool) {
+	if runOnce {
+		fmt.Println("No sessions are scheduled, as watchtower runs once and exits")
+		return
+	}
+	if apiOnly {
+		fmt.Println("No sessions are scheduled, as updates are only triggered using the HTTP API")
+		return
+	}
+
+	specs := []struct{ name, spec string }{
+		{"Update", scheduleSpec},
+		{"Report-only", reportScheduleSpec},
+		{"Check", checkScheduleSpec},
+		{"Cleanup", cleanupScheduleSpec},
+		{"Apply", applyScheduleSpec},
+	}
+	for _, s := range specs {
+		if s.spec == "" {
+			continue
+		}
+		runs, err := schedule.NextRuns(s.spec, time.Now(), 5)
+		if err != nil {
+			log.Fatalf("Invalid %s schedule %q: %v", strings.ToLower(s.name), s.spec, err)
+		}
+		fmt.Printf("%s sessions (%s) will run at:\n", s.name, s.spec)
+		for _, run := range runs {
+			fmt.Println("  " + run.Format("2006-01-02 15:04:05 -0700 MST"))
+		}
+	}
+}
+
+// addScheduledFunc adds the function to the scheduler, to run on the schedule described by the cron expression
+func addScheduledFunc(scheduler *cron.Cron, spec string, fn func()) error {
+	sched, err := schedule.Parse(spec)
+	if err != nil {
+		return err
+	}
+	scheduler.Schedule(sched, cron.FuncJob(fn))
 	return nil
 }
 
-func runUpdatesWithNotifications(filter t.Filter) *metrics.Metric {
+// logFirstRun logs when the additional sessions of the kind first run, if they are scheduled
+func logFirstRun(kind string, spec string) {
+	if spec == "" {
+		return
+	}
+	if runs, err := schedule.NextRuns(spec, time.Now(), 1); err == nil && len(runs) > 0 {
+		log.Infof("Scheduling first %s run: %s", kind, runs[0].Format("2006-01-02 15:04:05 -0700 MST"))
+	}
+}
+
+// drainRunningSession waits for the running session, which stops after finishing the container it is updating, giving
+// up once the drain timeout is reached, if one is set. It returns whether the session finished.
+func drainRunningSession(lock chan bool) bool {
+	if drainTimeout <= 0 {
+		<-lock
+		return true
+	}
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-lock:
+		return true
+	case <-timer.C:
+		log.Warnf("The running update did not finish within %s, shutting down anyway", drainTimeout)
+		return false
+	}
+}
+
+// errSessionInterrupted is returned by the one time session when watchtower was asked to shut down before it finished
+var errSessionInterrupted = errors.New("the session was interrupted by a shutdown")
+
+// runOnceUntilShutdown runs the one time session using the params and returns its report. When watchtower is asked to
+// shut down meanwhile, it drains the session like the scheduled ones and returns errSessionInterrupted, along with the
+// report if the session finished within the drain timeout, as the deferred containers were left as they are.
+func runOnceUntilShutdown(params t.UpdateParams) (t.Report, error) {
+	var report t.Report
+	var err error
+	finished := make(chan bool, 1)
+	go func() {
+		report, err = runReportedSession(params)
+		finished <- true
+	}()
+
+	select {
+	case <-finished:
+		return report, err
+	case <-shutdown:
+	}
+	log.Info("Waiting for running update to be finished...")
+	if !drainRunningSession(finished) {
+		return nil, errSessionInterrupted
+	}
+	return report, errSessionInterrupted
+}
+
+// runUpdatesWithNotifications runs an update session and sends its report. When reportOnly is set, the session only
+// checks for and reports updates, like when the monitor only flag is set.
+func runUpdatesWithNotifications(filter t.Filter, reportOnly bool) *metrics.Metric {
+	refreshSecrets()
+	return runSessionWithNotifications(newUpdateParams(filter, reportOnly))
+}
+
+// runSessionWithNotifications runs a session using the params and sends its report
+func runSessionWithNotifications(params t.UpdateParams) *metrics.Metric {
+	if !isLeader() {
+		return nil
+	}
+	result, _ := runReportedSession(params)
+	if result == nil {
+		return nil
+	}
+	return metrics.NewMetric(result)
+}
+
+// runReportedSession runs a session using the params, sends and logs its report and returns it
+func runReportedSession(params t.UpdateParams) (t.Report, error) {
+	if !params.MonitorOnly {
+		release, err := acquireSessionLock()
+		if err != nil {
+			log.WithError(err).Error("Skipping the session")
+			return nil, err
+		}
+		defer release()
+	}
+	if err := sessionHooks.Pre(params.MonitorOnly); err != nil {
+		log.WithError(err).Error("Skipping the session, as the pre-session command failed")
+		return nil, err
+	}
 	notifier.StartNotification()
-	updateParams := t.UpdateParams{
-		Filter:         filter,
-		Cleanup:        cleanup,
-		NoRestart:      noRestart,
-		Timeout:        timeout,
-		MonitorOnly:    monitorOnly,
-		LifecycleHooks: lifecycleHooks,
-		RollingRestart: rollingRestart,
-	}
-	result, err := actions.Update(client, updateParams)
+	if params.MonitorOnly {
+		log.Debug("Running a report-only session")
+	}
+	finished := recordSession(&params, "update")
+	if heartbeatPinger != nil {
+		heartbeatPinger.Start()
+	}
+	startedAt := time.Now()
+	result, err := actions.Update(client, params)
+	finished(result, err)
+	if heartbeatPinger != nil {
+		heartbeatPinger.Finish(result, err)
+	}
+	sessionHooks.Post(params.MonitorOnly, result, err)
 	if err != nil {
 		log.Error(err)
 	}
+	announceUpdates(result)
+	if mqttPublisher != nil {
+		mqttPublisher.PublishReport(result)
+	}
+	if natsPublisher != nil {
+		natsPublisher.PublishReport(result)
+	}
 	notifier.SendNotification(result)
+	sessionHooks.Report(params.MonitorOnly, result, err)
+	if webDashboard != nil {
+		webDashboard.Record(result, params.MonitorOnly)
+	}
+	if influxMetrics != nil && result != nil {
+		if err := influxMetrics.Write(result, time.Since(startedAt)); err != nil {
+			log.Warn(err)
+		}
+	}
 	metricResults := metrics.NewMetric(result)
 	notifications.LocalLog.WithFields(log.Fields{
 		"Scanned": metricResults.Scanned,
 		"Updated": metricResults.Updated,
 		"Failed":  metricResults.Failed,
 	}).Info("Session done")
-	return metricResults
+	return result, err
+}
+
+// runChecks runs a check session, which queues the containers with a new image to be updated by the next scheduled
+// update session, without sending notifications
+func runChecks(filter t.Filter) {
+	if !isLeader() {
+		return
+	}
+	refreshSecrets()
+	params := newUpdateParams(filter, true)
+	finished := recordSession(&params, "check")
+	result, err := actions.Update(client, params)
+	finished(result, err)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	announceUpdates(result)
+	if mqttPublisher != nil {
+		mqttPublisher.PublishReport(result)
+	}
+	if natsPublisher != nil {
+		natsPublisher.PublishReport(result)
+	}
+	if webDashboard != nil {
+		webDashboard.Record(result, true)
+	}
+	notifications.LocalLog.WithFields(log.Fields{
+		"Scanned": len(result.Scanned()),
+		"Pending": len(result.Stale()),
+	}).Info("Check done")
+}
+
+// recordSession records the start of the session in the event log, tagging the events of the session with its ID, and
+// returns the function recording its end
+func recordSession(params *t.UpdateParams, kind string) func(t.Report, error) {
+	recorder := eventRecorder()
+	if recorder == nil {
+		return func(t.Report, error) {}
+	}
+	id := params.SessionID
+	if id == "" {
+		id = session.NewID()
+	}
+	params.Events = events.WithSession(recorder, id)
+	events.Record(params.Events, t.Event{
+		Type:    events.SessionStarted,
+		Details: map[string]interface{}{"kind": kind, "reportOnly": params.MonitorOnly},
+	})
+
+	return func(report t.Report, err error) {
+		event := t.Event{Type: events.SessionFinished, Details: map[string]interface{}{"kind": kind}}
+		if err != nil {
+			event.Error = err.Error()
+		}
+		if report != nil {
+			event.Details["scanned"] = len(report.Scanned())
+			event.Details["updated"] = len(report.Updated())
+			event.Details["failed"] = len(report.Failed())
+			event.Details["skipped"] = len(report.Skipped())
+			event.Details["stale"] = len(report.Stale())
+		}
+		events.Record(params.Events, event)
+	}
+}
+
+// announceUpdates notifies of the updates found for the first time, ahead of the report of the session applying them
+func announceUpdates(report t.Report) {
+	updates := actions.NewlyAvailable(stateStore, report)
+	if len(updates) == 0 {
+		return
+	}
+	notifier.SendUpdatesAvailable(t.UpdatesAvailable{
+		Updates:          updates,
+		ApplyAt:          nextApplyTime(),
+		AwaitingApproval: requireApproval,
+	})
+}
+
+// nextApplyTime returns when the next scheduled session applying updates runs, or zero if no session is scheduled
+// to apply them
+func nextApplyTime() time.Time {
+	if atomic.LoadInt32(&schedulerStarted) == 0 || requireApproval {
+		return time.Time{}
+	}
+	spec := scheduleSpec
+	if applyScheduleSpec != "" {
+		spec = applyScheduleSpec
+	} else if monitorOnly || stageOnly {
+		return time.Time{}
+	}
+	runs, err := schedule.NextRuns(spec, time.Now(), 1)
+	if err != nil || len(runs) == 0 {
+		return time.Time{}
+	}
+	if until := blackouts.Until(runs[0]); !until.Equal(runs[0]) {
+		// the updates are only applied by the first session after the blackout
+		if until.IsZero() {
+			return time.Time{}
+		}
+		if runs, err = schedule.NextRuns(spec, until.Add(-time.Nanosecond), 1); err != nil || len(runs) == 0 {
+			return time.Time{}
+		}
+	}
+	return runs[0]
+}
+
+// pauseFileExists returns whether the pause file exists, which suspends applying updates
+func pauseFileExists() bool {
+	if pauseFile == "" {
+		return false
+	}
+	_, err := os.Stat(pauseFile)
+	return err == nil
+}
+
+// newUpdateParams creates the parameters of an update session from the flags
+func newUpdateParams(filter t.Filter, reportOnly bool) t.UpdateParams {
+	if !reportOnly && pauseFileExists() {
+		log.Infof("Only checking for updates, as the pause file %s exists", pauseFile)
+		reportOnly = true
+	}
+	if period, active := blackouts.Active(time.Now()); !reportOnly && active {
+		log.Infof("Only checking for updates, as updates are not applied on %s", period.Spec)
+		reportOnly = true
+	}
+	sessionID := ""
+	if sessionLabels && !reportOnly {
+		sessionID = session.NewID()
+	}
+	params := t.UpdateParams{
+		Filter:                      filter,
+		Cleanup:                     cleanup,
+		ImageRetention:              imageRetention,
+		CleanupDelay:                cleanupDelay,
+		State:                       stateStore,
+		NoRestart:                   noRestart,
+		Timeout:                     timeout,
+		MonitorOnly:                 monitorOnly || reportOnly,
+		LifecycleHooks:              lifecycleHooks,
+		RollingRestart:              rollingRestart,
+		TransactionalGroups:         transactionalGroups,
+		NetworksDenied:              networksDenied,
+		SelfUpdateTimeout:           selfUpdateTimeout,
+		HealthStartPeriodMultiplier: healthStartPeriodMultiplier,
+		SessionID:                   sessionID,
+		Releases:                    releaseResolver,
+		Retries:                     updateRetries,
+		RetryBackoff:                updateRetryBackoff,
+		RetrySessions:               updateRetrySessions,
+		QuarantineAfter:             quarantineAfter,
+		PendingUpdates:              checkScheduleSpec != "" && (monitorOnly || reportOnly),
+		CleanupScheduled:            cleanupScheduleSpec != "",
+		StageOnly:                   stageOnly,
+		MinImageAge:                 minImageAge,
+		FailureLogLines:             failureLogLines,
+		Compose:                     composeDeployer,
+		Strategy:                    updateStrategy,
+		RequireApproval:             requireApproval,
+		ErrorBudget:                 errorBudget,
+		Shutdown:                    shutdown,
+	}
+	if approvalLinks != nil {
+		params.ApprovalLink = approvalLink
+	}
+	return params
 }
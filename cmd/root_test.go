@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/filters"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCmd(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cmd Suite")
+}
+
+var _ = Describe("the update params", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-pause")
+		Expect(err).NotTo(HaveOccurred())
+		pauseFile = filepath.Join(dir, "pause")
+	})
+	AfterEach(func() {
+		pauseFile = ""
+		sessionLabels = false
+		_ = os.RemoveAll(dir)
+	})
+
+	It("should only check for updates while the pause file exists", func() {
+		Expect(os.WriteFile(pauseFile, nil, 0o644)).To(Succeed())
+		sessionLabels = true
+		params := newUpdateParams(filters.NoFilter, false)
+		Expect(params.MonitorOnly).To(BeTrue())
+		Expect(params.SessionID).To(BeEmpty())
+	})
+
+	It("should apply the updates again once the pause file has been removed", func() {
+		Expect(os.WriteFile(pauseFile, nil, 0o644)).To(Succeed())
+		Expect(newUpdateParams(filters.NoFilter, false).MonitorOnly).To(BeTrue())
+
+		Expect(os.Remove(pauseFile)).To(Succeed())
+		Expect(newUpdateParams(filters.NoFilter, false).MonitorOnly).To(BeFalse())
+	})
+})
+
+var _ = Describe("draining the running session", func() {
+	AfterEach(func() {
+		drainTimeout = 0
+	})
+
+	It("should report that the session finished when it did", func() {
+		lock := make(chan bool, 1)
+		lock <- true
+		Expect(drainRunningSession(lock)).To(BeTrue())
+	})
+
+	It("should give up once the drain timeout is reached", func() {
+		drainTimeout = 10 * time.Millisecond
+		Expect(drainRunningSession(make(chan bool, 1))).To(BeFalse())
+	})
+})
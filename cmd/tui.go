@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/containrrr/watchtower/internal/actions"
+	"github.com/containrrr/watchtower/internal/tui"
+	"github.com/containrrr/watchtower/pkg/filters"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newTuiCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tui [CONTAINER...]",
+		Short: "Check and update the containers interactively",
+		Long: `
+	Lists the monitored containers and whether a new image is available for them, and lets you update them one at a
+	time or all at once, skip them, and view what changes with the new images. The containers are updated the same way
+	as by a regular update session, using the same flags, but no notifications are sent.
+	`,
+		Args: cobra.ArbitraryArgs,
+		PreRun: func(_ *cobra.Command, names []string) {
+			PreRun(rootCmd, names)
+		},
+		Run: runTui,
+	}
+}
+
+func runTui(_ *cobra.Command, names []string) {
+	filter, _ := filters.BuildFilter(append(names, filterPreset.Names...), enableLabel, disableLabel, scope, filterPreset.Projects)
+	if rollingRestart && monitorOnly {
+		log.Fatal("Rolling restarts is not compatible with the global monitor only flag")
+	}
+	if err := actions.CheckForSanity(client, filter, rollingRestart); err != nil {
+		log.Fatal(err)
+	}
+
+	tui.New(client, newUpdateParams(filter, false), os.Stdin, os.Stdout).Run()
+	notifier.Close()
+	closeLogOutputs()
+}
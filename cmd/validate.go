@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/flags"
+	"github.com/containrrr/watchtower/pkg/api/schedule"
+	"github.com/containrrr/watchtower/pkg/blackout"
+	"github.com/containrrr/watchtower/pkg/filters"
+	"github.com/containrrr/watchtower/pkg/logging"
+	"github.com/containrrr/watchtower/pkg/notifications"
+	"github.com/containrrr/watchtower/pkg/registry/diag"
+	"github.com/containrrr/watchtower/pkg/registry/hosts"
+	"github.com/containrrr/watchtower/pkg/state"
+	"github.com/docker/distribution/reference"
+	sdkClient "github.com/docker/docker/client"
+	units "github.com/docker/go-units"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newValidateCommand() *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:   "validate [CONTAINER...]",
+		Short: "Check the configuration and connectivity without updating any containers",
+		Long: `
+	Checks the flags and environment variables, the schedules, secrets, notification URLs and TLS files, and
+	verifies that the Docker daemon and the registries of the monitored containers can be reached, reporting the
+	result of each check. Exits with a non-zero status if any of the checks failed.
+	`,
+		Args: cobra.ArbitraryArgs,
+		Run:  runValidate,
+	}
+	validateCmd.Flags().Duration("timeout", 30*time.Second, "Time limit for checking each of the registries")
+	return validateCmd
+}
+
+// validation prints the results of the checks, and tracks whether any of them failed
+type validation struct {
+	failed bool
+}
+
+func (v *validation) report(name string, detail string, errs ...error) {
+	if len(errs) == 0 {
+		fmt.Printf("%-14s %-7s %s\n", name, "ok", detail)
+		return
+	}
+	v.failed = true
+	for _, err := range errs {
+		fmt.Printf("%-14s %-7s %s\n", name, "FAILED", err)
+	}
+}
+
+func (v *validation) skip(name string, reason string) {
+	fmt.Printf("%-14s %-7s %s\n", name, "skipped", reason)
+}
+
+func runValidate(c *cobra.Command, names []string) {
+	f := rootCmd.PersistentFlags()
+	flags.ProcessFlagAliases(f)
+	configureLogging(f)
+	v := &validation{}
+
+	flagErrs := validateFlags(f)
+	v.report("Flags", "all values are valid", flagErrs...)
+	detail, errs := validateSchedules(f)
+	v.report("Schedule", detail, errs...)
+
+	flags.GetSecretsFromFiles(rootCmd)
+	resolver := flags.NewSecretResolver(rootCmd)
+	if !resolver.HasReferences() {
+		v.skip("Secrets", "no secrets refer to a secret provider")
+	} else if _, err := resolver.Resolve(); err != nil {
+		v.report("Secrets", "", err)
+	} else {
+		v.report("Secrets", "all secret references were resolved")
+	}
+
+	urls, _ := f.GetStringArray("notification-url")
+	legacy, _ := f.GetStringSlice("notifications")
+	v.report("Notifications", fmt.Sprintf("%d notification URL(s) and %d legacy notifier(s)", len(urls), len(legacy)),
+		notifications.Validate(rootCmd)...)
+
+	if err := flags.EnvConfig(rootCmd); err != nil {
+		v.report("TLS", "", err)
+	} else if detail, err := validateTLS(); err != nil {
+		v.report("TLS", "", err)
+	} else {
+		v.report("TLS", detail)
+	}
+
+	detail, err := validateDocker()
+	if err != nil {
+		v.report("Docker", "", err)
+		v.skip("Registries", "the docker daemon could not be reached")
+	} else {
+		v.report("Docker", detail)
+		if len(flagErrs) > 0 {
+			v.skip("Registries", "the flags are invalid")
+		} else {
+			timeout, _ := c.Flags().GetDuration("timeout")
+			validateRegistries(v, f, names, timeout)
+		}
+	}
+
+	if v.failed {
+		os.Exit(1)
+	}
+}
+
+// validateFlags checks the values of the flags that are otherwise only checked when watchtower starts
+func validateFlags(f *pflag.FlagSet) []error {
+	var errs []error
+	if timeout, _ := f.GetDuration("stop-timeout"); timeout < 0 {
+		errs = append(errs, errors.New("the stop timeout cannot be negative"))
+	}
+	metricsToken, _ := f.GetString("http-api-metrics-token")
+	if metricsNoAuth, _ := f.GetBool("http-api-metrics-no-auth"); metricsNoAuth && metricsToken != "" {
+		errs = append(errs, errors.New("the metrics API cannot both have a token and no authentication"))
+	}
+	apiUsername, _ := f.GetString("http-api-username")
+	apiPassword, _ := f.GetString("http-api-password")
+	apiPasswordFile, _ := f.GetString("http-api-password-file")
+	if apiPassword != "" && apiPasswordFile != "" {
+		errs = append(errs, errors.New("the HTTP API password cannot be set both directly and using a file"))
+	}
+	if (apiUsername == "") != (apiPassword == "" && apiPasswordFile == "") {
+		errs = append(errs, errors.New("the HTTP API basic authentication requires both a username and a password"))
+	}
+	origins, _ := f.GetStringSlice("http-api-cors-origin")
+	for _, origin := range origins {
+		if u, err := url.Parse(origin); origin != "*" && (err != nil || u.Scheme == "" || u.Host == "" || strings.Trim(u.Path, "/") != "") {
+			errs = append(errs, fmt.Errorf("invalid CORS origin %q, expected a scheme and host like https://dashboard.example.com", origin))
+		}
+	}
+	enableLabel, _ := f.GetBool("label-enable")
+	if disableLabel, _ := f.GetBool("label-disable"); enableLabel && disableLabel {
+		errs = append(errs, errors.New("the enable label and disable label modes cannot be used together"))
+	}
+	if _, err := filterPresetFromFlags(f); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := updateStrategyFromFlags(f); err != nil {
+		errs = append(errs, err)
+	}
+	rollingRestart, _ := f.GetBool("rolling-restart")
+	if monitorOnly, _ := f.GetBool("monitor-only"); rollingRestart && monitorOnly {
+		errs = append(errs, errors.New("rolling restarts are not compatible with the global monitor only flag"))
+	}
+	if transactionalGroups, _ := f.GetBool("transactional-groups"); rollingRestart && transactionalGroups {
+		errs = append(errs, errors.New("rolling restarts are not compatible with transactional groups"))
+	}
+
+	for _, name := range []string{"cleanup-keep", "update-retries", "update-retry-sessions", "quarantine-after", "log-file-max-backups", "failure-log-lines", "http-api-rate-limit", "http-api-lockout-attempts"} {
+		if value, _ := f.GetInt(name); value < 0 {
+			errs = append(errs, fmt.Errorf("--%s cannot be negative", name))
+		}
+	}
+	for _, name := range []string{"cleanup-keep-younger-than", "update-retry-backoff", "drain-timeout", "min-image-age", "log-file-max-age", "volume-backup-timeout", "compose-timeout", "http-api-lockout-duration", "session-command-timeout", "strategy-plugin-timeout"} {
+		if value, _ := f.GetDuration(name); value < 0 {
+			errs = append(errs, fmt.Errorf("--%s cannot be negative", name))
+		}
+	}
+
+	if value, _ := f.GetString("min-free-space"); value != "" {
+		if _, err := units.FromHumanSize(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid minimum free space %q: %w", value, err))
+		}
+	}
+	if value, _ := f.GetString("event-log-max-size"); value != "" {
+		if _, err := units.FromHumanSize(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid event log max size %q: %w", value, err))
+		}
+	}
+	if spec, _ := f.GetString("log-level"); spec != "" {
+		if _, err := logging.ParseLevels(spec, log.InfoLevel); err != nil {
+			errs = append(errs, fmt.Errorf("invalid log level %q: %w", spec, err))
+		}
+	}
+	if value, _ := f.GetString("log-file-max-size"); value != "" {
+		if _, err := units.FromHumanSize(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid log file max size %q: %w", value, err))
+		}
+	}
+	if dir, _ := f.GetString("registry-credentials-dir"); dir != "" {
+		if info, err := os.Stat(dir); err != nil {
+			errs = append(errs, fmt.Errorf("invalid registry credentials directory: %w", err))
+		} else if !info.IsDir() {
+			errs = append(errs, fmt.Errorf("the registry credentials directory %s is not a directory", dir))
+		}
+	}
+	if specs, _ := f.GetStringSlice("no-update-on"); len(specs) > 0 {
+		if _, err := blackout.Parse(specs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if configFile, err := flags.ReadConfigFile(f); err != nil {
+		errs = append(errs, err)
+	} else if err = hosts.Configure(configFile.Registries); err != nil {
+		errs = append(errs, fmt.Errorf("invalid registry settings: %w", err))
+	}
+	if stateFile, _ := f.GetString("state-file"); stateFile != "" {
+		if _, err := state.New(stateFile); err != nil {
+			errs = append(errs, fmt.Errorf("could not load the state file %s: %w", stateFile, err))
+		}
+	}
+	return errs
+}
+
+// validateSchedules parses the cron expressions of the update, report-only, check, cleanup and apply sessions,
+// describing when the next update session would run
+func validateSchedules(f *pflag.FlagSet) (string, []error) {
+	spec, _ := f.GetString("schedule")
+
+	var errs []error
+	detail := ""
+	if sched, err := schedule.Parse(spec); err != nil {
+		errs = append(errs, fmt.Errorf("invalid schedule %q: %w", spec, err))
+	} else {
+		detail = "next run at " + sched.Next(time.Now()).Format("2006-01-02 15:04:05 -0700 MST")
+	}
+	for _, kind := range []string{"report", "check", "cleanup", "apply"} {
+		value, _ := f.GetString(kind + "-schedule")
+		if value == "" {
+			continue
+		}
+		if _, err := schedule.Parse(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s schedule %q: %w", kind, value, err))
+		}
+	}
+	return detail, errs
+}
+
+// validateTLS loads the certificates used to connect to the docker daemon, if TLS verification is enabled
+func validateTLS() (string, error) {
+	if os.Getenv("DOCKER_TLS_VERIFY") == "" {
+		return "TLS verification is not enabled", nil
+	}
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if certPath == "" {
+		return "", errors.New("DOCKER_CERT_PATH must be set to the directory containing ca.pem, cert.pem and key.pem")
+	}
+
+	if _, err := tls.LoadX509KeyPair(filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem")); err != nil {
+		return "", fmt.Errorf("invalid client certificate: %w", err)
+	}
+	ca, err := os.ReadFile(filepath.Join(certPath, "ca.pem"))
+	if err != nil {
+		return "", fmt.Errorf("invalid CA certificate: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(ca) {
+		return "", fmt.Errorf("%s contains no valid certificates", filepath.Join(certPath, "ca.pem"))
+	}
+	return "certificates loaded from " + certPath, nil
+}
+
+// validateDocker checks that the docker daemon can be reached using the configured host
+func validateDocker() (string, error) {
+	cli, err := sdkClient.NewClientWithOpts(sdkClient.FromEnv)
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ping, err := cli.Ping(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not reach the docker daemon at %s: %w", cli.DaemonHost(), err)
+	}
+	return fmt.Sprintf("reached %s using API version %s", cli.DaemonHost(), ping.APIVersion), nil
+}
+
+// validateRegistries checks that the registries of the monitored containers can be reached, retrieving the digest of
+// one of the images of each registry
+func validateRegistries(v *validation, f *pflag.FlagSet, names []string, timeout time.Duration) {
+	preset, err := filterPresetFromFlags(f)
+	if err != nil {
+		v.report("Registries", "", err)
+		return
+	}
+	filter, _ := filters.BuildFilter(append(names, preset.Names...), preset.LabelEnable, preset.LabelDisable, preset.Scope, preset.Projects)
+
+	containers, err := newClientFromFlags(f).ListContainers(filter)
+	if err != nil {
+		v.report("Registries", "", fmt.Errorf("could not list the containers: %w", err))
+		return
+	}
+
+	targets := map[string]diag.Target{}
+	counts := map[string]int{}
+	for _, c := range containers {
+		named, err := reference.ParseNormalizedNamed(c.ImageName())
+		if err != nil {
+			continue
+		}
+		target, err := diag.ParseTarget(named.String())
+		if err != nil {
+			continue
+		}
+		if _, found := targets[target.Host]; !found {
+			targets[target.Host] = target
+		}
+		counts[target.Host]++
+	}
+	if len(targets) == 0 {
+		v.skip("Registries", "no containers are monitored")
+		return
+	}
+
+	hosts := make([]string, 0, len(targets))
+	for host := range targets {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		steps := diag.Run(ctx, targets[host])
+		cancel()
+
+		name := "Registry " + host
+		for _, step := range steps {
+			if step.Err != nil {
+				v.report(name, "", fmt.Errorf("%s step failed: %w (run `watchtower diag registry %s` for details)",
+					step.Name, step.Err, targets[host].Image))
+				break
+			}
+		}
+		if !diag.Failed(steps) {
+			v.report(name, fmt.Sprintf("reachable, used by %d container(s)", counts[host]))
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/containrrr/watchtower/internal/actions"
 	"github.com/containrrr/watchtower/pkg/container"
+	dockerContainer "github.com/docker/docker/api/types/container"
 
 	. "github.com/containrrr/watchtower/internal/actions/mocks"
 	. "github.com/onsi/ginkgo"
@@ -119,5 +120,42 @@ var _ = Describe("the actions package", func() {
 				Expect(client.TestData.TriedToRemoveImage()).To(BeFalse())
 			})
 		})
+		When("the instances elect a leader", func() {
+			newInstance := func(name string, cmd ...string) container.Container {
+				return CreateMockContainerWithConfig(name, name, "watchtower", true, false, time.Now(),
+					&dockerContainer.Config{
+						Image:  "watchtower",
+						Cmd:    cmd,
+						Env:    []string{"WATCHTOWER_LEADER_ELECTION=label"},
+						Labels: map[string]string{"com.centurylinklabs.watchtower": "true"},
+					})
+			}
+			var stopped []string
+			BeforeEach(func() {
+				stopped = nil
+			})
+			testData := func(containers ...container.Container) *TestData {
+				return &TestData{
+					NameOfContainerToKeep: "newest",
+					Containers:            containers,
+					OnStop:                func(c container.Container) { stopped = append(stopped, c.Name()) },
+				}
+			}
+
+			It("should leave the differently configured instances running", func() {
+				client := CreateMockClient(testData(newInstance("stack-a", "--label-enable"), newInstance("newest")), false, false)
+				Expect(actions.CheckForDuplicateWatchtowerInstances(client, false, "")).To(Succeed())
+				Expect(stopped).To(BeEmpty())
+			})
+			It("should stop the previous instances configured the same way", func() {
+				client := CreateMockClient(testData(
+					newInstance("previous", "--label-enable"),
+					newInstance("stack-b", "--interval", "60"),
+					newInstance("newest", "--label-enable"),
+				), false, false)
+				Expect(actions.CheckForDuplicateWatchtowerInstances(client, false, "")).To(Succeed())
+				Expect(stopped).To(ConsistOf("previous"))
+			})
+		})
 	})
 })
@@ -0,0 +1,53 @@
+package actions
+
+import (
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// announcedUpdatesKey is the state store key of the new images that have been announced as available, by container
+const announcedUpdatesKey = "announced-updates"
+
+// NewlyAvailable returns the stale containers of the report whose new image has not been announced yet, recording
+// them as announced. Containers that have been updated or turned out to be up to date are forgotten, so that their
+// next new image is announced again. The containers that could not be checked keep their previous state.
+func NewlyAvailable(state types.StateStore, report types.Report) []types.ContainerReport {
+	if state == nil || report == nil {
+		return nil
+	}
+
+	announced := map[string]types.ImageID{}
+	if _, err := state.Get(announcedUpdatesKey, &announced); err != nil {
+		log.WithError(err).Warn("Could not load the announced updates, starting over")
+		announced = map[string]types.ImageID{}
+	}
+
+	changed := false
+	for _, c := range append(report.Updated(), report.Fresh()...) {
+		if _, found := announced[c.Name()]; found {
+			delete(announced, c.Name())
+			changed = true
+		}
+	}
+
+	var available []types.ContainerReport
+	for _, c := range report.Stale() {
+		if announced[c.Name()] == c.LatestImageID() {
+			continue
+		}
+		announced[c.Name()] = c.LatestImageID()
+		available = append(available, c)
+		changed = true
+	}
+
+	if changed {
+		var value interface{} = announced
+		if len(announced) == 0 {
+			value = nil
+		}
+		if err := state.Set(announcedUpdatesKey, value); err != nil {
+			log.WithError(err).Error("Could not save the announced updates")
+		}
+	}
+	return available
+}
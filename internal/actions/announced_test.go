@@ -0,0 +1,43 @@
+package actions_test
+
+import (
+	"github.com/containrrr/watchtower/internal/actions"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/state"
+
+	. "github.com/containrrr/watchtower/internal/actions/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the announcement of available updates", func() {
+	It("should only announce each new image once", func() {
+		store, err := state.New("")
+		Expect(err).NotTo(HaveOccurred())
+
+		report := CreateMockProgressReport(session.StaleState, session.FreshState)
+		available := actions.NewlyAvailable(store, report)
+		Expect(available).To(HaveLen(1))
+		Expect(available[0].Name()).To(Equal("stal1"))
+
+		Expect(actions.NewlyAvailable(store, report)).To(BeEmpty())
+	})
+
+	It("should announce the update again once the container has been up to date", func() {
+		store, err := state.New("")
+		Expect(err).NotTo(HaveOccurred())
+		stale := CreateMockProgressReport(session.StaleState)
+		Expect(actions.NewlyAvailable(store, stale)).To(HaveLen(1))
+
+		c, _ := CreateContainerForProgress(0, 51, "stal%d")
+		progress := session.Progress{}
+		progress.AddScanned(c, c.ImageID())
+		Expect(actions.NewlyAvailable(store, progress.Report())).To(BeEmpty())
+
+		Expect(actions.NewlyAvailable(store, stale)).To(HaveLen(1))
+	})
+
+	It("should not announce anything without a state store", func() {
+		Expect(actions.NewlyAvailable(nil, CreateMockProgressReport(session.StaleState))).To(BeEmpty())
+	})
+})
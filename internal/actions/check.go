@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/containrrr/watchtower/pkg/types"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/containrrr/watchtower/pkg/filters"
@@ -38,9 +39,11 @@ func CheckForSanity(client container.Client, filter types.Filter, rollingRestart
 // CheckForMultipleWatchtowerInstances will ensure that there are not multiple instances of the
 // watchtower running simultaneously. If multiple watchtower containers are detected, this function
 // will stop and remove all but the most recently started container. This behaviour can be bypassed
-// if a scope UID is defined.
+// if a scope UID is defined. Only the instances using the exact same set of scopes are cleaned up.
+// Since this is only called once the configuration has been loaded and the docker API has been reached, asking the
+// previous instance to stop also acts as the signal that a self-update handoff has succeeded.
 func CheckForMultipleWatchtowerInstances(client container.Client, cleanup bool, scope string) error {
-	containers, err := client.ListContainers(filters.FilterByScope(scope, filters.WatchtowerContainersFilter))
+	containers, err := client.ListContainers(filters.FilterByExactScope(scope, filters.WatchtowerContainersFilter))
 
 	if err != nil {
 		return err
@@ -55,6 +58,53 @@ func CheckForMultipleWatchtowerInstances(client container.Client, cleanup bool,
 	return cleanupExcessWatchtowers(containers, client, cleanup)
 }
 
+// CheckForDuplicateWatchtowerInstances is used in place of CheckForMultipleWatchtowerInstances when the instances
+// elect a leader, as several of them are expected to share the docker host. Only the instances configured exactly like
+// the most recently started one are stopped, like the previous instance after a self-update, leaving the differently
+// configured instances running.
+func CheckForDuplicateWatchtowerInstances(client container.Client, cleanup bool, scope string) error {
+	containers, err := client.ListContainers(filters.FilterByExactScope(scope, filters.WatchtowerContainersFilter))
+	if err != nil {
+		return err
+	}
+	if len(containers) <= 1 {
+		log.Debug("There are no additional watchtower containers")
+		return nil
+	}
+
+	sort.Sort(sorter.ByCreated(containers))
+	newest := containers[len(containers)-1]
+	var duplicates []container.Container
+	for _, c := range containers[:len(containers)-1] {
+		if sameConfiguration(c, newest) {
+			duplicates = append(duplicates, c)
+		}
+	}
+	if len(duplicates) == 0 {
+		log.Debugf("Leaving the %d other watchtower instances running, as they are configured differently", len(containers)-1)
+		return nil
+	}
+
+	log.Info("Found previous instances of this watchtower instance. Cleaning up.")
+	return cleanupExcessWatchtowers(append(duplicates, newest), client, cleanup)
+}
+
+// sameConfiguration returns whether the containers use the same image, command and environment
+func sameConfiguration(a container.Container, b container.Container) bool {
+	configA, configB := a.ContainerInfo().Config, b.ContainerInfo().Config
+	if configA == nil || configB == nil {
+		return configA == configB
+	}
+	if a.ImageName() != b.ImageName() || strings.Join(configA.Cmd, "\x00") != strings.Join(configB.Cmd, "\x00") {
+		return false
+	}
+	envA := append([]string{}, configA.Env...)
+	envB := append([]string{}, configB.Env...)
+	sort.Strings(envA)
+	sort.Strings(envB)
+	return strings.Join(envA, "\x00") == strings.Join(envB, "\x00")
+}
+
 func cleanupExcessWatchtowers(containers []container.Container, client container.Client, cleanup bool) error {
 	var stopErrors int
 
@@ -69,7 +119,7 @@ func cleanupExcessWatchtowers(containers []container.Container, client container
 			continue
 		}
 
-		if cleanup {
+		if !c.IsNoCleanup(!cleanup) {
 			if err := client.RemoveImageByID(c.ImageID()); err != nil {
 				log.WithError(err).Warning("Could not cleanup watchtower images, possibly because of other watchtowers instances in other scopes.")
 			}
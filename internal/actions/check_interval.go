@@ -0,0 +1,54 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// lastChecksKey is the state store key of the times the containers with a check interval were last checked
+const lastChecksKey = "last-checks"
+
+func loadLastChecks(state types.StateStore) map[string]time.Time {
+	checks := map[string]time.Time{}
+	if _, err := state.Get(lastChecksKey, &checks); err != nil {
+		log.WithError(err).Warn("Could not load the times of the previous checks, checking every container")
+		return map[string]time.Time{}
+	}
+	return checks
+}
+
+func saveLastChecks(state types.StateStore, checks map[string]time.Time) {
+	var value interface{} = checks
+	if len(checks) == 0 {
+		value = nil
+	}
+	if err := state.Set(lastChecksKey, value); err != nil {
+		log.WithError(err).Error("Could not save the times of the checks")
+	}
+}
+
+// checkInterval returns the check interval of the container, forgetting when it was last checked if it has none
+func checkInterval(c container.Container, lastChecks map[string]time.Time) time.Duration {
+	interval, err := c.CheckInterval()
+	if err != nil {
+		log.WithField("container", c.Name()).Warnf("%v, checking it during every session", err)
+	}
+	if interval <= 0 {
+		delete(lastChecks, c.Name())
+	}
+	return interval
+}
+
+// checkNotDue returns how long ago the container was last checked, and whether that was less than its check interval
+// ago, in which case it is not checked again yet
+func checkNotDue(c container.Container, interval time.Duration, lastChecks map[string]time.Time, now time.Time) (time.Duration, bool) {
+	last, found := lastChecks[c.Name()]
+	if interval <= 0 || !found {
+		return 0, false
+	}
+	since := now.Sub(last)
+	return since, since < interval
+}
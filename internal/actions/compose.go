@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/events"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// splitComposed separates the containers created by docker compose, which are left to compose to recreate, from the
+// ones watchtower recreates itself. Watchtower always updates its own container.
+func splitComposed(containers []container.Container) (others []container.Container, composed []container.Container) {
+	for _, c := range containers {
+		if _, isComposed := c.ComposeProject(); isComposed && !c.IsWatchtower() {
+			composed = append(composed, c)
+		} else {
+			others = append(others, c)
+		}
+	}
+	return others, composed
+}
+
+// redeployComposeProjects re-deploys the compose projects of the containers to restart, once per project, rather than
+// recreating the containers. If a re-deploy fails, every container of the project that was to be restarted has failed.
+func redeployComposeProjects(containers []container.Container, client container.Client, params types.UpdateParams, progress *session.Progress) map[types.ContainerID]error {
+	failed := make(map[types.ContainerID]error, len(containers))
+	cleanup := newImageCleanup(len(containers))
+
+	var names []string
+	projects := map[string]types.ComposeProject{}
+	members := map[string][]container.Container{}
+	for _, c := range containers {
+		if !c.ToRestart() {
+			continue
+		}
+		project, _ := c.ComposeProject()
+		if known, found := projects[project.Name]; found {
+			project.Services = appendServices(known.Services, project.Services)
+		} else {
+			names = append(names, project.Name)
+		}
+		projects[project.Name] = project
+		members[project.Name] = append(members[project.Name], c)
+	}
+
+	for _, name := range names {
+		if deferRemaining(members[name], progress, params) {
+			continue
+		}
+		upStartedAt := time.Now()
+		err := params.Compose.Up(projects[name])
+		upDuration := time.Since(upStartedAt)
+		for _, c := range members[name] {
+			progress.AddRecreateDuration(c.ID(), upDuration)
+		}
+		if err != nil {
+			log.WithField("project", name).Error(err)
+			for _, c := range members[name] {
+				failed[c.ID()] = err
+			}
+			continue
+		}
+		for _, c := range members[name] {
+			events.Record(params.Events, types.Event{
+				Type:      events.ContainerRecreated,
+				Container: c.Name(),
+				Image:     c.ImageName(),
+				Details:   map[string]interface{}{"previousImage": c.SafeImageID(), "composeProject": name},
+			})
+			if c.Stale {
+				cleanup.add(c, progress, params)
+			}
+		}
+	}
+
+	cleanup.run(client, params)
+	return failed
+}
+
+// appendServices appends the services that are not listed yet
+func appendServices(services []string, added []string) []string {
+	for _, service := range added {
+		found := false
+		for _, listed := range services {
+			found = found || listed == service
+		}
+		if !found {
+			services = append(services, service)
+		}
+	}
+	return services
+}
@@ -0,0 +1,101 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// deferredRemovalsKey is the state store key of the previous images that are due to be removed
+const deferredRemovalsKey = "deferred-image-removals"
+
+// deferredRemoval is a previous image that is removed once its grace period has passed
+type deferredRemoval struct {
+	Repository  string    `json:"repository"`
+	RemoveAfter time.Time `json:"removeAfter"`
+}
+
+func loadDeferredRemovals(state types.StateStore) map[types.ImageID]deferredRemoval {
+	removals := map[types.ImageID]deferredRemoval{}
+	if _, err := state.Get(deferredRemovalsKey, &removals); err != nil {
+		log.WithError(err).Warn("Could not load the deferred image removals, starting over")
+		return map[types.ImageID]deferredRemoval{}
+	}
+	return removals
+}
+
+func saveDeferredRemovals(state types.StateStore, removals map[types.ImageID]deferredRemoval) {
+	var value interface{} = removals
+	if len(removals) == 0 {
+		value = nil
+	}
+	if err := state.Set(deferredRemovalsKey, value); err != nil {
+		log.WithError(err).Error("Could not save the deferred image removals")
+	}
+}
+
+// deferImageRemovals schedules the removal of the supplied images. Images that are already scheduled keep their
+// original removal time.
+func deferImageRemovals(state types.StateStore, images map[types.ImageID]string, removeAfter time.Time) {
+	if len(images) == 0 {
+		return
+	}
+	removals := loadDeferredRemovals(state)
+	for imageID, repository := range images {
+		if _, found := removals[imageID]; found {
+			continue
+		}
+		log.Infof("Removing image %s after %s", imageID.ShortID(), removeAfter.Format(time.RFC3339))
+		removals[imageID] = deferredRemoval{Repository: repository, RemoveAfter: removeAfter}
+	}
+	saveDeferredRemovals(state, removals)
+}
+
+// removeDeferredImages removes the images whose grace period has passed. Images that have been taken into use again
+// in the meantime, like after a manual rollback, are kept.
+func removeDeferredImages(client container.Client, state types.StateStore, now time.Time) {
+	removals := loadDeferredRemovals(state)
+	if len(removals) == 0 {
+		return
+	}
+
+	unused := map[string]map[types.ImageID]bool{}
+	for imageID, removal := range removals {
+		if now.Before(removal.RemoveAfter) {
+			continue
+		}
+
+		if _, listed := unused[removal.Repository]; !listed {
+			images, err := client.ListUnusedImages(removal.Repository)
+			if err != nil {
+				// The removal is retried during the next session
+				log.WithError(err).Errorf("Could not list the previous images of %s", removal.Repository)
+				continue
+			}
+			unused[removal.Repository] = map[types.ImageID]bool{}
+			for _, image := range images {
+				unused[removal.Repository][image.ID] = true
+			}
+		}
+		delete(removals, imageID)
+
+		if !unused[removal.Repository][imageID] {
+			log.Debugf("Keeping image %s, which is in use again or has already been removed", imageID.ShortID())
+			continue
+		}
+
+		if err := client.RemoveImageByID(imageID); err != nil {
+			log.Error(err)
+		}
+	}
+	saveDeferredRemovals(state, removals)
+}
+
+// RemoveDeferredImages removes the previous images whose removal has been deferred to the scheduled cleanup
+// sessions, once their cleanup delay, if any, has passed
+func RemoveDeferredImages(client container.Client, state types.StateStore) {
+	log.Debug("Removing the previous images of the updated containers")
+	removeDeferredImages(client, state, time.Now())
+}
@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// eventRetryDelay is how long to wait before subscribing to the docker events again, after the event stream failed
+const eventRetryDelay = 5 * time.Second
+
+// DiscoverNewContainers watches the docker events for containers that start, calling discovered with each of the
+// containers passing the filter whose name none of the monitored containers had so far, until done is closed.
+// Containers recreated with the name of a monitored container, like the ones watchtower updates, are not new.
+func DiscoverNewContainers(client container.Client, filter types.Filter, discovered func(container.Container), done <-chan struct{}) {
+	var known map[string]bool
+	known = discoverContainers(client, filter, known, discovered)
+
+	for {
+		ids, errs := client.WatchContainerStarts(done)
+		log.Debug("Watching the docker events for new containers")
+		err := watchContainerStarts(ids, errs, func() {
+			known = discoverContainers(client, filter, known, discovered)
+		})
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+		log.WithError(err).Warnf("Lost the docker event stream, watching it again in %s", eventRetryDelay)
+		select {
+		case <-done:
+			return
+		case <-time.After(eventRetryDelay):
+		}
+		// Containers could have started while the events were not watched
+		known = discoverContainers(client, filter, known, discovered)
+	}
+}
+
+// watchContainerStarts calls started for each of the container starts, until the event stream ends, returning the
+// error it failed with, if any
+func watchContainerStarts(ids <-chan types.ContainerID, errs <-chan error, started func()) error {
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case id, ok := <-ids:
+			if !ok {
+				select {
+				case err := <-errs:
+					return err
+				default:
+					return nil
+				}
+			}
+			log.WithField("id", id.ShortID()).Debug("A container has started")
+			started()
+		}
+	}
+}
+
+// discoverContainers lists the containers passing the filter, calling discovered with the ones whose names are not
+// known yet, and returns the updated names. If no names are known yet, the containers are only taken note of.
+func discoverContainers(client container.Client, filter types.Filter, known map[string]bool, discovered func(container.Container)) map[string]bool {
+	containers, err := client.ListContainers(filter)
+	if err != nil {
+		log.WithError(err).Warn("Could not list the containers to discover the new ones")
+		return known
+	}
+
+	if known == nil {
+		known = make(map[string]bool, len(containers))
+		for _, c := range containers {
+			known[c.Name()] = true
+		}
+		return known
+	}
+	for _, c := range containers {
+		if known[c.Name()] {
+			continue
+		}
+		known[c.Name()] = true
+		log.WithField("container", c.Name()).Info("Discovered a new container to monitor")
+		discovered(c)
+	}
+	return known
+}
@@ -0,0 +1,91 @@
+package actions_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/actions"
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/filters"
+	"github.com/containrrr/watchtower/pkg/types"
+
+	. "github.com/containrrr/watchtower/internal/actions/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// lockedClient lets the tests change the containers while they are being discovered
+type lockedClient struct {
+	MockClient
+	mutex *sync.Mutex
+}
+
+func (client lockedClient) ListContainers(filter types.Filter) ([]container.Container, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return client.MockClient.ListContainers(filter)
+}
+
+var _ = Describe("the discovery of new containers", func() {
+	var testData *TestData
+	var discovered chan string
+	var done chan struct{}
+	var mutex *sync.Mutex
+
+	setContainers := func(containers ...container.Container) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		testData.Containers = containers
+	}
+
+	BeforeEach(func() {
+		testData = &TestData{
+			Containers: []container.Container{
+				CreateMockContainer("test-container-01", "test-container-01", "fake-image:latest", time.Now()),
+			},
+			StartEvents: make(chan types.ContainerID),
+		}
+		discovered = make(chan string, 10)
+		done = make(chan struct{})
+		mutex = &sync.Mutex{}
+	})
+	AfterEach(func() {
+		close(done)
+	})
+
+	discover := func(filter types.Filter) {
+		client := lockedClient{CreateMockClient(testData, false, false), mutex}
+		go actions.DiscoverNewContainers(client, filter, func(c container.Container) {
+			discovered <- c.Name()
+		}, done)
+	}
+
+	It("should report the containers that start with a new name", func() {
+		discover(filters.NoFilter)
+		testData.StartEvents <- "test-container-01"
+		Consistently(discovered).ShouldNot(Receive())
+
+		setContainers(testData.Containers[0],
+			CreateMockContainer("test-container-02", "test-container-02", "fake-image:latest", time.Now()))
+		testData.StartEvents <- "test-container-02"
+		Eventually(discovered).Should(Receive(Equal("test-container-02")))
+	})
+
+	It("should not report containers that are recreated with a known name", func() {
+		discover(filters.NoFilter)
+		testData.StartEvents <- "test-container-01"
+		setContainers(CreateMockContainer("test-container-01-new", "test-container-01", "fake-image:latest", time.Now()))
+		testData.StartEvents <- "test-container-01-new"
+		Consistently(discovered).ShouldNot(Receive())
+	})
+
+	It("should not report containers that do not pass the filter", func() {
+		testData.ApplyFilter = true
+		discover(filters.FilterByNames([]string{"test-container-01"}, filters.NoFilter))
+		testData.StartEvents <- "test-container-01"
+		setContainers(testData.Containers[0],
+			CreateMockContainer("test-container-02", "test-container-02", "fake-image:latest", time.Now()))
+		testData.StartEvents <- "test-container-02"
+		Consistently(discovered).ShouldNot(Receive())
+	})
+})
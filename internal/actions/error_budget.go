@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// failedCheck is a container whose check failed, along with the error
+type failedCheck struct {
+	container container.Container
+	err       error
+}
+
+// spendErrorBudgets spends the error budget of every image that failed to be checked during the session, and restores
+// the one of every image that was only checked successfully, once per image however many containers use it. The failed
+// checks are escalated once the budget of their image is exhausted. The ones within the budget are left out of the
+// report, only keeping a skip reason, so that they neither trigger notifications nor are retried or quarantined.
+func spendErrorBudgets(budget types.ErrorBudget, failed map[string][]failedCheck, checked map[string]bool, progress *session.Progress) {
+	for imageName := range checked {
+		if _, found := failed[imageName]; !found {
+			budget.Restore(imageName)
+		}
+	}
+	for imageName, checks := range failed {
+		escalate := budget.Spend(imageName)
+		for _, check := range checks {
+			c := check.container
+			if escalate {
+				log.Warnf("Unable to update container %q: %v. Proceeding to next.", c.Name(), check.err)
+				progress.AddSkipped(c, check.err)
+				continue
+			}
+			log.Debugf("Unable to update container %q: %v. Proceeding to next.", c.Name(), check.err)
+			progress.AddScanned(c, c.SafeImageID())
+			progress.SetSkipReason(c.ID(), session.SkipWithinErrorBudget)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	dockerTypes "github.com/docker/docker/api/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxFailureLogBytes caps the logs added to the report of a failed container, keeping the end of them, so that the
+// notifications stay within the message limits of the services
+const maxFailureLogBytes = 4096
+
+// checkRecreated returns an error if the recreated container has stopped running or is unhealthy, carrying the tail of
+// its logs. It is only checked if the number of log lines to report is set, and requires the post-update wait label
+// to be set on the containers, as they are checked right after being started otherwise. The containers that were
+// created without being started, as their previous container was stopped, are not checked. Failing health checks are
+// ignored during the (scaled) start period declared by the health check of the recreated container.
+func checkRecreated(client container.Client, c container.Container, newContainerID types.ContainerID, params types.UpdateParams) error {
+	if params.FailureLogLines <= 0 {
+		return nil
+	}
+	recreated, err := client.GetContainer(newContainerID)
+	if err != nil || recreated.ContainerInfo() == nil || recreated.ContainerInfo().State == nil {
+		log.WithField("container", c.Name()).WithError(err).Debug("Could not inspect the recreated container")
+		return nil
+	}
+
+	var failure error
+	state := recreated.ContainerInfo().State
+	if !wasStarted(state) {
+		return nil
+	}
+	switch {
+	case state.Restarting || !state.Running:
+		failure = fmt.Errorf("%s exited with code %d after being recreated", c.Name(), state.ExitCode)
+	case state.Health != nil && state.Health.Status == "unhealthy":
+		if recreated.InHealthStartPeriod(params.HealthStartPeriodMultiplier, time.Now()) {
+			log.WithField("container", c.Name()).Debug("The recreated container is unhealthy, but still within its health check start period")
+			return nil
+		}
+		failure = fmt.Errorf("%s is unhealthy after being recreated", c.Name())
+	default:
+		return nil
+	}
+
+	var logs bytes.Buffer
+	if err := client.ContainerLogs(newContainerID, params.FailureLogLines, &logs); err != nil {
+		log.WithField("container", c.Name()).WithError(err).Warn("Could not retrieve the logs of the recreated container")
+		return failure
+	}
+	return session.WithLogs(capLogs(logs.String()), failure)
+}
+
+// wasStarted returns whether the container has been started, as docker reports the zero time for the containers that
+// never were
+func wasStarted(state *dockerTypes.ContainerState) bool {
+	startedAt, err := time.Parse(time.RFC3339Nano, state.StartedAt)
+	return state.Running || (err == nil && !startedAt.IsZero())
+}
+
+// capLogs keeps the end of the logs within maxFailureLogBytes, starting at a whole line
+func capLogs(logs string) string {
+	logs = strings.TrimRight(logs, "\n")
+	if len(logs) <= maxFailureLogBytes {
+		return logs
+	}
+	logs = logs[len(logs)-maxFailureLogBytes:]
+	if newline := strings.IndexByte(logs, '\n'); newline >= 0 {
+		logs = logs[newline+1:]
+	}
+	return "[...]\n" + logs
+}
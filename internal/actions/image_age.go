@@ -0,0 +1,36 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// imageAgeRemaining returns how much longer the new image of the container has to exist before the container is
+// updated to it, or zero if it is old enough. Images whose creation time is unknown are considered old enough.
+func imageAgeRemaining(client container.Client, c container.Container, imageID types.ImageID, params types.UpdateParams, now time.Time) time.Duration {
+	minAge, err := c.MinImageAge(params.MinImageAge)
+	if err != nil {
+		log.WithField("container", c.Name()).Warnf("%v, using the default of %s", err, minAge)
+	}
+	if minAge <= 0 {
+		return 0
+	}
+
+	info, err := client.GetImageInfo(imageID)
+	if err != nil {
+		log.WithField("container", c.Name()).WithError(err).Debug("Could not inspect the new image to determine its age")
+		return 0
+	}
+	created, err := time.Parse(time.RFC3339Nano, info.Created)
+	if err != nil {
+		log.WithField("container", c.Name()).WithError(err).Debug("Could not parse the creation time of the new image")
+		return 0
+	}
+	if remaining := created.Add(minAge).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/filters"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// LabelSnapshotVersion is the version of the label snapshot format written by SnapshotLabels
+const LabelSnapshotVersion = 1
+
+// LabelSnapshot contains the watchtower labels of a set of containers at a specific point in time
+type LabelSnapshot struct {
+	Version    int                      `json:"version"`
+	Created    time.Time                `json:"created"`
+	Containers []ContainerLabelSnapshot `json:"containers"`
+}
+
+// ContainerLabelSnapshot contains the watchtower labels of a single container
+type ContainerLabelSnapshot struct {
+	Name   string            `json:"name"`
+	Image  string            `json:"image"`
+	Labels map[string]string `json:"labels"`
+}
+
+// SnapshotLabels collects the watchtower labels of all the containers matching the filter
+func SnapshotLabels(client container.Client, filter types.Filter) (*LabelSnapshot, error) {
+	containers, err := client.ListContainers(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &LabelSnapshot{
+		Version:    LabelSnapshotVersion,
+		Created:    time.Now(),
+		Containers: make([]ContainerLabelSnapshot, 0, len(containers)),
+	}
+
+	for _, c := range containers {
+		labels := c.WatchtowerLabels()
+		if len(labels) == 0 {
+			continue
+		}
+		snapshot.Containers = append(snapshot.Containers, ContainerLabelSnapshot{
+			Name:   c.Name(),
+			Image:  c.ImageName(),
+			Labels: labels,
+		})
+	}
+
+	return snapshot, nil
+}
+
+// WriteLabelSnapshot encodes the snapshot as JSON and writes it to w
+func WriteLabelSnapshot(w io.Writer, snapshot *LabelSnapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// ReadLabelSnapshot decodes a JSON encoded snapshot from r
+func ReadLabelSnapshot(r io.Reader) (*LabelSnapshot, error) {
+	snapshot := &LabelSnapshot{}
+	if err := json.NewDecoder(r).Decode(snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Version != LabelSnapshotVersion {
+		return nil, fmt.Errorf("unsupported label snapshot version %d", snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+// RestoreLabels re-applies the labels in the snapshot to the running containers with the same names. Containers
+// whose watchtower labels already match the snapshot are left alone, while the others are recreated using the
+// snapshot labels. If dryRun is set, the containers that would be recreated are returned without touching them.
+func RestoreLabels(client container.Client, snapshot *LabelSnapshot, timeout time.Duration, dryRun bool) ([]string, error) {
+	var restored []string
+	var failed int
+
+	for _, entry := range snapshot.Containers {
+		clog := log.WithField("container", entry.Name)
+
+		containers, err := client.ListContainers(filters.FilterByNames([]string{entry.Name}, filters.NoFilter))
+		if err != nil {
+			return restored, err
+		}
+
+		target := findContainerByName(containers, entry.Name)
+		if target == nil {
+			clog.Warn("Container from snapshot not found. Skipping")
+			continue
+		}
+
+		if labelsEqual(target.WatchtowerLabels(), entry.Labels) {
+			clog.Debug("Labels already match the snapshot")
+			continue
+		}
+
+		if target.IsWatchtower() {
+			clog.Warn("Refusing to recreate the watchtower container to restore its labels. Skipping")
+			continue
+		}
+
+		if dryRun {
+			clog.Info("Labels differ from the snapshot, container would be recreated")
+			restored = append(restored, entry.Name)
+			continue
+		}
+
+		if err := target.VerifyConfiguration(); err != nil {
+			clog.WithError(err).Error("Unable to recreate container")
+			failed++
+			continue
+		}
+
+		target.ReplaceWatchtowerLabels(entry.Labels)
+
+		clog.Info("Recreating container to restore labels")
+		if err := client.StopContainer(*target, timeout); err != nil {
+			clog.WithError(err).Error("Failed to stop container")
+			failed++
+			continue
+		}
+		if _, err := client.StartContainer(*target); err != nil {
+			clog.WithError(err).Error("Failed to start container")
+			failed++
+			continue
+		}
+		restored = append(restored, entry.Name)
+	}
+
+	if failed > 0 {
+		return restored, fmt.Errorf("%d errors while restoring container labels", failed)
+	}
+
+	return restored, nil
+}
+
+func findContainerByName(containers []container.Container, name string) *container.Container {
+	for i, c := range containers {
+		if c.Name() == name || c.Name() == "/"+name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+func labelsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, val := range a {
+		if other, found := b[key]; !found || other != val {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package actions_test
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/actions"
+	"github.com/containrrr/watchtower/pkg/container"
+	dockerContainer "github.com/docker/docker/api/types/container"
+
+	. "github.com/containrrr/watchtower/internal/actions/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func getLabeledTestData() *TestData {
+	return &TestData{
+		Containers: []container.Container{
+			CreateMockContainerWithConfig(
+				"test-container-01",
+				"/test-container-01",
+				"fake-image:latest",
+				true,
+				false,
+				time.Now(),
+				&dockerContainer.Config{
+					Labels: map[string]string{
+						"com.centurylinklabs.watchtower.enable":       "true",
+						"com.centurylinklabs.watchtower.monitor-only": "true",
+						"org.opencontainers.image.title":              "unrelated",
+					},
+				}),
+			CreateMockContainer(
+				"test-container-02",
+				"/test-container-02",
+				"fake-image:latest",
+				time.Now()),
+		},
+	}
+}
+
+var _ = Describe("the label snapshot actions", func() {
+	When("taking a snapshot", func() {
+		It("should only include containers and labels used by watchtower", func() {
+			client := CreateMockClient(getLabeledTestData(), false, false)
+			snapshot, err := actions.SnapshotLabels(client, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshot.Version).To(Equal(actions.LabelSnapshotVersion))
+			Expect(snapshot.Containers).To(HaveLen(1))
+			Expect(snapshot.Containers[0].Name).To(Equal("/test-container-01"))
+			Expect(snapshot.Containers[0].Labels).To(Equal(map[string]string{
+				"com.centurylinklabs.watchtower.enable":       "true",
+				"com.centurylinklabs.watchtower.monitor-only": "true",
+			}))
+		})
+		It("should be possible to read back the written snapshot", func() {
+			client := CreateMockClient(getLabeledTestData(), false, false)
+			snapshot, err := actions.SnapshotLabels(client, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			buffer := &bytes.Buffer{}
+			Expect(actions.WriteLabelSnapshot(buffer, snapshot)).To(Succeed())
+			read, err := actions.ReadLabelSnapshot(buffer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(read.Containers).To(Equal(snapshot.Containers))
+		})
+		It("should refuse to read unknown snapshot versions", func() {
+			_, err := actions.ReadLabelSnapshot(bytes.NewBufferString(`{"version": 99}`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("restoring a snapshot", func() {
+		var snapshot *actions.LabelSnapshot
+		BeforeEach(func() {
+			snapshot = &actions.LabelSnapshot{
+				Version: actions.LabelSnapshotVersion,
+				Containers: []actions.ContainerLabelSnapshot{
+					{
+						Name:   "test-container-01",
+						Labels: map[string]string{"com.centurylinklabs.watchtower.enable": "true"},
+					},
+					{
+						Name:   "test-container-02",
+						Labels: map[string]string{"com.centurylinklabs.watchtower.enable": "false"},
+					},
+					{
+						Name:   "missing-container",
+						Labels: map[string]string{"com.centurylinklabs.watchtower.enable": "false"},
+					},
+				},
+			}
+		})
+		It("should only report changed containers in dry run mode without changing them", func() {
+			testData := getLabeledTestData()
+			client := CreateMockClient(testData, false, false)
+			restored, err := actions.RestoreLabels(client, snapshot, time.Second, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored).To(ConsistOf("test-container-01", "test-container-02"))
+			Expect(testData.Containers[1].WatchtowerLabels()).To(BeEmpty())
+		})
+		It("should replace the watchtower labels and keep the others", func() {
+			testData := getLabeledTestData()
+			client := CreateMockClient(testData, false, false)
+			restored, err := actions.RestoreLabels(client, snapshot, time.Second, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored).To(ConsistOf("test-container-01", "test-container-02"))
+			labels := testData.Containers[0].ContainerInfo().Config.Labels
+			Expect(labels).To(Equal(map[string]string{
+				"com.centurylinklabs.watchtower.enable": "true",
+				"org.opencontainers.image.title":        "unrelated",
+			}))
+		})
+	})
+})
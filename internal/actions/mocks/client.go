@@ -3,11 +3,14 @@ package mocks
 import (
 	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/containrrr/watchtower/pkg/container"
 
 	t "github.com/containrrr/watchtower/pkg/types"
+	"github.com/docker/docker/api/types"
 )
 
 // MockClient is a mock that passes as a watchtower Client
@@ -23,6 +26,36 @@ type TestData struct {
 	NameOfContainerToKeep   string
 	Containers              []container.Container
 	Staleness               map[string]bool
+	UnusedImages            map[string][]t.ImageSummary
+	Images                  map[t.ImageID]*types.ImageInspect
+	PulledBytes             map[string]int64
+	// NewestImages maps the names of the stale containers to the IDs of their newest images, if set
+	NewestImages map[string]t.ImageID
+	// CheckFailures is how many times checking each of the containers, by name, fails before succeeding
+	CheckFailures map[string]int
+	// CheckErrors are the errors the failed checks of the containers, by name, return, if set
+	CheckErrors map[string]error
+	// StartFailures is how many times recreating each of the containers, by name, fails before succeeding
+	StartFailures map[string]int
+	// StartEvents streams the IDs of the containers WatchContainerStarts reports as started, if set
+	StartEvents chan t.ContainerID
+	// ApplyFilter makes ListContainers only return the containers passing the filter, rather than all of them
+	ApplyFilter bool
+	// Logs is the output ContainerLogs returns for every container
+	Logs string
+	// BackupFailures maps the names of the containers whose volumes cannot be backed up to the error to return
+	BackupFailures map[string]error
+	// BackedUp holds the names of the containers whose volumes were backed up
+	BackedUp []string
+	// OnStop and OnStart are called with each of the containers that are stopped and recreated, if set
+	OnStop  func(c container.Container)
+	OnStart func(c container.Container)
+	// Tags maps the image names tagged using TagImage to the IDs of the images
+	Tags map[string]t.ImageID
+	// Commands holds the commands run using ExecuteCommand, in order
+	Commands []string
+	// VolumesRemovedOf holds the names of the containers whose anonymous volumes were removed
+	VolumesRemovedOf []string
 }
 
 // TriedToRemoveImage is a test helper function to check whether RemoveImageByID has been called
@@ -40,8 +73,17 @@ func CreateMockClient(data *TestData, pullImages bool, removeVolumes bool) MockC
 }
 
 // ListContainers is a mock method returning the provided container testdata
-func (client MockClient) ListContainers(_ t.Filter) ([]container.Container, error) {
-	return client.TestData.Containers, nil
+func (client MockClient) ListContainers(filter t.Filter) ([]container.Container, error) {
+	if !client.TestData.ApplyFilter {
+		return client.TestData.Containers, nil
+	}
+	var containers []container.Container
+	for _, c := range client.TestData.Containers {
+		if filter(c) {
+			containers = append(containers, c)
+		}
+	}
+	return containers, nil
 }
 
 // StopContainer is a mock method
@@ -49,12 +91,23 @@ func (client MockClient) StopContainer(c container.Container, _ time.Duration) e
 	if c.Name() == client.TestData.NameOfContainerToKeep {
 		return errors.New("tried to stop the instance we want to keep")
 	}
+	if client.TestData.OnStop != nil {
+		client.TestData.OnStop(c)
+	}
 	return nil
 }
 
-// StartContainer is a mock method
-func (client MockClient) StartContainer(_ container.Container) (t.ContainerID, error) {
-	return "", nil
+// StartContainer is a mock method, failing as many times as provided in the TestData, returning an ID made up of the
+// name of the container
+func (client MockClient) StartContainer(c container.Container) (t.ContainerID, error) {
+	if client.TestData.StartFailures[c.Name()] > 0 {
+		client.TestData.StartFailures[c.Name()]--
+		return "", errors.New("failed to start the container")
+	}
+	if client.TestData.OnStart != nil {
+		client.TestData.OnStart(c)
+	}
+	return t.ContainerID("recreated-" + strings.TrimPrefix(c.Name(), "/")), nil
 }
 
 // RenameContainer is a mock method
@@ -68,13 +121,48 @@ func (client MockClient) RemoveImageByID(_ t.ImageID) error {
 	return nil
 }
 
+// TagImage records the tag in the TestData
+func (client MockClient) TagImage(id t.ImageID, imageName string) error {
+	if client.TestData.Tags == nil {
+		client.TestData.Tags = map[string]t.ImageID{}
+	}
+	client.TestData.Tags[imageName] = id
+	return nil
+}
+
+// ListUnusedImages returns the images of the repository provided in the TestData
+func (client MockClient) ListUnusedImages(repository string) ([]t.ImageSummary, error) {
+	return client.TestData.UnusedImages[repository], nil
+}
+
+// GetImageInfo returns the image provided in the TestData
+func (client MockClient) GetImageInfo(id t.ImageID) (*types.ImageInspect, error) {
+	if image, found := client.TestData.Images[id]; found {
+		return image, nil
+	}
+	return nil, fmt.Errorf("image %s not found", id)
+}
+
+// PulledBytes returns the number of bytes provided in the TestData for the image, and removes them like the client does
+func (client MockClient) PulledBytes(imageName string) int64 {
+	bytes := client.TestData.PulledBytes[imageName]
+	delete(client.TestData.PulledBytes, imageName)
+	return bytes
+}
+
 // GetContainer is a mock method
 func (client MockClient) GetContainer(_ t.ContainerID) (container.Container, error) {
 	return client.TestData.Containers[0], nil
 }
 
+// RecoverImageInfo is a mock method returning the container as it is
+func (client MockClient) RecoverImageInfo(c container.Container) container.Container {
+	return c
+}
+
 // ExecuteCommand is a mock method
 func (client MockClient) ExecuteCommand(_ t.ContainerID, command string, _ int) (SkipUpdate bool, err error) {
+	client.TestData.Commands = append(client.TestData.Commands, command)
 	switch command {
 	case "/PreUpdateReturn0.sh":
 		return false, nil
@@ -89,14 +177,75 @@ func (client MockClient) ExecuteCommand(_ t.ContainerID, command string, _ int)
 
 // IsContainerStale is true if not explicitly stated in TestData for the mock client
 func (client MockClient) IsContainerStale(cont container.Container) (bool, t.ImageID, error) {
+	if client.TestData.CheckFailures[cont.Name()] > 0 {
+		client.TestData.CheckFailures[cont.Name()]--
+		if err, found := client.TestData.CheckErrors[cont.Name()]; found {
+			return false, "", err
+		}
+		return false, "", errors.New("registry unavailable")
+	}
 	stale, found := client.TestData.Staleness[cont.Name()]
 	if !found {
 		stale = true
 	}
-	return stale, "", nil
+	if !stale && cont.HasImageInfo() {
+		// Like the docker client, the current image is the newest one if the container is not stale
+		return false, cont.ImageID(), nil
+	}
+	return stale, client.TestData.NewestImages[cont.Name()], nil
 }
 
 // WarnOnHeadPullFailed is always true for the mock client
 func (client MockClient) WarnOnHeadPullFailed(_ container.Container) bool {
 	return true
 }
+
+// WatchContainerStarts is a mock method streaming the start events of the testdata
+func (client MockClient) WatchContainerStarts(done <-chan struct{}) (<-chan t.ContainerID, <-chan error) {
+	ids := make(chan t.ContainerID)
+	go func() {
+		defer close(ids)
+		for {
+			select {
+			case id := <-client.TestData.StartEvents:
+				select {
+				case ids <- id:
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return ids, make(chan error)
+}
+
+// ProbeCapabilities is a mock method reporting every capability as available
+func (client MockClient) ProbeCapabilities() container.Capabilities {
+	return container.Capabilities{Exec: true, Networks: true, ImageRemoval: true, APIVersion: "1.41"}
+}
+
+// ContainerLogs is a mock method writing the logs provided in the TestData, limited to the given number of lines
+func (client MockClient) ContainerLogs(_ t.ContainerID, tail int, w io.Writer) error {
+	lines := strings.Split(strings.TrimSuffix(client.TestData.Logs, "\n"), "\n")
+	if tail > 0 && len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// BackupVolumes is a mock method recording the backed up containers, failing for those in BackupFailures
+func (client MockClient) BackupVolumes(c container.Container, _ time.Duration) error {
+	if err, found := client.TestData.BackupFailures[c.Name()]; found {
+		return err
+	}
+	client.TestData.BackedUp = append(client.TestData.BackedUp, c.Name())
+	return nil
+}
+
+// RemoveAnonymousVolumes is a mock method recording the containers whose volumes were removed
+func (client MockClient) RemoveAnonymousVolumes(c container.Container) {
+	client.TestData.VolumesRemovedOf = append(client.TestData.VolumesRemovedOf, c.Name())
+}
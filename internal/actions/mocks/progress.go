@@ -29,6 +29,9 @@ func CreateMockProgressReport(states ...session.State) wt.Report {
 			c, newImage := CreateContainerForProgress(index, 11, "updt%d")
 			progress.AddScanned(c, newImage)
 			progress.MarkForUpdate(c.ID())
+		case session.StaleState:
+			c, newImage := CreateContainerForProgress(index, 51, "stal%d")
+			progress.AddScanned(c, newImage)
 		case session.FailedState:
 			c, newImage := CreateContainerForProgress(index, 21, "fail%d")
 			progress.AddScanned(c, newImage)
@@ -0,0 +1,84 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// pendingUpdatesKey is the state store key of the containers that have a new image, which have not been updated yet
+const pendingUpdatesKey = "pending-updates"
+
+// pendingUpdate is a container that was found to have a new image, queued to be updated by the next update session
+type pendingUpdate struct {
+	LatestImage types.ImageID `json:"latestImage"`
+	QueuedAt    time.Time     `json:"queuedAt"`
+}
+
+func loadPendingUpdates(state types.StateStore) map[string]pendingUpdate {
+	pending := map[string]pendingUpdate{}
+	if _, err := state.Get(pendingUpdatesKey, &pending); err != nil {
+		log.WithError(err).Warn("Could not load the pending updates, starting over")
+		return map[string]pendingUpdate{}
+	}
+	return pending
+}
+
+func savePendingUpdates(state types.StateStore, pending map[string]pendingUpdate) {
+	var value interface{} = pending
+	if len(pending) == 0 {
+		value = nil
+	}
+	if err := state.Set(pendingUpdatesKey, value); err != nil {
+		log.WithError(err).Error("Could not save the pending updates")
+	}
+}
+
+// tracksPendingUpdates returns whether the pending updates are queued by the check sessions
+func tracksPendingUpdates(params types.UpdateParams) bool {
+	return params.PendingUpdates && params.State != nil
+}
+
+// pendingFilter only lets the containers with a pending update through the base filter
+func pendingFilter(pending map[string]pendingUpdate, baseFilter types.Filter) types.Filter {
+	return func(c types.FilterableContainer) bool {
+		if _, found := pending[c.Name()]; !found {
+			return false
+		}
+		return baseFilter == nil || baseFilter(c)
+	}
+}
+
+// trackPendingUpdates queues the checked containers that have a new image, and removes the ones that have been
+// updated or turned out to be up to date. The containers that could not be checked keep their previous state.
+func trackPendingUpdates(containers []container.Container, progress *session.Progress, pending map[string]pendingUpdate, params types.UpdateParams) {
+	now := time.Now()
+	for _, c := range containers {
+		status := (*progress)[c.ID()]
+		// the containers that were not due to be checked are left as they were, as they are reported as up to date
+		if status == nil || status.SkipReason() == session.SkipCheckNotDue {
+			continue
+		}
+
+		name := c.Name()
+		switch status.State() {
+		case "Skipped", "Retrying", "Quarantined":
+			continue
+		case "Updated":
+			delete(pending, name)
+			continue
+		}
+		if status.LatestImageID() == status.CurrentImageID() {
+			delete(pending, name)
+			continue
+		}
+		if queued, found := pending[name]; !found || queued.LatestImage != status.LatestImageID() {
+			log.WithField("container", name).Debug("Queueing the update to the new image")
+			pending[name] = pendingUpdate{LatestImage: status.LatestImageID(), QueuedAt: now}
+		}
+	}
+	savePendingUpdates(params.State, pending)
+}
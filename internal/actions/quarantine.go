@@ -0,0 +1,99 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// fingerprint identifies the configuration of the container that the user controls, being its image name and labels.
+// Changing either of them releases the container from quarantine.
+func fingerprint(c container.Container) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.ImageName()))
+
+	var labels map[string]string
+	if info := c.ContainerInfo(); info != nil && info.Config != nil {
+		labels = info.Config.Labels
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(hash, "\x00%s=%s", key, labels[key])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// checkQuarantine returns whether the container is quarantined and should be skipped. Containers whose image name or
+// labels changed since they were quarantined are released, and tracked as if they never failed.
+func checkQuarantine(c container.Container, failures map[string]updateFailure) (bool, error) {
+	failure, found := failures[c.Name()]
+	if !found {
+		return false, nil
+	}
+	if failure.QuarantinedAt == nil {
+		log.WithField("container", c.Name()).Infof("Retrying after failing during the previous %d session(s): %s",
+			failure.Sessions, failure.LastError)
+		return false, nil
+	}
+	if failure.Fingerprint != fingerprint(c) {
+		log.WithField("container", c.Name()).Info("Releasing the container from quarantine, as its configuration changed")
+		delete(failures, c.Name())
+		return false, nil
+	}
+	return true, fmt.Errorf("quarantined after failing during %d consecutive sessions: %s", failure.Sessions, failure.LastError)
+}
+
+// ListQuarantined returns the containers that are currently quarantined
+func ListQuarantined(state types.StateStore) []types.QuarantinedContainer {
+	quarantined := []types.QuarantinedContainer{}
+	for name, failure := range loadUpdateFailures(state) {
+		if failure.QuarantinedAt == nil {
+			continue
+		}
+		quarantined = append(quarantined, types.QuarantinedContainer{
+			Name:          strings.TrimPrefix(name, "/"),
+			Sessions:      failure.Sessions,
+			LastError:     failure.LastError,
+			QuarantinedAt: *failure.QuarantinedAt,
+		})
+	}
+	sort.Slice(quarantined, func(i, j int) bool {
+		return quarantined[i].Name < quarantined[j].Name
+	})
+	return quarantined
+}
+
+// ReleaseQuarantined releases the named containers from quarantine, returning the names of the ones that were
+// quarantined. The containers are tracked as if they never failed.
+func ReleaseQuarantined(state types.StateStore, names []string) []string {
+	release := make(map[string]bool, len(names))
+	for _, name := range names {
+		release[strings.TrimPrefix(name, "/")] = true
+	}
+
+	failures := loadUpdateFailures(state)
+	released := []string{}
+	for name, failure := range failures {
+		if failure.QuarantinedAt == nil || !release[strings.TrimPrefix(name, "/")] {
+			continue
+		}
+		log.WithField("container", name).Info("Releasing the container from quarantine")
+		delete(failures, name)
+		released = append(released, strings.TrimPrefix(name, "/"))
+	}
+	sort.Strings(released)
+	if len(released) > 0 {
+		saveUpdateFailures(state, failures)
+	}
+	return released
+}
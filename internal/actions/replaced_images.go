@@ -0,0 +1,47 @@
+package actions
+
+import (
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// replacedImagesKey is the state store key of the previous images of the updated containers, which the image
+// retention policy applies to
+const replacedImagesKey = "replaced-images"
+
+func loadReplacedImages(state types.StateStore) map[types.ImageID]string {
+	replaced := map[types.ImageID]string{}
+	if state == nil {
+		return replaced
+	}
+	if _, err := state.Get(replacedImagesKey, &replaced); err != nil {
+		log.WithError(err).Warn("Could not load the replaced images, starting over")
+		return map[types.ImageID]string{}
+	}
+	return replaced
+}
+
+func saveReplacedImages(state types.StateStore, replaced map[types.ImageID]string) {
+	if state == nil {
+		return
+	}
+	var value interface{} = replaced
+	if len(replaced) == 0 {
+		value = nil
+	}
+	if err := state.Set(replacedImagesKey, value); err != nil {
+		log.WithError(err).Error("Could not save the replaced images")
+	}
+}
+
+// stagedImages returns the pulled images of the staged updates, which are not in use yet but must be kept
+func stagedImages(state types.StateStore) map[types.ImageID]bool {
+	images := map[types.ImageID]bool{}
+	if state == nil {
+		return images
+	}
+	for _, staged := range loadStagedUpdates(state) {
+		images[staged.LatestImage] = true
+	}
+	return images
+}
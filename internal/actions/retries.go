@@ -0,0 +1,122 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// updateFailuresKey is the state store key of the containers that failed to update during the previous sessions
+const updateFailuresKey = "update-failures"
+
+// updateFailure is a container that failed to be checked or updated during one or more consecutive sessions
+type updateFailure struct {
+	Sessions  int    `json:"sessions"`
+	LastError string `json:"lastError"`
+	// QuarantinedAt is when the container was quarantined after failing during too many sessions, if it was
+	QuarantinedAt *time.Time `json:"quarantinedAt,omitempty"`
+	// Fingerprint identifies the configuration of the container at the time it was quarantined
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// maxRetryDelay limits how long the delay between the retries grows by doubling, unless the backoff is longer already
+const maxRetryDelay = 5 * time.Minute
+
+// tracksFailures returns whether the failures need to be carried over into the next sessions
+func tracksFailures(params types.UpdateParams) bool {
+	return params.State != nil && (params.RetrySessions > 0 || params.QuarantineAfter > 0)
+}
+
+// withRetries calls attempt until it succeeds or the configured number of retries is exhausted, doubling the delay
+// between the attempts each time, up to maxRetryDelay. Shutting down watchtower stops retrying, even while waiting, and
+// running out of disk space is not retried, as waiting does not free any.
+func withRetries(params types.UpdateParams, action string, attempt func() error) error {
+	delay := params.RetryBackoff
+	for retry := 1; ; retry++ {
+		err := attempt()
+		if err == nil || retry > params.Retries || session.SkipReasonOf(err) == session.SkipInsufficientDiskSpace {
+			return err
+		}
+
+		log.WithError(err).Infof("Failed %s, retrying in %s (%d/%d)", action, delay, retry, params.Retries)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-params.Shutdown:
+			timer.Stop()
+			return err
+		}
+		if delay < maxRetryDelay/2 {
+			delay *= 2
+		} else if delay < maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+func loadUpdateFailures(state types.StateStore) map[string]updateFailure {
+	failures := map[string]updateFailure{}
+	if _, err := state.Get(updateFailuresKey, &failures); err != nil {
+		log.WithError(err).Warn("Could not load the update failures of the previous sessions, starting over")
+		return map[string]updateFailure{}
+	}
+	return failures
+}
+
+func saveUpdateFailures(state types.StateStore, failures map[string]updateFailure) {
+	var value interface{} = failures
+	if len(failures) == 0 {
+		value = nil
+	}
+	if err := state.Set(updateFailuresKey, value); err != nil {
+		log.WithError(err).Error("Could not save the update failures")
+	}
+}
+
+// trackFailures carries the failed containers of the session over into the next one, reporting them as retrying until
+// they have failed during more consecutive sessions than configured, and quarantining them once they have failed
+// during as many sessions as the quarantine threshold. Only the containers that failed to update, or to be checked,
+// count as failed. Containers that succeed are forgotten, while the ones that were not part of the session, or were
+// skipped, are kept as they were.
+func trackFailures(containers []container.Container, progress *session.Progress, failures map[string]updateFailure, params types.UpdateParams) {
+	for _, c := range containers {
+		status := (*progress)[c.ID()]
+		// the containers that were not due to be checked, or whose failed check is within the error budget, are left as
+		// they were, as they are reported as up to date
+		if status == nil || status.SkipReason() == session.SkipCheckNotDue || status.SkipReason() == session.SkipWithinErrorBudget {
+			continue
+		}
+
+		name := c.Name()
+		switch status.State() {
+		case "Quarantined":
+			continue
+		case "Skipped":
+			// only the failed checks count, while the containers skipped for other reasons, like being rolled back
+			// along with their group, are left as they were
+			if status.SkipReason() != session.SkipCheckFailed {
+				continue
+			}
+		case "Failed":
+		default:
+			delete(failures, name)
+			continue
+		}
+
+		failure := updateFailure{Sessions: failures[name].Sessions + 1, LastError: status.Error()}
+		if params.QuarantineAfter > 0 && failure.Sessions >= params.QuarantineAfter {
+			now := time.Now()
+			failure.QuarantinedAt = &now
+			failure.Fingerprint = fingerprint(c)
+			log.WithField("container", name).Warnf("Quarantining the container after failing during %d consecutive sessions",
+				failure.Sessions)
+		} else if failure.Sessions <= params.RetrySessions {
+			progress.MarkRetrying(c.ID())
+		}
+		failures[name] = failure
+	}
+	saveUpdateFailures(params.State, failures)
+}
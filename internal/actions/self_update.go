@@ -0,0 +1,122 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/util"
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// selfUpdatePollInterval is how often the state of the new watchtower instance is checked during a handoff
+var selfUpdatePollInterval = time.Second
+
+// updateSelf replaces the current watchtower container with a new instance using the updated image.
+// Since we can't shutdown a watchtower container immediately, we need to start the new one while the old one is
+// still running. This prevents us from re-using the same container name so we first rename the current instance so
+// that the new one can adopt the old name.
+// The new instance signals that it has loaded its configuration and is able to reach the docker API by asking this
+// instance to shut down. If it stops running or fails to do so in time, it is removed and the current instance
+// takes back its original name, keeping watchtower running on the old image. The ID of the new instance is returned
+// once it has taken over.
+func updateSelf(current container.Container, client container.Client, params types.UpdateParams) (types.ContainerID, error) {
+	originalName := strings.TrimPrefix(current.Name(), "/")
+
+	if err := client.RenameContainer(current, util.RandName()); err != nil {
+		log.Error(err)
+		return "", nil
+	}
+
+	newContainerID, err := client.StartContainer(current)
+	if err != nil {
+		log.Error(err)
+		if newContainerID != "" {
+			removeFailedInstance(client, newContainerID, params)
+		}
+		restoreName(client, current, originalName)
+		return "", err
+	}
+
+	if params.SelfUpdateTimeout <= 0 {
+		return newContainerID, nil
+	}
+
+	if err := awaitHandoff(client, newContainerID, params); err != nil {
+		log.WithError(err).Error("The new watchtower instance failed to take over. Aborting self-update")
+		removeFailedInstance(client, newContainerID, params)
+		restoreName(client, current, originalName)
+		return "", err
+	}
+
+	log.Info("The new watchtower instance is ready and has taken over")
+	return newContainerID, nil
+}
+
+// awaitHandoff waits until the new watchtower instance signals that it is ready, returning an error if it stops
+// running or the self-update timeout is reached first
+func awaitHandoff(client container.Client, newContainerID types.ContainerID, params types.UpdateParams) error {
+	log.WithField("timeout", params.SelfUpdateTimeout).Info("Waiting for the new watchtower instance to take over")
+
+	deadline := time.After(params.SelfUpdateTimeout)
+	ticker := time.NewTicker(selfUpdatePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-params.Shutdown:
+			return nil
+		case <-deadline:
+			return errors.New("timed out waiting for the new instance to become ready")
+		case <-ticker.C:
+			newContainer, err := client.GetContainer(newContainerID)
+			if err != nil {
+				return err
+			}
+			if err := checkNewInstanceState(newContainer, params.HealthStartPeriodMultiplier); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkNewInstanceState returns an error if the new instance has stopped running or is unhealthy. Failing health
+// checks are ignored during the (scaled) start period declared by its health check, since some instances are
+// expected to take a while to become healthy.
+func checkNewInstanceState(c container.Container, startPeriodMultiplier float64) error {
+	info := c.ContainerInfo()
+	if info.State == nil {
+		return errors.New("unable to retrieve the state of the new instance")
+	}
+	if !info.State.Running || info.State.Restarting || info.RestartCount > 0 {
+		return fmt.Errorf("the new instance is not running (status: %s, exit code: %d)", info.State.Status, info.State.ExitCode)
+	}
+	if info.State.Health != nil && info.State.Health.Status == "unhealthy" {
+		if c.InHealthStartPeriod(startPeriodMultiplier, time.Now()) {
+			log.Debug("The new instance is unhealthy, but still within its health check start period")
+			return nil
+		}
+		return errors.New("the new instance is unhealthy")
+	}
+	return nil
+}
+
+func removeFailedInstance(client container.Client, containerID types.ContainerID, params types.UpdateParams) {
+	failed, err := client.GetContainer(containerID)
+	if err != nil {
+		log.WithError(err).Error("Could not retrieve the failed watchtower instance")
+		return
+	}
+	if err := client.StopContainer(failed, params.Timeout); err != nil {
+		log.WithError(err).Error("Could not remove the failed watchtower instance")
+	}
+}
+
+func restoreName(client container.Client, current container.Container, originalName string) {
+	if err := client.RenameContainer(current, originalName); err != nil {
+		log.WithError(err).Errorf("Could not rename the current watchtower instance back to %q", originalName)
+	}
+}
@@ -0,0 +1,117 @@
+package actions
+
+import (
+	"sort"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// stagedUpdatesKey is the state store key of the containers whose new image has been pulled, but not applied yet
+const stagedUpdatesKey = "staged-updates"
+
+// stagedUpdate is a container whose new image has been pulled by a session, waiting for a session applying it
+type stagedUpdate struct {
+	LatestImage types.ImageID `json:"latestImage"`
+	StagedAt    time.Time     `json:"stagedAt"`
+}
+
+func loadStagedUpdates(state types.StateStore) map[string]stagedUpdate {
+	staged := map[string]stagedUpdate{}
+	if _, err := state.Get(stagedUpdatesKey, &staged); err != nil {
+		log.WithError(err).Warn("Could not load the staged updates, starting over")
+		return map[string]stagedUpdate{}
+	}
+	return staged
+}
+
+func saveStagedUpdates(state types.StateStore, staged map[string]stagedUpdate) {
+	var value interface{} = staged
+	if len(staged) == 0 {
+		value = nil
+	}
+	if err := state.Set(stagedUpdatesKey, value); err != nil {
+		log.WithError(err).Error("Could not save the staged updates")
+	}
+}
+
+// StagedUpdate is a container whose new image has been pulled, waiting for a session applying it
+type StagedUpdate struct {
+	Container   string
+	LatestImage types.ImageID
+	StagedAt    time.Time
+}
+
+// ListStagedUpdates returns the staged updates, sorted by the name of the container
+func ListStagedUpdates(state types.StateStore) []StagedUpdate {
+	var updates []StagedUpdate
+	for name, staged := range loadStagedUpdates(state) {
+		updates = append(updates, StagedUpdate{Container: name, LatestImage: staged.LatestImage, StagedAt: staged.StagedAt})
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].Container < updates[j].Container
+	})
+	return updates
+}
+
+// isStaging returns whether the new image of the container is only to be staged by the session, rather than applied
+func isStaging(c container.Container, params types.UpdateParams) bool {
+	return (params.StageOnly || params.RequireApproval || c.IsStageOnly()) && !params.ApplyStaged
+}
+
+// stagedFilter only lets the containers with a staged update through the base filter
+func stagedFilter(staged map[string]stagedUpdate, baseFilter types.Filter) types.Filter {
+	return func(c types.FilterableContainer) bool {
+		if _, found := staged[c.Name()]; !found {
+			return false
+		}
+		return baseFilter == nil || baseFilter(c)
+	}
+}
+
+// trackStagedUpdates records the pulled images of the staging containers that have a new image, and removes the
+// containers that have been updated or turned out to be up to date. The containers that could not be checked keep
+// their previous state.
+func trackStagedUpdates(containers []container.Container, progress *session.Progress, staged map[string]stagedUpdate, params types.UpdateParams) {
+	now := time.Now()
+	changed := false
+	for _, c := range containers {
+		status := (*progress)[c.ID()]
+		// the containers that were not due to be checked are left as they were, as they are reported as up to date
+		if status == nil || status.SkipReason() == session.SkipCheckNotDue {
+			continue
+		}
+
+		name := c.Name()
+		switch status.State() {
+		case "Skipped", "Retrying", "Quarantined":
+			continue
+		}
+		current, found := staged[name]
+		if status.State() == "Updated" || status.LatestImageID() == status.CurrentImageID() {
+			if found {
+				delete(staged, name)
+				changed = true
+			}
+			continue
+		}
+		if isStaging(c, params) && (!found || current.LatestImage != status.LatestImageID()) {
+			if params.RequireApproval && params.ApprovalLink != nil {
+				log.WithField("container", name).Infof("The update to image %s is pending approval, approve it at %s",
+					status.LatestImageID().ShortID(), params.ApprovalLink(name, status.LatestImageID()))
+			} else if params.RequireApproval {
+				log.WithField("container", name).Infof("The update to image %s is pending approval", status.LatestImageID().ShortID())
+			} else {
+				log.WithField("container", name).Infof("Staged the new image %s", status.LatestImageID().ShortID())
+			}
+			staged[name] = stagedUpdate{LatestImage: status.LatestImageID(), StagedAt: now}
+			changed = true
+		}
+	}
+	if changed {
+		saveStagedUpdates(params.State, staged)
+	}
+}
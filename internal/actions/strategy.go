@@ -0,0 +1,100 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/events"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/strategy"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// updateCandidate describes the stale container and the image it would be updated to for the update strategy
+func updateCandidate(client container.Client, c container.Container, latest types.ImageID) types.UpdateCandidate {
+	candidate := types.UpdateCandidate{
+		ID:             c.ID(),
+		Name:           c.Name(),
+		ImageName:      c.ImageName(),
+		CurrentImageID: c.SafeImageID(),
+		LatestImageID:  latest,
+		Labels:         map[string]string{},
+	}
+	if info := c.ContainerInfo(); info != nil && info.Config != nil && info.Config.Labels != nil {
+		candidate.Labels = info.Config.Labels
+	}
+	if project, found := c.ComposeProject(); found {
+		candidate.Project = project.Name
+	}
+	if info, err := client.GetImageInfo(latest); err == nil {
+		candidate.LatestImageCreated, _ = time.Parse(time.RFC3339Nano, info.Created)
+	}
+	return candidate
+}
+
+// decideByStrategy asks the update strategy whether to update the stale container, returning why it is left as it is,
+// or an empty reason to update it. Containers the strategy could not decide about are deferred to the next session.
+func decideByStrategy(client container.Client, c container.Container, latest types.ImageID, updateStrategy types.UpdateStrategy) types.SkipReason {
+	decision, err := updateStrategy.ShouldUpdate(updateCandidate(client, c, latest))
+	if err != nil {
+		log.WithField("container", c.Name()).Warnf("%v, deferring the update", err)
+		return session.SkipDeferredByStrategy
+	}
+	switch decision {
+	case strategy.Skip:
+		log.WithField("container", c.Name()).Info("Skipping the update, as decided by the update strategy")
+		return session.SkipVetoedByStrategy
+	case strategy.Defer:
+		log.WithField("container", c.Name()).Info("Deferring the update, as decided by the update strategy")
+		return session.SkipDeferredByStrategy
+	}
+	return ""
+}
+
+// performStrategyUpdates lets the update strategy update the stale containers to restart, returning the ones it left
+// to watchtower to recreate, along with the ones it failed to update. Watchtower always updates its own container, and
+// the containers only restarted due to their links.
+func performStrategyUpdates(containers []container.Container, client container.Client, params types.UpdateParams, progress *session.Progress) (others []container.Container, failed map[types.ContainerID]error) {
+	failed = make(map[types.ContainerID]error, len(containers))
+	cleanup := newImageCleanup(len(containers))
+
+	for i, c := range containers {
+		if !c.ToRestart() || !c.Stale || c.IsWatchtower() {
+			others = append(others, c)
+			continue
+		}
+		if shuttingDown(params) {
+			// Left to the others, which are deferred to the next session before being stopped
+			others = append(others, containers[i:]...)
+			break
+		}
+
+		latest := c.SafeImageID()
+		if status := (*progress)[c.ID()]; status != nil {
+			latest = status.LatestImageID()
+		}
+		updateStartedAt := time.Now()
+		handled, err := params.Strategy.PerformUpdate(updateCandidate(client, c, latest))
+		if !handled {
+			others = append(others, c)
+			continue
+		}
+		progress.AddRecreateDuration(c.ID(), time.Since(updateStartedAt))
+		if err != nil {
+			log.WithField("container", c.Name()).Error(err)
+			failed[c.ID()] = err
+			continue
+		}
+		events.Record(params.Events, types.Event{
+			Type:      events.ContainerRecreated,
+			Container: c.Name(),
+			Image:     c.ImageName(),
+			Details:   map[string]interface{}{"previousImage": c.SafeImageID(), "strategy": true},
+		})
+		cleanup.add(c, progress, params)
+	}
+
+	cleanup.run(client, params)
+	return others, failed
+}
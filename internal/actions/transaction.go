@@ -0,0 +1,114 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// groupOf returns the transactional group of the container, being the one set using the group label, or else the
+// docker compose project the container was created by. Watchtower itself does not belong to any group.
+func groupOf(c container.Container) (string, bool) {
+	if c.IsWatchtower() {
+		return "", false
+	}
+	if group, ok := c.Group(); ok {
+		return group, true
+	}
+	if project, ok := c.ComposeProject(); ok {
+		return project.Name, true
+	}
+	return "", false
+}
+
+// addFailedGroup records the group of the container as failed, unless it does not belong to one, keeping the name of
+// the first container that failed in each group
+func addFailedGroup(failedGroups map[string]string, c container.Container) {
+	group, ok := groupOf(c)
+	if _, found := failedGroups[group]; ok && !found {
+		failedGroups[group] = c.Name()
+	}
+}
+
+// holdBackIncompleteGroups leaves the stale containers of the groups in which any container could not be checked as
+// they are, so that the group does not end up running a mix of previous and new images. It returns the containers
+// that are held back.
+func holdBackIncompleteGroups(containers []container.Container, failedGroups map[string]string, progress *session.Progress) map[types.ContainerID]bool {
+	heldBack := map[types.ContainerID]bool{}
+	for _, c := range containers {
+		group, ok := groupOf(c)
+		failed, found := failedGroups[group]
+		if !ok || !found || !c.Stale {
+			continue
+		}
+		log.WithField("container", c.Name()).Infof("Not updating the container, as checking %s of group %s failed",
+			failed, group)
+		heldBack[c.ID()] = true
+		progress.SetSkipReason(c.ID(), session.SkipGroupIncomplete)
+	}
+	return heldBack
+}
+
+// rollBackFailedGroups recreates the stale containers of the groups in which any container failed to update using
+// their previous images again, so that the group does not end up running a mix of previous and new images. The previous
+// image is tagged using the image name of the container again first, which keeps the image name of the container, and
+// lets the next session retry the update. The rolled back containers are added to the failures.
+func rollBackFailedGroups(containers []container.Container, client container.Client, params types.UpdateParams, failedStop map[types.ContainerID]error, failed map[types.ContainerID]error, recreated map[types.ContainerID]types.ContainerID) {
+	failedGroups := map[string]string{}
+	for _, c := range containers {
+		if failedStop[c.ID()] != nil || failed[c.ID()] != nil {
+			addFailedGroup(failedGroups, c)
+		}
+	}
+	if len(failedGroups) == 0 {
+		return
+	}
+
+	var rollBack []container.Container
+	for _, c := range containers {
+		group, ok := groupOf(c)
+		_, attempted := recreated[c.ID()]
+		if _, found := failedGroups[group]; ok && found && attempted && c.Stale {
+			rollBack = append(rollBack, c)
+		}
+	}
+
+	for i := len(rollBack) - 1; i >= 0; i-- {
+		newContainerID := recreated[rollBack[i].ID()]
+		if newContainerID == "" {
+			continue
+		}
+		updated, err := client.GetContainer(newContainerID)
+		if err == nil {
+			err = client.StopContainer(updated, params.Timeout)
+		}
+		if err != nil {
+			log.WithError(err).Warnf("Could not remove the updated container %s to roll it back", rollBack[i].Name())
+		}
+	}
+
+	for _, c := range rollBack {
+		group, _ := groupOf(c)
+		log.Infof("Rolling back %s to image %s, as %s of group %s failed to update", c.Name(), c.ImageID().ShortID(),
+			failedGroups[group], group)
+		err := client.TagImage(c.ImageID(), c.ImageName())
+		if err == nil {
+			_, err = client.StartContainer(c)
+		}
+		if err != nil {
+			log.WithError(err).Errorf("Could not roll back %s", c.Name())
+			if failed[c.ID()] != nil {
+				err = fmt.Errorf("%v, and could not roll back the container: %w", failed[c.ID()], err)
+			} else {
+				err = fmt.Errorf("could not roll back the container: %w", err)
+			}
+			failed[c.ID()] = err
+		} else if failed[c.ID()] == nil {
+			failed[c.ID()] = session.WithSkipReason(session.SkipRolledBack,
+				fmt.Errorf("rolled back, as %s of group %s failed to update", failedGroups[group], group))
+		}
+	}
+}
@@ -3,22 +3,29 @@ package actions
 import (
 	"errors"
 	"strings"
+	"time"
 
-	"github.com/containrrr/watchtower/internal/util"
 	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/events"
 	"github.com/containrrr/watchtower/pkg/lifecycle"
+	"github.com/containrrr/watchtower/pkg/registry/helpers"
 	"github.com/containrrr/watchtower/pkg/session"
 	"github.com/containrrr/watchtower/pkg/sorter"
 	"github.com/containrrr/watchtower/pkg/types"
 	log "github.com/sirupsen/logrus"
 )
 
+// errNetworksDenied is the reason for skipping the containers that could not be connected to all of their networks
+var errNetworksDenied = errors.New("the container is attached to several networks, but the docker API does not " +
+	"permit connecting it to them")
+
 // Update looks at the running Docker containers to see if any of the images
 // used to start those containers have been updated. If a change is detected in
 // any of the images, the associated containers are stopped and restarted with
 // the new image.
 func Update(client container.Client, params types.UpdateParams) (types.Report, error) {
 	log.Debug("Checking containers for updated images")
+	startedAt := time.Now()
 	progress := &session.Progress{}
 	staleCount := 0
 
@@ -26,19 +33,108 @@ func Update(client container.Client, params types.UpdateParams) (types.Report, e
 		lifecycle.ExecutePreChecks(client, params)
 	}
 
-	containers, err := client.ListContainers(params.Filter)
+	var pending map[string]pendingUpdate
+	filter := params.Filter
+	if tracksPendingUpdates(params) {
+		pending = loadPendingUpdates(params.State)
+		if !params.MonitorOnly {
+			log.Debugf("Only updating the %d containers with a pending update", len(pending))
+			filter = pendingFilter(pending, filter)
+		}
+	}
+
+	var staged map[string]stagedUpdate
+	if params.State != nil {
+		staged = loadStagedUpdates(params.State)
+	}
+	if params.ApplyStaged {
+		log.Debugf("Only updating the %d containers with a staged update", len(staged))
+		filter = stagedFilter(staged, filter)
+	}
+
+	scanStartedAt := time.Now()
+	containers, err := client.ListContainers(filter)
 	if err != nil {
 		return nil, err
 	}
 
+	failures := map[string]updateFailure{}
+	if tracksFailures(params) {
+		failures = loadUpdateFailures(params.State)
+	}
+
+	// lastChecks is when the containers with a check interval were last checked
+	var lastChecks map[string]time.Time
+	if params.State != nil {
+		lastChecks = loadLastChecks(params.State)
+	}
+
 	staleCheckFailed := 0
+	// failedGroups maps the transactional groups in which a container could not be checked to the name of the first one
+	failedGroups := map[string]string{}
+	// tooNew are the stale containers whose new image has not reached the minimum image age yet
+	tooNew := map[types.ContainerID]bool{}
+	// decided are the stale containers the update strategy left as they are, with the reason
+	decided := map[types.ContainerID]types.SkipReason{}
+	// failedChecks are the failed checks by image, and checkedImages the images checked successfully, whose error
+	// budgets are spent or restored once the containers have been checked
+	failedChecks := map[string][]failedCheck{}
+	checkedImages := map[string]bool{}
 
 	for i, targetContainer := range containers {
-		stale, newestImage, err := client.IsContainerStale(targetContainer)
-		shouldUpdate := stale && !params.NoRestart && !params.MonitorOnly && !targetContainer.IsMonitorOnly()
+		if shuttingDown(params) {
+			log.Info("Not checking the remaining containers, as watchtower is shutting down")
+			containers = containers[:i]
+			break
+		}
+		if quarantined, reason := checkQuarantine(targetContainer, failures); quarantined {
+			log.WithField("container", targetContainer.Name()).Debug("Skipping the quarantined container")
+			progress.AddQuarantined(targetContainer, reason)
+			continue
+		}
+		interval := checkInterval(targetContainer, lastChecks)
+		// the staged updates are applied regardless of the check intervals, as they were approved or asked for
+		if since, notDue := checkNotDue(targetContainer, interval, lastChecks, time.Now()); notDue && !params.ApplyStaged {
+			log.WithField("container", targetContainer.Name()).Debugf(
+				"Not checking the container, as it was checked %s ago", since.Round(time.Second))
+			progress.AddScanned(targetContainer, targetContainer.SafeImageID())
+			progress.SetSkipReason(targetContainer.ID(), session.SkipCheckNotDue)
+			continue
+		}
+
+		// the containers whose image is missing are skipped until the image is restored, which is not a failed check
+		if !targetContainer.HasImageInfo() {
+			targetContainer = client.RecoverImageInfo(targetContainer)
+			containers[i] = targetContainer
+		}
+		if !targetContainer.HasImageInfo() {
+			err := targetContainer.ImageMissingError()
+			log.Infof("Unable to update container %q: %v. Proceeding to next.", targetContainer.Name(), err)
+			progress.AddSkipped(targetContainer, err)
+			if params.TransactionalGroups {
+				addFailedGroup(failedGroups, targetContainer)
+			}
+			continue
+		}
+
+		imageName := targetContainer.ImageName()
+		var stale bool
+		var newestImage types.ImageID
+		checkStartedAt := time.Now()
+		err := withRetries(params, "checking "+targetContainer.Name(), func() (err error) {
+			stale, newestImage, err = client.IsContainerStale(targetContainer)
+			return err
+		})
+		checkDuration := time.Since(checkStartedAt)
+		checkFailed := err != nil
+		if !checkFailed && lastChecks != nil && interval > 0 {
+			lastChecks[targetContainer.Name()] = time.Now()
+		}
+		shouldUpdate := stale && !params.NoRestart && !params.MonitorOnly && !targetContainer.IsMonitorOnly() &&
+			!isStaging(targetContainer, params)
 		if err == nil && shouldUpdate {
 			// Check to make sure we have all the necessary information for recreating the container
-			err = targetContainer.VerifyConfiguration()
+			err = session.WithSkipReason(session.SkipInvalidConfig, targetContainer.VerifyConfiguration())
 			// If the image information is incomplete and trace logging is enabled, log it for further diagnosis
 			if err != nil && log.IsLevelEnabled(log.TraceLevel) {
 				imageInfo := targetContainer.ImageInfo()
@@ -48,86 +144,268 @@ func Update(client container.Client, params types.UpdateParams) (types.Report, e
 					log.Tracef("Image config: %#v", imageInfo.Config)
 				}
 			}
+			if err == nil && params.NetworksDenied && targetContainer.HasAdditionalNetworks() {
+				err = session.WithSkipReason(session.SkipNotPermitted, errNetworksDenied)
+			}
+		}
+
+		if params.ErrorBudget != nil && !checkFailed {
+			checkedImages[imageName] = true
 		}
 
 		if err != nil {
-			log.Infof("Unable to update container %q: %v. Proceeding to next.", targetContainer.Name(), err)
 			stale = false
 			staleCheckFailed++
-			progress.AddSkipped(targetContainer, err)
+			if params.ErrorBudget != nil && checkFailed && session.SkipReasonOf(err) != session.SkipInsufficientDiskSpace {
+				// the failed checks are only escalated once the budget of the image has been spent after the loop,
+				// unlike running out of disk space, which is not a problem of the image
+				failedChecks[imageName] = append(failedChecks[imageName], failedCheck{container: targetContainer, err: err})
+			} else {
+				log.Infof("Unable to update container %q: %v. Proceeding to next.", targetContainer.Name(), err)
+				progress.AddSkipped(targetContainer, err)
+			}
+			if params.TransactionalGroups {
+				addFailedGroup(failedGroups, targetContainer)
+			}
 		} else {
 			progress.AddScanned(targetContainer, newestImage)
+			if stale {
+				describeLatestImage(client, progress, targetContainer, imageName, newestImage, params.Releases)
+			}
+			if shouldUpdate {
+				if remaining := imageAgeRemaining(client, targetContainer, newestImage, params, time.Now()); remaining > 0 {
+					log.WithField("container", targetContainer.Name()).Infof(
+						"Deferring the update, as the new image will only be old enough in %s", remaining.Round(time.Second))
+					tooNew[targetContainer.ID()] = true
+				} else if params.Strategy != nil {
+					if reason := decideByStrategy(client, targetContainer, newestImage, params.Strategy); reason != "" {
+						decided[targetContainer.ID()] = reason
+					}
+				}
+			}
+			if reason := leftAsItIs(targetContainer, stale, params); reason != "" {
+				progress.SetSkipReason(targetContainer.ID(), reason)
+			} else if tooNew[targetContainer.ID()] {
+				progress.SetSkipReason(targetContainer.ID(), session.SkipImageTooNew)
+			} else if reason := decided[targetContainer.ID()]; reason != "" {
+				progress.SetSkipReason(targetContainer.ID(), reason)
+			}
+		}
+		pulledBytes := client.PulledBytes(targetContainer.ImageName())
+		progress.AddPulledBytes(targetContainer.ID(), pulledBytes)
+		if pulledBytes > 0 {
+			progress.SetPullDuration(targetContainer.ID(), checkDuration)
 		}
 		containers[i].Stale = stale
+		recordCheck(params, targetContainer, newestImage, stale, pulledBytes, err)
 
 		if stale {
 			staleCount++
 		}
 	}
 
+	if params.ErrorBudget != nil {
+		spendErrorBudgets(params.ErrorBudget, failedChecks, checkedImages, progress)
+	}
+	scanDuration := time.Since(scanStartedAt)
+
 	containers, err = sorter.SortByDependencies(containers)
 	if err != nil {
 		return nil, err
 	}
 
+	var heldBack map[types.ContainerID]bool
+	if params.TransactionalGroups {
+		heldBack = holdBackIncompleteGroups(containers, failedGroups, progress)
+	}
 	UpdateImplicitRestart(containers)
 
 	var containersToUpdate []container.Container
 	if !params.MonitorOnly {
 		for _, c := range containers {
-			if !c.IsMonitorOnly() {
+			if !c.IsMonitorOnly() && !isStaging(c, params) && !tooNew[c.ID()] && decided[c.ID()] == "" &&
+				!heldBack[c.ID()] {
 				containersToUpdate = append(containersToUpdate, c)
 				progress.MarkForUpdate(c.ID())
 			}
 		}
 	}
 
+	if params.Strategy != nil {
+		var failedByStrategy map[types.ContainerID]error
+		containersToUpdate, failedByStrategy = performStrategyUpdates(containersToUpdate, client, params, progress)
+		progress.UpdateFailed(failedByStrategy)
+	}
+
+	if params.Compose != nil {
+		var composed []container.Container
+		containersToUpdate, composed = splitComposed(containersToUpdate)
+		progress.UpdateFailed(redeployComposeProjects(composed, client, params, progress))
+	}
+
 	if params.RollingRestart {
-		progress.UpdateFailed(performRollingRestart(containersToUpdate, client, params))
+		progress.UpdateFailed(performRollingRestart(containersToUpdate, client, params, progress))
 	} else {
-		failedStop, stoppedImages := stopContainersInReversedOrder(containersToUpdate, client, params)
+		failedStop, stoppedImages := stopContainersInReversedOrder(containersToUpdate, client, params, progress)
 		progress.UpdateFailed(failedStop)
-		failedStart := restartContainersInSortedOrder(containersToUpdate, client, params, stoppedImages)
+		failedStart := restartContainersInSortedOrder(containersToUpdate, client, params, progress, stoppedImages, failedStop)
 		progress.UpdateFailed(failedStart)
 	}
 
+	if lastChecks != nil {
+		saveLastChecks(params.State, lastChecks)
+	}
+	if params.State != nil && !params.CleanupScheduled {
+		removeDeferredImages(client, params.State, time.Now())
+	}
+	if tracksPendingUpdates(params) {
+		trackPendingUpdates(containers, progress, pending, params)
+	}
+	if params.State != nil {
+		trackStagedUpdates(containers, progress, staged, params)
+	}
+	if tracksFailures(params) {
+		trackFailures(containers, progress, failures, params)
+	}
+
 	if params.LifecycleHooks {
 		lifecycle.ExecutePostChecks(client, params)
 	}
-	return progress.Report(), nil
+	report := progress.TimedReport(time.Since(startedAt), scanDuration)
+	recordOutcomes(params, report)
+	return report, nil
+}
+
+// recordCheck records the check of the container, and the pull of its image if anything was downloaded
+func recordCheck(params types.UpdateParams, c container.Container, latest types.ImageID, stale bool, pulledBytes int64, err error) {
+	if params.Events == nil {
+		return
+	}
+	if pulledBytes > 0 {
+		events.Record(params.Events, types.Event{
+			Type:      events.ImagePulled,
+			Container: c.Name(),
+			Image:     c.ImageName(),
+			Details:   map[string]interface{}{"bytes": pulledBytes},
+		})
+	}
+	event := types.Event{
+		Type:      events.ContainerChecked,
+		Container: c.Name(),
+		Image:     c.ImageName(),
+		Details: map[string]interface{}{
+			"currentImage": c.SafeImageID(),
+			"latestImage":  latest,
+			"stale":        stale,
+		},
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	events.Record(params.Events, event)
+}
+
+// recordOutcomes records the containers that failed to update or were skipped by the session
+func recordOutcomes(params types.UpdateParams, report types.Report) {
+	if params.Events == nil {
+		return
+	}
+	for _, c := range report.Failed() {
+		events.Record(params.Events, types.Event{Type: events.ContainerFailed, Container: c.Name(), Image: c.ImageName(), Error: c.Error()})
+	}
+	for _, c := range report.Skipped() {
+		events.Record(params.Events, types.Event{
+			Type:      events.ContainerSkipped,
+			Container: c.Name(),
+			Image:     c.ImageName(),
+			Error:     c.Error(),
+			Details:   map[string]interface{}{"reason": c.SkipReason()},
+		})
+	}
 }
 
-func performRollingRestart(containers []container.Container, client container.Client, params types.UpdateParams) map[types.ContainerID]error {
-	cleanupImageIDs := make(map[types.ImageID]bool, len(containers))
+// leftAsItIs returns why the checked container is left as it is by the session, if it is for any other reason than
+// being up to date
+func leftAsItIs(c container.Container, stale bool, params types.UpdateParams) types.SkipReason {
+	if !stale {
+		if c.IsNoPull(false) {
+			return session.SkipNoPull
+		}
+		return ""
+	}
+	switch {
+	case params.MonitorOnly || c.IsMonitorOnly():
+		return session.SkipMonitorOnly
+	case isStaging(c, params) && params.RequireApproval:
+		return session.SkipAwaitingApproval
+	case isStaging(c, params):
+		return session.SkipStageOnly
+	}
+	return ""
+}
+
+// describeLatestImage adds the configuration changes of the latest image of a stale container to the report, along
+// with the references to the previous and latest image, and its release information if a resolver has been supplied.
+// The previous image name differs from the current one of the container if it is switched to another tag.
+func describeLatestImage(client container.Client, progress *session.Progress, c container.Container, previousImageName string, imageID types.ImageID, releases types.ReleaseResolver) {
+	latest, err := client.GetImageInfo(imageID)
+	if err != nil {
+		log.WithError(err).Debugf("Could not inspect image %s of container %s", imageID.ShortID(), c.Name())
+		return
+	}
+	currentRef := previousImageName
+	if c.HasImageInfo() {
+		progress.SetImageChanges(c.ID(), container.ImageConfigChanges(c.ImageInfo().Config, latest.Config))
+		currentRef = container.ImageReference(previousImageName, c.ImageInfo().RepoDigests)
+	}
+	progress.SetReferences(c.ID(), currentRef, container.ImageReference(c.ImageName(), latest.RepoDigests),
+		helpers.BrowseURL(c.ImageName()))
+	if releases != nil {
+		labels := map[string]string{}
+		if latest.Config != nil {
+			labels = latest.Config.Labels
+		}
+		progress.SetRelease(c.ID(), releases.Resolve(labels))
+	}
+}
+
+func performRollingRestart(containers []container.Container, client container.Client, params types.UpdateParams, progress *session.Progress) map[types.ContainerID]error {
+	cleanup := newImageCleanup(len(containers))
 	failed := make(map[types.ContainerID]error, len(containers))
 
+	containers = watchtowerLast(containers, true)
+
 	for i := len(containers) - 1; i >= 0; i-- {
 		if containers[i].ToRestart() {
-			err := stopStaleContainer(containers[i], client, params)
+			if deferRemaining(containers[:i+1], progress, params) {
+				break
+			}
+			err := stopStaleContainer(containers[i], client, params, progress)
 			if err != nil {
 				failed[containers[i].ID()] = err
 			} else {
-				if err := restartStaleContainer(containers[i], client, params); err != nil {
+				if _, err := restartStaleContainer(containers[i], client, params, progress); err != nil {
 					failed[containers[i].ID()] = err
 				} else if containers[i].Stale {
 					// Only add (previously) stale containers' images to cleanup
-					cleanupImageIDs[containers[i].ImageID()] = true
+					cleanup.add(containers[i], progress, params)
 				}
 			}
 		}
 	}
 
-	if params.Cleanup {
-		cleanupImages(client, cleanupImageIDs)
-	}
+	cleanup.run(client, params)
 	return failed
 }
 
-func stopContainersInReversedOrder(containers []container.Container, client container.Client, params types.UpdateParams) (failed map[types.ContainerID]error, stopped map[types.ImageID]bool) {
+func stopContainersInReversedOrder(containers []container.Container, client container.Client, params types.UpdateParams, progress *session.Progress) (failed map[types.ContainerID]error, stopped map[types.ImageID]bool) {
 	failed = make(map[types.ContainerID]error, len(containers))
 	stopped = make(map[types.ImageID]bool, len(containers))
 	for i := len(containers) - 1; i >= 0; i-- {
-		if err := stopStaleContainer(containers[i], client, params); err != nil {
+		if containers[i].ToRestart() && deferRemaining(containers[:i+1], progress, params) {
+			break
+		}
+		if err := stopStaleContainer(containers[i], client, params, progress); err != nil {
 			failed[containers[i].ID()] = err
 		} else {
 			// NOTE: If a container is restarted due to a dependency this might be empty
@@ -138,7 +416,36 @@ func stopContainersInReversedOrder(containers []container.Container, client cont
 	return
 }
 
-func stopStaleContainer(container container.Container, client container.Client, params types.UpdateParams) error {
+// shuttingDown returns whether watchtower has been asked to shut down
+func shuttingDown(params types.UpdateParams) bool {
+	select {
+	case <-params.Shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// deferRemaining leaves the containers that have not been stopped yet as they are once watchtower is shutting down,
+// so that only the container being updated is finished. They are reported as stale, and updated during the next
+// session. It returns whether watchtower is shutting down.
+func deferRemaining(containers []container.Container, progress *session.Progress, params types.UpdateParams) bool {
+	if !shuttingDown(params) {
+		return false
+	}
+	for i := range containers {
+		if !containers[i].ToRestart() {
+			continue
+		}
+		log.Infof("Not updating %s, as watchtower is shutting down", containers[i].Name())
+		containers[i].Stale = false
+		containers[i].LinkedToRestarting = false
+		progress.MarkDeferred(containers[i].ID())
+	}
+	return true
+}
+
+func stopStaleContainer(container container.Container, client container.Client, params types.UpdateParams, progress *session.Progress) error {
 	if container.IsWatchtower() {
 		log.Debugf("This is the watchtower container %s", container.Name())
 		return nil
@@ -164,74 +471,287 @@ func stopStaleContainer(container container.Container, client container.Client,
 		}
 		if skipUpdate {
 			log.Debug("Skipping container as the pre-update command returned exit code 75 (EX_TEMPFAIL)")
-			return errors.New("skipping container as the pre-update command returned exit code 75 (EX_TEMPFAIL)")
+			return session.WithSkipReason(session.SkipVetoedByHook,
+				errors.New("skipping container as the pre-update command returned exit code 75 (EX_TEMPFAIL)"))
+		}
+	}
+
+	if container.IsVolumeBackup() {
+		if err := client.BackupVolumes(container, params.Timeout); err != nil {
+			log.Error(err)
+			log.Info("Skipping container as backing up its volumes failed")
+			return err
 		}
 	}
 
+	stopStartedAt := time.Now()
 	if err := client.StopContainer(container, params.Timeout); err != nil {
 		log.Error(err)
 		return err
 	}
+	progress.AddRecreateDuration(container.ID(), time.Since(stopStartedAt))
 	return nil
 }
 
-func restartContainersInSortedOrder(containers []container.Container, client container.Client, params types.UpdateParams, stoppedImages map[types.ImageID]bool) map[types.ContainerID]error {
-	cleanupImageIDs := make(map[types.ImageID]bool, len(containers))
+// restartContainersInSortedOrder recreates the stopped containers. With transactional groups, the groups in which any
+// container failed to stop or to be recreated are rolled back before the previous images are cleaned up.
+func restartContainersInSortedOrder(containers []container.Container, client container.Client, params types.UpdateParams, progress *session.Progress, stoppedImages map[types.ImageID]bool, failedStop map[types.ContainerID]error) map[types.ContainerID]error {
+	cleanup := newImageCleanup(len(containers))
 	failed := make(map[types.ContainerID]error, len(containers))
+	// recreated maps the containers that were recreated, or attempted to be, to the IDs of the new containers
+	recreated := make(map[types.ContainerID]types.ContainerID, len(containers))
 
-	for _, c := range containers {
+	for _, c := range watchtowerLast(containers, false) {
 		if !c.ToRestart() {
 			continue
 		}
 		if stoppedImages[c.SafeImageID()] {
-			if err := restartStaleContainer(c, client, params); err != nil {
+			newContainerID, err := restartStaleContainer(c, client, params, progress)
+			recreated[c.ID()] = newContainerID
+			if err != nil {
 				failed[c.ID()] = err
-			} else if c.Stale {
-				// Only add (previously) stale containers' images to cleanup
-				cleanupImageIDs[c.ImageID()] = true
 			}
 		}
 	}
 
-	if params.Cleanup {
-		cleanupImages(client, cleanupImageIDs)
+	if params.TransactionalGroups {
+		rollBackFailedGroups(containers, client, params, failedStop, failed, recreated)
+	}
+	for _, c := range containers {
+		if _, attempted := recreated[c.ID()]; attempted && failed[c.ID()] == nil && c.Stale {
+			// Only add (previously) stale containers' images to cleanup
+			cleanup.add(c, progress, params)
+		}
 	}
 
+	cleanup.run(client, params)
+
 	return failed
 }
 
-func cleanupImages(client container.Client, imageIDs map[types.ImageID]bool) {
-	for imageID := range imageIDs {
-		if imageID == "" {
-			continue
+// watchtowerLast returns a copy of containers where any watchtower containers are ordered so that they are processed
+// last, letting the other containers be handled before the self-update handoff takes place
+func watchtowerLast(containers []container.Container, reversed bool) []container.Container {
+	others := make([]container.Container, 0, len(containers))
+	var watchtowers []container.Container
+	for _, c := range containers {
+		if c.IsWatchtower() {
+			watchtowers = append(watchtowers, c)
+		} else {
+			others = append(others, c)
+		}
+	}
+	if reversed {
+		return append(watchtowers, others...)
+	}
+	return append(others, watchtowers...)
+}
+
+// imageCleanup collects the previous images of the updated containers, and the repositories they belong to
+type imageCleanup struct {
+	imageIDs     map[types.ImageID]bool
+	imageRepos   map[types.ImageID]string
+	repositories map[string]bool
+}
+
+func newImageCleanup(size int) *imageCleanup {
+	return &imageCleanup{
+		imageIDs:     make(map[types.ImageID]bool, size),
+		imageRepos:   make(map[types.ImageID]string, size),
+		repositories: make(map[string]bool, size),
+	}
+}
+
+// add marks the previous image of the container for removal, unless cleanup is disabled for it. Images (and, when
+// retaining images, repositories) are kept if cleanup is disabled for any of the containers that used them. The image
+// is kept as well if the container was recreated using it, like when only the tag of the container changed.
+func (ic *imageCleanup) add(c container.Container, progress *session.Progress, params types.UpdateParams) {
+	imageID := c.ImageID()
+	repository, _ := c.ImageRepositoryAndTag()
+	ic.imageRepos[imageID] = repository
+	if status := (*progress)[c.ID()]; status != nil && status.LatestImageID() == imageID {
+		ic.imageIDs[imageID] = false
+		return
+	}
+	if c.IsNoCleanup(!params.Cleanup) {
+		ic.imageIDs[imageID] = false
+		ic.repositories[repository] = false
+		return
+	}
+	if _, found := ic.imageIDs[imageID]; !found {
+		ic.imageIDs[imageID] = true
+	}
+	if _, found := ic.repositories[repository]; !found {
+		ic.repositories[repository] = true
+	}
+}
+
+// run removes the marked images, or, if an image retention policy is set, the previous images of the marked
+// repositories that are not retained by it. If a cleanup delay is set, or the cleanup is scheduled separately, the
+// removal of the images is deferred instead.
+func (ic *imageCleanup) run(client container.Client, params types.UpdateParams) {
+	now := time.Now()
+	var removals map[types.ImageID]string
+	if params.ImageRetention.Enabled() {
+		removals = unretainedImages(client, ic, params.State, params.ImageRetention, now)
+	} else {
+		removals = make(map[types.ImageID]string, len(ic.imageIDs))
+		for imageID, remove := range ic.imageIDs {
+			if imageID != "" && remove {
+				removals[imageID] = ic.imageRepos[imageID]
+			}
 		}
+	}
+
+	if (params.CleanupDelay > 0 || params.CleanupScheduled) && params.State != nil {
+		deferImageRemovals(params.State, removals, now.Add(params.CleanupDelay))
+		return
+	}
+	for imageID := range removals {
 		if err := client.RemoveImageByID(imageID); err != nil {
 			log.Error(err)
 		}
 	}
 }
 
-func restartStaleContainer(container container.Container, client container.Client, params types.UpdateParams) error {
-	// Since we can't shutdown a watchtower container immediately, we need to
-	// start the new one while the old one is still running. This prevents us
-	// from re-using the same container name so we first rename the current
-	// instance so that the new one can adopt the old name.
+// unretainedImages returns the unused previous images of each of the marked repositories that are not retained by the
+// policy. Only the images watchtower replaced, during this or a previous session, are considered, leaving the other
+// images of the repositories, like the pulled images of the staged updates, alone.
+func unretainedImages(client container.Client, ic *imageCleanup, state types.StateStore, retention types.ImageRetention, now time.Time) map[types.ImageID]string {
+	replaced := loadReplacedImages(state)
+	for imageID, remove := range ic.imageIDs {
+		if imageID != "" && remove {
+			replaced[imageID] = ic.imageRepos[imageID]
+		}
+	}
+	staged := stagedImages(state)
+
+	removals := map[types.ImageID]string{}
+	for repository, remove := range ic.repositories {
+		if !remove {
+			continue
+		}
+		images, err := client.ListUnusedImages(repository)
+		if err != nil {
+			log.WithError(err).Errorf("Could not list the previous images of %s", repository)
+			continue
+		}
+		unused := make(map[types.ImageID]bool, len(images))
+		position := 0
+		for _, image := range images {
+			unused[image.ID] = true
+			if replaced[image.ID] != repository || staged[image.ID] {
+				continue
+			}
+			retained := retention.Retains(position, image.Created, now)
+			position++
+			if retained {
+				log.Debugf("Retaining previous image %s of %s", image.ID.ShortID(), repository)
+				continue
+			}
+			removals[image.ID] = repository
+			delete(replaced, image.ID)
+		}
+		// The images that are in use again, or have been removed, are no longer previous images of the repository
+		for imageID, imageRepository := range replaced {
+			if imageRepository == repository && !unused[imageID] {
+				delete(replaced, imageID)
+			}
+		}
+	}
+	saveReplacedImages(state, replaced)
+	return removals
+}
+
+// restartStaleContainer recreates the container, returning the ID of the new container, which is set even if it could
+// be created but not started
+func restartStaleContainer(container container.Container, client container.Client, params types.UpdateParams, progress *session.Progress) (types.ContainerID, error) {
+	container.SetUpdateSession(params.SessionID)
+
 	if container.IsWatchtower() {
-		if err := client.RenameContainer(container, util.RandName()); err != nil {
-			log.Error(err)
-			return nil
+		if params.NoRestart {
+			return "", nil
+		}
+		newContainerID, err := updateSelf(container, client, params)
+		if newContainerID != "" && params.LifecycleHooks {
+			lifecycle.ExecutePostUpdateCommand(client, newContainerID)
 		}
+		return newContainerID, err
 	}
 
 	if !params.NoRestart {
-		if newContainerID, err := client.StartContainer(container); err != nil {
+		startStartedAt := time.Now()
+		newContainerID, err := startContainer(container, client, params)
+		progress.AddRecreateDuration(container.ID(), time.Since(startStartedAt))
+		if err != nil {
 			log.Error(err)
-			return err
-		} else if container.ToRestart() && params.LifecycleHooks {
+			return newContainerID, err
+		}
+		events.Record(params.Events, types.Event{
+			Type:      events.ContainerRecreated,
+			Container: container.Name(),
+			Image:     container.ImageName(),
+			Details:   map[string]interface{}{"previousImage": container.SafeImageID(), "id": newContainerID},
+		})
+		if container.ToRestart() && params.LifecycleHooks {
 			lifecycle.ExecutePostUpdateCommand(client, newContainerID)
 		}
+		awaitSettled(container, params)
+		if err := checkRecreated(client, container, newContainerID, params); err != nil {
+			log.Error(err)
+			return newContainerID, err
+		}
+		// the anonymous volumes of the previous container are only removed once it has been replaced successfully
+		client.RemoveAnonymousVolumes(container)
+		return newContainerID, nil
+	}
+	return "", nil
+}
+
+// startContainer recreates the container, retrying if configured to. The container left behind by a failed attempt,
+// like one that was created but could not be started, is removed before retrying, as it would block the name.
+func startContainer(c container.Container, client container.Client, params types.UpdateParams) (types.ContainerID, error) {
+	var newContainerID types.ContainerID
+	err := withRetries(params, "recreating "+c.Name(), func() (err error) {
+		if newContainerID != "" {
+			removeFailedContainer(client, newContainerID, params)
+		}
+		newContainerID, err = client.StartContainer(c)
+		return err
+	})
+	return newContainerID, err
+}
+
+func removeFailedContainer(client container.Client, containerID types.ContainerID, params types.UpdateParams) {
+	failed, err := client.GetContainer(containerID)
+	if err == nil {
+		err = client.StopContainer(failed, params.Timeout)
+	}
+	if err != nil {
+		log.WithError(err).Warnf("Could not remove the container %s left behind by the failed attempt", containerID.ShortID())
+	}
+}
+
+// awaitSettled waits for the time set using the post-update wait label of the container, giving it time to warm up
+// before the next container is updated. Shutting down watchtower ends the wait early.
+func awaitSettled(container container.Container, params types.UpdateParams) {
+	wait, err := container.PostUpdateWait()
+	if err != nil {
+		log.WithField("container", container.Name()).Warn(err)
+		return
+	}
+	if wait <= 0 {
+		return
+	}
+
+	log.Infof("Waiting %s for %s to settle", wait, container.Name())
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-params.Shutdown:
+		log.Debugf("Stopped waiting for %s to settle, as watchtower is shutting down", container.Name())
 	}
-	return nil
 }
 
 // UpdateImplicitRestart iterates through the passed containers, setting the
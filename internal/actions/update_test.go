@@ -1,10 +1,17 @@
 package actions_test
 
 import (
+	"errors"
 	"time"
 
 	"github.com/containrrr/watchtower/internal/actions"
 	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/events"
+	"github.com/containrrr/watchtower/pkg/filters"
+	"github.com/containrrr/watchtower/pkg/releases"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/state"
+	"github.com/containrrr/watchtower/pkg/strategy"
 	"github.com/containrrr/watchtower/pkg/types"
 	dockerTypes "github.com/docker/docker/api/types"
 	dockerContainer "github.com/docker/docker/api/types/container"
@@ -15,6 +22,45 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// eventRecorder keeps the recorded events, in order
+type eventRecorder struct {
+	events []types.Event
+}
+
+func (r *eventRecorder) Record(event types.Event) {
+	r.events = append(r.events, event)
+}
+
+// composeRecorder keeps the re-deployed compose projects, failing every re-deploy with err if set
+type composeRecorder struct {
+	projects []types.ComposeProject
+	err      error
+}
+
+func (r *composeRecorder) Up(project types.ComposeProject) error {
+	r.projects = append(r.projects, project)
+	return r.err
+}
+
+// strategyRecorder decides about the containers and updates them using the decisions and results by name, keeping the
+// names of the containers it was asked to update
+type strategyRecorder struct {
+	decisions map[string]types.UpdateDecision
+	errors    map[string]error
+	handled   map[string]error
+	updated   []string
+}
+
+func (r *strategyRecorder) ShouldUpdate(candidate types.UpdateCandidate) (types.UpdateDecision, error) {
+	return r.decisions[candidate.Name], r.errors[candidate.Name]
+}
+
+func (r *strategyRecorder) PerformUpdate(candidate types.UpdateCandidate) (bool, error) {
+	r.updated = append(r.updated, candidate.Name)
+	err, handled := r.handled[candidate.Name]
+	return handled, err
+}
+
 func getCommonTestData(keepContainer string) *TestData {
 	return &TestData{
 		NameOfContainerToKeep: keepContainer,
@@ -67,6 +113,1164 @@ func getLinkedTestData(withImageInfo bool) *TestData {
 }
 
 var _ = Describe("the update action", func() {
+	When("watchtower has not been instructed to clean up", func() {
+		When("cleanup has been enabled for a container using a label", func() {
+			It("should only try to remove its image", func() {
+				testData := getCommonTestData("")
+				testData.Containers = append(
+					testData.Containers,
+					CreateMockContainerWithConfig(
+						"unique-test-container",
+						"unique-test-container",
+						"unique-fake-image:latest",
+						true,
+						false,
+						time.Now(),
+						&dockerContainer.Config{
+							Labels: map[string]string{
+								"com.centurylinklabs.watchtower.no-cleanup": "false",
+							},
+						}),
+				)
+				client := CreateMockClient(testData, false, false)
+				_, err := actions.Update(client, types.UpdateParams{Cleanup: false})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(client.TestData.TriedToRemoveImageCount).To(Equal(1))
+			})
+		})
+	})
+	When("a container was recreated using its current image", func() {
+		It("should not try to remove the image", func() {
+			testData := getCommonTestData("")
+			testData.Containers = testData.Containers[:1]
+			testData.NewestImages = map[string]types.ImageID{"test-container-01": testData.Containers[0].ImageID()}
+			client := CreateMockClient(testData, false, false)
+			_, err := actions.Update(client, types.UpdateParams{Cleanup: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(0))
+		})
+	})
+	When("an image retention policy has been set", func() {
+		var store *state.Store
+
+		BeforeEach(func() {
+			store, _ = state.New("")
+			// the previous images replaced during the earlier sessions
+			Expect(store.Set("replaced-images", map[types.ImageID]string{
+				"sha256:03": "fake-image",
+				"sha256:02": "fake-image",
+				"sha256:01": "fake-image",
+			})).To(Succeed())
+		})
+		getRetentionTestData := func() *TestData {
+			testData := getCommonTestData("")
+			testData.UnusedImages = map[string][]types.ImageSummary{
+				"fake-image": {
+					{ID: "sha256:04", Created: time.Now()},
+					{ID: "sha256:03", Created: time.Now().Add(-time.Hour)},
+					{ID: "sha256:02", Created: time.Now().Add(-48 * time.Hour)},
+					{ID: "sha256:01", Created: time.Now().Add(-72 * time.Hour)},
+				},
+			}
+			return testData
+		}
+		It("should keep the configured number of previous images", func() {
+			client := CreateMockClient(getRetentionTestData(), false, false)
+			_, err := actions.Update(client, types.UpdateParams{
+				Cleanup:        true,
+				ImageRetention: types.ImageRetention{Keep: 1},
+				State:          store,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(2))
+
+			replaced := map[types.ImageID]string{}
+			Expect(store.Get("replaced-images", &replaced)).To(BeTrue())
+			Expect(replaced).To(HaveKey(types.ImageID("sha256:03")))
+			Expect(replaced).NotTo(HaveKey(types.ImageID("sha256:02")))
+		})
+		It("should keep the previous images younger than the configured age", func() {
+			client := CreateMockClient(getRetentionTestData(), false, false)
+			_, err := actions.Update(client, types.UpdateParams{
+				Cleanup:        true,
+				ImageRetention: types.ImageRetention{KeepYoungerThan: 60 * time.Hour},
+				State:          store,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(1))
+		})
+		It("should not remove the images watchtower did not replace", func() {
+			client := CreateMockClient(getRetentionTestData(), false, false)
+			_, err := actions.Update(client, types.UpdateParams{
+				Cleanup:        true,
+				ImageRetention: types.ImageRetention{Keep: 1},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(0))
+		})
+		It("should not remove the pulled images of the staged updates", func() {
+			Expect(store.Set("staged-updates", map[string]interface{}{
+				"other-container": map[string]interface{}{"latestImage": "sha256:01"},
+			})).To(Succeed())
+			client := CreateMockClient(getRetentionTestData(), false, false)
+			_, err := actions.Update(client, types.UpdateParams{
+				Cleanup:        true,
+				ImageRetention: types.ImageRetention{Keep: 1},
+				State:          store,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(1))
+		})
+		It("should not remove any images if cleanup is disabled", func() {
+			client := CreateMockClient(getRetentionTestData(), false, false)
+			_, err := actions.Update(client, types.UpdateParams{
+				Cleanup:        false,
+				ImageRetention: types.ImageRetention{Keep: 1},
+				State:          store,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(0))
+		})
+	})
+	When("a cleanup delay has been set", func() {
+		It("should only remove the previous images once the delay has passed", func() {
+			store, err := state.New("")
+			Expect(err).NotTo(HaveOccurred())
+			testData := getCommonTestData("")
+			previousImage := testData.Containers[0].ImageID()
+			client := CreateMockClient(testData, false, false)
+			params := types.UpdateParams{Cleanup: true, CleanupDelay: time.Hour, State: store}
+
+			_, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(0))
+
+			removals := map[types.ImageID]struct{ RemoveAfter time.Time }{}
+			Expect(store.Get("deferred-image-removals", &removals)).To(BeTrue())
+			Expect(removals).To(HaveKey(previousImage))
+
+			// Pretend that the delay has passed
+			Expect(store.Set("deferred-image-removals", map[types.ImageID]interface{}{
+				previousImage: map[string]interface{}{"repository": "fake-image", "removeAfter": time.Now().Add(-time.Minute)},
+			})).To(Succeed())
+			testData.UnusedImages = map[string][]types.ImageSummary{"fake-image": {{ID: previousImage}}}
+			testData.Staleness = map[string]bool{"test-container-01": false, "test-container-02": false}
+
+			_, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(1))
+			Expect(store.Get("deferred-image-removals", &removals)).To(BeFalse())
+		})
+	})
+	When("the cleanup is scheduled separately", func() {
+		It("should only remove the previous images during the cleanup session", func() {
+			store, err := state.New("")
+			Expect(err).NotTo(HaveOccurred())
+			testData := getCommonTestData("")
+			previousImage := testData.Containers[0].ImageID()
+			client := CreateMockClient(testData, false, false)
+
+			_, err = actions.Update(client, types.UpdateParams{Cleanup: true, CleanupScheduled: true, State: store})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(0))
+
+			testData.UnusedImages = map[string][]types.ImageSummary{"fake-image": {{ID: previousImage}}}
+			actions.RemoveDeferredImages(client, store)
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(1))
+		})
+	})
+	When("the updates are staged", func() {
+		var testData *TestData
+		var store *state.Store
+		var started []string
+
+		BeforeEach(func() {
+			started = nil
+			testData = &TestData{
+				Containers: []container.Container{
+					CreateMockContainer("test-container-01", "test-container-01", "fake-image1:latest", time.Now()),
+					CreateMockContainerWithConfig("test-container-02", "test-container-02", "fake-image2:latest",
+						true, false, time.Now(), &dockerContainer.Config{
+							Labels: map[string]string{"com.centurylinklabs.watchtower.stage-only": "true"},
+						}),
+				},
+				ApplyFilter: true,
+				OnStart: func(c container.Container) {
+					started = append(started, c.Name())
+				},
+			}
+			store, _ = state.New("")
+		})
+
+		It("should leave the stage-only containers running until the staged updates are applied", func() {
+			client := CreateMockClient(testData, false, false)
+
+			report, err := actions.Update(client, types.UpdateParams{Filter: filters.NoFilter, State: store})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+			Expect(report.Stale()).To(HaveLen(1))
+			Expect(started).To(ConsistOf("test-container-01"))
+
+			report, err = actions.Update(client, types.UpdateParams{Filter: filters.NoFilter, State: store, ApplyStaged: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Scanned()).To(HaveLen(1))
+			Expect(started).To(ConsistOf("test-container-01", "test-container-02"))
+		})
+
+		It("should stage all the containers when staging only, until the staged updates are applied", func() {
+			client := CreateMockClient(testData, false, false)
+			params := types.UpdateParams{Filter: filters.NoFilter, State: store, StageOnly: true}
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Stale()).To(HaveLen(2))
+			Expect(started).To(BeEmpty())
+
+			params.ApplyStaged = true
+			report, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(2))
+			Expect(started).To(HaveLen(2))
+
+			report, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Scanned()).To(BeEmpty())
+		})
+
+		It("should hold the updates pending approval until they are approved", func() {
+			client := CreateMockClient(testData, false, false)
+			params := types.UpdateParams{Filter: filters.NoFilter, State: store, RequireApproval: true}
+
+			_, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(started).To(BeEmpty())
+			pending := actions.ListStagedUpdates(store)
+			Expect(pending).To(HaveLen(2))
+			Expect(pending[0].Container).To(Equal("test-container-01"))
+
+			params.ApplyStaged = true
+			params.Filter = filters.FilterByNames([]string{"test-container-02"}, filters.NoFilter)
+			_, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(started).To(ConsistOf("test-container-02"))
+			Expect(actions.ListStagedUpdates(store)).To(HaveLen(1))
+		})
+
+		It("should keep the staged updates of the containers not due to be checked, and apply them anyway", func() {
+			testData.Containers[1].ContainerInfo().Config.Labels["com.centurylinklabs.watchtower.check-interval"] = "24h"
+			client := CreateMockClient(testData, false, false)
+			params := types.UpdateParams{Filter: filters.NoFilter, State: store}
+
+			_, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actions.ListStagedUpdates(store)).To(HaveLen(1))
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Fresh()[0].SkipReason()).To(Equal(session.SkipCheckNotDue))
+			Expect(actions.ListStagedUpdates(store)).To(HaveLen(1))
+
+			params.ApplyStaged = true
+			_, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(started).To(ContainElement("test-container-02"))
+			Expect(actions.ListStagedUpdates(store)).To(BeEmpty())
+		})
+	})
+	When("the checks are scheduled separately from the updates", func() {
+		var testData *TestData
+		var store *state.Store
+		var started []string
+
+		BeforeEach(func() {
+			started = nil
+			testData = &TestData{
+				Containers: []container.Container{
+					CreateMockContainer("test-container-01", "test-container-01", "fake-image1:latest", time.Now()),
+					CreateMockContainer("test-container-02", "test-container-02", "fake-image2:latest", time.Now()),
+				},
+				Staleness:   map[string]bool{"test-container-01": true, "test-container-02": false},
+				ApplyFilter: true,
+				OnStart: func(c container.Container) {
+					started = append(started, c.Name())
+				},
+			}
+			store, _ = state.New("")
+		})
+
+		It("should only update the containers that a check queued", func() {
+			client := CreateMockClient(testData, false, false)
+			params := types.UpdateParams{Filter: filters.NoFilter, State: store, PendingUpdates: true}
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Scanned()).To(BeEmpty())
+			Expect(started).To(BeEmpty())
+
+			checkParams := params
+			checkParams.MonitorOnly = true
+			report, err = actions.Update(client, checkParams)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Stale()).To(HaveLen(1))
+
+			// New images found after the check are left for the next check to queue
+			testData.Staleness["test-container-02"] = true
+			report, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+			Expect(started).To(ConsistOf("test-container-01"))
+		})
+
+		It("should remove the updated containers from the queue", func() {
+			client := CreateMockClient(testData, false, false)
+			params := types.UpdateParams{Filter: filters.NoFilter, State: store, PendingUpdates: true}
+			checkParams := params
+			checkParams.MonitorOnly = true
+
+			_, err := actions.Update(client, checkParams)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(started).To(HaveLen(1))
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Scanned()).To(BeEmpty())
+			Expect(started).To(HaveLen(1))
+		})
+
+		It("should keep the containers queued that failed to update", func() {
+			testData.StartFailures = map[string]int{"test-container-01": 1}
+			client := CreateMockClient(testData, false, false)
+			params := types.UpdateParams{Filter: filters.NoFilter, State: store, PendingUpdates: true}
+			checkParams := params
+			checkParams.MonitorOnly = true
+
+			_, err := actions.Update(client, checkParams)
+			Expect(err).NotTo(HaveOccurred())
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Failed()).To(HaveLen(1))
+
+			report, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+		})
+	})
+	When("a container has a post-update wait label", func() {
+		getWaitTestData := func() *TestData {
+			return &TestData{
+				Containers: []container.Container{
+					CreateMockContainerWithConfig(
+						"test-container-01",
+						"test-container-01",
+						"fake-image:latest",
+						true,
+						false,
+						time.Now(),
+						&dockerContainer.Config{
+							Labels: map[string]string{
+								"com.centurylinklabs.watchtower.post-update-wait": "100ms",
+							},
+						}),
+				},
+			}
+		}
+		It("should wait after restarting the container", func() {
+			client := CreateMockClient(getWaitTestData(), false, false)
+			start := time.Now()
+			_, err := actions.Update(client, types.UpdateParams{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+		})
+		It("should stop waiting when watchtower is shutting down", func() {
+			testData := getWaitTestData()
+			testData.Containers[0].ContainerInfo().Config.Labels["com.centurylinklabs.watchtower.post-update-wait"] = "1h"
+			shutdown := make(chan struct{})
+			testData.OnStart = func(container.Container) { close(shutdown) }
+			client := CreateMockClient(testData, false, false)
+			_, err := actions.Update(client, types.UpdateParams{Shutdown: shutdown})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+	When("a session ID has been supplied", func() {
+		It("should label the recreated containers with the session", func() {
+			testData := getCommonTestData("")
+			client := CreateMockClient(testData, false, false)
+			_, err := actions.Update(client, types.UpdateParams{SessionID: "0123456789ab"})
+			Expect(err).NotTo(HaveOccurred())
+			labels := testData.Containers[0].ContainerInfo().Config.Labels
+			Expect(labels).To(HaveKeyWithValue("com.centurylinklabs.watchtower.session.id", "0123456789ab"))
+			Expect(labels).To(HaveKeyWithValue("com.centurylinklabs.watchtower.session.previous-image",
+				testData.Containers[0].ContainerInfo().Image))
+		})
+	})
+	When("a release resolver has been supplied", func() {
+		It("should include the releases of the new images in the report", func() {
+			testData := getCommonTestData("")
+			staleID := testData.Containers[0].ID()
+			testData.Staleness = map[string]bool{testData.Containers[1].Name(): false}
+			testData.Images = map[types.ImageID]*dockerTypes.ImageInspect{
+				"": {Config: &dockerContainer.Config{Labels: map[string]string{
+					releases.SourceLabel:  "https://github.com/containrrr/watchtower",
+					releases.VersionLabel: "1.5.0",
+				}}},
+			}
+			client := CreateMockClient(testData, false, false)
+			report, err := actions.Update(client, types.UpdateParams{Releases: releases.NewResolver(false, "")})
+			Expect(err).NotTo(HaveOccurred())
+			for _, c := range report.All() {
+				if c.ID() == staleID {
+					Expect(c.Release().URL).To(Equal("https://github.com/containrrr/watchtower/releases/tag/1.5.0"))
+				} else {
+					Expect(c.Release().URL).To(BeEmpty())
+				}
+			}
+		})
+	})
+	When("a minimum image age has been set", func() {
+		getAgeTestData := func(created time.Time) *TestData {
+			testData := getCommonTestData("")
+			testData.Staleness = map[string]bool{testData.Containers[1].Name(): false}
+			testData.Images = map[types.ImageID]*dockerTypes.ImageInspect{
+				"": {Created: created.Format(time.RFC3339Nano), Config: &dockerContainer.Config{}},
+			}
+			return testData
+		}
+		It("should defer the update while the new image is too young", func() {
+			testData := getAgeTestData(time.Now().Add(-time.Hour))
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{MinImageAge: 48 * time.Hour})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(BeEmpty())
+			Expect(report.Stale()).To(HaveLen(1))
+			Expect(report.Stale()[0].SkipReason()).To(Equal(session.SkipImageTooNew))
+			Expect(testData.TriedToRemoveImageCount).To(BeZero())
+		})
+		It("should update the container once the new image is old enough", func() {
+			testData := getAgeTestData(time.Now().Add(-72 * time.Hour))
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{MinImageAge: 48 * time.Hour})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+		})
+		It("should let the label override the minimum age", func() {
+			testData := getAgeTestData(time.Now().Add(-time.Hour))
+			testData.Containers[0].ContainerInfo().Config.Labels = map[string]string{
+				"com.centurylinklabs.watchtower.min-image-age": "0",
+			}
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{MinImageAge: 48 * time.Hour})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+		})
+	})
+	When("the failure log lines have been set", func() {
+		failLogs := func(state *dockerTypes.ContainerState) *TestData {
+			testData := getCommonTestData("")
+			testData.Staleness = map[string]bool{testData.Containers[1].Name(): false}
+			testData.Logs = "starting\nlistening on :8080\npanic: config file missing\n"
+			testData.OnStart = func(c container.Container) {
+				// the mock client inspects the first container as the recreated one
+				testData.Containers[0].ContainerInfo().State = state
+			}
+			return testData
+		}
+		It("should report a recreated container that exited as failed, with the tail of its logs", func() {
+			testData := failLogs(&dockerTypes.ContainerState{Running: false, ExitCode: 2,
+				StartedAt: "2024-05-01T10:00:00.123456789Z"})
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{FailureLogLines: 2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(BeEmpty())
+			Expect(report.Failed()).To(HaveLen(1))
+			Expect(report.Failed()[0].Error()).To(ContainSubstring("exited with code 2"))
+			Expect(report.Failed()[0].Logs()).To(Equal("listening on :8080\npanic: config file missing"))
+		})
+		It("should report a recreated container that is unhealthy as failed", func() {
+			testData := failLogs(&dockerTypes.ContainerState{Running: true, Health: &dockerTypes.Health{Status: "unhealthy"}})
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{FailureLogLines: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Failed()).To(HaveLen(1))
+			Expect(report.Failed()[0].Logs()).To(HavePrefix("starting"))
+		})
+		It("should not report a recreated container that is unhealthy within its health check start period", func() {
+			testData := failLogs(&dockerTypes.ContainerState{Running: true, StartedAt: time.Now().Format(time.RFC3339Nano),
+				Health: &dockerTypes.Health{Status: "unhealthy"}})
+			onStart := testData.OnStart
+			testData.OnStart = func(c container.Container) {
+				onStart(c)
+				testData.Containers[0].ContainerInfo().Config.Healthcheck = &dockerContainer.HealthConfig{StartPeriod: time.Hour}
+			}
+			params := types.UpdateParams{FailureLogLines: 10, HealthStartPeriodMultiplier: 1}
+			report, err := actions.Update(CreateMockClient(testData, false, false), params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+			Expect(report.Failed()).To(BeEmpty())
+		})
+		It("should report a recreated container that is running as updated", func() {
+			testData := failLogs(&dockerTypes.ContainerState{Running: true})
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{FailureLogLines: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+			Expect(report.Failed()).To(BeEmpty())
+		})
+		It("should not check a recreated container that was not started", func() {
+			testData := failLogs(&dockerTypes.ContainerState{Running: false, StartedAt: "0001-01-01T00:00:00Z"})
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{FailureLogLines: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+			Expect(report.Failed()).To(BeEmpty())
+		})
+	})
+	When("the anonymous volumes are cleaned up", func() {
+		It("should only remove the volumes of the containers that were recreated", func() {
+			testData := &TestData{
+				Containers: []container.Container{
+					CreateMockContainer("test-container-01", "test-container-01", "fake-image1:latest", time.Now()),
+					CreateMockContainer("test-container-02", "test-container-02", "fake-image2:latest", time.Now()),
+				},
+				StartFailures: map[string]int{"test-container-01": 1},
+			}
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Failed()).To(HaveLen(1))
+			Expect(report.Updated()).To(HaveLen(1))
+			Expect(testData.VolumesRemovedOf).To(ConsistOf("test-container-02"))
+		})
+	})
+	When("a container asks for its volumes to be backed up", func() {
+		backupData := func() *TestData {
+			return &TestData{
+				Containers: []container.Container{
+					CreateMockContainerWithConfig(
+						"test-container-01",
+						"test-container-01",
+						"fake-image:latest",
+						true,
+						false,
+						time.Now(),
+						&dockerContainer.Config{
+							Labels: map[string]string{
+								"com.centurylinklabs.watchtower.volume-backup": "true",
+							},
+						}),
+					CreateMockContainer("test-container-02", "test-container-02", "fake-image2:latest", time.Now()),
+				},
+			}
+		}
+		It("should back up its volumes before updating it", func() {
+			testData := backupData()
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testData.BackedUp).To(ConsistOf("test-container-01"))
+			Expect(report.Updated()).To(HaveLen(2))
+		})
+		It("should start the recreated container after the backup", func() {
+			testData := backupData()
+			var started []string
+			testData.OnStart = func(c container.Container) {
+				// the client only starts the recreated containers whose previous container was running
+				if c.Name() == "test-container-01" && c.IsRunning() {
+					started = append(started, c.Name())
+				}
+			}
+			_, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testData.BackedUp).To(ConsistOf("test-container-01"))
+			Expect(started).To(ConsistOf("test-container-01"))
+		})
+		It("should not update it if the backup fails", func() {
+			testData := backupData()
+			testData.BackupFailures = map[string]error{"test-container-01": errors.New("the backup command exited with 1")}
+			var stopped []string
+			testData.OnStop = func(c container.Container) { stopped = append(stopped, c.Name()) }
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stopped).To(ConsistOf("test-container-02"))
+			Expect(report.Failed()).To(HaveLen(1))
+			Expect(report.Failed()[0].Error()).To(ContainSubstring("the backup command exited with 1"))
+		})
+	})
+	When("a container has a check interval", func() {
+		It("should only be checked again once the interval has passed", func() {
+			store, err := state.New("")
+			Expect(err).NotTo(HaveOccurred())
+			testData := &TestData{
+				Containers: []container.Container{
+					CreateMockContainerWithConfig("test-container-01", "test-container-01", "fake-image:latest",
+						true, false, time.Now(), &dockerContainer.Config{
+							Labels: map[string]string{"com.centurylinklabs.watchtower.check-interval": "24h"},
+						}),
+				},
+			}
+			client := CreateMockClient(testData, false, false)
+			params := types.UpdateParams{State: store}
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+
+			report, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(BeEmpty())
+			Expect(report.Fresh()).To(HaveLen(1))
+			Expect(report.Fresh()[0].SkipReason()).To(Equal(session.SkipCheckNotDue))
+
+			// Pretend that the interval has passed
+			Expect(store.Set("last-checks", map[string]time.Time{
+				"test-container-01": time.Now().Add(-25 * time.Hour),
+			})).To(Succeed())
+			report, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+		})
+	})
+	When("a container joins the network namespace of a stale container", func() {
+		It("should be recreated after it", func() {
+			vpn := CreateMockContainer("vpn", "/vpn", "vpn:latest", time.Now())
+			app := CreateMockContainer("app", "/app", "app:latest", time.Now())
+			app.ContainerInfo().HostConfig.NetworkMode = "container:vpn"
+			testData := &TestData{
+				Staleness:  map[string]bool{"/app": false},
+				Containers: []container.Container{app, vpn},
+			}
+			var stopped, started []string
+			testData.OnStop = func(c container.Container) { stopped = append(stopped, c.Name()) }
+			testData.OnStart = func(c container.Container) { started = append(started, c.Name()) }
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stopped).To(Equal([]string{"/app", "/vpn"}))
+			Expect(started).To(Equal([]string{"/vpn", "/app"}))
+			Expect(report.Updated()).To(HaveLen(1))
+		})
+	})
+	When("compose projects are re-deployed", func() {
+		composeData := func() *TestData {
+			composeLabels := func(project string, service string) *dockerContainer.Config {
+				return &dockerContainer.Config{Labels: map[string]string{
+					"com.docker.compose.project":              project,
+					"com.docker.compose.project.config_files": "/srv/" + project + "/compose.yml",
+					"com.docker.compose.service":              service,
+				}}
+			}
+			return &TestData{
+				Containers: []container.Container{
+					CreateMockContainerWithConfig("shop-web", "shop-web", "web:latest", true, false, time.Now(), composeLabels("shop", "web")),
+					CreateMockContainerWithConfig("shop-db", "shop-db", "db:latest", true, false, time.Now(), composeLabels("shop", "db")),
+					CreateMockContainer("standalone", "standalone", "app:latest", time.Now()),
+				},
+			}
+		}
+		It("should re-deploy each project once instead of recreating its containers", func() {
+			testData := composeData()
+			var started []string
+			testData.OnStart = func(c container.Container) { started = append(started, c.Name()) }
+			deployer := &composeRecorder{}
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{Compose: deployer})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deployer.projects).To(HaveLen(1))
+			Expect(deployer.projects[0].Name).To(Equal("shop"))
+			Expect(deployer.projects[0].ConfigFiles).To(Equal([]string{"/srv/shop/compose.yml"}))
+			Expect(deployer.projects[0].Services).To(Equal([]string{"web", "db"}))
+			Expect(started).To(ConsistOf("standalone"))
+			Expect(report.Updated()).To(HaveLen(3))
+		})
+		It("should report the containers of a project that failed to re-deploy as failed", func() {
+			deployer := &composeRecorder{err: errors.New("could not re-deploy compose project shop")}
+			report, err := actions.Update(CreateMockClient(composeData(), false, false), types.UpdateParams{Compose: deployer})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Failed()).To(HaveLen(2))
+			Expect(report.Updated()).To(HaveLen(1))
+		})
+	})
+	When("an update strategy has been set", func() {
+		strategyData := func() *TestData {
+			return &TestData{
+				Containers: []container.Container{
+					CreateMockContainer("test-container-01", "test-container-01", "fake-image1:latest", time.Now()),
+					CreateMockContainer("test-container-02", "test-container-02", "fake-image2:latest", time.Now()),
+					CreateMockContainer("test-container-03", "test-container-03", "fake-image3:latest", time.Now()),
+				},
+			}
+		}
+		It("should leave the containers the strategy skips or defers as they are", func() {
+			testData := strategyData()
+			testData.Containers = testData.Containers[:2]
+			updateStrategy := &strategyRecorder{decisions: map[string]types.UpdateDecision{
+				"test-container-01": strategy.Skip,
+				"test-container-02": strategy.Defer,
+			}}
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{Strategy: updateStrategy})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(BeEmpty())
+			Expect(report.Stale()).To(HaveLen(2))
+			Expect(report.Stale()[0].SkipReason()).To(Equal(session.SkipVetoedByStrategy))
+			Expect(report.Stale()[1].SkipReason()).To(Equal(session.SkipDeferredByStrategy))
+			Expect(updateStrategy.updated).To(BeEmpty())
+		})
+		It("should defer the updates the strategy could not decide about", func() {
+			testData := strategyData()
+			updateStrategy := &strategyRecorder{errors: map[string]error{"test-container-01": errors.New("plugin crashed")}}
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{Strategy: updateStrategy})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(2))
+			Expect(report.Stale()).To(HaveLen(1))
+			Expect(report.Stale()[0].SkipReason()).To(Equal(session.SkipDeferredByStrategy))
+		})
+		It("should only recreate the containers the strategy did not update itself", func() {
+			testData := strategyData()
+			var stopped, started []string
+			testData.OnStop = func(c container.Container) { stopped = append(stopped, c.Name()) }
+			testData.OnStart = func(c container.Container) { started = append(started, c.Name()) }
+			updateStrategy := &strategyRecorder{handled: map[string]error{
+				"test-container-01": nil,
+				"test-container-03": errors.New("the canary failed"),
+			}}
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{Strategy: updateStrategy})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updateStrategy.updated).To(ConsistOf("test-container-01", "test-container-02", "test-container-03"))
+			Expect(stopped).To(ConsistOf("test-container-02"))
+			Expect(started).To(ConsistOf("test-container-02"))
+			Expect(report.Updated()).To(HaveLen(2))
+			Expect(report.Failed()).To(HaveLen(1))
+			Expect(report.Failed()[0].Name()).To(Equal("test-container-03"))
+		})
+	})
+	When("retries have been configured", func() {
+		retryParams := types.UpdateParams{Retries: 2, RetryBackoff: time.Millisecond}
+		It("should retry failed update checks", func() {
+			testData := getCommonTestData("")
+			name := testData.Containers[0].Name()
+			testData.CheckFailures = map[string]int{name: 2}
+			report, err := actions.Update(CreateMockClient(testData, false, false), retryParams)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Skipped()).To(BeEmpty())
+			Expect(testData.CheckFailures[name]).To(BeZero())
+		})
+		It("should give up once the retries are exhausted", func() {
+			testData := getCommonTestData("")
+			testData.CheckFailures = map[string]int{testData.Containers[0].Name(): 3}
+			report, err := actions.Update(CreateMockClient(testData, false, false), retryParams)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Skipped()).To(HaveLen(1))
+		})
+		It("should stop waiting to retry when watchtower is shutting down", func() {
+			testData := getCommonTestData("")
+			testData.CheckFailures = map[string]int{testData.Containers[0].Name(): 1}
+			shutdown := make(chan struct{})
+			time.AfterFunc(50*time.Millisecond, func() { close(shutdown) })
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_, _ = actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{
+					Retries: 2, RetryBackoff: time.Hour, Shutdown: shutdown,
+				})
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+		It("should retry recreating containers", func() {
+			testData := getCommonTestData("")
+			testData.StartFailures = map[string]int{testData.Containers[0].Name(): 1}
+			report, err := actions.Update(CreateMockClient(testData, false, false), retryParams)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Failed()).To(BeEmpty())
+		})
+	})
+	When("transactional groups are enabled", func() {
+		groupConfig := func(image string, group string) *dockerContainer.Config {
+			return &dockerContainer.Config{
+				Image:  image,
+				Labels: map[string]string{"com.centurylinklabs.watchtower.group": group},
+			}
+		}
+		getGroupTestData := func() *TestData {
+			return &TestData{
+				Containers: []container.Container{
+					CreateMockContainerWithConfig("test-container-01", "test-container-01", "fake-image1:latest", true, false, time.Now(), groupConfig("fake-image1:latest", "shop")),
+					CreateMockContainerWithConfig("test-container-02", "test-container-02", "fake-image2:latest", true, false, time.Now(), groupConfig("fake-image2:latest", "shop")),
+					CreateMockContainer("test-container-03", "test-container-03", "fake-image3:latest", time.Now()),
+				},
+			}
+		}
+		params := types.UpdateParams{Cleanup: true, TransactionalGroups: true}
+
+		It("should not update any container of a group in which a container could not be checked", func() {
+			testData := getGroupTestData()
+			testData.CheckFailures = map[string]int{"test-container-02": 1}
+			var started []string
+			testData.OnStart = func(c container.Container) { started = append(started, c.Name()) }
+
+			report, err := actions.Update(CreateMockClient(testData, false, false), params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(started).To(ConsistOf("test-container-03"))
+			Expect(report.Skipped()).To(HaveLen(1))
+			Expect(report.Stale()).To(HaveLen(1))
+			Expect(report.Stale()[0].Name()).To(Equal("test-container-01"))
+			Expect(report.Stale()[0].SkipReason()).To(Equal(session.SkipGroupIncomplete))
+		})
+
+		It("should roll back the whole group if a container failed to update", func() {
+			testData := getGroupTestData()
+			testData.StartFailures = map[string]int{"test-container-02": 1}
+			var started []string
+			testData.OnStart = func(c container.Container) { started = append(started, c.Name()) }
+
+			report, err := actions.Update(CreateMockClient(testData, false, false), params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(started).To(Equal([]string{"test-container-01", "test-container-03", "test-container-01", "test-container-02"}))
+			Expect(testData.Tags).To(Equal(map[string]types.ImageID{
+				"fake-image1:latest": "fake-image1:latest",
+				"fake-image2:latest": "fake-image2:latest",
+			}))
+			Expect(report.Updated()).To(HaveLen(1))
+			Expect(report.Failed()).To(HaveLen(1))
+			Expect(report.Failed()[0].Name()).To(Equal("test-container-02"))
+			Expect(report.Skipped()).To(HaveLen(1))
+			Expect(report.Skipped()[0].SkipReason()).To(Equal(session.SkipRolledBack))
+			Expect(testData.TriedToRemoveImageCount).To(Equal(1), "only the image of the updated container should be removed")
+		})
+
+		It("should only quarantine the container that failed to update, not the ones rolled back along with it", func() {
+			testData := getGroupTestData()
+			testData.StartFailures = map[string]int{"test-container-02": 1}
+			store, _ := state.New("")
+			quarantineParams := params
+			quarantineParams.State, quarantineParams.QuarantineAfter = store, 1
+
+			report, err := actions.Update(CreateMockClient(testData, false, false), quarantineParams)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Skipped()[0].SkipReason()).To(Equal(session.SkipRolledBack))
+			quarantined := actions.ListQuarantined(store)
+			Expect(quarantined).To(HaveLen(1))
+			Expect(quarantined[0].Name).To(Equal("test-container-02"))
+		})
+
+		It("should not roll back the groups that were updated", func() {
+			testData := getGroupTestData()
+			report, err := actions.Update(CreateMockClient(testData, false, false), params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(3))
+			Expect(testData.Tags).To(BeEmpty())
+		})
+	})
+	When("failed containers are retried during the next sessions", func() {
+		It("should report them as retrying until the retry sessions are exhausted", func() {
+			testData := &TestData{
+				Containers: []container.Container{
+					CreateMockContainer("test-container-01", "test-container-01", "fake-image:latest", time.Now()),
+				},
+				CheckFailures: map[string]int{"test-container-01": 2},
+			}
+			store, _ := state.New("")
+			params := types.UpdateParams{State: store, RetrySessions: 1}
+			client := CreateMockClient(testData, false, false)
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Retrying()).To(HaveLen(1))
+			Expect(report.Retrying()[0].State()).To(Equal("Retrying"))
+			Expect(report.Skipped()).To(BeEmpty())
+
+			report, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Retrying()).To(BeEmpty())
+			Expect(report.Skipped()).To(HaveLen(1))
+
+			By("forgetting the failures once the container succeeds")
+			report, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Skipped()).To(BeEmpty())
+			found, err := store.Get("update-failures", &map[string]interface{}{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+		It("should not retry the failed checks within the error budget", func() {
+			testData := &TestData{
+				Containers: []container.Container{
+					CreateMockContainer("test-container-01", "test-container-01", "fake-image:latest", time.Now()),
+				},
+				CheckFailures: map[string]int{"test-container-01": 2},
+			}
+			store, _ := state.New("")
+			params := types.UpdateParams{State: store, RetrySessions: 1, ErrorBudget: session.NewErrorBudget(2, store)}
+			client := CreateMockClient(testData, false, false)
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Retrying()).To(BeEmpty())
+			Expect(report.Skipped()).To(BeEmpty())
+			Expect(report.Scanned()).To(HaveLen(1))
+			Expect(report.Scanned()[0].SkipReason()).To(Equal(session.SkipWithinErrorBudget))
+
+			By("escalating them once the budget is exhausted")
+			report, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Retrying()).To(HaveLen(1))
+		})
+		It("should keep the previous failures of a container whose check failed within the error budget", func() {
+			testData := &TestData{
+				Containers: []container.Container{
+					CreateMockContainer("test-container-01", "test-container-01", "fake-image:latest", time.Now()),
+				},
+				StartFailures: map[string]int{"test-container-01": 1},
+			}
+			store, _ := state.New("")
+			params := types.UpdateParams{State: store, QuarantineAfter: 2, ErrorBudget: session.NewErrorBudget(2, store)}
+			client := CreateMockClient(testData, false, false)
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Failed()).To(HaveLen(1))
+
+			testData.CheckFailures = map[string]int{"test-container-01": 1}
+			_, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			failures := map[string]interface{}{}
+			found, err := store.Get("update-failures", &failures)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(failures).To(HaveKey("test-container-01"))
+		})
+		It("should spend the error budget of an image once per session", func() {
+			testData := &TestData{
+				Containers: []container.Container{
+					CreateMockContainer("test-container-01", "test-container-01", "fake-image:latest", time.Now()),
+					CreateMockContainer("test-container-02", "test-container-02", "fake-image:latest", time.Now()),
+					CreateMockContainer("test-container-03", "test-container-03", "fake-image:latest", time.Now()),
+				},
+				CheckFailures: map[string]int{"test-container-01": 2, "test-container-02": 2},
+				// the containers are listed anew in each session, as sorting them by their dependencies reuses the slice
+				ApplyFilter: true,
+			}
+			params := types.UpdateParams{Filter: filters.NoFilter, ErrorBudget: session.NewErrorBudget(2, nil)}
+			client := CreateMockClient(testData, false, false)
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Skipped()).To(BeEmpty(), "two failed checks of the image should only spend its budget once")
+
+			By("escalating them in the next session, even though another container of the image was checked")
+			report, err = actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Skipped()).To(HaveLen(2))
+		})
+	})
+	When("there is not enough disk space to pull the image of a container", func() {
+		It("should report it as skipped, without retrying, quarantining or spending the error budget", func() {
+			name := "test-container-01"
+			testData := &TestData{
+				Containers: []container.Container{
+					CreateMockContainer(name, name, "fake-image:latest", time.Now()),
+				},
+				CheckFailures: map[string]int{name: 2},
+				CheckErrors: map[string]error{
+					name: session.WithSkipReason(session.SkipInsufficientDiskSpace, errors.New("not enough disk space")),
+				},
+			}
+			store, _ := state.New("")
+			params := types.UpdateParams{State: store, Retries: 1, RetrySessions: 1, QuarantineAfter: 1,
+				ErrorBudget: session.NewErrorBudget(2, store)}
+
+			report, err := actions.Update(CreateMockClient(testData, false, false), params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Skipped()).To(HaveLen(1))
+			Expect(report.Skipped()[0].SkipReason()).To(Equal(session.SkipInsufficientDiskSpace))
+			Expect(report.Retrying()).To(BeEmpty())
+			Expect(report.Quarantined()).To(BeEmpty())
+			Expect(testData.CheckFailures[name]).To(Equal(1), "the check should not be retried")
+			for _, key := range []string{"update-failures", "error-budget"} {
+				found, err := store.Get(key, &map[string]interface{}{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeFalse(), key)
+			}
+		})
+	})
+	When("the image of a container is missing", func() {
+		It("should report it as skipped, rather than as a failed check", func() {
+			name := "test-container-01"
+			testData := &TestData{
+				Containers: []container.Container{
+					CreateMockContainerWithImageInfoP(name, name, "fake-image:latest", time.Now(), nil),
+				},
+				CheckFailures: map[string]int{name: 1},
+			}
+			store, _ := state.New("")
+			params := types.UpdateParams{State: store, Retries: 1, RetrySessions: 1, ErrorBudget: session.NewErrorBudget(1, store)}
+
+			report, err := actions.Update(CreateMockClient(testData, false, false), params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Retrying()).To(BeEmpty())
+			Expect(report.Skipped()).To(HaveLen(1))
+			Expect(report.Skipped()[0].SkipReason()).To(Equal(session.SkipImageMissing))
+			Expect(testData.CheckFailures[name]).To(Equal(1), "the container should not be checked")
+			Expect(session.ExitCode(report, nil, false)).To(Equal(session.ExitOK))
+		})
+	})
+	When("containers are quarantined after failing repeatedly", func() {
+		var testData *TestData
+		var store *state.Store
+		var params types.UpdateParams
+
+		BeforeEach(func() {
+			testData = &TestData{
+				Containers: []container.Container{
+					CreateMockContainer("test-container-01", "test-container-01", "fake-image:latest", time.Now()),
+				},
+				CheckFailures: map[string]int{"test-container-01": 10},
+			}
+			store, _ = state.New("")
+			params = types.UpdateParams{State: store, QuarantineAfter: 2}
+		})
+
+		It("should stop trying to update them once the threshold is reached", func() {
+			client := CreateMockClient(testData, false, false)
+			for i := 0; i < 2; i++ {
+				report, err := actions.Update(client, params)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Skipped()).To(HaveLen(1))
+				Expect(report.Quarantined()).To(BeEmpty())
+			}
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Skipped()).To(BeEmpty())
+			Expect(report.Quarantined()).To(HaveLen(1))
+			Expect(report.Quarantined()[0].State()).To(Equal("Quarantined"))
+			Expect(testData.CheckFailures["test-container-01"]).To(Equal(8))
+
+			quarantined := actions.ListQuarantined(store)
+			Expect(quarantined).To(HaveLen(1))
+			Expect(quarantined[0].Name).To(Equal("test-container-01"))
+			Expect(quarantined[0].Sessions).To(Equal(2))
+		})
+
+		It("should release them when asked to", func() {
+			client := CreateMockClient(testData, false, false)
+			for i := 0; i < 2; i++ {
+				_, _ = actions.Update(client, params)
+			}
+
+			Expect(actions.ReleaseQuarantined(store, []string{"other-container"})).To(BeEmpty())
+			Expect(actions.ReleaseQuarantined(store, []string{"/test-container-01"})).To(ConsistOf("test-container-01"))
+			Expect(actions.ListQuarantined(store)).To(BeEmpty())
+
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Quarantined()).To(BeEmpty())
+			Expect(report.Skipped()).To(HaveLen(1))
+		})
+
+		It("should release them when their labels change", func() {
+			client := CreateMockClient(testData, false, false)
+			for i := 0; i < 2; i++ {
+				_, _ = actions.Update(client, params)
+			}
+
+			testData.Containers[0].ContainerInfo().Config.Labels["com.example.fixed"] = "true"
+			report, err := actions.Update(client, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Quarantined()).To(BeEmpty())
+			Expect(report.Skipped()).To(HaveLen(1))
+		})
+	})
+	When("watchtower is shutting down during the session", func() {
+		var testData *TestData
+		var shutdown chan struct{}
+
+		BeforeEach(func() {
+			shutdown = make(chan struct{})
+			testData = &TestData{
+				Containers: []container.Container{
+					CreateMockContainer("test-container-01", "test-container-01", "fake-image-01:latest", time.Now()),
+					CreateMockContainer("test-container-02", "test-container-02", "fake-image-02:latest", time.Now()),
+				},
+				Staleness: map[string]bool{"test-container-01": true, "test-container-02": true},
+				OnStop: func(c container.Container) {
+					select {
+					case <-shutdown:
+					default:
+						close(shutdown)
+					}
+				},
+			}
+		})
+
+		It("should only finish the container being updated", func() {
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{Shutdown: shutdown})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+			Expect(report.Stale()).To(HaveLen(1))
+			Expect(report.Failed()).To(BeEmpty())
+		})
+
+		It("should only finish the container being updated when performing a rolling restart", func() {
+			params := types.UpdateParams{Shutdown: shutdown, RollingRestart: true}
+			report, err := actions.Update(CreateMockClient(testData, false, false), params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+			Expect(report.Stale()).To(HaveLen(1))
+		})
+
+		It("should not check any containers if it was already shutting down", func() {
+			close(shutdown)
+			report, err := actions.Update(CreateMockClient(testData, false, false), types.UpdateParams{Shutdown: shutdown})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Scanned()).To(BeEmpty())
+		})
+	})
+	When("images have been pulled for the containers", func() {
+		It("should include the transferred bytes in the report", func() {
+			testData := getCommonTestData("")
+			testData.PulledBytes = map[string]int64{"fake-image:latest": 1024}
+			client := CreateMockClient(testData, false, false)
+			report, err := actions.Update(client, types.UpdateParams{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.PulledBytes()).To(BeEquivalentTo(1024))
+		})
+		It("should include the time spent on the session, the scan and the pulls in the report", func() {
+			testData := getCommonTestData("")
+			testData.PulledBytes = map[string]int64{"fake-image:latest": 1024}
+			client := CreateMockClient(testData, false, false)
+			report, err := actions.Update(client, types.UpdateParams{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.ScanDuration()).To(BeNumerically(">", 0))
+			Expect(report.Duration()).To(BeNumerically(">=", report.ScanDuration()))
+			for _, c := range report.All() {
+				if c.PulledBytes() > 0 {
+					Expect(c.PullDuration()).To(BeNumerically(">", 0))
+				} else {
+					Expect(c.PullDuration()).To(BeZero())
+				}
+			}
+			for _, c := range report.Updated() {
+				Expect(c.RecreateDuration()).To(BeNumerically(">", 0))
+			}
+		})
+	})
+	When("the latest image of a container has another configuration", func() {
+		It("should include the changes in the report", func() {
+			testData := getCommonTestData("")
+			stale := testData.Containers[0]
+			stale.ImageInfo().Config = &dockerContainer.Config{Entrypoint: []string{"/app"}}
+			testData.Staleness = map[string]bool{testData.Containers[1].Name(): false}
+			testData.Images = map[types.ImageID]*dockerTypes.ImageInspect{
+				"": {Config: &dockerContainer.Config{
+					Entrypoint:   []string{"/entrypoint.sh"},
+					ExposedPorts: nat.PortSet{"8080/tcp": {}},
+				}},
+			}
+			client := CreateMockClient(testData, false, false)
+			report, err := actions.Update(client, types.UpdateParams{})
+			Expect(err).NotTo(HaveOccurred())
+			for _, c := range report.All() {
+				if c.ID() == stale.ID() {
+					Expect(c.ImageChanges()).To(Equal([]string{
+						`entrypoint changed from ["/app"] to ["/entrypoint.sh"]`,
+						"exposed port 8080/tcp added",
+					}))
+				} else {
+					Expect(c.ImageChanges()).To(BeEmpty())
+				}
+			}
+		})
+	})
 	When("watchtower has been instructed to clean up", func() {
 		When("there are multiple containers using the same image", func() {
 			It("should only try to remove the image once", func() {
@@ -110,6 +1314,30 @@ var _ = Describe("the update action", func() {
 				Expect(client.TestData.TriedToRemoveImageCount).To(Equal(1))
 			})
 		})
+		When("cleanup has been disabled for a container using a label", func() {
+			It("should not try to remove its image", func() {
+				testData := getCommonTestData("")
+				testData.Containers = append(
+					testData.Containers,
+					CreateMockContainerWithConfig(
+						"unique-test-container",
+						"unique-test-container",
+						"unique-fake-image:latest",
+						true,
+						false,
+						time.Now(),
+						&dockerContainer.Config{
+							Labels: map[string]string{
+								"com.centurylinklabs.watchtower.no-cleanup": "true",
+							},
+						}),
+				)
+				client := CreateMockClient(testData, false, false)
+				_, err := actions.Update(client, types.UpdateParams{Cleanup: true})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(client.TestData.TriedToRemoveImageCount).To(Equal(1))
+			})
+		})
 		When("updating a linked container with missing image info", func() {
 			It("should gracefully fail", func() {
 				client := CreateMockClient(getLinkedTestData(false), false, false)
@@ -124,6 +1352,27 @@ var _ = Describe("the update action", func() {
 		})
 	})
 
+	When("an event recorder has been set", func() {
+		It("should record the checks and the recreated containers", func() {
+			recorder := &eventRecorder{}
+			client := CreateMockClient(getCommonTestData(""), false, false)
+			_, err := actions.Update(client, types.UpdateParams{Events: recorder})
+			Expect(err).NotTo(HaveOccurred())
+
+			var checked, recreated []string
+			for _, event := range recorder.events {
+				switch event.Type {
+				case events.ContainerChecked:
+					checked = append(checked, event.Container)
+				case events.ContainerRecreated:
+					recreated = append(recreated, event.Container)
+				}
+			}
+			Expect(checked).To(HaveLen(3))
+			Expect(recreated).To(HaveLen(3))
+		})
+	})
+
 	When("watchtower has been instructed to monitor only", func() {
 		When("certain containers are set to monitor only", func() {
 			It("should not update those containers", func() {
@@ -153,9 +1402,11 @@ var _ = Describe("the update action", func() {
 					false,
 					false,
 				)
-				_, err := actions.Update(client, types.UpdateParams{Cleanup: true})
+				report, err := actions.Update(client, types.UpdateParams{Cleanup: true})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(client.TestData.TriedToRemoveImageCount).To(Equal(1))
+				Expect(report.Stale()).To(HaveLen(1))
+				Expect(report.Stale()[0].SkipReason()).To(Equal(session.SkipMonitorOnly))
 			})
 		})
 
@@ -247,9 +1498,11 @@ var _ = Describe("the update action", func() {
 					false,
 					false,
 				)
-				_, err := actions.Update(client, types.UpdateParams{Cleanup: true, LifecycleHooks: true})
+				report, err := actions.Update(client, types.UpdateParams{Cleanup: true, LifecycleHooks: true})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(client.TestData.TriedToRemoveImageCount).To(Equal(0))
+				Expect(report.Skipped()).To(HaveLen(1))
+				Expect(report.Skipped()[0].SkipReason()).To(Equal(session.SkipVetoedByHook))
 			})
 
 		})
@@ -399,3 +1652,70 @@ var _ = Describe("the update action", func() {
 
 	})
 })
+
+var _ = Describe("the self-update handoff", func() {
+	getWatchtowerTestData := func() *TestData {
+		return &TestData{
+			Containers: []container.Container{
+				CreateMockContainerWithConfig(
+					"watchtower",
+					"/watchtower",
+					"containrrr/watchtower:latest",
+					true,
+					false,
+					time.Now(),
+					&dockerContainer.Config{
+						Labels: map[string]string{
+							"com.centurylinklabs.watchtower": "true",
+						},
+					}),
+			},
+		}
+	}
+
+	When("the new instance signals that it is ready", func() {
+		It("should report the container as updated", func() {
+			shutdown := make(chan struct{})
+			testData := getWatchtowerTestData()
+			// The new instance asks this one to shut down once it is ready
+			testData.OnStart = func(container.Container) { close(shutdown) }
+			client := CreateMockClient(testData, false, false)
+			report, err := actions.Update(client, types.UpdateParams{
+				SelfUpdateTimeout: time.Minute,
+				Shutdown:          shutdown,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Updated()).To(HaveLen(1))
+			Expect(report.Failed()).To(BeEmpty())
+		})
+		It("should run the post-update command in the new instance", func() {
+			shutdown := make(chan struct{})
+			testData := getWatchtowerTestData()
+			testData.Containers[0].ContainerInfo().Config.Labels["com.centurylinklabs.watchtower.lifecycle.post-update"] =
+				"/post-update.sh"
+			testData.OnStart = func(container.Container) { close(shutdown) }
+			client := CreateMockClient(testData, false, false)
+			_, err := actions.Update(client, types.UpdateParams{
+				SelfUpdateTimeout: time.Minute,
+				Shutdown:          shutdown,
+				LifecycleHooks:    true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testData.Commands).To(ContainElement("/post-update.sh"))
+		})
+	})
+
+	When("the new instance does not become ready in time", func() {
+		It("should abort the update and keep the old image", func() {
+			client := CreateMockClient(getWatchtowerTestData(), false, false)
+			report, err := actions.Update(client, types.UpdateParams{
+				Cleanup:           true,
+				SelfUpdateTimeout: 10 * time.Millisecond,
+				Shutdown:          make(chan struct{}),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Failed()).To(HaveLen(1))
+			Expect(client.TestData.TriedToRemoveImageCount).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,45 @@
+package flags
+
+import (
+	"fmt"
+
+	"github.com/containrrr/watchtower/pkg/filters"
+	"github.com/containrrr/watchtower/pkg/registry/hosts"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// ConfigFile holds the settings read from the config file that are not available as flags
+type ConfigFile struct {
+	// Registries are the settings of the registries, keyed by their host
+	Registries map[string]hosts.Config
+	// NotificationTemplates are the notification templates, keyed by the name the notification URLs refer to them by
+	NotificationTemplates map[string]string
+	// FilterPresets are the named combinations of filters, keyed by the name passed using --filter-preset
+	FilterPresets map[string]filters.Preset
+}
+
+// ReadConfigFile reads the config file passed using --config-file, returning an empty config if there is none
+func ReadConfigFile(flags *pflag.FlagSet) (ConfigFile, error) {
+	var config ConfigFile
+	path, _ := flags.GetString("config-file")
+	if path == "" {
+		return config, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return config, fmt.Errorf("could not read the config file %s: %w", path, err)
+	}
+	if err := v.UnmarshalKey("registries", &config.Registries); err != nil {
+		return config, fmt.Errorf("invalid registries in the config file %s: %w", path, err)
+	}
+	if err := v.UnmarshalKey("notification_templates", &config.NotificationTemplates); err != nil {
+		return config, fmt.Errorf("invalid notification templates in the config file %s: %w", path, err)
+	}
+	if err := v.UnmarshalKey("filter_presets", &config.FilterPresets); err != nil {
+		return config, fmt.Errorf("invalid filter presets in the config file %s: %w", path, err)
+	}
+	return config, nil
+}
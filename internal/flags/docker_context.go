@@ -0,0 +1,119 @@
+package flags
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// contextScheme is the prefix of the hosts referring to a context of the docker CLI, like context://my-remote
+const contextScheme = "context://"
+
+// dockerContext is the docker endpoint of a context of the docker CLI
+type dockerContext struct {
+	Host          string
+	SkipTLSVerify bool
+	// CertPath is the directory containing the ca.pem, cert.pem and key.pem of the context, if it has any
+	CertPath string
+}
+
+// dockerConfigDir returns the configuration directory of the docker CLI, which DOCKER_CONFIG overrides
+func dockerConfigDir() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find the docker CLI configuration: %w", err)
+	}
+	return filepath.Join(home, ".docker"), nil
+}
+
+// resolveDockerContext reads the docker endpoint of the named context from the context store of the docker CLI. An
+// empty name refers to the current context of the CLI.
+func resolveDockerContext(name string) (dockerContext, error) {
+	configDir, err := dockerConfigDir()
+	if err != nil {
+		return dockerContext{}, err
+	}
+	if name == "" {
+		if name, err = currentDockerContext(configDir); err != nil {
+			return dockerContext{}, err
+		}
+	}
+	if name == "default" {
+		return dockerContext{Host: "unix:///var/run/docker.sock"}, nil
+	}
+
+	// the contexts are stored in directories named by the digest of their name
+	digest := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(digest[:])
+
+	data, err := os.ReadFile(filepath.Join(configDir, "contexts", "meta", id, "meta.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return dockerContext{}, fmt.Errorf("the docker context %q does not exist in %s", name, configDir)
+	} else if err != nil {
+		return dockerContext{}, fmt.Errorf("could not read the docker context %q: %w", name, err)
+	}
+	var meta struct {
+		Endpoints map[string]struct {
+			Host          string
+			SkipTLSVerify bool
+		}
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return dockerContext{}, fmt.Errorf("could not read the docker context %q: %w", name, err)
+	}
+	endpoint, found := meta.Endpoints["docker"]
+	if !found || endpoint.Host == "" {
+		return dockerContext{}, fmt.Errorf("the docker context %q has no docker endpoint", name)
+	}
+
+	resolved := dockerContext{Host: endpoint.Host, SkipTLSVerify: endpoint.SkipTLSVerify}
+	certPath := filepath.Join(configDir, "contexts", "tls", id, "docker")
+	var present, missing []string
+	for _, file := range []string{"ca.pem", "cert.pem", "key.pem"} {
+		if _, err := os.Stat(filepath.Join(certPath, file)); err == nil {
+			present = append(present, file)
+		} else {
+			missing = append(missing, file)
+		}
+	}
+	if len(present) > 0 && len(missing) > 0 {
+		return dockerContext{}, fmt.Errorf("the TLS material of the docker context %q is incomplete, missing %s",
+			name, strings.Join(missing, ", "))
+	}
+	if len(present) > 0 {
+		resolved.CertPath = certPath
+	}
+	return resolved, nil
+}
+
+// currentDockerContext returns the name of the context selected in the configuration of the docker CLI, which
+// DOCKER_CONTEXT overrides
+func currentDockerContext(configDir string) (string, error) {
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" {
+		return name, nil
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "default", nil
+	} else if err != nil {
+		return "", fmt.Errorf("could not read the docker CLI configuration: %w", err)
+	}
+	var config struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf("could not read the docker CLI configuration: %w", err)
+	}
+	if config.CurrentContext == "" {
+		return "default", nil
+	}
+	return config.CurrentContext, nil
+}
@@ -0,0 +1,70 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultDockerSocket is the socket of the docker daemon used by default
+var defaultDockerSocket = "/var/run/docker.sock"
+
+// dockerSocket is a socket that may be used instead of the default one
+type dockerSocket struct {
+	path string
+	kind string
+}
+
+// socketCandidates returns the sockets of rootless docker and podman for the current user, followed by the one of
+// rootful podman, in the order they are looked for
+func socketCandidates() []dockerSocket {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if uid := os.Getuid(); runtimeDir == "" && uid > 0 {
+		runtimeDir = fmt.Sprintf("/run/user/%d", uid)
+	}
+
+	var candidates []dockerSocket
+	if runtimeDir != "" {
+		candidates = append(candidates,
+			dockerSocket{path: filepath.Join(runtimeDir, "docker.sock"), kind: "rootless docker"},
+			dockerSocket{path: filepath.Join(runtimeDir, "podman", "podman.sock"), kind: "rootless podman"},
+		)
+	}
+	return append(candidates, dockerSocket{path: "/run/podman/podman.sock", kind: "podman"})
+}
+
+// discoverDockerSocket returns the host to use when none has been set. The default socket is used if it exists, or
+// else the first of the rootless docker and podman sockets that does. Without any of them, the default socket is kept,
+// so that the errors refer to it.
+func discoverDockerSocket() string {
+	defaultHost := "unix://" + defaultDockerSocket
+	if isSocket(defaultDockerSocket) {
+		log.Debugf("Using the docker socket at %s", defaultDockerSocket)
+		return defaultHost
+	}
+
+	candidates := socketCandidates()
+	for _, candidate := range candidates {
+		if isSocket(candidate.path) {
+			log.Infof("The docker socket at %s does not exist, using the %s socket at %s",
+				defaultDockerSocket, candidate.kind, candidate.path)
+			return "unix://" + candidate.path
+		}
+	}
+
+	paths := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		paths[i] = candidate.path
+	}
+	log.Warnf("Could not find a docker socket at %s or any of %s. Mount the socket of the docker daemon, or set the host "+
+		"to connect to", defaultDockerSocket, strings.Join(paths, ", "))
+	return defaultHost
+}
+
+func isSocket(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
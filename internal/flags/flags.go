@@ -16,7 +16,7 @@ import (
 )
 
 // DockerAPIMinVersion is the minimum version of the docker api required to
-// use watchtower
+// use watchtower. Unless a version is set, the client negotiates the version with the daemon.
 const DockerAPIMinVersion string = "1.25"
 
 var defaultInterval = int((time.Hour * 24).Seconds())
@@ -26,7 +26,7 @@ func RegisterDockerFlags(rootCmd *cobra.Command) {
 	flags := rootCmd.PersistentFlags()
 	flags.StringP("host", "H", viper.GetString("DOCKER_HOST"), "daemon socket to connect to")
 	flags.BoolP("tlsverify", "v", viper.GetBool("DOCKER_TLS_VERIFY"), "use TLS and verify the remote")
-	flags.StringP("api-version", "a", viper.GetString("DOCKER_API_VERSION"), "api version to use by docker client")
+	flags.StringP("api-version", "a", viper.GetString("DOCKER_API_VERSION"), "api version to use by docker client, negotiated with the daemon if not set")
 }
 
 // RegisterSystemFlags that are used by watchtower to modify the program flow
@@ -44,12 +44,96 @@ func RegisterSystemFlags(rootCmd *cobra.Command) {
 		viper.GetString("WATCHTOWER_SCHEDULE"),
 		"The cron expression which defines when to update")
 
+	flags.StringP(
+		"report-schedule",
+		"",
+		viper.GetString("WATCHTOWER_REPORT_SCHEDULE"),
+		"The cron expression which defines when to check for and report updates without applying them")
+
+	flags.StringP(
+		"check-schedule",
+		"",
+		viper.GetString("WATCHTOWER_CHECK_SCHEDULE"),
+		"The cron expression which defines when to check for updates, which the scheduled sessions then apply")
+
+	flags.StringP(
+		"cleanup-schedule",
+		"",
+		viper.GetString("WATCHTOWER_CLEANUP_SCHEDULE"),
+		"The cron expression which defines when to remove the previous images of the updated containers")
+
+	flags.StringP(
+		"apply-schedule",
+		"",
+		viper.GetString("WATCHTOWER_APPLY_SCHEDULE"),
+		"The cron expression which defines when to apply the staged updates")
+
 	flags.DurationP(
 		"stop-timeout",
 		"t",
 		viper.GetDuration("WATCHTOWER_TIMEOUT"),
 		"Timeout before a container is forcefully stopped")
 
+	flags.DurationP(
+		"self-update-timeout",
+		"",
+		viper.GetDuration("WATCHTOWER_SELF_UPDATE_TIMEOUT"),
+		"Time to wait for a new watchtower instance to take over before aborting a self-update, 0 to not wait")
+
+	flags.DurationP(
+		"drain-timeout",
+		"",
+		viper.GetDuration("WATCHTOWER_DRAIN_TIMEOUT"),
+		"Time to wait for the running update to finish when shutting down, 0 to wait until it has finished")
+
+	flags.StringP(
+		"pause-file",
+		"",
+		viper.GetString("WATCHTOWER_PAUSE_FILE"),
+		"Only check for updates, without applying them, while a file exists at this path")
+
+	flags.StringSliceP(
+		"no-update-on",
+		"",
+		commaSeparated(viper.GetString("WATCHTOWER_NO_UPDATE_ON")),
+		"Only check for updates, without applying them, on these days, like \"Dec 24-Jan 2\" or \"last Friday of month\"")
+
+	flags.StringP(
+		"vault-address",
+		"",
+		viper.GetString("WATCHTOWER_VAULT_ADDRESS"),
+		"Address of the HashiCorp Vault server used to resolve vault: secret references, defaults to VAULT_ADDR")
+
+	flags.StringP(
+		"vault-token",
+		"",
+		viper.GetString("WATCHTOWER_VAULT_TOKEN"),
+		"Token used to read secrets from the HashiCorp Vault server, defaults to VAULT_TOKEN")
+
+	flags.DurationP(
+		"secrets-refresh-interval",
+		"",
+		viper.GetDuration("WATCHTOWER_SECRETS_REFRESH_INTERVAL"),
+		"Time after which the secrets referring to a secret provider are resolved again before a session, 0 to never")
+
+	flags.Float64P(
+		"health-start-period-multiplier",
+		"",
+		viper.GetFloat64("WATCHTOWER_HEALTH_START_PERIOD_MULTIPLIER"),
+		"Multiplier for the health check start period during which new and recreated containers are not considered unhealthy")
+
+	flags.IntP(
+		"check-failure-threshold",
+		"",
+		viper.GetInt("WATCHTOWER_CHECK_FAILURE_THRESHOLD"),
+		"Number of sessions in a row in which the check of an image has to fail before the failure is escalated")
+
+	flags.BoolP(
+		"session-labels",
+		"",
+		viper.GetBool("WATCHTOWER_SESSION_LABELS"),
+		"Label recreated containers with the ID of the update session and the image they were running before")
+
 	flags.BoolP(
 		"no-pull",
 		"",
@@ -74,18 +158,144 @@ func RegisterSystemFlags(rootCmd *cobra.Command) {
 		viper.GetBool("WATCHTOWER_CLEANUP"),
 		"Remove previously used images after updating")
 
+	flags.IntP(
+		"cleanup-keep",
+		"",
+		viper.GetInt("WATCHTOWER_CLEANUP_KEEP"),
+		"Number of previous images to keep for each repository when cleaning up")
+
+	flags.DurationP(
+		"cleanup-keep-younger-than",
+		"",
+		viper.GetDuration("WATCHTOWER_CLEANUP_KEEP_YOUNGER_THAN"),
+		"Keep the previous images created less than this long ago when cleaning up")
+
+	flags.DurationP(
+		"cleanup-delay",
+		"",
+		viper.GetDuration("WATCHTOWER_CLEANUP_DELAY"),
+		"Time to wait after an update before removing the previous images when cleaning up")
+
+	flags.StringP(
+		"state-file",
+		"",
+		viper.GetString("WATCHTOWER_STATE_FILE"),
+		"File in which to keep the state between sessions, to preserve it across restarts")
+
+	flags.StringP(
+		"config-file",
+		"",
+		viper.GetString("WATCHTOWER_CONFIG_FILE"),
+		"A YAML, JSON or TOML file with the settings that are not available as flags, like the per-registry settings")
+
+	flags.StringP(
+		"archive-logs",
+		"",
+		viper.GetString("WATCHTOWER_ARCHIVE_LOGS"),
+		"Keep the logs of the containers removed to be recreated, in this directory or by posting them to this HTTP URL")
+
+	flags.StringP(
+		"volume-backup-target",
+		"",
+		viper.GetString("WATCHTOWER_VOLUME_BACKUP_TARGET"),
+		"Host path or volume to back up the volumes of containers with the volume-backup label to before updating them")
+
+	flags.StringP(
+		"volume-backup-image",
+		"",
+		viper.GetString("WATCHTOWER_VOLUME_BACKUP_IMAGE"),
+		"Image used to run the volume backup command, alpine:latest if not set")
+
+	flags.StringP(
+		"volume-backup-command",
+		"",
+		viper.GetString("WATCHTOWER_VOLUME_BACKUP_COMMAND"),
+		"Command backing up the volume mounted at /volume to the backup target mounted at /backup, archiving it using tar if not set")
+
+	flags.DurationP(
+		"volume-backup-timeout",
+		"",
+		viper.GetDuration("WATCHTOWER_VOLUME_BACKUP_TIMEOUT"),
+		"Time limit for backing up a single volume, or 0 for no limit")
+
 	flags.BoolP(
 		"remove-volumes",
 		"",
 		viper.GetBool("WATCHTOWER_REMOVE_VOLUMES"),
 		"Remove attached volumes before updating")
 
+	flags.BoolP(
+		"cleanup-volumes",
+		"",
+		viper.GetBool("WATCHTOWER_CLEANUP_VOLUMES"),
+		"Remove anonymous volumes only used by the replaced containers after updating")
+
+	flags.BoolP(
+		"cleanup-volumes-dry-run",
+		"",
+		viper.GetBool("WATCHTOWER_CLEANUP_VOLUMES_DRY_RUN"),
+		"Log the anonymous volumes that would be removed by --cleanup-volumes, without removing them")
+
+	flags.IntP(
+		"update-retries",
+		"",
+		viper.GetInt("WATCHTOWER_UPDATE_RETRIES"),
+		"How many times to retry failed update checks and container recreations within a session")
+
+	flags.DurationP(
+		"update-retry-backoff",
+		"",
+		viper.GetDuration("WATCHTOWER_UPDATE_RETRY_BACKOFF"),
+		"The delay before retrying a failed update, which doubles with every further retry")
+
+	flags.IntP(
+		"update-retry-sessions",
+		"",
+		viper.GetInt("WATCHTOWER_UPDATE_RETRY_SESSIONS"),
+		"For how many consecutive sessions failed containers are reported as retrying before being reported as failed")
+
+	flags.IntP(
+		"quarantine-after",
+		"",
+		viper.GetInt("WATCHTOWER_QUARANTINE_AFTER"),
+		"Stop updating containers that failed during this many consecutive sessions, until they are released")
+
+	flags.StringP(
+		"min-free-space",
+		"",
+		viper.GetString("WATCHTOWER_MIN_FREE_SPACE"),
+		"Skip pulling new images that would leave less free disk space than this, like 2GB")
+
+	flags.StringP(
+		"disk-space-path",
+		"",
+		viper.GetString("WATCHTOWER_DISK_SPACE_PATH"),
+		"Where to check the free disk space for --min-free-space, defaulting to the Docker data root")
+
+	flags.StringP(
+		"registry-credentials-dir",
+		"",
+		viper.GetString("WATCHTOWER_REGISTRY_CREDENTIALS_DIR"),
+		"Directory containing the credential sets that containers may refer to using the registry-auth label")
+
 	flags.BoolP(
 		"label-enable",
 		"e",
 		viper.GetBool("WATCHTOWER_LABEL_ENABLE"),
 		"Watch containers where the com.centurylinklabs.watchtower.enable label is true")
 
+	flags.BoolP(
+		"label-disable",
+		"",
+		viper.GetBool("WATCHTOWER_LABEL_DISABLE"),
+		"Watch all containers except the ones where the com.centurylinklabs.watchtower.enable label is false, including the ones without a scope")
+
+	flags.StringP(
+		"filter-preset",
+		"",
+		viper.GetString("WATCHTOWER_FILTER_PRESET"),
+		"Name of the filter preset of the config file to combine with the other filters")
+
 	flags.BoolP(
 		"debug",
 		"d",
@@ -104,12 +314,228 @@ func RegisterSystemFlags(rootCmd *cobra.Command) {
 		viper.GetBool("WATCHTOWER_MONITOR_ONLY"),
 		"Will only monitor for new images, not update the containers")
 
+	flags.BoolP(
+		"stage-only",
+		"",
+		viper.GetBool("WATCHTOWER_STAGE_ONLY"),
+		"Will only pull the new images and stage them, leaving the containers running until the staged updates are applied")
+
+	flags.DurationP(
+		"min-image-age",
+		"",
+		viper.GetDuration("WATCHTOWER_MIN_IMAGE_AGE"),
+		"How long ago a new image has to have been created before the containers are updated to it")
+
+	flags.IntP(
+		"failure-log-lines",
+		"",
+		viper.GetInt("WATCHTOWER_FAILURE_LOG_LINES"),
+		"Report the updates of containers that stopped or became unhealthy once recreated as failed, including this many lines of their logs")
+
+	flags.BoolP(
+		"compose-redeploy",
+		"",
+		viper.GetBool("WATCHTOWER_COMPOSE_REDEPLOY"),
+		"Re-deploy the compose projects of updated containers created by docker compose, rather than recreating them")
+
+	flags.StringP(
+		"compose-binary",
+		"",
+		viper.GetString("WATCHTOWER_COMPOSE_BINARY"),
+		"Binary running docker compose, either the docker CLI or docker-compose")
+
+	flags.DurationP(
+		"compose-timeout",
+		"",
+		viper.GetDuration("WATCHTOWER_COMPOSE_TIMEOUT"),
+		"Time limit for re-deploying a compose project, or 0 for no limit")
+
+	flags.StringP(
+		"strategy-script",
+		"",
+		viper.GetString("WATCHTOWER_STRATEGY_SCRIPT"),
+		"File with an expression deciding whether to update each stale container")
+
+	flags.StringP(
+		"strategy-plugin",
+		"",
+		viper.GetString("WATCHTOWER_STRATEGY_PLUGIN"),
+		"Executable deciding whether to update each stale container, and optionally updating it, using JSON over stdin and stdout")
+
+	flags.DurationP(
+		"strategy-plugin-timeout",
+		"",
+		viper.GetDuration("WATCHTOWER_STRATEGY_PLUGIN_TIMEOUT"),
+		"Time limit for each run of the update strategy plugin, or 0 for no limit")
+
+	flags.BoolP(
+		"require-approval",
+		"",
+		viper.GetBool("WATCHTOWER_REQUIRE_APPROVAL"),
+		"Will only apply the updates once they have been approved using the HTTP API")
+
 	flags.BoolP(
 		"run-once",
 		"R",
 		viper.GetBool("WATCHTOWER_RUN_ONCE"),
 		"Run once now and exit")
 
+	flags.BoolP(
+		"fail-on-update",
+		"",
+		viper.GetBool("WATCHTOWER_FAIL_ON_UPDATE"),
+		"Exit with a non-zero code when running once if any container was updated or has a new image")
+
+	flags.StringP(
+		"metrics-push-url",
+		"",
+		viper.GetString("WATCHTOWER_METRICS_PUSH_URL"),
+		"The URL of a Pushgateway to push the metrics of the session to when running once")
+
+	flags.StringP(
+		"metrics-push-job",
+		"",
+		viper.GetString("WATCHTOWER_METRICS_PUSH_JOB"),
+		"The job name to push the metrics with")
+
+	flags.StringP(
+		"metrics-influx-url",
+		"",
+		viper.GetString("WATCHTOWER_METRICS_INFLUX_URL"),
+		"The URL of an InfluxDB write endpoint to write the metrics of each session to, using the line protocol")
+
+	flags.StringP(
+		"metrics-influx-token",
+		"",
+		viper.GetString("WATCHTOWER_METRICS_INFLUX_TOKEN"),
+		"The InfluxDB API token used to write the metrics")
+
+	flags.StringP(
+		"heartbeat-url",
+		"",
+		viper.GetString("WATCHTOWER_HEARTBEAT_URL"),
+		"The URL of a healthchecks.io style check to ping when the update sessions start, succeed and fail")
+
+	flags.StringP(
+		"mqtt-url",
+		"",
+		viper.GetString("WATCHTOWER_MQTT_URL"),
+		"The URL of an MQTT broker to publish the events and the status of the containers to, like mqtt://broker:1883")
+
+	flags.StringP(
+		"mqtt-topic-prefix",
+		"",
+		viper.GetString("WATCHTOWER_MQTT_TOPIC_PREFIX"),
+		"The prefix of the topics published to on the MQTT broker")
+
+	flags.IntP(
+		"mqtt-qos",
+		"",
+		viper.GetInt("WATCHTOWER_MQTT_QOS"),
+		"The QoS of the messages published to the MQTT broker, being 0, 1 or 2")
+
+	flags.StringP(
+		"mqtt-client-id",
+		"",
+		viper.GetString("WATCHTOWER_MQTT_CLIENT_ID"),
+		"The client ID used to connect to the MQTT broker, defaulting to watchtower-<hostname>")
+
+	flags.BoolP(
+		"mqtt-homeassistant",
+		"",
+		viper.GetBool("WATCHTOWER_MQTT_HOMEASSISTANT"),
+		"Publish the discovery payloads of Home Assistant, showing each container as an update entity")
+
+	flags.StringP(
+		"mqtt-homeassistant-prefix",
+		"",
+		viper.GetString("WATCHTOWER_MQTT_HOMEASSISTANT_PREFIX"),
+		"The discovery prefix of Home Assistant")
+
+	flags.StringSliceP(
+		"kafka-brokers",
+		"",
+		commaSeparated(viper.GetString("WATCHTOWER_KAFKA_BROKERS")),
+		"The addresses of the Kafka brokers to produce the events of the updates and of the sessions to, like kafka:9092")
+
+	flags.StringP(
+		"kafka-topic",
+		"",
+		viper.GetString("WATCHTOWER_KAFKA_TOPIC"),
+		"The Kafka topic the events are produced to")
+
+	flags.BoolP(
+		"kafka-tls",
+		"",
+		viper.GetBool("WATCHTOWER_KAFKA_TLS"),
+		"Connect to the Kafka brokers using TLS")
+
+	flags.BoolP(
+		"kafka-tls-skip-verify",
+		"",
+		viper.GetBool("WATCHTOWER_KAFKA_TLS_SKIP_VERIFY"),
+		"Do not verify the certificates of the Kafka brokers")
+
+	flags.StringP(
+		"kafka-sasl-mechanism",
+		"",
+		viper.GetString("WATCHTOWER_KAFKA_SASL_MECHANISM"),
+		"The SASL mechanism used to authenticate to the Kafka brokers, being PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")
+
+	flags.StringP(
+		"kafka-sasl-username",
+		"",
+		viper.GetString("WATCHTOWER_KAFKA_SASL_USERNAME"),
+		"The username used to authenticate to the Kafka brokers")
+
+	flags.StringP(
+		"kafka-sasl-password",
+		"",
+		viper.GetString("WATCHTOWER_KAFKA_SASL_PASSWORD"),
+		"The password used to authenticate to the Kafka brokers")
+
+	flags.StringP(
+		"nats-url",
+		"",
+		viper.GetString("WATCHTOWER_NATS_URL"),
+		"The URL of a NATS server to publish the events and the reports of the sessions to, like nats://server:4222")
+
+	flags.StringP(
+		"nats-subject-prefix",
+		"",
+		viper.GetString("WATCHTOWER_NATS_SUBJECT_PREFIX"),
+		"The prefix of the subjects published to on the NATS server")
+
+	flags.StringP(
+		"pre-session-command",
+		"",
+		viper.GetString("WATCHTOWER_PRE_SESSION_COMMAND"),
+		"Command to run inside the watchtower container before each update session, skipping the session if it fails")
+
+	flags.StringP(
+		"post-session-command",
+		"",
+		viper.GetString("WATCHTOWER_POST_SESSION_COMMAND"),
+		"Command to run inside the watchtower container after each update session, with a summary of it in the environment")
+
+	flags.StringP(
+		"report-command",
+		"",
+		viper.GetString("WATCHTOWER_REPORT_COMMAND"),
+		"Command to run inside the watchtower container after each update session, with its report as JSON on stdin")
+
+	flags.DurationP(
+		"session-command-timeout",
+		"",
+		viper.GetDuration("WATCHTOWER_SESSION_COMMAND_TIMEOUT"),
+		"Time limit for each of the session commands, or 0 for no limit")
+
+	flags.BoolP(
+		"show-schedule",
+		"",
+		viper.GetBool("WATCHTOWER_SHOW_SCHEDULE"),
+		"Print the next times the scheduled sessions will run and exit")
+
 	flags.BoolP(
 		"include-restarting",
 		"",
@@ -128,6 +554,24 @@ func RegisterSystemFlags(rootCmd *cobra.Command) {
 		viper.GetBool("WATCHTOWER_REVIVE_STOPPED"),
 		"Will also start stopped containers that were updated, if include-stopped is active")
 
+	flags.BoolP(
+		"ignore-oneshot",
+		"",
+		viper.GetBool("WATCHTOWER_IGNORE_ONESHOT"),
+		"Leave out the exited containers with the restart policy no, even if include-stopped is active")
+
+	flags.BoolP(
+		"watch-events",
+		"",
+		viper.GetBool("WATCHTOWER_WATCH_EVENTS"),
+		"Watch the docker events to discover new containers as soon as they start")
+
+	flags.BoolP(
+		"check-new-containers",
+		"",
+		viper.GetBool("WATCHTOWER_CHECK_NEW_CONTAINERS"),
+		"Run an update session for each of the containers discovered by watch-events right away")
+
 	flags.BoolP(
 		"enable-lifecycle-hooks",
 		"",
@@ -140,6 +584,12 @@ func RegisterSystemFlags(rootCmd *cobra.Command) {
 		viper.GetBool("WATCHTOWER_ROLLING_RESTART"),
 		"Restart containers one at a time")
 
+	flags.BoolP(
+		"transactional-groups",
+		"",
+		viper.GetBool("WATCHTOWER_TRANSACTIONAL_GROUPS"),
+		"Update the containers of each group, set by label or compose project, all or nothing, rolling back failed groups")
+
 	flags.BoolP(
 		"http-api-update",
 		"",
@@ -151,18 +601,89 @@ func RegisterSystemFlags(rootCmd *cobra.Command) {
 		viper.GetBool("WATCHTOWER_HTTP_API_METRICS"),
 		"Runs Watchtower with the Prometheus metrics API enabled")
 
+	flags.StringP(
+		"http-api-metrics-token",
+		"",
+		viper.GetString("WATCHTOWER_HTTP_API_METRICS_TOKEN"),
+		"Sets an authentication token to the metrics API requests, rather than the HTTP API token")
+
+	flags.BoolP(
+		"http-api-metrics-no-auth",
+		"",
+		viper.GetBool("WATCHTOWER_HTTP_API_METRICS_NO_AUTH"),
+		"Serves the metrics API without authentication")
+	flags.BoolP(
+		"http-api-dashboard",
+		"",
+		viper.GetBool("WATCHTOWER_HTTP_API_DASHBOARD"),
+		"Serves a web dashboard showing the containers and recent sessions at /dashboard")
+
 	flags.StringP(
 		"http-api-token",
 		"",
 		viper.GetString("WATCHTOWER_HTTP_API_TOKEN"),
 		"Sets an authentication token to HTTP API requests.")
 
+	flags.StringP(
+		"http-api-username",
+		"",
+		viper.GetString("WATCHTOWER_HTTP_API_USERNAME"),
+		"Accepts HTTP API requests using basic authentication with this username, as an alternative to the token")
+
+	flags.StringP(
+		"http-api-password",
+		"",
+		viper.GetString("WATCHTOWER_HTTP_API_PASSWORD"),
+		"The password of the HTTP API basic authentication")
+
+	flags.StringP(
+		"http-api-password-file",
+		"",
+		viper.GetString("WATCHTOWER_HTTP_API_PASSWORD_FILE"),
+		"A file containing the password of the HTTP API basic authentication")
+
+	flags.StringP(
+		"http-api-public-url",
+		"",
+		viper.GetString("WATCHTOWER_HTTP_API_PUBLIC_URL"),
+		"The URL the HTTP API is reachable at, used for the approval links in the notifications")
+
 	flags.BoolP(
 		"http-api-periodic-polls",
 		"",
 		viper.GetBool("WATCHTOWER_HTTP_API_PERIODIC_POLLS"),
 		"Also run periodic updates (specified with --interval and --schedule) if HTTP API is enabled")
 
+	flags.IntP(
+		"http-api-rate-limit",
+		"",
+		viper.GetInt("WATCHTOWER_HTTP_API_RATE_LIMIT"),
+		"The number of HTTP API requests each source can send per minute, or 0 for no limit")
+
+	flags.IntP(
+		"http-api-lockout-attempts",
+		"",
+		viper.GetInt("WATCHTOWER_HTTP_API_LOCKOUT_ATTEMPTS"),
+		"Lock out the sources of the HTTP API requests after this many invalid tokens, or 0 to never lock them out")
+
+	flags.DurationP(
+		"http-api-lockout-duration",
+		"",
+		viper.GetDuration("WATCHTOWER_HTTP_API_LOCKOUT_DURATION"),
+		"How long a source is locked out of the HTTP API the first time, doubling with each further lockout")
+
+	flags.BoolP(
+		"http-api-trust-proxy",
+		"",
+		viper.GetBool("WATCHTOWER_HTTP_API_TRUST_PROXY"),
+		"Identify the sources of the HTTP API requests by the X-Forwarded-For header set by a reverse proxy")
+
+	flags.StringSliceP(
+		"http-api-cors-origin",
+		"",
+		commaSeparated(viper.GetString("WATCHTOWER_HTTP_API_CORS_ORIGIN")),
+		"Allow pages served from this origin, like https://dashboard.example.com, to call the HTTP API, or * for any. May be repeated")
+
 	// https://no-color.org/
 	flags.BoolP(
 		"no-color",
@@ -170,11 +691,148 @@ func RegisterSystemFlags(rootCmd *cobra.Command) {
 		viper.IsSet("NO_COLOR"),
 		"Disable ANSI color escape codes in log output")
 
+	flags.StringP(
+		"log-level",
+		"",
+		viper.GetString("WATCHTOWER_LOG_LEVEL"),
+		"The log level, optionally per subsystem, like registry=debug,container=info,notifications=warn")
+
+	flags.StringArray(
+		"log-output",
+		viper.GetStringSlice("WATCHTOWER_LOG_OUTPUT"),
+		"Additionally ship the logs to a syslog server or fluentd, e.g. syslog://host:514 or fluentd://host:24224")
+
+	flags.StringP(
+		"log-file",
+		"",
+		viper.GetString("WATCHTOWER_LOG_FILE"),
+		"Additionally write the logs to this file, rotating it by size and age")
+
+	flags.StringP(
+		"log-file-max-size",
+		"",
+		viper.GetString("WATCHTOWER_LOG_FILE_MAX_SIZE"),
+		"Rotate the log file once it would exceed this size, like 10MB")
+
+	flags.DurationP(
+		"log-file-max-age",
+		"",
+		viper.GetDuration("WATCHTOWER_LOG_FILE_MAX_AGE"),
+		"Rotate the log file once it has been written to for this long, like 24h")
+
+	flags.IntP(
+		"log-file-max-backups",
+		"",
+		viper.GetInt("WATCHTOWER_LOG_FILE_MAX_BACKUPS"),
+		"The number of rotated log files to keep")
+
+	flags.StringP(
+		"event-log",
+		"",
+		viper.GetString("WATCHTOWER_EVENT_LOG"),
+		"Write a structured event for every action taken to this file, as JSON lines")
+
+	flags.StringP(
+		"event-log-max-size",
+		"",
+		viper.GetString("WATCHTOWER_EVENT_LOG_MAX_SIZE"),
+		"Rotate the event log once it would exceed this size, like 10MB")
+
+	flags.IntP(
+		"event-log-max-files",
+		"",
+		viper.GetInt("WATCHTOWER_EVENT_LOG_MAX_FILES"),
+		"The number of rotated event log files to keep")
+
 	flags.StringP(
 		"scope",
 		"",
 		viper.GetString("WATCHTOWER_SCOPE"),
-		"Defines a monitoring scope for the Watchtower instance.")
+		"Defines a monitoring scope (or a comma-separated list of scopes) for the Watchtower instance.")
+
+	flags.StringP(
+		"leader-election",
+		"",
+		viper.GetString("WATCHTOWER_LEADER_ELECTION"),
+		"Elect a leader among the instances sharing the docker host, which runs the sessions. Possible values: label or file")
+
+	flags.StringP(
+		"leader-lease-file",
+		"",
+		viper.GetString("WATCHTOWER_LEADER_LEASE_FILE"),
+		"The path of the lease file shared by the instances, when electing the leader using a file")
+
+	flags.DurationP(
+		"leader-lease-duration",
+		"",
+		viper.GetDuration("WATCHTOWER_LEADER_LEASE_DURATION"),
+		"How long the lease of the leader is valid without being renewed, when electing the leader using a file")
+
+	flags.StringP(
+		"session-lock",
+		"",
+		viper.GetString("WATCHTOWER_SESSION_LOCK"),
+		"The URL of a redis or etcd server holding a lock that serializes the update sessions of the instances using it")
+
+	flags.DurationP(
+		"session-lock-timeout",
+		"",
+		viper.GetDuration("WATCHTOWER_SESSION_LOCK_TIMEOUT"),
+		"How long to wait for the session lock held by another instance before skipping the session")
+
+	flags.StringP(
+		"registry-record",
+		"",
+		viper.GetString("WATCHTOWER_REGISTRY_RECORD"),
+		"Record the requests made to registries as fixtures in the given directory")
+
+	flags.StringP(
+		"registry-replay",
+		"",
+		viper.GetString("WATCHTOWER_REGISTRY_REPLAY"),
+		"Answer the requests made to registries using the fixtures recorded in the given directory")
+
+	flags.DurationP(
+		"registry-dns-ttl",
+		"",
+		viper.GetDuration("WATCHTOWER_REGISTRY_DNS_TTL"),
+		"How long to cache the DNS lookups of registry hosts, 0 to not cache them")
+
+	flags.StringP(
+		"registry-proxy",
+		"",
+		viper.GetString("WATCHTOWER_REGISTRY_PROXY"),
+		"The URL of the proxy to send the requests made directly to registries through, instead of HTTPS_PROXY")
+
+	flags.StringP(
+		"registry-no-proxy",
+		"",
+		viper.GetString("WATCHTOWER_REGISTRY_NO_PROXY"),
+		"The registry hosts, domains and networks to reach without the proxy, instead of NO_PROXY")
+
+	flags.DurationP(
+		"registry-connect-timeout",
+		"",
+		viper.GetDuration("WATCHTOWER_REGISTRY_CONNECT_TIMEOUT"),
+		"How long to wait for the connection to a registry, including the TLS handshake")
+
+	flags.DurationP(
+		"registry-read-timeout",
+		"",
+		viper.GetDuration("WATCHTOWER_REGISTRY_READ_TIMEOUT"),
+		"How long to wait for the response of a registry after sending a request, 0 to wait indefinitely")
+
+	flags.DurationP(
+		"registry-request-timeout",
+		"",
+		viper.GetDuration("WATCHTOWER_REGISTRY_REQUEST_TIMEOUT"),
+		"The deadline of each request made to a registry, including its retries, 0 for none")
+
+	flags.IntP(
+		"registry-max-retries",
+		"",
+		viper.GetInt("WATCHTOWER_REGISTRY_MAX_RETRIES"),
+		"How many times to retry a registry request that failed to connect, timed out or got a server error")
 
 	flags.StringP(
 		"porcelain",
@@ -266,6 +924,48 @@ Should only be used for testing.`)
 		viper.GetString("WATCHTOWER_NOTIFICATION_EMAIL_SUBJECTTAG"),
 		"Subject prefix tag for notifications via mail")
 
+	flags.StringP(
+		"notification-email-html-template",
+		"",
+		viper.GetString("WATCHTOWER_NOTIFICATION_EMAIL_HTML_TEMPLATE"),
+		"The html/template, or the name of a template of the config file, for an HTML alternative to the text of the emails")
+
+	flags.BoolP(
+		"notification-email-attach-report",
+		"",
+		viper.GetBool("WATCHTOWER_NOTIFICATION_EMAIL_ATTACH_REPORT"),
+		"Attach the report of the session to the notification emails as a JSON file")
+
+	flags.StringP(
+		"notification-email-oauth2-token-url",
+		"",
+		viper.GetString("WATCHTOWER_NOTIFICATION_EMAIL_OAUTH2_TOKEN_URL"),
+		"The token endpoint of the OAuth2 provider, authenticating with the SMTP server using XOAUTH2 instead of the password")
+
+	flags.StringP(
+		"notification-email-oauth2-client-id",
+		"",
+		viper.GetString("WATCHTOWER_NOTIFICATION_EMAIL_OAUTH2_CLIENT_ID"),
+		"The ID of the OAuth2 client requesting the access tokens for the SMTP server")
+
+	flags.StringP(
+		"notification-email-oauth2-client-secret",
+		"",
+		viper.GetString("WATCHTOWER_NOTIFICATION_EMAIL_OAUTH2_CLIENT_SECRET"),
+		"The secret of the OAuth2 client requesting the access tokens for the SMTP server")
+
+	flags.StringP(
+		"notification-email-oauth2-refresh-token",
+		"",
+		viper.GetString("WATCHTOWER_NOTIFICATION_EMAIL_OAUTH2_REFRESH_TOKEN"),
+		"The refresh token exchanged for the access tokens, instead of using the client credentials flow")
+
+	flags.StringP(
+		"notification-email-oauth2-scope",
+		"",
+		viper.GetString("WATCHTOWER_NOTIFICATION_EMAIL_OAUTH2_SCOPE"),
+		"The scope of the access tokens for the SMTP server")
+
 	flags.StringP(
 		"notification-slack-hook-url",
 		"",
@@ -332,6 +1032,11 @@ Should only be used for testing.`)
 		viper.GetString("WATCHTOWER_NOTIFICATION_TEMPLATE"),
 		"The shoutrrr text/template for the messages")
 
+	flags.String(
+		"notification-template-file",
+		viper.GetString("WATCHTOWER_NOTIFICATION_TEMPLATE_FILE"),
+		"A file or http(s) URL to read the shoutrrr text/template for the messages from, reloading it once it changes")
+
 	flags.StringArray(
 		"notification-url",
 		viper.GetStringSlice("WATCHTOWER_NOTIFICATION_URL"),
@@ -341,6 +1046,20 @@ Should only be used for testing.`)
 		viper.GetBool("WATCHTOWER_NOTIFICATION_REPORT"),
 		"Use the session report as the notification template data")
 
+	flags.Bool("notification-update-available",
+		viper.GetBool("WATCHTOWER_NOTIFICATION_UPDATE_AVAILABLE"),
+		"Also notify when an update is found for the first time, ahead of the report of the session applying it")
+
+	flags.StringArray(
+		"notification-watchdog-url",
+		viper.GetStringSlice("WATCHTOWER_NOTIFICATION_WATCHDOG_URL"),
+		"The shoutrrr URL to alert when the scheduled update sessions stop completing")
+
+	flags.Float64(
+		"notification-watchdog-grace",
+		viper.GetFloat64("WATCHTOWER_NOTIFICATION_WATCHDOG_GRACE"),
+		"How many intervals of the schedule may pass without a completed session before alerting the watchdog URLs")
+
 	flags.StringP(
 		"notification-title-tag",
 		"",
@@ -351,11 +1070,31 @@ Should only be used for testing.`)
 		viper.GetBool("WATCHTOWER_NOTIFICATION_SKIP_TITLE"),
 		"Do not pass the title param to notifications")
 
+	flags.Bool(
+		"notification-release-links",
+		viper.GetBool("WATCHTOWER_NOTIFICATION_RELEASE_LINKS"),
+		"Include links to the releases of the new images in the report, using their OCI source labels")
+
+	flags.Bool(
+		"notification-release-notes",
+		viper.GetBool("WATCHTOWER_NOTIFICATION_RELEASE_NOTES"),
+		"Include the notes of the GitHub releases of the new images in the report. Implies notification-release-links")
+
+	flags.String(
+		"notification-github-token",
+		viper.GetString("WATCHTOWER_NOTIFICATION_GITHUB_TOKEN"),
+		"The GitHub token used to retrieve the release notes")
+
 	flags.String(
 		"warn-on-head-failure",
 		viper.GetString("WATCHTOWER_WARN_ON_HEAD_FAILURE"),
 		"When to warn about HEAD pull requests failing. Possible values: always, auto or never")
 
+	flags.String(
+		"missing-image-info",
+		viper.GetString("WATCHTOWER_MISSING_IMAGE_INFO"),
+		"What to do with containers whose image is no longer available locally. Possible values: skip, pull or update")
+
 	flags.Bool(
 		"notification-log-stdout",
 		viper.GetBool("WATCHTOWER_NOTIFICATION_LOG_STDOUT"),
@@ -366,10 +1105,36 @@ Should only be used for testing.`)
 func SetDefaults() {
 	viper.AutomaticEnv()
 	viper.SetDefault("DOCKER_HOST", "unix:///var/run/docker.sock")
-	viper.SetDefault("DOCKER_API_VERSION", DockerAPIMinVersion)
 	viper.SetDefault("WATCHTOWER_POLL_INTERVAL", defaultInterval)
 	viper.SetDefault("WATCHTOWER_TIMEOUT", time.Second*10)
+	viper.SetDefault("WATCHTOWER_SELF_UPDATE_TIMEOUT", time.Minute)
+	viper.SetDefault("WATCHTOWER_CHECK_FAILURE_THRESHOLD", 1)
+	viper.SetDefault("WATCHTOWER_UPDATE_RETRY_BACKOFF", time.Second*10)
+	viper.SetDefault("WATCHTOWER_REGISTRY_DNS_TTL", time.Minute)
+	viper.SetDefault("WATCHTOWER_REGISTRY_CONNECT_TIMEOUT", 30*time.Second)
+	viper.SetDefault("WATCHTOWER_HEALTH_START_PERIOD_MULTIPLIER", 1.0)
+	viper.SetDefault("WATCHTOWER_MISSING_IMAGE_INFO", "skip")
+	viper.SetDefault("WATCHTOWER_EVENT_LOG_MAX_SIZE", "10MB")
+	viper.SetDefault("WATCHTOWER_EVENT_LOG_MAX_FILES", 5)
+	viper.SetDefault("WATCHTOWER_LOG_FILE_MAX_SIZE", "10MB")
+	viper.SetDefault("WATCHTOWER_LOG_FILE_MAX_BACKUPS", 5)
+	viper.SetDefault("WATCHTOWER_VOLUME_BACKUP_TIMEOUT", 10*time.Minute)
+	viper.SetDefault("WATCHTOWER_HTTP_API_LOCKOUT_ATTEMPTS", 5)
+	viper.SetDefault("WATCHTOWER_HTTP_API_LOCKOUT_DURATION", time.Minute)
+	viper.SetDefault("WATCHTOWER_COMPOSE_BINARY", "docker")
+	viper.SetDefault("WATCHTOWER_COMPOSE_TIMEOUT", 10*time.Minute)
+	viper.SetDefault("WATCHTOWER_STRATEGY_PLUGIN_TIMEOUT", 10*time.Minute)
+	viper.SetDefault("WATCHTOWER_METRICS_PUSH_JOB", "watchtower")
+	viper.SetDefault("WATCHTOWER_MQTT_TOPIC_PREFIX", "watchtower")
+	viper.SetDefault("WATCHTOWER_MQTT_HOMEASSISTANT_PREFIX", "homeassistant")
+	viper.SetDefault("WATCHTOWER_KAFKA_TOPIC", "watchtower")
+	viper.SetDefault("WATCHTOWER_KAFKA_SASL_MECHANISM", "PLAIN")
+	viper.SetDefault("WATCHTOWER_NATS_SUBJECT_PREFIX", "watchtower")
+	viper.SetDefault("WATCHTOWER_LEADER_LEASE_DURATION", time.Hour)
+	viper.SetDefault("WATCHTOWER_SESSION_LOCK_TIMEOUT", 30*time.Minute)
+	viper.SetDefault("WATCHTOWER_SESSION_COMMAND_TIMEOUT", 5*time.Minute)
 	viper.SetDefault("WATCHTOWER_NOTIFICATIONS", []string{})
+	viper.SetDefault("WATCHTOWER_NOTIFICATION_WATCHDOG_GRACE", 2.0)
 	viper.SetDefault("WATCHTOWER_NOTIFICATIONS_LEVEL", "info")
 	viper.SetDefault("WATCHTOWER_NOTIFICATION_EMAIL_SERVER_PORT", 25)
 	viper.SetDefault("WATCHTOWER_NOTIFICATION_EMAIL_SUBJECTTAG", "")
@@ -377,7 +1142,9 @@ func SetDefaults() {
 }
 
 // EnvConfig translates the command-line options into environment variables
-// that will initialize the api client
+// that will initialize the api client. Hosts referring to a context of the docker CLI, like context://my-remote,
+// are resolved to the endpoint and TLS material of the context. Without a host, the sockets of rootless docker and
+// podman are used when the default socket does not exist.
 func EnvConfig(cmd *cobra.Command) error {
 	var err error
 	var host string
@@ -395,6 +1162,22 @@ func EnvConfig(cmd *cobra.Command) error {
 	if version, err = flags.GetString("api-version"); err != nil {
 		return err
 	}
+	if !flags.Changed("host") && os.Getenv("DOCKER_HOST") == "" {
+		host = discoverDockerSocket()
+	}
+	if strings.HasPrefix(host, contextScheme) {
+		resolved, err := resolveDockerContext(strings.TrimPrefix(host, contextScheme))
+		if err != nil {
+			return err
+		}
+		host = resolved.Host
+		if resolved.CertPath != "" {
+			if err = setEnvOptStr("DOCKER_CERT_PATH", resolved.CertPath); err != nil {
+				return err
+			}
+			tls = tls || !resolved.SkipTLSVerify
+		}
+	}
 	if err = setEnvOptStr("DOCKER_HOST", host); err != nil {
 		return err
 	}
@@ -456,15 +1239,10 @@ func setEnvOptBool(env string, opt bool) error {
 func GetSecretsFromFiles(rootCmd *cobra.Command) {
 	flags := rootCmd.PersistentFlags()
 
-	secrets := []string{
-		"notification-email-server-password",
-		"notification-slack-hook-url",
-		"notification-msteams-hook",
-		"notification-gotify-token",
-		"notification-url",
-	}
-	for _, secret := range secrets {
-		getSecretFromFile(flags, secret)
+	for _, secret := range append(secretFlags, "vault-token") {
+		if flags.Lookup(secret) != nil {
+			getSecretFromFile(flags, secret)
+		}
 	}
 }
 
@@ -588,3 +1366,14 @@ func setFlagIfDefault(flags *pflag.FlagSet, name string, value string) {
 		log.Errorf(`Failed to set flag: %v`, err)
 	}
 }
+
+// commaSeparated splits the comma separated values of an environment variable, which viper would split on whitespace
+func commaSeparated(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
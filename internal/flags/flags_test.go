@@ -1,9 +1,14 @@
 package flags
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -46,6 +51,70 @@ func TestEnvConfig_Custom(t *testing.T) {
 	// assert.Equal(t, "1.99", os.Getenv("DOCKER_API_VERSION"))
 }
 
+func TestEnvConfig_DockerContext(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+	t.Setenv("DOCKER_CERT_PATH", "")
+
+	// the ID of a context is the SHA-256 digest of its name
+	id := fmt.Sprintf("%x", sha256.Sum256([]byte("my-remote")))
+	metaDir := filepath.Join(configDir, "contexts", "meta", id)
+	tlsDir := filepath.Join(configDir, "contexts", "tls", id, "docker")
+	require.NoError(t, os.MkdirAll(metaDir, 0o755))
+	require.NoError(t, os.MkdirAll(tlsDir, 0o755))
+	meta := `{"Name":"my-remote","Endpoints":{"docker":{"Host":"tcp://remote:2376","SkipTLSVerify":false}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(metaDir, "meta.json"), []byte(meta), 0o644))
+	for _, file := range []string{"ca.pem", "cert.pem", "key.pem"} {
+		require.NoError(t, os.WriteFile(filepath.Join(tlsDir, file), []byte("pem"), 0o600))
+	}
+
+	cmd := new(cobra.Command)
+	SetDefaults()
+	RegisterDockerFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--host", "context://my-remote"}))
+	require.NoError(t, EnvConfig(cmd))
+
+	assert.Equal(t, "tcp://remote:2376", os.Getenv("DOCKER_HOST"))
+	assert.Equal(t, "1", os.Getenv("DOCKER_TLS_VERIFY"))
+	assert.Equal(t, tlsDir, os.Getenv("DOCKER_CERT_PATH"))
+
+	cmd = new(cobra.Command)
+	RegisterDockerFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--host", "context://missing"}))
+	assert.Error(t, EnvConfig(cmd))
+}
+
+func TestEnvConfig_RootlessSocket(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+	defaultSocket := defaultDockerSocket
+	defaultDockerSocket = filepath.Join(runtimeDir, "missing.sock")
+	defer func() { defaultDockerSocket = defaultSocket }()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(runtimeDir, "podman"), 0o755))
+	socket := filepath.Join(runtimeDir, "podman", "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	cmd := new(cobra.Command)
+	SetDefaults()
+	RegisterDockerFlags(cmd)
+	require.NoError(t, EnvConfig(cmd))
+	assert.Equal(t, "unix://"+socket, os.Getenv("DOCKER_HOST"))
+
+	// an explicitly set host is used even if it does not exist
+	cmd = new(cobra.Command)
+	RegisterDockerFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--host", "unix:///var/run/docker.sock"}))
+	require.NoError(t, EnvConfig(cmd))
+	assert.Equal(t, "unix:///var/run/docker.sock", os.Getenv("DOCKER_HOST"))
+}
+
 func TestGetSecretsFromFilesWithString(t *testing.T) {
 	value := "supersecretstring"
 
@@ -216,3 +285,87 @@ func TestProcessFlagAliasesInvalidPorcelaineVersion(t *testing.T) {
 		ProcessFlagAliases(flags)
 	})
 }
+
+func TestReadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchtower.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+registries:
+  harbor.example.com:8443:
+    timeout: 2m
+    insecure: true
+    ca_bundle: /etc/ssl/harbor.pem
+  docker.io:
+    username: user
+    password: secret
+    mirror: https://mirror.example.com
+    rate_limit: 100
+`), 0600))
+
+	cmd := new(cobra.Command)
+	SetDefaults()
+	RegisterSystemFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--config-file", path}))
+
+	config, err := ReadConfigFile(cmd.PersistentFlags())
+	require.NoError(t, err)
+	require.Len(t, config.Registries, 2)
+
+	harbor := config.Registries["harbor.example.com:8443"]
+	assert.Equal(t, 2*time.Minute, harbor.Timeout)
+	assert.True(t, harbor.Insecure)
+	assert.Equal(t, "/etc/ssl/harbor.pem", harbor.CABundle)
+
+	hub := config.Registries["docker.io"]
+	assert.Equal(t, "user", hub.Username)
+	assert.Equal(t, "https://mirror.example.com", hub.Mirror)
+	assert.Equal(t, 100, hub.RateLimit)
+}
+
+func TestReadConfigFileFilterPresets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchtower.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+filter_presets:
+  prod:
+    names: ["web-*", "!web-canary"]
+    label_disable: true
+    scope: prod
+    projects: [shop]
+`), 0600))
+
+	cmd := new(cobra.Command)
+	SetDefaults()
+	RegisterSystemFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--config-file", path}))
+
+	config, err := ReadConfigFile(cmd.PersistentFlags())
+	require.NoError(t, err)
+	require.Contains(t, config.FilterPresets, "prod")
+
+	prod := config.FilterPresets["prod"]
+	assert.Equal(t, []string{"web-*", "!web-canary"}, prod.Names)
+	assert.True(t, prod.LabelDisable)
+	assert.False(t, prod.LabelEnable)
+	assert.Equal(t, "prod", prod.Scope)
+	assert.Equal(t, []string{"shop"}, prod.Projects)
+}
+
+func TestReadConfigFileWithoutFile(t *testing.T) {
+	cmd := new(cobra.Command)
+	SetDefaults()
+	RegisterSystemFlags(cmd)
+
+	config, err := ReadConfigFile(cmd.PersistentFlags())
+	require.NoError(t, err)
+	assert.Empty(t, config.Registries)
+}
+
+func TestNoUpdateOnFromEnvironment(t *testing.T) {
+	t.Setenv("WATCHTOWER_NO_UPDATE_ON", "Dec 24-Jan 2, last Friday of month")
+	cmd := new(cobra.Command)
+	SetDefaults()
+	RegisterSystemFlags(cmd)
+
+	days, err := cmd.PersistentFlags().GetStringSlice("no-update-on")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Dec 24-Jan 2", "last Friday of month"}, days)
+}
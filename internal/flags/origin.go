@@ -0,0 +1,139 @@
+package flags
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Origin describes where the effective value of a setting came from
+type Origin string
+
+const (
+	// DefaultOrigin is used for settings that have not been configured
+	DefaultOrigin Origin = "default"
+	// FlagOrigin is used for settings passed as command line arguments
+	FlagOrigin Origin = "flag"
+	// EnvOrigin is used for settings read from environment variables
+	EnvOrigin Origin = "env"
+	// FileOrigin is used for secrets read from files
+	FileOrigin Origin = "file"
+)
+
+// maskedValue replaces the values of the secret settings
+const maskedValue = "********"
+
+// maskedFlags are the secret flags, in addition to the secretFlags, whose values are never printed
+var maskedFlags = []string{"http-api-token", "http-api-metrics-token", "vault-token", "metrics-push-url"}
+
+// envVarExceptions are the environment variables of the flags that are not named after the flag itself
+var envVarExceptions = map[string]string{
+	"host":                      "DOCKER_HOST",
+	"tlsverify":                 "DOCKER_TLS_VERIFY",
+	"api-version":               "DOCKER_API_VERSION",
+	"interval":                  "WATCHTOWER_POLL_INTERVAL",
+	"stop-timeout":              "WATCHTOWER_TIMEOUT",
+	"enable-lifecycle-hooks":    "WATCHTOWER_LIFECYCLE_HOOKS",
+	"no-color":                  "NO_COLOR",
+	"notification-msteams-hook": "WATCHTOWER_NOTIFICATION_MSTEAMS_HOOK_URL",
+	"notification-msteams-data": "WATCHTOWER_NOTIFICATION_MSTEAMS_USE_LOG_DATA",
+}
+
+// Setting is the effective value of a flag, along with where it came from
+type Setting struct {
+	Name   string
+	Value  string
+	Origin Origin
+	// Source is the environment variable or file the value was read from, if any
+	Source string
+	Secret bool
+}
+
+// Settings are the effective values of all the flags of a command
+type Settings []Setting
+
+// EnvVarName returns the name of the environment variable the default value of the flag is read from
+func EnvVarName(flag string) string {
+	if name, found := envVarExceptions[flag]; found {
+		return name
+	}
+	return "WATCHTOWER_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// IsSecret returns whether the flag holds a password, token or webhook that should not be printed
+func IsSecret(flag string) bool {
+	for _, name := range append(maskedFlags, secretFlags...) {
+		if name == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordSettings returns the values of the flags and where they came from. Since the flag aliases and the secret
+// files replace the values of the flags, the settings must be recorded before those are processed, and refreshed after.
+func RecordSettings(flags *pflag.FlagSet) Settings {
+	var settings Settings
+	flags.VisitAll(func(flag *pflag.Flag) {
+		setting := Setting{
+			Name:   flag.Name,
+			Origin: DefaultOrigin,
+			Secret: IsSecret(flag.Name),
+		}
+		envVar := EnvVarName(flag.Name)
+		if flag.Changed {
+			setting.Origin = FlagOrigin
+		} else if _, found := os.LookupEnv(envVar); found {
+			setting.Origin = EnvOrigin
+			setting.Source = envVar
+		}
+		if setting.Secret {
+			for _, value := range flagValues(flag) {
+				if value != "" && isFile(value) {
+					setting.Origin = FileOrigin
+					setting.Source = value
+					break
+				}
+			}
+		}
+		setting.Value = settingValue(flag, setting.Secret)
+		settings = append(settings, setting)
+	})
+	return settings
+}
+
+// Refresh updates the values of the settings to the current values of the flags, keeping their origins
+func (settings Settings) Refresh(flags *pflag.FlagSet) {
+	for i, setting := range settings {
+		if flag := flags.Lookup(setting.Name); flag != nil {
+			settings[i].Value = settingValue(flag, setting.Secret)
+		}
+	}
+}
+
+// settingValue returns the value of the flag for printing, masking it if the flag is a secret
+func settingValue(flag *pflag.Flag, secret bool) string {
+	if !secret {
+		return flag.Value.String()
+	}
+	if _, ok := flag.Value.(pflag.SliceValue); ok {
+		values := flagValues(flag)
+		for i := range values {
+			values[i] = maskedValue
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	}
+	if flag.Value.String() == "" {
+		return ""
+	}
+	return maskedValue
+}
+
+// flagValues returns the values of a slice flag, or the single value of any other flag
+func flagValues(flag *pflag.Flag) []string {
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		return sliceValue.GetSlice()
+	}
+	return []string{flag.Value.String()}
+}
@@ -0,0 +1,86 @@
+package flags
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func findSetting(t *testing.T, settings Settings, name string) Setting {
+	for _, setting := range settings {
+		if setting.Name == name {
+			return setting
+		}
+	}
+	require.Failf(t, "missing setting", "no setting named %s", name)
+	return Setting{}
+}
+
+func TestEnvVarName(t *testing.T) {
+	assert.Equal(t, "WATCHTOWER_ROLLING_RESTART", EnvVarName("rolling-restart"))
+	assert.Equal(t, "WATCHTOWER_POLL_INTERVAL", EnvVarName("interval"))
+	assert.Equal(t, "DOCKER_HOST", EnvVarName("host"))
+}
+
+func TestRecordSettings(t *testing.T) {
+	require.NoError(t, os.Setenv("WATCHTOWER_CLEANUP", "true"))
+	defer os.Unsetenv("WATCHTOWER_CLEANUP")
+
+	file, err := ioutil.TempFile(os.TempDir(), "watchtower-")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("hunter2")
+	require.NoError(t, err)
+	file.Close()
+
+	cmd := new(cobra.Command)
+	SetDefaults()
+	RegisterDockerFlags(cmd)
+	RegisterSystemFlags(cmd)
+	RegisterNotificationFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{
+		"--rolling-restart",
+		"--notification-gotify-token", "abc",
+		"--notification-email-server-password", file.Name(),
+		"--http-api-metrics-token", "m3tr1cs",
+	}))
+
+	settings := RecordSettings(cmd.PersistentFlags())
+
+	assert.Equal(t, Setting{Name: "rolling-restart", Value: "true", Origin: FlagOrigin},
+		findSetting(t, settings, "rolling-restart"))
+	assert.Equal(t, Setting{Name: "cleanup", Value: "true", Origin: EnvOrigin, Source: "WATCHTOWER_CLEANUP"},
+		findSetting(t, settings, "cleanup"))
+	assert.Equal(t, Setting{Name: "monitor-only", Value: "false", Origin: DefaultOrigin},
+		findSetting(t, settings, "monitor-only"))
+	assert.Equal(t, Setting{Name: "notification-gotify-token", Value: "********", Origin: FlagOrigin, Secret: true},
+		findSetting(t, settings, "notification-gotify-token"))
+	assert.Equal(t, Setting{Name: "notification-email-server-password", Value: "********", Origin: FileOrigin,
+		Source: file.Name(), Secret: true}, findSetting(t, settings, "notification-email-server-password"))
+	assert.Equal(t, "", findSetting(t, settings, "http-api-token").Value)
+	assert.Equal(t, Setting{Name: "http-api-metrics-token", Value: "********", Origin: FlagOrigin, Secret: true},
+		findSetting(t, settings, "http-api-metrics-token"))
+}
+
+func TestRefreshSettings(t *testing.T) {
+	cmd := new(cobra.Command)
+	SetDefaults()
+	RegisterDockerFlags(cmd)
+	RegisterSystemFlags(cmd)
+	RegisterNotificationFlags(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--interval", "30", "--notification-url", "logger://"}))
+
+	flags := cmd.PersistentFlags()
+	settings := RecordSettings(flags)
+	ProcessFlagAliases(flags)
+	settings.Refresh(flags)
+
+	schedule := findSetting(t, settings, "schedule")
+	assert.Equal(t, "@every 30s", schedule.Value)
+	assert.Equal(t, DefaultOrigin, schedule.Origin)
+	assert.Equal(t, "[********]", findSetting(t, settings, "notification-url").Value)
+}
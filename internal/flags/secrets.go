@@ -0,0 +1,181 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// secretFlags are the flags holding passwords, tokens and webhooks, which may be passed as a file or as a reference
+// to a secret provider instead of as plaintext
+var secretFlags = []string{
+	"notification-email-server-password",
+	"notification-email-oauth2-client-secret",
+	"notification-email-oauth2-refresh-token",
+	"notification-slack-hook-url",
+	"notification-msteams-hook",
+	"notification-gotify-token",
+	"notification-github-token",
+	"notification-url",
+	"notification-watchdog-url",
+	"metrics-influx-token",
+	"heartbeat-url",
+	"mqtt-url",
+	"kafka-sasl-password",
+	"nats-url",
+	"session-lock",
+	"http-api-password",
+	"http-api-metrics-token",
+}
+
+// secretEnvVars are the environment variables holding the registry credentials, which may refer to a secret provider
+var secretEnvVars = []string{"REPO_USER", "REPO_PASS"}
+
+// SecretProvider resolves references to the secrets kept by an external secrets manager
+type SecretProvider interface {
+	// Resolve returns the secret that the reference, without the provider prefix, refers to
+	Resolve(reference string) (string, error)
+}
+
+// secretProviderFactories create the secret providers by the prefix of the references they resolve, like `vault:`
+var secretProviderFactories = map[string]func(flags *pflag.FlagSet) (SecretProvider, error){
+	"vault": newVaultProvider,
+}
+
+// SecretResolver resolves the secret flags and registry credentials that refer to a secret provider. The references
+// are kept, so that the secrets can be resolved again after they have been rotated.
+type SecretResolver struct {
+	flags     *pflag.FlagSet
+	flagRefs  map[string][]string
+	envRefs   map[string]string
+	providers map[string]SecretProvider
+}
+
+// NewSecretResolver collects the secret flags and registry credentials of the command that refer to a secret provider
+func NewSecretResolver(rootCmd *cobra.Command) *SecretResolver {
+	resolver := &SecretResolver{
+		flags:     rootCmd.PersistentFlags(),
+		flagRefs:  map[string][]string{},
+		envRefs:   map[string]string{},
+		providers: map[string]SecretProvider{},
+	}
+
+	for _, name := range secretFlags {
+		flag := resolver.flags.Lookup(name)
+		if flag == nil {
+			continue
+		}
+		values := []string{flag.Value.String()}
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			values = sliceValue.GetSlice()
+		}
+		for _, value := range values {
+			if _, _, found := splitSecretReference(value); found {
+				resolver.flagRefs[name] = values
+				break
+			}
+		}
+	}
+	for _, name := range secretEnvVars {
+		if value := os.Getenv(name); value != "" {
+			if _, _, found := splitSecretReference(value); found {
+				resolver.envRefs[name] = value
+			}
+		}
+	}
+	return resolver
+}
+
+// HasReferences returns whether any of the secrets refer to a secret provider
+func (resolver *SecretResolver) HasReferences() bool {
+	return len(resolver.flagRefs) > 0 || len(resolver.envRefs) > 0
+}
+
+// Resolve resolves all the references, replacing the values of the flags and environment variables, and returns
+// whether any of the values changed. Nothing is replaced if any of the references cannot be resolved.
+func (resolver *SecretResolver) Resolve() (bool, error) {
+	flagValues := make(map[string][]string, len(resolver.flagRefs))
+	for name, refs := range resolver.flagRefs {
+		values := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			value, err := resolver.resolve(ref)
+			if err != nil {
+				return false, fmt.Errorf("could not resolve the secret of %s: %w", name, err)
+			}
+			values = append(values, value)
+		}
+		flagValues[name] = values
+	}
+	envValues := make(map[string]string, len(resolver.envRefs))
+	for name, ref := range resolver.envRefs {
+		value, err := resolver.resolve(ref)
+		if err != nil {
+			return false, fmt.Errorf("could not resolve the secret of %s: %w", name, err)
+		}
+		envValues[name] = value
+	}
+
+	changed := false
+	for name, values := range flagValues {
+		flag := resolver.flags.Lookup(name)
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			if strings.Join(sliceValue.GetSlice(), "\x00") != strings.Join(values, "\x00") {
+				changed = true
+				if err := sliceValue.Replace(values); err != nil {
+					return changed, err
+				}
+			}
+			continue
+		}
+		if flag.Value.String() != values[0] {
+			changed = true
+			if err := resolver.flags.Set(name, values[0]); err != nil {
+				return changed, err
+			}
+		}
+	}
+	for name, value := range envValues {
+		if os.Getenv(name) != value {
+			changed = true
+			if err := os.Setenv(name, value); err != nil {
+				return changed, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// resolve returns the secret the value refers to, or the value itself if it does not refer to a secret provider
+func (resolver *SecretResolver) resolve(value string) (string, error) {
+	prefix, reference, found := splitSecretReference(value)
+	if !found {
+		return value, nil
+	}
+
+	provider, found := resolver.providers[prefix]
+	if !found {
+		var err error
+		if provider, err = secretProviderFactories[prefix](resolver.flags); err != nil {
+			return "", err
+		}
+		resolver.providers[prefix] = provider
+	}
+	return provider.Resolve(reference)
+}
+
+// splitSecretReference splits a value like `vault:secret/data/watchtower#password` into the prefix of the secret
+// provider and the reference, returning whether the value refers to a known secret provider at all
+func splitSecretReference(value string) (prefix string, reference string, found bool) {
+	colon := strings.IndexRune(value, ':')
+	if colon < 0 {
+		return "", "", false
+	}
+	prefix = value[:colon]
+	if _, known := secretProviderFactories[prefix]; !known {
+		return "", "", false
+	}
+	return prefix, value[colon+1:], true
+}
@@ -0,0 +1,111 @@
+package flags
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVaultTestServer(t *testing.T, secrets map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, found := secrets[r.URL.Path]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, err := w.Write([]byte(body))
+		require.NoError(t, err)
+	}))
+}
+
+func newSecretsTestCommand(t *testing.T, args ...string) *cobra.Command {
+	cmd := new(cobra.Command)
+	SetDefaults()
+	RegisterSystemFlags(cmd)
+	RegisterNotificationFlags(cmd)
+	require.NoError(t, cmd.ParseFlags(args))
+	return cmd
+}
+
+func TestResolveVaultSecrets(t *testing.T) {
+	server := newVaultTestServer(t, map[string]string{
+		"/v1/secret/data/watchtower": `{"data": {"data": {"password": "hunter2", "user": "admin"}, "metadata": {"version": 1}}}`,
+		"/v1/kv/watchtower":          `{"data": {"hook": "https://hooks.example.com/abc"}}`,
+	})
+	defer server.Close()
+
+	require.NoError(t, os.Setenv("REPO_USER", "vault:secret/data/watchtower#user"))
+	defer os.Unsetenv("REPO_USER")
+
+	cmd := newSecretsTestCommand(t,
+		"--vault-address", server.URL,
+		"--vault-token", "test-token",
+		"--notification-email-server-password", "vault:secret/data/watchtower#password",
+		"--notification-slack-hook-url", "vault:kv/watchtower#hook",
+		"--notification-url", "logger://",
+		"--notification-url", "vault:kv/watchtower#hook")
+
+	resolver := NewSecretResolver(cmd)
+	assert.True(t, resolver.HasReferences())
+	changed, err := resolver.Resolve()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	flags := cmd.PersistentFlags()
+	password, _ := flags.GetString("notification-email-server-password")
+	assert.Equal(t, "hunter2", password)
+	hook, _ := flags.GetString("notification-slack-hook-url")
+	assert.Equal(t, "https://hooks.example.com/abc", hook)
+	urls, _ := flags.GetStringArray("notification-url")
+	assert.Equal(t, []string{"logger://", "https://hooks.example.com/abc"}, urls)
+	assert.Equal(t, "admin", os.Getenv("REPO_USER"))
+
+	changed, err = resolver.Resolve()
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestResolveVaultSecretsFailure(t *testing.T) {
+	server := newVaultTestServer(t, map[string]string{
+		"/v1/secret/data/watchtower": `{"data": {"data": {"password": "hunter2"}, "metadata": {"version": 1}}}`,
+	})
+	defer server.Close()
+
+	cmd := newSecretsTestCommand(t,
+		"--vault-address", server.URL,
+		"--vault-token", "test-token",
+		"--notification-email-server-password", "vault:secret/data/watchtower#password",
+		"--notification-gotify-token", "vault:secret/data/watchtower#missing")
+
+	_, err := NewSecretResolver(cmd).Resolve()
+	assert.Error(t, err)
+	password, _ := cmd.PersistentFlags().GetString("notification-email-server-password")
+	assert.Equal(t, "vault:secret/data/watchtower#password", password)
+}
+
+func TestResolveSecretsWithoutReferences(t *testing.T) {
+	cmd := newSecretsTestCommand(t, "--notification-email-server-password", "plain:text")
+
+	resolver := NewSecretResolver(cmd)
+	assert.False(t, resolver.HasReferences())
+	changed, err := resolver.Resolve()
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestResolveVaultSecretsWithoutAddress(t *testing.T) {
+	os.Unsetenv("VAULT_ADDR")
+	cmd := newSecretsTestCommand(t, "--notification-gotify-token", "vault:secret/data/watchtower#token")
+
+	_, err := NewSecretResolver(cmd).Resolve()
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package flags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/meta"
+	"github.com/spf13/pflag"
+)
+
+// vaultProvider reads secrets from the key/value secrets engine of a HashiCorp Vault server
+type vaultProvider struct {
+	address   string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+// newVaultProvider creates a vaultProvider using the vault flags, falling back to the environment variables used by
+// the vault CLI
+func newVaultProvider(flags *pflag.FlagSet) (SecretProvider, error) {
+	address, _ := flags.GetString("vault-address")
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	token, _ := flags.GetString("vault-token")
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if address == "" || token == "" {
+		return nil, errors.New("the vault address and token are required to resolve vault secrets")
+	}
+	return &vaultProvider{
+		address:   strings.TrimSuffix(address, "/"),
+		token:     token,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Resolve reads the secret at the path and returns the value of its key, the reference being like
+// `secret/data/watchtower#password`. Both version 1 and 2 of the key/value secrets engine are supported.
+func (vault *vaultProvider) Resolve(reference string) (string, error) {
+	path, key, found := strings.Cut(reference, "#")
+	if !found || path == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference %q, expected <path>#<key>", reference)
+	}
+
+	req, err := http.NewRequest("GET", vault.address+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", meta.UserAgent)
+	req.Header.Set("X-Vault-Token", vault.token)
+	if vault.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", vault.namespace)
+	}
+
+	res, err := vault.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault responded to the request for %s with %q", path, res.Status)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&secret); err != nil {
+		return "", err
+	}
+
+	data := secret.Data
+	// Version 2 of the engine nests the secret within the data, next to its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = nested
+		}
+	}
+	value, found := data[key]
+	if !found {
+		return "", fmt.Errorf("the vault secret %s has no key %q", path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
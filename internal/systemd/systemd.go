@@ -0,0 +1,80 @@
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Ready, Stopping and WatchdogPing are the states sent to the service manager
+const (
+	Ready        = "READY=1"
+	Stopping     = "STOPPING=1"
+	WatchdogPing = "WATCHDOG=1"
+)
+
+// Notify sends the state to the service manager, if watchtower was started by systemd using Type=notify. It returns
+// false if there is no service manager to notify.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// Sockets starting with @ are in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("could not connect to the service manager: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("could not notify the service manager: %w", err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the interval within which the service manager expects a watchdog ping, or 0 if the
+// watchdog is not enabled for this process
+func WatchdogInterval() (time.Duration, error) {
+	value := os.Getenv("WATCHDOG_USEC")
+	if value == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+
+	usec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", value)
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
+
+// Watchdog pings the service manager at half the interval, for as long as alive reports that watchtower is working,
+// until done is closed. Once alive reports otherwise the pings stop, and the service manager restarts watchtower
+// when the interval runs out.
+func Watchdog(interval time.Duration, alive func() bool, done <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if !alive() {
+				continue
+			}
+			if _, err := Notify(WatchdogPing); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listen(t *testing.T) *net.UnixConn {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", socket)
+	return conn
+}
+
+func receive(t *testing.T, conn *net.UnixConn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	conn := listen(t)
+
+	notified, err := Notify(Ready)
+	require.NoError(t, err)
+	assert.True(t, notified)
+	assert.Equal(t, "READY=1", receive(t, conn))
+}
+
+func TestNotify_WithoutServiceManager(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+
+	notified, err := Notify(Ready)
+	assert.NoError(t, err)
+	assert.False(t, notified)
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	interval, err := WatchdogInterval()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, interval)
+
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	interval, err = WatchdogInterval()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), interval)
+
+	t.Setenv("WATCHDOG_USEC", "soon")
+	t.Setenv("WATCHDOG_PID", "")
+	_, err = WatchdogInterval()
+	assert.Error(t, err)
+}
+
+func TestWatchdog(t *testing.T) {
+	conn := listen(t)
+	done := make(chan struct{})
+	defer close(done)
+
+	alive := make(chan bool, 1)
+	alive <- false
+	go Watchdog(20*time.Millisecond, func() bool {
+		select {
+		case value := <-alive:
+			return value
+		default:
+			return true
+		}
+	}, done, func(err error) { t.Error(err) })
+
+	// The first tick is not alive, so the first ping comes from the second one
+	start := time.Now()
+	assert.Equal(t, "WATCHDOG=1", receive(t, conn))
+	assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+}
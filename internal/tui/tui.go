@@ -0,0 +1,296 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/containrrr/watchtower/internal/actions"
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// clearScreen moves the cursor to the top left corner of the terminal and clears it
+const clearScreen = "\033[H\033[2J"
+
+// logLines is how many of the most recent log lines are shown below the containers
+const logLines = 5
+
+// Session is an attended session listing the monitored containers and their pending updates, letting the user check
+// them again, update or skip them one by one and view what changes with the new images
+type Session struct {
+	client  container.Client
+	params  types.UpdateParams
+	input   *bufio.Scanner
+	output  io.Writer
+	logs    *logTail
+	rows    []row
+	skipped map[string]bool
+	message []string
+}
+
+// row is a container as listed by the session, along with the result of its last check or update
+type row struct {
+	name   string
+	report types.ContainerReport
+}
+
+// New creates a Session updating the containers using the params, reading the commands from input and drawing the
+// screen to output
+func New(client container.Client, params types.UpdateParams, input io.Reader, output io.Writer) *Session {
+	return &Session{
+		client:  client,
+		params:  params,
+		input:   bufio.NewScanner(input),
+		output:  output,
+		logs:    &logTail{},
+		skipped: map[string]bool{},
+	}
+}
+
+// Run checks the containers and handles the commands until the user quits or the input is closed. The log output is
+// shown as part of the screen while the session runs.
+func (s *Session) Run() {
+	logger := log.StandardLogger()
+	previousOutput := logger.Out
+	logger.SetOutput(s.logs)
+	defer logger.SetOutput(previousOutput)
+
+	s.check()
+	for {
+		s.render()
+		if !s.input.Scan() || !s.handle(s.input.Text()) {
+			return
+		}
+	}
+}
+
+// handle runs the command on the line, returning false if the session should end
+func (s *Session) handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	s.message = nil
+
+	command := fields[0]
+	if command == "q" || command == "quit" {
+		return false
+	}
+	if command == "r" || command == "refresh" {
+		s.check()
+		return true
+	}
+	if (command == "u" || command == "update") && len(fields) == 1 {
+		s.updatePending()
+		return true
+	}
+	if len(fields) != 2 {
+		s.message = []string{fmt.Sprintf("Unknown command %q", line)}
+		return true
+	}
+
+	index, err := strconv.Atoi(fields[1])
+	if err != nil || index < 1 || index > len(s.rows) {
+		s.message = []string{fmt.Sprintf("There is no container number %s", fields[1])}
+		return true
+	}
+	selected := s.rows[index-1]
+
+	switch command {
+	case "u", "update":
+		s.update([]string{selected.name})
+	case "s", "skip":
+		s.skipped[selected.name] = !s.skipped[selected.name]
+	case "d", "diff":
+		s.message = describeChanges(selected)
+	default:
+		s.message = []string{fmt.Sprintf("Unknown command %q", line)}
+	}
+	return true
+}
+
+// check checks all the containers for new images, without updating any of them
+func (s *Session) check() {
+	params := s.params
+	params.MonitorOnly = true
+	report, err := actions.Update(s.client, params)
+	if err != nil {
+		s.message = []string{"Could not check the containers: " + err.Error()}
+		return
+	}
+
+	s.rows = s.rows[:0]
+	for _, c := range report.All() {
+		s.rows = append(s.rows, row{name: strings.TrimPrefix(c.Name(), "/"), report: c})
+	}
+	sort.Slice(s.rows, func(i, j int) bool {
+		return s.rows[i].name < s.rows[j].name
+	})
+}
+
+// updatePending updates all the containers with a new image, except for the ones the user skipped
+func (s *Session) updatePending() {
+	var names []string
+	for _, r := range s.rows {
+		if r.report.State() == "Stale" && !s.skipped[r.name] {
+			names = append(names, r.name)
+		}
+	}
+	if len(names) == 0 {
+		s.message = []string{"There are no pending updates"}
+		return
+	}
+	s.update(names)
+}
+
+// update updates the named containers, if they have a new image
+func (s *Session) update(names []string) {
+	if s.params.MonitorOnly {
+		s.message = []string{"Containers are not updated in monitor only mode"}
+		return
+	}
+
+	params := s.params
+	params.Filter = byNames(names, s.params.Filter)
+	report, err := actions.Update(s.client, params)
+	if err != nil {
+		s.message = []string{"Could not update the containers: " + err.Error()}
+		return
+	}
+
+	for _, c := range report.All() {
+		name := strings.TrimPrefix(c.Name(), "/")
+		for i := range s.rows {
+			if s.rows[i].name == name {
+				s.rows[i].report = c
+			}
+		}
+	}
+	s.message = []string{fmt.Sprintf("%d container(s) updated, %d failed", len(report.Updated()), len(report.Failed()))}
+}
+
+// render draws the containers, the message of the last command and the most recent log lines
+func (s *Session) render() {
+	pending := 0
+	for _, r := range s.rows {
+		if r.report.State() == "Stale" {
+			pending++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	fmt.Fprintf(&b, "watchtower: %d container(s), %d update(s) pending\n\n", len(s.rows), pending)
+	fmt.Fprintf(&b, "  %-3s %-30s %-40s %s\n", "#", "CONTAINER", "IMAGE", "STATUS")
+	for i, r := range s.rows {
+		status := describeState(r.report)
+		if s.skipped[r.name] {
+			status += " (skipped)"
+		}
+		fmt.Fprintf(&b, "  %-3d %-30s %-40s %s\n", i+1, r.name, r.report.ImageName(), status)
+	}
+
+	if len(s.message) > 0 {
+		b.WriteString("\n")
+		for _, line := range s.message {
+			b.WriteString(line + "\n")
+		}
+	}
+	if lines := s.logs.Lines(); len(lines) > 0 {
+		b.WriteString("\n")
+		for _, line := range lines {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	b.WriteString("\n[r]efresh  [u]pdate all  [u]pdate N  [s]kip N  [d]iff N  [q]uit\n> ")
+	_, _ = io.WriteString(s.output, b.String())
+}
+
+// describeState describes the result of the last check or update of the container
+func describeState(c types.ContainerReport) string {
+	switch c.State() {
+	case "Stale":
+		return "update available"
+	case "Fresh":
+		return "up to date"
+	case "Updated":
+		return "updated"
+	case "Failed":
+		return "failed: " + c.Error()
+	case "Skipped":
+		return fmt.Sprintf("not checked (%s): %s", c.SkipReason(), c.Error())
+	case "Quarantined":
+		return "quarantined: " + c.Error()
+	}
+	return strings.ToLower(c.State())
+}
+
+// describeChanges lists the images of the container along with the changes of the new image, if it has one
+func describeChanges(r row) []string {
+	c := r.report
+	lines := []string{fmt.Sprintf("%s (%s)", r.name, c.ImageName()), "  current image: " + c.CurrentImageID().ShortID()}
+	if c.LatestImageID() == "" || c.LatestImageID() == c.CurrentImageID() {
+		return append(lines, "  there is no new image")
+	}
+	lines = append(lines, "  new image:     "+c.LatestImageID().ShortID())
+
+	if release := c.Release(); release.Version != "" || release.URL != "" {
+		lines = append(lines, strings.TrimRight(fmt.Sprintf("  release:       %s %s", release.Version, release.URL), " "))
+	}
+	changes := c.ImageChanges()
+	if len(changes) == 0 {
+		return append(lines, "  the configuration of the image is unchanged")
+	}
+	for _, change := range changes {
+		lines = append(lines, "  - "+change)
+	}
+	return lines
+}
+
+// byNames only lets the containers with any of the names through the base filter
+func byNames(names []string, baseFilter types.Filter) types.Filter {
+	return func(c types.FilterableContainer) bool {
+		for _, name := range names {
+			if strings.TrimPrefix(c.Name(), "/") == name {
+				return baseFilter == nil || baseFilter(c)
+			}
+		}
+		return false
+	}
+}
+
+// logTail keeps the last few lines written to it
+type logTail struct {
+	mutex   sync.Mutex
+	lines   []string
+	partial string
+}
+
+// Write splits the output into lines, keeping the most recent ones
+func (tail *logTail) Write(p []byte) (int, error) {
+	tail.mutex.Lock()
+	defer tail.mutex.Unlock()
+
+	text := tail.partial + string(p)
+	lines := strings.Split(text, "\n")
+	tail.partial = lines[len(lines)-1]
+	tail.lines = append(tail.lines, lines[:len(lines)-1]...)
+	if len(tail.lines) > logLines {
+		tail.lines = tail.lines[len(tail.lines)-logLines:]
+	}
+	return len(p), nil
+}
+
+// Lines returns the most recent complete lines
+func (tail *logTail) Lines() []string {
+	tail.mutex.Lock()
+	defer tail.mutex.Unlock()
+	return append([]string(nil), tail.lines...)
+}
@@ -0,0 +1,16 @@
+package tui_test
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestTUI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	logrus.SetOutput(GinkgoWriter)
+	RunSpecs(t, "TUI Suite")
+}
@@ -0,0 +1,102 @@
+package tui_test
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/tui"
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/filters"
+	"github.com/containrrr/watchtower/pkg/types"
+
+	. "github.com/containrrr/watchtower/internal/actions/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// filteringClient is a mock client that only lists the containers passing the filter, unlike the MockClient
+type filteringClient struct {
+	MockClient
+}
+
+func (client filteringClient) ListContainers(filter types.Filter) ([]container.Container, error) {
+	var containers []container.Container
+	for _, c := range client.TestData.Containers {
+		if filter(c) {
+			containers = append(containers, c)
+		}
+	}
+	return containers, nil
+}
+
+var _ = Describe("the interactive session", func() {
+	var testData *TestData
+	var started []string
+
+	BeforeEach(func() {
+		started = nil
+		testData = &TestData{
+			Containers: []container.Container{
+				CreateMockContainer("test-container-02", "test-container-02", "fake-image2:latest", time.Now()),
+				CreateMockContainer("test-container-01", "test-container-01", "fake-image1:latest", time.Now()),
+			},
+			OnStart: func(c container.Container) {
+				started = append(started, c.Name())
+			},
+		}
+	})
+
+	run := func(params types.UpdateParams, commands ...string) string {
+		if params.Filter == nil {
+			params.Filter = filters.NoFilter
+		}
+		output := &bytes.Buffer{}
+		client := filteringClient{CreateMockClient(testData, false, false)}
+		input := strings.NewReader(strings.Join(commands, "\n") + "\n")
+		tui.New(client, params, input, output).Run()
+		return output.String()
+	}
+
+	It("should list the containers and whether they have a new image", func() {
+		testData.Staleness = map[string]bool{"test-container-02": false}
+		output := run(types.UpdateParams{}, "q")
+		Expect(output).To(ContainSubstring("2 container(s), 1 update(s) pending"))
+		Expect(output).To(MatchRegexp(`1 +test-container-01 +fake-image1:latest +update available`))
+		Expect(output).To(MatchRegexp(`2 +test-container-02 +fake-image2:latest +up to date`))
+		Expect(started).To(BeEmpty())
+	})
+
+	It("should only update the selected container", func() {
+		output := run(types.UpdateParams{}, "u 2", "q")
+		Expect(started).To(ConsistOf("test-container-02"))
+		Expect(output).To(ContainSubstring("1 container(s) updated, 0 failed"))
+		Expect(output).To(MatchRegexp(`2 +test-container-02 +fake-image2:latest +updated`))
+	})
+
+	It("should update all the pending containers except for the skipped ones", func() {
+		output := run(types.UpdateParams{}, "s 1", "u", "q")
+		Expect(started).To(ConsistOf("test-container-02"))
+		Expect(output).To(MatchRegexp(`1 +test-container-01 +fake-image1:latest +update available \(skipped\)`))
+	})
+
+	It("should not update any containers in monitor only mode", func() {
+		output := run(types.UpdateParams{MonitorOnly: true}, "u", "q")
+		Expect(started).To(BeEmpty())
+		Expect(output).To(ContainSubstring("Containers are not updated in monitor only mode"))
+	})
+
+	It("should describe the images of the selected container", func() {
+		testData.Staleness = map[string]bool{"test-container-01": false}
+		output := run(types.UpdateParams{}, "d 1", "q")
+		Expect(output).To(ContainSubstring("test-container-01 (fake-image1:latest)"))
+		Expect(output).To(ContainSubstring("there is no new image"))
+	})
+
+	It("should reject unknown commands and containers", func() {
+		output := run(types.UpdateParams{}, "x 1", "u 3", "q")
+		Expect(output).To(ContainSubstring(`Unknown command "x 1"`))
+		Expect(output).To(ContainSubstring("There is no container number 3"))
+		Expect(started).To(BeEmpty())
+	})
+})
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 
@@ -13,6 +14,15 @@ const tokenMissingMsg = "api token is empty or has not been set. exiting"
 type API struct {
 	Token       string
 	hasHandlers bool
+	// usesToken is set once a handler requiring the API token has been registered
+	usesToken bool
+	// Limiter limits the rate of the requests, and locks out the sources sending invalid tokens, if set
+	Limiter *Limiter
+	// Username and Password are accepted using basic authentication instead of the token, if the username is set
+	Username string
+	Password string
+	// CORSOrigins are the origins of the pages allowed to call the API, or * for any
+	CORSOrigins []string
 }
 
 // New is a factory function creating a new API instance
@@ -23,33 +33,102 @@ func New(token string) *API {
 	}
 }
 
-// RequireToken is wrapper around http.HandleFunc that checks token validity
+// RequireToken is wrapper around http.HandleFunc that checks token validity. The username and password are accepted
+// instead, if set.
 func (api *API) RequireToken(fn http.HandlerFunc) http.HandlerFunc {
+	return api.requireAuth(api.authorized, api.Username != "", fn)
+}
+
+// requireToken wraps the handler, only passing on the requests carrying the token as their bearer token
+func (api *API) requireToken(token string, fn http.HandlerFunc) http.HandlerFunc {
+	return api.requireAuth(func(r *http.Request) bool { return hasBearerToken(r, token) }, false, fn)
+}
+
+// requireAuth wraps the handler, only passing on the authorized requests. Invalid credentials are counted by the
+// limiter. When challenge is set, the unauthorized requests are asked for basic authentication, making browsers prompt
+// for the username and password.
+func (api *API) requireAuth(authorized func(r *http.Request) bool, challenge bool, fn http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		auth := r.Header.Get("Authorization")
-		want := fmt.Sprintf("Bearer %s", api.Token)
-		if auth != want {
+		if !authorized(r) {
+			api.Limiter.Failed(r)
+			if challenge {
+				w.Header().Set("WWW-Authenticate", `Basic realm="watchtower", charset="UTF-8"`)
+			}
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 		log.Debug("Valid token found.")
+		api.Limiter.Succeeded(r)
 		fn(w, r)
 	}
 }
 
+// authorized returns whether the request carries the API token, or the username and password, if they are set
+func (api *API) authorized(r *http.Request) bool {
+	if api.Token != "" && hasBearerToken(r, api.Token) {
+		return true
+	}
+	if api.Username == "" {
+		return false
+	}
+	username, password, ok := r.BasicAuth()
+	return ok && subtle.ConstantTimeCompare([]byte(username), []byte(api.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(api.Password)) == 1
+}
+
+// hasBearerToken returns whether the request carries the token as its bearer token
+func hasBearerToken(r *http.Request, token string) bool {
+	return r.Header.Get("Authorization") == fmt.Sprintf("Bearer %s", token)
+}
+
+// limit wraps the handler, rejecting the requests the limiter does not allow. The limiter is looked up for each
+// request, so that it applies to the handlers registered before it was set.
+func (api *API) limit(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		api.Limiter.Limit(fn)(w, r)
+	}
+}
+
+// wrap wraps a registered handler, answering the CORS requests and limiting the requests
+func (api *API) wrap(fn http.HandlerFunc) http.HandlerFunc {
+	return api.cors(api.limit(fn))
+}
+
 // RegisterFunc is a wrapper around http.HandleFunc that also sets the flag used to determine whether to launch the API
 func (api *API) RegisterFunc(path string, fn http.HandlerFunc) {
 	api.hasHandlers = true
-	http.HandleFunc(path, api.RequireToken(fn))
+	api.usesToken = true
+	http.HandleFunc(path, api.wrap(api.RequireToken(fn)))
 }
 
 // RegisterHandler is a wrapper around http.Handler that also sets the flag used to determine whether to launch the API
 func (api *API) RegisterHandler(path string, handler http.Handler) {
 	api.hasHandlers = true
-	http.Handle(path, api.RequireToken(handler.ServeHTTP))
+	api.usesToken = true
+	http.Handle(path, api.wrap(api.RequireToken(handler.ServeHTTP)))
+}
+
+// RegisterHandlerWithToken is a wrapper around http.Handler requiring its own token rather than the API token, like
+// the metrics, which are scraped using other credentials than the ones triggering updates. An empty token leaves the
+// handler open.
+func (api *API) RegisterHandlerWithToken(path string, token string, handler http.Handler) {
+	api.hasHandlers = true
+	if token == "" {
+		http.Handle(path, api.wrap(handler.ServeHTTP))
+		return
+	}
+	http.Handle(path, api.wrap(api.requireToken(token, handler.ServeHTTP)))
+}
+
+// RegisterPublicFunc is a wrapper around http.HandleFunc for content that does not require the token, like the page
+// of the dashboard, which uses the token to retrieve the data it shows
+func (api *API) RegisterPublicFunc(path string, fn http.HandlerFunc) {
+	api.hasHandlers = true
+	http.HandleFunc(path, api.wrap(fn))
 }
 
-// Start the API and serve over HTTP. Requires an API Token to be set.
+// Start the API and serve over HTTP. Requires an API Token, or a username, to be set, unless none of the handlers use
+// them.
 func (api *API) Start(block bool) error {
 
 	if !api.hasHandlers {
@@ -57,7 +136,7 @@ func (api *API) Start(block bool) error {
 		return nil
 	}
 
-	if api.Token == "" {
+	if api.usesToken && api.Token == "" && api.Username == "" {
 		log.Fatal(tokenMissingMsg)
 	}
 
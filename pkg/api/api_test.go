@@ -58,6 +58,73 @@ var _ = Describe("API", func() {
 			Expect(rec.Code).To(Equal(http.StatusOK))
 		})
 	})
+
+	Describe("basic authentication", func() {
+		basic := New(token)
+		basic.Username, basic.Password = "admin", "secret"
+
+		serve := func(configure func(req *http.Request)) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/hello", nil)
+			configure(req)
+			basic.RequireToken(testHandler)(rec, req)
+			return rec
+		}
+
+		It("should accept the username and password", func() {
+			rec := serve(func(req *http.Request) { req.SetBasicAuth("admin", "secret") })
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should still accept the token", func() {
+			rec := serve(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) })
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should ask for the credentials when they are invalid", func() {
+			rec := serve(func(req *http.Request) { req.SetBasicAuth("admin", "guess") })
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rec.Header().Get("WWW-Authenticate")).To(HavePrefix("Basic "))
+		})
+
+		It("should not accept basic authentication without a username", func() {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/hello", nil)
+			req.SetBasicAuth("", "")
+			api.RequireToken(testHandler)(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rec.Header().Get("WWW-Authenticate")).To(BeEmpty())
+		})
+	})
+
+	Describe("handlers with their own token", func() {
+		serve := func(path string, auth string) int {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", path, nil)
+			if auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			http.DefaultServeMux.ServeHTTP(rec, req)
+			return rec.Code
+		}
+
+		It("should only accept their own token", func() {
+			api.RegisterHandlerWithToken("/own-token", "scrape", http.HandlerFunc(testHandler))
+			Expect(serve("/own-token", "Bearer scrape")).To(Equal(http.StatusOK))
+			Expect(serve("/own-token", "Bearer "+token)).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("should be open without a token", func() {
+			api.RegisterHandlerWithToken("/no-token", "", http.HandlerFunc(testHandler))
+			Expect(serve("/no-token", "")).To(Equal(http.StatusOK))
+		})
+
+		It("should not require the API token to be set", func() {
+			open := New("")
+			open.RegisterHandlerWithToken("/open", "", http.HandlerFunc(testHandler))
+			Expect(open.usesToken).To(BeFalse())
+		})
+	})
 })
 
 func testHandler(w http.ResponseWriter, req *http.Request) {
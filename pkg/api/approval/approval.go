@@ -0,0 +1,115 @@
+package approval
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Pending is an update waiting for approval, with the new image already pulled
+type Pending struct {
+	Container   string    `json:"container"`
+	LatestImage string    `json:"latestImage"`
+	StagedAt    time.Time `json:"stagedAt"`
+}
+
+// Result is the outcome of applying an approved update
+type Result struct {
+	Container  string `json:"container"`
+	State      string `json:"state"`
+	Error      string `json:"error,omitempty"`
+	SkipReason string `json:"skipReason,omitempty"`
+}
+
+// New is a factory function creating a new Handler instance. The approve function applies the approved updates of the
+// named containers, or of all the pending ones if no names are passed. The update lock makes sure it only runs while
+// no other session does.
+func New(pendingFn func() []Pending, approveFn func(containers []string) (types.Report, error), updateLock chan bool) *Handler {
+	return &Handler{
+		pending:     pendingFn,
+		approve:     approveFn,
+		lock:        updateLock,
+		PendingPath: "/v1/pending",
+		ApprovePath: "/v1/approve",
+	}
+}
+
+// Handler is an API handler used for listing the updates waiting for approval, and for approving them
+type Handler struct {
+	pending     func() []Pending
+	approve     func(containers []string) (types.Report, error)
+	lock        chan bool
+	PendingPath string
+	ApprovePath string
+}
+
+// HandlePending returns the updates waiting for approval on GET requests
+func (handle *Handler) HandlePending(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	pending := handle.pending()
+	if pending == nil {
+		pending = []Pending{}
+	}
+	writeJSON(w, pending)
+}
+
+// HandleApprove applies the approved updates on POST requests, once the running session has finished. The containers
+// to approve the updates of are passed using the container query parameter, approving all the pending updates if it
+// is left out.
+func (handle *Handler) HandleApprove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var containers []string
+	for _, value := range r.URL.Query()["container"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				containers = append(containers, name)
+			}
+		}
+	}
+	if len(containers) > 0 {
+		log.Infof("Updates of %s approved by HTTP API request.", strings.Join(containers, ", "))
+	} else {
+		log.Info("All pending updates approved by HTTP API request.")
+	}
+
+	chanValue := <-handle.lock
+	defer func() { handle.lock <- chanValue }()
+
+	report, err := handle.approve(containers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	results := []Result{}
+	if report != nil {
+		for _, c := range report.All() {
+			results = append(results, Result{
+				Container:  strings.TrimPrefix(c.Name(), "/"),
+				State:      c.State(),
+				Error:      c.Error(),
+				SkipReason: string(c.SkipReason()),
+			})
+		}
+	}
+	writeJSON(w, results)
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.WithError(err).Debug("Could not write the API response")
+	}
+}
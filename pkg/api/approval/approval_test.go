@@ -0,0 +1,105 @@
+package approval_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/api/approval"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	"github.com/sirupsen/logrus"
+
+	. "github.com/containrrr/watchtower/internal/actions/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestApproval(t *testing.T) {
+	RegisterFailHandler(Fail)
+	logrus.SetOutput(GinkgoWriter)
+	RunSpecs(t, "Approval Suite")
+}
+
+var _ = Describe("the approval API", func() {
+	var handler *approval.Handler
+	var pending []approval.Pending
+	var approved [][]string
+	var approveErr error
+	var updateLock chan bool
+
+	BeforeEach(func() {
+		pending = nil
+		approved = nil
+		approveErr = nil
+		updateLock = make(chan bool, 1)
+		updateLock <- true
+		handler = approval.New(
+			func() []approval.Pending { return pending },
+			func(containers []string) (types.Report, error) {
+				approved = append(approved, containers)
+				if approveErr != nil {
+					return nil, approveErr
+				}
+				return CreateMockProgressReport(session.UpdatedState), nil
+			},
+			updateLock)
+	})
+
+	It("should list the pending updates", func() {
+		pending = []approval.Pending{{Container: "web", LatestImage: "0123456789ab", StagedAt: time.Now()}}
+		res := httptest.NewRecorder()
+		handler.HandlePending(res, httptest.NewRequest(http.MethodGet, handler.PendingPath, nil))
+
+		Expect(res.Code).To(Equal(http.StatusOK))
+		var body []approval.Pending
+		Expect(json.NewDecoder(res.Body).Decode(&body)).To(Succeed())
+		Expect(body).To(HaveLen(1))
+		Expect(body[0].Container).To(Equal("web"))
+	})
+
+	It("should return an empty list if nothing is pending", func() {
+		res := httptest.NewRecorder()
+		handler.HandlePending(res, httptest.NewRequest(http.MethodGet, handler.PendingPath, nil))
+		Expect(res.Body.String()).To(Equal("[]\n"))
+	})
+
+	It("should approve the updates of the named containers", func() {
+		res := httptest.NewRecorder()
+		handler.HandleApprove(res, httptest.NewRequest(http.MethodPost, handler.ApprovePath+"?container=web,db&container=cache", nil))
+
+		Expect(res.Code).To(Equal(http.StatusOK))
+		Expect(approved).To(Equal([][]string{{"web", "db", "cache"}}))
+		var body []approval.Result
+		Expect(json.NewDecoder(res.Body).Decode(&body)).To(Succeed())
+		Expect(body).To(Equal([]approval.Result{{Container: "updt1", State: "Updated"}}))
+		Expect(updateLock).To(HaveLen(1))
+	})
+
+	It("should approve all the pending updates if no containers are named", func() {
+		res := httptest.NewRecorder()
+		handler.HandleApprove(res, httptest.NewRequest(http.MethodPost, handler.ApprovePath, nil))
+		Expect(res.Code).To(Equal(http.StatusOK))
+		Expect(approved).To(Equal([][]string{nil}))
+	})
+
+	It("should report the error if the updates could not be applied", func() {
+		approveErr = errors.New("no docker")
+		res := httptest.NewRecorder()
+		handler.HandleApprove(res, httptest.NewRequest(http.MethodPost, handler.ApprovePath, nil))
+		Expect(res.Code).To(Equal(http.StatusInternalServerError))
+		Expect(updateLock).To(HaveLen(1))
+	})
+
+	It("should only accept the supported methods", func() {
+		res := httptest.NewRecorder()
+		handler.HandleApprove(res, httptest.NewRequest(http.MethodGet, handler.ApprovePath, nil))
+		Expect(res.Code).To(Equal(http.StatusMethodNotAllowed))
+		res = httptest.NewRecorder()
+		handler.HandlePending(res, httptest.NewRequest(http.MethodPost, handler.PendingPath, nil))
+		Expect(res.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
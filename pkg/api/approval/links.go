@@ -0,0 +1,207 @@
+package approval
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LinkTTL is how long an approval link can be used after it has been created
+const LinkTTL = 24 * time.Hour
+
+// TokenPath is the path of the approval links, which is not protected by the API token
+const TokenPath = "/v1/approve-token"
+
+var errInvalidToken = errors.New("the approval link is invalid")
+
+// Signer creates and redeems approval links, each approving the update of a container to a specific image once
+type Signer struct {
+	key     []byte
+	baseURL string
+	mutex   sync.Mutex
+	// used are the nonces of the redeemed links, by when they expire
+	used map[string]time.Time
+}
+
+// NewSigner creates a Signer for the links to the public base URL of the HTTP API, deriving the signing key from the
+// API token
+func NewSigner(apiToken string, baseURL string) *Signer {
+	key := sha256.Sum256([]byte("watchtower-approval-links:" + apiToken))
+	return &Signer{
+		key:     key[:],
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		used:    map[string]time.Time{},
+	}
+}
+
+// Link returns a link approving the update of the container to the image, valid for LinkTTL
+func (s *Signer) Link(container string, image string) string {
+	return s.baseURL + TokenPath + "?token=" + url.QueryEscape(s.sign(container, image, time.Now().Add(LinkTTL)))
+}
+
+func (s *Signer) sign(container string, image string, expires time.Time) string {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		log.WithError(err).Warn("Could not create a random nonce for the approval link")
+	}
+	payload := strings.Join([]string{container, image, strconv.FormatInt(expires.Unix(), 10), hex.EncodeToString(nonce)}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+func (s *Signer) mac(payload string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// verify checks the signature and expiry of the token, returning its container, image and nonce
+func (s *Signer) verify(token string, now time.Time) (container string, image string, nonce string, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", "", "", errInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", "", errInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, s.mac(string(payload))) {
+		return "", "", "", errInvalidToken
+	}
+
+	fields := strings.Split(string(payload), "|")
+	if len(fields) != 4 {
+		return "", "", "", errInvalidToken
+	}
+	expires, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return "", "", "", errInvalidToken
+	}
+	if now.After(time.Unix(expires, 0)) {
+		return "", "", "", errors.New("the approval link has expired")
+	}
+	return fields[0], fields[1], fields[3], nil
+}
+
+// redeem marks the token as used, failing if it is invalid or has been used before
+func (s *Signer) redeem(token string, now time.Time) (container string, image string, err error) {
+	container, image, nonce, err := s.verify(token, now)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for used, expires := range s.used {
+		if now.After(expires) {
+			delete(s.used, used)
+		}
+	}
+	if _, found := s.used[nonce]; found {
+		return "", "", errors.New("the approval link has already been used")
+	}
+	s.used[nonce] = now.Add(LinkTTL)
+	return container, image, nil
+}
+
+var page = template.Must(template.New("approval").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Watchtower</title>
+  <style>body { font-family: system-ui, sans-serif; margin: 2em auto; max-width: 30em; padding: 0 1em; }</style>
+</head>
+<body>
+  <h1>Watchtower</h1>
+  <p>{{.Message}}</p>
+  {{if .Token}}<form method="post">
+    <input type="hidden" name="token" value="{{.Token}}">
+    <button type="submit">Approve</button>
+  </form>{{end}}
+</body>
+</html>
+`))
+
+// HandleApproveToken approves updates using the links created by the signer. GET requests ask for a confirmation,
+// so that the links are not redeemed by previews, and POST requests apply the approved update.
+func (handle *Handler) HandleApproveToken(signer *Signer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.FormValue("token")
+		switch r.Method {
+		case http.MethodGet:
+			container, image, _, err := signer.verify(token, time.Now())
+			if err != nil {
+				renderPage(w, http.StatusBadRequest, err.Error()+".", "")
+				return
+			}
+			renderPage(w, http.StatusOK, "Approve the update of "+container+" to image "+image+"?", token)
+		case http.MethodPost:
+			handle.redeem(w, signer, token)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func (handle *Handler) redeem(w http.ResponseWriter, signer *Signer, token string) {
+	container, image, err := signer.redeem(token, time.Now())
+	if err != nil {
+		renderPage(w, http.StatusBadRequest, err.Error()+".", "")
+		return
+	}
+
+	chanValue := <-handle.lock
+	defer func() { handle.lock <- chanValue }()
+
+	// Only approve the image the link was created for, not a newer one pulled since
+	stillPending := false
+	for _, pending := range handle.pending() {
+		if pending.Container == container && pending.LatestImage == image {
+			stillPending = true
+		}
+	}
+	if !stillPending {
+		renderPage(w, http.StatusConflict, "The update of "+container+" to image "+image+" is no longer pending.", "")
+		return
+	}
+
+	log.Infof("Update of %s approved using an approval link.", container)
+	report, err := handle.approve([]string{container})
+	if err != nil {
+		renderPage(w, http.StatusInternalServerError, "Could not update "+container+": "+err.Error(), "")
+		return
+	}
+	message := "Approved the update of " + container + "."
+	if report != nil {
+		for _, c := range report.All() {
+			if c.Error() != "" {
+				message = "Could not update " + container + ": " + c.Error()
+			} else {
+				message = "The update of " + container + " has been applied."
+			}
+		}
+	}
+	renderPage(w, http.StatusOK, message, "")
+}
+
+func renderPage(w http.ResponseWriter, status int, message string, token string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := page.Execute(w, struct{ Message, Token string }{message, token}); err != nil {
+		log.WithError(err).Debug("Could not write the approval page")
+	}
+}
@@ -0,0 +1,92 @@
+package approval_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/containrrr/watchtower/pkg/api/approval"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+
+	. "github.com/containrrr/watchtower/internal/actions/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the approval links", func() {
+	var handler http.HandlerFunc
+	var signer *approval.Signer
+	var approved [][]string
+
+	BeforeEach(func() {
+		approved = nil
+		updateLock := make(chan bool, 1)
+		updateLock <- true
+		signer = approval.NewSigner("mytoken", "https://watchtower.example.com/")
+		approvals := approval.New(
+			func() []approval.Pending {
+				return []approval.Pending{{Container: "nginx", LatestImage: "0123456789ab"}}
+			},
+			func(containers []string) (types.Report, error) {
+				approved = append(approved, containers)
+				return CreateMockProgressReport(session.UpdatedState), nil
+			},
+			updateLock)
+		handler = approvals.HandleApproveToken(signer)
+	})
+
+	request := func(method string, link string) *httptest.ResponseRecorder {
+		parsed, err := url.Parse(link)
+		Expect(err).NotTo(HaveOccurred())
+		res := httptest.NewRecorder()
+		if method == http.MethodGet {
+			handler(res, httptest.NewRequest(method, parsed.RequestURI(), nil))
+			return res
+		}
+		form := url.Values{"token": {parsed.Query().Get("token")}}
+		req := httptest.NewRequest(method, parsed.Path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handler(res, req)
+		return res
+	}
+
+	It("should link to the public URL of the API", func() {
+		Expect(signer.Link("nginx", "0123456789ab")).To(HavePrefix("https://watchtower.example.com/v1/approve-token?token="))
+	})
+
+	It("should ask for a confirmation before approving the update", func() {
+		res := request(http.MethodGet, signer.Link("nginx", "0123456789ab"))
+		Expect(res.Code).To(Equal(http.StatusOK))
+		Expect(res.Body.String()).To(ContainSubstring("Approve the update of nginx to image 0123456789ab?"))
+		Expect(res.Body.String()).To(ContainSubstring(`<form method="post">`))
+		Expect(approved).To(BeEmpty())
+	})
+
+	It("should approve the update only once", func() {
+		link := signer.Link("nginx", "0123456789ab")
+		res := request(http.MethodPost, link)
+		Expect(res.Code).To(Equal(http.StatusOK))
+		Expect(res.Body.String()).To(ContainSubstring("The update of nginx has been applied."))
+		Expect(approved).To(Equal([][]string{{"nginx"}}))
+
+		res = request(http.MethodPost, link)
+		Expect(res.Code).To(Equal(http.StatusBadRequest))
+		Expect(res.Body.String()).To(ContainSubstring("already been used"))
+		Expect(approved).To(HaveLen(1))
+	})
+
+	It("should reject links signed using another token", func() {
+		other := approval.NewSigner("othertoken", "https://watchtower.example.com")
+		res := request(http.MethodPost, other.Link("nginx", "0123456789ab"))
+		Expect(res.Code).To(Equal(http.StatusBadRequest))
+		Expect(approved).To(BeEmpty())
+	})
+
+	It("should not approve an image that is no longer pending", func() {
+		res := request(http.MethodPost, signer.Link("nginx", "ba9876543210"))
+		Expect(res.Code).To(Equal(http.StatusConflict))
+		Expect(approved).To(BeEmpty())
+	})
+})
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// corsMaxAge is how long browsers may cache the response to a preflight request, in seconds
+const corsMaxAge = "600"
+
+// allowedOrigin returns the origin allowed to read the response to a request sent by a page served from the origin,
+// being either the origin itself or any origin, or an empty string if the page may not call the API
+func (api *API) allowedOrigin(origin string) string {
+	allowed := ""
+	for _, o := range api.CORSOrigins {
+		if o == "*" {
+			allowed = "*"
+		} else if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return origin
+		}
+	}
+	return allowed
+}
+
+// cors wraps the handler, adding the CORS headers to the responses to the allowed origins, and answering their
+// preflight requests, which browsers send without credentials before calling the API from a page of another origin.
+// Only the origins allowed by name may send the credentials the browser keeps, like the ones of basic authentication.
+func (api *API) cors(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := ""
+		if origin != "" {
+			allowed = api.allowedOrigin(origin)
+		}
+		if allowed == "" {
+			fn(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		header.Set("Access-Control-Allow-Origin", allowed)
+		if allowed != "*" {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		header.Set("Access-Control-Expose-Headers", "Retry-After")
+
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			fn(w, r)
+			return
+		}
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		} else {
+			header.Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		}
+		header.Set("Access-Control-Max-Age", corsMaxAge)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
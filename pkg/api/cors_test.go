@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CORS", func() {
+	serve := func(origins []string, method string, origin string, auth string) *httptest.ResponseRecorder {
+		api := New(token)
+		api.CORSOrigins = origins
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/hello", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			req.Header.Set("Access-Control-Request-Headers", "authorization")
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		api.wrap(api.RequireToken(testHandler))(rec, req)
+		return rec
+	}
+	dashboard := "https://dashboard.example.com"
+
+	It("should answer the preflight requests of the allowed origins without credentials", func() {
+		rec := serve([]string{dashboard}, http.MethodOptions, dashboard, "")
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(rec.Header().Get("Access-Control-Allow-Origin")).To(Equal(dashboard))
+		Expect(rec.Header().Get("Access-Control-Allow-Methods")).To(ContainSubstring("POST"))
+		Expect(rec.Header().Get("Access-Control-Allow-Headers")).To(Equal("authorization"))
+		Expect(rec.Header().Get("Access-Control-Allow-Credentials")).To(Equal("true"))
+	})
+
+	It("should add the headers to the responses to the allowed origins", func() {
+		rec := serve([]string{dashboard + "/"}, http.MethodGet, dashboard, "Bearer "+token)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Access-Control-Allow-Origin")).To(Equal(dashboard))
+	})
+
+	It("should not add the headers for other origins", func() {
+		rec := serve([]string{dashboard}, http.MethodOptions, "https://evil.example.com", "")
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rec.Header().Get("Access-Control-Allow-Origin")).To(BeEmpty())
+	})
+
+	It("should allow any origin without credentials", func() {
+		rec := serve([]string{"*"}, http.MethodGet, "https://any.example.com", "Bearer "+token)
+		Expect(rec.Header().Get("Access-Control-Allow-Origin")).To(Equal("*"))
+		Expect(rec.Header().Get("Access-Control-Allow-Credentials")).To(BeEmpty())
+	})
+
+	It("should not add the headers without origins", func() {
+		rec := serve(nil, http.MethodGet, dashboard, "Bearer "+token)
+		Expect(rec.Header().Get("Access-Control-Allow-Origin")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,209 @@
+package dashboard
+
+import (
+	_ "embed" // the page of the dashboard is embedded in the binary
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// historySize is how many of the most recent sessions are kept
+const historySize = 20
+
+//go:embed index.html
+var page []byte
+
+// Container is the state of a container as of the last session that checked it
+type Container struct {
+	Name          string    `json:"name"`
+	Image         string    `json:"image"`
+	State         string    `json:"state"`
+	Error         string    `json:"error,omitempty"`
+	SkipReason    string    `json:"skipReason,omitempty"`
+	CurrentImage  string    `json:"currentImage"`
+	LatestImage   string    `json:"latestImage,omitempty"`
+	ReleaseURL    string    `json:"releaseUrl,omitempty"`
+	LastCheckedAt time.Time `json:"lastCheckedAt"`
+}
+
+// Session is the outcome of an update session
+type Session struct {
+	FinishedAt time.Time `json:"finishedAt"`
+	ReportOnly bool      `json:"reportOnly"`
+	Scanned    int       `json:"scanned"`
+	Updated    int       `json:"updated"`
+	Failed     int       `json:"failed"`
+	Stale      int       `json:"stale"`
+	Skipped    int       `json:"skipped"`
+}
+
+// Status is what the dashboard shows: the monitored containers, the pending updates and the recent sessions
+type Status struct {
+	Paused     bool        `json:"paused"`
+	Running    bool        `json:"running"`
+	Pending    int         `json:"pending"`
+	Containers []Container `json:"containers"`
+	History    []Session   `json:"history"`
+}
+
+// New is a factory function creating a new Handler instance. The update lock is used to only trigger a session if
+// none is running already.
+func New(updateFn func(), setPausedFn func(paused bool), pausedFn func() bool, updateLock chan bool) *Handler {
+	return &Handler{
+		update:     updateFn,
+		setPaused:  setPausedFn,
+		paused:     pausedFn,
+		lock:       updateLock,
+		containers: map[string]Container{},
+		PagePath:   "/dashboard",
+		Path:       "/v1/dashboard",
+	}
+}
+
+// Handler serves the dashboard page, and the API endpoints it uses to show the status and to trigger or pause updates
+type Handler struct {
+	update     func()
+	setPaused  func(paused bool)
+	paused     func() bool
+	lock       chan bool
+	mutex      sync.Mutex
+	containers map[string]Container
+	history    []Session
+	PagePath   string
+	Path       string
+}
+
+// Record keeps the outcome of a finished session, and the state of each of the containers it checked
+func (handle *Handler) Record(report types.Report, reportOnly bool) {
+	if report == nil {
+		return
+	}
+	handle.mutex.Lock()
+	defer handle.mutex.Unlock()
+
+	now := time.Now()
+	for _, c := range report.All() {
+		name := strings.TrimPrefix(c.Name(), "/")
+		container := Container{
+			Name:          name,
+			Image:         c.ImageName(),
+			State:         c.State(),
+			Error:         c.Error(),
+			SkipReason:    string(c.SkipReason()),
+			CurrentImage:  c.CurrentImageID().ShortID(),
+			ReleaseURL:    c.Release().URL,
+			LastCheckedAt: now,
+		}
+		if c.LatestImageID() != "" && c.LatestImageID() != c.CurrentImageID() {
+			container.LatestImage = c.LatestImageID().ShortID()
+		}
+		handle.containers[name] = container
+	}
+
+	session := Session{
+		FinishedAt: now,
+		ReportOnly: reportOnly,
+		Scanned:    len(report.Scanned()),
+		Updated:    len(report.Updated()),
+		Failed:     len(report.Failed()),
+		Stale:      len(report.Stale()),
+		Skipped:    len(report.Skipped()),
+	}
+	handle.history = append([]Session{session}, handle.history...)
+	if len(handle.history) > historySize {
+		handle.history = handle.history[:historySize]
+	}
+}
+
+// Status returns the current status of the dashboard, with the containers sorted by name and the most recent
+// session first
+func (handle *Handler) Status() Status {
+	handle.mutex.Lock()
+	defer handle.mutex.Unlock()
+
+	status := Status{
+		Paused:     handle.paused(),
+		Running:    len(handle.lock) == 0,
+		Containers: make([]Container, 0, len(handle.containers)),
+		History:    append([]Session{}, handle.history...),
+	}
+	for _, c := range handle.containers {
+		if c.State == "Stale" {
+			status.Pending++
+		}
+		status.Containers = append(status.Containers, c)
+	}
+	sort.Slice(status.Containers, func(i, j int) bool {
+		return status.Containers[i].Name < status.Containers[j].Name
+	})
+	return status
+}
+
+// HandlePage serves the page of the dashboard, which asks for the API token to retrieve the status
+func (handle *Handler) HandlePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(page)
+}
+
+// Handle returns the status on GET requests to the path. POST requests to the update sub path trigger an update
+// session, and to the pause and resume sub paths pause or resume the scheduled sessions.
+func (handle *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, handle.Path), "/")
+	if action == "" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, handle.Status())
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	switch action {
+	case "update":
+		select {
+		case chanValue := <-handle.lock:
+			log.Info("Updates triggered from the dashboard.")
+			go func() {
+				defer func() { handle.lock <- chanValue }()
+				handle.update()
+			}()
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.Error(w, "another update is already running", http.StatusConflict)
+		}
+	case "pause":
+		log.Info("Scheduled updates paused from the dashboard.")
+		handle.setPaused(true)
+		writeJSON(w, handle.Status())
+	case "resume":
+		log.Info("Scheduled updates resumed from the dashboard.")
+		handle.setPaused(false)
+		writeJSON(w, handle.Status())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.WithError(err).Debug("Could not write the API response")
+	}
+}
@@ -0,0 +1,120 @@
+package dashboard_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/api/dashboard"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/sirupsen/logrus"
+
+	. "github.com/containrrr/watchtower/internal/actions/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDashboard(t *testing.T) {
+	RegisterFailHandler(Fail)
+	logrus.SetOutput(GinkgoWriter)
+	RunSpecs(t, "Dashboard Suite")
+}
+
+var _ = Describe("the dashboard", func() {
+	var handler *dashboard.Handler
+	var updateLock chan bool
+	var updates chan bool
+	var paused bool
+
+	BeforeEach(func() {
+		paused = false
+		updates = make(chan bool, 1)
+		updateLock = make(chan bool, 1)
+		updateLock <- true
+		handler = dashboard.New(
+			func() { updates <- true },
+			func(value bool) { paused = value },
+			func() bool { return paused },
+			updateLock)
+	})
+
+	getStatus := func() dashboard.Status {
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodGet, handler.Path, nil))
+		Expect(res.Code).To(Equal(http.StatusOK))
+		var status dashboard.Status
+		Expect(json.NewDecoder(res.Body).Decode(&status)).To(Succeed())
+		return status
+	}
+
+	It("should serve the page", func() {
+		res := httptest.NewRecorder()
+		handler.HandlePage(res, httptest.NewRequest(http.MethodGet, handler.PagePath, nil))
+		Expect(res.Code).To(Equal(http.StatusOK))
+		Expect(res.Header().Get("Content-Type")).To(HavePrefix("text/html"))
+		Expect(res.Body.String()).To(ContainSubstring("/v1/dashboard"))
+	})
+
+	It("should show the containers and sessions that were recorded", func() {
+		handler.Record(CreateMockProgressReport(session.UpdatedState, session.FreshState), false)
+		handler.Record(CreateMockProgressReport(session.StaleState, session.FreshState), true)
+
+		status := getStatus()
+		Expect(status.Pending).To(Equal(1))
+		Expect(status.Running).To(BeFalse())
+		names := []string{}
+		for _, c := range status.Containers {
+			names = append(names, c.Name)
+		}
+		Expect(names).To(Equal([]string{"frsh1", "stal1", "updt1"}))
+		Expect(status.Containers[1].State).To(Equal("Stale"))
+		Expect(status.Containers[1].LatestImage).NotTo(BeEmpty())
+
+		Expect(status.History).To(HaveLen(2))
+		Expect(status.History[0].ReportOnly).To(BeTrue())
+		Expect(status.History[0].Stale).To(Equal(1))
+		Expect(status.History[1].Updated).To(Equal(1))
+	})
+
+	It("should trigger an update session", func() {
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodPost, handler.Path+"/update", nil))
+		Expect(res.Code).To(Equal(http.StatusAccepted))
+		Eventually(updates).Should(Receive())
+		Eventually(updateLock).Should(HaveLen(1))
+	})
+
+	It("should not trigger an update session while another one is running", func() {
+		<-updateLock
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodPost, handler.Path+"/update", nil))
+		Expect(res.Code).To(Equal(http.StatusConflict))
+		Consistently(updates, 100*time.Millisecond).ShouldNot(Receive())
+		Expect(getStatus().Running).To(BeTrue())
+	})
+
+	It("should pause and resume the scheduled updates", func() {
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodPost, handler.Path+"/pause", nil))
+		Expect(res.Code).To(Equal(http.StatusOK))
+		Expect(paused).To(BeTrue())
+		Expect(getStatus().Paused).To(BeTrue())
+
+		res = httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodPost, handler.Path+"/resume", nil))
+		Expect(res.Code).To(Equal(http.StatusOK))
+		Expect(paused).To(BeFalse())
+	})
+
+	It("should reject other methods and actions", func() {
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodGet, handler.Path+"/pause", nil))
+		Expect(res.Code).To(Equal(http.StatusMethodNotAllowed))
+
+		res = httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodPost, handler.Path+"/restart", nil))
+		Expect(res.Code).To(Equal(http.StatusNotFound))
+	})
+})
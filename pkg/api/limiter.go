@@ -0,0 +1,197 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxLockout caps the lockout of a source, as it doubles with each lockout
+const maxLockout = time.Hour
+
+// Reasons for rejecting a request, as counted by the metrics
+const (
+	rejectedInvalidToken = "invalid-token"
+	rejectedRateLimited  = "rate-limited"
+	rejectedLockedOut    = "locked-out"
+)
+
+// Limiter limits the number of requests each source can send per minute, and locks out the sources repeatedly sending
+// invalid tokens. The lockout doubles with every invalid token sent after a lockout, until a valid one is sent.
+type Limiter struct {
+	// RatePerMinute is the number of requests a source can send per minute, or 0 for no limit
+	RatePerMinute int
+	// Attempts is the number of invalid tokens after which a source is locked out, or 0 to never lock out sources
+	Attempts int
+	// Lockout is how long a source is locked out the first time
+	Lockout time.Duration
+	// TrustProxy identifies the sources by the last address of the X-Forwarded-For header, as set by a reverse proxy
+	TrustProxy bool
+
+	mutex   sync.Mutex
+	sources map[string]*source
+	pruned  time.Time
+	now     func() time.Time
+}
+
+// source is the state of the requests of a single address
+type source struct {
+	lastSeen    time.Time
+	windowStart time.Time
+	requests    int
+	failures    int
+	lockouts    int
+	lockedUntil time.Time
+}
+
+// NewLimiter returns a limiter allowing each source the rate of requests per minute, and locking it out for the
+// lockout after the number of invalid tokens
+func NewLimiter(ratePerMinute int, attempts int, lockout time.Duration, trustProxy bool) *Limiter {
+	return &Limiter{
+		RatePerMinute: ratePerMinute,
+		Attempts:      attempts,
+		Lockout:       lockout,
+		TrustProxy:    trustProxy,
+		sources:       map[string]*source{},
+		now:           time.Now,
+	}
+}
+
+// Enabled returns whether the limiter limits the rate, or locks out sources
+func (l *Limiter) Enabled() bool {
+	return l != nil && (l.RatePerMinute > 0 || (l.Attempts > 0 && l.Lockout > 0))
+}
+
+// Limit wraps the handler, rejecting the requests of the sources that are locked out or exceed the rate
+func (l *Limiter) Limit(fn http.HandlerFunc) http.HandlerFunc {
+	if !l.Enabled() {
+		return fn
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if wait, reason := l.allow(l.sourceOf(r)); wait > 0 {
+			metrics.RegisterAPIRejection(reason)
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second).Seconds())))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fn(w, r)
+	}
+}
+
+// allow counts the request of the source, returning how long it has to wait and why, if it is rejected
+func (l *Limiter) allow(addr string) (time.Duration, string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	l.prune(now)
+
+	src := l.source(addr)
+	src.lastSeen = now
+	if now.Before(src.lockedUntil) {
+		return src.lockedUntil.Sub(now), rejectedLockedOut
+	}
+	if l.RatePerMinute <= 0 {
+		return 0, ""
+	}
+	if now.Sub(src.windowStart) >= time.Minute {
+		src.windowStart = now
+		src.requests = 0
+	}
+	src.requests++
+	if src.requests > l.RatePerMinute {
+		if src.requests == l.RatePerMinute+1 {
+			log.WithField("source", addr).Warnf("Rate limiting the HTTP API requests, as more than %d were sent within a minute", l.RatePerMinute)
+		}
+		return src.windowStart.Add(time.Minute).Sub(now), rejectedRateLimited
+	}
+	return 0, ""
+}
+
+// Failed counts an invalid token sent by the source of the request, locking the source out once it sent too many
+func (l *Limiter) Failed(r *http.Request) {
+	metrics.RegisterAPIRejection(rejectedInvalidToken)
+	if l == nil || l.Attempts <= 0 || l.Lockout <= 0 {
+		return
+	}
+	addr := l.sourceOf(r)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	src := l.source(addr)
+	src.failures++
+	if src.failures < l.Attempts {
+		log.WithField("source", addr).Debugf("Invalid HTTP API token, %d of %d attempts", src.failures, l.Attempts)
+		return
+	}
+	lockout := l.Lockout
+	for i := 0; i < src.lockouts && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > maxLockout && l.Lockout < maxLockout {
+		lockout = maxLockout
+	}
+	src.lockouts++
+	src.lockedUntil = l.now().Add(lockout)
+	metrics.RegisterAPILockout()
+	log.WithField("source", addr).Warnf("Locking out the source from the HTTP API for %s, after %d invalid tokens", lockout, src.failures)
+}
+
+// Succeeded forgets the invalid tokens sent by the source of the request, as it sent a valid one
+func (l *Limiter) Succeeded(r *http.Request) {
+	if l == nil || l.Attempts <= 0 {
+		return
+	}
+	addr := l.sourceOf(r)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if src, found := l.sources[addr]; found {
+		src.failures = 0
+		src.lockouts = 0
+	}
+}
+
+// source returns the state of the source, adding it if it is new
+func (l *Limiter) source(addr string) *source {
+	src, found := l.sources[addr]
+	if !found {
+		src = &source{}
+		l.sources[addr] = src
+	}
+	return src
+}
+
+// prune forgets the sources that have not sent a request for as long as the longest lockout, at most once a minute
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < time.Minute {
+		return
+	}
+	l.pruned = now
+	for addr, src := range l.sources {
+		if now.Sub(src.lastSeen) >= maxLockout && now.After(src.lockedUntil) {
+			delete(l.sources, addr)
+		}
+	}
+}
+
+// sourceOf returns the address the request was sent from. Behind a trusted reverse proxy, it is the last address of
+// the X-Forwarded-For header, being the one the proxy received the request from.
+func (l *Limiter) sourceOf(r *http.Request) string {
+	if l.TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			addrs := strings.Split(forwarded, ",")
+			if addr := strings.TrimSpace(addrs[len(addrs)-1]); addr != "" {
+				return addr
+			}
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the limiter", func() {
+	var api *API
+	var now time.Time
+
+	newAPI := func(rate int, attempts int, trustProxy bool) http.HandlerFunc {
+		now = time.Unix(1700000000, 0)
+		api = New(token)
+		api.Limiter = NewLimiter(rate, attempts, time.Minute, trustProxy)
+		api.Limiter.now = func() time.Time { return now }
+		return api.limit(api.RequireToken(testHandler))
+	}
+	send := func(handler http.HandlerFunc, auth string, addr string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/hello", nil)
+		req.RemoteAddr = addr + ":40000"
+		req.Header.Set("Authorization", auth)
+		handler(rec, req)
+		return rec
+	}
+
+	It("should limit the number of requests of each source per minute", func() {
+		handler := newAPI(2, 0, false)
+		Expect(send(handler, "Bearer "+token, "10.0.0.1").Code).To(Equal(http.StatusOK))
+		Expect(send(handler, "Bearer "+token, "10.0.0.1").Code).To(Equal(http.StatusOK))
+
+		now = now.Add(15 * time.Second)
+		rec := send(handler, "Bearer "+token, "10.0.0.1")
+		Expect(rec.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(rec.Header().Get("Retry-After")).To(Equal("45"))
+		Expect(send(handler, "Bearer "+token, "10.0.0.2").Code).To(Equal(http.StatusOK))
+
+		now = now.Add(45 * time.Second)
+		Expect(send(handler, "Bearer "+token, "10.0.0.1").Code).To(Equal(http.StatusOK))
+	})
+
+	It("should lock out the sources sending invalid tokens repeatedly", func() {
+		handler := newAPI(0, 2, false)
+		Expect(send(handler, "Bearer guess", "10.0.0.1").Code).To(Equal(http.StatusUnauthorized))
+		Expect(send(handler, "Bearer guess", "10.0.0.1").Code).To(Equal(http.StatusUnauthorized))
+		Expect(send(handler, "Bearer "+token, "10.0.0.1").Code).To(Equal(http.StatusTooManyRequests))
+		Expect(send(handler, "Bearer "+token, "10.0.0.2").Code).To(Equal(http.StatusOK))
+
+		now = now.Add(time.Minute)
+		Expect(send(handler, "Bearer guess", "10.0.0.1").Code).To(Equal(http.StatusUnauthorized))
+		now = now.Add(time.Minute)
+		rec := send(handler, "Bearer "+token, "10.0.0.1")
+		Expect(rec.Code).To(Equal(http.StatusTooManyRequests), "the second lockout should be twice as long")
+		Expect(rec.Header().Get("Retry-After")).To(Equal("60"))
+
+		now = now.Add(time.Minute)
+		Expect(send(handler, "Bearer "+token, "10.0.0.1").Code).To(Equal(http.StatusOK))
+		Expect(send(handler, "Bearer guess", "10.0.0.1").Code).To(Equal(http.StatusUnauthorized))
+		Expect(send(handler, "Bearer "+token, "10.0.0.1").Code).To(Equal(http.StatusOK),
+			"a valid token should reset the invalid ones")
+	})
+
+	It("should identify the sources by the address forwarded by a trusted proxy", func() {
+		handler := newAPI(0, 1, true)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/hello", nil)
+		req.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7")
+		handler(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+
+		Expect(send(handler, "Bearer "+token, "198.51.100.7").Code).To(Equal(http.StatusTooManyRequests))
+		Expect(send(handler, "Bearer "+token, "192.0.2.1").Code).To(Equal(http.StatusOK))
+	})
+
+	It("should not be enabled without a rate or lockout", func() {
+		Expect(NewLimiter(0, 5, 0, false).Enabled()).To(BeFalse())
+		Expect((*Limiter)(nil).Enabled()).To(BeFalse())
+	})
+})
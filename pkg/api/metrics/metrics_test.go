@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/containrrr/watchtower/pkg/api"
 	metricsAPI "github.com/containrrr/watchtower/pkg/api/metrics"
@@ -87,4 +88,21 @@ var _ = Describe("the metrics API", func() {
 			HaveKeyWithValue("watchtower_scans_skipped", "3"),
 		))
 	})
+
+	It("should serve the durations of the sessions, pulls and recreations", func() {
+		metrics.RegisterScan(&metrics.Metric{
+			Duration:          90 * time.Second,
+			ScanDuration:      30 * time.Second,
+			PullDurations:     map[string]time.Duration{"nginx:latest": 20 * time.Second},
+			RecreateDurations: map[string]time.Duration{"web": 5 * time.Second},
+		})
+		Eventually(metrics.Default().QueueIsEmpty).Should(BeTrue())
+
+		Eventually(tryGetMetrics).Should(SatisfyAll(
+			HaveKeyWithValue("watchtower_session_duration_seconds_sum", "90"),
+			HaveKeyWithValue("watchtower_scan_duration_seconds_sum", "30"),
+			HaveKeyWithValue(`watchtower_image_pull_duration_seconds_sum{image="nginx:latest"}`, "20"),
+			HaveKeyWithValue(`watchtower_container_recreate_duration_seconds_count{container="web"}`, "1"),
+		))
+	})
 })
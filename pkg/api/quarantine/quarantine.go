@@ -0,0 +1,68 @@
+package quarantine
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// New is a factory function creating a new Handler instance. The update lock is held while releasing containers, so
+// that a running session does not overwrite the changes when it finishes.
+func New(listFn func() []types.QuarantinedContainer, releaseFn func(names []string) []string, updateLock chan bool) *Handler {
+	return &Handler{
+		list:    listFn,
+		release: releaseFn,
+		lock:    updateLock,
+		Path:    "/v1/quarantine",
+	}
+}
+
+// Handler is an API handler used for listing and releasing the quarantined containers
+type Handler struct {
+	list    func() []types.QuarantinedContainer
+	release func(names []string) []string
+	lock    chan bool
+	Path    string
+}
+
+// Handle lists the quarantined containers on GET requests, and releases the containers passed using the container
+// query parameter on DELETE requests
+func (handle *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, handle.list())
+	case http.MethodDelete:
+		var names []string
+		for _, value := range r.URL.Query()["container"] {
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+		if len(names) == 0 {
+			http.Error(w, "no containers to release were passed using the container query parameter", http.StatusBadRequest)
+			return
+		}
+
+		log.Info("Releasing quarantined containers by HTTP API request.")
+		if handle.lock != nil {
+			chanValue := <-handle.lock
+			defer func() { handle.lock <- chanValue }()
+		}
+		writeJSON(w, map[string][]string{"released": handle.release(names)})
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.WithError(err).Debug("Could not write the API response")
+	}
+}
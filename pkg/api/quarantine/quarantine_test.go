@@ -0,0 +1,77 @@
+package quarantine_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containrrr/watchtower/pkg/api/quarantine"
+	"github.com/containrrr/watchtower/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestQuarantine(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Quarantine Suite")
+}
+
+var _ = Describe("the quarantine API", func() {
+	var handler *quarantine.Handler
+	var releasedNames []string
+
+	BeforeEach(func() {
+		releasedNames = nil
+		updateLock := make(chan bool, 1)
+		updateLock <- true
+		handler = quarantine.New(
+			func() []types.QuarantinedContainer {
+				return []types.QuarantinedContainer{{Name: "foo", Sessions: 3, LastError: "pull failed"}}
+			},
+			func(names []string) []string {
+				releasedNames = names
+				return names[:1]
+			},
+			updateLock)
+	})
+
+	It("should list the quarantined containers", func() {
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodGet, handler.Path, nil))
+
+		Expect(res.Code).To(Equal(http.StatusOK))
+		var body []types.QuarantinedContainer
+		Expect(json.NewDecoder(res.Body).Decode(&body)).To(Succeed())
+		Expect(body).To(HaveLen(1))
+		Expect(body[0].Name).To(Equal("foo"))
+		Expect(body[0].Sessions).To(Equal(3))
+	})
+
+	It("should release the passed containers", func() {
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodDelete, handler.Path+"?container=foo,bar&container=baz", nil))
+
+		Expect(res.Code).To(Equal(http.StatusOK))
+		Expect(releasedNames).To(Equal([]string{"foo", "bar", "baz"}))
+		var body map[string][]string
+		Expect(json.NewDecoder(res.Body).Decode(&body)).To(Succeed())
+		Expect(body["released"]).To(Equal([]string{"foo"}))
+	})
+
+	It("should reject release requests without containers", func() {
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodDelete, handler.Path, nil))
+
+		Expect(res.Code).To(Equal(http.StatusBadRequest))
+		Expect(releasedNames).To(BeNil())
+	})
+
+	It("should reject other methods", func() {
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodPost, handler.Path, nil))
+
+		Expect(res.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
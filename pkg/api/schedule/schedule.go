@@ -0,0 +1,152 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultCount is how many run times are returned if no count is requested, and maxCount the most that can be
+const (
+	defaultCount = 5
+	maxCount     = 100
+)
+
+// Schedule is a cron expression along with the next times it runs at
+type Schedule struct {
+	Spec     string      `json:"spec"`
+	NextRuns []time.Time `json:"nextRuns"`
+}
+
+// timezonePrefixes are the prefixes of a cron expression setting the time zone it is evaluated in
+var timezonePrefixes = []string{"CRON_TZ=", "TZ="}
+
+// Parse parses the cron expression. The expression can start with the time zone it is evaluated in, like
+// `CRON_TZ=Europe/Kyiv 0 0 3 * * *`, overriding the local time zone of watchtower for this schedule only.
+func Parse(spec string) (cron.Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	var location *time.Location
+	for _, prefix := range timezonePrefixes {
+		if !strings.HasPrefix(spec, prefix) {
+			continue
+		}
+		fields := strings.SplitN(strings.TrimPrefix(spec, prefix), " ", 2)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("missing the cron expression after the time zone %q", fields[0])
+		}
+		var err error
+		if location, err = time.LoadLocation(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", fields[0], err)
+		}
+		spec = strings.TrimSpace(fields[1])
+		break
+	}
+
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	// the schedules without a time zone of their own keep running the way they always have across daylight saving time
+	// changes
+	if spec, ok := schedule.(*cron.SpecSchedule); ok && location != nil {
+		return wallClockSchedule{schedule: spec, location: location}, nil
+	}
+	return schedule, nil
+}
+
+// wallClockSchedule evaluates a schedule by the wall clock of its time zone. Unlike the schedule itself, it does not
+// run twice when the clocks go back.
+type wallClockSchedule struct {
+	schedule *cron.SpecSchedule
+	location *time.Location
+}
+
+// Next returns the next time the schedule runs at after the given time, skipping the repeated wall clock times
+func (s wallClockSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.location)
+	next := s.schedule.Next(t)
+	for !next.IsZero() && !wallClock(next).After(wallClock(t)) {
+		next = s.schedule.Next(next)
+	}
+	return next
+}
+
+// wallClock returns the time as shown by the clock of its time zone, ignoring the offset
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// NextRuns parses the cron expression and returns the next count times it runs at after the given time
+func NextRuns(spec string, after time.Time, count int) ([]time.Time, error) {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]time.Time, 0, count)
+	for next := after; len(runs) < count; {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next)
+	}
+	return runs, nil
+}
+
+// New is a factory function creating a new Handler instance for the cron expressions of the schedules, by the kind of
+// the sessions they schedule. The schedules that are not set are left out.
+func New(specs map[string]string) *Handler {
+	return &Handler{
+		specs: specs,
+		Path:  "/v1/schedule",
+	}
+}
+
+// Handler is an API handler used for previewing when the scheduled sessions will run
+type Handler struct {
+	specs map[string]string
+	Path  string
+}
+
+// Handle returns the next run times of each of the schedules, as many as passed using the count query parameter
+func (handle *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	count := defaultCount
+	if value := r.URL.Query().Get("count"); value != "" {
+		var err error
+		if count, err = strconv.Atoi(value); err != nil || count < 1 || count > maxCount {
+			http.Error(w, "the count must be a number between 1 and 100", http.StatusBadRequest)
+			return
+		}
+	}
+
+	now := time.Now()
+	response := map[string]Schedule{}
+	for name, spec := range handle.specs {
+		if spec == "" {
+			continue
+		}
+		runs, err := NextRuns(spec, now, count)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response[name] = Schedule{Spec: spec, NextRuns: runs}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.WithError(err).Debug("Could not write the API response")
+	}
+}
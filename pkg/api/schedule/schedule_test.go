@@ -0,0 +1,116 @@
+package schedule_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/api/schedule"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSchedule(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Schedule Suite")
+}
+
+var _ = Describe("the schedule API", func() {
+	It("should list the next run times of the schedule", func() {
+		after := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+		runs, err := schedule.NextRuns("0 0 4 * * *", after, 3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runs).To(Equal([]time.Time{
+			time.Date(2023, 3, 2, 4, 0, 0, 0, time.UTC),
+			time.Date(2023, 3, 3, 4, 0, 0, 0, time.UTC),
+			time.Date(2023, 3, 4, 4, 0, 0, 0, time.UTC),
+		}))
+	})
+
+	It("should evaluate the schedule in the time zone it starts with", func() {
+		kyiv, err := time.LoadLocation("Europe/Kyiv")
+		Expect(err).NotTo(HaveOccurred())
+		after := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+		for _, spec := range []string{"CRON_TZ=Europe/Kyiv 0 0 4 * * *", "TZ=Europe/Kyiv 0 0 4 * * *"} {
+			runs, err := schedule.NextRuns(spec, after, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runs[0].Equal(time.Date(2023, 3, 2, 4, 0, 0, 0, kyiv))).To(BeTrue())
+			Expect(runs[0].UTC().Hour()).To(Equal(2))
+		}
+	})
+
+	It("should keep to the local time of the time zone across daylight saving time changes", func() {
+		// The clocks in Kyiv go back from 04:00 to 03:00 on the last Sunday of October
+		after := time.Date(2023, 10, 28, 12, 0, 0, 0, time.UTC)
+		runs, err := schedule.NextRuns("CRON_TZ=Europe/Kyiv 0 30 3 * * *", after, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runs[0].UTC()).To(Equal(time.Date(2023, 10, 29, 0, 30, 0, 0, time.UTC)))
+		// The second 03:30 of the day is skipped
+		Expect(runs[1].UTC()).To(Equal(time.Date(2023, 10, 30, 1, 30, 0, 0, time.UTC)))
+	})
+
+	It("should keep running the schedules without a time zone at the repeated times, like before", func() {
+		kyiv, err := time.LoadLocation("Europe/Kyiv")
+		Expect(err).NotTo(HaveOccurred())
+		// The clocks in Kyiv go back from 04:00 to 03:00 on the last Sunday of October
+		after := time.Date(2023, 10, 29, 2, 0, 0, 0, kyiv)
+		runs, err := schedule.NextRuns("0 30 3 * * *", after, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runs[0].UTC()).To(Equal(time.Date(2023, 10, 29, 0, 30, 0, 0, time.UTC)))
+		Expect(runs[1].UTC()).To(Equal(time.Date(2023, 10, 29, 1, 30, 0, 0, time.UTC)))
+	})
+
+	It("should keep running constant delay schedules across daylight saving time changes", func() {
+		after := time.Date(2023, 10, 29, 0, 30, 0, 0, time.UTC)
+		runs, err := schedule.NextRuns("CRON_TZ=Europe/Kyiv @every 1h", after, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runs[0].UTC()).To(Equal(time.Date(2023, 10, 29, 1, 30, 0, 0, time.UTC)))
+	})
+
+	It("should fail for unknown time zones or a missing cron expression", func() {
+		_, err := schedule.Parse("CRON_TZ=Nowhere/Atlantis 0 0 3 * * *")
+		Expect(err).To(HaveOccurred())
+		_, err = schedule.Parse("CRON_TZ=Europe/Kyiv")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail for invalid cron expressions", func() {
+		_, err := schedule.NextRuns("not a schedule", time.Now(), 3)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return the update and report schedules", func() {
+		handler := schedule.New(map[string]string{"update": "@every 1h", "report": "@every 10m"})
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodGet, handler.Path+"?count=2", nil))
+
+		Expect(res.Code).To(Equal(http.StatusOK))
+		var body map[string]schedule.Schedule
+		Expect(json.NewDecoder(res.Body).Decode(&body)).To(Succeed())
+		Expect(body["update"].Spec).To(Equal("@every 1h"))
+		Expect(body["update"].NextRuns).To(HaveLen(2))
+		Expect(body["report"].NextRuns).To(HaveLen(2))
+		Expect(body["report"].NextRuns[0]).To(BeTemporally("<", body["update"].NextRuns[0]))
+	})
+
+	It("should return five run times by default and leave out an unset report schedule", func() {
+		handler := schedule.New(map[string]string{"update": "0 0 4 * * *", "report": ""})
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodGet, handler.Path, nil))
+
+		var body map[string]schedule.Schedule
+		Expect(json.NewDecoder(res.Body).Decode(&body)).To(Succeed())
+		Expect(body["update"].NextRuns).To(HaveLen(5))
+		Expect(body).NotTo(HaveKey("report"))
+	})
+
+	It("should reject invalid counts", func() {
+		handler := schedule.New(map[string]string{"update": "0 0 4 * * *", "report": ""})
+		res := httptest.NewRecorder()
+		handler.Handle(res, httptest.NewRequest(http.MethodGet, handler.Path+"?count=1000", nil))
+		Expect(res.Code).To(Equal(http.StatusBadRequest))
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -13,8 +14,9 @@ var (
 	lock chan bool
 )
 
-// New is a factory function creating a new  Handler instance
-func New(updateFn func(images []string), updateLock chan bool) *Handler {
+// New is a factory function creating a new  Handler instance. The update function is told whether the session should
+// only apply the staged updates.
+func New(updateFn func(images []string, applyStaged bool), updateLock chan bool) *Handler {
 	if updateLock != nil {
 		lock = updateLock
 	} else {
@@ -30,7 +32,7 @@ func New(updateFn func(images []string), updateLock chan bool) *Handler {
 
 // Handler is an API handler used for triggering container update scans
 type Handler struct {
-	fn   func(images []string)
+	fn   func(images []string, applyStaged bool)
 	Path string
 }
 
@@ -55,15 +57,17 @@ func (handle *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 		images = nil
 	}
 
+	applyStaged, _ := strconv.ParseBool(r.URL.Query().Get("staged"))
+
 	if len(images) > 0 {
 		chanValue := <-lock
 		defer func() { lock <- chanValue }()
-		handle.fn(images)
+		handle.fn(images, applyStaged)
 	} else {
 		select {
 		case chanValue := <-lock:
 			defer func() { lock <- chanValue }()
-			handle.fn(images)
+			handle.fn(images, applyStaged)
 		default:
 			log.Debug("Skipped. Another update already running.")
 		}
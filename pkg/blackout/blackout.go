@@ -0,0 +1,193 @@
+package blackout
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDays is how many days ahead Until looks for the end of a blackout, after which it gives up
+const maxDays = 2 * 366
+
+// Calendar is a set of days on which no updates are applied, like seasonal change freezes
+type Calendar []Period
+
+// Period is a set of days, described by the expression it was parsed from
+type Period struct {
+	Spec   string
+	covers func(year int, month time.Month, day int) bool
+}
+
+var (
+	monthDayPattern = regexp.MustCompile(`^([a-z]+)\.? +(\d{1,2})(?: *(?:-|to) *(?:([a-z]+)\.? +)?(\d{1,2}))?$`)
+	datePattern     = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(?: +to +(\d{4}-\d{2}-\d{2}))?$`)
+	weekdayPattern  = regexp.MustCompile(`^(?:(first|second|third|fourth|last) +)?([a-z]+?)s?(?: +(?:of|in) +(?:the|every|each) +month| +of +month)?$`)
+)
+
+var ordinals = map[string]int{"first": 1, "second": 2, "third": 3, "fourth": 4, "last": -1}
+
+// Parse parses the blackout periods. Each of them is either
+//   - a day or a range of days of every year, like `Dec 24`, `Dec 24-26` or `Dec 24-Jan 2`,
+//   - a date or a range of dates, like `2026-11-27` or `2026-11-27 to 2026-11-30`,
+//   - a day of the week, like `Friday`, optionally limited to the first to fourth or the last one of every month, like
+//     `last Friday of month`.
+func Parse(specs []string) (Calendar, error) {
+	calendar := make(Calendar, 0, len(specs))
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		covers, err := parsePeriod(strings.ToLower(strings.Join(strings.Fields(spec), " ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid blackout period %q: %w", spec, err)
+		}
+		calendar = append(calendar, Period{Spec: spec, covers: covers})
+	}
+	return calendar, nil
+}
+
+func parsePeriod(spec string) (func(int, time.Month, int) bool, error) {
+	if match := datePattern.FindStringSubmatch(spec); match != nil {
+		return parseDates(match[1], match[2])
+	}
+	if match := monthDayPattern.FindStringSubmatch(spec); match != nil {
+		if _, found := parseMonth(match[1]); found {
+			return parseMonthDays(match[1], match[2], match[3], match[4])
+		}
+	}
+	if match := weekdayPattern.FindStringSubmatch(spec); match != nil {
+		if weekday, found := parseWeekday(match[2]); found {
+			return weekdayCovers(weekday, ordinals[match[1]]), nil
+		}
+	}
+	return nil, fmt.Errorf("expected a day like Dec 24, a date like 2026-12-24, or a weekday like last Friday of month")
+}
+
+func parseDates(from string, to string) (func(int, time.Month, int) bool, error) {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, err
+	}
+	end := start
+	if to != "" {
+		if end, err = time.Parse("2006-01-02", to); err != nil {
+			return nil, err
+		}
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("the range ends before it starts")
+	}
+	return func(year int, month time.Month, day int) bool {
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return !date.Before(start) && !date.After(end)
+	}, nil
+}
+
+func parseMonthDays(fromMonth string, fromDay string, toMonth string, toDay string) (func(int, time.Month, int) bool, error) {
+	startMonth, _ := parseMonth(fromMonth)
+	endMonth := startMonth
+	if toMonth != "" {
+		var found bool
+		if endMonth, found = parseMonth(toMonth); !found {
+			return nil, fmt.Errorf("unknown month %q", toMonth)
+		}
+	}
+	startDay, err := parseDay(startMonth, fromDay)
+	if err != nil {
+		return nil, err
+	}
+	endDay := startDay
+	if toDay != "" {
+		if endDay, err = parseDay(endMonth, toDay); err != nil {
+			return nil, err
+		}
+	}
+
+	start, end := int(startMonth)*100+startDay, int(endMonth)*100+endDay
+	return func(_ int, month time.Month, day int) bool {
+		current := int(month)*100 + day
+		if start <= end {
+			return current >= start && current <= end
+		}
+		// the range wraps around the end of the year
+		return current >= start || current <= end
+	}, nil
+}
+
+func parseDay(month time.Month, value string) (int, error) {
+	day, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	// the days are checked against a leap year, for Feb 29 to be accepted
+	if day < 1 || day > time.Date(2024, month+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+		return 0, fmt.Errorf("%s has no day %d", month, day)
+	}
+	return day, nil
+}
+
+func weekdayCovers(weekday time.Weekday, ordinal int) func(int, time.Month, int) bool {
+	return func(year int, month time.Month, day int) bool {
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		if date.Weekday() != weekday {
+			return false
+		}
+		switch {
+		case ordinal > 0:
+			return (day-1)/7+1 == ordinal
+		case ordinal < 0:
+			return date.AddDate(0, 0, 7).Month() != month
+		}
+		return true
+	}
+}
+
+func parseMonth(value string) (time.Month, bool) {
+	for month := time.January; month <= time.December; month++ {
+		name := strings.ToLower(month.String())
+		if value == name || len(value) >= 3 && strings.HasPrefix(name, value) {
+			return month, true
+		}
+	}
+	return 0, false
+}
+
+func parseWeekday(value string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if value == name || len(value) >= 3 && strings.HasPrefix(name, value) {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
+
+// Active returns the period covering the day of the given time, in its time zone, if there is one
+func (calendar Calendar) Active(now time.Time) (Period, bool) {
+	year, month, day := now.Date()
+	for _, period := range calendar {
+		if period.covers(year, month, day) {
+			return period, true
+		}
+	}
+	return Period{}, false
+}
+
+// Until returns the start of the first day after the given time that is not covered by any of the periods, or the
+// given time itself if its day is not covered. Zero is returned if the blackout does not end within two years.
+func (calendar Calendar) Until(now time.Time) time.Time {
+	if _, active := calendar.Active(now); !active {
+		return now
+	}
+	year, month, day := now.Date()
+	for offset := 1; offset <= maxDays; offset++ {
+		next := time.Date(year, month, day+offset, 0, 0, 0, 0, now.Location())
+		if _, active := calendar.Active(next); !active {
+			return next
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,89 @@
+package blackout_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/blackout"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBlackout(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Blackout Suite")
+}
+
+func active(calendar blackout.Calendar, now time.Time) bool {
+	_, found := calendar.Active(now)
+	return found
+}
+
+func day(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+var _ = Describe("the blackout calendar", func() {
+	parse := func(specs ...string) blackout.Calendar {
+		calendar, err := blackout.Parse(specs)
+		Expect(err).NotTo(HaveOccurred())
+		return calendar
+	}
+
+	It("should cover the days of a range wrapping around the end of the year", func() {
+		calendar := parse("Dec 24-Jan 2")
+		Expect(active(calendar, day(2026, time.December, 23))).To(BeFalse())
+		for _, covered := range []time.Time{day(2026, time.December, 24), day(2026, time.December, 31), day(2027, time.January, 2)} {
+			period, found := calendar.Active(covered)
+			Expect(found).To(BeTrue())
+			Expect(period.Spec).To(Equal("Dec 24-Jan 2"))
+		}
+		Expect(active(calendar, day(2027, time.January, 3))).To(BeFalse())
+	})
+
+	It("should cover the days of a range within a month", func() {
+		calendar := parse("november 27 - 30")
+		Expect(active(calendar, day(2026, time.November, 26))).To(BeFalse())
+		Expect(active(calendar, day(2026, time.November, 28))).To(BeTrue())
+		Expect(active(calendar, day(2026, time.December, 1))).To(BeFalse())
+	})
+
+	It("should only cover the given dates of a single year", func() {
+		calendar := parse("2026-11-27 to 2026-11-30")
+		Expect(active(calendar, day(2026, time.November, 30))).To(BeTrue())
+		Expect(active(calendar, day(2027, time.November, 30))).To(BeFalse())
+	})
+
+	It("should cover the last weekday of every month", func() {
+		calendar := parse("last Friday of month")
+		Expect(active(calendar, day(2026, time.October, 30))).To(BeTrue())
+		Expect(active(calendar, day(2026, time.October, 23))).To(BeFalse())
+		Expect(active(calendar, day(2026, time.November, 27))).To(BeTrue())
+	})
+
+	It("should cover the numbered weekday of every month", func() {
+		calendar := parse("first Monday of the month")
+		Expect(active(calendar, day(2026, time.October, 5))).To(BeTrue())
+		Expect(active(calendar, day(2026, time.October, 12))).To(BeFalse())
+	})
+
+	It("should cover every given weekday", func() {
+		calendar := parse("Fridays")
+		Expect(active(calendar, day(2026, time.October, 16))).To(BeTrue())
+		Expect(active(calendar, day(2026, time.October, 15))).To(BeFalse())
+	})
+
+	It("should return when the blackout ends", func() {
+		calendar := parse("Dec 24-Jan 2", "Jan 3")
+		Expect(calendar.Until(day(2026, time.December, 30))).To(Equal(time.Date(2027, time.January, 4, 0, 0, 0, 0, time.UTC)))
+		Expect(calendar.Until(day(2026, time.December, 1))).To(Equal(day(2026, time.December, 1)))
+	})
+
+	It("should reject invalid periods", func() {
+		for _, spec := range []string{"Dec 32", "Feb 30", "someday", "2026-12-24 to 2026-12-01", "Dec 24-Foo 2"} {
+			_, err := blackout.Parse([]string{spec})
+			Expect(err).To(HaveOccurred(), spec)
+		}
+	})
+})
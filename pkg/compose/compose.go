@@ -0,0 +1,101 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultBinary is the binary used to run docker compose if none is configured
+const DefaultBinary = "docker"
+
+// outputLines limits how much of the output of a failed re-deploy is included in its error
+const outputLines = 5
+
+type deployer struct {
+	binary  string
+	timeout time.Duration
+}
+
+// New returns a deployer running `up -d` for the projects using the binary, which is either the docker CLI, using its
+// compose plugin, or the standalone docker-compose. The time it may take is limited by the timeout, if set.
+func New(binary string, timeout time.Duration) types.ComposeDeployer {
+	if binary == "" {
+		binary = DefaultBinary
+	}
+	return deployer{binary: binary, timeout: timeout}
+}
+
+// Up re-deploys the services of the project, making compose recreate the containers whose image has changed
+func (d deployer) Up(project types.ComposeProject) error {
+	args, err := d.args(project)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	log.WithField("project", project.Name).Infof("Re-deploying the compose project using %s %s", d.binary, strings.Join(args, " "))
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.binary, args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("re-deploying compose project %s did not finish within %s", project.Name, d.timeout)
+		}
+		return fmt.Errorf("could not re-deploy compose project %s: %w%s", project.Name, err, tail(output.String()))
+	}
+	log.WithField("project", project.Name).Debug(strings.TrimSpace(output.String()))
+	return nil
+}
+
+// args returns the arguments to the binary re-deploying the project
+func (d deployer) args(project types.ComposeProject) ([]string, error) {
+	if len(project.ConfigFiles) == 0 {
+		return nil, fmt.Errorf("the configuration files of compose project %s are unknown", project.Name)
+	}
+
+	var args []string
+	if name := strings.TrimSuffix(filepath.Base(d.binary), ".exe"); name == "docker" {
+		args = append(args, "compose")
+	}
+	args = append(args, "--project-name", project.Name)
+	if project.WorkingDir != "" {
+		args = append(args, "--project-directory", project.WorkingDir)
+	}
+	for _, file := range project.ConfigFiles {
+		args = append(args, "--file", file)
+	}
+	args = append(args, "up", "--detach")
+	if len(project.Services) > 0 {
+		// only the services of the updated containers are recreated, leaving the rest of the project as it is
+		args = append(append(args, "--no-deps"), project.Services...)
+	}
+	return args, nil
+}
+
+// tail returns the last lines of the output, to explain why the re-deploy failed
+func tail(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > outputLines {
+		lines = lines[len(lines)-outputLines:]
+	}
+	if joined := strings.Join(lines, "\n"); joined != "" {
+		return ": " + joined
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package compose
+
+import (
+	"testing"
+
+	"github.com/containrrr/watchtower/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCompose(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Compose Suite")
+}
+
+var _ = Describe("the compose deployer", func() {
+	project := types.ComposeProject{
+		Name:        "shop",
+		WorkingDir:  "/srv/shop",
+		ConfigFiles: []string{"/srv/shop/compose.yml", "/srv/shop/compose.prod.yml"},
+	}
+
+	It("should use the compose plugin of the docker CLI", func() {
+		args, err := New("", 0).(deployer).args(project)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{
+			"compose", "--project-name", "shop", "--project-directory", "/srv/shop",
+			"--file", "/srv/shop/compose.yml", "--file", "/srv/shop/compose.prod.yml", "up", "--detach",
+		}))
+	})
+
+	It("should only recreate the services of the updated containers", func() {
+		services := project
+		services.Services = []string{"web", "worker"}
+		args, err := New("", 0).(deployer).args(services)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args[len(args)-5:]).To(Equal([]string{"up", "--detach", "--no-deps", "web", "worker"}))
+	})
+
+	It("should call the standalone docker-compose directly", func() {
+		args, err := New("/usr/local/bin/docker-compose", 0).(deployer).args(project)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args[0]).To(Equal("--project-name"))
+	})
+
+	It("should refuse projects without configuration files", func() {
+		_, err := New("", 0).(deployer).args(types.ComposeProject{Name: "shop"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail if compose does not succeed", func() {
+		err := New("false", 0).Up(project)
+		Expect(err).To(MatchError(ContainSubstring("could not re-deploy compose project shop")))
+	})
+})
@@ -0,0 +1,68 @@
+package container
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/versions"
+	sdkClient "github.com/docker/docker/client"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// The first docker API versions supporting the features used when pulling images and recreating containers. Unless a
+// version is set explicitly, the client negotiates the highest version supported by both itself and the daemon, and
+// the features the negotiated version does not support are left out.
+const (
+	// stopTimeoutMinAPIVersion is the first version supporting Config.StopTimeout
+	stopTimeoutMinAPIVersion = "1.25"
+	// pullPlatformMinAPIVersion is the first version supporting the platform of the pulled images
+	pullPlatformMinAPIVersion = "1.32"
+	// deviceRequestsMinAPIVersion is the first version supporting HostConfig.DeviceRequests
+	deviceRequestsMinAPIVersion = "1.40"
+	// createPlatformMinAPIVersion is the first version supporting the platform of the created containers
+	createPlatformMinAPIVersion = "1.41"
+)
+
+// supportsAPI returns whether the API version used by the client is at least the supplied one
+func (client dockerClient) supportsAPI(minVersion string) bool {
+	return !versions.LessThan(client.api.ClientVersion(), minVersion)
+}
+
+// withAPIVersion returns a client of the same daemon using the supplied API version, for the requests which can not do
+// without a feature the API version set explicitly does not support. It fails if the daemon does not support it either.
+func (client dockerClient) withAPIVersion(ctx context.Context, version string) (sdkClient.CommonAPIClient, error) {
+	server, err := client.api.ServerVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if versions.LessThan(server.APIVersion, version) {
+		return nil, fmt.Errorf("docker API version %s is required, but the daemon only supports up to version %s",
+			version, server.APIVersion)
+	}
+	return sdkClient.NewClientWithOpts(
+		sdkClient.WithHost(client.api.DaemonHost()),
+		sdkClient.WithHTTPClient(client.api.HTTPClient()),
+		sdkClient.WithVersion(version))
+}
+
+// imagePlatform returns the platform of the image of the container, or nil if it is unknown. It is passed when
+// pulling the new image and recreating the container, so that containers running images for another platform, like
+// using emulation, keep doing so rather than switching to the platform of the daemon.
+func (c Container) imagePlatform() *specs.Platform {
+	if c.imageInfo == nil || c.imageInfo.Os == "" || c.imageInfo.Architecture == "" {
+		return nil
+	}
+	return &specs.Platform{OS: c.imageInfo.Os, Architecture: c.imageInfo.Architecture, Variant: c.imageInfo.Variant}
+}
+
+// platformString formats the platform like os/architecture/variant, as used when pulling images
+func platformString(platform *specs.Platform) string {
+	if platform == nil {
+		return ""
+	}
+	s := platform.OS + "/" + platform.Architecture
+	if platform.Variant != "" {
+		s += "/" + platform.Variant
+	}
+	return s
+}
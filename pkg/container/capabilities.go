@@ -0,0 +1,58 @@
+package container
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	log "github.com/sirupsen/logrus"
+)
+
+// probeName is the name of the container and network referred to by the probes, which is not expected to exist
+const probeName = "watchtower-capability-probe"
+
+// probeImage is the ID of the image the removal probe refers to, which can not exist
+var probeImage = "sha256:" + strings.Repeat("0", 64)
+
+// Capabilities are the parts of the docker API which watchtower is permitted to use. Proxies restricting access to the
+// docker socket, like docker-socket-proxy, commonly deny some of them.
+type Capabilities struct {
+	// Exec is needed to run the lifecycle hooks inside of the containers
+	Exec bool
+	// Networks is needed to connect the recreated containers to all of their networks
+	Networks bool
+	// ImageRemoval is needed to remove the previous images when cleaning up
+	ImageRemoval bool
+	// APIVersion is the docker API version used by the client, as negotiated with the daemon if not set explicitly
+	APIVersion string
+}
+
+// ProbeCapabilities sends a request to each of the endpoints the capabilities depend on, referring to a container,
+// network and image that do not exist, so that nothing is changed. Only requests that are denied, instead of failing
+// as expected, mark the capability as unavailable, as other errors do not tell whether it would be permitted.
+func (client dockerClient) ProbeCapabilities() Capabilities {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, execErr := client.api.ContainerExecCreate(ctx, probeName, types.ExecConfig{Cmd: []string{"true"}})
+	networksErr := client.api.NetworkConnect(ctx, probeName, probeName, nil)
+	_, removalErr := client.api.ImageRemove(ctx, probeImage, types.ImageRemoveOptions{})
+
+	return Capabilities{
+		Exec:         permitted("exec", execErr),
+		Networks:     permitted("networks", networksErr),
+		ImageRemoval: permitted("image removal", removalErr),
+		APIVersion:   client.api.ClientVersion(),
+	}
+}
+
+func permitted(capability string, err error) bool {
+	if err != nil && (errdefs.IsForbidden(err) || errdefs.IsUnauthorized(err)) {
+		log.WithError(err).Debugf("The docker API denied the %s probe", capability)
+		return false
+	}
+	log.WithError(err).Tracef("The docker API permitted the %s probe", capability)
+	return true
+}
@@ -0,0 +1,73 @@
+package container
+
+import (
+	"net/http"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	cli "github.com/docker/docker/client"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the capability probes", func() {
+	var mockServer *ghttp.Server
+	var client dockerClient
+	BeforeEach(func() {
+		mockServer = ghttp.NewServer()
+		docker, _ := cli.NewClientWithOpts(
+			cli.WithHost(mockServer.URL()),
+			cli.WithHTTPClient(mockServer.HTTPTestServer.Client()))
+		client = dockerClient{api: docker}
+	})
+	AfterEach(func() {
+		mockServer.Close()
+	})
+
+	denied := ghttp.RespondWithJSONEncoded(http.StatusForbidden, map[string]string{"message": "forbidden"})
+	notFound := ghttp.RespondWithJSONEncoded(http.StatusNotFound, map[string]string{"message": "no such object"})
+
+	It("should only mark the denied capabilities as unavailable", func() {
+		mockServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", HaveSuffix("/containers/%s/exec", probeName)),
+				denied,
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", HaveSuffix("/networks/%s/connect", probeName)),
+				notFound,
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("DELETE", HaveSuffix("/images/%s", probeImage)),
+				notFound,
+			),
+		)
+		Expect(client.ProbeCapabilities()).To(Equal(Capabilities{
+			Exec: false, Networks: true, ImageRemoval: true, APIVersion: client.api.ClientVersion(),
+		}))
+		Expect(mockServer.ReceivedRequests()).To(HaveLen(3))
+	})
+
+	It("should mark every capability as unavailable behind a read-only proxy", func() {
+		mockServer.AppendHandlers(denied, denied, denied)
+		Expect(client.ProbeCapabilities()).To(Equal(Capabilities{APIVersion: client.api.ClientVersion()}))
+	})
+
+	It("should tell whether a container has to be connected to further networks", func() {
+		c := mockContainerWithImageName("web:latest")
+		c.containerInfo.HostConfig = &container.HostConfig{NetworkMode: "frontend"}
+		c.containerInfo.NetworkSettings = &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{
+			"frontend": {},
+		}}
+		Expect(c.HasAdditionalNetworks()).To(BeFalse())
+
+		c.containerInfo.NetworkSettings.Networks["backend"] = &network.EndpointSettings{}
+		Expect(c.HasAdditionalNetworks()).To(BeTrue())
+
+		c.containerInfo.HostConfig.NetworkMode = "host"
+		Expect(c.HasAdditionalNetworks()).To(BeFalse())
+	})
+})
@@ -2,48 +2,86 @@ package container
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/containrrr/watchtower/pkg/registry"
 	"github.com/containrrr/watchtower/pkg/registry/digest"
+	"github.com/containrrr/watchtower/pkg/registry/tags"
+	"github.com/containrrr/watchtower/pkg/session"
 
 	t "github.com/containrrr/watchtower/pkg/types"
+	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
 	sdkClient "github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 )
 
 const defaultStopSignal = "SIGTERM"
 
+// anchoredTagPattern matches valid image tags
+var anchoredTagPattern = regexp.MustCompile(`^` + reference.TagRegexp.String() + `$`)
+
 // A Client is the interface through which watchtower interacts with the
 // Docker API.
 type Client interface {
 	ListContainers(t.Filter) ([]Container, error)
 	GetContainer(containerID t.ContainerID) (Container, error)
+	RecoverImageInfo(Container) Container
 	StopContainer(Container, time.Duration) error
 	StartContainer(Container) (t.ContainerID, error)
 	RenameContainer(Container, string) error
 	IsContainerStale(Container) (stale bool, latestImage t.ImageID, err error)
 	ExecuteCommand(containerID t.ContainerID, command string, timeout int) (SkipUpdate bool, err error)
 	RemoveImageByID(t.ImageID) error
+	TagImage(id t.ImageID, imageName string) error
+	ListUnusedImages(repository string) ([]t.ImageSummary, error)
+	GetImageInfo(t.ImageID) (*types.ImageInspect, error)
+	PulledBytes(imageName string) int64
 	WarnOnHeadPullFailed(container Container) bool
+	WatchContainerStarts(done <-chan struct{}) (<-chan t.ContainerID, <-chan error)
+	ProbeCapabilities() Capabilities
+	ContainerLogs(containerID t.ContainerID, tail int, w io.Writer) error
+	BackupVolumes(Container, time.Duration) error
+	RemoveAnonymousVolumes(Container)
 }
 
 // NewClient returns a new Client instance which can be used to interact with
 // the Docker API.
 // The client reads its configuration from the following environment variables:
-//  * DOCKER_HOST			the docker-engine host to send api requests to
-//  * DOCKER_TLS_VERIFY		whether to verify tls certificates
-//  * DOCKER_API_VERSION	the minimum docker api version to work with
+//   - DOCKER_HOST			the docker-engine host to send api requests to
+//   - DOCKER_TLS_VERIFY		whether to verify tls certificates
+//   - DOCKER_API_VERSION	the docker api version to use, negotiated with the daemon if not set
+//
+// Hosts using the ssh:// scheme are reached by running the docker CLI on them through the ssh client. The TLS material
+// in DOCKER_CERT_PATH is read again whenever it changes.
 func NewClient(opts ClientOptions) Client {
-	cli, err := sdkClient.NewClientWithOpts(sdkClient.FromEnv)
+	clientOpts := []sdkClient.Opt{sdkClient.FromEnv}
+	if os.Getenv("DOCKER_API_VERSION") == "" {
+		clientOpts = append(clientOpts, sdkClient.WithAPIVersionNegotiation())
+	}
+	if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, sshScheme) {
+		sshOpts, err := sshClientOpts(host)
+		if err != nil {
+			log.Fatalf("Error instantiating Docker client: %s", err)
+		}
+		clientOpts = append(clientOpts, sshOpts...)
+	} else if certPath := os.Getenv("DOCKER_CERT_PATH"); certPath != "" {
+		clientOpts = append(clientOpts, withReloadedTLS(certPath, os.Getenv("DOCKER_TLS_VERIFY") != ""))
+	}
+	cli, err := sdkClient.NewClientWithOpts(clientOpts...)
 
 	if err != nil {
 		log.Fatalf("Error instantiating Docker client: %s", err)
@@ -51,6 +89,7 @@ func NewClient(opts ClientOptions) Client {
 
 	return dockerClient{
 		api:           cli,
+		pulls:         newPullTransfers(),
 		ClientOptions: opts,
 	}
 }
@@ -59,10 +98,25 @@ func NewClient(opts ClientOptions) Client {
 type ClientOptions struct {
 	PullImages        bool
 	RemoveVolumes     bool
+	CleanupVolumes    bool
+	VolumesDryRun     bool
 	IncludeStopped    bool
 	ReviveStopped     bool
 	IncludeRestarting bool
 	WarnOnHeadFailed  WarningStrategy
+	MissingImageInfo  MissingImageInfoPolicy
+	// IgnoreOneshot leaves out the exited containers that are not restarted by docker, like the containers of jobs
+	IgnoreOneshot bool
+	// MinFreeSpace is the number of bytes that must remain free after pulling an image, if set
+	MinFreeSpace int64
+	// DiskSpacePath is where to check the free disk space, defaulting to the Docker data root
+	DiskSpacePath string
+	// CredentialsDir contains the credential sets that containers may refer to using the registry auth label
+	CredentialsDir string
+	// LogArchive keeps the logs of the containers before they are removed, if set
+	LogArchive t.LogArchiver
+	// VolumeBackup describes how to back up the volumes of containers using the volume backup label, if set
+	VolumeBackup *VolumeBackup
 }
 
 // WarningStrategy is a value determining when to show warnings
@@ -77,8 +131,21 @@ const (
 	WarnAuto WarningStrategy = "auto"
 )
 
+// MissingImageInfoPolicy is a value determining how to handle containers whose image is no longer available locally
+type MissingImageInfoPolicy string
+
+const (
+	// MissingImageSkip skips checking the container for updates
+	MissingImageSkip MissingImageInfoPolicy = "skip"
+	// MissingImagePull pulls the current image tag, in case it still refers to the image used by the container
+	MissingImagePull MissingImageInfoPolicy = "pull"
+	// MissingImageUpdate updates the container using the image currently referred to by its image tag
+	MissingImageUpdate MissingImageInfoPolicy = "update"
+)
+
 type dockerClient struct {
-	api sdkClient.CommonAPIClient
+	api   sdkClient.CommonAPIClient
+	pulls *pullTransfers
 	ClientOptions
 }
 
@@ -119,12 +186,23 @@ func (client dockerClient) ListContainers(fn t.Filter) ([]Container, error) {
 	}
 
 	for _, runningContainer := range containers {
+		// The stopped containers are always listed, so that their include-stopped label can override the global setting
+		stopped := runningContainer.State == "created" || runningContainer.State == "exited"
+		include, labelled := client.includesStopped(runningContainer.Labels)
+		if stopped && !include {
+			continue
+		}
 
 		c, err := client.GetContainer(t.ContainerID(runningContainer.ID))
 		if err != nil {
 			return nil, err
 		}
 
+		if client.IgnoreOneshot && !labelled && c.IsOneshot() {
+			log.Debugf("Leaving out %s, as it has exited and is not restarted by docker", c.Name())
+			continue
+		}
+
 		if fn(c) {
 			cs = append(cs, c)
 		}
@@ -133,14 +211,20 @@ func (client dockerClient) ListContainers(fn t.Filter) ([]Container, error) {
 	return cs, nil
 }
 
+// includesStopped returns whether a stopped container with the labels is monitored, and whether that was decided by its
+// include-stopped label rather than the global setting
+func (client dockerClient) includesStopped(labels map[string]string) (include bool, labelled bool) {
+	if include, err := strconv.ParseBool(labels[includeStoppedLabel]); err == nil {
+		return include, true
+	}
+	return client.IncludeStopped, false
+}
+
 func (client dockerClient) createListFilter() filters.Args {
 	filterArgs := filters.NewArgs()
 	filterArgs.Add("status", "running")
-
-	if client.IncludeStopped {
-		filterArgs.Add("status", "created")
-		filterArgs.Add("status", "exited")
-	}
+	filterArgs.Add("status", "created")
+	filterArgs.Add("status", "exited")
 
 	if client.IncludeRestarting {
 		filterArgs.Add("status", "restarting")
@@ -156,6 +240,7 @@ func (client dockerClient) GetContainer(containerID t.ContainerID) (Container, e
 	if err != nil {
 		return Container{}, err
 	}
+	client.resolveNamespaces(bg, &containerInfo)
 
 	imageInfo, _, err := client.api.ImageInspectWithRaw(bg, containerInfo.Image)
 	if err != nil {
@@ -166,6 +251,68 @@ func (client dockerClient) GetContainer(containerID t.ContainerID) (Container, e
 	return Container{containerInfo: &containerInfo, imageInfo: &imageInfo}, nil
 }
 
+// RecoverImageInfo tries to restore the image info of a container whose image is no longer available locally,
+// according to the configured MissingImageInfo policy. It is only called for the containers that are checked for
+// updates, so that the images of the other containers are never pulled. The container is returned as it is if its
+// image info could not be restored.
+func (client dockerClient) RecoverImageInfo(c Container) Container {
+	if c.HasImageInfo() || c.containerInfo == nil {
+		return c
+	}
+	c.imageInfo = client.recoverImageInfo(context.Background(), c.containerInfo)
+	return c
+}
+
+// recoverImageInfo tries to retrieve replacement image info for a container whose image is no longer available
+// locally, according to the configured MissingImageInfo policy. Returns nil if no image info could be retrieved.
+func (client dockerClient) recoverImageInfo(ctx context.Context, containerInfo *types.ContainerJSON) *types.ImageInspect {
+	imageName := containerInfo.Config.Image
+	fields := log.Fields{
+		"container": containerInfo.Name,
+		"image":     imageName,
+		"policy":    client.MissingImageInfo,
+	}
+	c := Container{containerInfo: containerInfo}
+	credentialSet, _ := c.RegistryCredentialSet()
+	noPull := c.IsNoPull(!client.PullImages)
+
+	switch client.MissingImageInfo {
+	case MissingImagePull:
+		if noPull {
+			log.WithFields(fields).Debug("Not pulling the current image tag, as pulling is disabled for the container")
+			return nil
+		}
+		log.WithFields(fields).Info("Pulling the current image tag to restore the missing image info")
+		if err := client.pullImageByName(ctx, imageName, credentialSet); err != nil {
+			log.WithFields(fields).WithError(err).Warn("Failed to pull the current image tag")
+			return nil
+		}
+		imageInfo, _, err := client.api.ImageInspectWithRaw(ctx, containerInfo.Image)
+		if err != nil {
+			log.WithFields(fields).Warn("The current image tag no longer refers to the image used by the container")
+			return nil
+		}
+		return &imageInfo
+	case MissingImageUpdate:
+		imageInfo, _, err := client.api.ImageInspectWithRaw(ctx, imageName)
+		if err != nil && !noPull {
+			if err = client.pullImageByName(ctx, imageName, credentialSet); err == nil {
+				imageInfo, _, err = client.api.ImageInspectWithRaw(ctx, imageName)
+			}
+		}
+		if err != nil {
+			log.WithFields(fields).WithError(err).Warn("Failed to retrieve the image info of the current image tag")
+			return nil
+		}
+		log.WithFields(fields).Warnf("Using the info of image %s in place of the missing image", t.ImageID(imageInfo.ID).ShortID())
+		// The stand-in keeps the ID of the missing image, so that the current image is never removed during cleanup
+		imageInfo.ID = containerInfo.Image
+		return &imageInfo
+	default:
+		return nil
+	}
+}
+
 func (client dockerClient) StopContainer(c Container, timeout time.Duration) error {
 	bg := context.Background()
 	signal := c.StopSignal()
@@ -176,9 +323,16 @@ func (client dockerClient) StopContainer(c Container, timeout time.Duration) err
 	idStr := string(c.ID())
 	shortID := c.ID().ShortID()
 
+	// the logs of containers that are removed automatically are gone once they have stopped
+	autoRemove := c.containerInfo.HostConfig.AutoRemove
+	if autoRemove {
+		client.archiveLogs(c)
+	}
+
 	if c.IsRunning() {
 		log.Infof("Stopping %s (%s) with %s", c.Name(), shortID, signal)
-		if err := client.api.ContainerKill(bg, idStr, signal); err != nil {
+		// the container is no longer running if it was stopped to back up its volumes
+		if err := client.api.ContainerKill(bg, idStr, signal); err != nil && !errdefs.IsConflict(err) {
 			return err
 		}
 	}
@@ -186,9 +340,10 @@ func (client dockerClient) StopContainer(c Container, timeout time.Duration) err
 	// TODO: This should probably be checked.
 	_ = client.waitForStopOrTimeout(c, timeout)
 
-	if c.containerInfo.HostConfig.AutoRemove {
+	if autoRemove {
 		log.Debugf("AutoRemove container %s, skipping ContainerRemove call.", shortID)
 	} else {
+		client.archiveLogs(c)
 		log.Debugf("Removing container %s", shortID)
 
 		if err := client.api.ContainerRemove(bg, idStr, types.ContainerRemoveOptions{Force: true, RemoveVolumes: client.RemoveVolumes}); err != nil {
@@ -208,47 +363,76 @@ func (client dockerClient) StartContainer(c Container) (t.ContainerID, error) {
 	bg := context.Background()
 	config := c.runtimeConfig()
 	hostConfig := c.hostConfig()
-	networkConfig := &network.NetworkingConfig{EndpointsConfig: c.containerInfo.NetworkSettings.Networks}
-	// simpleNetworkConfig is a networkConfig with only 1 network.
+	name := c.Name()
+
+	endpoints := c.endpointsConfig()
+	if c.IsWatchtower() {
+		// The current watchtower instance keeps running until the new one has taken over, still using its addresses
+		for _, endpoint := range endpoints {
+			endpoint.MacAddress = ""
+		}
+	}
+	client.dropUnavailableAddresses(bg, name, endpoints)
+
+	// Only the primary network is passed when creating the container, as older API versions do not support more.
+	// The container is then connected to the rest of the networks, in order, using the same settings.
 	// see: https://github.com/docker/docker/issues/29265
-	simpleNetworkConfig := func() *network.NetworkingConfig {
-		oneEndpoint := make(map[string]*network.EndpointSettings)
-		for k, v := range networkConfig.EndpointsConfig {
-			oneEndpoint[k] = v
-			// we only need 1
-			break
+	primary := primaryNetwork(string(hostConfig.NetworkMode), endpoints)
+	createNetworkConfig := &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+	if primary != "" {
+		createNetworkConfig.EndpointsConfig[primary] = endpoints[primary]
+		if mac := endpoints[primary].MacAddress; mac != "" && config.MacAddress == "" && !supportsEndpointMacAddress(client.api.ClientVersion()) {
+			config.MacAddress = mac
 		}
-		return &network.NetworkingConfig{EndpointsConfig: oneEndpoint}
-	}()
+	}
 
-	name := c.Name()
+	// The daemon ignores the device requests sent using older API versions, which would recreate the container
+	// without its devices (e.g. GPUs), so it is created using the first version supporting them instead
+	api := client.api
+	if c.HasDeviceRequests() && !client.supportsAPI(deviceRequestsMinAPIVersion) {
+		log.Debugf("Creating %s using docker API version %s, as it requests devices", name, deviceRequestsMinAPIVersion)
+		var err error
+		if api, err = client.withAPIVersion(bg, deviceRequestsMinAPIVersion); err != nil {
+			return "", fmt.Errorf("could not recreate %s along with its devices: %w", name, err)
+		}
+	}
+
+	if config.StopTimeout != nil && !client.supportsAPI(stopTimeoutMinAPIVersion) {
+		log.Debugf("The stop timeout of %s can not be preserved using API version %s", name, client.api.ClientVersion())
+		config.StopTimeout = nil
+	}
+	platform := c.imagePlatform()
+	if platform != nil && !client.supportsAPI(createPlatformMinAPIVersion) {
+		log.Debugf("The platform of %s can not be preserved using API version %s", name, client.api.ClientVersion())
+		platform = nil
+	}
 
 	log.Infof("Creating %s", name)
-	createdContainer, err := client.api.ContainerCreate(bg, config, hostConfig, simpleNetworkConfig, nil, name)
+	createdContainer, err := api.ContainerCreate(bg, config, hostConfig, createNetworkConfig, platform, name)
 	if err != nil {
 		return "", err
 	}
 
 	if !(hostConfig.NetworkMode.IsHost()) {
-
-		for k := range simpleNetworkConfig.EndpointsConfig {
-			err = client.api.NetworkDisconnect(bg, k, createdContainer.ID, true)
-			if err != nil {
-				return "", err
+		for _, networkName := range sortedNetworkNames(endpoints) {
+			if networkName == primary {
+				continue
 			}
-		}
-
-		for k, v := range networkConfig.EndpointsConfig {
-			err = client.api.NetworkConnect(bg, k, createdContainer.ID, v)
+			endpoint := endpoints[networkName]
+			if endpoint.MacAddress != "" && !supportsEndpointMacAddress(client.api.ClientVersion()) {
+				log.Debugf("The MAC address of %s on network %s can not be preserved using API version %s",
+					name, networkName, client.api.ClientVersion())
+			}
+			log.Debugf("Connecting %s to network %s", name, networkName)
+			err = client.api.NetworkConnect(bg, networkName, createdContainer.ID, endpoint)
 			if err != nil {
-				return "", err
+				return t.ContainerID(createdContainer.ID), err
 			}
 		}
-
 	}
 
 	createdContainerID := t.ContainerID(createdContainer.ID)
-	if !c.IsRunning() && !client.ReviveStopped {
+	if !c.IsRunning() && !c.IsReviveStopped(client.ReviveStopped) {
 		return createdContainerID, nil
 	}
 
@@ -256,6 +440,35 @@ func (client dockerClient) StartContainer(c Container) (t.ContainerID, error) {
 
 }
 
+// dropUnavailableAddresses removes the static addresses that are outside of the subnets of their networks from the
+// supplied endpoints, as connecting the recreated container would otherwise fail
+func (client dockerClient) dropUnavailableAddresses(ctx context.Context, name string, endpoints map[string]*network.EndpointSettings) {
+	for _, networkName := range sortedNetworkNames(endpoints) {
+		ipam := endpoints[networkName].IPAMConfig
+		if ipam == nil || (ipam.IPv4Address == "" && ipam.IPv6Address == "") {
+			continue
+		}
+
+		inspected, err := client.api.NetworkInspect(ctx, networkName, types.NetworkInspectOptions{})
+		if err != nil {
+			log.WithError(err).Debugf("Could not inspect network %s, keeping the static addresses of %s", networkName, name)
+			continue
+		}
+		subnets := make([]string, 0, len(inspected.IPAM.Config))
+		for _, ipamConfig := range inspected.IPAM.Config {
+			subnets = append(subnets, ipamConfig.Subnet)
+		}
+
+		for _, address := range []*string{&ipam.IPv4Address, &ipam.IPv6Address} {
+			if *address != "" && !subnetsContain(subnets, *address) {
+				log.Warnf("The static address %s of %s is no longer part of network %s, a new address will be assigned",
+					*address, name, networkName)
+				*address = ""
+			}
+		}
+	}
+}
+
 func (client dockerClient) doStartContainer(bg context.Context, c Container, creation container.ContainerCreateCreatedBody) error {
 	name := c.Name()
 
@@ -276,13 +489,102 @@ func (client dockerClient) RenameContainer(c Container, newName string) error {
 func (client dockerClient) IsContainerStale(container Container) (stale bool, latestImage t.ImageID, err error) {
 	ctx := context.Background()
 
-	if !client.PullImages {
+	if !container.HasImageInfo() {
+		return false, container.SafeImageID(), container.ImageMissingError()
+	}
+
+	originalImageName := container.ImageName()
+	targetTag, hasTargetTag := container.TargetTag()
+	constraint, hasConstraint := container.TagConstraint()
+	if hasTargetTag && hasConstraint {
+		return false, container.SafeImageID(), errors.New("the target tag and tag constraint labels cannot be used together")
+	} else if hasTargetTag {
+		if err := client.switchTag(container, targetTag); err != nil {
+			return false, container.SafeImageID(), err
+		}
+	} else if hasConstraint {
+		if err := client.followTagConstraint(container, constraint); err != nil {
+			return false, container.SafeImageID(), err
+		}
+	}
+
+	if container.IsNoPull(!client.PullImages) {
 		log.Debugf("Skipping image pull.")
 	} else if err := client.PullImage(ctx, container); err != nil {
 		return false, container.SafeImageID(), err
 	}
 
-	return client.HasNewImage(ctx, container)
+	stale, latestImage, err = client.HasNewImage(ctx, container)
+	if err == nil && !stale && container.ImageName() != originalImageName {
+		// The new tag refers to the same image, but the container still needs to be recreated to reference it
+		log.Debugf("Tag of %s changed to %s, which refers to the current image", container.Name(), container.ImageName())
+		stale = true
+	}
+	return stale, latestImage, err
+}
+
+// switchTag changes the image of the container to the target tag in the same repository, causing the container to
+// be recreated using that tag. Once the container uses the target tag, this has no further effect.
+func (client dockerClient) switchTag(container Container, targetTag string) error {
+	if !anchoredTagPattern.MatchString(targetTag) {
+		return fmt.Errorf("invalid target tag %q", targetTag)
+	}
+	if isPinnedImage(container.ImageName()) {
+		return session.WithSkipReason(session.SkipPinnedDigest, errors.New("container uses a pinned image, and cannot be switched to another tag"))
+	}
+
+	repository, currentTag := container.ImageRepositoryAndTag()
+	if currentTag == targetTag {
+		return nil
+	}
+
+	log.WithField("container", container.Name()).Infof("Migrating %s from tag %s to %s", repository, currentTag, targetTag)
+	container.retarget(fmt.Sprintf("%s:%s", repository, targetTag))
+	return nil
+}
+
+// followTagConstraint switches the image of the container to the newest tag of its repository that satisfies the
+// tag constraint, causing the container to be recreated using that tag if it refers to a different image
+func (client dockerClient) followTagConstraint(container Container, constraint string) error {
+	parsed, err := tags.ParseConstraint(constraint)
+	if err != nil {
+		return fmt.Errorf("invalid tag constraint %q: %w", constraint, err)
+	}
+
+	imageName := container.ImageName()
+	if isPinnedImage(imageName) {
+		return session.WithSkipReason(session.SkipPinnedDigest, errors.New("container uses a pinned image, and cannot follow a tag constraint"))
+	}
+	repository, currentTag := container.ImageRepositoryAndTag()
+
+	credentialSet, _ := container.RegistryCredentialSet()
+	opts, err := client.pullOptions(imageName, credentialSet)
+	if err != nil {
+		return err
+	}
+	available, err := tags.ListTags(container, opts.RegistryAuth)
+	if err != nil {
+		return fmt.Errorf("failed to list the tags of %s: %w", repository, err)
+	}
+	newest, err := tags.Newest(available, parsed, currentTag)
+	if err != nil {
+		return fmt.Errorf("no tag of %s satisfies the constraint %q", repository, constraint)
+	}
+
+	fields := log.Fields{"container": container.Name(), "constraint": constraint}
+	if newest == currentTag {
+		log.WithFields(fields).Debugf("Tag %s is the newest satisfying the constraint", currentTag)
+		return nil
+	}
+
+	log.WithFields(fields).Infof("Found newer tag %s for %s (currently %s)", newest, repository, currentTag)
+	container.retarget(fmt.Sprintf("%s:%s", repository, newest))
+	return nil
+}
+
+// isPinnedImage returns whether the image name refers to an image by its ID or digest, rather than by a tag
+func isPinnedImage(imageName string) bool {
+	return strings.HasPrefix(imageName, "sha256:") || strings.Contains(imageName, "@")
 }
 
 func (client dockerClient) HasNewImage(ctx context.Context, container Container) (hasNew bool, latestImage t.ImageID, err error) {
@@ -316,11 +618,12 @@ func (client dockerClient) PullImage(ctx context.Context, container Container) e
 	}
 
 	if strings.HasPrefix(imageName, "sha256:") {
-		return fmt.Errorf("container uses a pinned image, and cannot be updated by watchtower")
+		return session.WithSkipReason(session.SkipPinnedDigest, errors.New("container uses a pinned image, and cannot be updated by watchtower"))
 	}
 
 	log.WithFields(fields).Debugf("Trying to load authentication credentials.")
-	opts, err := registry.GetPullOptions(imageName)
+	credentialSet, _ := container.RegistryCredentialSet()
+	opts, err := client.pullOptions(imageName, credentialSet)
 	if err != nil {
 		log.Debugf("Error loading authentication credentials %s", err)
 		return err
@@ -345,23 +648,78 @@ func (client dockerClient) PullImage(ctx context.Context, container Container) e
 		log.Debug("Digests did not match, doing a pull.")
 	}
 
+	if client.MinFreeSpace > 0 {
+		if err := client.checkDiskSpace(ctx, container, opts.RegistryAuth); err != nil {
+			return err
+		}
+	}
+
+	if client.supportsAPI(pullPlatformMinAPIVersion) {
+		opts.Platform = platformString(container.imagePlatform())
+	}
+
 	log.WithFields(fields).Debugf("Pulling image")
 
+	return client.doPullImage(ctx, imageName, opts)
+}
+
+// pullImageByName pulls the supplied image, without checking whether the pull is needed first
+func (client dockerClient) pullImageByName(ctx context.Context, imageName string, credentialSet string) error {
+	if strings.HasPrefix(imageName, "sha256:") {
+		return session.WithSkipReason(session.SkipPinnedDigest, errors.New("container uses a pinned image, and cannot be pulled by watchtower"))
+	}
+
+	opts, err := client.pullOptions(imageName, credentialSet)
+	if err != nil {
+		log.Debugf("Error loading authentication credentials %s", err)
+		return err
+	}
+
+	return client.doPullImage(ctx, imageName, opts)
+}
+
+// pullOptions returns the options for pulling the image, using the named credential set instead of the global
+// credentials if one is passed
+func (client dockerClient) pullOptions(imageName string, credentialSet string) (types.ImagePullOptions, error) {
+	if credentialSet == "" {
+		return registry.GetPullOptions(imageName)
+	}
+	if client.CredentialsDir == "" {
+		return types.ImagePullOptions{}, fmt.Errorf("the container refers to the credential set %q, but no registry credentials directory has been set", credentialSet)
+	}
+	return registry.GetCredentialSetPullOptions(imageName, client.CredentialsDir, credentialSet)
+}
+
+func (client dockerClient) doPullImage(ctx context.Context, imageName string, opts types.ImagePullOptions) error {
 	response, err := client.api.ImagePull(ctx, imageName, opts)
 	if err != nil {
 		log.Debugf("Error pulling image %s, %s", imageName, err)
-		return err
+		return pullError(err)
 	}
 
 	defer response.Close()
-	// the pull request will be aborted prematurely unless the response is read
-	if _, err = ioutil.ReadAll(response); err != nil {
+	bytes, err := readPullProgress(response)
+	client.pulls.add(imageName, bytes)
+	if err != nil {
 		log.Error(err)
-		return err
+		return pullError(err)
 	}
 	return nil
 }
 
+// pullError annotates the errors of the registries refusing to serve an image due to their rate limits
+func pullError(err error) error {
+	if strings.Contains(err.Error(), "toomanyrequests") || strings.Contains(err.Error(), "429 Too Many Requests") {
+		return session.WithSkipReason(session.SkipRateLimited, err)
+	}
+	return err
+}
+
+// PulledBytes returns the number of bytes downloaded while pulling the image since the last call
+func (client dockerClient) PulledBytes(imageName string) int64 {
+	return client.pulls.take(imageName)
+}
+
 func (client dockerClient) RemoveImageByID(id t.ImageID) error {
 	log.Infof("Removing image %s", id.ShortID())
 
@@ -375,6 +733,85 @@ func (client dockerClient) RemoveImageByID(id t.ImageID) error {
 	return err
 }
 
+// TagImage tags the image as imageName, which makes the containers created using imageName use the image
+func (client dockerClient) TagImage(id t.ImageID, imageName string) error {
+	log.Debugf("Tagging image %s as %s", id.ShortID(), imageName)
+	return client.api.ImageTag(context.Background(), string(id), imageName)
+}
+
+// ListUnusedImages returns the local images of the repository that are not used by any container, newest first.
+// Images that are no longer tagged are included, as long as their digests refer to the repository.
+func (client dockerClient) ListUnusedImages(repository string) ([]t.ImageSummary, error) {
+	bg := context.Background()
+	named, err := reference.ParseNormalizedNamed(repository)
+	if err != nil {
+		return nil, err
+	}
+	repository = named.Name()
+
+	containers, err := client.api.ContainerList(bg, types.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		used[c.ImageID] = true
+	}
+
+	images, err := client.api.ImageList(bg, types.ImageListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var unused []t.ImageSummary
+	for _, image := range images {
+		if used[image.ID] || !inRepository(repository, append(image.RepoTags, image.RepoDigests...)) {
+			continue
+		}
+		unused = append(unused, t.ImageSummary{ID: t.ImageID(image.ID), Created: time.Unix(image.Created, 0)})
+	}
+	sort.SliceStable(unused, func(i, j int) bool {
+		return unused[i].Created.After(unused[j].Created)
+	})
+	return unused, nil
+}
+
+// GetImageInfo returns the details of the local image with the supplied ID
+func (client dockerClient) GetImageInfo(id t.ImageID) (*types.ImageInspect, error) {
+	imageInfo, _, err := client.api.ImageInspectWithRaw(context.Background(), string(id))
+	if err != nil {
+		return nil, err
+	}
+	return &imageInfo, nil
+}
+
+// inRepository returns whether any of the image references belong to the (normalized) repository
+func inRepository(repository string, references []string) bool {
+	for _, ref := range references {
+		// Only the repository part of digest references is needed
+		ref = strings.SplitN(ref, "@", 2)[0]
+		if named, err := reference.ParseNormalizedNamed(ref); err == nil && named.Name() == repository {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageReference returns the reference to the image by its name along with the digest it has in the repository of the
+// name, like nginx:1.25@sha256:..., or just the name if the image does not have a digest in that repository
+func ImageReference(imageName string, repoDigests []string) string {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil || isPinnedImage(imageName) {
+		return imageName
+	}
+	for _, ref := range repoDigests {
+		parts := strings.SplitN(ref, "@", 2)
+		if len(parts) == 2 && inRepository(named.Name(), parts[:1]) {
+			return imageName + "@" + parts[1]
+		}
+	}
+	return imageName
+}
+
 func (client dockerClient) ExecuteCommand(containerID t.ContainerID, command string, timeout int) (SkipUpdate bool, err error) {
 	bg := context.Background()
 	clog := log.WithField("containerID", containerID)
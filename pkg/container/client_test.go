@@ -3,10 +3,13 @@ package container
 import (
 	"github.com/containrrr/watchtower/pkg/container/mocks"
 	"github.com/containrrr/watchtower/pkg/filters"
+	"github.com/containrrr/watchtower/pkg/session"
 	t "github.com/containrrr/watchtower/pkg/types"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
 	cli "github.com/docker/docker/client"
 	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/ghttp"
@@ -17,7 +20,10 @@ import (
 	gt "github.com/onsi/gomega/types"
 
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"os"
 )
 
 var _ = Describe("the client", func() {
@@ -72,7 +78,7 @@ var _ = Describe("the client", func() {
 	When("listing containers", func() {
 		When("no filter is provided", func() {
 			It("should return all available containers", func() {
-				mockServer.AppendHandlers(mocks.ListContainersHandler("running"))
+				mockServer.AppendHandlers(mocks.ListContainersHandler("running", "exited", "created"))
 				mockServer.AppendHandlers(mocks.GetContainerHandlers("watchtower", "running")...)
 				client := dockerClient{
 					api:           docker,
@@ -85,7 +91,7 @@ var _ = Describe("the client", func() {
 		})
 		When("a filter matching nothing", func() {
 			It("should return an empty array", func() {
-				mockServer.AppendHandlers(mocks.ListContainersHandler("running"))
+				mockServer.AppendHandlers(mocks.ListContainersHandler("running", "exited", "created"))
 				mockServer.AppendHandlers(mocks.GetContainerHandlers("watchtower", "running")...)
 				filter := filters.FilterByNames([]string{"lollercoaster"}, filters.NoFilter)
 				client := dockerClient{
@@ -99,7 +105,7 @@ var _ = Describe("the client", func() {
 		})
 		When("a watchtower filter is provided", func() {
 			It("should return only the watchtower container", func() {
-				mockServer.AppendHandlers(mocks.ListContainersHandler("running"))
+				mockServer.AppendHandlers(mocks.ListContainersHandler("running", "exited", "created"))
 				mockServer.AppendHandlers(mocks.GetContainerHandlers("watchtower", "running")...)
 				client := dockerClient{
 					api:           docker,
@@ -123,9 +129,62 @@ var _ = Describe("the client", func() {
 				Expect(containers).To(ContainElement(havingRunningState(false)))
 			})
 		})
+		When(`a stopped container has the include stopped label`, func() {
+			listHandler := func(include string) http.HandlerFunc {
+				return ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", HaveSuffix("containers/json")),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, []types.Container{
+						{
+							ID:     "ae8964ba86c7cd7522cf84e09781343d88e0e3543281c747d88b27e246578b65",
+							State:  "exited",
+							Labels: map[string]string{"com.centurylinklabs.watchtower.include-stopped": include},
+						},
+						{ID: "b978af0b858aa8855cce46b628817d4ed58e58f2c4f66c9b9c5449134ed4c008", State: "running"},
+					}),
+				)
+			}
+			It("should include it even if include stopped is disabled", func() {
+				mockServer.AppendHandlers(listHandler("true"))
+				mockServer.AppendHandlers(mocks.GetContainerHandlers("stopped", "running")...)
+				client := dockerClient{
+					api:           docker,
+					ClientOptions: ClientOptions{PullImages: false, IncludeStopped: false},
+				}
+				containers, err := client.ListContainers(filters.NoFilter)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(containers).To(HaveLen(2))
+				Expect(containers).To(ContainElement(havingRunningState(false)))
+			})
+			It("should leave it out even if include stopped is enabled", func() {
+				mockServer.AppendHandlers(listHandler("false"))
+				mockServer.AppendHandlers(mocks.GetContainerHandlers("running")...)
+				client := dockerClient{
+					api:           docker,
+					ClientOptions: ClientOptions{PullImages: false, IncludeStopped: true},
+				}
+				containers, err := client.ListContainers(filters.NoFilter)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(containers).To(HaveLen(1))
+				Expect(containers).NotTo(ContainElement(havingRunningState(false)))
+			})
+		})
+		When(`one-shot containers are ignored`, func() {
+			It("should leave out the exited containers that are not restarted", func() {
+				mockServer.AppendHandlers(mocks.ListContainersHandler("running", "exited", "created"))
+				mockServer.AppendHandlers(mocks.GetContainerHandlers("stopped", "watchtower", "running")...)
+				client := dockerClient{
+					api:           docker,
+					ClientOptions: ClientOptions{PullImages: false, IncludeStopped: true, IgnoreOneshot: true},
+				}
+				containers, err := client.ListContainers(filters.NoFilter)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(containers).To(HaveLen(2))
+				Expect(containers).NotTo(ContainElement(havingRunningState(false)))
+			})
+		})
 		When(`include restarting is enabled`, func() {
 			It("should return both restarting and running containers", func() {
-				mockServer.AppendHandlers(mocks.ListContainersHandler("running", "restarting"))
+				mockServer.AppendHandlers(mocks.ListContainersHandler("running", "exited", "created", "restarting"))
 				mockServer.AppendHandlers(mocks.GetContainerHandlers("watchtower", "running", "restarting")...)
 				client := dockerClient{
 					api:           docker,
@@ -138,7 +197,7 @@ var _ = Describe("the client", func() {
 		})
 		When(`include restarting is disabled`, func() {
 			It("should not return restarting containers", func() {
-				mockServer.AppendHandlers(mocks.ListContainersHandler("running"))
+				mockServer.AppendHandlers(mocks.ListContainersHandler("running", "exited", "created"))
 				mockServer.AppendHandlers(mocks.GetContainerHandlers("watchtower", "running")...)
 				client := dockerClient{
 					api:           docker,
@@ -150,6 +209,488 @@ var _ = Describe("the client", func() {
 			})
 		})
 	})
+	When("the image of a container is no longer available locally", func() {
+		runningID := t.ContainerID("b978af0b858aa8855cce46b628817d4ed58e58f2c4f66c9b9c5449134ed4c008")
+		missingImageID := "sha256:19d07168491a3f9e2798a9bed96544e34d57ddc4757a4ac5bb199dea896c87fd"
+		newImageID := "sha256:4dbc5f9c07028a985e14d1393e849ea07f68804c4293050d5a641b138db72daa"
+		missingImageHandler := ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", HaveSuffix("/images/%s/json", missingImageID)),
+			ghttp.RespondWith(http.StatusNotFound, `{"message": "No such image"}`),
+		)
+		containerHandler := func() http.HandlerFunc {
+			return mocks.GetContainerHandlers("running")[0]
+		}
+		When(`the missing image info policy is "skip"`, func() {
+			It("should skip the container without pulling", func() {
+				client := dockerClient{
+					api:           docker,
+					ClientOptions: ClientOptions{PullImages: true, MissingImageInfo: MissingImageSkip},
+				}
+				mockServer.AppendHandlers(containerHandler(), missingImageHandler)
+				c, err := client.GetContainer(runningID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(c.HasImageInfo()).To(BeFalse())
+
+				stale, _, err := client.IsContainerStale(c)
+				Expect(err).To(MatchError(ContainSubstring("no longer available locally")))
+				Expect(stale).To(BeFalse())
+				Expect(mockServer.ReceivedRequests()).To(HaveLen(2))
+			})
+		})
+		When(`the missing image info policy is "pull"`, func() {
+			It("should restore the image info by pulling the current tag", func() {
+				client := dockerClient{
+					api:           docker,
+					ClientOptions: ClientOptions{PullImages: true, MissingImageInfo: MissingImagePull},
+				}
+				mockServer.AppendHandlers(containerHandler(), missingImageHandler)
+				c, err := client.GetContainer(runningID)
+				Expect(err).NotTo(HaveOccurred())
+				By("leaving the image alone while inspecting the container")
+				Expect(c.HasImageInfo()).To(BeFalse())
+				Expect(mockServer.ReceivedRequests()).To(HaveLen(2))
+
+				mockServer.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("POST", HaveSuffix("/images/create"), "fromImage=portainer%2Fportainer&tag=latest"),
+						ghttp.RespondWith(http.StatusOK, `{"status": "Downloaded newer image"}`),
+					),
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", HaveSuffix("/images/%s/json", missingImageID)),
+						ghttp.RespondWithJSONEncoded(http.StatusOK, types.ImageInspect{ID: missingImageID}),
+					),
+				)
+				c = client.RecoverImageInfo(c)
+				Expect(c.HasImageInfo()).To(BeTrue())
+				Expect(c.ImageID()).To(BeEquivalentTo(missingImageID))
+			})
+			It("should not pull the current tag when pulling is disabled", func() {
+				client := dockerClient{
+					api:           docker,
+					ClientOptions: ClientOptions{PullImages: false, MissingImageInfo: MissingImagePull},
+				}
+				mockServer.AppendHandlers(containerHandler(), missingImageHandler)
+				c, err := client.GetContainer(runningID)
+				Expect(err).NotTo(HaveOccurred())
+				c = client.RecoverImageInfo(c)
+				Expect(c.HasImageInfo()).To(BeFalse())
+				Expect(mockServer.ReceivedRequests()).To(HaveLen(2))
+			})
+		})
+		When(`the missing image info policy is "update"`, func() {
+			It("should consider the container stale, using the info of the current tag", func() {
+				client := dockerClient{
+					api:           docker,
+					ClientOptions: ClientOptions{PullImages: false, MissingImageInfo: MissingImageUpdate},
+				}
+				newImageHandler := ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", HaveSuffix("/images/portainer/portainer:latest/json")),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, types.ImageInspect{ID: newImageID}),
+				)
+				mockServer.AppendHandlers(containerHandler(), missingImageHandler, newImageHandler, newImageHandler)
+				c, err := client.GetContainer(runningID)
+				Expect(err).NotTo(HaveOccurred())
+				c = client.RecoverImageInfo(c)
+				Expect(c.HasImageInfo()).To(BeTrue())
+				// the stand-in image info must not cause the new image to be removed during cleanup
+				Expect(c.ImageID()).To(BeEquivalentTo(missingImageID))
+
+				stale, latest, err := client.IsContainerStale(c)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stale).To(BeTrue())
+				Expect(latest).To(BeEquivalentTo(newImageID))
+			})
+		})
+	})
+	When("pulling an image", func() {
+		It("should count the downloaded bytes of the layers", func() {
+			client := dockerClient{api: docker, pulls: newPullTransfers()}
+			progress := `{"status":"Pulling from portainer/portainer","id":"latest"}
+{"status":"Already exists","id":"a1"}
+{"status":"Downloading","progressDetail":{"current":512,"total":2048},"id":"b2"}
+{"status":"Downloading","progressDetail":{"current":1536,"total":2048},"id":"b2"}
+{"status":"Download complete","id":"b2"}
+{"status":"Downloading","progressDetail":{"current":100,"total":300},"id":"c3"}
+{"status":"Status: Downloaded newer image for portainer/portainer:latest"}`
+			mockServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", HaveSuffix("/images/create"), "fromImage=portainer%2Fportainer&tag=latest"),
+					ghttp.RespondWith(http.StatusOK, progress),
+				),
+			)
+			Expect(client.pullImageByName(context.Background(), "portainer/portainer:latest", "")).To(Succeed())
+			Expect(client.PulledBytes("portainer/portainer:latest")).To(BeEquivalentTo(2048 + 100))
+			By("resetting the count once it has been collected")
+			Expect(client.PulledBytes("portainer/portainer:latest")).To(BeZero())
+		})
+	})
+	When("a minimum amount of free disk space has been set", func() {
+		It("should refuse pulls that would leave less free space", func() {
+			client := dockerClient{ClientOptions: ClientOptions{MinFreeSpace: 1 << 62, DiskSpacePath: os.TempDir()}}
+			err := client.checkDiskSpace(context.Background(), *mockContainerWithLabels(nil), "")
+			Expect(err).To(MatchError(ContainSubstring("not enough disk space")))
+			Expect(session.SkipReasonOf(err)).To(Equal(session.SkipInsufficientDiskSpace))
+		})
+		It("should allow pulls that leave enough free space", func() {
+			client := dockerClient{ClientOptions: ClientOptions{MinFreeSpace: 1, DiskSpacePath: os.TempDir()}}
+			Expect(client.checkDiskSpace(context.Background(), *mockContainerWithLabels(nil), "")).To(Succeed())
+		})
+		It("should not block pulls if the free space cannot be determined", func() {
+			client := dockerClient{ClientOptions: ClientOptions{MinFreeSpace: 1 << 62, DiskSpacePath: "/does/not/exist"}}
+			Expect(client.checkDiskSpace(context.Background(), *mockContainerWithLabels(nil), "")).To(Succeed())
+		})
+	})
+	When("referring to an image by its tag and digest", func() {
+		It("should use the digest of the repository of the image", func() {
+			digests := []string{"mirror.example.com/nginx@sha256:aaa", "nginx@sha256:bbb"}
+			Expect(ImageReference("nginx:1.25", digests)).To(Equal("nginx:1.25@sha256:bbb"))
+		})
+		It("should only use the name without a digest of the repository", func() {
+			Expect(ImageReference("nginx:1.25", []string{"mirror.example.com/nginx@sha256:aaa"})).To(Equal("nginx:1.25"))
+		})
+	})
+	When("listing the unused images of a repository", func() {
+		It("should include untagged images of the repository, newest first", func() {
+			client := dockerClient{api: docker}
+			mockServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", HaveSuffix("/containers/json")),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, []types.Container{{ImageID: "sha256:current"}}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", HaveSuffix("/images/json")),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, []types.ImageSummary{
+						{ID: "sha256:current", RepoTags: []string{"nginx:latest"}, Created: 400},
+						{ID: "sha256:older", RepoTags: []string{"<none>:<none>"}, RepoDigests: []string{"nginx@sha256:6f2b"}, Created: 100},
+						{ID: "sha256:previous", RepoTags: []string{"docker.io/library/nginx:1.24"}, Created: 300},
+						{ID: "sha256:other", RepoTags: []string{"nginx-proxy:latest"}, Created: 200},
+					}),
+				),
+			)
+			images, err := client.ListUnusedImages("nginx")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(images).To(HaveLen(2))
+			Expect(images[0].ID).To(BeEquivalentTo("sha256:previous"))
+			Expect(images[1].ID).To(BeEquivalentTo("sha256:older"))
+		})
+	})
+	When("cleaning up the volumes of a replaced container", func() {
+		anonymous := "3f8c1d2e4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+		shared := "9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2b1a0f9e8d"
+		mockContainerWithVolumes := func() Container {
+			return *NewContainer(&types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{ID: "container_id", Name: "/volumes"},
+				Config:            &container.Config{},
+				Mounts: []types.MountPoint{
+					{Type: "volume", Name: anonymous, Destination: "/data"},
+					{Type: "volume", Name: shared, Destination: "/shared"},
+					{Type: "volume", Name: "named-data", Destination: "/named"},
+					{Type: "bind", Source: "/srv/config", Destination: "/config"},
+				},
+			}, nil)
+		}
+		listHandler := func(volume string, users []types.Container) http.HandlerFunc {
+			return ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", HaveSuffix("/containers/json")),
+				ghttp.VerifyFormKV("filters", fmt.Sprintf(`{"volume":{"%s":true}}`, volume)),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, users),
+			)
+		}
+
+		It("should only remove the anonymous volumes that are not used by other containers", func() {
+			client := dockerClient{api: docker, ClientOptions: ClientOptions{CleanupVolumes: true}}
+			mockServer.AppendHandlers(
+				listHandler(anonymous, []types.Container{}),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("DELETE", HaveSuffix("/volumes/%s", anonymous)),
+					ghttp.RespondWith(http.StatusNoContent, nil),
+				),
+				listHandler(shared, []types.Container{{ID: "other"}}),
+			)
+			client.RemoveAnonymousVolumes(mockContainerWithVolumes())
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(3))
+		})
+		It("should not remove any volumes in dry-run mode", func() {
+			client := dockerClient{api: docker, ClientOptions: ClientOptions{VolumesDryRun: true}}
+			mockServer.AppendHandlers(
+				listHandler(anonymous, []types.Container{}),
+				listHandler(shared, []types.Container{}),
+			)
+			client.RemoveAnonymousVolumes(mockContainerWithVolumes())
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(2))
+		})
+		It("should not remove any volumes unless the cleanup of volumes is enabled", func() {
+			client := dockerClient{api: docker}
+			client.RemoveAnonymousVolumes(mockContainerWithVolumes())
+			Expect(mockServer.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+	When("the container has a target tag label", func() {
+		imageID := "sha256:19d07168491a3f9e2798a9bed96544e34d57ddc4757a4ac5bb199dea896c87fd"
+		mockTaggedContainer := func(labels map[string]string) Container {
+			return *NewContainer(&types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{
+					ID:    "container_id",
+					Name:  "/tagged",
+					Image: imageID,
+				},
+				Config: &container.Config{Image: "portainer/portainer:1.2", Labels: labels},
+			}, &types.ImageInspect{ID: imageID, Config: &container.Config{}})
+		}
+
+		It("should switch the container to the target tag, even if it refers to the same image", func() {
+			client := dockerClient{api: docker, ClientOptions: ClientOptions{PullImages: false}}
+			mockServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", HaveSuffix("/images/portainer/portainer:1.3/json")),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, types.ImageInspect{ID: imageID}),
+			))
+			c := mockTaggedContainer(map[string]string{"com.centurylinklabs.watchtower.target-tag": "1.3"})
+
+			stale, _, err := client.IsContainerStale(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stale).To(BeTrue())
+			Expect(c.ImageName()).To(Equal("portainer/portainer:1.3"))
+		})
+		It("should not be combined with a tag constraint", func() {
+			client := dockerClient{api: docker, ClientOptions: ClientOptions{PullImages: false}}
+			c := mockTaggedContainer(map[string]string{
+				"com.centurylinklabs.watchtower.target-tag":     "1.3",
+				"com.centurylinklabs.watchtower.tag-constraint": "~1.2",
+			})
+
+			stale, _, err := client.IsContainerStale(c)
+			Expect(err).To(HaveOccurred())
+			Expect(stale).To(BeFalse())
+			Expect(mockServer.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+	When("recreating a container connected to multiple networks", func() {
+		It("should reconnect it to all networks using the same settings", func() {
+			client := dockerClient{
+				api:           docker,
+				ClientOptions: ClientOptions{},
+			}
+			containerID := t.ContainerID("b978af0b858aa8855cce46b628817d4ed58e58f2c4f66c9b9c5449134ed4c008")
+			mockServer.AppendHandlers(mocks.GetContainerHandlers("running")...)
+			c, err := client.GetContainer(containerID)
+			Expect(err).NotTo(HaveOccurred())
+
+			c.containerInfo.HostConfig.NetworkMode = "proxy"
+			c.containerInfo.NetworkSettings.Networks = map[string]*network.EndpointSettings{
+				"proxy": {
+					IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "172.30.0.10"},
+					Aliases:    []string{"web", containerID.ShortID()},
+					NetworkID:  "8b1a1fc4d2e3",
+					EndpointID: "2f3e4d5c6b7a",
+					IPAddress:  "172.30.0.10",
+					MacAddress: "02:42:ac:1e:00:0a",
+				},
+				"backend": {
+					IPAMConfig: &network.EndpointIPAMConfig{
+						IPv4Address:  "10.1.0.5",
+						IPv6Address:  "fd00::5",
+						LinkLocalIPs: []string{"169.254.10.10"},
+					},
+					Aliases:    []string{"api", containerID.ShortID(), "backend-alias"},
+					NetworkID:  "1c2d3e4f5a6b",
+					IPAddress:  "10.1.0.5",
+					MacAddress: "02:42:0a:01:00:05",
+				},
+			}
+
+			var created struct {
+				container.Config
+				NetworkingConfig network.NetworkingConfig
+			}
+			var connected types.NetworkConnect
+			newID := "5a3e2c6d0f9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2"
+			mockServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", HaveSuffix("/networks/backend")),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, types.NetworkResource{
+						Name: "backend",
+						IPAM: network.IPAM{Config: []network.IPAMConfig{{Subnet: "10.1.0.0/24"}, {Subnet: "fd01::/64"}}},
+					}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", HaveSuffix("/networks/proxy")),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, types.NetworkResource{
+						Name: "proxy",
+						IPAM: network.IPAM{Config: []network.IPAMConfig{{Subnet: "172.30.0.0/16"}}},
+					}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", HaveSuffix("/containers/create")),
+					func(w http.ResponseWriter, r *http.Request) {
+						Expect(json.NewDecoder(r.Body).Decode(&created)).To(Succeed())
+					},
+					ghttp.RespondWithJSONEncoded(http.StatusCreated, container.ContainerCreateCreatedBody{ID: newID}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", HaveSuffix("/networks/backend/connect")),
+					func(w http.ResponseWriter, r *http.Request) {
+						Expect(json.NewDecoder(r.Body).Decode(&connected)).To(Succeed())
+					},
+					ghttp.RespondWith(http.StatusOK, nil),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", HaveSuffix("/containers/%s/start", newID)),
+					ghttp.RespondWith(http.StatusNoContent, nil),
+				),
+			)
+
+			_, err = client.StartContainer(c)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(created.NetworkingConfig.EndpointsConfig).To(HaveLen(1))
+			proxy := created.NetworkingConfig.EndpointsConfig["proxy"]
+			Expect(proxy).NotTo(BeNil())
+			Expect(proxy.Aliases).To(Equal([]string{"web"}))
+			Expect(proxy.IPAMConfig.IPv4Address).To(Equal("172.30.0.10"))
+			Expect(proxy.NetworkID).To(BeEmpty())
+			Expect(proxy.EndpointID).To(BeEmpty())
+			// API versions before 1.44 only support setting the MAC address of the primary network
+			Expect(created.MacAddress).To(Equal("02:42:ac:1e:00:0a"))
+
+			backend := connected.EndpointConfig
+			Expect(connected.Container).To(Equal(newID))
+			Expect(backend.Aliases).To(Equal([]string{"api", "backend-alias"}))
+			Expect(backend.IPAMConfig.IPv4Address).To(Equal("10.1.0.5"))
+			Expect(backend.IPAMConfig.IPv6Address).To(BeEmpty())
+			Expect(backend.IPAMConfig.LinkLocalIPs).To(ConsistOf("169.254.10.10"))
+			Expect(backend.MacAddress).To(Equal("02:42:0a:01:00:05"))
+		})
+	})
+	When("recreating a container using the nvidia runtime", func() {
+		It("should preserve the device configuration", func() {
+			client := dockerClient{
+				api:           docker,
+				ClientOptions: ClientOptions{},
+			}
+			mockServer.AppendHandlers(mocks.GetContainerHandlers("nvidia")...)
+			c, err := client.GetContainer("4f2d1b5c9e8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.HasDeviceRequests()).To(BeTrue())
+
+			var created struct {
+				HostConfig container.HostConfig
+			}
+			newID := "5a3e2c6d0f9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2"
+			mockServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", HaveSuffix("/containers/create"), "name=%2Fgpu-worker&platform=linux%2Famd64"),
+					func(w http.ResponseWriter, r *http.Request) {
+						Expect(json.NewDecoder(r.Body).Decode(&created)).To(Succeed())
+					},
+					ghttp.RespondWithJSONEncoded(http.StatusCreated, container.ContainerCreateCreatedBody{ID: newID}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", HaveSuffix("/containers/%s/start", newID)),
+					ghttp.RespondWith(http.StatusNoContent, nil),
+				),
+			)
+
+			id, err := client.StartContainer(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(BeEquivalentTo(newID))
+
+			original := c.ContainerInfo().HostConfig
+			Expect(created.HostConfig.Runtime).To(Equal("nvidia"))
+			Expect(created.HostConfig.DeviceRequests).To(Equal(original.DeviceRequests))
+			Expect(created.HostConfig.DeviceRequests).To(HaveLen(2))
+			Expect(created.HostConfig.DeviceRequests[0].Count).To(Equal(-1))
+			Expect(created.HostConfig.DeviceRequests[1].DeviceIDs).To(HaveLen(1))
+			Expect(created.HostConfig.Devices).To(Equal(original.Devices))
+			Expect(created.HostConfig.DeviceCgroupRules).To(ConsistOf("c 195:* rmw", "c 509:* rmw"))
+		})
+	})
+	When("recreating a container requesting devices using an API version without the device requests", func() {
+		var client dockerClient
+		var c Container
+		BeforeEach(func() {
+			oldDocker, _ := cli.NewClientWithOpts(
+				cli.WithHost(mockServer.URL()),
+				cli.WithHTTPClient(mockServer.HTTPTestServer.Client()),
+				cli.WithVersion("1.39"))
+			client = dockerClient{api: oldDocker}
+			mockServer.AppendHandlers(mocks.GetContainerHandlers("nvidia")...)
+			var err error
+			c, err = client.GetContainer("4f2d1b5c9e8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should create it using the first API version supporting them", func() {
+			var created struct {
+				HostConfig container.HostConfig
+			}
+			newID := "5a3e2c6d0f9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2"
+			mockServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", HaveSuffix("/v1.39/version")),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, types.Version{APIVersion: "1.41"}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", HaveSuffix("/v1.40/containers/create"), "name=%2Fgpu-worker"),
+					func(w http.ResponseWriter, r *http.Request) {
+						Expect(json.NewDecoder(r.Body).Decode(&created)).To(Succeed())
+					},
+					ghttp.RespondWithJSONEncoded(http.StatusCreated, container.ContainerCreateCreatedBody{ID: newID}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", HaveSuffix("/v1.39/containers/%s/start", newID)),
+					ghttp.RespondWith(http.StatusNoContent, nil),
+				),
+			)
+
+			id, err := client.StartContainer(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(BeEquivalentTo(newID))
+			Expect(created.HostConfig.DeviceRequests).To(Equal(c.ContainerInfo().HostConfig.DeviceRequests))
+		})
+
+		It("should fail instead of creating it without its devices if the daemon does not support them", func() {
+			mockServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", HaveSuffix("/v1.39/version")),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, types.Version{APIVersion: "1.39"}),
+				),
+			)
+
+			_, err := client.StartContainer(c)
+			Expect(err).To(MatchError(ContainSubstring("docker API version 1.40 is required")))
+			Expect(mockServer.ReceivedRequests()).NotTo(ContainElement(
+				WithTransform(func(r *http.Request) string { return r.URL.Path }, HaveSuffix("/containers/create"))))
+		})
+	})
+	When("recreating a container using an API version without the container platform", func() {
+		It("should leave out the platform", func() {
+			oldDocker, _ := cli.NewClientWithOpts(
+				cli.WithHost(mockServer.URL()),
+				cli.WithHTTPClient(mockServer.HTTPTestServer.Client()),
+				cli.WithVersion("1.40"))
+			client := dockerClient{api: oldDocker}
+			mockServer.AppendHandlers(mocks.GetContainerHandlers("nvidia")...)
+			c, err := client.GetContainer("4f2d1b5c9e8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.imagePlatform()).NotTo(BeNil())
+
+			newID := "5a3e2c6d0f9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2"
+			mockServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", HaveSuffix("/v1.40/containers/create"), "name=%2Fgpu-worker"),
+					ghttp.RespondWithJSONEncoded(http.StatusCreated, container.ContainerCreateCreatedBody{ID: newID}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", HaveSuffix("/containers/%s/start", newID)),
+					ghttp.RespondWith(http.StatusNoContent, nil),
+				),
+			)
+
+			id, err := client.StartContainer(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(BeEquivalentTo(newID))
+		})
+	})
 	Describe(`ExecuteCommand`, func() {
 		When(`logging`, func() {
 			It("should include container id field", func() {
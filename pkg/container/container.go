@@ -1,11 +1,14 @@
 package container
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/containrrr/watchtower/internal/util"
+	"github.com/containrrr/watchtower/pkg/session"
 	wt "github.com/containrrr/watchtower/pkg/types"
 
 	"github.com/docker/docker/api/types"
@@ -92,6 +95,56 @@ func (c Container) ImageName() string {
 	return imageName
 }
 
+// TagConstraint returns the semantic version constraint that the tag of the container image should follow, and
+// whether the tag constraint label was set
+func (c Container) TagConstraint() (string, bool) {
+	constraint, ok := c.getLabelValue(tagConstraintLabel)
+	if !ok || strings.TrimSpace(constraint) == "" {
+		return "", false
+	}
+	return constraint, true
+}
+
+// RegistryCredentialSet returns the name of the credential set used to authenticate to the registry of the container
+// image, and whether the registry auth label was set
+func (c Container) RegistryCredentialSet() (string, bool) {
+	set, ok := c.getLabelValue(registryAuthLabel)
+	set = strings.TrimSpace(set)
+	if !ok || set == "" {
+		return "", false
+	}
+	return set, true
+}
+
+// TargetTag returns the tag that the container should be migrated to, and whether the target tag label was set
+func (c Container) TargetTag() (string, bool) {
+	tag, ok := c.getLabelValue(targetTagLabel)
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), ":")
+	if !ok || tag == "" {
+		return "", false
+	}
+	return tag, true
+}
+
+// ImageRepositoryAndTag splits the image name of the container into its repository and tag
+func (c Container) ImageRepositoryAndTag() (string, string) {
+	imageName := c.ImageName()
+	sep := strings.LastIndex(imageName, ":")
+	if sep < 0 || strings.Contains(imageName[sep:], "/") {
+		return imageName, "latest"
+	}
+	return imageName[:sep], imageName[sep+1:]
+}
+
+// retarget changes the image that the container will be recreated from. Since the container info is shared between
+// copies of the container, this affects all of them.
+func (c Container) retarget(imageName string) {
+	if _, ok := c.getLabelValue(zodiacLabel); ok {
+		c.containerInfo.Config.Labels[zodiacLabel] = imageName
+	}
+	c.containerInfo.Config.Image = imageName
+}
+
 // Enabled returns the value of the container enabled label and if the label
 // was set.
 func (c Container) Enabled() (bool, bool) {
@@ -124,6 +177,85 @@ func (c Container) IsMonitorOnly() bool {
 	return parsedBool
 }
 
+// IsStageOnly returns the value of the stage-only label. If the label
+// is not set then false is returned.
+func (c Container) IsStageOnly() bool {
+	rawBool, ok := c.getLabelValue(stageOnlyLabel)
+	if !ok {
+		return false
+	}
+
+	parsedBool, err := strconv.ParseBool(rawBool)
+	if err != nil {
+		return false
+	}
+
+	return parsedBool
+}
+
+// IsNoPull returns whether pulling new images should be skipped for the container. The value of the no-pull label
+// overrides the supplied global setting.
+func (c Container) IsNoPull(defaultNoPull bool) bool {
+	if noPull, ok := c.getBoolLabelValue(noPullLabel); ok {
+		return noPull
+	}
+	return defaultNoPull
+}
+
+// IsNoCleanup returns whether the previous image of the container should be kept after updating it. The value of
+// the no-cleanup label overrides the supplied global setting.
+func (c Container) IsNoCleanup(defaultNoCleanup bool) bool {
+	if noCleanup, ok := c.getBoolLabelValue(noCleanupLabel); ok {
+		return noCleanup
+	}
+	return defaultNoCleanup
+}
+
+// IsReviveStopped returns whether the container should be started once recreated, even though it was stopped. The
+// value of the revive-stopped label overrides the supplied global setting.
+func (c Container) IsReviveStopped(defaultReviveStopped bool) bool {
+	if revive, ok := c.getBoolLabelValue(reviveStoppedLabel); ok {
+		return revive
+	}
+	return defaultReviveStopped
+}
+
+// IsOneshot returns whether the container has exited and is not restarted by docker, like the containers of jobs that
+// only run once
+func (c Container) IsOneshot() bool {
+	info := c.containerInfo
+	if info == nil || info.State == nil || info.State.Status != "exited" || info.HostConfig == nil {
+		return false
+	}
+	policy := info.HostConfig.RestartPolicy.Name
+	return policy == "" || policy == "no"
+}
+
+// IsVolumeBackup returns whether the volumes of the container should be backed up before it is updated
+func (c Container) IsVolumeBackup() bool {
+	backup, _ := c.getBoolLabelValue(volumeBackupLabel)
+	return backup
+}
+
+// ComposeProject returns the docker compose project the container was created by, holding the service of the
+// container, and whether it was created by docker compose at all
+func (c Container) ComposeProject() (wt.ComposeProject, bool) {
+	name := c.getLabelValueOrEmpty(composeProjectLabel)
+	if name == "" {
+		return wt.ComposeProject{}, false
+	}
+	project := wt.ComposeProject{Name: name, WorkingDir: c.getLabelValueOrEmpty(composeWorkingDirLabel)}
+	if service := c.getLabelValueOrEmpty(composeServiceLabel); service != "" {
+		project.Services = []string{service}
+	}
+	for _, file := range strings.Split(c.getLabelValueOrEmpty(composeConfigLabel), ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			project.ConfigFiles = append(project.ConfigFiles, file)
+		}
+	}
+	return project, true
+}
+
 // Scope returns the value of the scope UID label and if the label
 // was set.
 func (c Container) Scope() (string, bool) {
@@ -136,7 +268,7 @@ func (c Container) Scope() (string, bool) {
 }
 
 // Links returns a list containing the names of all the containers to which
-// this container is linked.
+// this container is linked, including the containers whose network or PID namespace it joins.
 func (c Container) Links() []string {
 	var links []string
 
@@ -144,7 +276,7 @@ func (c Container) Links() []string {
 
 	if dependsOnLabelValue != "" {
 		links := strings.Split(dependsOnLabelValue, ",")
-		return links
+		return append(links, c.namespaceContainers()...)
 	}
 
 	if (c.containerInfo != nil) && (c.containerInfo.HostConfig != nil) {
@@ -154,7 +286,7 @@ func (c Container) Links() []string {
 		}
 	}
 
-	return links
+	return append(links, c.namespaceContainers()...)
 }
 
 // ToRestart return whether the container should be restarted, either because
@@ -190,6 +322,74 @@ func (c Container) PreUpdateTimeout() int {
 	return minutes
 }
 
+// Group returns the name of the group set using the group label, whose containers are updated together when
+// transactional groups are enabled, and whether the label was set
+func (c Container) Group() (string, bool) {
+	group, ok := c.getLabelValue(groupLabel)
+	group = strings.TrimSpace(group)
+	return group, ok && group != ""
+}
+
+// PostUpdateWait returns how long to wait after starting the recreated container before moving on to the next one.
+// The value is either a duration, like 1m30s, or a number of seconds. If the label is not set, zero is returned.
+func (c Container) PostUpdateWait() (time.Duration, error) {
+	val, ok := c.getLabelValue(postUpdateWaitLabel)
+	if !ok || strings.TrimSpace(val) == "" {
+		return 0, nil
+	}
+	val = strings.TrimSpace(val)
+
+	if seconds, err := strconv.Atoi(val); err == nil {
+		val = fmt.Sprintf("%ds", seconds)
+	}
+	wait, err := time.ParseDuration(val)
+	if err == nil && wait < 0 {
+		err = errors.New("the wait time cannot be negative")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid post-update wait %q: %w", val, err)
+	}
+	return wait, nil
+}
+
+// CheckInterval returns the minimum time between the checks for a new image of the container, set using the
+// check-interval label as a duration like 24h, or zero if it is checked during every session
+func (c Container) CheckInterval() (time.Duration, error) {
+	val, ok := c.getLabelValue(checkIntervalLabel)
+	if !ok || strings.TrimSpace(val) == "" {
+		return 0, nil
+	}
+	val = strings.TrimSpace(val)
+
+	interval, err := time.ParseDuration(val)
+	if err == nil && interval < 0 {
+		err = errors.New("the interval cannot be negative")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid check interval %q: %w", val, err)
+	}
+	return interval, nil
+}
+
+// MinImageAge returns how old a new image has to be before the container is updated to it. The value of the
+// min-image-age label, a duration like 48h, overrides the supplied global setting.
+func (c Container) MinImageAge(defaultAge time.Duration) (time.Duration, error) {
+	val, ok := c.getLabelValue(minImageAgeLabel)
+	if !ok || strings.TrimSpace(val) == "" {
+		return defaultAge, nil
+	}
+	val = strings.TrimSpace(val)
+
+	age, err := time.ParseDuration(val)
+	if err == nil && age < 0 {
+		err = errors.New("the age cannot be negative")
+	}
+	if err != nil {
+		return defaultAge, fmt.Errorf("invalid minimum image age %q: %w", val, err)
+	}
+	return age, nil
+}
+
 // PostUpdateTimeout checks whether a container has a specific timeout set
 // for how long the post-update command is allowed to run. This value is expressed
 // either as an integer, in minutes, or as 0 which will allow the command/script
@@ -216,6 +416,32 @@ func (c Container) StopSignal() string {
 	return c.getLabelValueOrEmpty(signalLabel)
 }
 
+// HealthStartPeriod returns the start period declared by the health check of the container, falling back to the
+// health check of its image. If no start period is declared, zero is returned.
+func (c Container) HealthStartPeriod() time.Duration {
+	if config := c.containerInfo.Config; config != nil && config.Healthcheck != nil && config.Healthcheck.StartPeriod > 0 {
+		return config.Healthcheck.StartPeriod
+	}
+	if c.imageInfo != nil && c.imageInfo.Config != nil && c.imageInfo.Config.Healthcheck != nil {
+		return c.imageInfo.Config.Healthcheck.StartPeriod
+	}
+	return 0
+}
+
+// InHealthStartPeriod returns whether the container is still within its health check start period, scaled by the
+// supplied multiplier. Failing health checks should not be held against a container during this time.
+func (c Container) InHealthStartPeriod(multiplier float64, now time.Time) bool {
+	startPeriod := time.Duration(float64(c.HealthStartPeriod()) * multiplier)
+	if startPeriod <= 0 || c.containerInfo.State == nil {
+		return false
+	}
+	startedAt, err := time.Parse(time.RFC3339Nano, c.containerInfo.State.StartedAt)
+	if err != nil {
+		return false
+	}
+	return now.Before(startedAt.Add(startPeriod))
+}
+
 // Ideally, we'd just be able to take the ContainerConfig from the old container
 // and use it as the starting point for creating the new container; however,
 // the ContainerConfig that comes back from the Inspect call merges the default
@@ -286,11 +512,31 @@ func (c Container) hostConfig() *dockercontainer.HostConfig {
 	return hostConfig
 }
 
+// HasDeviceRequests returns whether the container requests any devices (such as GPUs) from a device driver
+func (c Container) HasDeviceRequests() bool {
+	hostConfig := c.containerInfo.HostConfig
+	return hostConfig != nil && len(hostConfig.DeviceRequests) > 0
+}
+
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(values)), values...)
+}
+
 // HasImageInfo returns whether image information could be retrieved for the container
 func (c Container) HasImageInfo() bool {
 	return c.imageInfo != nil
 }
 
+// ImageMissingError returns the error the container is skipped with when its image is no longer available locally
+func (c Container) ImageMissingError() error {
+	return session.WithSkipReason(session.SkipImageMissing, fmt.Errorf("the image %s used by the container is no "+
+		"longer available locally (see --missing-image-info for how to handle this)",
+		wt.ImageID(c.containerInfo.Image).ShortID()))
+}
+
 // ImageInfo fetches the ImageInspect data of the current container
 func (c Container) ImageInfo() *types.ImageInspect {
 	return c.imageInfo
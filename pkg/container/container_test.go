@@ -1,6 +1,8 @@
 package container
 
 import (
+	"time"
+
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/go-connections/nat"
@@ -205,6 +207,21 @@ var _ = Describe("the container", func() {
 					Expect(links).To(HaveLen(0))
 				})
 			})
+			When("the container joins the namespaces of another container", func() {
+				It("should depend on that container", func() {
+					c = mockContainerWithLinks(nil)
+					c.containerInfo.HostConfig.NetworkMode = "container:vpn"
+					c.containerInfo.HostConfig.PidMode = "container:vpn"
+					Expect(c.Links()).To(Equal([]string{"/vpn"}))
+				})
+				It("should depend on it along with the depends on label", func() {
+					c = mockContainerWithLabels(map[string]string{
+						"com.centurylinklabs.watchtower.depends-on": "postgres",
+					})
+					c.containerInfo.HostConfig = &container.HostConfig{NetworkMode: "container:vpn"}
+					Expect(c.Links()).To(Equal([]string{"postgres", "/vpn"}))
+				})
+			})
 			When("the depends on label is not present", func() {
 				It("should fetch depending containers from host config links", func() {
 					c = mockContainerWithLinks([]string{
@@ -217,6 +234,228 @@ var _ = Describe("the container", func() {
 			})
 		})
 
+		When("fetching the watchtower labels", func() {
+			It("should only return the labels used by watchtower", func() {
+				c = mockContainerWithLabels(map[string]string{
+					"com.centurylinklabs.watchtower":            "true",
+					"com.centurylinklabs.watchtower.enable":     "false",
+					"com.centurylinklabs.watchtowerish":         "nope",
+					"com.centurylinklabs.zodiac.original-image": "the-original-image",
+				})
+				Expect(c.WatchtowerLabels()).To(Equal(map[string]string{
+					"com.centurylinklabs.watchtower":        "true",
+					"com.centurylinklabs.watchtower.enable": "false",
+				}))
+			})
+			It("should replace the watchtower labels while keeping the others", func() {
+				c = mockContainerWithLabels(map[string]string{
+					"com.centurylinklabs.watchtower.enable":       "false",
+					"com.centurylinklabs.watchtower.monitor-only": "true",
+					"funny.label": "true",
+				})
+				c.ReplaceWatchtowerLabels(map[string]string{"com.centurylinklabs.watchtower.enable": "true"})
+				Expect(c.ContainerInfo().Config.Labels).To(Equal(map[string]string{
+					"com.centurylinklabs.watchtower.enable": "true",
+					"funny.label":                           "true",
+				}))
+			})
+		})
+
+		When("checking the health check start period", func() {
+			startedAt := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+			var hc *Container
+			BeforeEach(func() {
+				hc = mockContainerWithLabels(nil)
+				hc.containerInfo.State = &types.ContainerState{StartedAt: startedAt.Format(time.RFC3339Nano)}
+				hc.imageInfo = &types.ImageInspect{Config: &container.Config{
+					Healthcheck: &container.HealthConfig{StartPeriod: time.Minute},
+				}}
+			})
+			It("should fall back to the start period of the image", func() {
+				Expect(hc.HealthStartPeriod()).To(Equal(time.Minute))
+			})
+			It("should prefer the start period of the container", func() {
+				hc.containerInfo.Config.Healthcheck = &container.HealthConfig{StartPeriod: 2 * time.Minute}
+				Expect(hc.HealthStartPeriod()).To(Equal(2 * time.Minute))
+			})
+			It("should scale the start period using the multiplier", func() {
+				now := startedAt.Add(90 * time.Second)
+				Expect(hc.InHealthStartPeriod(1, now)).To(BeFalse())
+				Expect(hc.InHealthStartPeriod(2, now)).To(BeTrue())
+			})
+			It("should not be in the start period if none is declared", func() {
+				hc.imageInfo = &types.ImageInspect{}
+				Expect(hc.InHealthStartPeriod(2, startedAt)).To(BeFalse())
+			})
+		})
+
+		When("labelling the container with the update session", func() {
+			It("should add the session labels, without treating them as configuration", func() {
+				c = mockContainerWithLabels(map[string]string{})
+				c.SetUpdateSession("0123456789ab")
+				Expect(c.containerInfo.Config.Labels).To(HaveKeyWithValue(sessionIDLabel, "0123456789ab"))
+				Expect(c.containerInfo.Config.Labels).To(HaveKey(previousImageLabel))
+				Expect(c.WatchtowerLabels()).To(BeEmpty())
+			})
+			It("should remove the session labels when no session is given", func() {
+				c = mockContainerWithLabels(map[string]string{
+					sessionIDLabel:     "0123456789ab",
+					previousImageLabel: "sha256:0123",
+				})
+				c.SetUpdateSession("")
+				Expect(c.containerInfo.Config.Labels).To(BeEmpty())
+			})
+		})
+
+		When("a post-update wait has been set", func() {
+			It("should accept durations as well as seconds", func() {
+				c = mockContainerWithLabels(map[string]string{postUpdateWaitLabel: "1m30s"})
+				Expect(c.PostUpdateWait()).To(Equal(90 * time.Second))
+				c = mockContainerWithLabels(map[string]string{postUpdateWaitLabel: "60"})
+				Expect(c.PostUpdateWait()).To(Equal(time.Minute))
+			})
+			It("should return an error for invalid or negative values", func() {
+				c = mockContainerWithLabels(map[string]string{postUpdateWaitLabel: "soon"})
+				_, err := c.PostUpdateWait()
+				Expect(err).To(HaveOccurred())
+				c = mockContainerWithLabels(map[string]string{postUpdateWaitLabel: "-5s"})
+				_, err = c.PostUpdateWait()
+				Expect(err).To(HaveOccurred())
+			})
+			It("should return zero if the label is not set", func() {
+				c = mockContainerWithLabels(map[string]string{})
+				Expect(c.PostUpdateWait()).To(BeZero())
+			})
+		})
+
+		When("a group has been set", func() {
+			It("should return the trimmed group", func() {
+				c = mockContainerWithLabels(map[string]string{groupLabel: " shop "})
+				group, ok := c.Group()
+				Expect(ok).To(BeTrue())
+				Expect(group).To(Equal("shop"))
+			})
+			It("should not return an empty group", func() {
+				c = mockContainerWithLabels(map[string]string{groupLabel: ""})
+				_, ok := c.Group()
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		When("a minimum image age has been set", func() {
+			It("should override the default using the label", func() {
+				c = mockContainerWithLabels(map[string]string{minImageAgeLabel: "72h"})
+				Expect(c.MinImageAge(48 * time.Hour)).To(Equal(72 * time.Hour))
+				c = mockContainerWithLabels(map[string]string{minImageAgeLabel: "0"})
+				Expect(c.MinImageAge(48 * time.Hour)).To(BeZero())
+			})
+			It("should return the default if the label is not set", func() {
+				c = mockContainerWithLabels(map[string]string{})
+				Expect(c.MinImageAge(48 * time.Hour)).To(Equal(48 * time.Hour))
+			})
+			It("should return the default along with an error for invalid or negative values", func() {
+				for _, value := range []string{"two days", "-1h"} {
+					c = mockContainerWithLabels(map[string]string{minImageAgeLabel: value})
+					age, err := c.MinImageAge(48 * time.Hour)
+					Expect(err).To(HaveOccurred())
+					Expect(age).To(Equal(48 * time.Hour))
+				}
+			})
+		})
+
+		When("using a registry credential set", func() {
+			It("should return the name of the set if the label is set", func() {
+				c = mockContainerWithLabels(map[string]string{registryAuthLabel: " harbor-prod "})
+				set, ok := c.RegistryCredentialSet()
+				Expect(ok).To(BeTrue())
+				Expect(set).To(Equal("harbor-prod"))
+			})
+			It("should return false if the label is not set", func() {
+				c = mockContainerWithLabels(map[string]string{})
+				_, ok := c.RegistryCredentialSet()
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		When("migrating to a target tag", func() {
+			It("should return the tag without a leading colon", func() {
+				c = mockContainerWithLabels(map[string]string{
+					"com.centurylinklabs.watchtower.target-tag": ":1.3",
+				})
+				tag, ok := c.TargetTag()
+				Expect(ok).To(BeTrue())
+				Expect(tag).To(Equal("1.3"))
+			})
+			It("should return false if the label is empty", func() {
+				c = mockContainerWithLabels(map[string]string{
+					"com.centurylinklabs.watchtower.target-tag": " ",
+				})
+				_, ok := c.TargetTag()
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		When("following a tag constraint", func() {
+			It("should return the constraint if the label is set", func() {
+				c = mockContainerWithLabels(map[string]string{
+					"com.centurylinklabs.watchtower.tag-constraint": "~1.24",
+				})
+				constraint, ok := c.TagConstraint()
+				Expect(ok).To(BeTrue())
+				Expect(constraint).To(Equal("~1.24"))
+			})
+			It("should split the image name into repository and tag", func() {
+				c = mockContainerWithImageName("registry.example.com:5000/team/app:1.24.0")
+				repository, tag := c.ImageRepositoryAndTag()
+				Expect(repository).To(Equal("registry.example.com:5000/team/app"))
+				Expect(tag).To(Equal("1.24.0"))
+			})
+			It("should recreate the container using the new tag after retargeting", func() {
+				c = mockContainerWithImageName("nginx:1.24.0")
+				c.containerInfo.Config.Labels = map[string]string{}
+				c.imageInfo = &types.ImageInspect{Config: &container.Config{}}
+				c.containerInfo.HostConfig = &container.HostConfig{}
+				c.retarget("nginx:1.24.3")
+				Expect(c.ImageName()).To(Equal("nginx:1.24.3"))
+				Expect(c.runtimeConfig().Image).To(Equal("nginx:1.24.3"))
+			})
+		})
+
+		When("checking the no-pull and no-cleanup labels", func() {
+			It("should use the global setting if the labels are not set", func() {
+				c = mockContainerWithLabels(map[string]string{})
+				Expect(c.IsNoPull(true)).To(BeTrue())
+				Expect(c.IsNoPull(false)).To(BeFalse())
+				Expect(c.IsNoCleanup(true)).To(BeTrue())
+				Expect(c.IsNoCleanup(false)).To(BeFalse())
+			})
+			It("should override the global setting if the labels are set", func() {
+				c = mockContainerWithLabels(map[string]string{
+					"com.centurylinklabs.watchtower.no-pull":    "true",
+					"com.centurylinklabs.watchtower.no-cleanup": "false",
+				})
+				Expect(c.IsNoPull(false)).To(BeTrue())
+				Expect(c.IsNoCleanup(true)).To(BeFalse())
+			})
+			It("should ignore invalid label values", func() {
+				c = mockContainerWithLabels(map[string]string{
+					"com.centurylinklabs.watchtower.no-pull": "maybe",
+				})
+				Expect(c.IsNoPull(false)).To(BeFalse())
+			})
+		})
+
+		When("checking the revive-stopped label", func() {
+			It("should override the global setting if the label is set", func() {
+				c = mockContainerWithLabels(map[string]string{
+					"com.centurylinklabs.watchtower.revive-stopped": "false",
+				})
+				Expect(c.IsReviveStopped(true)).To(BeFalse())
+				c = mockContainerWithLabels(map[string]string{})
+				Expect(c.IsReviveStopped(true)).To(BeTrue())
+			})
+		})
+
 		When("there is a pre or post update timeout", func() {
 			It("should return minute values", func() {
 				c = mockContainerWithLabels(map[string]string{
@@ -231,6 +470,46 @@ var _ = Describe("the container", func() {
 		})
 
 	})
+	Describe("ImageConfigChanges", func() {
+		current := &container.Config{
+			Entrypoint:   []string{"/app"},
+			Env:          []string{"PATH=/usr/bin", "MODE=prod", "DEBUG"},
+			ExposedPorts: nat.PortSet{"80/tcp": {}},
+			Volumes:      map[string]struct{}{"/data": {}},
+			Labels:       map[string]string{"version": "1.0"},
+		}
+		When("the configurations are the same", func() {
+			It("should not report any changes", func() {
+				Expect(ImageConfigChanges(current, current)).To(BeEmpty())
+			})
+		})
+		When("the configurations differ", func() {
+			It("should describe every change", func() {
+				latest := &container.Config{
+					Entrypoint:   []string{"/entrypoint.sh", "app"},
+					Env:          []string{"PATH=/usr/local/bin:/usr/bin", "DEBUG", "PORT=8080"},
+					ExposedPorts: nat.PortSet{"8080/tcp": {}},
+					Volumes:      map[string]struct{}{"/data": {}, "/cache": {}},
+					Labels:       map[string]string{"version": "1.1"},
+				}
+				Expect(ImageConfigChanges(current, latest)).To(Equal([]string{
+					`entrypoint changed from ["/app"] to ["/entrypoint.sh" "app"]`,
+					"exposed port 80/tcp removed",
+					"exposed port 8080/tcp added",
+					"env MODE removed",
+					`env PATH changed from "/usr/bin" to "/usr/local/bin:/usr/bin"`,
+					`env PORT added: "8080"`,
+					"volume /cache added",
+					`label version changed from "1.0" to "1.1"`,
+				}))
+			})
+		})
+		When("either configuration is missing", func() {
+			It("should not report any changes", func() {
+				Expect(ImageConfigChanges(nil, current)).To(BeNil())
+			})
+		})
+	})
 })
 
 func mockContainerWithPortBindings(portBindingSources ...string) *Container {
@@ -0,0 +1,53 @@
+package container
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/containrrr/watchtower/pkg/registry/size"
+	"github.com/containrrr/watchtower/pkg/session"
+	units "github.com/docker/go-units"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkDiskSpace returns an error if pulling the image of the container would leave less free disk space than the
+// configured minimum, which the container is skipped with as SkipInsufficientDiskSpace. Failing to determine the free space does not prevent the pull, as that would block every update.
+func (client dockerClient) checkDiskSpace(ctx context.Context, container Container, registryAuth string) error {
+	path := client.DiskSpacePath
+	if path == "" {
+		info, err := client.api.Info(ctx)
+		if err != nil {
+			log.WithError(err).Warn("Could not retrieve the Docker data root, skipping the disk space check")
+			return nil
+		}
+		path = info.DockerRootDir
+	}
+
+	free, err := freeDiskSpace(path)
+	if err != nil {
+		log.WithError(err).Warnf("Could not determine the free disk space of %s, skipping the disk space check", path)
+		return nil
+	}
+
+	required, err := size.Estimate(container, registryAuth)
+	if err != nil {
+		log.WithError(err).Debugf("Could not estimate the size of %s, only checking the minimum free space", container.ImageName())
+		required = 0
+	}
+
+	log.WithFields(log.Fields{
+		"path":     path,
+		"free":     units.HumanSize(float64(free)),
+		"required": units.HumanSize(float64(required)),
+	}).Debug("Checked the free disk space before pulling")
+
+	if free-required < client.MinFreeSpace {
+		return session.WithSkipReason(session.SkipInsufficientDiskSpace, fmt.Errorf(
+			"not enough disk space to pull %s: %s available, the image needs about %s and %s must remain free",
+			container.ImageName(),
+			units.HumanSize(float64(free)),
+			units.HumanSize(float64(required)),
+			units.HumanSize(float64(client.MinFreeSpace))))
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package container
+
+import "syscall"
+
+// freeDiskSpace returns the number of bytes available to unprivileged users on the file system containing path
+func freeDiskSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,42 @@
+package container
+
+import (
+	"context"
+
+	t "github.com/containrrr/watchtower/pkg/types"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// WatchContainerStarts streams the IDs of the containers as they start, until done is closed or the event stream
+// fails. The returned error channel receives the error the stream failed with, if any, after which the ID channel is
+// closed.
+func (client dockerClient) WatchContainerStarts(done <-chan struct{}) (<-chan t.ContainerID, <-chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	messages, errs := client.api.Events(ctx, types.EventsOptions{
+		Filters: filters.NewArgs(filters.Arg("type", "container"), filters.Arg("event", "start")),
+	})
+
+	ids := make(chan t.ContainerID)
+	failed := make(chan error, 1)
+	go func() {
+		defer cancel()
+		defer close(ids)
+		for {
+			select {
+			case <-done:
+				return
+			case err := <-errs:
+				failed <- err
+				return
+			case message := <-messages:
+				select {
+				case ids <- t.ContainerID(message.Actor.ID):
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return ids, failed
+}
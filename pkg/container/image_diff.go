@@ -0,0 +1,121 @@
+package container
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/containrrr/watchtower/internal/util"
+	dockerContainer "github.com/docker/docker/api/types/container"
+)
+
+// ImageConfigChanges describes the differences between the configurations of the current and latest image of a
+// container that are likely to affect it, like changed entrypoints, exposed ports, environment defaults, volumes and
+// labels. The changes are returned as human-readable lines, sorted by the part of the configuration they concern.
+func ImageConfigChanges(current *dockerContainer.Config, latest *dockerContainer.Config) []string {
+	if current == nil || latest == nil {
+		return nil
+	}
+
+	var changes []string
+	if !util.SliceEqual(current.Entrypoint, latest.Entrypoint) {
+		changes = append(changes, fmt.Sprintf("entrypoint changed from %s to %s",
+			formatCommand(current.Entrypoint), formatCommand(latest.Entrypoint)))
+	}
+
+	currentPorts := make(map[string]string, len(current.ExposedPorts))
+	for port := range current.ExposedPorts {
+		currentPorts[string(port)] = ""
+	}
+	latestPorts := make(map[string]string, len(latest.ExposedPorts))
+	for port := range latest.ExposedPorts {
+		latestPorts[string(port)] = ""
+	}
+	changes = append(changes, setChanges("exposed port", currentPorts, latestPorts)...)
+
+	changes = append(changes, mapChanges("env", envMap(current.Env), envMap(latest.Env))...)
+
+	currentVolumes := make(map[string]string, len(current.Volumes))
+	for volume := range current.Volumes {
+		currentVolumes[volume] = ""
+	}
+	latestVolumes := make(map[string]string, len(latest.Volumes))
+	for volume := range latest.Volumes {
+		latestVolumes[volume] = ""
+	}
+	changes = append(changes, setChanges("volume", currentVolumes, latestVolumes)...)
+
+	return append(changes, mapChanges("label", current.Labels, latest.Labels)...)
+}
+
+// setChanges lists the keys that were added to or removed from the set
+func setChanges(kind string, current map[string]string, latest map[string]string) []string {
+	var changes []string
+	for _, key := range sortedKeys(current, latest) {
+		_, inCurrent := current[key]
+		_, inLatest := latest[key]
+		if inCurrent && !inLatest {
+			changes = append(changes, fmt.Sprintf("%s %s removed", kind, key))
+		} else if inLatest && !inCurrent {
+			changes = append(changes, fmt.Sprintf("%s %s added", kind, key))
+		}
+	}
+	return changes
+}
+
+// mapChanges lists the keys that were added, removed or given another value
+func mapChanges(kind string, current map[string]string, latest map[string]string) []string {
+	var changes []string
+	for _, key := range sortedKeys(current, latest) {
+		currentValue, inCurrent := current[key]
+		latestValue, inLatest := latest[key]
+		switch {
+		case inCurrent && !inLatest:
+			changes = append(changes, fmt.Sprintf("%s %s removed", kind, key))
+		case inLatest && !inCurrent:
+			changes = append(changes, fmt.Sprintf("%s %s added: %q", kind, key, latestValue))
+		case currentValue != latestValue:
+			changes = append(changes, fmt.Sprintf("%s %s changed from %q to %q", kind, key, currentValue, latestValue))
+		}
+	}
+	return changes
+}
+
+func sortedKeys(maps ...map[string]string) []string {
+	unique := map[string]bool{}
+	for _, m := range maps {
+		for key := range m {
+			unique[key] = true
+		}
+	}
+	keys := make([]string, 0, len(unique))
+	for key := range unique {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func envMap(env []string) map[string]string {
+	vars := make(map[string]string, len(env))
+	for _, entry := range env {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 {
+			vars[parts[0]] = parts[1]
+		} else {
+			vars[parts[0]] = ""
+		}
+	}
+	return vars
+}
+
+func formatCommand(command []string) string {
+	if len(command) == 0 {
+		return "none"
+	}
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = fmt.Sprintf("%q", arg)
+	}
+	return "[" + strings.Join(quoted, " ") + "]"
+}
@@ -0,0 +1,62 @@
+package container
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	t "github.com/containrrr/watchtower/pkg/types"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// ContainerLogs writes the stdout and stderr output of the container to the writer, limited to the given number of
+// lines from the end, or all of it if tail is 0
+func (client dockerClient) ContainerLogs(containerID t.ContainerID, tail int, w io.Writer) error {
+	bg := context.Background()
+
+	info, err := client.api.ContainerInspect(bg, string(containerID))
+	if err != nil {
+		return err
+	}
+
+	opts := types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Tail: "all"}
+	if tail > 0 {
+		opts.Tail = strconv.Itoa(tail)
+	}
+	logs, err := client.api.ContainerLogs(bg, string(containerID), opts)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	// the output of containers with a TTY is not multiplexed
+	if info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(w, logs)
+		return err
+	}
+	_, err = stdcopy.StdCopy(w, w, logs)
+	return err
+}
+
+// archiveLogs keeps the logs of the container using the log archive, if one is set. Failing to do so is only logged,
+// as it should not hold up the update.
+func (client dockerClient) archiveLogs(c Container) {
+	if client.LogArchive == nil {
+		return
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		_ = writer.CloseWithError(client.ContainerLogs(c.ID(), 0, writer))
+	}()
+	err := client.LogArchive.Archive(c.Name(), c.ImageName(), time.Now(), reader)
+	// stops the logs from being copied if the archive gave up early
+	_ = reader.Close()
+	if err != nil {
+		log.WithError(err).Warnf("Could not archive the logs of %s", c.Name())
+		return
+	}
+	log.Debugf("Archived the logs of %s", c.Name())
+}
@@ -1,19 +1,44 @@
 package container
 
+import (
+	"strconv"
+	"strings"
+)
+
 const (
-	watchtowerLabel       = "com.centurylinklabs.watchtower"
-	signalLabel           = "com.centurylinklabs.watchtower.stop-signal"
-	enableLabel           = "com.centurylinklabs.watchtower.enable"
-	monitorOnlyLabel      = "com.centurylinklabs.watchtower.monitor-only"
-	dependsOnLabel        = "com.centurylinklabs.watchtower.depends-on"
-	zodiacLabel           = "com.centurylinklabs.zodiac.original-image"
-	scope                 = "com.centurylinklabs.watchtower.scope"
-	preCheckLabel         = "com.centurylinklabs.watchtower.lifecycle.pre-check"
-	postCheckLabel        = "com.centurylinklabs.watchtower.lifecycle.post-check"
-	preUpdateLabel        = "com.centurylinklabs.watchtower.lifecycle.pre-update"
-	postUpdateLabel       = "com.centurylinklabs.watchtower.lifecycle.post-update"
-	preUpdateTimeoutLabel = "com.centurylinklabs.watchtower.lifecycle.pre-update-timeout"
+	watchtowerLabel        = "com.centurylinklabs.watchtower"
+	signalLabel            = "com.centurylinklabs.watchtower.stop-signal"
+	enableLabel            = "com.centurylinklabs.watchtower.enable"
+	monitorOnlyLabel       = "com.centurylinklabs.watchtower.monitor-only"
+	stageOnlyLabel         = "com.centurylinklabs.watchtower.stage-only"
+	dependsOnLabel         = "com.centurylinklabs.watchtower.depends-on"
+	zodiacLabel            = "com.centurylinklabs.zodiac.original-image"
+	scope                  = "com.centurylinklabs.watchtower.scope"
+	preCheckLabel          = "com.centurylinklabs.watchtower.lifecycle.pre-check"
+	postCheckLabel         = "com.centurylinklabs.watchtower.lifecycle.post-check"
+	preUpdateLabel         = "com.centurylinklabs.watchtower.lifecycle.pre-update"
+	postUpdateLabel        = "com.centurylinklabs.watchtower.lifecycle.post-update"
+	preUpdateTimeoutLabel  = "com.centurylinklabs.watchtower.lifecycle.pre-update-timeout"
 	postUpdateTimeoutLabel = "com.centurylinklabs.watchtower.lifecycle.post-update-timeout"
+	noPullLabel            = "com.centurylinklabs.watchtower.no-pull"
+	noCleanupLabel         = "com.centurylinklabs.watchtower.no-cleanup"
+	tagConstraintLabel     = "com.centurylinklabs.watchtower.tag-constraint"
+	targetTagLabel         = "com.centurylinklabs.watchtower.target-tag"
+	postUpdateWaitLabel    = "com.centurylinklabs.watchtower.post-update-wait"
+	minImageAgeLabel       = "com.centurylinklabs.watchtower.min-image-age"
+	checkIntervalLabel     = "com.centurylinklabs.watchtower.check-interval"
+	registryAuthLabel      = "com.centurylinklabs.watchtower.registry-auth"
+	volumeBackupLabel      = "com.centurylinklabs.watchtower.volume-backup"
+	includeStoppedLabel    = "com.centurylinklabs.watchtower.include-stopped"
+	reviveStoppedLabel     = "com.centurylinklabs.watchtower.revive-stopped"
+	groupLabel             = "com.centurylinklabs.watchtower.group"
+	composeProjectLabel    = "com.docker.compose.project"
+	composeWorkingDirLabel = "com.docker.compose.project.working_dir"
+	composeConfigLabel     = "com.docker.compose.project.config_files"
+	composeServiceLabel    = "com.docker.compose.service"
+	sessionLabelPrefix     = "com.centurylinklabs.watchtower.session."
+	sessionIDLabel         = sessionLabelPrefix + "id"
+	previousImageLabel     = sessionLabelPrefix + "previous-image"
 )
 
 // GetLifecyclePreCheckCommand returns the pre-check command set in the container metadata or an empty string
@@ -36,6 +61,61 @@ func (c Container) GetLifecyclePostUpdateCommand() string {
 	return c.getLabelValueOrEmpty(postUpdateLabel)
 }
 
+// WatchtowerLabels returns a copy of all the labels in the container metadata that are used to configure watchtower
+func (c Container) WatchtowerLabels() map[string]string {
+	labels := map[string]string{}
+	for key, val := range c.containerInfo.Config.Labels {
+		if IsWatchtowerLabel(key) {
+			labels[key] = val
+		}
+	}
+	return labels
+}
+
+// ReplaceWatchtowerLabels removes all the watchtower labels from the container metadata and replaces them with
+// the supplied ones. Labels not used by watchtower are left untouched. The change only takes effect once the
+// container is recreated.
+func (c Container) ReplaceWatchtowerLabels(labels map[string]string) {
+	config := c.containerInfo.Config
+	if config.Labels == nil {
+		config.Labels = map[string]string{}
+	}
+	for key := range config.Labels {
+		if IsWatchtowerLabel(key) {
+			delete(config.Labels, key)
+		}
+	}
+	for key, val := range labels {
+		config.Labels[key] = val
+	}
+}
+
+// SetUpdateSession labels the container with the ID of the update session recreating it and the ID of the image it
+// was running before, so that tools watching the docker events can relate the create event to watchtower.
+// An empty session ID removes the labels instead. The change only takes effect once the container is recreated.
+func (c Container) SetUpdateSession(sessionID string) {
+	config := c.containerInfo.Config
+	if sessionID == "" {
+		delete(config.Labels, sessionIDLabel)
+		delete(config.Labels, previousImageLabel)
+		return
+	}
+	if config.Labels == nil {
+		config.Labels = map[string]string{}
+	}
+	config.Labels[sessionIDLabel] = sessionID
+	config.Labels[previousImageLabel] = c.containerInfo.Image
+}
+
+// IsWatchtowerLabel returns whether the supplied label key is used to configure watchtower. The labels describing the
+// update session are set by watchtower itself, and are not considered configuration.
+func IsWatchtowerLabel(key string) bool {
+	if strings.HasPrefix(key, sessionLabelPrefix) {
+		return false
+	}
+	return key == watchtowerLabel || strings.HasPrefix(key, watchtowerLabel+".")
+}
+
 // ContainsWatchtowerLabel takes a map of labels and values and tells
 // the consumer whether it contains a valid watchtower instance label
 func ContainsWatchtowerLabel(labels map[string]string) bool {
@@ -54,3 +134,18 @@ func (c Container) getLabelValue(label string) (string, bool) {
 	val, ok := c.containerInfo.Config.Labels[label]
 	return val, ok
 }
+
+// getBoolLabelValue returns the parsed value of a boolean label, and whether it was set to a valid value
+func (c Container) getBoolLabelValue(label string) (bool, bool) {
+	rawBool, ok := c.getLabelValue(label)
+	if !ok {
+		return false, false
+	}
+
+	parsedBool, err := strconv.ParseBool(rawBool)
+	if err != nil {
+		return false, false
+	}
+
+	return parsedBool, true
+}
@@ -1,6 +1,9 @@
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	types "github.com/containrrr/watchtower/pkg/types"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // FilterableContainer is an autogenerated mock type for the FilterableContainer type
 type FilterableContainer struct {
@@ -92,3 +95,24 @@ func (_m *FilterableContainer) ImageName() string {
 
 	return r0
 }
+
+// ComposeProject provides a mock function with given fields:
+func (_m *FilterableContainer) ComposeProject() (types.ComposeProject, bool) {
+	ret := _m.Called()
+
+	var r0 types.ComposeProject
+	if rf, ok := ret.Get(0).(func() types.ComposeProject); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(types.ComposeProject)
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func() bool); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
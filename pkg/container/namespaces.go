@@ -0,0 +1,58 @@
+package container
+
+import (
+	"context"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	log "github.com/sirupsen/logrus"
+)
+
+// namespaceContainers returns the names of the containers whose network or PID namespace the container joins, like
+// an application using network_mode: container:vpn. It has to be recreated whenever they are, to join the namespaces
+// of the recreated containers. The names start with a slash, like the ones of the links.
+func (c Container) namespaceContainers() []string {
+	if c.containerInfo == nil || c.containerInfo.HostConfig == nil {
+		return nil
+	}
+	var names []string
+	if mode := c.containerInfo.HostConfig.NetworkMode; mode.IsContainer() {
+		names = append(names, "/"+strings.TrimPrefix(mode.ConnectedContainer(), "/"))
+	}
+	if mode := c.containerInfo.HostConfig.PidMode; mode.IsContainer() {
+		if name := "/" + strings.TrimPrefix(mode.Container(), "/"); len(names) == 0 || names[0] != name {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// resolveNamespaces refers to the containers whose namespaces the container joins by name rather than by ID, as
+// docker compose does, since their IDs change when they are recreated. This lets the container be recreated joining
+// the namespaces of the recreated containers, and be ordered after them.
+func (client dockerClient) resolveNamespaces(ctx context.Context, info *types.ContainerJSON) {
+	if info.HostConfig == nil {
+		return
+	}
+	if mode := info.HostConfig.NetworkMode; mode.IsContainer() {
+		if name := client.containerName(ctx, mode.ConnectedContainer()); name != "" {
+			info.HostConfig.NetworkMode = dockercontainer.NetworkMode("container:" + name)
+		}
+	}
+	if mode := info.HostConfig.PidMode; mode.IsContainer() {
+		if name := client.containerName(ctx, mode.Container()); name != "" {
+			info.HostConfig.PidMode = dockercontainer.PidMode("container:" + name)
+		}
+	}
+}
+
+// containerName returns the name of the container referred to by name or ID, or an empty string if it is gone
+func (client dockerClient) containerName(ctx context.Context, ref string) string {
+	info, err := client.api.ContainerInspect(ctx, ref)
+	if err != nil {
+		log.WithError(err).Debugf("Could not find the container %s whose namespace is joined", ref)
+		return ""
+	}
+	return strings.TrimPrefix(info.Name, "/")
+}
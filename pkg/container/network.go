@@ -0,0 +1,116 @@
+package container
+
+import (
+	"net"
+	"sort"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/versions"
+)
+
+// endpointMacAddressMinAPIVersion is the first docker API version that supports setting a MAC address per endpoint.
+// Earlier versions only support setting the MAC address of the primary network using the container config.
+const endpointMacAddressMinAPIVersion = "1.44"
+
+// endpointsConfig returns the settings needed to connect a recreated container to each of the networks that the
+// container is connected to. The state belonging to the current endpoints (IDs, gateways and dynamically assigned
+// addresses) is left out, as is the alias containing the short ID of the current container, which would otherwise
+// accumulate with every update.
+func (c Container) endpointsConfig() map[string]*network.EndpointSettings {
+	endpoints := make(map[string]*network.EndpointSettings)
+	if c.containerInfo.NetworkSettings == nil {
+		return endpoints
+	}
+
+	shortID := c.ID().ShortID()
+	for name, current := range c.containerInfo.NetworkSettings.Networks {
+		if current == nil {
+			continue
+		}
+		endpoint := &network.EndpointSettings{
+			Links:      copyStrings(current.Links),
+			MacAddress: current.MacAddress,
+		}
+		for _, alias := range current.Aliases {
+			if alias != shortID {
+				endpoint.Aliases = append(endpoint.Aliases, alias)
+			}
+		}
+		if ipam := current.IPAMConfig; ipam != nil {
+			endpoint.IPAMConfig = &network.EndpointIPAMConfig{
+				IPv4Address:  ipam.IPv4Address,
+				IPv6Address:  ipam.IPv6Address,
+				LinkLocalIPs: copyStrings(ipam.LinkLocalIPs),
+			}
+		}
+		if current.DriverOpts != nil {
+			endpoint.DriverOpts = make(map[string]string, len(current.DriverOpts))
+			for key, val := range current.DriverOpts {
+				endpoint.DriverOpts[key] = val
+			}
+		}
+		endpoints[name] = endpoint
+	}
+
+	return endpoints
+}
+
+// HasAdditionalNetworks returns whether the recreated container has to be connected to further networks after being
+// created with its primary one
+func (c Container) HasAdditionalNetworks() bool {
+	if c.containerInfo == nil || c.containerInfo.ContainerJSONBase == nil || c.containerInfo.HostConfig == nil ||
+		c.containerInfo.HostConfig.NetworkMode.IsHost() {
+		return false
+	}
+	return len(c.endpointsConfig()) > 1
+}
+
+// primaryNetwork returns the name of the network that the recreated container should be created with. This is the
+// network set as the network mode of the container if it is connected to it, otherwise the first network by name.
+func primaryNetwork(mode string, endpoints map[string]*network.EndpointSettings) string {
+	if _, found := endpoints[mode]; found {
+		return mode
+	}
+	names := sortedNetworkNames(endpoints)
+	if len(names) < 1 {
+		return ""
+	}
+	return names[0]
+}
+
+func sortedNetworkNames(endpoints map[string]*network.EndpointSettings) []string {
+	names := make([]string, 0, len(endpoints))
+	for name := range endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// supportsEndpointMacAddress returns whether the supplied API version allows the MAC address to be set per endpoint
+func supportsEndpointMacAddress(apiVersion string) bool {
+	return !versions.LessThan(apiVersion, endpointMacAddressMinAPIVersion)
+}
+
+// subnetsContain returns whether the address is part of any of the supplied subnets. If no subnets could be
+// determined, or the address can not be parsed, the subnets are assumed to contain the address.
+func subnetsContain(subnets []string, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return true
+	}
+
+	checked := 0
+	for _, subnet := range subnets {
+		_, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			continue
+		}
+		checked++
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return checked == 0
+}
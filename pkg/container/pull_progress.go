@@ -0,0 +1,104 @@
+package container
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// pullMessage is the part of the progress messages of an image pull that is needed to count the transferred bytes
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+}
+
+type layerProgress struct {
+	current  int64
+	total    int64
+	complete bool
+}
+
+// readPullProgress reads the progress stream of an image pull until it ends, returning the number of bytes that were
+// downloaded. Layers that already existed locally are not counted.
+func readPullProgress(stream io.Reader) (int64, error) {
+	layers := map[string]*layerProgress{}
+	decoder := json.NewDecoder(stream)
+	for {
+		var message pullMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			break
+		} else if err != nil {
+			// The pull request will be aborted prematurely unless the response is read
+			_, _ = io.Copy(ioutil.Discard, stream)
+			return countLayerBytes(layers), err
+		}
+		if message.ID == "" {
+			continue
+		}
+
+		switch message.Status {
+		case "Downloading":
+			layer := layers[message.ID]
+			if layer == nil {
+				layer = &layerProgress{}
+				layers[message.ID] = layer
+			}
+			layer.current = message.ProgressDetail.Current
+			if message.ProgressDetail.Total > 0 {
+				layer.total = message.ProgressDetail.Total
+			}
+		case "Download complete":
+			if layer := layers[message.ID]; layer != nil {
+				layer.complete = true
+			}
+		}
+	}
+	return countLayerBytes(layers), nil
+}
+
+func countLayerBytes(layers map[string]*layerProgress) int64 {
+	var bytes int64
+	for _, layer := range layers {
+		if layer.complete && layer.total > 0 {
+			bytes += layer.total
+		} else {
+			bytes += layer.current
+		}
+	}
+	return bytes
+}
+
+// pullTransfers keeps track of the bytes pulled per image until they are collected for the session report
+type pullTransfers struct {
+	mutex sync.Mutex
+	bytes map[string]int64
+}
+
+func newPullTransfers() *pullTransfers {
+	return &pullTransfers{bytes: map[string]int64{}}
+}
+
+func (p *pullTransfers) add(imageName string, bytes int64) {
+	if p == nil || bytes == 0 {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.bytes[imageName] += bytes
+}
+
+func (p *pullTransfers) take(imageName string) int64 {
+	if p == nil {
+		return 0
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	bytes := p.bytes[imageName]
+	delete(p.bytes, imageName)
+	return bytes
+}
@@ -0,0 +1,23 @@
+package container
+
+import (
+	"fmt"
+
+	"github.com/docker/cli/cli/connhelper"
+	sdkClient "github.com/docker/docker/client"
+)
+
+// sshScheme is the scheme of the docker hosts reached through an SSH connection
+const sshScheme = "ssh://"
+
+// sshClientOpts returns the options connecting the client to the host in the ssh:// URL by running the ssh client,
+// which forwards the connection to the daemon using the docker CLI on the host, like the docker CLI does itself. Only
+// key and agent authentication is supported, as there is no terminal to enter a password in, so the ssh client is run
+// in batch mode.
+func sshClientOpts(host string) ([]sdkClient.Opt, error) {
+	helper, err := connhelper.GetConnectionHelperWithSSHOpts(host, []string{"-o", "BatchMode=yes"})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh host: %w", err)
+	}
+	return []sdkClient.Opt{sdkClient.WithHost(helper.Host), sdkClient.WithDialContext(helper.Dialer)}, nil
+}
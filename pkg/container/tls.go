@@ -0,0 +1,194 @@
+package container
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	sdkClient "github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
+	log "github.com/sirupsen/logrus"
+)
+
+// tlsReloader keeps the TLS material of the docker client in DOCKER_CERT_PATH up to date, reading the files again
+// whenever they changed since they were last read. This allows short-lived certificates to be rotated without
+// restarting watchtower.
+type tlsReloader struct {
+	caFile   string
+	certFile string
+	keyFile  string
+	verify   bool
+	// serverName is the host of DOCKER_HOST, which the certificate of the daemon is verified against
+	serverName string
+	// reloaded is called after new TLS material has been read, to stop reusing the connections using the old one
+	reloaded func()
+
+	mutex  sync.Mutex
+	stamps []fileStamp
+	cert   *tls.Certificate
+	roots  *x509.CertPool
+}
+
+// fileStamp is used to tell whether a file changed since it was read
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// newTLSReloader reads the ca.pem, cert.pem and key.pem files in the directory. The CA is only used, and required,
+// when verifying the certificate of the daemon.
+func newTLSReloader(certPath string, verify bool) (*tlsReloader, error) {
+	r := &tlsReloader{
+		caFile:   filepath.Join(certPath, "ca.pem"),
+		certFile: filepath.Join(certPath, "cert.pem"),
+		keyFile:  filepath.Join(certPath, "key.pem"),
+		verify:   verify,
+	}
+	if _, err := r.refresh(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *tlsReloader) files() []string {
+	if r.verify {
+		return []string{r.certFile, r.keyFile, r.caFile}
+	}
+	return []string{r.certFile, r.keyFile}
+}
+
+// refresh reads the files again if any of them changed, returning whether new material was read. If the new files
+// cannot be used, like when only some of them have been replaced yet, the previous material is kept.
+func (r *tlsReloader) refresh() (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	files := r.files()
+	stamps := make([]fileStamp, len(files))
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, fmt.Errorf("could not read the docker tls material: %w", err)
+		}
+		stamps[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	if r.stamps != nil && equalStamps(stamps, r.stamps) {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("could not read the docker tls material: %w", err)
+	}
+	var roots *x509.CertPool
+	if r.verify {
+		pem, err := ioutil.ReadFile(r.caFile)
+		if err != nil {
+			return false, fmt.Errorf("could not read the docker tls material: %w", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return false, fmt.Errorf("could not read the docker tls material: no certificates in %s", r.caFile)
+		}
+	}
+
+	reloaded := r.stamps != nil
+	r.cert, r.roots, r.stamps = &cert, roots, stamps
+	return reloaded, nil
+}
+
+func equalStamps(a, b []fileStamp) bool {
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+// check refreshes the material before a handshake, logging when it changed or could not be read
+func (r *tlsReloader) check() {
+	reloaded, err := r.refresh()
+	if err != nil {
+		log.WithError(err).Warn("Could not reload the TLS certificates of the docker client, using the previous ones")
+		return
+	}
+	if reloaded {
+		log.Info("Reloaded the TLS certificates of the docker client")
+		if r.reloaded != nil {
+			r.reloaded()
+		}
+	}
+}
+
+func (r *tlsReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.cert, r.roots
+}
+
+// config returns a client configuration using the current material for each new connection. The certificate of the
+// daemon is verified by VerifyConnection instead of the default verification, as the roots can change. It is verified
+// against the host of DOCKER_HOST, as the server name of the connection is not set when the host is an IP address.
+func (r *tlsReloader) config() *tls.Config {
+	config := tlsconfig.ClientDefault()
+	config.InsecureSkipVerify = true
+	config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		cert, _ := r.current()
+		return cert, nil
+	}
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		r.check()
+		if !r.verify {
+			return nil
+		}
+		if len(state.PeerCertificates) == 0 {
+			return errors.New("the docker daemon did not present a certificate")
+		}
+		serverName := r.serverName
+		if serverName == "" {
+			serverName = state.ServerName
+		}
+		_, roots := r.current()
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			DNSName:       serverName,
+		})
+		return err
+	}
+	return config
+}
+
+// withReloadedTLS replaces the static TLS configuration the client read from DOCKER_CERT_PATH by one reloading the
+// files when they change
+func withReloadedTLS(certPath string, verify bool) sdkClient.Opt {
+	return func(c *sdkClient.Client) error {
+		transport, ok := c.HTTPClient().Transport.(*http.Transport)
+		if !ok {
+			return errors.New("could not configure the docker tls reloading: unexpected transport")
+		}
+		reloader, err := newTLSReloader(certPath, verify)
+		if err != nil {
+			return err
+		}
+		reloader.reloaded = transport.CloseIdleConnections
+		if host, err := url.Parse(c.DaemonHost()); err == nil {
+			reloader.serverName = host.Hostname()
+		}
+		transport.TLSClientConfig = reloader.config()
+		return nil
+	}
+}
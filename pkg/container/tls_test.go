@@ -0,0 +1,159 @@
+package container
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// writeTestCertificate writes a self-signed certificate, valid for the loopback address and usable as its own CA, as
+// the cert.pem, key.pem and ca.pem files in the directory
+func writeTestCertificate(dir string, serial int64, modTime time.Time) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "watchtower"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	files := map[string][]byte{"cert.pem": certPEM, "key.pem": keyPEM, "ca.pem": certPEM}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		ExpectWithOffset(1, os.WriteFile(path, content, 0o600)).To(Succeed())
+		ExpectWithOffset(1, os.Chtimes(path, modTime, modTime)).To(Succeed())
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	return pair
+}
+
+var _ = Describe("the docker tls reloading", func() {
+	var dir string
+	var start time.Time
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-tls")
+		Expect(err).NotTo(HaveOccurred())
+		start = time.Now().Add(-time.Minute)
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	It("should use the rotated certificates for new connections", func() {
+		first := writeTestCertificate(dir, 1, start)
+		reloader, err := newTLSReloader(dir, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		var clientSerials []int64
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clientSerials = append(clientSerials, r.TLS.PeerCertificates[0].SerialNumber.Int64())
+		}))
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, Certificates: []tls.Certificate{first}}
+		server.StartTLS()
+		defer server.Close()
+
+		reloader.serverName = "127.0.0.1"
+		transport := &http.Transport{TLSClientConfig: reloader.config()}
+		reloader.reloaded = transport.CloseIdleConnections
+		client := &http.Client{Transport: transport}
+
+		res, err := client.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		res.Body.Close()
+
+		second := writeTestCertificate(dir, 2, start.Add(time.Second))
+		server.TLS.Certificates = []tls.Certificate{second}
+		transport.CloseIdleConnections()
+		res, err = client.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		res.Body.Close()
+
+		Expect(clientSerials).To(Equal([]int64{1, 2}))
+	})
+
+	It("should reject daemons that are not signed by the CA", func() {
+		writeTestCertificate(dir, 1, start)
+		reloader, err := newTLSReloader(dir, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		defer server.Close()
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: reloader.config()}}
+		_, err = client.Get(server.URL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject daemons whose certificate is not valid for the host of DOCKER_HOST", func() {
+		cert := writeTestCertificate(dir, 1, start)
+		reloader, err := newTLSReloader(dir, true)
+		Expect(err).NotTo(HaveOccurred())
+		reloader.serverName = "192.0.2.1"
+
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+		server.StartTLS()
+		defer server.Close()
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: reloader.config()}}
+		_, err = client.Get(server.URL)
+		Expect(err).To(MatchError(ContainSubstring("192.0.2.1")))
+	})
+
+	It("should keep the previous material while the new files do not match", func() {
+		writeTestCertificate(dir, 1, start)
+		reloader, err := newTLSReloader(dir, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		otherDir, err := os.MkdirTemp("", "watchtower-tls")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(otherDir)
+		writeTestCertificate(otherDir, 2, start)
+		key, err := os.ReadFile(filepath.Join(otherDir, "key.pem"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, "key.pem"), key, 0o600)).To(Succeed())
+
+		reloaded, err := reloader.refresh()
+		Expect(err).To(HaveOccurred())
+		Expect(reloaded).To(BeFalse())
+		cert, _ := reloader.current()
+		Expect(cert).NotTo(BeNil())
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leaf.SerialNumber.Int64()).To(Equal(int64(1)))
+	})
+
+	It("should fail when the files are missing", func() {
+		_, err := newTLSReloader(dir, true)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,194 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/pkg/stdcopy"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultVolumeBackupImage is the image used to back up volumes if none is configured
+	DefaultVolumeBackupImage = "alpine:latest"
+	// DefaultVolumeBackupCommand archives the volume mounted at /volume to the backup target mounted at /backup
+	DefaultVolumeBackupCommand = `tar czf "/backup/${WATCHTOWER_CONTAINER}_${WATCHTOWER_VOLUME}_${WATCHTOWER_TIMESTAMP}.tar.gz" -C /volume .`
+)
+
+// VolumeBackup describes how the volumes of the containers using the volume backup label are backed up
+type VolumeBackup struct {
+	// Target is the host path or the name of the volume the backups are written to, mounted at /backup
+	Target string
+	// Image is the image of the containers running the backup command
+	Image string
+	// Command is run by sh in the backup container, with the volume to back up mounted read-only at /volume
+	Command string
+	// Timeout limits the time spent backing up a single volume
+	Timeout time.Duration
+}
+
+// backupVolumes returns the names of the volumes mounted by the container, in the order they are mounted
+func (c Container) backupVolumes() []string {
+	var names []string
+	for _, m := range c.containerInfo.Mounts {
+		if m.Type == mount.TypeVolume && m.Name != "" {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
+
+// BackupVolumes backs up the volumes of the container by running the backup command against each of them. The
+// container is stopped first, so that its volumes are not written to while they are being backed up, and is started
+// again if any of the backups fail. Containers that are removed automatically are backed up while running instead.
+func (client dockerClient) BackupVolumes(c Container, timeout time.Duration) error {
+	volumes := c.backupVolumes()
+	if len(volumes) == 0 {
+		log.Debugf("%s has no volumes to back up", c.Name())
+		return nil
+	}
+	if client.VolumeBackup == nil || client.VolumeBackup.Target == "" {
+		return fmt.Errorf("%s asks for its volumes to be backed up, but no volume backup target is configured", c.Name())
+	}
+
+	bg := context.Background()
+	backup := *client.VolumeBackup
+	if backup.Image == "" {
+		backup.Image = DefaultVolumeBackupImage
+	}
+	if backup.Command == "" {
+		backup.Command = DefaultVolumeBackupCommand
+	}
+
+	if _, _, err := client.api.ImageInspectWithRaw(bg, backup.Image); err != nil {
+		log.Debugf("Pulling the volume backup image %s", backup.Image)
+		if err := client.pullImageByName(bg, backup.Image, ""); err != nil {
+			return fmt.Errorf("could not pull the volume backup image %s: %w", backup.Image, err)
+		}
+	}
+
+	stopped := false
+	if c.IsRunning() && !c.containerInfo.HostConfig.AutoRemove {
+		if err := client.stopForBackup(bg, c, timeout); err != nil {
+			return err
+		}
+		stopped = true
+	}
+
+	timestamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, volume := range volumes {
+		log.Infof("Backing up volume %s of %s", volume, c.Name())
+		if err := client.runVolumeBackup(bg, c, volume, timestamp, backup); err != nil {
+			err = fmt.Errorf("could not back up volume %s: %w", volume, err)
+			if stopped {
+				if startErr := client.api.ContainerStart(bg, string(c.ID()), types.ContainerStartOptions{}); startErr != nil {
+					log.WithError(startErr).Errorf("Could not start %s again after the failed backup", c.Name())
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// stopForBackup stops the container the same way it would be stopped for the update. The container is still described
+// as running, as that is what decides whether the recreated container is started.
+func (client dockerClient) stopForBackup(ctx context.Context, c Container, timeout time.Duration) error {
+	signal := c.StopSignal()
+	if signal == "" {
+		signal = defaultStopSignal
+	}
+	log.Infof("Stopping %s (%s) with %s to back up its volumes", c.Name(), c.ID().ShortID(), signal)
+	if err := client.api.ContainerKill(ctx, string(c.ID()), signal); err != nil {
+		return err
+	}
+	_ = client.waitForStopOrTimeout(c, timeout)
+	return nil
+}
+
+// runVolumeBackup runs the backup command in a new container mounting the volume and the backup target, failing if
+// the command does not exit with 0 in time
+func (client dockerClient) runVolumeBackup(ctx context.Context, c Container, volume string, timestamp string, backup VolumeBackup) error {
+	config := &dockerContainer.Config{
+		Image: backup.Image,
+		Cmd:   []string{"sh", "-c", backup.Command},
+		Env: []string{
+			"WATCHTOWER_CONTAINER=" + strings.TrimPrefix(c.Name(), "/"),
+			"WATCHTOWER_IMAGE=" + c.ImageName(),
+			"WATCHTOWER_VOLUME=" + volume,
+			"WATCHTOWER_TIMESTAMP=" + timestamp,
+		},
+	}
+	hostConfig := &dockerContainer.HostConfig{
+		Mounts: []mount.Mount{
+			{Type: mount.TypeVolume, Source: volume, Target: "/volume", ReadOnly: true},
+			{Type: backupTargetType(backup.Target), Source: backup.Target, Target: "/backup"},
+		},
+	}
+
+	created, err := client.api.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := client.api.ContainerRemove(context.Background(), created.ID, types.ContainerRemoveOptions{Force: true}); err != nil {
+			log.WithError(err).Warnf("Could not remove the volume backup container %s", created.ID)
+		}
+	}()
+
+	if err := client.api.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return err
+	}
+
+	waitCtx := ctx
+	if backup.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, backup.Timeout)
+		defer cancel()
+	}
+	statusCh, errCh := client.api.ContainerWait(waitCtx, created.ID, dockerContainer.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("the backup did not finish within %s", backup.Timeout)
+		}
+		return err
+	case status := <-statusCh:
+		if status.Error != nil {
+			return errors.New(status.Error.Message)
+		}
+		if status.StatusCode != 0 {
+			return fmt.Errorf("the backup command exited with %d%s", status.StatusCode, client.backupOutput(ctx, created.ID))
+		}
+	}
+	return nil
+}
+
+// backupOutput returns the last lines written by the backup container, to explain why it failed
+func (client dockerClient) backupOutput(ctx context.Context, containerID string) string {
+	logs, err := client.api.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Tail: "5"})
+	if err != nil {
+		return ""
+	}
+	defer logs.Close()
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, logs); err != nil || output.Len() == 0 {
+		return ""
+	}
+	return ": " + strings.TrimSpace(output.String())
+}
+
+// backupTargetType returns whether the backup target is a host path or the name of a volume
+func backupTargetType(target string) mount.Type {
+	if strings.HasPrefix(target, "/") {
+		return mount.TypeBind
+	}
+	return mount.TypeVolume
+}
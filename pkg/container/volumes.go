@@ -0,0 +1,68 @@
+package container
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	sdkClient "github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// anonymousVolumeName matches the generated names of anonymous volumes
+var anonymousVolumeName = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// anonymousVolumes returns the names of the anonymous volumes mounted by the container. Named volumes are never
+// included, as they are meant to outlive the containers using them.
+func (c Container) anonymousVolumes() []string {
+	var names []string
+	for _, m := range c.containerInfo.Mounts {
+		if m.Type == mount.TypeVolume && anonymousVolumeName.MatchString(m.Name) {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
+
+// RemoveAnonymousVolumes removes the anonymous volumes of a container that has been replaced that are not used by any
+// other container, if the cleanup of volumes is enabled. In dry-run mode, the volumes that would have been removed are
+// only logged.
+func (client dockerClient) RemoveAnonymousVolumes(c Container) {
+	if !client.CleanupVolumes && !client.VolumesDryRun {
+		return
+	}
+	ctx := context.Background()
+	for _, name := range c.anonymousVolumes() {
+		fields := log.Fields{"container": c.Name(), "volume": name}
+
+		users, err := client.api.ContainerList(ctx, types.ContainerListOptions{
+			All:     true,
+			Filters: filters.NewArgs(filters.Arg("volume", name)),
+		})
+		if err != nil {
+			log.WithFields(fields).WithError(err).Warn("Could not check whether the volume is still in use, keeping it")
+			continue
+		}
+		if len(users) > 0 {
+			log.WithFields(fields).Debugf("Keeping anonymous volume used by %d other container(s)", len(users))
+			continue
+		}
+
+		if client.VolumesDryRun {
+			log.WithFields(fields).Info("Would remove anonymous volume of the replaced container (dry run)")
+			continue
+		}
+
+		if err := client.api.VolumeRemove(ctx, name, false); err != nil {
+			if sdkClient.IsErrNotFound(err) {
+				// The volume was already removed together with the container
+				continue
+			}
+			log.WithFields(fields).WithError(err).Warn("Failed to remove anonymous volume")
+			continue
+		}
+		log.WithFields(fields).Info("Removed anonymous volume of the replaced container")
+	}
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/rotate"
+	"github.com/containrrr/watchtower/pkg/types"
+)
+
+// The types of the recorded events
+const (
+	SessionStarted     = "session.started"
+	SessionFinished    = "session.finished"
+	ContainerChecked   = "container.checked"
+	ImagePulled        = "image.pulled"
+	ContainerRecreated = "container.recreated"
+	ContainerFailed    = "container.failed"
+	ContainerSkipped   = "container.skipped"
+	NotificationSent   = "notification.sent"
+)
+
+// Record records the event using the recorder, if there is one, setting its time if it has none
+func Record(recorder types.EventRecorder, event types.Event) {
+	if recorder == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	recorder.Record(event)
+}
+
+type sessionRecorder struct {
+	recorder types.EventRecorder
+	session  string
+}
+
+func (r sessionRecorder) Record(event types.Event) {
+	event.Session = r.session
+	r.recorder.Record(event)
+}
+
+// WithSession returns a recorder adding the session ID to the events, or nil if there is no recorder
+func WithSession(recorder types.EventRecorder, session string) types.EventRecorder {
+	if recorder == nil {
+		return nil
+	}
+	return sessionRecorder{recorder: recorder, session: session}
+}
+
+type multiRecorder []types.EventRecorder
+
+func (r multiRecorder) Record(event types.Event) {
+	for _, recorder := range r {
+		recorder.Record(event)
+	}
+}
+
+// Combine returns a recorder passing the events to each of the recorders that are not nil, or nil if there are none
+func Combine(recorders ...types.EventRecorder) types.EventRecorder {
+	var combined multiRecorder
+	for _, recorder := range recorders {
+		if recorder != nil {
+			combined = append(combined, recorder)
+		}
+	}
+	switch len(combined) {
+	case 0:
+		return nil
+	case 1:
+		return combined[0]
+	}
+	return combined
+}
+
+// Log is an EventRecorder writing the events to a file as JSON lines. Once the file would exceed the maximum size, it
+// is rotated, keeping the configured number of previous files with the suffixes .1, .2 and so on.
+type Log struct {
+	file *rotate.File
+}
+
+// Open opens the event log at the path, appending to it if it exists. A max size of 0 disables the rotation.
+func Open(path string, maxSize int64, maxFiles int) (*Log, error) {
+	file, err := rotate.Open(path, maxSize, maxFiles)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the event log: %w", err)
+	}
+	return &Log{file: file}, nil
+}
+
+// Record writes the event as a line of JSON, rotating the file first if it would exceed the maximum size. Failures
+// are only printed, as logging them would not reach the event log either. Events recorded once the log has been
+// closed are dropped.
+func (l *Log) Record(event types.Event) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode the %s event: %v\n", event.Type, err)
+		return
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil && !errors.Is(err, os.ErrClosed) {
+		fmt.Fprintf(os.Stderr, "Failed to write the %s event: %v\n", event.Type, err)
+	}
+}
+
+// Close closes the event log, after which further events are dropped
+func (l *Log) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,115 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containrrr/watchtower/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEvents(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Events Suite")
+}
+
+func readEvents(path string) []types.Event {
+	file, err := os.Open(path)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	defer file.Close()
+
+	var events []types.Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event types.Event
+		ExpectWithOffset(1, json.Unmarshal(scanner.Bytes(), &event)).To(Succeed())
+		events = append(events, event)
+	}
+	return events
+}
+
+var _ = Describe("the event log", func() {
+	var dir, path string
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-events")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "log", "events.jsonl")
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	It("should write each event as a line of JSON", func() {
+		log, err := Open(path, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Record(WithSession(log, "abc"), types.Event{Type: ContainerChecked, Container: "/web"})
+		Record(log, types.Event{Type: NotificationSent, Details: map[string]interface{}{"service": "logger"}})
+		Expect(log.Close()).To(Succeed())
+
+		events := readEvents(path)
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Type).To(Equal(ContainerChecked))
+		Expect(events[0].Session).To(Equal("abc"))
+		Expect(events[0].Time.IsZero()).To(BeFalse())
+		Expect(events[1].Details).To(HaveKeyWithValue("service", "logger"))
+	})
+
+	It("should append to the existing file", func() {
+		log, err := Open(path, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Record(log, types.Event{Type: SessionStarted})
+		Expect(log.Close()).To(Succeed())
+
+		log, err = Open(path, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Record(log, types.Event{Type: SessionFinished})
+		Expect(log.Close()).To(Succeed())
+
+		Expect(readEvents(path)).To(HaveLen(2))
+	})
+
+	It("should rotate the file once it would exceed the max size", func() {
+		log, err := Open(path, 200, 2)
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < 12; i++ {
+			Record(log, types.Event{Type: ContainerChecked, Container: strings.Repeat("c", 40)})
+		}
+		Expect(log.Close()).To(Succeed())
+
+		Expect(path + ".1").To(BeAnExistingFile())
+		Expect(path + ".2").To(BeAnExistingFile())
+		Expect(path + ".3").NotTo(BeAnExistingFile())
+		for _, file := range []string{path, path + ".1", path + ".2"} {
+			info, err := os.Stat(file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Size()).To(BeNumerically("<=", 200))
+		}
+	})
+
+	It("should not record anything without a recorder", func() {
+		Expect(WithSession(nil, "abc")).To(BeNil())
+		Record(nil, types.Event{Type: SessionStarted})
+	})
+
+	It("should pass the events to each of the combined recorders", func() {
+		Expect(Combine()).To(BeNil())
+		first, err := Open(path, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Combine(nil, first)).To(BeIdenticalTo(first))
+
+		second, err := Open(path+".copy", 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Record(Combine(first, second), types.Event{Type: SessionStarted})
+		Expect(first.Close()).To(Succeed())
+		Expect(second.Close()).To(Succeed())
+
+		Expect(readEvents(path)).To(HaveLen(1))
+		Expect(readEvents(path + ".copy")).To(HaveLen(1))
+	})
+})
@@ -1,6 +1,7 @@
 package filters
 
 import (
+	"path"
 	"regexp"
 	"strings"
 
@@ -13,34 +14,82 @@ func WatchtowerContainersFilter(c t.FilterableContainer) bool { return c.IsWatch
 // NoFilter will not filter out any containers
 func NoFilter(t.FilterableContainer) bool { return true }
 
-// FilterByNames returns all containers that match the specified name
+// FilterByNames returns all containers that match the specified names. Names may be regular expressions or shell-style
+// glob patterns, like `web-*`. Names prefixed with `!` exclude the matching containers instead, and when only
+// exclusions are passed, all other containers are returned.
 func FilterByNames(names []string, baseFilter t.Filter) t.Filter {
 	if len(names) == 0 {
 		return baseFilter
 	}
 
+	includes, excludes := splitNames(names)
+
 	return func(c t.FilterableContainer) bool {
-		for _, name := range names {
-			if name == c.Name() || name == c.Name()[1:] {
+		for _, name := range excludes {
+			if matchesName(name, c.Name()) {
+				return false
+			}
+		}
+		if len(includes) == 0 {
+			return baseFilter(c)
+		}
+		for _, name := range includes {
+			if matchesName(name, c.Name()) {
 				return baseFilter(c)
 			}
+		}
+		return false
+	}
+}
 
-			if re, err := regexp.Compile(name); err == nil {
-				indices := re.FindStringIndex(c.Name())
-				if indices == nil {
-					continue
-				}
-				start := indices[0]
-				end := indices[1]
-				if start <= 1 && end >= len(c.Name())-1 {
-					return baseFilter(c)
-				}
+// FilterByExactNames returns the containers with exactly any of the specified names, with or without the leading
+// slash. Unlike FilterByNames, the names are not used as patterns. Passing no names returns the base filter.
+func FilterByExactNames(names []string, baseFilter t.Filter) t.Filter {
+	if len(names) == 0 {
+		return baseFilter
+	}
+
+	return func(c t.FilterableContainer) bool {
+		trimmedName := strings.TrimPrefix(c.Name(), "/")
+		for _, name := range names {
+			if strings.TrimPrefix(name, "/") == trimmedName {
+				return baseFilter(c)
 			}
 		}
 		return false
 	}
 }
 
+// splitNames separates the names into the ones to include and the ones prefixed with `!` to exclude
+func splitNames(names []string) (includes []string, excludes []string) {
+	for _, name := range names {
+		if strings.HasPrefix(name, "!") {
+			excludes = append(excludes, name[1:])
+		} else {
+			includes = append(includes, name)
+		}
+	}
+	return includes, excludes
+}
+
+// matchesName returns whether the container name, with or without its leading slash, is the name or is matched by it.
+// Names containing any of `*`, `?` or `[` are only used as glob patterns, while the other ones are used as regular
+// expressions. Either has to match the whole name.
+func matchesName(name string, containerName string) bool {
+	trimmedName := strings.TrimPrefix(containerName, "/")
+	if name == containerName || name == trimmedName {
+		return true
+	}
+
+	if strings.ContainsAny(name, "*?[") {
+		matched, err := path.Match(strings.TrimPrefix(name, "/"), trimmedName)
+		return err == nil && matched
+	}
+
+	re, err := regexp.Compile(`^(?:` + strings.TrimPrefix(name, "/") + `)$`)
+	return err == nil && re.MatchString(trimmedName)
+}
+
 // FilterByEnableLabel returns all containers that have the enabled label set
 func FilterByEnableLabel(baseFilter t.Filter) t.Filter {
 	return func(c t.FilterableContainer) bool {
@@ -68,18 +117,109 @@ func FilterByDisabledLabel(baseFilter t.Filter) t.Filter {
 	}
 }
 
-// FilterByScope returns all containers that belongs to a specific scope
+// FilterByScope returns all containers that belongs to a specific scope. Both the scope and the scope label of the
+// containers may contain a comma-separated list of scopes, in which case containers belonging to any of the scopes
+// are returned.
 func FilterByScope(scope string, baseFilter t.Filter) t.Filter {
 	if scope == "" {
 		return baseFilter
 	}
 
+	scopes := ParseScopes(scope)
+
 	return func(c t.FilterableContainer) bool {
 		containerScope, ok := c.Scope()
-		if ok && containerScope == scope {
+		if !ok {
+			return false
+		}
+		for _, cs := range ParseScopes(containerScope) {
+			if containsScope(scopes, cs) {
+				return baseFilter(c)
+			}
+		}
+
+		return false
+	}
+}
+
+// FilterByScopeOrUnscoped returns all containers that belong to the scope, like FilterByScope, as well as the ones
+// that do not belong to any scope
+func FilterByScopeOrUnscoped(scope string, baseFilter t.Filter) t.Filter {
+	scoped := FilterByScope(scope, baseFilter)
+	return func(c t.FilterableContainer) bool {
+		if containerScope, ok := c.Scope(); !ok || len(ParseScopes(containerScope)) == 0 {
 			return baseFilter(c)
 		}
+		return scoped(c)
+	}
+}
+
+// FilterByExactScope returns all containers that belong to the same set of scopes as the one specified, regardless
+// of their order. Containers that only share some of the scopes are not returned.
+func FilterByExactScope(scope string, baseFilter t.Filter) t.Filter {
+	if scope == "" {
+		return baseFilter
+	}
+
+	scopes := ParseScopes(scope)
+
+	return func(c t.FilterableContainer) bool {
+		containerScope, ok := c.Scope()
+		if !ok {
+			return false
+		}
+		containerScopes := ParseScopes(containerScope)
+		if len(containerScopes) != len(scopes) {
+			return false
+		}
+		for _, cs := range containerScopes {
+			if !containsScope(scopes, cs) {
+				return false
+			}
+		}
 
+		return baseFilter(c)
+	}
+}
+
+// ParseScopes splits a comma-separated list of scopes, ignoring empty and duplicate entries
+func ParseScopes(scope string) []string {
+	var scopes []string
+	for _, s := range strings.Split(scope, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" && !containsScope(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterByProjects returns all containers that were created by docker compose as part of any of the projects. Passing
+// no projects returns the base filter.
+func FilterByProjects(projects []string, baseFilter t.Filter) t.Filter {
+	if len(projects) == 0 {
+		return baseFilter
+	}
+
+	return func(c t.FilterableContainer) bool {
+		project, ok := c.ComposeProject()
+		if !ok {
+			return false
+		}
+		for _, name := range projects {
+			if name == project.Name {
+				return baseFilter(c)
+			}
+		}
 		return false
 	}
 }
@@ -102,20 +242,31 @@ func FilterByImage(images []string, baseFilter t.Filter) t.Filter {
 	}
 }
 
-// BuildFilter creates the needed filter of containers
-func BuildFilter(names []string, enableLabel bool, scope string) (t.Filter, string) {
+// BuildFilter creates the needed filter of containers. With enableLabel, only the containers enabled using the enable
+// label are checked. With disableLabel, all containers are checked except the ones disabled using the enable label,
+// and the ones without a scope are checked along with the ones in the scope. With projects, only the containers created
+// by docker compose as part of the projects are checked.
+func BuildFilter(names []string, enableLabel bool, disableLabel bool, scope string, projects []string) (t.Filter, string) {
 	sb := strings.Builder{}
 	filter := NoFilter
 	filter = FilterByNames(names, filter)
 
-	if len(names) > 0 {
+	includes, excludes := splitNames(names)
+	if len(includes) > 0 {
 		sb.WriteString("which name matches \"")
-		for i, n := range names {
-			sb.WriteString(n)
-			if i < len(names)-1 {
-				sb.WriteString(`" or "`)
-			}
-		}
+		sb.WriteString(strings.Join(includes, `" or "`))
+		sb.WriteString(`", `)
+	}
+	if len(excludes) > 0 {
+		sb.WriteString("which name does not match \"")
+		sb.WriteString(strings.Join(excludes, `" or "`))
+		sb.WriteString(`", `)
+	}
+
+	if len(projects) > 0 {
+		filter = FilterByProjects(projects, filter)
+		sb.WriteString("of the compose projects \"")
+		sb.WriteString(strings.Join(projects, `" or "`))
 		sb.WriteString(`", `)
 	}
 
@@ -127,11 +278,24 @@ func BuildFilter(names []string, enableLabel bool, scope string) (t.Filter, stri
 	}
 	if scope != "" {
 		// If a scope has been defined, containers should only be considered
-		// if the scope is specifically set.
-		filter = FilterByScope(scope, filter)
-		sb.WriteString(`in scope "`)
-		sb.WriteString(scope)
-		sb.WriteString(`", `)
+		// if the scope is specifically set, unless only the disabled containers are left out.
+		scopes := ParseScopes(scope)
+		if len(scopes) > 1 {
+			sb.WriteString(`in any of the scopes "`)
+		} else {
+			sb.WriteString(`in scope "`)
+		}
+		sb.WriteString(strings.Join(scopes, `", "`))
+		if disableLabel {
+			filter = FilterByScopeOrUnscoped(scope, filter)
+			sb.WriteString(`" or without a scope, `)
+		} else {
+			filter = FilterByScope(scope, filter)
+			sb.WriteString(`", `)
+		}
+	}
+	if disableLabel && sb.Len() > 0 {
+		sb.WriteString("except the ones disabled using the enable label, ")
 	}
 	filter = FilterByDisabledLabel(filter)
 
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/containrrr/watchtower/pkg/container/mocks"
+	"github.com/containrrr/watchtower/pkg/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -47,6 +48,27 @@ func TestFilterByNames(t *testing.T) {
 	container.AssertExpectations(t)
 }
 
+func TestFilterByExactNames(t *testing.T) {
+	assert.Nil(t, FilterByExactNames(nil, nil))
+
+	filter := FilterByExactNames([]string{"web", "/db"}, NoFilter)
+
+	container := new(mocks.FilterableContainer)
+	container.On("Name").Return("/web")
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Name").Return("db")
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Name").Return("/webapp")
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+}
+
 func TestFilterByNamesRegex(t *testing.T) {
 	names := []string{`ba(b|ll)oon`}
 
@@ -111,6 +133,56 @@ func TestFilterByScope(t *testing.T) {
 	container.AssertExpectations(t)
 }
 
+func TestFilterByMultipleScopes(t *testing.T) {
+	filter := FilterByScope("team-a, team-b", NoFilter)
+	assert.NotNil(t, filter)
+
+	container := new(mocks.FilterableContainer)
+	container.On("Scope").Return("team-b", true)
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Scope").Return("team-c,team-a", true)
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Scope").Return("team-c,team-d", true)
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+}
+
+func TestFilterByExactScope(t *testing.T) {
+	filter := FilterByExactScope("team-a,team-b", NoFilter)
+	assert.NotNil(t, filter)
+
+	container := new(mocks.FilterableContainer)
+	container.On("Scope").Return("team-b, team-a", true)
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Scope").Return("team-a", true)
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Scope").Return("team-a,team-b,team-c", true)
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Scope").Return("", false)
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+}
+
+func TestParseScopes(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, ParseScopes(" a,,b, a "))
+	assert.Empty(t, ParseScopes(""))
+}
+
 func TestFilterByDisabledLabel(t *testing.T) {
 	filter := FilterByDisabledLabel(NoFilter)
 	assert.NotNil(t, filter)
@@ -171,7 +243,7 @@ func TestFilterByImage(t *testing.T) {
 func TestBuildFilter(t *testing.T) {
 	names := []string{"test", "valid"}
 
-	filter, desc := BuildFilter(names, false, "")
+	filter, desc := BuildFilter(names, false, false, "", nil)
 	assert.Contains(t, desc, "test")
 	assert.Contains(t, desc, "or")
 	assert.Contains(t, desc, "valid")
@@ -210,7 +282,7 @@ func TestBuildFilterEnableLabel(t *testing.T) {
 	var names []string
 	names = append(names, "test")
 
-	filter, desc := BuildFilter(names, true, "")
+	filter, desc := BuildFilter(names, true, false, "", nil)
 	assert.Contains(t, desc, "using enable label")
 
 	container := new(mocks.FilterableContainer)
@@ -235,3 +307,172 @@ func TestBuildFilterEnableLabel(t *testing.T) {
 	assert.False(t, filter(container))
 	container.AssertExpectations(t)
 }
+
+func TestFilterByNamesGlob(t *testing.T) {
+	filter := FilterByNames([]string{"web-*", "db-?"}, NoFilter)
+	assert.NotNil(t, filter)
+
+	container := new(mocks.FilterableContainer)
+	container.On("Name").Return("/web-frontend")
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Name").Return("/db-1")
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Name").Return("/db-12")
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Name").Return("/cache")
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+
+	// the glob patterns are not used as regular expressions as well
+	container = new(mocks.FilterableContainer)
+	container.On("Name").Return("/db")
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+}
+
+func TestFilterByNamesAnchored(t *testing.T) {
+	filter := FilterByNames([]string{"web", `api-\d+`}, NoFilter)
+
+	for name, matches := range map[string]bool{
+		"/web":     true,
+		"/webx":    false,
+		"/xweb":    false,
+		"/api-12":  true,
+		"/api-":    false,
+		"/api-1-b": false,
+	} {
+		container := new(mocks.FilterableContainer)
+		container.On("Name").Return(name)
+		assert.Equal(t, matches, filter(container), name)
+		container.AssertExpectations(t)
+	}
+
+	filter = FilterByNames([]string{"api-(1|2)"}, NoFilter)
+	container := new(mocks.FilterableContainer)
+	container.On("Name").Return("/api-2")
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+}
+
+func TestFilterByNamesNegation(t *testing.T) {
+	filter := FilterByNames([]string{"web-*", "!web-canary"}, NoFilter)
+
+	container := new(mocks.FilterableContainer)
+	container.On("Name").Return("/web-frontend")
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Name").Return("/web-canary")
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+
+	filter = FilterByNames([]string{"!web-*"}, NoFilter)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Name").Return("/cache")
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Name").Return("/web-frontend")
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+}
+
+func TestBuildFilterNegation(t *testing.T) {
+	_, desc := BuildFilter([]string{"web-*", "!web-canary"}, false, false, "", nil)
+	assert.Contains(t, desc, `which name matches "web-*"`)
+	assert.Contains(t, desc, `which name does not match "web-canary"`)
+}
+
+func TestFilterByScopeOrUnscoped(t *testing.T) {
+	filter := FilterByScopeOrUnscoped("testscope", NoFilter)
+
+	container := new(mocks.FilterableContainer)
+	container.On("Scope").Return("testscope", true)
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Scope").Return("", false)
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Scope").Return("otherscope", true)
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+}
+
+func TestBuildFilterDisableLabel(t *testing.T) {
+	filter, desc := BuildFilter(nil, false, true, "", nil)
+	assert.Equal(t, "Checking all containers (except explicitly disabled with label)", desc)
+
+	container := new(mocks.FilterableContainer)
+	container.On("Enabled").Return(false, true)
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+
+	filter, desc = BuildFilter(nil, false, true, "testscope", nil)
+	assert.Contains(t, desc, `in scope "testscope" or without a scope`)
+	assert.Contains(t, desc, "except the ones disabled using the enable label")
+
+	container = new(mocks.FilterableContainer)
+	container.On("Scope").Return("", false)
+	container.On("Enabled").Return(false, false)
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("Scope").Return("otherscope", true)
+	container.On("Enabled").Return(false, false)
+	assert.False(t, filter(container))
+
+	container = new(mocks.FilterableContainer)
+	container.On("Scope").Return("testscope", true)
+	container.On("Enabled").Return(false, true)
+	assert.False(t, filter(container))
+}
+
+func TestFilterByProjects(t *testing.T) {
+	filter := FilterByProjects(nil, NoFilter)
+	assert.NotNil(t, filter)
+
+	filter = FilterByProjects([]string{"shop", "blog"}, NoFilter)
+
+	container := new(mocks.FilterableContainer)
+	container.On("ComposeProject").Return(types.ComposeProject{Name: "blog"}, true)
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("ComposeProject").Return(types.ComposeProject{Name: "wiki"}, true)
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+
+	container = new(mocks.FilterableContainer)
+	container.On("ComposeProject").Return(types.ComposeProject{}, false)
+	assert.False(t, filter(container))
+	container.AssertExpectations(t)
+}
+
+func TestBuildFilterProjects(t *testing.T) {
+	filter, desc := BuildFilter(nil, false, false, "", []string{"shop", "blog"})
+	assert.Equal(t, `Only checking containers of the compose projects "shop" or "blog"`, desc)
+
+	container := new(mocks.FilterableContainer)
+	container.On("ComposeProject").Return(types.ComposeProject{Name: "shop"}, true)
+	container.On("Enabled").Return(false, false)
+	assert.True(t, filter(container))
+	container.AssertExpectations(t)
+}
@@ -0,0 +1,42 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Preset is a named combination of filters, defined in the config file and selected using --filter-preset
+type Preset struct {
+	// Names are the names of the containers to check, used like the container names passed as arguments
+	Names []string
+	// LabelEnable only checks the containers enabled using the enable label, like --label-enable
+	LabelEnable bool `mapstructure:"label_enable"`
+	// LabelDisable checks all containers except the ones disabled using the enable label, like --label-disable
+	LabelDisable bool `mapstructure:"label_disable"`
+	// Scope is the scope, or comma-separated list of scopes, of the containers to check, like --scope
+	Scope string
+	// Projects are the docker compose projects of the containers to check
+	Projects []string
+}
+
+// Merge combines the filters of both presets. The names and projects of both are checked, while the scope may only be
+// set by one of them, unless they set the same one.
+func (p Preset) Merge(other Preset) (Preset, error) {
+	merged := Preset{
+		Names:        append(append([]string{}, p.Names...), other.Names...),
+		LabelEnable:  p.LabelEnable || other.LabelEnable,
+		LabelDisable: p.LabelDisable || other.LabelDisable,
+		Scope:        p.Scope,
+		Projects:     append(append([]string{}, p.Projects...), other.Projects...),
+	}
+	if merged.LabelEnable && merged.LabelDisable {
+		return merged, errors.New("the enable and disable label modes cannot be combined")
+	}
+	if other.Scope != "" {
+		if p.Scope != "" && p.Scope != other.Scope {
+			return merged, fmt.Errorf("conflicting scopes %q and %q", p.Scope, other.Scope)
+		}
+		merged.Scope = other.Scope
+	}
+	return merged, nil
+}
@@ -0,0 +1,31 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresetMerge(t *testing.T) {
+	flags := Preset{Names: []string{"web"}, Scope: "prod"}
+	merged, err := flags.Merge(Preset{Names: []string{"!web-canary"}, LabelDisable: true, Scope: "prod", Projects: []string{"shop"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web", "!web-canary"}, merged.Names)
+	assert.True(t, merged.LabelDisable)
+	assert.Equal(t, "prod", merged.Scope)
+	assert.Equal(t, []string{"shop"}, merged.Projects)
+	assert.Equal(t, []string{"web"}, flags.Names)
+
+	merged, err = Preset{}.Merge(Preset{Scope: "staging"})
+	require.NoError(t, err)
+	assert.Equal(t, "staging", merged.Scope)
+}
+
+func TestPresetMergeConflicts(t *testing.T) {
+	_, err := Preset{Scope: "prod"}.Merge(Preset{Scope: "staging"})
+	assert.Error(t, err)
+
+	_, err = Preset{LabelEnable: true}.Merge(Preset{LabelDisable: true})
+	assert.Error(t, err)
+}
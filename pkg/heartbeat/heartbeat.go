@@ -0,0 +1,90 @@
+package heartbeat
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/meta"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Pinger pings a heartbeat check following the conventions of healthchecks.io: the start of a session is signalled
+// using /start, a successful session using the URL itself and a failed session using /fail, so that the check alerts
+// both when watchtower stops running sessions and when the sessions fail
+type Pinger struct {
+	url    string
+	client *http.Client
+}
+
+// New creates a pinger for the check at the URL
+func New(url string) *Pinger {
+	return &Pinger{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Start signals that a session has started
+func (p *Pinger) Start() {
+	p.ping(p.url+"/start", "")
+}
+
+// Finish signals the outcome of the session, including a summary of the report in the body. The session is
+// considered failed when it would exit with a non-zero code when running once.
+func (p *Pinger) Finish(report types.Report, err error) {
+	url := p.url
+	if session.ExitCode(report, err, false) != session.ExitOK {
+		url += "/fail"
+	}
+	p.ping(url, Summary(report, err))
+}
+
+func (p *Pinger) ping(url string, body string) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		log.Warnf("Could not ping the heartbeat check: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("User-Agent", meta.UserAgent)
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		log.Warnf("Could not ping the heartbeat check: %v", err)
+		return
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= 300 {
+		log.Warnf("Could not ping the heartbeat check: %s", res.Status)
+		return
+	}
+	log.Debugf("Pinged the heartbeat check at %s", url[len(p.url):])
+}
+
+// Summary returns the counts of the report, followed by the containers that failed or were skipped
+func Summary(report types.Report, err error) string {
+	if report == nil {
+		if err != nil {
+			return fmt.Sprintf("Session failed: %v\n", err)
+		}
+		return "Session failed\n"
+	}
+
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "Scanned: %d, Updated: %d, Failed: %d, Skipped: %d, Stale: %d\n", len(report.Scanned()),
+		len(report.Updated()), len(report.Failed()), len(report.Skipped()), len(report.Stale()))
+	if err != nil {
+		fmt.Fprintf(&sb, "Session failed: %v\n", err)
+	}
+	for _, c := range report.Failed() {
+		fmt.Fprintf(&sb, "- %s (%s): Failed: %s\n", c.Name(), c.ImageName(), c.Error())
+	}
+	for _, c := range report.Skipped() {
+		fmt.Fprintf(&sb, "- %s (%s): Skipped (%s): %s\n", c.Name(), c.ImageName(), c.SkipReason(), c.Error())
+	}
+	return sb.String()
+}
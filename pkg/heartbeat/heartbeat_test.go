@@ -0,0 +1,61 @@
+package heartbeat_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	"github.com/containrrr/watchtower/pkg/heartbeat"
+	"github.com/containrrr/watchtower/pkg/session"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHeartbeat(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Heartbeat Suite")
+}
+
+var _ = Describe("the heartbeat pinger", func() {
+	var server *httptest.Server
+	var paths, bodies []string
+
+	BeforeEach(func() {
+		paths, bodies = nil, nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			paths = append(paths, r.URL.Path)
+			bodies = append(bodies, string(body))
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should ping the start and the success of a session", func() {
+		pinger := heartbeat.New(server.URL + "/ping/abc/")
+		pinger.Start()
+		pinger.Finish(mocks.CreateMockProgressReport(session.UpdatedState, session.FreshState), nil)
+
+		Expect(paths).To(Equal([]string{"/ping/abc/start", "/ping/abc"}))
+		Expect(bodies[1]).To(HavePrefix("Scanned: 2, Updated: 1, Failed: 0"))
+	})
+
+	It("should ping the failure of a session with failed containers", func() {
+		heartbeat.New(server.URL+"/ping/abc").Finish(mocks.CreateMockProgressReport(session.FailedState), nil)
+
+		Expect(paths).To(Equal([]string{"/ping/abc/fail"}))
+		Expect(bodies[0]).To(ContainSubstring("Failed: accidentally the whole container"))
+	})
+
+	It("should ping the failure of a session that could not run", func() {
+		heartbeat.New(server.URL+"/ping/abc").Finish(nil, errors.New("no docker"))
+
+		Expect(paths).To(Equal([]string{"/ping/abc/fail"}))
+		Expect(bodies[0]).To(Equal("Session failed: no docker\n"))
+	})
+})
@@ -0,0 +1,139 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Shell is the shell running the commands
+const Shell = "sh"
+
+// outputLines limits how much of the output of a failed command is included in its error
+const outputLines = 5
+
+// Session runs the commands configured to run inside the watchtower container before and after each session
+type Session struct {
+	pre     string
+	post    string
+	report  string
+	timeout time.Duration
+}
+
+// New creates the session hooks running the commands, which may be empty to not run any. The report command receives
+// the report of the session as JSON on its standard input. The time each command may take is limited by the timeout,
+// if set.
+func New(pre string, post string, report string, timeout time.Duration) *Session {
+	return &Session{pre: pre, post: post, report: report, timeout: timeout}
+}
+
+// Pre runs the pre-session command, returning an error if it failed, in which case the session should be skipped
+func (s *Session) Pre(reportOnly bool) error {
+	if s.pre == "" {
+		return nil
+	}
+	log.Debugf("Running the pre-session command %s", s.pre)
+	return run(s.pre, s.timeout, []string{"WATCHTOWER_REPORT_ONLY=" + strconv.FormatBool(reportOnly)}, nil)
+}
+
+// Post runs the post-session command with the summary of the report in its environment, logging its failure
+func (s *Session) Post(reportOnly bool, report types.Report, err error) {
+	if s.post == "" {
+		return
+	}
+	log.Debugf("Running the post-session command %s", s.post)
+	env := append([]string{"WATCHTOWER_REPORT_ONLY=" + strconv.FormatBool(reportOnly)}, Env(report, err)...)
+	if err := run(s.post, s.timeout, env, nil); err != nil {
+		log.Error(err)
+	}
+}
+
+// Report runs the report command, passing the report of the session as JSON on its standard input, along with the
+// summary of the report in its environment, and logs its failure. Nothing is run if the session did not produce a
+// report.
+func (s *Session) Report(reportOnly bool, report types.Report, err error) {
+	if s.report == "" || report == nil {
+		return
+	}
+	content, jsonErr := json.Marshal(report)
+	if jsonErr != nil {
+		log.WithError(jsonErr).Error("Could not pass the report to the report command")
+		return
+	}
+	log.Debugf("Running the report command %s", s.report)
+	env := append([]string{"WATCHTOWER_REPORT_ONLY=" + strconv.FormatBool(reportOnly)}, Env(report, err)...)
+	if err := run(s.report, s.timeout, env, bytes.NewReader(content)); err != nil {
+		log.Error(err)
+	}
+}
+
+// Env returns the variables summarizing the report of the session: the number of containers in each state, the exit
+// code the session would have when running once, and its error, if any
+func Env(report types.Report, err error) []string {
+	env := []string{"WATCHTOWER_EXIT_CODE=" + strconv.Itoa(session.ExitCode(report, err, false))}
+	if err != nil {
+		env = append(env, "WATCHTOWER_ERROR="+err.Error())
+	}
+	if report == nil {
+		return env
+	}
+	return append(env,
+		"WATCHTOWER_SCANNED="+strconv.Itoa(len(report.Scanned())),
+		"WATCHTOWER_UPDATED="+strconv.Itoa(len(report.Updated())),
+		"WATCHTOWER_FAILED="+strconv.Itoa(len(report.Failed())),
+		"WATCHTOWER_SKIPPED="+strconv.Itoa(len(report.Skipped())),
+		"WATCHTOWER_STALE="+strconv.Itoa(len(report.Stale())),
+		"WATCHTOWER_FRESH="+strconv.Itoa(len(report.Fresh())),
+	)
+}
+
+// run runs the command using the shell, adding env to the environment of watchtower, and passing stdin to it, if set
+func run(command string, timeout time.Duration, env []string, stdin io.Reader) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, Shell, "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("the command %s did not finish within %s", command, timeout)
+		}
+		return fmt.Errorf("the command %s failed: %w%s", command, err, tail(output.String()))
+	}
+	if out := strings.TrimSpace(output.String()); out != "" {
+		log.WithField("command", command).Debug(out)
+	}
+	return nil
+}
+
+// tail returns the last lines of the output, to explain why the command failed
+func tail(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > outputLines {
+		lines = lines[len(lines)-outputLines:]
+	}
+	if joined := strings.Join(lines, "\n"); joined != "" {
+		return ": " + joined
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package hooks_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	"github.com/containrrr/watchtower/pkg/hooks"
+	"github.com/containrrr/watchtower/pkg/session"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHooks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hooks Suite")
+}
+
+var _ = Describe("the session hooks", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-hooks")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		Expect(err).NotTo(HaveOccurred())
+		return string(content)
+	}
+
+	It("should run the pre-session command", func() {
+		h := hooks.New("echo $WATCHTOWER_REPORT_ONLY > "+filepath.Join(dir, "pre"), "", "", time.Minute)
+		Expect(h.Pre(true)).To(Succeed())
+		Expect(read("pre")).To(Equal("true\n"))
+	})
+
+	It("should return the error of a failed pre-session command, with its output", func() {
+		err := hooks.New("echo monitoring unavailable; exit 3", "", "", time.Minute).Pre(false)
+		Expect(err).To(MatchError(ContainSubstring("monitoring unavailable")))
+	})
+
+	It("should stop commands running longer than the timeout", func() {
+		err := hooks.New("exec sleep 5", "", "", 50*time.Millisecond).Pre(false)
+		Expect(err).To(MatchError(ContainSubstring("did not finish within")))
+	})
+
+	It("should pass the summary of the session to the post-session command", func() {
+		h := hooks.New("", "echo $WATCHTOWER_SCANNED $WATCHTOWER_UPDATED $WATCHTOWER_EXIT_CODE > "+filepath.Join(dir, "post"), "", time.Minute)
+		progress := session.Progress{}
+		c, _ := mocks.CreateContainerForProgress(0, 0, "c%d")
+		progress.AddScanned(c, "sha256:new")
+		progress.MarkForUpdate(c.ID())
+		h.Post(false, progress.Report(), nil)
+		Expect(read("post")).To(Equal("1 1 0\n"))
+	})
+
+	It("should pass the report as JSON to the report command", func() {
+		h := hooks.New("", "", "cat > "+filepath.Join(dir, "report.json"), time.Minute)
+		progress := session.Progress{}
+		c, _ := mocks.CreateContainerForProgress(0, 0, "c%d")
+		progress.AddScanned(c, "sha256:new")
+		progress.MarkForUpdate(c.ID())
+		h.Report(false, progress.Report(), nil)
+
+		var report struct {
+			Updated []struct {
+				Name  string `json:"name"`
+				State string `json:"state"`
+			} `json:"updated"`
+		}
+		Expect(json.Unmarshal([]byte(read("report.json")), &report)).To(Succeed())
+		Expect(report.Updated).To(HaveLen(1))
+		Expect(report.Updated[0].Name).To(Equal("c1"))
+		Expect(report.Updated[0].State).To(Equal("updated"))
+	})
+
+	It("should not run the report command without a report", func() {
+		h := hooks.New("", "", "touch "+filepath.Join(dir, "report.json"), time.Minute)
+		h.Report(false, nil, errors.New("docker is unavailable"))
+		Expect(filepath.Join(dir, "report.json")).NotTo(BeAnExistingFile())
+	})
+
+	It("should include the error of a failed session", func() {
+		env := hooks.Env(nil, errors.New("docker is unavailable"))
+		Expect(env).To(ContainElement("WATCHTOWER_ERROR=docker is unavailable"))
+		Expect(env).To(ContainElement("WATCHTOWER_EXIT_CODE=1"))
+	})
+
+	It("should not run anything without commands", func() {
+		h := hooks.New("", "", "", 0)
+		Expect(h.Pre(false)).To(Succeed())
+		h.Post(false, nil, nil)
+	})
+})
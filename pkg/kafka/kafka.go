@@ -0,0 +1,214 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/events"
+	"github.com/containrrr/watchtower/pkg/types"
+	"github.com/segmentio/kafka-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// localLog logs the failures of producing without sending them as notifications
+var localLog = log.WithField("notify", "no")
+
+// queueSize is how many events may wait to be produced, before the new ones are dropped
+const queueSize = 1024
+
+// maxAttempts is how many times a record is produced before giving up, giving the cluster time to elect the leader of
+// its partition
+const maxAttempts = 3
+
+// producedEvents are the types of the events produced to the topic, being one per updated container and one per
+// session
+var producedEvents = map[string]bool{
+	events.ContainerRecreated: true,
+	events.ContainerFailed:    true,
+	events.SessionFinished:    true,
+}
+
+// Config is the configuration of the producer
+type Config struct {
+	// Brokers are the addresses of the brokers the metadata of the cluster is fetched from, like kafka:9092
+	Brokers []string
+	Topic   string
+	// TLS connects to the brokers using TLS, verifying their certificates unless TLSSkipVerify is set
+	TLS           bool
+	TLSSkipVerify bool
+	// Mechanism is the SASL mechanism used to authenticate, if the username is set, defaulting to PLAIN
+	Mechanism string
+	Username  string
+	Password  string
+	// Timeout is how long to wait for the connections and the requests, defaulting to 10 seconds
+	Timeout time.Duration
+}
+
+// message is the value of the records, being the event along with the host of watchtower
+type message struct {
+	types.Event
+	Host string `json:"host,omitempty"`
+}
+
+// Producer produces the events of the updates of the containers and of the sessions to a Kafka topic, as JSON. The
+// events of a container are keyed by its name, and the ones of a session by its ID, so that the events sharing a key
+// are kept in order by the partition they are produced to.
+type Producer struct {
+	writer *kafka.Writer
+	host   string
+	done   chan struct{}
+
+	mu     sync.Mutex
+	queue  chan types.Event
+	closed bool
+}
+
+// New returns a producer to the topic of the brokers
+func New(config Config) (*Producer, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("no Kafka brokers")
+	}
+	if config.Topic == "" || strings.ContainsAny(config.Topic, "/\\ ") {
+		return nil, fmt.Errorf("invalid Kafka topic %q", config.Topic)
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	transport := &kafka.Transport{ClientID: "watchtower", DialTimeout: config.Timeout}
+	if config.TLS {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: config.TLSSkipVerify}
+	}
+	if config.Username != "" {
+		config.Mechanism = strings.ToUpper(config.Mechanism)
+		if config.Mechanism == "" {
+			config.Mechanism = MechanismPlain
+		}
+		mechanism, err := newMechanism(config.Mechanism, config.Username, config.Password)
+		if err != nil {
+			return nil, err
+		}
+		transport.SASL = mechanism
+	}
+	return newProducer(config, transport), nil
+}
+
+// newProducer returns a producer sending its requests to the brokers using the transport
+func newProducer(config Config, transport kafka.RoundTripper) *Producer {
+	host, _ := os.Hostname()
+	p := &Producer{
+		writer: &kafka.Writer{
+			Addr:  kafka.TCP(config.Brokers...),
+			Topic: config.Topic,
+			// the records sharing a key are produced to the same partition
+			Balancer:     &kafka.Hash{},
+			MaxAttempts:  maxAttempts,
+			BatchSize:    1,
+			ReadTimeout:  config.Timeout,
+			WriteTimeout: config.Timeout,
+			RequiredAcks: kafka.RequireAll,
+			// the topic is created if the brokers allow it
+			AllowAutoTopicCreation: true,
+			Transport:              transport,
+			ErrorLogger:            kafka.LoggerFunc(localLog.Debugf),
+		},
+		host:  host,
+		queue: make(chan types.Event, queueSize),
+		done:  make(chan struct{}),
+	}
+	go p.produceQueued()
+	return p
+}
+
+// Record implements types.EventRecorder, queueing the events of the updates of the containers and of the sessions to
+// be produced, dropping them if too many are waiting already
+func (p *Producer) Record(event types.Event) {
+	if !producedEvents[event.Type] {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- event:
+	default:
+		localLog.Warnf("Dropped the %s event, as the Kafka brokers cannot keep up", event.Type)
+	}
+}
+
+// produceQueued produces the queued events, until the queue is closed
+func (p *Producer) produceQueued() {
+	defer close(p.done)
+	defer p.writer.Close()
+	for event := range p.queue {
+		r, err := p.record(event)
+		if err == nil {
+			err = p.produce(r)
+		}
+		if err != nil {
+			localLog.Warnf("Could not produce the %s event to Kafka: %v", event.Type, err)
+		}
+	}
+}
+
+// record returns the record of the event, keyed by its container or its session
+func (p *Producer) record(event types.Event) (kafka.Message, error) {
+	value, err := json.Marshal(message{Event: event, Host: p.host})
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	key := strings.TrimPrefix(event.Container, "/")
+	if key == "" {
+		key = event.Session
+	}
+	r := kafka.Message{
+		Value: value,
+		Time:  event.Time,
+		Headers: []kafka.Header{
+			{Key: "type", Value: []byte(event.Type)},
+			{Key: "content-type", Value: []byte("application/json")},
+		},
+	}
+	if key != "" {
+		r.Key = []byte(key)
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	return r, nil
+}
+
+// produce produces the record to the partition of its key, which the writer retries for as long as the error is
+// temporary, like when the leader of the partition changed
+func (p *Producer) produce(r kafka.Message) error {
+	err := p.writer.WriteMessages(context.Background(), r)
+	var errs kafka.WriteErrors
+	if errors.As(err, &errs) && len(errs) == 1 {
+		return errs[0]
+	}
+	return err
+}
+
+// Close produces the queued events, waiting for them for at most the timeout, and disconnects from the brokers
+func (p *Producer) Close(timeout time.Duration) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(timeout):
+		return errors.New("could not produce all of the events to Kafka in time")
+	}
+}
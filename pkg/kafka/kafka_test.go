@@ -0,0 +1,216 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/events"
+	"github.com/containrrr/watchtower/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+)
+
+func TestKafka(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Kafka Suite")
+}
+
+// produced is a record produced to the fake cluster
+type produced struct {
+	partition int32
+	key       string
+	value     string
+	headers   map[string]string
+}
+
+// fakeTransport is a fake Kafka cluster, having a single broker being the leader of all of the partitions of its topic
+type fakeTransport struct {
+	topic      string
+	partitions int
+
+	mu sync.Mutex
+	// produceErrors are returned by the next produce requests
+	produceErrors []kafka.Error
+	requests      []protocol.ApiKey
+	acks          []int16
+	records       []produced
+}
+
+func (t *fakeTransport) configure(produceErrors ...kafka.Error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.produceErrors = produceErrors
+}
+
+func (t *fakeTransport) produced() []produced {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]produced(nil), t.records...)
+}
+
+func (t *fakeTransport) received() []protocol.ApiKey {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]protocol.ApiKey(nil), t.requests...)
+}
+
+// RoundTrip implements kafka.RoundTripper, answering the metadata and produce requests
+func (t *fakeTransport) RoundTrip(_ context.Context, _ net.Addr, req protocol.Message) (protocol.Message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests = append(t.requests, req.ApiKey())
+
+	switch req := req.(type) {
+	case *metadata.Request:
+		partitions := make([]metadata.ResponsePartition, t.partitions)
+		for i := range partitions {
+			partitions[i] = metadata.ResponsePartition{PartitionIndex: int32(i), LeaderID: 1}
+		}
+		return &metadata.Response{
+			Brokers: []metadata.ResponseBroker{{NodeID: 1, Host: "kafka", Port: 9092}},
+			Topics:  []metadata.ResponseTopic{{Name: t.topic, Partitions: partitions}},
+		}, nil
+	case *produce.Request:
+		Expect(req.Topics).To(HaveLen(1))
+		Expect(req.Topics[0].Topic).To(Equal(t.topic))
+		Expect(req.Topics[0].Partitions).To(HaveLen(1))
+		partition := req.Topics[0].Partitions[0]
+		t.acks = append(t.acks, req.Acks)
+
+		code := kafka.Error(0)
+		if len(t.produceErrors) > 0 {
+			code, t.produceErrors = t.produceErrors[0], t.produceErrors[1:]
+		} else {
+			t.records = append(t.records, readRecords(partition)...)
+		}
+		return &produce.Response{Topics: []produce.ResponseTopic{{
+			Topic:      req.Topics[0].Topic,
+			Partitions: []produce.ResponsePartition{{Partition: partition.Partition, ErrorCode: int16(code)}},
+		}}}, nil
+	}
+	return nil, fmt.Errorf("unexpected %T request", req)
+}
+
+// readRecords reads the records of the partition of a produce request
+func readRecords(partition produce.RequestPartition) []produced {
+	var records []produced
+	for {
+		r, err := partition.RecordSet.Records.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		Expect(err).NotTo(HaveOccurred())
+		record := produced{partition: partition.Partition, headers: map[string]string{}}
+		if r.Key != nil {
+			key, _ := protocol.ReadAll(r.Key)
+			record.key = string(key)
+		}
+		value, _ := protocol.ReadAll(r.Value)
+		record.value = string(value)
+		for _, header := range r.Headers {
+			record.headers[header.Key] = string(header.Value)
+		}
+		records = append(records, record)
+	}
+}
+
+var _ = Describe("the Kafka producer", func() {
+	var transport *fakeTransport
+	config := Config{Brokers: []string{"kafka:9092"}, Topic: "watchtower", Timeout: 5 * time.Second}
+
+	BeforeEach(func() {
+		transport = &fakeTransport{topic: "watchtower", partitions: 3}
+	})
+
+	It("should produce one event per updated container and per session, keyed by the container or the session", func() {
+		p := newProducer(config, transport)
+		p.Record(types.Event{Type: events.SessionStarted, Session: "s1"})
+		p.Record(types.Event{Type: events.ContainerChecked, Session: "s1", Container: "/web"})
+		p.Record(types.Event{Type: events.ContainerRecreated, Session: "s1", Container: "/web", Image: "nginx:latest",
+			Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)})
+		p.Record(types.Event{Type: events.ContainerFailed, Session: "s1", Container: "/db", Error: "no space left"})
+		p.Record(types.Event{Type: events.SessionFinished, Session: "s1"})
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+
+		records := transport.produced()
+		Expect(records).To(HaveLen(3))
+		Expect(records[0].key).To(Equal("web"))
+		Expect(records[0].partition).To(BeEquivalentTo((&kafka.Hash{}).Balance(kafka.Message{Key: []byte("web")}, 0, 1, 2)))
+		Expect(records[0].headers).To(Equal(map[string]string{
+			"type":         events.ContainerRecreated,
+			"content-type": "application/json",
+		}))
+		var event map[string]interface{}
+		Expect(json.Unmarshal([]byte(records[0].value), &event)).To(Succeed())
+		Expect(event).To(HaveKeyWithValue("type", events.ContainerRecreated))
+		Expect(event).To(HaveKeyWithValue("session", "s1"))
+		Expect(event).To(HaveKeyWithValue("container", "/web"))
+		Expect(event).To(HaveKeyWithValue("image", "nginx:latest"))
+		Expect(event).To(HaveKeyWithValue("time", "2024-05-01T12:00:00Z"))
+		Expect(event).To(HaveKey("host"))
+
+		Expect(records[1].key).To(Equal("db"))
+		Expect(records[1].headers).To(HaveKeyWithValue("type", events.ContainerFailed))
+		Expect(records[2].key).To(Equal("s1"))
+		Expect(records[2].headers).To(HaveKeyWithValue("type", events.SessionFinished))
+		// the records are acknowledged by all of the in-sync replicas
+		Expect(transport.acks).To(Equal([]int16{-1, -1, -1}))
+	})
+
+	It("should produce the record again once the leader of the partition changed", func() {
+		transport.configure(kafka.NotLeaderForPartition)
+		p := newProducer(config, transport)
+		p.Record(types.Event{Type: events.SessionFinished, Session: "s1"})
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+
+		Expect(transport.produced()).To(HaveLen(1))
+		Expect(transport.received()).To(ContainElements(protocol.Metadata, protocol.Produce))
+		Expect(transport.acks).To(HaveLen(2))
+	})
+
+	It("should not retry the errors that would occur again", func() {
+		transport.configure(kafka.TopicAuthorizationFailed)
+		p := newProducer(config, transport)
+		err := p.produce(kafka.Message{Value: []byte("{}"), Time: time.Now()})
+		Expect(errors.Is(err, kafka.TopicAuthorizationFailed)).To(BeTrue(), "%v", err)
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+		Expect(transport.acks).To(HaveLen(1))
+	})
+
+	It("should authenticate using the SASL mechanism", func() {
+		p, err := New(Config{Brokers: []string{"kafka:9092"}, Topic: "watchtower", Username: "watchtower",
+			Password: "secret"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.writer.Transport.(*kafka.Transport).SASL.Name()).To(Equal(MechanismPlain))
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+
+		p, err = New(Config{Brokers: []string{"kafka:9092"}, Topic: "watchtower", Username: "watchtower",
+			Password: "secret", Mechanism: "scram-sha-512", TLS: true})
+		Expect(err).NotTo(HaveOccurred())
+		transport := p.writer.Transport.(*kafka.Transport)
+		Expect(transport.SASL.Name()).To(Equal(MechanismScramSHA512))
+		Expect(transport.TLS).NotTo(BeNil())
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+	})
+
+	It("should refuse invalid configurations", func() {
+		_, err := New(Config{Topic: "watchtower"})
+		Expect(err).To(HaveOccurred())
+		_, err = New(Config{Brokers: []string{"kafka:9092"}, Topic: "watch tower"})
+		Expect(err).To(HaveOccurred())
+		_, err = New(Config{Brokers: []string{"kafka:9092"}, Topic: "watchtower", Username: "watchtower",
+			Mechanism: "GSSAPI"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,30 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// The SASL mechanisms supported to authenticate to the brokers
+const (
+	MechanismPlain       = "PLAIN"
+	MechanismScramSHA256 = "SCRAM-SHA-256"
+	MechanismScramSHA512 = "SCRAM-SHA-512"
+)
+
+// newMechanism returns the SASL mechanism authenticating using the credentials
+func newMechanism(name string, username string, password string) (sasl.Mechanism, error) {
+	switch name {
+	case MechanismPlain:
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case MechanismScramSHA256:
+		return scram.Mechanism(scram.SHA256, username, password)
+	case MechanismScramSHA512:
+		return scram.Mechanism(scram.SHA512, username, password)
+	}
+	return nil, fmt.Errorf("unsupported Kafka SASL mechanism %q, expected %s, %s or %s", name, MechanismPlain,
+		MechanismScramSHA256, MechanismScramSHA512)
+}
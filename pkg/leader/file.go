@@ -0,0 +1,140 @@
+package leader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// staleLockAge is after how long the lock file guarding the lease is considered to be left behind by a crashed
+// instance, and is removed
+const staleLockAge = 30 * time.Second
+
+// lease is the content of the lease file
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// FileLease elects the leader using a lease kept in a file shared between the instances, like on a shared volume.
+// The instance holding an unexpired lease is the leader, renewing it for every session. Once the lease expires, like
+// when its holder has stopped, the next instance to check takes it over.
+type FileLease struct {
+	path     string
+	holder   string
+	duration time.Duration
+
+	mutex   sync.Mutex
+	current string
+	now     func() time.Time
+}
+
+// NewFileLease creates an elector using the lease file at the path, identifying this instance as holder. A lease
+// taken or renewed is valid for the duration.
+func NewFileLease(path string, holder string, duration time.Duration) *FileLease {
+	return &FileLease{path: path, holder: holder, duration: duration, now: time.Now}
+}
+
+// IsLeader takes the lease if it is not held by another instance, or renews it if it is held by this one
+func (l *FileLease) IsLeader() (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	unlock, err := l.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	now := l.now()
+	current, err := l.read()
+	if err != nil {
+		return false, err
+	}
+	if current.Holder != "" && current.Holder != l.holder && now.Before(current.Expires) {
+		l.current = current.Holder
+		return false, nil
+	}
+
+	if err := l.write(lease{Holder: l.holder, Expires: now.Add(l.duration)}); err != nil {
+		return false, err
+	}
+	l.current = l.holder
+	return true, nil
+}
+
+// Holder returns the holder of the lease when it was last checked
+func (l *FileLease) Holder() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.current
+}
+
+// lock creates the lock file next to the lease file, so that only one instance at a time reads and updates the lease
+func (l *FileLease) lock() (func(), error) {
+	lockPath := l.path + ".lock"
+	for attempt := 0; ; attempt++ {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_ = file.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("could not lock the leader lease: %w", err)
+		}
+		if info, err := os.Stat(lockPath); err == nil && l.now().Sub(info.ModTime()) > staleLockAge {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if attempt >= 50 {
+			return nil, fmt.Errorf("could not lock the leader lease, %s is held by another instance", lockPath)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (l *FileLease) read() (lease, error) {
+	var current lease
+	data, err := ioutil.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return current, nil
+	}
+	if err != nil {
+		return current, fmt.Errorf("could not read the leader lease: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &current); err != nil {
+			return current, fmt.Errorf("could not read the leader lease %s: %w", l.path, err)
+		}
+	}
+	return current, nil
+}
+
+// write replaces the lease file, using a temporary file so that it is never read partially written
+func (l *FileLease) write(current lease) error {
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not write the leader lease: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		_ = temp.Close()
+		return fmt.Errorf("could not write the leader lease: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("could not write the leader lease: %w", err)
+	}
+	if err := os.Rename(temp.Name(), l.path); err != nil {
+		return fmt.Errorf("could not write the leader lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package leader
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/containrrr/watchtower/pkg/filters"
+	"github.com/containrrr/watchtower/pkg/sorter"
+	"github.com/containrrr/watchtower/pkg/types"
+)
+
+// LabelElector elects the oldest of the running watchtower containers within the scope as the leader, identifying
+// them by their watchtower and scope labels. As every instance sees the same containers, they all agree on the leader without
+// sharing any state, and the next oldest instance takes over once the leader stops.
+type LabelElector struct {
+	client container.Client
+	scope  string
+	self   string
+
+	mutex   sync.Mutex
+	current string
+}
+
+// NewLabelElector creates an elector for the instances within the scope, identifying the container of this instance
+// by its ID, or the prefix of it that docker uses as the default hostname
+func NewLabelElector(client container.Client, scope string, self string) *LabelElector {
+	return &LabelElector{client: client, scope: scope, self: self}
+}
+
+// IsLeader returns whether the container of this instance is the oldest running watchtower container in the scope
+func (e *LabelElector) IsLeader() (bool, error) {
+	containers, err := e.client.ListContainers(e.filter())
+	if err != nil {
+		return false, fmt.Errorf("could not list the watchtower instances: %w", err)
+	}
+	if len(containers) == 0 {
+		return false, errors.New("could not find any watchtower instances")
+	}
+	sort.Stable(sorter.ByCreated(containers))
+
+	found := false
+	for _, c := range containers {
+		if e.isSelf(c) {
+			found = true
+		}
+	}
+	if !found {
+		return false, fmt.Errorf("could not find the container of this instance, %q, within the watchtower instances",
+			e.self)
+	}
+
+	leader := containers[0]
+	e.mutex.Lock()
+	e.current = strings.TrimPrefix(leader.Name(), "/")
+	e.mutex.Unlock()
+	return e.isSelf(leader), nil
+}
+
+// Holder returns the name of the leader when it was last checked
+func (e *LabelElector) Holder() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.current
+}
+
+// filter selects the watchtower containers in the scope. Without a scope, only the unscoped instances take part, as
+// the scoped ones do not update the unscoped containers.
+func (e *LabelElector) filter() types.Filter {
+	if e.scope != "" {
+		return filters.FilterByExactScope(e.scope, filters.WatchtowerContainersFilter)
+	}
+	return func(c types.FilterableContainer) bool {
+		if scope, ok := c.Scope(); ok && scope != "" {
+			return false
+		}
+		return filters.WatchtowerContainersFilter(c)
+	}
+}
+
+func (e *LabelElector) isSelf(c container.Container) bool {
+	return e.self != "" && strings.HasPrefix(string(c.ID()), e.self)
+}
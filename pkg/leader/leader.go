@@ -0,0 +1,10 @@
+package leader
+
+// Elector decides whether this instance is the leader of the watchtower instances sharing a docker host, which is
+// the only one running the update sessions
+type Elector interface {
+	// IsLeader returns whether this instance is the leader, taking or renewing the leadership if it can
+	IsLeader() (bool, error)
+	// Holder returns a description of the current leader, for logging
+	Holder() string
+}
@@ -0,0 +1,111 @@
+package leader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/docker/docker/api/types"
+	dockerContainer "github.com/docker/docker/api/types/container"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeader(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Leader Suite")
+}
+
+var _ = Describe("the file lease", func() {
+	var dir, path string
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-lease")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "leader.json")
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	newLease := func(holder string, at time.Time) *FileLease {
+		lease := NewFileLease(path, holder, time.Hour)
+		lease.now = func() time.Time { return at }
+		return lease
+	}
+
+	It("should let only one instance hold the lease", func() {
+		Expect(newLease("a", now).IsLeader()).To(BeTrue())
+		b := newLease("b", now.Add(time.Minute))
+		Expect(b.IsLeader()).To(BeFalse())
+		Expect(b.Holder()).To(Equal("a"))
+	})
+
+	It("should renew the lease of the leader", func() {
+		Expect(newLease("a", now).IsLeader()).To(BeTrue())
+		Expect(newLease("a", now.Add(50*time.Minute)).IsLeader()).To(BeTrue())
+		Expect(newLease("b", now.Add(70*time.Minute)).IsLeader()).To(BeFalse())
+	})
+
+	It("should let another instance take over an expired lease", func() {
+		Expect(newLease("a", now).IsLeader()).To(BeTrue())
+		Expect(newLease("b", now.Add(61*time.Minute)).IsLeader()).To(BeTrue())
+		Expect(newLease("a", now.Add(62*time.Minute)).IsLeader()).To(BeFalse())
+	})
+
+	It("should remove a lock left behind by a crashed instance", func() {
+		Expect(os.WriteFile(path+".lock", nil, 0o644)).To(Succeed())
+		Expect(newLease("a", time.Now().Add(time.Minute)).IsLeader()).To(BeTrue())
+		Expect(path + ".lock").NotTo(BeAnExistingFile())
+	})
+})
+
+var _ = Describe("the label elector", func() {
+	newInstance := func(id string, name string, created time.Time, labels ...string) container.Container {
+		config := &dockerContainer.Config{Labels: map[string]string{"com.centurylinklabs.watchtower": "true"}}
+		for i := 0; i+1 < len(labels); i += 2 {
+			config.Labels[labels[i]] = labels[i+1]
+		}
+		return *container.NewContainer(&types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				ID:      id,
+				Name:    name,
+				Created: created.Format(time.RFC3339Nano),
+			},
+			Config: config,
+		}, nil)
+	}
+	now := time.Now()
+	client := mocks.CreateMockClient(&mocks.TestData{
+		ApplyFilter: true,
+		Containers: []container.Container{
+			newInstance("bbbbbbbbbbbb1234", "/stack-b", now),
+			newInstance("aaaaaaaaaaaa1234", "/stack-a", now.Add(-time.Hour)),
+			newInstance("cccccccccccc1234", "/scoped", now.Add(-2*time.Hour),
+				"com.centurylinklabs.watchtower.scope", "team-c"),
+		},
+	}, false, false)
+
+	It("should elect the oldest instance", func() {
+		a := NewLabelElector(client, "", "aaaaaaaaaaaa")
+		Expect(a.IsLeader()).To(BeTrue())
+		b := NewLabelElector(client, "", "bbbbbbbbbbbb")
+		Expect(b.IsLeader()).To(BeFalse())
+		Expect(b.Holder()).To(Equal("stack-a"))
+	})
+
+	It("should only elect the instances within the scope", func() {
+		Expect(NewLabelElector(client, "team-c", "cccccccccccc").IsLeader()).To(BeTrue())
+	})
+
+	It("should fail when the container of the instance cannot be found", func() {
+		_, err := NewLabelElector(client, "", "custom-hostname").IsLeader()
+		Expect(err).To(HaveOccurred())
+	})
+})
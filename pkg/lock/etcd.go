@@ -0,0 +1,121 @@
+package lock
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.uber.org/zap"
+)
+
+const etcdTimeout = 5 * time.Second
+
+// etcd keeps the lock as a mutex of the concurrency package, which is a key under the key of the lock attached to a
+// lease expiring after the TTL. The lease is kept alive by its session, and revoked along with the key on release.
+type etcd struct {
+	config clientv3.Config
+	key    string
+
+	mutex    sync.Mutex
+	client   *clientv3.Client
+	sessions map[string]*concurrency.Session
+}
+
+func newEtcd(u *url.URL, key string) (*etcd, error) {
+	config := clientv3.Config{
+		Endpoints:   []string{"http://" + hostPort(u, "2379")},
+		DialTimeout: etcdTimeout,
+		Logger:      zap.NewNop(),
+	}
+	if u.Scheme == "etcds" {
+		config.Endpoints = []string{"https://" + hostPort(u, "2379")}
+		config.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if u.User != nil {
+		config.Username = u.User.Username()
+		config.Password, _ = u.User.Password()
+	}
+	return &etcd{config: config, key: key, sessions: map[string]*concurrency.Session{}}, nil
+}
+
+// connect returns the client, creating it when the lock is first acquired, as it authenticates with the server right
+// away, which should not keep watchtower from starting
+func (e *etcd) connect() (*clientv3.Client, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.client != nil {
+		return e.client, nil
+	}
+	client, err := clientv3.New(e.config)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to etcd: %w", err)
+	}
+	e.client = client
+	return client, nil
+}
+
+func (e *etcd) tryAcquire(token string) (bool, error) {
+	client, err := e.connect()
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+	// the lease is granted here, so that an unreachable server times out rather than blocking the session
+	lease, err := client.Grant(ctx, int64(ttl.Seconds()))
+	if err != nil {
+		return false, fmt.Errorf("could not reach etcd: %w", err)
+	}
+	session, err := concurrency.NewSession(client, concurrency.WithLease(lease.ID))
+	if err != nil {
+		_, _ = client.Revoke(ctx, lease.ID)
+		return false, err
+	}
+
+	if err := concurrency.NewMutex(session, e.key).TryLock(ctx); err != nil {
+		_ = session.Close()
+		if errors.Is(err, concurrency.ErrLocked) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	e.mutex.Lock()
+	e.sessions[token] = session
+	e.mutex.Unlock()
+	return true, nil
+}
+
+// renew only checks whether the lease is still alive, as the session keeps it alive on its own
+func (e *etcd) renew(token string) (bool, error) {
+	e.mutex.Lock()
+	session, found := e.sessions[token]
+	e.mutex.Unlock()
+	if !found {
+		return false, nil
+	}
+
+	select {
+	case <-session.Done():
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+func (e *etcd) release(token string) error {
+	e.mutex.Lock()
+	session, found := e.sessions[token]
+	delete(e.sessions, token)
+	e.mutex.Unlock()
+	if !found {
+		return nil
+	}
+	return session.Close()
+}
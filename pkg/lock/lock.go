@@ -0,0 +1,138 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultKey is the key of the lock, unless the URL sets another one using the key parameter
+	defaultKey = "watchtower/session-lock"
+	// ttl is how long the lock is held without being renewed, so that it is released when its holder crashes
+	ttl = time.Minute
+	// retryInterval is how often acquiring a lock held by another instance is retried
+	retryInterval = 2 * time.Second
+)
+
+// Lock is a lock shared by the instances across hosts, serializing their update sessions
+type Lock interface {
+	// Acquire waits until the lock has been acquired, or the context is done. The lock is renewed until the returned
+	// function releasing it is called.
+	Acquire(ctx context.Context) (release func(), err error)
+}
+
+// backend is the storage of a lock held by a token, which is what the backends implement
+type backend interface {
+	// tryAcquire takes the lock for the token if it is not held, returning whether it did
+	tryAcquire(token string) (bool, error)
+	// renew extends the lock for the token, returning false if it is no longer held by it
+	renew(token string) (bool, error)
+	// release releases the lock if it is held by the token
+	release(token string) error
+}
+
+// New returns the lock kept by the backend at the URL, like redis://:password@redis:6379/0 or etcd://etcd:2379. The
+// key of the lock defaults to watchtower/session-lock, and can be set using the key parameter.
+func New(rawURL string) (Lock, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session lock URL: %w", err)
+	}
+	key := parsed.Query().Get("key")
+	if key == "" {
+		key = defaultKey
+	}
+
+	var b backend
+	switch parsed.Scheme {
+	case "redis", "rediss":
+		b, err = newRedis(parsed, key)
+	case "etcd", "etcds":
+		b, err = newEtcd(parsed, key)
+	default:
+		return nil, fmt.Errorf(`unknown session lock backend %q, supported: "redis" or "etcd"`, parsed.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lock{backend: b, key: key}, nil
+}
+
+type lock struct {
+	backend backend
+	key     string
+}
+
+func (l *lock) Acquire(ctx context.Context) (func(), error) {
+	token := newToken()
+	for {
+		acquired, err := l.backend.tryAcquire(token)
+		if err != nil {
+			return nil, fmt.Errorf("could not acquire the session lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+		log.Debugf("The session lock %s is held by another instance, waiting", l.key)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for the session lock %s held by another instance", l.key)
+		case <-time.After(retryInterval):
+		}
+	}
+	log.Debugf("Acquired the session lock %s", l.key)
+
+	done := make(chan struct{})
+	go l.keepAlive(token, done)
+	return func() {
+		close(done)
+		if err := l.backend.release(token); err != nil {
+			log.WithError(err).Warn("Could not release the session lock, it expires on its own")
+			return
+		}
+		log.Debugf("Released the session lock %s", l.key)
+	}, nil
+}
+
+// keepAlive renews the lock until done is closed
+func (l *lock) keepAlive(token string, done <-chan struct{}) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			held, err := l.backend.renew(token)
+			if err != nil {
+				log.WithError(err).Warn("Could not renew the session lock")
+			} else if !held {
+				log.Warn("The session lock has expired while the session is running, another instance may have taken it")
+				return
+			}
+		}
+	}
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// hostPort returns the host of the URL, adding the default port if it has none
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return strings.TrimSuffix(u.Host, ":") + ":" + defaultPort
+}
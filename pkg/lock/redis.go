@@ -0,0 +1,65 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+const redisTimeout = 5 * time.Second
+
+var (
+	// redisRenewScript and redisReleaseScript only touch the lock while it is still held by the token
+	redisRenewScript = goredis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then ` +
+		`return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`)
+	redisReleaseScript = goredis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then ` +
+		`return redis.call("del", KEYS[1]) else return 0 end`)
+)
+
+// redis keeps the lock as a key expiring after the TTL, set using SET NX and renewed and released using scripts
+// comparing the token
+type redis struct {
+	client *goredis.Client
+	key    string
+}
+
+func newRedis(u *url.URL, key string) (*redis, error) {
+	// the key parameter is the one of the lock, any other is passed on as an option of the client
+	withoutKey := *u
+	query := withoutKey.Query()
+	query.Del("key")
+	withoutKey.RawQuery = query.Encode()
+
+	options, err := goredis.ParseURL(withoutKey.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	if options.Password == "" {
+		// redis://password@host is commonly used for the password without a user
+		options.Username, options.Password = "", options.Username
+	}
+	options.DialTimeout = redisTimeout
+	return &redis{client: goredis.NewClient(options), key: key}, nil
+}
+
+func (r *redis) tryAcquire(token string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return r.client.SetNX(ctx, r.key, token, ttl).Result()
+}
+
+func (r *redis) renew(token string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	renewed, err := redisRenewScript.Run(ctx, r.client, []string{r.key}, token, ttl.Milliseconds()).Int64()
+	return renewed == 1, err
+}
+
+func (r *redis) release(token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return redisReleaseScript.Run(ctx, r.client, []string{r.key}, token).Err()
+}
@@ -0,0 +1,95 @@
+package logarchive
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/types"
+)
+
+// uploadTimeout limits the time spent uploading the logs of a single container
+const uploadTimeout = 2 * time.Minute
+
+// unsafeChars are replaced in the names of the archived logs
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// New returns an archiver for the target, which is either the URL of an HTTP endpoint receiving the logs using POST
+// requests, or a directory to write the logs to
+func New(target string) (types.LogArchiver, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		if _, err := url.ParseRequestURI(target); err != nil {
+			return nil, fmt.Errorf("invalid log archive URL: %w", err)
+		}
+		return httpArchiver{url: target, client: &http.Client{Timeout: uploadTimeout}}, nil
+	}
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create the log archive directory: %w", err)
+	}
+	return dirArchiver{dir: target}, nil
+}
+
+// FileName returns the name of the archived logs of the container, made of its name, its image and the time it was
+// removed, like web_nginx-1.23_20221014T040002Z.log
+func FileName(container string, image string, removed time.Time) string {
+	parts := []string{strings.TrimPrefix(container, "/"), image, removed.UTC().Format("20060102T150405Z")}
+	for i, part := range parts {
+		parts[i] = strings.Trim(unsafeChars.ReplaceAllString(part, "-"), "-")
+	}
+	return strings.Join(parts, "_") + ".log"
+}
+
+type dirArchiver struct {
+	dir string
+}
+
+// Archive writes the logs to a file in the directory, removing it again if they could not be written completely
+func (a dirArchiver) Archive(container string, image string, removed time.Time, logs io.Reader) error {
+	path := filepath.Join(a.dir, FileName(container, image, removed))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, logs)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
+
+type httpArchiver struct {
+	url    string
+	client *http.Client
+}
+
+// Archive uploads the logs as the body of a POST request, describing them using the X-Watchtower headers
+func (a httpArchiver) Archive(container string, image string, removed time.Time, logs io.Reader) error {
+	req, err := http.NewRequest(http.MethodPost, a.url, logs)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, FileName(container, image, removed)))
+	req.Header.Set("X-Watchtower-Container", strings.TrimPrefix(container, "/"))
+	req.Header.Set("X-Watchtower-Image", image)
+	req.Header.Set("X-Watchtower-Removed", removed.UTC().Format(time.RFC3339))
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("the log archive responded with %s", res.Status)
+	}
+	return nil
+}
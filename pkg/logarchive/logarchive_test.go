@@ -0,0 +1,81 @@
+package logarchive
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func TestLogArchive(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Log Archive Suite")
+}
+
+var removed = time.Date(2022, 10, 14, 4, 0, 2, 0, time.UTC)
+
+var _ = Describe("the log archive", func() {
+	It("should name the logs after the container, image and removal time", func() {
+		Expect(FileName("/web", "ghcr.io/org/app:1.2", removed)).To(Equal("web_ghcr.io-org-app-1.2_20221014T040002Z.log"))
+	})
+
+	When("archiving to a directory", func() {
+		var dir string
+		BeforeEach(func() {
+			var err error
+			dir, err = os.MkdirTemp("", "watchtower-archive")
+			Expect(err).NotTo(HaveOccurred())
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		It("should write the logs to a file in the directory", func() {
+			archive, err := New(filepath.Join(dir, "logs"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(archive.Archive("/web", "nginx:latest", removed, strings.NewReader("listening\n"))).To(Succeed())
+
+			content, err := os.ReadFile(filepath.Join(dir, "logs", "web_nginx-latest_20221014T040002Z.log"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("listening\n"))
+		})
+	})
+
+	When("archiving to an HTTP endpoint", func() {
+		var server *ghttp.Server
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should post the logs along with the container and image", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest(http.MethodPost, "/logs"),
+				ghttp.VerifyHeaderKV("X-Watchtower-Container", "web"),
+				ghttp.VerifyHeaderKV("X-Watchtower-Image", "nginx:latest"),
+				ghttp.VerifyHeaderKV("X-Watchtower-Removed", "2022-10-14T04:00:02Z"),
+				ghttp.VerifyBody([]byte("listening\n")),
+				ghttp.RespondWith(http.StatusCreated, nil),
+			))
+			archive, err := New(server.URL() + "/logs")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(archive.Archive("/web", "nginx:latest", removed, strings.NewReader("listening\n"))).To(Succeed())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("should fail if the endpoint does not accept the logs", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusInternalServerError, nil))
+			archive, err := New(server.URL())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(archive.Archive("/web", "nginx:latest", removed, strings.NewReader("listening\n"))).NotTo(Succeed())
+		})
+	})
+})
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/rotate"
+	log "github.com/sirupsen/logrus"
+)
+
+// FileHook is a logrus hook writing the log entries to a file, in addition to the console. The file is rotated once it
+// would exceed the maximum size, or once it has been written to for longer than the maximum age, keeping the
+// configured number of previous files with the suffixes .1, .2 and so on.
+type FileHook struct {
+	maxAge    time.Duration
+	formatter log.Formatter
+	now       func() time.Time
+
+	// mutex makes checking the age of the file and writing to it atomic
+	mutex sync.Mutex
+	file  *rotate.File
+}
+
+// NewFileHook opens the log file at the path, appending to it if it exists. A max size or age of 0 disables the
+// rotation by that criterion.
+func NewFileHook(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*FileHook, error) {
+	file, err := rotate.Open(path, maxSize, maxBackups)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the log file: %w", err)
+	}
+	return &FileHook{
+		maxAge: maxAge,
+		formatter: &log.TextFormatter{
+			DisableColors: true,
+			FullTimestamp: true,
+			// the caller is only reported to determine the subsystem of the entries
+			CallerPrettyfier: func(*runtime.Frame) (string, string) { return "", "" },
+		},
+		now:  time.Now,
+		file: file,
+	}, nil
+}
+
+// Levels returns all the levels, as the entries reaching the hook have already been filtered by the logger
+func (h *FileHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire writes the entry to the file, rotating it first if needed. Failures are only printed, as logging them would
+// end up in the hook again.
+func (h *FileHook) Fire(entry *log.Entry) error {
+	if !Visible(entry) {
+		return nil
+	}
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to format the log entry: %v\n", err)
+		return nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.maxAge > 0 && h.now().Sub(h.file.Opened()) >= h.maxAge {
+		if err := h.file.Rotate(); err != nil && !errors.Is(err, os.ErrClosed) {
+			fmt.Fprintf(os.Stderr, "Failed to rotate the log file: %v\n", err)
+		}
+	}
+	if _, err := h.file.Write(line); err != nil && !errors.Is(err, os.ErrClosed) {
+		fmt.Fprintf(os.Stderr, "Failed to write to the log file: %v\n", err)
+	}
+	return nil
+}
+
+// Close closes the log file, after which further entries are only written to the console
+func (h *FileHook) Close() error {
+	return h.file.Close()
+}
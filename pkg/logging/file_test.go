@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the log file", func() {
+	var dir, path string
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-logs")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "logs", "watchtower.log")
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	read := func(path string) string {
+		content, err := os.ReadFile(path)
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+		return string(content)
+	}
+
+	It("should write the entries without colors", func() {
+		hook, err := NewFileHook(path, 0, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hook.Fire(testEntry())).To(Succeed())
+		Expect(hook.Close()).To(Succeed())
+
+		content := read(path)
+		Expect(content).To(ContainSubstring(`level=warning msg="Unable to update container" container=/web error=failed`))
+		Expect(content).NotTo(ContainSubstring("\x1b["))
+	})
+
+	It("should rotate the file once it would exceed the max size", func() {
+		hook, err := NewFileHook(path, 200, 0, 2)
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < 4; i++ {
+			Expect(hook.Fire(testEntry())).To(Succeed())
+		}
+		Expect(hook.Close()).To(Succeed())
+
+		Expect(strings.Count(read(path), "\n")).To(Equal(1))
+		Expect(strings.Count(read(path+".1"), "\n")).To(Equal(1))
+		Expect(strings.Count(read(path+".2"), "\n")).To(Equal(1))
+		Expect(path + ".3").NotTo(BeAnExistingFile())
+	})
+
+	It("should rotate the file once it is older than the max age", func() {
+		hook, err := NewFileHook(path, 0, time.Hour, 1)
+		Expect(err).NotTo(HaveOccurred())
+		now := hook.file.Opened()
+		hook.now = func() time.Time { return now }
+
+		Expect(hook.Fire(testEntry())).To(Succeed())
+		now = now.Add(30 * time.Minute)
+		Expect(hook.Fire(testEntry())).To(Succeed())
+		Expect(path + ".1").NotTo(BeAnExistingFile())
+
+		now = now.Add(30 * time.Minute)
+		Expect(hook.Fire(testEntry())).To(Succeed())
+		Expect(hook.Close()).To(Succeed())
+
+		Expect(strings.Count(read(path), "\n")).To(Equal(1))
+		Expect(strings.Count(read(path+".1"), "\n")).To(Equal(2))
+	})
+})
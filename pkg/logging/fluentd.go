@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"net"
+
+	"github.com/fluent/fluent-logger-golang/fluent"
+	log "github.com/sirupsen/logrus"
+)
+
+// fluentdWriter sends log entries using the message mode of the fluentd forward protocol
+type fluentdWriter struct {
+	tag string
+}
+
+func (w *fluentdWriter) write(conn net.Conn, entry *log.Entry) error {
+	data, err := w.encode(entry)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(data)
+	return err
+}
+
+// encode returns the entry as a forward protocol message, with its fields as the record
+func (w *fluentdWriter) encode(entry *log.Entry) ([]byte, error) {
+	record := map[string]string{
+		"level":   entry.Level.String(),
+		"message": entry.Message,
+	}
+	for name, value := range entry.Data {
+		record[name] = fieldValue(value)
+	}
+	msg := &fluent.Message{Tag: w.tag, Time: entry.Time.Unix(), Record: record}
+	return msg.MarshalMsg(nil)
+}
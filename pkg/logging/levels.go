@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// modulePath prefixes the functions of watchtower, from which the subsystem of the log entries is derived
+const modulePath = "github.com/containrrr/watchtower/"
+
+// Levels are the log levels of the subsystems of watchtower, being the packages below pkg and internal, like registry,
+// container or notifications, as well as cmd. Entries logged elsewhere use the default level.
+type Levels struct {
+	Default    log.Level
+	Subsystems map[string]log.Level
+}
+
+// current holds the levels applied using ApplyLevels
+var current atomic.Value
+
+// ParseLevels parses a comma separated list of subsystem=level pairs, like registry=debug,container=info. A level
+// without a subsystem sets the default level, which is fallback otherwise.
+func ParseLevels(spec string, fallback log.Level) (Levels, error) {
+	levels := Levels{Default: fallback, Subsystems: map[string]log.Level{}}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, found := strings.Cut(part, "=")
+		if !found {
+			level, err := log.ParseLevel(part)
+			if err != nil {
+				return Levels{}, err
+			}
+			levels.Default = level
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return Levels{}, fmt.Errorf("missing the subsystem of the level %q", part)
+		}
+		level, err := log.ParseLevel(strings.TrimSpace(value))
+		if err != nil {
+			return Levels{}, fmt.Errorf("invalid level of the %s subsystem: %w", name, err)
+		}
+		levels.Subsystems[name] = level
+	}
+	return levels, nil
+}
+
+// Max returns the most verbose of the levels
+func (l Levels) Max() log.Level {
+	max := l.Default
+	for _, level := range l.Subsystems {
+		if level > max {
+			max = level
+		}
+	}
+	return max
+}
+
+// Enabled returns whether the entry is logged at the level of its subsystem
+func (l Levels) Enabled(entry *log.Entry) bool {
+	if len(l.Subsystems) == 0 {
+		return entry.Level <= l.Default
+	}
+	level, found := l.Subsystems[subsystem(entry)]
+	if !found {
+		level = l.Default
+	}
+	return entry.Level <= level
+}
+
+// subsystem returns the subsystem of the function the entry was logged from, or an empty string if it is unknown
+func subsystem(entry *log.Entry) string {
+	if entry.Caller == nil || !strings.HasPrefix(entry.Caller.Function, modulePath) {
+		return ""
+	}
+	function := strings.TrimPrefix(entry.Caller.Function, modulePath)
+	// the package path ends at the first dot after its last slash, the rest being the function and its receiver
+	pkgPath := function
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		pkgPath = function[:slash+1+dot]
+	}
+	segments := strings.Split(pkgPath, "/")
+	if (segments[0] == "pkg" || segments[0] == "internal") && len(segments) > 1 {
+		return segments[1]
+	}
+	return segments[0]
+}
+
+// ApplyLevels sets the level of the standard logger to the most verbose of the levels. If there are levels per
+// subsystem, the entries are tagged with their caller, and the ones below the level of their subsystem are left out
+// of the console output, the log file and outputs, and the notifications.
+func ApplyLevels(levels Levels) {
+	logger := log.StandardLogger()
+	logger.SetLevel(levels.Max())
+	current.Store(levels)
+	filter, filtered := logger.Formatter.(filterFormatter)
+	if len(levels.Subsystems) == 0 {
+		logger.SetReportCaller(false)
+		if filtered {
+			logger.SetFormatter(filter.Formatter)
+		}
+		return
+	}
+	logger.SetReportCaller(true)
+	if !filtered {
+		logger.SetFormatter(filterFormatter{logger.Formatter})
+	}
+}
+
+// Visible returns whether the entry is logged at the level of its subsystem, as set using ApplyLevels
+func Visible(entry *log.Entry) bool {
+	levels, applied := current.Load().(Levels)
+	return !applied || len(levels.Subsystems) == 0 || levels.Enabled(entry)
+}
+
+// filterFormatter leaves out the entries below the level of their subsystem, and the caller from the others
+type filterFormatter struct {
+	log.Formatter
+}
+
+func (f filterFormatter) Format(entry *log.Entry) ([]byte, error) {
+	if !Visible(entry) {
+		return nil, nil
+	}
+	// the caller is only reported to determine the subsystem, and the hooks have already been fired
+	entry.Caller = nil
+	return f.Formatter.Format(entry)
+}
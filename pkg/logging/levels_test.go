@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+func entryFrom(function string, level log.Level) *log.Entry {
+	entry := log.NewEntry(log.StandardLogger())
+	entry.Level = level
+	entry.Caller = &runtime.Frame{Function: function}
+	return entry
+}
+
+var _ = Describe("the subsystem log levels", func() {
+	It("should parse the levels of the subsystems and the default level", func() {
+		levels, err := ParseLevels("registry=debug, container=info,warn", log.InfoLevel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(levels.Default).To(Equal(log.WarnLevel))
+		Expect(levels.Subsystems).To(Equal(map[string]log.Level{"registry": log.DebugLevel, "container": log.InfoLevel}))
+		Expect(levels.Max()).To(Equal(log.DebugLevel))
+
+		levels, err = ParseLevels("notifications=warn", log.TraceLevel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(levels.Default).To(Equal(log.TraceLevel))
+	})
+
+	It("should reject invalid levels", func() {
+		for _, spec := range []string{"verbose", "registry=verbose", "=debug"} {
+			_, err := ParseLevels(spec, log.InfoLevel)
+			Expect(err).To(HaveOccurred(), spec)
+		}
+	})
+
+	It("should derive the subsystem from the package of the caller", func() {
+		Expect(subsystem(entryFrom("github.com/containrrr/watchtower/pkg/registry/auth.GetToken", log.InfoLevel))).To(Equal("registry"))
+		Expect(subsystem(entryFrom("github.com/containrrr/watchtower/pkg/container.dockerClient.StartContainer", log.InfoLevel))).To(Equal("container"))
+		Expect(subsystem(entryFrom("github.com/containrrr/watchtower/internal/actions.Update.func1", log.InfoLevel))).To(Equal("actions"))
+		Expect(subsystem(entryFrom("github.com/containrrr/watchtower/cmd.runUpdatesWithNotifications", log.InfoLevel))).To(Equal("cmd"))
+		Expect(subsystem(entryFrom("main.main", log.InfoLevel))).To(BeEmpty())
+	})
+
+	It("should only enable the entries within the level of their subsystem", func() {
+		levels, err := ParseLevels("registry=debug,notifications=warn", log.InfoLevel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(levels.Enabled(entryFrom("github.com/containrrr/watchtower/pkg/registry/digest.GetDigest", log.DebugLevel))).To(BeTrue())
+		Expect(levels.Enabled(entryFrom("github.com/containrrr/watchtower/pkg/container.NewClient", log.DebugLevel))).To(BeFalse())
+		Expect(levels.Enabled(entryFrom("github.com/containrrr/watchtower/pkg/container.NewClient", log.InfoLevel))).To(BeTrue())
+		Expect(levels.Enabled(entryFrom("github.com/containrrr/watchtower/pkg/notifications.NewNotifier", log.InfoLevel))).To(BeFalse())
+	})
+})
@@ -0,0 +1,169 @@
+package logging
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// queueSize is the number of log entries that are buffered while they are being shipped
+	queueSize = 1024
+	// dialTimeout limits the time spent trying to connect to a log collector
+	dialTimeout = 5 * time.Second
+	// defaultTag is used as the syslog app name and fluentd tag unless another one is configured
+	defaultTag = "watchtower"
+)
+
+// entryWriter ships a single log entry to a log collector
+type entryWriter interface {
+	write(conn net.Conn, entry *log.Entry) error
+}
+
+// OutputHook is a logrus hook shipping log entries, including their fields, to a syslog server or fluentd. Entries
+// are sent in the background, and dropped if the collector cannot keep up, so that logging never blocks watchtower.
+type OutputHook struct {
+	network string
+	address string
+	writer  entryWriter
+
+	entries chan *log.Entry
+	quit    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	conn    net.Conn
+}
+
+// NewOutputHook creates a hook for the supplied output URL, like syslog://host:514, syslog+tcp://host:514 or
+// fluentd://host:24224. The tag query parameter overrides the syslog app name and fluentd tag.
+func NewOutputHook(output string) (*OutputHook, error) {
+	parsed, err := url.Parse(output)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Hostname() == "" {
+		return nil, fmt.Errorf("log output %q is missing a host", output)
+	}
+
+	tag := parsed.Query().Get("tag")
+	if tag == "" {
+		tag = defaultTag
+	}
+
+	hook := &OutputHook{
+		entries: make(chan *log.Entry, queueSize),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	port := parsed.Port()
+	switch parsed.Scheme {
+	case "syslog", "syslog+udp":
+		hook.network, hook.writer = "udp", newSyslogWriter(tag, false)
+		port = defaultString(port, "514")
+	case "syslog+tcp":
+		hook.network, hook.writer = "tcp", newSyslogWriter(tag, true)
+		port = defaultString(port, "514")
+	case "fluentd":
+		hook.network, hook.writer = "tcp", &fluentdWriter{tag: tag}
+		port = defaultString(port, "24224")
+	default:
+		return nil, fmt.Errorf("unsupported log output %q, expected syslog, syslog+tcp or fluentd", parsed.Scheme)
+	}
+	hook.address = net.JoinHostPort(parsed.Hostname(), port)
+
+	go hook.run()
+	return hook, nil
+}
+
+// Levels returns the levels that the hook is fired for, which is every level enabled on the logger
+func (h *OutputHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire queues the entry to be sent, dropping it if the queue is full
+func (h *OutputHook) Fire(entry *log.Entry) error {
+	if !Visible(entry) {
+		return nil
+	}
+	// The entry is reused by logrus once the hooks have been fired
+	queued := entry.Dup()
+	queued.Message, queued.Level, queued.Time = entry.Message, entry.Level, entry.Time
+	select {
+	case h.entries <- queued:
+	default:
+	}
+	return nil
+}
+
+// Close sends the remaining queued entries, waiting at most for the supplied timeout
+func (h *OutputHook) Close(timeout time.Duration) {
+	h.once.Do(func() { close(h.quit) })
+	select {
+	case <-h.done:
+	case <-time.After(timeout):
+	}
+}
+
+func (h *OutputHook) run() {
+	defer close(h.done)
+	defer func() {
+		if h.conn != nil {
+			_ = h.conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case entry := <-h.entries:
+			h.ship(entry)
+		case <-h.quit:
+			for {
+				select {
+				case entry := <-h.entries:
+					h.ship(entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (h *OutputHook) ship(entry *log.Entry) {
+	if err := h.send(entry); err != nil {
+		// Logging the failure would cause it to be shipped as well, so it is only printed
+		fmt.Fprintf(os.Stderr, "Failed to ship log entry to %s: %v\n", h.address, err)
+	}
+}
+
+// send writes the entry using the current connection, reconnecting once if that fails
+func (h *OutputHook) send(entry *log.Entry) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if h.conn == nil {
+			if h.conn, err = net.DialTimeout(h.network, h.address, dialTimeout); err != nil {
+				h.conn = nil
+				return err
+			}
+		}
+		_ = h.conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+		if err = h.writer.write(h.conn, entry); err == nil {
+			return nil
+		}
+		_ = h.conn.Close()
+		h.conn = nil
+	}
+	return err
+}
+
+func defaultString(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fluent/fluent-logger-golang/fluent"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestLogging(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Logging Suite")
+}
+
+func testEntry() *log.Entry {
+	entry := log.NewEntry(log.StandardLogger()).WithFields(log.Fields{
+		"container": "/web",
+		"quote":     `say "hi" [now]`,
+	}).WithError(errors.New("failed"))
+	entry.Message = "Unable to update container"
+	entry.Level = log.WarnLevel
+	entry.Time = time.Date(2022, 9, 1, 12, 30, 0, 0, time.UTC)
+	return entry
+}
+
+var _ = Describe("the log outputs", func() {
+	Describe("parsing the output URL", func() {
+		It("should use the default ports", func() {
+			hook, err := NewOutputHook("syslog://logs.example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hook.network).To(Equal("udp"))
+			Expect(hook.address).To(Equal("logs.example.com:514"))
+
+			hook, err = NewOutputHook("fluentd://logs.example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hook.network).To(Equal("tcp"))
+			Expect(hook.address).To(Equal("logs.example.com:24224"))
+		})
+		It("should reject unsupported outputs", func() {
+			_, err := NewOutputHook("kafka://logs.example.com")
+			Expect(err).To(HaveOccurred())
+			_, err = NewOutputHook("syslog://")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("the syslog format", func() {
+		It("should include the fields as structured data", func() {
+			writer := &syslogWriter{tag: "watchtower", hostname: "host"}
+			message := writer.format(testEntry())
+			Expect(message).To(HavePrefix("<28>1 2022-09-01T12:30:00Z host watchtower "))
+			Expect(message).To(HaveSuffix(
+				`- [fields@32473 level="warning" container="/web" error="failed" quote="say \"hi\" [now\]"] Unable to update container`))
+		})
+	})
+
+	Describe("the fluentd format", func() {
+		It("should encode the entry as a forward protocol message", func() {
+			data, err := (&fluentdWriter{tag: "watchtower"}).encode(testEntry())
+			Expect(err).NotTo(HaveOccurred())
+
+			var msg fluent.Message
+			rest, err := msg.UnmarshalMsg(data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rest).To(BeEmpty())
+			Expect(msg.Tag).To(Equal("watchtower"))
+			Expect(msg.Time).To(BeEquivalentTo(1662035400))
+			Expect(msg.Record).To(Equal(map[string]interface{}{
+				"level":     "warning",
+				"message":   "Unable to update container",
+				"container": "/web",
+				"error":     "failed",
+				"quote":     `say "hi" [now]`,
+			}))
+		})
+	})
+
+	When("shipping entries to a collector", func() {
+		It("should send them in the background", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			received := make(chan string, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString(']')
+				received <- line
+			}()
+
+			hook, err := NewOutputHook("syslog+tcp://" + listener.Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hook.Fire(testEntry())).To(Succeed())
+			hook.Close(time.Second)
+
+			Eventually(received).Should(Receive(WithTransform(func(line string) bool {
+				return strings.Contains(line, `container="/web"`)
+			}, BeTrue())))
+		})
+	})
+})
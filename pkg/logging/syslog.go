@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// syslogFacility is the daemon facility, used for all entries
+const syslogFacility = 3
+
+// syslogWriter formats log entries according to RFC 5424, with the entry fields as structured data
+type syslogWriter struct {
+	tag      string
+	hostname string
+	// framed prefixes each message with its length, as required when using TCP (RFC 6587)
+	framed bool
+}
+
+func newSyslogWriter(tag string, framed bool) *syslogWriter {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &syslogWriter{tag: tag, hostname: hostname, framed: framed}
+}
+
+func (w *syslogWriter) write(conn net.Conn, entry *log.Entry) error {
+	message := w.format(entry)
+	if w.framed {
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+	_, err := conn.Write([]byte(message))
+	return err
+}
+
+func (w *syslogWriter) format(entry *log.Entry) string {
+	priority := syslogFacility*8 + syslogSeverity(entry.Level)
+	return fmt.Sprintf("<%d>1 %s %s %s %d - %s %s", priority, entry.Time.Format(time.RFC3339Nano), w.hostname,
+		w.tag, os.Getpid(), structuredData(entry.Data, entry.Level), entry.Message)
+}
+
+func syslogSeverity(level log.Level) int {
+	switch level {
+	case log.PanicLevel:
+		return 0
+	case log.FatalLevel:
+		return 2
+	case log.ErrorLevel:
+		return 3
+	case log.WarnLevel:
+		return 4
+	case log.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// structuredData formats the entry fields as a single SD-ELEMENT, sorted by name
+func structuredData(fields log.Fields, level log.Level) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sd := strings.Builder{}
+	sd.WriteString(`[fields@32473 level="`)
+	sd.WriteString(level.String())
+	sd.WriteString(`"`)
+	for _, name := range names {
+		fmt.Fprintf(&sd, ` %s="%s"`, sdName(name), sdEscaper.Replace(fieldValue(fields[name])))
+	}
+	sd.WriteString("]")
+	return sd.String()
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// sdName replaces the characters that are not allowed in structured data parameter names
+func sdName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func fieldValue(value interface{}) string {
+	if err, ok := value.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/types"
+)
+
+// Influx writes the measurements of the sessions to InfluxDB, or any other endpoint accepting the line protocol
+type Influx struct {
+	url    string
+	token  string
+	host   string
+	client *http.Client
+}
+
+// NewInflux returns an exporter writing to the URL of the write endpoint, like
+// http://influxdb:8086/api/v2/write?org=home&bucket=watchtower. The token is sent as an InfluxDB v2 API token, if set.
+func NewInflux(url, token string) *Influx {
+	host, _ := os.Hostname()
+	return &Influx{
+		url:    url,
+		token:  token,
+		host:   host,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Write writes the session measurement and a measurement for each of the containers in the report
+func (i *Influx) Write(report types.Report, duration time.Duration) error {
+	body := LineProtocol(report, duration, i.host, time.Now())
+
+	req, err := http.NewRequest(http.MethodPost, i.url, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not write the metrics to influxdb: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.token != "" {
+		req.Header.Set("Authorization", "Token "+i.token)
+	}
+
+	res, err := i.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not write the metrics to influxdb: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("could not write the metrics to influxdb: %s: %s", res.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// LineProtocol formats the report as the watchtower_session measurement, and a watchtower_container measurement for
+// each of the containers, tagged by the host and timestamped in nanoseconds
+func LineProtocol(report types.Report, duration time.Duration, host string, at time.Time) string {
+	timestamp := at.UnixNano()
+	hostTag := ""
+	if host != "" {
+		hostTag = ",host=" + escapeTag(host)
+	}
+
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "watchtower_session%s scanned=%di,updated=%di,failed=%di,skipped=%di,stale=%di,quarantined=%di,"+
+		"pulled_bytes=%di,duration_seconds=%g,scan_duration_seconds=%g %d\n",
+		hostTag, len(report.Scanned()), len(report.Updated()), len(report.Failed()), len(report.Skipped()),
+		len(report.Stale()), len(report.Quarantined()), report.PulledBytes(), duration.Seconds(),
+		report.ScanDuration().Seconds(), timestamp)
+
+	for _, c := range report.All() {
+		fmt.Fprintf(&sb, "watchtower_container%s,container=%s,image=%s,state=%s current_image=%s,latest_image=%s,"+
+			"pulled_bytes=%di",
+			hostTag, escapeTag(strings.TrimPrefix(c.Name(), "/")), escapeTag(c.ImageName()),
+			escapeTag(strings.ToLower(c.State())),
+			quoteField(c.CurrentImageID().ShortID()), quoteField(c.LatestImageID().ShortID()), c.PulledBytes())
+		if pull := c.PullDuration(); pull > 0 {
+			fmt.Fprintf(&sb, ",pull_duration_seconds=%g", pull.Seconds())
+		}
+		if recreate := c.RecreateDuration(); recreate > 0 {
+			fmt.Fprintf(&sb, ",recreate_duration_seconds=%g", recreate.Seconds())
+		}
+		if reason := c.SkipReason(); reason != "" {
+			fmt.Fprintf(&sb, ",skip_reason=%s", quoteField(string(reason)))
+		}
+		if err := c.Error(); err != "" {
+			fmt.Fprintf(&sb, ",error=%s", quoteField(err))
+		}
+		fmt.Fprintf(&sb, " %d\n", timestamp)
+	}
+	return sb.String()
+}
+
+var tagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\ `)
+
+// escapeTag escapes the characters with a special meaning in tag values, using a placeholder for empty values, as
+// they are not allowed. Newlines are not supported at all, and are replaced by spaces.
+func escapeTag(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return tagEscaper.Replace(value)
+}
+
+var fieldEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ")
+
+// quoteField quotes the value as a string field, replacing newlines by spaces
+func quoteField(value string) string {
+	return `"` + fieldEscaper.Replace(value) + `"`
+}
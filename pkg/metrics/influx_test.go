@@ -0,0 +1,61 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	"github.com/containrrr/watchtower/pkg/metrics"
+	"github.com/containrrr/watchtower/pkg/session"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the influxdb metrics", func() {
+	at := time.Unix(1700000000, 0)
+
+	It("should format a session measurement and a measurement per container", func() {
+		report := mocks.CreateMockProgressReport(session.UpdatedState, session.FailedState)
+		lines := strings.Split(strings.TrimSpace(metrics.LineProtocol(report, 1500*time.Millisecond, "my host", at)), "\n")
+
+		Expect(lines).To(HaveLen(3))
+		Expect(lines[0]).To(HavePrefix(`watchtower_session,host=my\ host scanned=2i,updated=1i,failed=1i,`))
+		Expect(lines[0]).To(ContainSubstring("duration_seconds=1.5"))
+		Expect(lines[0]).To(HaveSuffix(" 1700000000000000000"))
+		Expect(lines[1]).To(HavePrefix(`watchtower_container,host=my\ host,container=updt1,`))
+		Expect(lines[1]).To(ContainSubstring(",state=updated "))
+		Expect(lines[2]).To(ContainSubstring(",state=failed "))
+		Expect(lines[2]).To(ContainSubstring(`,error="accidentally the whole container"`))
+	})
+
+	It("should write the measurements using the token", func() {
+		var auth, body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth = r.Header.Get("Authorization")
+			bytes, _ := ioutil.ReadAll(r.Body)
+			body = string(bytes)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		influx := metrics.NewInflux(server.URL+"/api/v2/write?org=home&bucket=watchtower", "secret")
+		Expect(influx.Write(mocks.CreateMockProgressReport(session.FreshState), time.Second)).To(Succeed())
+		Expect(auth).To(Equal("Token secret"))
+		Expect(body).To(HavePrefix("watchtower_session"))
+	})
+
+	It("should fail when the endpoint refuses the measurements", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"unauthorized"}`))
+		}))
+		defer server.Close()
+
+		err := metrics.NewInflux(server.URL, "").Write(mocks.CreateMockProgressReport(), time.Second)
+		Expect(err).To(MatchError(ContainSubstring("unauthorized")))
+	})
+})
@@ -1,6 +1,9 @@
 package metrics
 
 import (
+	"strings"
+	"time"
+
 	"github.com/containrrr/watchtower/pkg/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -13,6 +16,17 @@ type Metric struct {
 	Scanned int
 	Updated int
 	Failed  int
+	// PulledBytes is the number of bytes downloaded while pulling images
+	PulledBytes int64
+	// Quarantined is the number of containers that were skipped as they are quarantined
+	Quarantined int
+	// Duration is the time the session took, and ScanDuration the part of it spent checking the containers
+	Duration     time.Duration
+	ScanDuration time.Duration
+	// PullDurations are the times spent pulling the images that were downloaded, by image name
+	PullDurations map[string]time.Duration
+	// RecreateDurations are the times spent recreating the updated containers, by container name
+	RecreateDurations map[string]time.Duration
 }
 
 // Metrics is the handler processing all individual scan metrics
@@ -23,16 +37,51 @@ type Metrics struct {
 	failed  prometheus.Gauge
 	total   prometheus.Counter
 	skipped prometheus.Counter
+	pulled  prometheus.Gauge
+	// pulledTotal counts the bytes downloaded across all scans
+	pulledTotal prometheus.Counter
+	quarantined prometheus.Gauge
+	// the durations of the sessions, the scans, the pulls of each image and the recreations of each container
+	duration         prometheus.Histogram
+	scanDuration     prometheus.Histogram
+	pullDuration     *prometheus.HistogramVec
+	recreateDuration *prometheus.HistogramVec
+	// apiRejected counts the rejected HTTP API requests by the reason, and apiLockouts the sources locked out
+	apiRejected *prometheus.CounterVec
+	apiLockouts prometheus.Counter
 }
 
+// durationBuckets range from half a second to about 17 minutes, as pulling an image or recreating a container can take
+// from seconds to minutes, depending on the registry and the storage
+var durationBuckets = prometheus.ExponentialBuckets(0.5, 2, 12)
+
 // NewMetric returns a Metric with the counts taken from the appropriate types.Report fields
 func NewMetric(report types.Report) *Metric {
-	return &Metric{
+	metric := &Metric{
 		Scanned: len(report.Scanned()),
 		// Note: This is for backwards compatibility. ideally, stale containers should be counted separately
 		Updated: len(report.Updated()) + len(report.Stale()),
 		Failed:  len(report.Failed()),
+
+		PulledBytes: report.PulledBytes(),
+		Quarantined: len(report.Quarantined()),
+
+		Duration:          report.Duration(),
+		ScanDuration:      report.ScanDuration(),
+		PullDurations:     map[string]time.Duration{},
+		RecreateDurations: map[string]time.Duration{},
+	}
+	for _, c := range report.All() {
+		if _, found := metric.PullDurations[c.ImageName()]; !found && c.PullDuration() > 0 {
+			metric.PullDurations[c.ImageName()] = c.PullDuration()
+		}
 	}
+	for _, c := range report.Updated() {
+		if c.RecreateDuration() > 0 {
+			metric.RecreateDurations[strings.TrimPrefix(c.Name(), "/")] = c.RecreateDuration()
+		}
+	}
+	return metric
 }
 
 // QueueIsEmpty checks whether any messages are enqueued in the channel
@@ -72,6 +121,46 @@ func Default() *Metrics {
 			Name: "watchtower_scans_skipped",
 			Help: "Number of skipped scans since watchtower started",
 		}),
+		pulled: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "watchtower_pulled_bytes",
+			Help: "Number of bytes downloaded while pulling images during the last scan",
+		}),
+		pulledTotal: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "watchtower_pulled_bytes_total",
+			Help: "Number of bytes downloaded while pulling images since watchtower started",
+		}),
+		quarantined: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "watchtower_containers_quarantined",
+			Help: "Number of containers skipped during the last scan, as they are quarantined after failing repeatedly",
+		}),
+		duration: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "watchtower_session_duration_seconds",
+			Help:    "Time the sessions took, in seconds",
+			Buckets: durationBuckets,
+		}),
+		scanDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "watchtower_scan_duration_seconds",
+			Help:    "Time spent checking the containers for new images during the sessions, in seconds",
+			Buckets: durationBuckets,
+		}),
+		pullDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "watchtower_image_pull_duration_seconds",
+			Help:    "Time spent pulling the new images, in seconds",
+			Buckets: durationBuckets,
+		}, []string{"image"}),
+		recreateDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "watchtower_container_recreate_duration_seconds",
+			Help:    "Time spent stopping the updated containers and starting them again, in seconds",
+			Buckets: durationBuckets,
+		}, []string{"container"}),
+		apiRejected: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "watchtower_api_requests_rejected_total",
+			Help: "Number of HTTP API requests rejected since watchtower started, by the reason",
+		}, []string{"reason"}),
+		apiLockouts: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "watchtower_api_lockouts_total",
+			Help: "Number of times a source was locked out of the HTTP API after sending invalid tokens repeatedly",
+		}),
 		channel: make(chan *Metric, 10),
 	}
 
@@ -86,6 +175,16 @@ func RegisterScan(metric *Metric) {
 	metrics.Register(metric)
 }
 
+// RegisterAPIRejection counts an HTTP API request rejected for the reason, like an invalid token
+func RegisterAPIRejection(reason string) {
+	Default().apiRejected.WithLabelValues(reason).Inc()
+}
+
+// RegisterAPILockout counts a source being locked out of the HTTP API
+func RegisterAPILockout() {
+	Default().apiLockouts.Inc()
+}
+
 // HandleUpdate dequeue the metric channel and processes it
 func (metrics *Metrics) HandleUpdate(channel <-chan *Metric) {
 	for change := range channel {
@@ -96,6 +195,7 @@ func (metrics *Metrics) HandleUpdate(channel <-chan *Metric) {
 			metrics.scanned.Set(0)
 			metrics.updated.Set(0)
 			metrics.failed.Set(0)
+			metrics.pulled.Set(0)
 			continue
 		}
 		// Update metrics with the new values
@@ -103,5 +203,18 @@ func (metrics *Metrics) HandleUpdate(channel <-chan *Metric) {
 		metrics.scanned.Set(float64(change.Scanned))
 		metrics.updated.Set(float64(change.Updated))
 		metrics.failed.Set(float64(change.Failed))
+		metrics.pulled.Set(float64(change.PulledBytes))
+		metrics.pulledTotal.Add(float64(change.PulledBytes))
+		metrics.quarantined.Set(float64(change.Quarantined))
+		if change.Duration > 0 {
+			metrics.duration.Observe(change.Duration.Seconds())
+			metrics.scanDuration.Observe(change.ScanDuration.Seconds())
+		}
+		for image, duration := range change.PullDurations {
+			metrics.pullDuration.WithLabelValues(image).Observe(duration.Seconds())
+		}
+		for container, duration := range change.RecreateDurations {
+			metrics.recreateDuration.WithLabelValues(container).Observe(duration.Seconds())
+		}
 	}
 }
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// Push sends the metrics of a single session to the Pushgateway at the URL, replacing the ones previously pushed for
+// the job and the host. It is used when running once, as there is no long-running process to scrape the metrics from.
+// The metric is nil when the session could not be run. Credentials in the URL are sent using basic authentication.
+func Push(url, job string, metric *Metric, exitCode int) error {
+	if metric == nil {
+		metric = &Metric{}
+	}
+	gauge := func(name, help string, value float64) prometheus.Collector {
+		g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: help})
+		g.Set(value)
+		return g
+	}
+
+	pusher := push.New(url, job).
+		Collector(gauge("watchtower_containers_scanned",
+			"Number of containers scanned for changes by watchtower during the last scan", float64(metric.Scanned))).
+		Collector(gauge("watchtower_containers_updated",
+			"Number of containers updated by watchtower during the last scan", float64(metric.Updated))).
+		Collector(gauge("watchtower_containers_failed",
+			"Number of containers where update failed during the last scan", float64(metric.Failed))).
+		Collector(gauge("watchtower_pulled_bytes",
+			"Number of bytes downloaded while pulling images during the last scan", float64(metric.PulledBytes))).
+		Collector(gauge("watchtower_containers_quarantined",
+			"Number of containers skipped during the last scan, as they are quarantined after failing repeatedly",
+			float64(metric.Quarantined))).
+		Collector(gauge("watchtower_last_session_duration_seconds",
+			"Time the last session took, in seconds", metric.Duration.Seconds())).
+		Collector(gauge("watchtower_last_scan_duration_seconds",
+			"Time spent checking the containers for new images during the last session, in seconds",
+			metric.ScanDuration.Seconds())).
+		Collector(gauge("watchtower_exit_code",
+			"Exit code of the last run of watchtower", float64(exitCode))).
+		Collector(gauge("watchtower_last_run_timestamp_seconds",
+			"Time the last run of watchtower finished, in seconds since the epoch", float64(time.Now().Unix())))
+
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		pusher = pusher.Grouping("instance", hostname)
+	}
+
+	if err := pusher.Push(); err != nil {
+		return fmt.Errorf("could not push the metrics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containrrr/watchtower/pkg/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Metrics Suite")
+}
+
+var _ = Describe("pushing the metrics", func() {
+	var server *httptest.Server
+	var method, path, body, user string
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method, path = r.Method, r.URL.Path
+			user, _, _ = r.BasicAuth()
+			bytes, _ := ioutil.ReadAll(r.Body)
+			body = string(bytes)
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should replace the metrics of the job with the ones of the session", func() {
+		metric := &metrics.Metric{Scanned: 3, Updated: 1, Failed: 1}
+		Expect(metrics.Push(server.URL, "nightly", metric, 2)).To(Succeed())
+
+		Expect(method).To(Equal(http.MethodPut))
+		Expect(path).To(HavePrefix("/metrics/job/nightly/instance/"))
+		Expect(body).To(ContainSubstring("watchtower_containers_scanned"))
+		Expect(body).To(ContainSubstring("watchtower_exit_code"))
+	})
+
+	It("should push the exit code when the session could not run", func() {
+		Expect(metrics.Push(server.URL, "watchtower", nil, 1)).To(Succeed())
+		Expect(body).To(ContainSubstring("watchtower_containers_scanned"))
+	})
+
+	It("should use the credentials in the URL", func() {
+		url := "http://pusher:secret@" + server.Listener.Addr().String()
+		Expect(metrics.Push(url, "watchtower", nil, 0)).To(Succeed())
+		Expect(user).To(Equal("pusher"))
+	})
+
+	It("should fail when the Pushgateway refuses the metrics", func() {
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		})
+		Expect(metrics.Push(server.URL, "watchtower", nil, 0)).NotTo(Succeed())
+	})
+})
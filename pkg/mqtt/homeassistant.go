@@ -0,0 +1,157 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/containrrr/watchtower/internal/meta"
+	"github.com/containrrr/watchtower/pkg/types"
+)
+
+// installPayload is the payload of the commands of Home Assistant installing the update of a container
+const installPayload = "install"
+
+// releaseSummaryLength is the maximum length of the release summary of an update entity of Home Assistant
+const releaseSummaryLength = 255
+
+// invalidIDCharacters are the characters that are not allowed in the IDs of the discovery topics of Home Assistant
+var invalidIDCharacters = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// homeAssistant publishes the discovery payloads of Home Assistant, so that each container appears as an update
+// entity of a watchtower device, showing its installed and latest images. When install is set, the entities can
+// install the updates, which runs a session updating the container.
+type homeAssistant struct {
+	prefix  string
+	nodeID  string
+	install func(container string)
+
+	mu sync.Mutex
+	// states are the last states of the entities, by the name of their container
+	states map[string]updateState
+}
+
+// updateState is the state of an update entity, as read by Home Assistant from the JSON payload of its state topic
+type updateState struct {
+	InstalledVersion string `json:"installed_version"`
+	LatestVersion    string `json:"latest_version"`
+	Title            string `json:"title,omitempty"`
+	ReleaseURL       string `json:"release_url,omitempty"`
+	ReleaseSummary   string `json:"release_summary,omitempty"`
+	InProgress       bool   `json:"in_progress"`
+}
+
+// EnableHomeAssistant publishes the discovery payloads of Home Assistant below the discovery prefix, along with the
+// state of the containers, once their status is published. When install is set, the containers can be updated from
+// Home Assistant, which calls it with the name of the container in its own goroutine.
+func (p *Publisher) EnableHomeAssistant(discoveryPrefix string, install func(container string)) {
+	nodeID := invalidIDCharacters.ReplaceAllString(p.client.clientID, "_")
+	p.homeAssistant = &homeAssistant{
+		prefix:  strings.Trim(discoveryPrefix, "/"),
+		nodeID:  nodeID,
+		install: install,
+		states:  map[string]updateState{},
+	}
+	if install == nil {
+		return
+	}
+	p.client.onMessage = p.receive
+	onConnect := p.client.onConnect
+	p.client.onConnect = func() error {
+		if err := onConnect(); err != nil {
+			return err
+		}
+		return p.client.Subscribe(p.Topic("containers", "+", installPayload), 1)
+	}
+}
+
+// publishEntity publishes the discovery payload of the entity of the container, along with its state
+func (p *Publisher) publishEntity(c types.ContainerReport) {
+	ha := p.homeAssistant
+	name := ContainerName(c.Name())
+	objectID := invalidIDCharacters.ReplaceAllString(name, "_")
+	host, _ := os.Hostname()
+
+	config := map[string]interface{}{
+		"name":               name,
+		"unique_id":          ha.nodeID + "_" + objectID,
+		"state_topic":        p.Topic("containers", name, "update"),
+		"availability_topic": p.Topic("status"),
+		"device": map[string]interface{}{
+			"identifiers":  []string{ha.nodeID},
+			"name":         "Watchtower " + host,
+			"manufacturer": "containrrr",
+			"model":        "Watchtower",
+			"sw_version":   meta.Version,
+		},
+	}
+	if ha.install != nil {
+		config["command_topic"] = p.Topic("containers", name, installPayload)
+		config["payload_install"] = installPayload
+	}
+	if payload, err := json.Marshal(config); err == nil {
+		p.enqueue(strings.Join([]string{ha.prefix, "update", ha.nodeID, objectID, "config"}, "/"), payload, true)
+	}
+
+	state := containerState(c)
+	ha.mu.Lock()
+	ha.states[name] = state
+	ha.mu.Unlock()
+	p.publishState(name, state)
+}
+
+// publishState publishes the state of the entity of the container
+func (p *Publisher) publishState(name string, state updateState) {
+	if payload, err := json.Marshal(state); err == nil {
+		p.enqueue(p.Topic("containers", name, "update"), payload, true)
+	}
+}
+
+// containerState returns the state of the entity of the container, the versions being the short IDs of the images
+func containerState(c types.ContainerReport) updateState {
+	installed, latest := c.CurrentImageID().ShortID(), c.LatestImageID().ShortID()
+	if latest == "" {
+		latest = installed
+	}
+	if c.State() == "Updated" {
+		installed = latest
+	}
+	summary := c.Release().Notes
+	if runes := []rune(summary); len(runes) > releaseSummaryLength {
+		summary = string(runes[:releaseSummaryLength-1]) + "…"
+	}
+	return updateState{
+		InstalledVersion: installed,
+		LatestVersion:    latest,
+		Title:            c.ImageName(),
+		ReleaseURL:       c.Release().URL,
+		ReleaseSummary:   summary,
+	}
+}
+
+// receive handles the install commands of Home Assistant, marking the update of the container as in progress until
+// the next status of the container is published, if it was published since watchtower started
+func (p *Publisher) receive(msg Message) {
+	levels := strings.Split(strings.TrimPrefix(msg.Topic, p.prefix+"/"), "/")
+	// a retained command would update the container again on every connection
+	if msg.Retain || len(levels) != 3 || levels[0] != "containers" || levels[2] != installPayload ||
+		string(msg.Payload) != installPayload {
+		return
+	}
+	name := levels[1]
+	ha := p.homeAssistant
+	ha.mu.Lock()
+	state, found := ha.states[name]
+	if found {
+		state.InProgress = true
+		ha.states[name] = state
+	}
+	ha.mu.Unlock()
+	localLog.Infof("Updating %s, as requested by Home Assistant", name)
+	if found {
+		p.publishState(name, state)
+	}
+	go ha.install(name)
+}
@@ -0,0 +1,83 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	"github.com/containrrr/watchtower/pkg/session"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the Home Assistant discovery", func() {
+	var b *broker
+
+	BeforeEach(func() {
+		b = newBroker()
+	})
+	AfterEach(func() {
+		b.close()
+	})
+
+	payload := func(topic string) map[string]interface{} {
+		var last map[string]interface{}
+		for _, msg := range b.published() {
+			if msg.Topic == topic {
+				Expect(msg.Retain).To(BeTrue())
+				last = map[string]interface{}{}
+				Expect(json.Unmarshal(msg.Payload, &last)).To(Succeed())
+			}
+		}
+		return last
+	}
+
+	It("should publish an update entity for each container", func() {
+		p, err := New(b.url(""), "watchtower", 1, "watchtower-nas.local")
+		Expect(err).NotTo(HaveOccurred())
+		p.EnableHomeAssistant("homeassistant", nil)
+		p.PublishReport(mocks.CreateMockProgressReport(session.StaleState))
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+
+		config := payload("homeassistant/update/watchtower-nas_local/stal1/config")
+		Expect(config).To(HaveKeyWithValue("name", "stal1"))
+		Expect(config).To(HaveKeyWithValue("unique_id", "watchtower-nas_local_stal1"))
+		Expect(config).To(HaveKeyWithValue("state_topic", "watchtower/containers/stal1/update"))
+		Expect(config).To(HaveKeyWithValue("availability_topic", "watchtower/status"))
+		Expect(config).NotTo(HaveKey("command_topic"))
+		Expect(config["device"]).To(HaveKeyWithValue("identifiers", []interface{}{"watchtower-nas_local"}))
+
+		state := payload("watchtower/containers/stal1/update")
+		Expect(state["installed_version"]).NotTo(Equal(state["latest_version"]))
+		Expect(state).To(HaveKeyWithValue("in_progress", false))
+		Expect(b.subscriptions).To(BeEmpty())
+	})
+
+	It("should show the containers that were updated as up to date", func() {
+		state := containerState(mocks.CreateMockProgressReport(session.UpdatedState).Updated()[0])
+		Expect(state.InstalledVersion).To(Equal(state.LatestVersion))
+		Expect(state.InstalledVersion).NotTo(BeEmpty())
+	})
+
+	It("should update the containers when Home Assistant asks to install their update", func() {
+		installed := make(chan string, 1)
+		p, err := New(b.url(""), "watchtower", 1, "watchtower")
+		Expect(err).NotTo(HaveOccurred())
+		p.EnableHomeAssistant("homeassistant", func(container string) { installed <- container })
+		p.PublishReport(mocks.CreateMockProgressReport(session.StaleState))
+
+		Eventually(func() map[string]interface{} {
+			return payload("homeassistant/update/watchtower/stal1/config")
+		}).Should(HaveKeyWithValue("command_topic", "watchtower/containers/stal1/install"))
+		Expect(b.subscriptions).To(Equal([]string{"watchtower/containers/+/install"}))
+
+		b.deliver(Message{Topic: "watchtower/containers/stal1/install", Payload: []byte("install"), Retain: true})
+		b.deliver(Message{Topic: "watchtower/containers/stal1/install", Payload: []byte("install")})
+		Eventually(installed).Should(Receive(Equal("stal1")))
+		Consistently(installed).ShouldNot(Receive())
+		Eventually(func() map[string]interface{} {
+			return payload("watchtower/containers/stal1/update")
+		}).Should(HaveKeyWithValue("in_progress", true))
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+	})
+})
@@ -0,0 +1,265 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMQTT(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MQTT Suite")
+}
+
+// broker is a fake MQTT broker, recording the connections and the messages published to it
+type broker struct {
+	listener net.Listener
+	// refuse is the return code of the CONNACK packets, accepting the connections if 0
+	refuse byte
+
+	mu            sync.Mutex
+	writeMu       sync.Mutex
+	connects      []string
+	wills         []Message
+	messages      []Message
+	subscriptions []string
+	conns         []net.Conn
+}
+
+func newBroker() *broker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	b := &broker{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns = append(b.conns, conn)
+			b.mu.Unlock()
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *broker) url(user string) string {
+	return fmt.Sprintf("mqtt://%s%s", user, b.listener.Addr())
+}
+
+func (b *broker) close() {
+	_ = b.listener.Close()
+	b.dropConnections()
+}
+
+// dropConnections closes the connections of the clients, as if the network was lost
+func (b *broker) dropConnections() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, conn := range b.conns {
+		_ = conn.Close()
+	}
+	b.conns = nil
+}
+
+func (b *broker) published() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.messages...)
+}
+
+func (b *broker) write(conn net.Conn, packet packets.ControlPacket) {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	_ = packet.Write(conn)
+}
+
+// deliver publishes the message to the connected clients, regardless of their subscriptions
+func (b *broker) deliver(msg Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, conn := range b.conns {
+		publish := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+		publish.TopicName, publish.Payload, publish.Retain = msg.Topic, msg.Payload, msg.Retain
+		b.write(conn, publish)
+	}
+}
+
+func (b *broker) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch packet := packet.(type) {
+		case *packets.ConnectPacket:
+			b.connect(packet)
+			connack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+			connack.ReturnCode = b.refuse
+			b.write(conn, connack)
+		case *packets.PublishPacket:
+			b.mu.Lock()
+			b.messages = append(b.messages, Message{Topic: packet.TopicName, Payload: packet.Payload, QoS: packet.Qos,
+				Retain: packet.Retain})
+			b.mu.Unlock()
+			switch packet.Qos {
+			case 1:
+				puback := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				puback.MessageID = packet.MessageID
+				b.write(conn, puback)
+			case 2:
+				pubrec := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+				pubrec.MessageID = packet.MessageID
+				b.write(conn, pubrec)
+			}
+		case *packets.PubrelPacket:
+			pubcomp := packets.NewControlPacket(packets.Pubcomp).(*packets.PubcompPacket)
+			pubcomp.MessageID = packet.MessageID
+			b.write(conn, pubcomp)
+		case *packets.SubscribePacket:
+			b.mu.Lock()
+			b.subscriptions = append(b.subscriptions, packet.Topics...)
+			b.mu.Unlock()
+			suback := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			suback.MessageID, suback.ReturnCodes = packet.MessageID, packet.Qoss
+			b.write(conn, suback)
+		case *packets.PingreqPacket:
+			b.write(conn, packets.NewControlPacket(packets.Pingresp))
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+// connect records the client ID, the credentials and the will of the CONNECT packet
+func (b *broker) connect(packet *packets.ConnectPacket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	connect := packet.ClientIdentifier
+	if packet.WillFlag {
+		b.wills = append(b.wills, Message{Topic: packet.WillTopic, Payload: packet.WillMessage, QoS: packet.WillQos,
+			Retain: packet.WillRetain})
+	}
+	if packet.UsernameFlag {
+		connect += " " + packet.Username
+	}
+	if packet.PasswordFlag {
+		connect += ":" + string(packet.Password)
+	}
+	b.connects = append(b.connects, connect)
+}
+
+var _ = Describe("the MQTT publisher", func() {
+	var b *broker
+
+	BeforeEach(func() {
+		b = newBroker()
+	})
+	AfterEach(func() {
+		b.close()
+	})
+
+	topics := func() []string {
+		var topics []string
+		for _, msg := range b.published() {
+			topics = append(topics, msg.Topic)
+		}
+		return topics
+	}
+
+	It("should announce that watchtower is online, and have the broker announce when it is not", func() {
+		p, err := New(b.url("user:secret@"), "home/watchtower/", 1, "watchtower-test")
+		Expect(err).NotTo(HaveOccurred())
+		p.Record(types.Event{Type: "session.started", Session: "1"})
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+
+		Expect(b.connects).To(Equal([]string{"watchtower-test user:secret"}))
+		Expect(b.wills).To(Equal([]Message{{Topic: "home/watchtower/status", Payload: []byte("offline"), QoS: 1,
+			Retain: true}}))
+		messages := b.published()
+		Expect(messages).To(HaveLen(3))
+		Expect(messages[0]).To(Equal(Message{Topic: "home/watchtower/status", Payload: []byte("online"), QoS: 1,
+			Retain: true}))
+		Expect(messages[1].Topic).To(Equal("home/watchtower/events/session/started"))
+		Expect(messages[2]).To(Equal(Message{Topic: "home/watchtower/status", Payload: []byte("offline"), QoS: 1,
+			Retain: true}))
+	})
+
+	It("should publish the events, and retain the status of the containers", func() {
+		p, err := New(b.url(""), "watchtower", 2, "")
+		Expect(err).NotTo(HaveOccurred())
+		p.Record(types.Event{Type: "container.recreated", Container: "/web", Image: "nginx:latest"})
+		p.PublishReport(mocks.CreateMockProgressReport(session.UpdatedState, session.FailedState))
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+
+		Expect(topics()).To(Equal([]string{
+			"watchtower/status",
+			"watchtower/events/container/recreated",
+			"watchtower/containers/updt1",
+			"watchtower/containers/fail1",
+			"watchtower/status",
+		}))
+		messages := b.published()
+		var event types.Event
+		Expect(json.Unmarshal(messages[1].Payload, &event)).To(Succeed())
+		Expect(event.Container).To(Equal("/web"))
+		Expect(messages[1].Retain).To(BeFalse())
+		Expect(messages[1].QoS).To(BeEquivalentTo(2))
+
+		var status map[string]interface{}
+		Expect(json.Unmarshal(messages[3].Payload, &status)).To(Succeed())
+		Expect(status).To(HaveKeyWithValue("state", "failed"))
+		Expect(messages[3].Retain).To(BeTrue())
+	})
+
+	It("should connect again once the connection was lost", func() {
+		p, err := New(b.url(""), "watchtower", 1, "")
+		Expect(err).NotTo(HaveOccurred())
+		p.Record(types.Event{Type: "session.started"})
+		Eventually(topics).Should(HaveLen(2))
+
+		b.dropConnections()
+		Eventually(p.client.isConnected).Should(BeFalse())
+		p.Record(types.Event{Type: "session.finished"})
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+
+		Expect(topics()).To(Equal([]string{
+			"watchtower/status",
+			"watchtower/events/session/started",
+			"watchtower/status",
+			"watchtower/events/session/finished",
+			"watchtower/status",
+		}))
+		Expect(b.connects).To(HaveLen(2))
+	})
+
+	It("should return the reason the broker refused the connection", func() {
+		b.refuse = 5
+		c, err := newClient(b.url(""), "watchtower")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Publish(Message{Topic: "watchtower/status"})).To(MatchError(
+			"could not connect to the MQTT broker: not Authorized"))
+	})
+
+	It("should refuse invalid configurations", func() {
+		_, err := New("http://broker", "watchtower", 0, "")
+		Expect(err).To(HaveOccurred())
+		_, err = New(b.url(""), "watchtower/#", 0, "")
+		Expect(err).To(HaveOccurred())
+		_, err = New(b.url(""), "watchtower", 3, "")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,259 @@
+package nats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestNATS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NATS Suite")
+}
+
+// published is a message published to the fake server
+type published struct {
+	subject string
+	headers map[string]string
+	payload string
+}
+
+// connectOptions are the options of the CONNECT sent by the clients, checked by the fake server
+type connectOptions struct {
+	Headers   bool   `json:"headers"`
+	User      string `json:"user"`
+	Pass      string `json:"pass"`
+	AuthToken string `json:"auth_token"`
+}
+
+// server is a fake NATS server, recording the connections and the messages published to it
+type server struct {
+	listener net.Listener
+	// token is the token the clients have to authenticate with, if set
+	token string
+	// denied is the subject the clients are not allowed to publish to
+	denied string
+
+	mu       sync.Mutex
+	connects []connectOptions
+	messages []published
+	conns    []net.Conn
+}
+
+func newServer(token string, denied string) *server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	s := &server{listener: listener, token: token, denied: denied}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *server) url(user string) string {
+	return fmt.Sprintf("nats://%s%s", user, s.listener.Addr())
+}
+
+func (s *server) close() {
+	_ = s.listener.Close()
+	s.dropConnections()
+}
+
+// dropConnections closes the connections of the clients, as if the network was lost
+func (s *server) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *server) published() []published {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]published(nil), s.messages...)
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+	_, _ = fmt.Fprint(conn, `INFO {"server_id":"test","version":"2.10.0","headers":true,"max_payload":1048576}`+"\r\n")
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		op, args, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch op {
+		case "CONNECT":
+			var options connectOptions
+			Expect(json.Unmarshal([]byte(args), &options)).To(Succeed())
+			s.mu.Lock()
+			s.connects = append(s.connects, options)
+			s.mu.Unlock()
+			if s.token != "" && options.AuthToken != s.token {
+				_, _ = fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			_, _ = fmt.Fprint(conn, "PONG\r\n")
+		case "PUB", "HPUB":
+			fields := strings.Fields(args)
+			headerLength := 0
+			if op == "HPUB" {
+				Expect(fields).To(HaveLen(3))
+				headerLength, _ = strconv.Atoi(fields[1])
+			} else {
+				Expect(fields).To(HaveLen(2))
+			}
+			totalLength, _ := strconv.Atoi(fields[len(fields)-1])
+			body := make([]byte, totalLength+2)
+			if _, err := io.ReadFull(reader, body); err != nil {
+				return
+			}
+			if fields[0] == s.denied {
+				_, _ = fmt.Fprintf(conn, "-ERR 'Permissions Violation for Publish to \"%s\"'\r\n", fields[0])
+				continue
+			}
+			msg := published{subject: fields[0], headers: map[string]string{},
+				payload: string(body[headerLength:totalLength])}
+			if op == "HPUB" {
+				lines := strings.Split(string(body[:headerLength]), "\r\n")
+				Expect(lines[0]).To(Equal("NATS/1.0"))
+				for _, header := range lines[1:] {
+					if name, value, found := strings.Cut(header, ": "); found {
+						msg.headers[name] = value
+					}
+				}
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+		}
+	}
+}
+
+var _ = Describe("the NATS publisher", func() {
+	var s *server
+
+	BeforeEach(func() {
+		s = newServer("", "watchtower.denied")
+	})
+	AfterEach(func() {
+		s.close()
+	})
+
+	It("should publish the events and the reports, along with the headers used by JetStream", func() {
+		p, err := New(s.url("user:secret@"), "home.watchtower.")
+		Expect(err).NotTo(HaveOccurred())
+		p.Record(types.Event{Type: "container.recreated", Session: "s1", Container: "/web"})
+		p.PublishReport(mocks.CreateMockProgressReport(session.UpdatedState, session.FailedState))
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+
+		Expect(s.connects).To(HaveLen(1))
+		Expect(s.connects[0].User).To(Equal("user"))
+		Expect(s.connects[0].Pass).To(Equal("secret"))
+		Expect(s.connects[0].Headers).To(BeTrue())
+
+		messages := s.published()
+		Expect(messages).To(HaveLen(2))
+		Expect(messages[0].subject).To(Equal("home.watchtower.events.container.recreated"))
+		Expect(messages[0].headers).To(HaveKeyWithValue("Watchtower-Type", "container.recreated"))
+		Expect(messages[0].headers).To(HaveKeyWithValue("Watchtower-Session", "s1"))
+		Expect(messages[0].headers).To(HaveKeyWithValue("Content-Type", "application/json"))
+		Expect(messages[0].headers["Nats-Msg-Id"]).To(HaveLen(32))
+		var event types.Event
+		Expect(json.Unmarshal([]byte(messages[0].payload), &event)).To(Succeed())
+		Expect(event.Container).To(Equal("/web"))
+
+		Expect(messages[1].subject).To(Equal("home.watchtower.report"))
+		Expect(messages[1].headers).To(HaveKeyWithValue("Watchtower-Type", "report"))
+		Expect(messages[1].headers).NotTo(HaveKey("Watchtower-Session"))
+		Expect(messages[1].headers["Nats-Msg-Id"]).NotTo(Equal(messages[0].headers["Nats-Msg-Id"]))
+		var report struct {
+			Updated []map[string]interface{} `json:"updated"`
+			Failed  []map[string]interface{} `json:"failed"`
+		}
+		Expect(json.Unmarshal([]byte(messages[1].payload), &report)).To(Succeed())
+		Expect(report.Updated[0]).To(HaveKeyWithValue("name", "updt1"))
+		Expect(report.Failed[0]).To(HaveKeyWithValue("name", "fail1"))
+	})
+
+	It("should connect again once the connection was lost", func() {
+		p, err := New(s.url(""), "watchtower")
+		Expect(err).NotTo(HaveOccurred())
+		p.Record(types.Event{Type: "session.started"})
+		Eventually(s.published).Should(HaveLen(1))
+
+		s.dropConnections()
+		p.Record(types.Event{Type: "session.finished"})
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+
+		messages := s.published()
+		Expect(messages).To(HaveLen(2))
+		Expect(messages[1].subject).To(Equal("watchtower.events.session.finished"))
+		Expect(s.connects).To(HaveLen(2))
+	})
+
+	It("should report the errors of the server without disconnecting", func() {
+		errs := make(chan error, 1)
+		u, _ := parseServer(s.url(""))
+		conn, err := dial(u, time.Second, func(err error) { errs <- err })
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		Expect(publish(conn, Message{Subject: "watchtower.denied", Payload: []byte("{}")}, time.Second)).To(Succeed())
+		Eventually(errs).Should(Receive(MatchError(ContainSubstring(
+			`Permissions Violation for Publish to "watchtower.denied"`))))
+		Expect(publish(conn, Message{Subject: "watchtower.allowed", Payload: []byte("{}")}, time.Second)).To(Succeed())
+		Expect(s.published()).To(HaveLen(1))
+		Expect(s.connects).To(HaveLen(1))
+	})
+
+	It("should authenticate using the token", func() {
+		s.close()
+		s = newServer("s3cr3t", "")
+		p, err := New(s.url("s3cr3t@"), "watchtower")
+		Expect(err).NotTo(HaveOccurred())
+		p.Record(types.Event{Type: "session.started"})
+		Expect(p.Close(5 * time.Second)).To(Succeed())
+		Expect(s.published()).To(HaveLen(1))
+
+		u, _ := parseServer(s.url("wrong@"))
+		_, err = dial(u, time.Second, func(error) {})
+		Expect(err).To(MatchError(ContainSubstring("could not connect to the NATS server: nats: Authorization Violation")))
+	})
+
+	It("should refuse invalid configurations", func() {
+		_, err := New("http://server", "watchtower")
+		Expect(err).To(HaveOccurred())
+		_, err = New(s.url(""), "watchtower.>")
+		Expect(err).To(HaveOccurred())
+		_, err = New(s.url(""), "watch tower")
+		Expect(err).To(HaveOccurred())
+		_, err = New(s.url(""), "")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,107 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	t "github.com/containrrr/watchtower/pkg/types"
+)
+
+// phasesParam is the query parameter of the notification URLs selecting which notifications they receive. It is
+// removed from the URLs before they are passed to shoutrrr.
+const phasesParam = "watchtower-phases"
+
+// The notification phases: updates being found for the first time, and the report of the session applying them
+const (
+	phaseAvailable = "available"
+	phaseApplied   = "applied"
+)
+
+const updatesAvailableTemplate = `
+{{- range .Updates}}
+{{- .Name}} ({{.ImageName}}): {{.CurrentImageID.ShortID}} can be updated to {{.LatestImageID.ShortID}}
+{{- with .Release.URL}} ({{.}}){{end}}
+{{end -}}
+{{- if .AwaitingApproval -}}
+The updates are waiting for approval.
+{{- else if not .ApplyAt.IsZero -}}
+The updates are scheduled to be applied at {{.ApplyAt.Format "2006-01-02 15:04:05 -0700 MST"}}.
+{{- end -}}`
+
+var availableTemplate = template.Must(template.New("available").Parse(updatesAvailableTemplate))
+
+// AvailableData is the template data model of the notifications of the updates found for the first time
+type AvailableData struct {
+	StaticData
+	t.UpdatesAvailable
+}
+
+// splitPhases returns the URLs receiving the session reports and the ones receiving the notifications of the
+// updates found for the first time, as selected by their phases parameter. URLs without it receive the session
+// reports, as well as the available updates if available is set.
+func splitPhases(urls []string, available bool) (applied []string, availableUrls []string, err error) {
+	for _, rawURL := range urls {
+		clean, phases, err := parsePhases(rawURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		if phases == nil {
+			phases = map[string]bool{phaseApplied: true, phaseAvailable: available}
+		}
+		if phases[phaseApplied] {
+			applied = append(applied, clean)
+		}
+		if phases[phaseAvailable] {
+			availableUrls = append(availableUrls, clean)
+		}
+	}
+	return applied, availableUrls, nil
+}
+
+// parsePhases removes the phases parameter from the URL, returning the phases it selects, or nil if it has none
+func parsePhases(rawURL string) (string, map[string]bool, error) {
+	if !strings.Contains(rawURL, phasesParam) {
+		return rawURL, nil, nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid notification URL for %s: %w", GetScheme(rawURL), err)
+	}
+	query := parsed.Query()
+	values, found := query[phasesParam]
+	if !found {
+		return rawURL, nil, nil
+	}
+
+	phases := map[string]bool{}
+	for _, value := range values {
+		for _, phase := range strings.Split(value, ",") {
+			switch phase = strings.TrimSpace(phase); phase {
+			case phaseAvailable, phaseApplied:
+				phases[phase] = true
+			case "":
+			default:
+				return "", nil, fmt.Errorf("unknown notification phase %q for %s", phase, GetScheme(rawURL))
+			}
+		}
+	}
+	query.Del(phasesParam)
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), phases, nil
+}
+
+// SendUpdatesAvailable sends the updates found for the first time to the URLs receiving them, if there are any
+func (n *shoutrrrTypeNotifier) SendUpdatesAvailable(available t.UpdatesAvailable) {
+	if n.AvailableRouter == nil || len(available.Updates) == 0 {
+		return
+	}
+	var body bytes.Buffer
+	if err := availableTemplate.Execute(&body, AvailableData{n.data, available}); err != nil {
+		LocalLog.WithError(err).Error("Could not build the notification of the available updates")
+		return
+	}
+	n.messages <- message{body: body.String(), available: true}
+}
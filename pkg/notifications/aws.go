@@ -0,0 +1,52 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// awsTimeout limits how long a request to the API of AWS, including resolving the credentials it is signed with, may
+// take
+const awsTimeout = 30 * time.Second
+
+// loadAWSConfig returns the configuration of the AWS SDK, which resolves the region, unless set, and the credentials
+// the same way the AWS CLI does, using the profile of the shared configuration if set. The credentials are resolved
+// once the first request is sent, and temporary ones are kept until they expire.
+func loadAWSConfig(region string, profile string) (aws.Config, error) {
+	var options []func(*config.LoadOptions) error
+	if region != "" {
+		options = append(options, config.WithRegion(region))
+	}
+	if profile != "" {
+		options = append(options, config.WithSharedConfigProfile(profile))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), options...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("could not load the AWS configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// awsEndpoint returns the regional endpoint of the AWS service
+func awsEndpoint(service string, region string) string {
+	if strings.HasPrefix(region, "cn-") {
+		return fmt.Sprintf("https://%s.%s.amazonaws.com.cn", service, region)
+	}
+	return fmt.Sprintf("https://%s.%s.amazonaws.com", service, region)
+}
+
+// awsPartition returns the partition of the region, which the ARNs of its resources start with
+func awsPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	}
+	return "aws"
+}
@@ -0,0 +1,94 @@
+package notifications
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// setAWSEnvironment sets the variables of the AWS SDK to the values, and unsets the other ones it reads, so that the
+// configuration of the host does not leak into the tests. It returns the function restoring the environment.
+func setAWSEnvironment(values map[string]string) func() {
+	previous := map[string]*string{}
+	for _, key := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE", "AWS_REGION",
+		"AWS_DEFAULT_REGION", "AWS_CONFIG_FILE", "AWS_SHARED_CREDENTIALS_FILE", "AWS_EC2_METADATA_DISABLED",
+		"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+	} {
+		if value, set := os.LookupEnv(key); set {
+			previous[key] = &value
+		} else {
+			previous[key] = nil
+		}
+		_ = os.Unsetenv(key)
+	}
+	for key, value := range values {
+		if _, known := previous[key]; !known {
+			previous[key] = nil
+		}
+		_ = os.Setenv(key, value)
+	}
+	return func() {
+		for key, value := range previous {
+			if value == nil {
+				_ = os.Unsetenv(key)
+			} else {
+				_ = os.Setenv(key, *value)
+			}
+		}
+	}
+}
+
+var _ = Describe("the AWS configuration", func() {
+	var dir string
+	var restore func()
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-aws")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, "config"), []byte(
+			"[default]\nregion = eu-west-1\n\n[profile watchtower]\nregion = eu-central-1\n"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "credentials"), []byte(
+			"[default]\naws_access_key_id = default\naws_secret_access_key = default\n\n"+
+				"[watchtower]\naws_access_key_id = id\naws_secret_access_key = secret\n"), 0600)).To(Succeed())
+		restore = setAWSEnvironment(map[string]string{
+			"AWS_CONFIG_FILE":             filepath.Join(dir, "config"),
+			"AWS_SHARED_CREDENTIALS_FILE": filepath.Join(dir, "credentials"),
+			"AWS_EC2_METADATA_DISABLED":   "true",
+		})
+	})
+	AfterEach(func() {
+		restore()
+		_ = os.RemoveAll(dir)
+	})
+
+	It("should use the region and the credentials of the profile", func() {
+		cfg, err := loadAWSConfig("", "watchtower")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Region).To(Equal("eu-central-1"))
+		credentials, err := cfg.Credentials.Retrieve(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials.AccessKeyID).To(Equal("id"))
+	})
+
+	It("should prefer the region of the URL and the credentials of the environment", func() {
+		Expect(os.Setenv("AWS_ACCESS_KEY_ID", "env")).To(Succeed())
+		Expect(os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")).To(Succeed())
+		cfg, err := loadAWSConfig("us-east-1", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Region).To(Equal("us-east-1"))
+		credentials, err := cfg.Credentials.Retrieve(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials.AccessKeyID).To(Equal("env"))
+	})
+
+	It("should refuse missing profiles", func() {
+		_, err := loadAWSConfig("", "missing")
+		Expect(err).To(MatchError(ContainSubstring("could not load the AWS configuration")))
+	})
+})
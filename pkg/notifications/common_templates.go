@@ -6,18 +6,41 @@ var commonTemplates = map[string]string{
 	`default`: `
 {{- if .Report -}}
   {{- with .Report -}}
-    {{- if ( or .Updated .Failed ) -}}
+    {{- if ( or .Updated .Failed .Retrying ) -}}
 {{len .Scanned}} Scanned, {{len .Updated}} Updated, {{len .Failed}} Failed
+      {{- with .Retrying}}, {{len .}} Retrying{{end}}
+      {{- with .Quarantined}}, {{len .}} Quarantined{{end}}
+      {{- with .PulledBytes}}, {{HumanSize .}} Pulled{{end}}
       {{- range .Updated}}
 - {{.Name}} ({{.ImageName}}): {{.CurrentImageID.ShortID}} updated to {{.LatestImageID.ShortID}}
+        {{- with .Release.URL}} ({{.}}){{end}}
+        {{- if .LatestReference}}
+  {{.CurrentReference}} → {{.LatestReference}}
+          {{- with .CompareURL}} ({{.}}){{end}}
+        {{- end}}
+        {{- with .Release.Notes}}
+{{.}}
+        {{- end -}}
+        {{- range .ImageChanges}}
+  - {{.}}
+        {{- end -}}
       {{- end -}}
       {{- range .Fresh}}
 - {{.Name}} ({{.ImageName}}): {{.State}}
 	  {{- end -}}
 	  {{- range .Skipped}}
-- {{.Name}} ({{.ImageName}}): {{.State}}: {{.Error}}
+- {{.Name}} ({{.ImageName}}): {{.State}} ({{.SkipReason}}): {{.Error}}
 	  {{- end -}}
 	  {{- range .Failed}}
+- {{.Name}} ({{.ImageName}}): {{.State}}: {{.Error}}
+        {{- with .Logs}}
+{{.}}
+        {{- end -}}
+	  {{- end -}}
+	  {{- range .Retrying}}
+- {{.Name}} ({{.ImageName}}): {{.State}}: {{.Error}}
+	  {{- end -}}
+	  {{- range .Quarantined}}
 - {{.Name}} ({{.ImageName}}): {{.State}}: {{.Error}}
 	  {{- end -}}
     {{- end -}}
@@ -30,10 +53,13 @@ var commonTemplates = map[string]string{
 {{- if .Report -}}
   {{- range .Report.All }}
     {{- .Name}} ({{.ImageName}}): {{.State -}}
+    {{- with .SkipReason}} Reason: {{.}}{{end}}
     {{- with .Error}} Error: {{.}}{{end}}{{ println }}
   {{- else -}}
     no containers matched filter
   {{- end -}}
 {{- end -}}`,
+
+	`json.v1`: `{{ . | ToJSON }}`,
 }
 
@@ -19,6 +19,11 @@ type emailTypeNotifier struct {
 	Server, User, Password, SubjectTag string
 	Port                               int
 	tlsSkipVerify                      bool
+	HTMLTemplate                       string
+	AttachReport                       bool
+	OAuth2TokenURL, OAuth2ClientID     string
+	OAuth2ClientSecret, OAuth2Refresh  string
+	OAuth2Scope                        string
 	entries                            []*log.Entry
 	logLevels                          []log.Level
 	delay                              time.Duration
@@ -36,6 +41,13 @@ func newEmailNotifier(c *cobra.Command, acceptedLogLevels []log.Level) t.Convert
 	tlsSkipVerify, _ := flags.GetBool("notification-email-server-tls-skip-verify")
 	delay, _ := flags.GetInt("notification-email-delay")
 	subjecttag, _ := flags.GetString("notification-email-subjecttag")
+	htmlTemplate, _ := flags.GetString("notification-email-html-template")
+	attachReport, _ := flags.GetBool("notification-email-attach-report")
+	oauth2TokenURL, _ := flags.GetString("notification-email-oauth2-token-url")
+	oauth2ClientID, _ := flags.GetString("notification-email-oauth2-client-id")
+	oauth2ClientSecret, _ := flags.GetString("notification-email-oauth2-client-secret")
+	oauth2Refresh, _ := flags.GetString("notification-email-oauth2-refresh-token")
+	oauth2Scope, _ := flags.GetString("notification-email-oauth2-scope")
 
 	n := &emailTypeNotifier{
 		entries:       []*log.Entry{},
@@ -49,6 +61,14 @@ func newEmailNotifier(c *cobra.Command, acceptedLogLevels []log.Level) t.Convert
 		logLevels:     acceptedLogLevels,
 		delay:         time.Duration(delay) * time.Second,
 		SubjectTag:    subjecttag,
+		HTMLTemplate:  htmlTemplate,
+		AttachReport:  attachReport,
+
+		OAuth2TokenURL:     oauth2TokenURL,
+		OAuth2ClientID:     oauth2ClientID,
+		OAuth2ClientSecret: oauth2ClientSecret,
+		OAuth2Refresh:      oauth2Refresh,
+		OAuth2Scope:        oauth2Scope,
 	}
 
 	return n
@@ -81,6 +101,11 @@ func (e *emailTypeNotifier) GetURL(c *cobra.Command, title string) (string, erro
 	return conf.GetURL().String(), nil
 }
 
+// sendsItself returns whether watchtower sends the emails itself, as they use features that shoutrrr does not support
+func (e *emailTypeNotifier) sendsItself() bool {
+	return e.HTMLTemplate != "" || e.AttachReport || e.OAuth2TokenURL != ""
+}
+
 func (e *emailTypeNotifier) GetDelay() time.Duration {
 	return e.delay
 }
@@ -1,10 +1,19 @@
 package notifications
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	shoutrrrGotify "github.com/containrrr/shoutrrr/pkg/services/gotify"
+	"github.com/containrrr/shoutrrr/pkg/types"
 	t "github.com/containrrr/watchtower/pkg/types"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -75,6 +84,140 @@ func (n *gotifyTypeNotifier) GetURL(c *cobra.Command, title string) (string, err
 		Title:      title,
 		Token:      n.gotifyAppToken,
 	}
+	u := config.GetURL()
+
+	// Clicking the notifications opens the dashboard, when it is reachable
+	publicURL, _ := c.PersistentFlags().GetString("http-api-public-url")
+	if dashboard, _ := c.PersistentFlags().GetBool("http-api-dashboard"); dashboard && publicURL != "" {
+		query := u.Query()
+		query.Set("clickurl", strings.TrimSuffix(publicURL, "/")+"/dashboard")
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String(), nil
+}
+
+// gotifyTimeout limits how long sending a message to Gotify may take
+const gotifyTimeout = 10 * time.Second
+
+// gotifyPriorities are the default priorities of the messages of the sessions by their severity. Failures notify
+// loudly, while clean updates only show up in the list of messages.
+var gotifyPriorities = map[severity]int{
+	severityUpdated: 2,
+	severityWarning: 5,
+	severityFailed:  8,
+}
+
+// gotifyService sends the notifications to Gotify, rather than shoutrrr, to pick the priority of the messages by the
+// severity of the session, and to add the extras opening a page when the notification is clicked. It uses the same
+// URLs as shoutrrr, along with the priorities of the severities and the click URL, like
+// gotify://gotify.example.com/token?failedpriority=10&clickurl=https://watchtower.example.com/dashboard.
+type gotifyService struct {
+	config     *shoutrrrGotify.Config
+	priorities map[severity]int
+	clickURL   string
+	client     *http.Client
+}
+
+// gotifyMessage is the payload of the messages sent to Gotify
+type gotifyMessage struct {
+	Message  string                 `json:"message"`
+	Title    string                 `json:"title"`
+	Priority int                    `json:"priority"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+// newGotifyService returns the service sending to the application of the URL
+func newGotifyService(u *url.URL) (service, error) {
+	s := &gotifyService{priorities: map[severity]int{}}
+
+	// the parameters of watchtower are removed, as shoutrrr refuses unknown parameters
+	query := u.Query()
+	priorities := severityParams(query, "priority")["priority"]
+	s.clickURL = query.Get("clickurl")
+	query.Del("clickurl")
+	base := *u
+	base.RawQuery = query.Encode()
+	s.config = &shoutrrrGotify.Config{}
+	if err := s.config.SetURL(&base); err != nil {
+		return nil, err
+	}
+	if s.config.Token == "" {
+		return nil, fmt.Errorf("the gotify URL has no application token")
+	}
+	if s.clickURL != "" {
+		if _, err := url.ParseRequestURI(s.clickURL); err != nil {
+			return nil, fmt.Errorf("invalid gotify click URL: %w", err)
+		}
+	}
+
+	s.priorities[severityNone] = s.config.Priority
+	for sev, priority := range gotifyPriorities {
+		s.priorities[sev] = priority
+		if value, found := priorities[sev]; found {
+			var err error
+			if s.priorities[sev], err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("invalid gotify priority %q: %w", value, err)
+			}
+		}
+	}
 
-	return config.GetURL().String(), nil
+	s.client = &http.Client{
+		Transport: &http.Transport{
+			// Like shoutrrr, as Gotify may redirect HTTP to HTTPS
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: s.config.DisableTLS},
+		},
+		Timeout: gotifyTimeout,
+	}
+	return s, nil
+}
+
+// Send implements service, sending the message using the priority of the severity of the report
+func (s *gotifyService) Send(message string, report t.Report, params *types.Params) error {
+	title := s.config.Title
+	if params != nil {
+		if paramsTitle, found := params.Title(); found && paramsTitle != "" {
+			title = paramsTitle
+		}
+	}
+	if title == "" {
+		title = "Watchtower"
+	}
+	msg := gotifyMessage{
+		Message:  message,
+		Title:    title,
+		Priority: s.priorities[reportSeverity(report)],
+	}
+	if s.clickURL != "" {
+		msg.Extras = map[string]interface{}{
+			"client::notification": map[string]interface{}{"click": map[string]string{"url": s.clickURL}},
+		}
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	scheme := "https"
+	if s.config.DisableTLS {
+		scheme = "http"
+	}
+	endpoint := fmt.Sprintf("%s://%s%s/message", scheme, s.config.Host, strings.TrimSuffix(s.config.Path, "/"))
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// the token is sent as a header, so that it does not end up in the errors and the logs along with the URL
+	req.Header.Set("X-Gotify-Key", s.config.Token)
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification to Gotify: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("gotify refused the message: %s %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
 }
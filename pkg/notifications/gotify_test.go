@@ -0,0 +1,104 @@
+package notifications
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/containrrr/shoutrrr/pkg/types"
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	s "github.com/containrrr/watchtower/pkg/session"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the Gotify service", func() {
+	const token = "Aaa.bbb.ccc.ddd"
+	var server *httptest.Server
+	var sent []gotifyMessage
+
+	BeforeEach(func() {
+		sent = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/gotify/message"))
+			if r.Header.Get("X-Gotify-Key") != token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var msg gotifyMessage
+			Expect(json.NewDecoder(r.Body).Decode(&msg)).To(Succeed())
+			sent = append(sent, msg)
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newTestService := func(query string) (service, error) {
+		u, err := url.Parse("gotify://" + strings.TrimPrefix(server.URL, "http://") + "/gotify/" + token + "?disabletls=yes&" + query)
+		Expect(err).NotTo(HaveOccurred())
+		return newGotifyService(u)
+	}
+	params := func(title string) *types.Params {
+		p := &types.Params{}
+		p.SetTitle(title)
+		return p
+	}
+
+	It("should pick the priority by the severity of the report", func() {
+		svc, err := newTestService("")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(svc.Send("failed", mocks.CreateMockProgressReport(s.UpdatedState, s.FailedState), params("Updates"))).To(Succeed())
+		Expect(svc.Send("skipped", mocks.CreateMockProgressReport(s.SkippedState), nil)).To(Succeed())
+		Expect(svc.Send("updated", mocks.CreateMockProgressReport(s.UpdatedState), nil)).To(Succeed())
+		Expect(svc.Send("started", nil, nil)).To(Succeed())
+
+		Expect(sent).To(HaveLen(4))
+		Expect(sent[0].Title).To(Equal("Updates"))
+		var priorities []int
+		for _, msg := range sent {
+			priorities = append(priorities, msg.Priority)
+			Expect(msg.Extras).To(BeNil())
+		}
+		Expect(priorities).To(Equal([]int{8, 5, 2, 0}))
+	})
+
+	It("should use the priorities of the URL", func() {
+		svc, err := newTestService("priority=1&failedpriority=10&updatedpriority=3")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(svc.Send("failed", mocks.CreateMockProgressReport(s.FailedState), nil)).To(Succeed())
+		Expect(svc.Send("updated", mocks.CreateMockProgressReport(s.UpdatedState), nil)).To(Succeed())
+		Expect(svc.Send("started", nil, nil)).To(Succeed())
+		Expect([]int{sent[0].Priority, sent[1].Priority, sent[2].Priority}).To(Equal([]int{10, 3, 1}))
+	})
+
+	It("should add the click URL to the extras", func() {
+		svc, err := newTestService("clickurl=https://watchtower.example.com/dashboard")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(svc.Send("updated", nil, nil)).To(Succeed())
+		Expect(sent[0].Extras).To(Equal(map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]interface{}{"url": "https://watchtower.example.com/dashboard"},
+			},
+		}))
+	})
+
+	It("should refuse invalid parameters", func() {
+		for _, query := range []string{"failedpriority=high", "clickurl=dashboard", "unknown=1"} {
+			_, err := newTestService(query)
+			Expect(err).To(HaveOccurred(), query)
+		}
+	})
+
+	It("should return the errors of Gotify", func() {
+		u, _ := url.Parse("gotify://" + strings.TrimPrefix(server.URL, "http://") + "/gotify/Awrong.token.xx?disabletls=yes")
+		svc, err := newGotifyService(u)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(svc.Send("updated", nil, nil)).To(MatchError(ContainSubstring("401")))
+	})
+})
@@ -0,0 +1,46 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// entryJSON is the JSON representation of a log entry
+type entryJSON struct {
+	Message string                 `json:"message"`
+	Level   string                 `json:"level"`
+	Time    time.Time              `json:"time"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, representing the report as it is marshalled by the session
+func (d Data) MarshalJSON() ([]byte, error) {
+	entries := make([]entryJSON, len(d.Entries))
+	for i, entry := range d.Entries {
+		data := make(map[string]interface{}, len(entry.Data))
+		for key, value := range entry.Data {
+			// Errors have no exported fields, and would be marshalled as empty objects
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			data[key] = value
+		}
+		entries[i] = entryJSON{Message: entry.Message, Level: entry.Level.String(), Time: entry.Time, Data: data}
+	}
+	return json.Marshal(map[string]interface{}{
+		"title":   d.Title,
+		"host":    d.Host,
+		"entries": entries,
+		"report":  d.Report,
+	})
+}
+
+// toJSON marshals the value as JSON, for use in templates
+func toJSON(value interface{}) string {
+	bytes, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("failed to marshal JSON in notification template: %v", err)
+	}
+	return string(bytes)
+}
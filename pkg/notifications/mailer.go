@@ -0,0 +1,342 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// mailerScheme identifies the emails sent by the mailer in the names of the notification services and the events
+const mailerScheme = "smtp://"
+
+// mailerDialTimeout limits how long connecting to the SMTP server may take
+const mailerDialTimeout = 30 * time.Second
+
+// reportAttachmentName is the name of the file the session report is attached as
+const reportAttachmentName = "watchtower-report.json"
+
+// mailer sends the notification emails itself, rather than through shoutrrr, to add an HTML alternative to the plain
+// text body, to attach the session report as JSON, and to authenticate using OAuth2
+type mailer struct {
+	from    string
+	to      []string
+	host    string
+	port    int
+	user    string
+	pass    string
+	subject string
+	// tlsSkipVerify skips verifying the certificate of the server when upgrading the connection to TLS
+	tlsSkipVerify bool
+	// html is the template of the HTML alternative, if any
+	html *htmltemplate.Template
+	// legacy passes the log entries to the HTML template, rather than the complete notification data
+	legacy       bool
+	attachReport bool
+	// oauth2 provides the access tokens authenticating the user instead of the password, if set
+	oauth2 *oauth2TokenSource
+}
+
+// mail is a notification email to send
+type mail struct {
+	text   string
+	html   string
+	report []byte
+}
+
+// newMailer returns the mailer sending the notification emails configured using the legacy email flags, if they ask
+// for an HTML alternative, the attached report or OAuth2 authentication, which shoutrrr cannot provide. Otherwise it returns nil, leaving the
+// emails to shoutrrr. The HTML template is either the name of one of the templates of the config file, or the
+// template itself.
+func newMailer(c *cobra.Command, title string, templates map[string]string, legacy bool) (*mailer, error) {
+	f := c.PersistentFlags()
+	if types, _ := f.GetStringSlice("notifications"); !containsString(types, emailType) {
+		return nil, nil
+	}
+	email := newEmailNotifier(c, []log.Level{}).(*emailTypeNotifier)
+	if !email.sendsItself() {
+		return nil, nil
+	}
+
+	m := &mailer{
+		from:          email.From,
+		host:          email.Server,
+		port:          email.Port,
+		user:          email.User,
+		pass:          email.Password,
+		subject:       email.getSubject(c, title),
+		tlsSkipVerify: email.tlsSkipVerify,
+		legacy:        legacy,
+		attachReport:  email.AttachReport,
+	}
+	for _, to := range strings.Split(email.To, ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			m.to = append(m.to, to)
+		}
+	}
+	if email.HTMLTemplate != "" {
+		text := email.HTMLTemplate
+		if named, found := templates[text]; found {
+			text = named
+		}
+		tpl, err := htmltemplate.New("").Funcs(htmltemplate.FuncMap(templateFuncs())).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid email HTML template: %w", err)
+		}
+		m.html = tpl
+	}
+	if email.OAuth2TokenURL != "" {
+		if email.User == "" || email.OAuth2ClientID == "" {
+			return nil, errors.New("OAuth2 authentication of the notification emails requires the user and the client ID")
+		}
+		if _, err := url.ParseRequestURI(email.OAuth2TokenURL); err != nil {
+			return nil, fmt.Errorf("invalid OAuth2 token URL: %w", err)
+		}
+		m.oauth2 = newOAuth2TokenSource(email.OAuth2TokenURL, email.OAuth2ClientID, email.OAuth2ClientSecret,
+			email.OAuth2Refresh, email.OAuth2Scope)
+	}
+	return m, nil
+}
+
+// containsString returns whether the value is one of the values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// build builds the email of the notification with the text body. The report is only attached to the notifications of
+// a session.
+func (m *mailer) build(data Data, text string) (mail, error) {
+	msg := mail{text: text}
+	if m.html != nil {
+		var templateData interface{} = data
+		if m.legacy {
+			templateData = data.Entries
+		}
+		var body bytes.Buffer
+		if err := m.html.Execute(&body, templateData); err != nil {
+			return msg, err
+		}
+		msg.html = body.String()
+	}
+	if m.attachReport && data.Report != nil {
+		report, err := json.MarshalIndent(data.Report, "", "  ")
+		if err != nil {
+			return msg, err
+		}
+		msg.report = report
+	}
+	return msg, nil
+}
+
+// compose returns the content of the email, being either only the text body, the text body with its HTML alternative,
+// or either of them along with the attached report
+func (m *mailer) compose(msg mail, now time.Time) ([]byte, error) {
+	var content bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", m.from)
+	header.Set("To", strings.Join(m.to, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", m.subject))
+	header.Set("Date", now.Format(time.RFC1123Z))
+	header.Set("MIME-Version", "1.0")
+
+	bodyHeader, body, err := composeBody(msg)
+	if err != nil {
+		return nil, err
+	}
+	if msg.report == nil {
+		for key, values := range bodyHeader {
+			header[key] = values
+		}
+		writeHeader(&content, header)
+		content.Write(body)
+		return content.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&content)
+	header.Set("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	writeHeader(&content, header)
+	part, err := mixed.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(body); err != nil {
+		return nil, err
+	}
+
+	part, err = mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/json"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", reportAttachmentName)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(msg.report)
+	for len(encoded) > 76 {
+		fmt.Fprintf(part, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(part, "%s\r\n", encoded)
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return content.Bytes(), nil
+}
+
+// composeBody returns the header describing the body, along with the body, which is either the text, or the text
+// along with its HTML alternative
+func composeBody(msg mail) (textproto.MIMEHeader, []byte, error) {
+	var body bytes.Buffer
+	if msg.html == "" {
+		header := textproto.MIMEHeader{
+			"Content-Type":              {`text/plain; charset="utf-8"`},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		}
+		err := writeQuotedPrintable(&body, msg.text)
+		return header, body.Bytes(), err
+	}
+
+	alternative := multipart.NewWriter(&body)
+	for _, p := range []struct{ contentType, content string }{
+		{`text/plain; charset="utf-8"`, msg.text},
+		{`text/html; charset="utf-8"`, msg.html},
+	} {
+		part, err := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := writeQuotedPrintable(part, p.content); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := alternative.Close(); err != nil {
+		return nil, nil, err
+	}
+	header := textproto.MIMEHeader{"Content-Type": {"multipart/alternative; boundary=" + alternative.Boundary()}}
+	return header, body.Bytes(), nil
+}
+
+// writeHeader writes the header, followed by the empty line separating it from the body
+func writeHeader(w *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, key := range []string{"From", "To", "Subject", "Date", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if value := header.Get(key); value != "" {
+			fmt.Fprintf(w, "%s: %s\r\n", key, value)
+		}
+	}
+	w.WriteString("\r\n")
+}
+
+// writeQuotedPrintable writes the content using the quoted-printable encoding
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// send sends the email through the SMTP server, using implicit TLS on port 465, and upgrading the connection using
+// STARTTLS on the other ports if the server supports it
+func (m *mailer) send(msg mail) error {
+	content, err := m.compose(msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("could not compose the notification email: %w", err)
+	}
+
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	tlsConfig := &tls.Config{ServerName: m.host, InsecureSkipVerify: m.tlsSkipVerify}
+	var conn net.Conn
+	if m.port == 465 {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: mailerDialTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, mailerDialTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("could not connect to the SMTP server: %w", err)
+	}
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("could not connect to the SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if m.port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("could not upgrade the connection to the SMTP server to TLS: %w", err)
+			}
+		}
+	}
+	if err := m.authenticate(client); err != nil {
+		return err
+	}
+	if err := client.Mail(m.from); err != nil {
+		return fmt.Errorf("the SMTP server refused the sender: %w", err)
+	}
+	for _, to := range m.to {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("the SMTP server refused the recipient %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("could not send the notification email: %w", err)
+	}
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("could not send the notification email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("could not send the notification email: %w", err)
+	}
+	return client.Quit()
+}
+
+// authenticate authenticates the user with the SMTP server, using either the access token or the password
+func (m *mailer) authenticate(client *smtp.Client) error {
+	if m.oauth2 == nil {
+		if m.user == "" {
+			return nil
+		}
+		if err := client.Auth(smtp.PlainAuth("", m.user, m.pass, m.host)); err != nil {
+			return fmt.Errorf("could not authenticate with the SMTP server: %w", err)
+		}
+		return nil
+	}
+
+	token, err := m.oauth2.accessToken()
+	if err != nil {
+		return err
+	}
+	if err := client.Auth(&xoauth2Auth{user: m.user, token: token, host: m.host}); err != nil {
+		// the token may have been revoked, so the next email uses a new one
+		m.oauth2.forget()
+		return fmt.Errorf("could not authenticate with the SMTP server using OAuth2: %w", err)
+	}
+	return nil
+}
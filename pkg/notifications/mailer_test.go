@@ -0,0 +1,131 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"strings"
+	"time"
+
+	s "github.com/containrrr/watchtower/pkg/session"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the mailer", func() {
+	newTestMailer := func(html string, attachReport bool) *mailer {
+		m := &mailer{
+			from:         "watchtower@example.com",
+			to:           []string{"admin@example.com", "ops@example.com"},
+			subject:      "Watchtower updates",
+			attachReport: attachReport,
+		}
+		if html != "" {
+			m.html = htmltemplate.Must(htmltemplate.New("").Funcs(htmltemplate.FuncMap(templateFuncs())).Parse(html))
+		}
+		return m
+	}
+	// parts parses the email, returning its parts by content type, along with the raw content of the report
+	parts := func(content []byte) (map[string]string, []byte) {
+		msg, err := netmail.ReadMessage(bytes.NewReader(content))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msg.Header.Get("To")).To(Equal("admin@example.com, ops@example.com"))
+		found := map[string]string{}
+		var report []byte
+		var walk func(contentType string, body io.Reader)
+		walk = func(contentType string, body io.Reader) {
+			mediaType, params, err := mime.ParseMediaType(contentType)
+			Expect(err).NotTo(HaveOccurred())
+			reader := multipart.NewReader(body, params["boundary"])
+			for {
+				part, err := reader.NextPart()
+				if err == io.EOF {
+					return
+				}
+				Expect(err).NotTo(HaveOccurred())
+				partType := part.Header.Get("Content-Type")
+				if part.FileName() != "" {
+					Expect(mediaType).To(Equal("multipart/mixed"))
+					Expect(part.FileName()).To(Equal(reportAttachmentName))
+					report, err = io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+					Expect(err).NotTo(HaveOccurred())
+					continue
+				}
+				if strings.HasPrefix(partType, "multipart/") {
+					walk(partType, part)
+					continue
+				}
+				text, err := io.ReadAll(part)
+				Expect(err).NotTo(HaveOccurred())
+				found[partType] = string(text)
+			}
+		}
+		walk(msg.Header.Get("Content-Type"), msg.Body)
+		return found, report
+	}
+
+	It("should send the HTML alternative along with the text", func() {
+		m := newTestMailer(`<p>{{ len .Report.Fresh }} fresh</p>`, false)
+		data := mockDataFromStates(s.FreshState)
+		msg, err := m.build(data, "1 fresh")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msg.report).To(BeNil())
+
+		content, err := m.compose(msg, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		found, report := parts(content)
+		Expect(found).To(Equal(map[string]string{
+			`text/plain; charset="utf-8"`: "1 fresh",
+			`text/html; charset="utf-8"`:  "<p>1 fresh</p>",
+		}))
+		Expect(report).To(BeNil())
+	})
+
+	It("should attach the report as JSON", func() {
+		m := newTestMailer("", true)
+		data := mockDataFromStates(s.UpdatedState, s.FailedState)
+		msg, err := m.build(data, "updated")
+		Expect(err).NotTo(HaveOccurred())
+
+		content, err := m.compose(msg, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		found, report := parts(content)
+		Expect(found).To(Equal(map[string]string{`text/plain; charset="utf-8"`: "updated"}))
+
+		var decoded struct {
+			Updated []json.RawMessage `json:"updated"`
+			Failed  []json.RawMessage `json:"failed"`
+		}
+		Expect(json.Unmarshal(report, &decoded)).To(Succeed())
+		Expect(decoded.Updated).To(HaveLen(1))
+		Expect(decoded.Failed).To(HaveLen(1))
+	})
+
+	It("should not attach a report to the notifications outside of a session", func() {
+		m := newTestMailer("", true)
+		msg, err := m.build(Data{}, "started")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msg.report).To(BeNil())
+
+		content, err := m.compose(msg, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		parsed, err := netmail.ReadMessage(bytes.NewReader(content))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.Header.Get("Content-Type")).To(HavePrefix("text/plain"))
+	})
+
+	It("should pass the entries to the HTML template when the legacy template is used", func() {
+		m := newTestMailer(`{{ range . }}<b>{{ .Message }}</b>{{ end }}`, false)
+		m.legacy = true
+		data := mockDataFromStates()
+		data.Report = nil
+		msg, err := m.build(data, "text")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msg.html).To(ContainSubstring("<b>"))
+	})
+})
@@ -43,6 +43,10 @@ func (n *msTeamsTypeNotifier) GetURL(c *cobra.Command, title string) (string, er
 	if err != nil {
 		return "", err
 	}
+	if !isTeamsConnectorURL(webhookURL) {
+		// Workflows receive the notifications as Adaptive Cards
+		return teamsWorkflowURL(webhookURL), nil
+	}
 
 	config, err := shoutrrrTeams.ConfigFromWebhookURL(*webhookURL)
 	if err != nil {
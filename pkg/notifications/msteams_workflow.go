@@ -0,0 +1,223 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/containrrr/shoutrrr/pkg/types"
+	t "github.com/containrrr/watchtower/pkg/types"
+	units "github.com/docker/go-units"
+)
+
+// teamsWorkflowScheme is the scheme of the URLs of the Teams workflow webhooks, which are posted to using https
+const teamsWorkflowScheme = "teams-workflow"
+
+// teamsWorkflowTimeout limits how long posting a card to a workflow webhook may take
+const teamsWorkflowTimeout = 30 * time.Second
+
+// cardElement is an element of an Adaptive Card
+type cardElement map[string]interface{}
+
+// teamsWorkflowService posts the notifications as Adaptive Cards to the webhook of a Teams workflow, which replaces the
+// retired Office 365 connectors. The session reports are rendered as sections listing the containers by state, while
+// the other notifications only show their text.
+type teamsWorkflowService struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// newTeamsWorkflowService returns the service posting to the webhook at the URL, with the teams-workflow scheme
+// standing for https
+func newTeamsWorkflowService(u *url.URL) (service, error) {
+	if u.Host == "" {
+		return nil, errors.New("the URL of the workflow webhook has no host")
+	}
+	webhook := *u
+	webhook.Scheme = "https"
+	return &teamsWorkflowService{
+		webhookURL: webhook.String(),
+		client:     &http.Client{Timeout: teamsWorkflowTimeout},
+	}, nil
+}
+
+// teamsWorkflowURL returns the teams-workflow URL of the webhook of a Teams workflow
+func teamsWorkflowURL(webhook *url.URL) string {
+	u := *webhook
+	u.Scheme = teamsWorkflowScheme
+	return u.String()
+}
+
+// isTeamsConnectorURL returns whether the webhook is one of the legacy Office 365 connectors, rather than a workflow
+func isTeamsConnectorURL(webhook *url.URL) bool {
+	host := strings.ToLower(webhook.Hostname())
+	return host == "outlook.office.com" || strings.HasSuffix(host, ".webhook.office.com")
+}
+
+// Send implements service, posting the card of the notification to the webhook
+func (s *teamsWorkflowService) Send(message string, report t.Report, params *types.Params) error {
+	title := "Watchtower"
+	if params != nil {
+		if paramsTitle, found := params.Title(); found && paramsTitle != "" {
+			title = paramsTitle
+		}
+	}
+	payload, err := json.Marshal(cardElement{
+		"type": "message",
+		"attachments": []cardElement{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     adaptiveCard(title, message, report),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.Post(s.webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("could not post to the Teams workflow: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("the Teams workflow refused the card: %s %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// cardSection is a state the containers of a report are listed by on the card
+type cardSection struct {
+	title      string
+	style      string
+	containers func(t.Report) []t.ContainerReport
+}
+
+// cardSections are the sections of the card, in the order they are shown
+var cardSections = []cardSection{
+	{"Failed", "attention", t.Report.Failed},
+	{"Quarantined", "attention", t.Report.Quarantined},
+	{"Retrying", "warning", t.Report.Retrying},
+	{"Updated", "good", t.Report.Updated},
+	{"Stale", "accent", t.Report.Stale},
+	{"Skipped", "warning", t.Report.Skipped},
+}
+
+// adaptiveCard returns the Adaptive Card of the notification. The title is colored by the outcome of the session.
+func adaptiveCard(title string, message string, report t.Report) cardElement {
+	body := []cardElement{{
+		"type":   "TextBlock",
+		"text":   title,
+		"size":   "Large",
+		"weight": "Bolder",
+		"wrap":   true,
+		"color":  outcomeColor(report),
+	}}
+
+	if report == nil {
+		body = append(body, cardElement{
+			"type":     "TextBlock",
+			"text":     strings.TrimSpace(message),
+			"wrap":     true,
+			"fontType": "Monospace",
+		})
+	} else {
+		body = append(body, cardElement{"type": "FactSet", "facts": summaryFacts(report)})
+		for _, section := range cardSections {
+			containers := section.containers(report)
+			if len(containers) == 0 {
+				continue
+			}
+			facts := make([]cardElement, 0, len(containers))
+			for _, c := range containers {
+				facts = append(facts, cardElement{"title": c.Name(), "value": containerFact(c)})
+			}
+			body = append(body, cardElement{
+				"type":  "Container",
+				"style": section.style,
+				"bleed": true,
+				"items": []cardElement{
+					{
+						"type":   "TextBlock",
+						"text":   fmt.Sprintf("%s (%d)", section.title, len(containers)),
+						"weight": "Bolder",
+					},
+					{"type": "FactSet", "facts": facts},
+				},
+			})
+		}
+	}
+
+	return cardElement{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"msteams": cardElement{"width": "Full"},
+		"body":    body,
+	}
+}
+
+// outcomeColor returns the color of the title, by the severity of the report
+func outcomeColor(report t.Report) string {
+	switch reportSeverity(report) {
+	case severityFailed:
+		return "Attention"
+	case severityWarning:
+		return "Warning"
+	case severityUpdated:
+		return "Good"
+	}
+	return "Default"
+}
+
+// summaryFacts returns the facts summing up the report, being the number of containers in each state
+func summaryFacts(report t.Report) []cardElement {
+	facts := []cardElement{{"title": "Scanned", "value": fmt.Sprint(len(report.Scanned()))}}
+	for _, count := range []struct {
+		title      string
+		containers []t.ContainerReport
+	}{
+		{"Updated", report.Updated()},
+		{"Failed", report.Failed()},
+		{"Retrying", report.Retrying()},
+		{"Quarantined", report.Quarantined()},
+		{"Stale", report.Stale()},
+		{"Skipped", report.Skipped()},
+		{"Fresh", report.Fresh()},
+	} {
+		if len(count.containers) > 0 {
+			facts = append(facts, cardElement{"title": count.title, "value": fmt.Sprint(len(count.containers))})
+		}
+	}
+	if pulled := report.PulledBytes(); pulled > 0 {
+		facts = append(facts, cardElement{"title": "Pulled", "value": units.HumanSize(float64(pulled))})
+	}
+	if duration := report.Duration(); duration > 0 {
+		facts = append(facts, cardElement{"title": "Duration", "value": duration.Round(time.Second).String()})
+	}
+	return facts
+}
+
+// containerFact returns the value of the fact of the container, showing its image along with the images it was
+// updated between, or why it was not
+func containerFact(c t.ContainerReport) string {
+	fact := c.ImageName()
+	if c.State() == "Updated" || c.State() == "Stale" {
+		fact += fmt.Sprintf(": %s → %s", c.CurrentImageID().ShortID(), c.LatestImageID().ShortID())
+		if release := c.Release().URL; release != "" {
+			fact += fmt.Sprintf(" ([release](%s))", release)
+		}
+	}
+	if reason := c.SkipReason(); reason != "" {
+		fact += fmt.Sprintf(" (%s)", reason)
+	}
+	if err := c.Error(); err != "" {
+		fact += ": " + err
+	}
+	return fact
+}
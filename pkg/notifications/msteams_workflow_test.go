@@ -0,0 +1,154 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/containrrr/shoutrrr/pkg/types"
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	s "github.com/containrrr/watchtower/pkg/session"
+	t "github.com/containrrr/watchtower/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the Teams workflow service", func() {
+	var server *httptest.Server
+	var posted []map[string]interface{}
+	var status int
+
+	BeforeEach(func() {
+		posted = nil
+		status = http.StatusAccepted
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/workflows/abc/triggers/manual/paths/invoke"))
+			Expect(r.URL.Query().Get("sig")).To(Equal("secret"))
+			body, err := io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			var payload map[string]interface{}
+			Expect(json.Unmarshal(body, &payload)).To(Succeed())
+			posted = append(posted, payload)
+			w.WriteHeader(status)
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newTestService := func() service {
+		svc, err := newService(teamsWorkflowScheme + strings.TrimPrefix(server.URL, "https") +
+			"/workflows/abc/triggers/manual/paths/invoke?sig=secret")
+		Expect(err).NotTo(HaveOccurred())
+		svc.(*teamsWorkflowService).client = server.Client()
+		return svc
+	}
+	params := func(title string) *types.Params {
+		p := &types.Params{}
+		p.SetTitle(title)
+		return p
+	}
+	// card returns the card of the payload posted
+	card := func(payload map[string]interface{}) map[string]interface{} {
+		attachments := payload["attachments"].([]interface{})
+		Expect(attachments).To(HaveLen(1))
+		attachment := attachments[0].(map[string]interface{})
+		Expect(attachment["contentType"]).To(Equal("application/vnd.microsoft.card.adaptive"))
+		return attachment["content"].(map[string]interface{})
+	}
+
+	It("should post the sections of the report as an adaptive card", func() {
+		report := mocks.CreateMockProgressReport(s.UpdatedState, s.FailedState, s.FreshState)
+		Expect(newTestService().Send("ignored", report, params("Watchtower updates on test"))).To(Succeed())
+		Expect(posted).To(HaveLen(1))
+
+		body := card(posted[0])["body"].([]interface{})
+		title := body[0].(map[string]interface{})
+		Expect(title["text"]).To(Equal("Watchtower updates on test"))
+		Expect(title["color"]).To(Equal("Attention"))
+
+		summary := body[1].(map[string]interface{})
+		Expect(summary["type"]).To(Equal("FactSet"))
+		Expect(summary["facts"]).To(ContainElement(map[string]interface{}{"title": "Updated", "value": "1"}))
+
+		Expect(body).To(HaveLen(4))
+		var sections []string
+		for _, element := range body[2:] {
+			items := element.(map[string]interface{})["items"].([]interface{})
+			sections = append(sections, items[0].(map[string]interface{})["text"].(string))
+		}
+		Expect(sections).To(Equal([]string{"Failed (1)", "Updated (1)"}))
+	})
+
+	It("should post the text of the notifications outside of a session", func() {
+		Expect(newTestService().Send("Watchtower started\n", nil, nil)).To(Succeed())
+
+		body := card(posted[0])["body"].([]interface{})
+		Expect(body[0].(map[string]interface{})["text"]).To(Equal("Watchtower"))
+		Expect(body[1].(map[string]interface{})["text"]).To(Equal("Watchtower started"))
+	})
+
+	It("should return an error when the workflow refuses the card", func() {
+		status = http.StatusBadRequest
+		Expect(newTestService().Send("text", nil, nil)).To(MatchError(ContainSubstring("400")))
+	})
+
+	It("should color the title by the outcome of the session", func() {
+		Expect(outcomeColor(mocks.CreateMockProgressReport(s.UpdatedState))).To(Equal("Good"))
+		Expect(outcomeColor(mocks.CreateMockProgressReport(s.SkippedState))).To(Equal("Warning"))
+		Expect(outcomeColor(mocks.CreateMockProgressReport(s.FreshState))).To(Equal("Default"))
+	})
+})
+
+var _ = Describe("the service router", func() {
+	It("should return the errors in the order of the URLs", func() {
+		shoutrrrRouter := &recordingRouter{}
+		r, err := newServiceRouter(
+			[]string{"logger://", "teams-workflow://example.com/workflows/abc", "logger://"},
+			func(urls []string) (router, error) {
+				Expect(urls).To(Equal([]string{"logger://", "logger://"}))
+				return erroringRouter{errs: []error{nil, errors.New("second")}, recording: shoutrrrRouter}, nil
+			},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		failing := errors.New("workflow")
+		r.(*serviceRouter).services[1] = serviceFunc(func(string, t.Report, *types.Params) error { return failing })
+
+		errs := send(r, message{body: "text"}, &types.Params{})
+		Expect(errs).To(Equal([]error{nil, failing, errors.New("second")}))
+		Expect(shoutrrrRouter.messages).To(Equal([]string{"text"}))
+	})
+
+	It("should use the router of shoutrrr as is without services of watchtower", func() {
+		shoutrrrRouter := &recordingRouter{}
+		r, err := newServiceRouter([]string{"logger://"}, func([]string) (router, error) { return shoutrrrRouter, nil })
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r).To(BeIdenticalTo(shoutrrrRouter))
+	})
+
+	It("should refuse invalid URLs of the services", func() {
+		Expect(validateURL("teams-workflow:///workflows/abc")).To(HaveOccurred())
+		Expect(validateURL("teams-workflow://example.com/workflows/abc")).To(Succeed())
+	})
+})
+
+// erroringRouter returns the errors, recording the messages using the recording router
+type erroringRouter struct {
+	errs      []error
+	recording *recordingRouter
+}
+
+func (r erroringRouter) Send(message string, params *types.Params) []error {
+	r.recording.Send(message, params)
+	return r.errs
+}
+
+// serviceFunc implements service using a function
+type serviceFunc func(message string, report t.Report, params *types.Params) error
+
+func (f serviceFunc) Send(message string, report t.Report, params *types.Params) error {
+	return f(message, report, params)
+}
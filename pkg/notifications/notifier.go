@@ -1,18 +1,24 @@
 package notifications
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/containrrr/watchtower/internal/flags"
 	ty "github.com/containrrr/watchtower/pkg/types"
 	"github.com/johntdyer/slackrus"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-// NewNotifier creates and returns a new Notifier, using global configuration.
-func NewNotifier(c *cobra.Command) ty.Notifier {
+// NewNotifier creates and returns a new Notifier, using global configuration. The notifications sent are recorded
+// using the event recorder, if one is passed.
+func NewNotifier(c *cobra.Command, events ty.EventRecorder) ty.Notifier {
 	f := c.PersistentFlags()
 
 	level, _ := f.GetString("notifications-level")
@@ -31,20 +37,63 @@ func NewNotifier(c *cobra.Command) ty.Notifier {
 	stdout, _ := f.GetBool("notification-log-stdout")
 	tplString, _ := f.GetString("notification-template")
 	urls, _ := f.GetStringArray("notification-url")
+	available, _ := f.GetBool("notification-update-available")
+	config, err := flags.ReadConfigFile(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	source, text, err := templateFromFile(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if source != nil {
+		tplString = text
+	}
 
 	data := GetTemplateData(c)
 	urls, delay := AppendLegacyUrls(urls, c, data.Title)
+	mailer, err := newMailer(c, data.Title, config.NotificationTemplates, !reportTemplate)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return newShoutrrrNotifier(tplString, source, mailer, config.NotificationTemplates, levels, !reportTemplate, data, delay, stdout, available, events, urls...)
+}
 
-	return newShoutrrrNotifier(tplString, levels, !reportTemplate, data, delay, stdout, urls...)
+// templateFromFile returns the source of the global template set using --notification-template-file, along with the
+// template it has loaded, or a nil source if the flag is not set
+func templateFromFile(f *pflag.FlagSet) (*templateSource, string, error) {
+	location, _ := f.GetString("notification-template-file")
+	if location == "" {
+		return nil, "", nil
+	}
+	if tplString, _ := f.GetString("notification-template"); tplString != "" {
+		return nil, "", errors.New("the notification template cannot be set both directly and using a file")
+	}
+	source := newTemplateSource(location)
+	text, _, err := source.load()
+	if err != nil {
+		return nil, "", err
+	}
+	return source, text, nil
 }
 
 // AppendLegacyUrls creates shoutrrr equivalent URLs from legacy notification flags
 func AppendLegacyUrls(urls []string, cmd *cobra.Command, title string) ([]string, time.Duration) {
+	urls, delay, err := appendLegacyUrls(urls, cmd, title)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return urls, delay
+}
+
+func appendLegacyUrls(urls []string, cmd *cobra.Command, title string) ([]string, time.Duration, error) {
 
 	// Parse types and create notifiers.
 	types, err := cmd.Flags().GetStringSlice("notifications")
 	if err != nil {
-		log.WithError(err).Fatal("could not read notifications argument")
+		return nil, 0, fmt.Errorf("could not read notifications argument: %w", err)
 	}
 
 	legacyDelay := time.Duration(0)
@@ -56,7 +105,13 @@ func AppendLegacyUrls(urls []string, cmd *cobra.Command, title string) ([]string
 
 		switch t {
 		case emailType:
-			legacyNotifier = newEmailNotifier(cmd, []log.Level{})
+			email := newEmailNotifier(cmd, []log.Level{}).(*emailTypeNotifier)
+			if email.sendsItself() {
+				// Sent by the mailer rather than shoutrrr
+				legacyDelay = email.GetDelay()
+				continue
+			}
+			legacyNotifier = email
 		case slackType:
 			legacyNotifier = newSlackNotifier(cmd, []log.Level{})
 		case msTeamsType:
@@ -66,14 +121,12 @@ func AppendLegacyUrls(urls []string, cmd *cobra.Command, title string) ([]string
 		case shoutrrrType:
 			continue
 		default:
-			log.Fatalf("Unknown notification type %q", t)
-			// Not really needed, used for nil checking static analysis
-			continue
+			return nil, 0, fmt.Errorf("unknown notification type %q", t)
 		}
 
 		shoutrrrURL, err := legacyNotifier.GetURL(cmd, title)
 		if err != nil {
-			log.Fatal("failed to create notification config: ", err)
+			return nil, 0, fmt.Errorf("failed to create notification config: %w", err)
 		}
 		urls = append(urls, shoutrrrURL)
 
@@ -85,7 +138,90 @@ func AppendLegacyUrls(urls []string, cmd *cobra.Command, title string) ([]string
 	}
 
 	delay := GetDelay(cmd, legacyDelay)
-	return urls, delay
+	return urls, delay, nil
+}
+
+// Validate checks the notification settings without sending any notifications, returning an error for each of the
+// settings that are invalid, like notification URLs of unknown services
+func Validate(c *cobra.Command) []error {
+	f := c.PersistentFlags()
+	var errs []error
+
+	level, _ := f.GetString("notifications-level")
+	if logLevel, err := log.ParseLevel(level); err != nil {
+		errs = append(errs, fmt.Errorf("invalid notifications level: %w", err))
+	} else if len(slackrus.LevelThreshold(logLevel)) == 0 {
+		errs = append(errs, fmt.Errorf("unsupported notifications level %q", level))
+	}
+
+	reportTemplate, _ := f.GetBool("notification-report")
+	config, err := flags.ReadConfigFile(f)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	tplString, _ := f.GetString("notification-template")
+	if text, found := config.NotificationTemplates[tplString]; found {
+		tplString = text
+	}
+	if source, text, err := templateFromFile(f); err != nil {
+		errs = append(errs, err)
+	} else if source != nil {
+		tplString = text
+	}
+	if _, err := getShoutrrrTemplate(tplString, !reportTemplate); err != nil {
+		errs = append(errs, fmt.Errorf("invalid notification template: %w", err))
+	}
+	templateNames := make([]string, 0, len(config.NotificationTemplates))
+	for name := range config.NotificationTemplates {
+		templateNames = append(templateNames, name)
+	}
+	sort.Strings(templateNames)
+	for _, name := range templateNames {
+		if _, err := getShoutrrrTemplate(config.NotificationTemplates[name], !reportTemplate); err != nil {
+			errs = append(errs, fmt.Errorf("invalid notification template %q: %w", name, err))
+		}
+	}
+
+	if _, err := newMailer(c, GetTemplateData(c).Title, config.NotificationTemplates, !reportTemplate); err != nil {
+		errs = append(errs, err)
+	}
+
+	urls, _ := f.GetStringArray("notification-url")
+	urls, _, err = appendLegacyUrls(urls, c, GetTemplateData(c).Title)
+	if err != nil {
+		return append(errs, err)
+	}
+	applied, available, err := splitPhases(urls, true)
+	if err != nil {
+		return append(errs, err)
+	}
+	if available, err = stripTemplateNames(available); err != nil {
+		return append(errs, err)
+	}
+	applied, _, names, err := splitTemplates(applied)
+	if err != nil {
+		return append(errs, err)
+	}
+	for _, name := range names {
+		if _, err := getNamedTemplate(name, config.NotificationTemplates, !reportTemplate); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	watchdogUrls, _ := f.GetStringArray("notification-watchdog-url")
+	if grace, _ := f.GetFloat64("notification-watchdog-grace"); len(watchdogUrls) > 0 && grace < 1 {
+		errs = append(errs, fmt.Errorf("the watchdog grace must be at least 1 interval, got %g", grace))
+	}
+	seen := map[string]bool{}
+	for _, url := range append(append(applied, available...), watchdogUrls...) {
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+		if err := validateURL(url); err != nil {
+			errs = append(errs, fmt.Errorf("invalid notification URL for %s: %w", GetScheme(url), err))
+		}
+	}
+	return errs
 }
 
 // GetDelay returns the legacy delay if defined, otherwise the delay as set by args is returned
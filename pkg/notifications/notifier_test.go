@@ -25,7 +25,7 @@ var _ = Describe("notifications", func() {
 				"shoutrrr",
 			})
 			Expect(err).NotTo(HaveOccurred())
-			notif := notifications.NewNotifier(command)
+			notif := notifications.NewNotifier(command, nil)
 
 			Expect(notif.GetNames()).To(BeEmpty())
 		})
@@ -137,6 +137,30 @@ var _ = Describe("notifications", func() {
 			})
 		})
 	})
+	Describe("validating the notification settings", func() {
+		validate := func(args ...string) []error {
+			command := cmd.NewRootCommand()
+			flags.RegisterNotificationFlags(command)
+			Expect(command.ParseFlags(args)).To(Succeed())
+			return notifications.Validate(command)
+		}
+		It("should accept valid settings", func() {
+			Expect(validate("--notification-url", "logger://", "--notifications-level", "warn")).To(BeEmpty())
+		})
+		It("should return an error for each of the invalid settings", func() {
+			errs := validate(
+				"--notification-url", "logger://",
+				"--notification-url", "unknown://host",
+				"--notifications-level", "loud",
+				"--notification-report",
+				"--notification-template", "{{ .Unclosed ",
+			)
+			Expect(errs).To(HaveLen(3))
+		})
+		It("should return an error for unknown legacy notifier types", func() {
+			Expect(validate("--notifications", "carrier-pigeon")).To(HaveLen(1))
+		})
+	})
 	Describe("the slack notifier", func() {
 		// builderFn := notifications.NewSlackNotifier
 
@@ -275,6 +299,28 @@ var _ = Describe("notifications", func() {
 				testURL(args, expectedOutput, time.Duration(0))
 			})
 		})
+		When("the dashboard is reachable", func() {
+			It("should open it when the notifications are clicked", func() {
+				command := cmd.NewRootCommand()
+				flags.RegisterSystemFlags(command)
+				flags.RegisterNotificationFlags(command)
+				Expect(command.ParseFlags([]string{
+					"--notifications",
+					"gotify",
+					"--notification-gotify-url",
+					"https://shoutrrr.local",
+					"--notification-gotify-token",
+					"aaa",
+					"--http-api-dashboard",
+					"--http-api-public-url",
+					"https://watchtower.example.com/",
+				})).To(Succeed())
+
+				urls, _ := notifications.AppendLegacyUrls([]string{}, command, "")
+				Expect(urls).To(HaveLen(1))
+				Expect(urls[0]).To(ContainSubstring("clickurl=" + url.QueryEscape("https://watchtower.example.com/dashboard")))
+			})
+		})
 	})
 
 	Describe("the teams notifier", func() {
@@ -300,6 +346,21 @@ var _ = Describe("notifications", func() {
 					hookURL,
 				}
 
+				testURL(args, expectedOutput, time.Duration(0))
+			})
+		})
+		When("the hook is the webhook of a workflow", func() {
+			It("should post adaptive cards to the workflow", func() {
+				hookURL := "https://prod-01.westeurope.logic.azure.com:443/workflows/abc/triggers/manual/paths/invoke?api-version=2016-06-01&sig=secret"
+				expectedOutput := "teams-workflow://prod-01.westeurope.logic.azure.com:443/workflows/abc/triggers/manual/paths/invoke?api-version=2016-06-01&sig=secret"
+
+				args := []string{
+					"--notifications",
+					"msteams",
+					"--notification-msteams-hook",
+					hookURL,
+				}
+
 				testURL(args, expectedOutput, time.Duration(0))
 			})
 		})
@@ -358,6 +419,41 @@ var _ = Describe("notifications", func() {
 				testURL(args, expectedOutput, expectedDelay)
 			})
 		})
+		When("attaching the report", func() {
+			It("should send the emails itself rather than using a shoutrrr url", func() {
+				command := cmd.NewRootCommand()
+				flags.RegisterNotificationFlags(command)
+				Expect(command.ParseFlags([]string{
+					"--notifications",
+					"email",
+					"--notification-email-from",
+					"sender@example.com",
+					"--notification-email-to",
+					"receiver@example.com",
+					"--notification-email-server",
+					"mail.containrrr.dev",
+					"--notification-email-attach-report",
+				})).To(Succeed())
+
+				urls, _ := notifications.AppendLegacyUrls([]string{}, command, "")
+				Expect(urls).To(BeEmpty())
+				Expect(notifications.NewNotifier(command, nil).GetNames()).To(ConsistOf("smtp"))
+			})
+		})
+		When("the HTML template is invalid", func() {
+			It("should fail the validation", func() {
+				command := cmd.NewRootCommand()
+				flags.RegisterNotificationFlags(command)
+				Expect(command.ParseFlags([]string{
+					"--notifications",
+					"email",
+					"--notification-email-html-template",
+					"{{ .Missing",
+				})).To(Succeed())
+
+				Expect(notifications.Validate(command)).NotTo(BeEmpty())
+			})
+		})
 	})
 })
 
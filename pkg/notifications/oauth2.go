@@ -0,0 +1,140 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// oauth2FetchTimeout limits how long requesting an access token may take
+const oauth2FetchTimeout = 30 * time.Second
+
+// oauth2ExpiryMargin is how long before it expires an access token is replaced, so that it does not expire while
+// sending an email
+const oauth2ExpiryMargin = time.Minute
+
+// oauth2TokenSource requests the access tokens used to authenticate with the SMTP server from the token endpoint of
+// the provider, keeping them until they expire. Without a refresh token, the tokens are requested using the client
+// credentials flow, as used by Office 365. Otherwise the refresh token is exchanged for them, as used by Gmail.
+type oauth2TokenSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	refreshToken string
+	scope        string
+	client       *http.Client
+	now          func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// oauth2TokenResponse is the answer of the token endpoint
+type oauth2TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// newOAuth2TokenSource returns the source of the access tokens requested from the token endpoint
+func newOAuth2TokenSource(tokenURL, clientID, clientSecret, refreshToken, scope string) *oauth2TokenSource {
+	return &oauth2TokenSource{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		refreshToken: refreshToken,
+		scope:        scope,
+		client:       &http.Client{Timeout: oauth2FetchTimeout},
+		now:          time.Now,
+	}
+}
+
+// accessToken returns the current access token, requesting a new one if it is about to expire
+func (s *oauth2TokenSource) accessToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && s.now().Add(oauth2ExpiryMargin).Before(s.expiry) {
+		return s.token, nil
+	}
+
+	form := url.Values{"client_id": {s.clientID}, "client_secret": {s.clientSecret}}
+	if s.refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", s.refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if s.scope != "" {
+		form.Set("scope", s.scope)
+	}
+	res, err := s.client.PostForm(s.tokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("could not request an OAuth2 access token: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("could not read the OAuth2 access token: %w", err)
+	}
+
+	var token oauth2TokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("could not parse the OAuth2 access token (%s): %w", res.Status, err)
+	}
+	if token.Error != "" {
+		return "", fmt.Errorf("the OAuth2 access token was refused: %s %s", token.Error, token.ErrorDescription)
+	}
+	if res.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("the OAuth2 token endpoint did not return an access token (%s)", res.Status)
+	}
+	s.token = token.AccessToken
+	s.expiry = s.now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// forget drops the current access token, so that a new one is requested, as the server refused it
+func (s *oauth2TokenSource) forget() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+// xoauth2Auth implements smtp.Auth, authenticating the user with the access token using the XOAUTH2 mechanism
+type xoauth2Auth struct {
+	user  string
+	token string
+	host  string
+}
+
+// Start implements smtp.Auth, refusing to send the token over connections that are not using TLS, like smtp.PlainAuth
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "XOAUTH2", []byte("user=" + a.user + "\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+// Next implements smtp.Auth. When refusing the token, the server sends the details of the error, which the client
+// acknowledges with an empty response to get the final error.
+func (a *xoauth2Auth) Next(_ []byte, more bool) ([]byte, error) {
+	if more {
+		return []byte{}, nil
+	}
+	return nil, nil
+}
+
+// isLocalhost returns whether the host is the local host, to which smtp.PlainAuth also sends credentials without TLS
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
@@ -0,0 +1,156 @@
+package notifications
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the OAuth2 authentication of the emails", func() {
+	var server *httptest.Server
+	var requests []map[string]string
+
+	BeforeEach(func() {
+		requests = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.ParseForm()).To(Succeed())
+			form := map[string]string{}
+			for key := range r.PostForm {
+				form[key] = r.PostForm.Get(key)
+			}
+			requests = append(requests, form)
+			if form["client_secret"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, `{"error":"invalid_client","error_description":"bad secret"}`)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, len(requests))
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	When("requesting the access tokens", func() {
+		It("should use the client credentials flow and keep the token until it expires", func() {
+			now := time.Now()
+			source := newOAuth2TokenSource(server.URL, "watchtower", "secret", "", "https://outlook.office365.com/.default")
+			source.now = func() time.Time { return now }
+
+			Expect(source.accessToken()).To(Equal("token-1"))
+			Expect(source.accessToken()).To(Equal("token-1"))
+			Expect(requests).To(Equal([]map[string]string{{
+				"grant_type":    "client_credentials",
+				"client_id":     "watchtower",
+				"client_secret": "secret",
+				"scope":         "https://outlook.office365.com/.default",
+			}}))
+
+			now = now.Add(time.Hour - oauth2ExpiryMargin)
+			Expect(source.accessToken()).To(Equal("token-2"))
+		})
+
+		It("should exchange the refresh token when set", func() {
+			source := newOAuth2TokenSource(server.URL, "watchtower", "secret", "refresh", "")
+			Expect(source.accessToken()).To(Equal("token-1"))
+			Expect(requests[0]).To(HaveKeyWithValue("grant_type", "refresh_token"))
+			Expect(requests[0]).To(HaveKeyWithValue("refresh_token", "refresh"))
+			Expect(requests[0]).NotTo(HaveKey("scope"))
+		})
+
+		It("should request a new token once it was forgotten", func() {
+			source := newOAuth2TokenSource(server.URL, "watchtower", "secret", "", "")
+			Expect(source.accessToken()).To(Equal("token-1"))
+			source.forget()
+			Expect(source.accessToken()).To(Equal("token-2"))
+		})
+
+		It("should return the error of the provider", func() {
+			source := newOAuth2TokenSource(server.URL, "watchtower", "wrong", "", "")
+			_, err := source.accessToken()
+			Expect(err).To(MatchError(ContainSubstring("invalid_client bad secret")))
+		})
+	})
+
+	When("authenticating with the SMTP server", func() {
+		It("should refuse to send the token without TLS", func() {
+			auth := &xoauth2Auth{user: "watchtower@example.com", token: "token", host: "smtp.example.com"}
+			_, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com"})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should send the email using the access token", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+			received := make(chan []string, 1)
+			go serveSMTP(listener, received)
+
+			host, port, _ := net.SplitHostPort(listener.Addr().String())
+			m := &mailer{
+				from:    "watchtower@example.com",
+				to:      []string{"admin@example.com"},
+				host:    host,
+				user:    "watchtower@example.com",
+				subject: "Watchtower updates",
+				oauth2:  newOAuth2TokenSource(server.URL, "watchtower", "secret", "", ""),
+			}
+			m.port, err = strconv.Atoi(port)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(m.send(mail{text: "updated"})).To(Succeed())
+			var commands []string
+			Eventually(received).Should(Receive(&commands))
+			auth := base64.StdEncoding.EncodeToString([]byte("user=watchtower@example.com\x01auth=Bearer token-1\x01\x01"))
+			Expect(commands).To(ContainElement("AUTH XOAUTH2 " + auth))
+			Expect(commands).To(ContainElement("RCPT TO:<admin@example.com>"))
+		})
+	})
+})
+
+// serveSMTP answers a single SMTP session accepting any XOAUTH2 token, sending the commands it received once it ends
+func serveSMTP(listener net.Listener, received chan<- []string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	var commands []string
+	defer func() { received <- commands }()
+
+	_ = text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		commands = append(commands, line)
+		switch strings.ToUpper(strings.SplitN(line, " ", 2)[0]) {
+		case "EHLO":
+			_ = text.PrintfLine("250-localhost\r\n250 AUTH XOAUTH2")
+		case "AUTH":
+			_ = text.PrintfLine("235 2.7.0 Accepted")
+		case "DATA":
+			_ = text.PrintfLine("354 Go ahead")
+			_, _ = io.Copy(io.Discard, text.DotReader())
+			_ = text.PrintfLine("250 OK")
+		case "QUIT":
+			_ = text.PrintfLine("221 Bye")
+			return
+		default:
+			_ = text.PrintfLine("250 OK")
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package notifications
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	t "github.com/containrrr/watchtower/pkg/types"
+)
+
+// ReportGroup is a set of the containers of a report sharing the same key, like their state or compose project
+type ReportGroup struct {
+	Key        string
+	Containers []t.ContainerReport
+}
+
+// stateOrder is the order of the groups returned by ByState, listing the states needing attention first
+var stateOrder = []string{"Failed", "Retrying", "Quarantined", "Updated", "Stale", "Skipped", "Fresh", "Scanned"}
+
+// reportFields are the fields of the containers that the reports can be grouped and sorted by
+var reportFields = map[string]func(t.ContainerReport) string{
+	"name":    func(c t.ContainerReport) string { return c.Name() },
+	"image":   func(c t.ContainerReport) string { return c.ImageName() },
+	"state":   func(c t.ContainerReport) string { return c.State() },
+	"project": func(c t.ContainerReport) string { return c.Project() },
+	"id":      func(c t.ContainerReport) string { return string(c.ID()) },
+}
+
+// ByState returns the containers of the report grouped by their state, the failed ones first
+func (d Data) ByState() []ReportGroup {
+	if d.Report == nil {
+		return nil
+	}
+	groups, err := groupBy("state", d.Report.All())
+	if err != nil {
+		return nil
+	}
+	rank := map[string]int{}
+	for i, state := range stateOrder {
+		rank[state] = i + 1
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return rank[groups[i].Key] < rank[groups[j].Key]
+	})
+	return groups
+}
+
+// ByProject returns the containers of the report grouped by their compose project, ordered by its name, followed by
+// the containers not belonging to any project under an empty key
+func (d Data) ByProject() []ReportGroup {
+	if d.Report == nil {
+		return nil
+	}
+	groups, err := groupBy("project", d.Report.All())
+	if err != nil {
+		return nil
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Key == "" || groups[j].Key == "" {
+			return groups[j].Key == ""
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
+
+// ByHost returns the containers of the report grouped by the host they run on. As every watchtower instance manages
+// a single docker host, this is a single group keyed by the host name, for templates shared between the instances.
+func (d Data) ByHost() []ReportGroup {
+	if d.Report == nil {
+		return nil
+	}
+	return []ReportGroup{{Key: d.Host, Containers: d.Report.All()}}
+}
+
+// groupBy groups the containers by the given field, in the order the values of the field first appear in
+func groupBy(field string, containers []t.ContainerReport) ([]ReportGroup, error) {
+	key, found := reportFields[strings.ToLower(field)]
+	if !found {
+		return nil, fmt.Errorf("unknown report field %q", field)
+	}
+	var groups []ReportGroup
+	index := map[string]int{}
+	for _, c := range containers {
+		value := key(c)
+		i, found := index[value]
+		if !found {
+			i = len(groups)
+			index[value] = i
+			groups = append(groups, ReportGroup{Key: value})
+		}
+		groups[i].Containers = append(groups[i].Containers, c)
+	}
+	return groups, nil
+}
+
+// sortBy returns a copy of the containers sorted by the given field, keeping the order of the ones with equal values.
+// The field may be prefixed with a minus to sort in descending order.
+func sortBy(field string, containers []t.ContainerReport) ([]t.ContainerReport, error) {
+	descending := strings.HasPrefix(field, "-")
+	key, found := reportFields[strings.ToLower(strings.TrimPrefix(field, "-"))]
+	if !found {
+		return nil, fmt.Errorf("unknown report field %q", field)
+	}
+	sorted := make([]t.ContainerReport, len(containers))
+	copy(sorted, containers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if descending {
+			return key(sorted[i]) > key(sorted[j])
+		}
+		return key(sorted[i]) < key(sorted[j])
+	})
+	return sorted, nil
+}
@@ -0,0 +1,127 @@
+package notifications
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/containrrr/shoutrrr"
+	"github.com/containrrr/shoutrrr/pkg/services/gotify"
+	"github.com/containrrr/shoutrrr/pkg/services/pushover"
+	"github.com/containrrr/shoutrrr/pkg/services/telegram"
+	"github.com/containrrr/shoutrrr/pkg/types"
+	t "github.com/containrrr/watchtower/pkg/types"
+)
+
+// service is a notification service implemented by watchtower rather than shoutrrr, as it needs more than the text of
+// the notification, like the report it was built from, which is nil for the notifications sent outside of a session
+type service interface {
+	Send(message string, report t.Report, params *types.Params) error
+}
+
+// services create the notification services implemented by watchtower from their URLs, by the scheme of the URLs
+var services = map[string]func(u *url.URL) (service, error){
+	teamsWorkflowScheme: newTeamsWorkflowService,
+	telegram.Scheme:     newTelegramService,
+	pushover.Scheme:     newPushoverService,
+	gotify.Scheme:       newGotifyService,
+	snsScheme:           newSNSService,
+	sqsScheme:           newSQSService,
+}
+
+// newService returns the service implemented by watchtower for the URL, or nil if the URL is one of shoutrrr
+func newService(rawURL string) (service, error) {
+	create, found := services[GetScheme(rawURL)]
+	if !found {
+		return nil, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return create(u)
+}
+
+// validateURL returns an error if the notification URL is invalid, or names an unknown service
+func validateURL(rawURL string) error {
+	if _, found := services[GetScheme(rawURL)]; found {
+		_, err := newService(rawURL)
+		return err
+	}
+	_, err := shoutrrr.CreateSender(rawURL)
+	return err
+}
+
+// reportRouter is a router that also passes the report of the notification to the services
+type reportRouter interface {
+	router
+	SendReport(message string, report t.Report, params *types.Params) []error
+}
+
+// serviceRouter sends the notifications to the URLs of the services implemented by watchtower, and to the other ones
+// using shoutrrr, returning the errors in the order of the URLs
+type serviceRouter struct {
+	// services holds the service of each URL, being nil for the ones sent using shoutrrr
+	services []service
+	shoutrrr router
+}
+
+// newServiceRouter returns the router sending the notifications to the URLs, creating the router of the URLs of
+// shoutrrr using newShoutrrrRouter. It returns that router as is when none of the URLs are of watchtower services.
+func newServiceRouter(urls []string, newShoutrrrRouter func(urls []string) (router, error)) (router, error) {
+	r := &serviceRouter{services: make([]service, len(urls))}
+	var shoutrrrUrls []string
+	for i, u := range urls {
+		s, err := newService(u)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notification URL for %s: %w", GetScheme(u), err)
+		}
+		if s == nil {
+			shoutrrrUrls = append(shoutrrrUrls, u)
+		}
+		r.services[i] = s
+	}
+	if len(shoutrrrUrls) == len(urls) {
+		return newShoutrrrRouter(urls)
+	}
+	if len(shoutrrrUrls) > 0 {
+		var err error
+		if r.shoutrrr, err = newShoutrrrRouter(shoutrrrUrls); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Send implements router, sending the notification without its report
+func (r *serviceRouter) Send(message string, params *types.Params) []error {
+	return r.SendReport(message, nil, params)
+}
+
+// SendReport sends the notification, passing its report to the services implemented by watchtower
+func (r *serviceRouter) SendReport(message string, report t.Report, params *types.Params) []error {
+	var shoutrrrErrs []error
+	if r.shoutrrr != nil {
+		shoutrrrErrs = r.shoutrrr.Send(message, params)
+	}
+	errs := make([]error, len(r.services))
+	next := 0
+	for i, s := range r.services {
+		if s != nil {
+			errs[i] = s.Send(message, report, params)
+			continue
+		}
+		if next < len(shoutrrrErrs) {
+			errs[i] = shoutrrrErrs[next]
+		}
+		next++
+	}
+	return errs
+}
+
+// send sends the message using the router, along with its report if the router passes it to the services
+func send(r router, msg message, params *types.Params) []error {
+	if rr, ok := r.(reportRouter); ok {
+		return rr.SendReport(msg.body, msg.report, params)
+	}
+	return r.Send(msg.body, params)
+}
@@ -0,0 +1,60 @@
+package notifications
+
+import (
+	"net/url"
+
+	t "github.com/containrrr/watchtower/pkg/types"
+)
+
+// severity is how much attention the outcome of a session needs, for the services that can make a notification more
+// or less noticeable
+type severity int
+
+const (
+	// severityNone is the severity of the sessions that changed nothing, and of the notifications outside of a session
+	severityNone severity = iota
+	// severityUpdated is the severity of the sessions that updated containers without any issue
+	severityUpdated
+	// severityWarning is the severity of the sessions that skipped containers, or will retry updating them
+	severityWarning
+	// severityFailed is the severity of the sessions that failed to update containers, or quarantined them
+	severityFailed
+)
+
+// reportSeverity returns the severity of the report, being the one of the worst state of its containers
+func reportSeverity(report t.Report) severity {
+	switch {
+	case report == nil:
+		return severityNone
+	case len(report.Failed()) > 0 || len(report.Quarantined()) > 0:
+		return severityFailed
+	case len(report.Retrying()) > 0 || len(report.Skipped()) > 0:
+		return severityWarning
+	case len(report.Updated()) > 0:
+		return severityUpdated
+	}
+	return severityNone
+}
+
+// severityNames are the names of the severities, prefixing the parameters of the notification URLs that apply to them
+var severityNames = map[string]severity{
+	"updated": severityUpdated,
+	"warning": severityWarning,
+	"failed":  severityFailed,
+}
+
+// severityParams removes the parameters of the severities from the query, like failedpriority, returning their values
+// by the name they end with, and by severity
+func severityParams(query url.Values, names ...string) map[string]map[severity]string {
+	params := map[string]map[severity]string{}
+	for _, name := range names {
+		params[name] = map[severity]string{}
+		for prefix, sev := range severityNames {
+			if value := query.Get(prefix + name); value != "" {
+				params[name][sev] = value
+			}
+			query.Del(prefix + name)
+		}
+	}
+	return params
+}
@@ -5,12 +5,16 @@ import (
 	stdlog "log"
 	"os"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
 	"github.com/containrrr/shoutrrr"
 	"github.com/containrrr/shoutrrr/pkg/types"
+	"github.com/containrrr/watchtower/pkg/events"
+	"github.com/containrrr/watchtower/pkg/logging"
 	t "github.com/containrrr/watchtower/pkg/types"
+	units "github.com/docker/go-units"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -34,11 +38,37 @@ type shoutrrrTypeNotifier struct {
 	entries        []*log.Entry
 	logLevels      []log.Level
 	template       *template.Template
-	messages       chan string
+	messages       chan message
 	done           chan bool
 	legacyTemplate bool
 	params         *types.Params
 	data           StaticData
+
+	// events records the notifications sent, if set
+	events t.EventRecorder
+	// AvailableUrls and AvailableRouter receive the notifications of the updates found for the first time, if any
+	AvailableUrls   []string
+	AvailableRouter router
+	// templated are the URLs receiving the session reports built using their own template
+	templated []templatedTarget
+	// templateSource is where the global template is reloaded from once it has changed, if set. templateMu guards
+	// replacing the template.
+	templateSource *templateSource
+	templateMu     sync.Mutex
+	// mailer sends the notification emails that shoutrrr cannot send, if set
+	mailer *mailer
+}
+
+// message is a notification to send, using the router of either the session reports, the available updates, or the
+// URLs using their own template if target is set
+type message struct {
+	body      string
+	available bool
+	target    *templatedTarget
+	// report is the report the message was built from, passed to the services implemented by watchtower
+	report t.Report
+	// mail is the email to send using the mailer instead, if set
+	mail *mail
 }
 
 // GetScheme returns the scheme part of a Shoutrrr URL
@@ -52,16 +82,67 @@ func GetScheme(url string) string {
 
 // GetNames returns a list of notification services that has been added
 func (n *shoutrrrTypeNotifier) GetNames() []string {
-	names := make([]string, len(n.Urls))
-	for i, u := range n.Urls {
-		names[i] = GetScheme(u)
+	names := make([]string, 0, len(n.Urls))
+	applied := map[string]bool{}
+	for _, u := range n.Urls {
+		names = append(names, GetScheme(u))
+		applied[u] = true
+	}
+	for _, target := range n.templated {
+		for _, u := range target.urls {
+			names = append(names, GetScheme(u))
+			applied[u] = true
+		}
+	}
+	for _, u := range n.AvailableUrls {
+		if !applied[u] {
+			names = append(names, GetScheme(u))
+		}
+	}
+	if n.mailer != nil {
+		names = append(names, GetScheme(mailerScheme))
 	}
 	return names
 }
 
-func newShoutrrrNotifier(tplString string, levels []log.Level, legacy bool, data StaticData, delay time.Duration, stdout bool, urls ...string) t.Notifier {
+func newShoutrrrNotifier(tplString string, source *templateSource, mailer *mailer, templates map[string]string, levels []log.Level, legacy bool, data StaticData, delay time.Duration, stdout bool, available bool, recorder t.EventRecorder, urls ...string) t.Notifier {
 
+	urls, availableUrls, err := splitPhases(urls, available)
+	if err != nil {
+		log.Fatalf("Failed to initialize Shoutrrr notifications: %s\n", err.Error())
+	}
+	if availableUrls, err = stripTemplateNames(availableUrls); err != nil {
+		log.Fatalf("Failed to initialize Shoutrrr notifications: %s\n", err.Error())
+	}
+	urls, named, names, err := splitTemplates(urls)
+	if err != nil {
+		log.Fatalf("Failed to initialize Shoutrrr notifications: %s\n", err.Error())
+	}
+	if text, found := templates[tplString]; found {
+		tplString = text
+	}
 	notifier := createNotifier(urls, levels, tplString, legacy, data, stdout)
+	notifier.events = recorder
+	notifier.templateSource = source
+	notifier.mailer = mailer
+	for _, name := range names {
+		tpl, err := getNamedTemplate(name, templates, legacy)
+		if tpl == nil {
+			log.Fatalf("Failed to initialize Shoutrrr notifications: %s\n", err.Error())
+		} else if err != nil {
+			log.Errorf("Could not use notification template %s: %s. Using default template", name, err)
+		}
+		notifier.templated = append(notifier.templated, templatedTarget{
+			name:     name,
+			urls:     named[name],
+			router:   newSender(stdout)(named[name]),
+			template: tpl,
+		})
+	}
+	if len(availableUrls) > 0 {
+		notifier.AvailableUrls = availableUrls
+		notifier.AvailableRouter = newSender(stdout)(availableUrls)
+	}
 	log.AddHook(notifier)
 
 	// Do the sending in a separate goroutine so we don't block the main process.
@@ -76,16 +157,7 @@ func createNotifier(urls []string, levels []log.Level, tplString string, legacy
 		log.Errorf("Could not use configured notification template: %s. Using default template", err)
 	}
 
-	var logger types.StdLogger
-	if stdout {
-		logger = stdlog.New(os.Stdout, ``, 0)
-	} else {
-		logger = stdlog.New(log.StandardLogger().WriterLevel(log.TraceLevel), "Shoutrrr: ", 0)
-	}
-	r, err := shoutrrr.NewSender(logger, urls...)
-	if err != nil {
-		log.Fatalf("Failed to initialize Shoutrrr notifications: %s\n", err.Error())
-	}
+	r := newSender(stdout)(urls)
 
 	params := &types.Params{}
 	if data.Title != "" {
@@ -95,7 +167,7 @@ func createNotifier(urls []string, levels []log.Level, tplString string, legacy
 	return &shoutrrrTypeNotifier{
 		Urls:           urls,
 		Router:         r,
-		messages:       make(chan string, 1),
+		messages:       make(chan message, 1),
 		done:           make(chan bool),
 		logLevels:      levels,
 		template:       tpl,
@@ -105,14 +177,50 @@ func createNotifier(urls []string, levels []log.Level, tplString string, legacy
 	}
 }
 
+// newSender returns a function creating the router sending the notifications to the URLs, using shoutrrr for the
+// ones that are not of the services implemented by watchtower
+func newSender(stdout bool) func(urls []string) router {
+	var logger types.StdLogger
+	if stdout {
+		logger = stdlog.New(os.Stdout, ``, 0)
+	} else {
+		logger = stdlog.New(log.StandardLogger().WriterLevel(log.TraceLevel), "Shoutrrr: ", 0)
+	}
+	return func(urls []string) router {
+		r, err := newServiceRouter(urls, func(urls []string) (router, error) {
+			r, err := shoutrrr.NewSender(logger, urls...)
+			if err != nil {
+				return nil, err
+			}
+			return r, nil
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize Shoutrrr notifications: %s\n", err.Error())
+		}
+		return r
+	}
+}
+
 func sendNotifications(n *shoutrrrTypeNotifier, delay time.Duration) {
 	for msg := range n.messages {
 		time.Sleep(delay)
-		errs := n.Router.Send(msg, n.params)
+		r, urls := n.Router, n.Urls
+		if msg.available {
+			r, urls = n.AvailableRouter, n.AvailableUrls
+		} else if msg.target != nil {
+			r, urls = msg.target.router, msg.target.urls
+		}
+		var errs []error
+		if msg.mail != nil {
+			urls = []string{mailerScheme}
+			errs = []error{n.mailer.send(*msg.mail)}
+		} else {
+			errs = send(r, msg, n.params)
+		}
 
 		for i, err := range errs {
 			if err != nil {
-				scheme := GetScheme(n.Urls[i])
+				scheme := GetScheme(urls[i])
 				// Use fmt so it doesn't trigger another notification.
 				LocalLog.WithFields(log.Fields{
 					"service": scheme,
@@ -120,18 +228,68 @@ func sendNotifications(n *shoutrrrTypeNotifier, delay time.Duration) {
 				}).WithError(err).Error("Failed to send shoutrrr notification")
 			}
 		}
+		n.recordSent(urls, errs, msg.available)
 	}
 
 	n.done <- true
 }
 
+// recordSent records the notification sent to each of the URLs, along with the error sending it failed with
+func (n *shoutrrrTypeNotifier) recordSent(urls []string, errs []error, available bool) {
+	if n.events == nil {
+		return
+	}
+	for i, u := range urls {
+		event := t.Event{
+			Time:    time.Now(),
+			Type:    events.NotificationSent,
+			Details: map[string]interface{}{"service": GetScheme(u), "index": i, "updatesAvailable": available},
+		}
+		if i < len(errs) && errs[i] != nil {
+			event.Error = errs[i].Error()
+		}
+		n.events.Record(event)
+	}
+}
+
 func (n *shoutrrrTypeNotifier) buildMessage(data Data) (string, error) {
+	return n.executeTemplate(n.currentTemplate(), data)
+}
+
+// currentTemplate returns the global template, first replacing it by the one of the template source if that has
+// changed. The previous template is kept if the new one could not be loaded or parsed.
+func (n *shoutrrrTypeNotifier) currentTemplate() *template.Template {
+	if n.templateSource == nil {
+		return n.template
+	}
+	n.templateMu.Lock()
+	defer n.templateMu.Unlock()
+
+	text, changed, err := n.templateSource.load()
+	if err != nil {
+		LocalLog.WithError(err).Warn("Could not reload the notification template, using the previous one")
+		return n.template
+	}
+	if !changed {
+		return n.template
+	}
+	tpl, err := getShoutrrrTemplate(text, n.legacyTemplate)
+	if err != nil {
+		LocalLog.WithError(err).Error("Could not use the changed notification template, using the previous one")
+		return n.template
+	}
+	LocalLog.WithField("template", n.templateSource.location).Info("Reloaded the notification template")
+	n.template = tpl
+	return n.template
+}
+
+func (n *shoutrrrTypeNotifier) executeTemplate(tpl *template.Template, data Data) (string, error) {
 	var body bytes.Buffer
 	var templateData interface{} = data
 	if n.legacyTemplate {
 		templateData = data.Entries
 	}
-	if err := n.template.Execute(&body, templateData); err != nil {
+	if err := tpl.Execute(&body, templateData); err != nil {
 		return "", err
 	}
 
@@ -139,14 +297,33 @@ func (n *shoutrrrTypeNotifier) buildMessage(data Data) (string, error) {
 }
 
 func (n *shoutrrrTypeNotifier) sendEntries(entries []*log.Entry, report t.Report) {
-	msg, err := n.buildMessage(Data{n.data, entries, report})
+	data := Data{n.data, entries, report}
+	// the global template is only skipped when all the URLs use their own, and there are no emails to send
+	global := len(n.Urls) > 0 || (len(n.templated) == 0 && n.mailer == nil)
+	if global || n.mailer != nil {
+		msg, err := n.buildMessage(data)
+		if global {
+			n.queue(message{body: msg, report: report}, err, len(n.Urls))
+		}
+		if n.mailer != nil {
+			n.queueMail(data, msg, err, global)
+		}
+	}
+	for i := range n.templated {
+		target := &n.templated[i]
+		msg, err := n.executeTemplate(target.template, data)
+		n.queue(message{body: msg, target: target, report: report}, err, len(target.urls))
+	}
+}
 
-	if msg == "" {
+// queue queues the message to be sent, unless building it failed or it is empty
+func (n *shoutrrrTypeNotifier) queue(msg message, err error, urls int) {
+	if msg.body == "" {
 		// Log in go func in case we entered from Fire to avoid stalling
 		go func() {
 			if err != nil {
 				LocalLog.WithError(err).Fatal("Notification template error")
-			} else if len(n.Urls) > 1 {
+			} else if urls > 1 {
 				LocalLog.Info("Skipping notification due to empty message")
 			}
 		}()
@@ -155,6 +332,23 @@ func (n *shoutrrrTypeNotifier) sendEntries(entries []*log.Entry, report t.Report
 	n.messages <- msg
 }
 
+// queueMail queues the email of the notification with the text body, unless building the body failed, in which case
+// the error has already been queued along with the global message
+func (n *shoutrrrTypeNotifier) queueMail(data Data, body string, err error, queued bool) {
+	if err != nil {
+		if !queued {
+			n.queue(message{}, err, 1)
+		}
+		return
+	}
+	email, err := n.mailer.build(data, body)
+	if err != nil {
+		n.queue(message{}, err, 1)
+		return
+	}
+	n.queue(message{body: body, mail: &email}, nil, 1)
+}
+
 // StartNotification begins queueing up messages to send them as a batch
 func (n *shoutrrrTypeNotifier) StartNotification() {
 	if n.entries == nil {
@@ -189,6 +383,10 @@ func (n *shoutrrrTypeNotifier) Fire(entry *log.Entry) error {
 		// Skip logging if explicitly tagged as non-notify
 		return nil
 	}
+	if !logging.Visible(entry) {
+		// Skip entries below the log level of their subsystem
+		return nil
+	}
 	if n.entries != nil {
 		n.entries = append(n.entries, entry)
 	} else {
@@ -198,13 +396,25 @@ func (n *shoutrrrTypeNotifier) Fire(entry *log.Entry) error {
 	return nil
 }
 
-func getShoutrrrTemplate(tplString string, legacy bool) (tpl *template.Template, err error) {
-	funcs := template.FuncMap{
+// templateFuncs returns the functions available to the notification templates
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
 		"ToUpper": strings.ToUpper,
 		"ToLower": strings.ToLower,
 		"Title":   cases.Title(language.AmericanEnglish).String,
+		"HumanSize": func(size int64) string {
+			return units.HumanSize(float64(size))
+		},
+		"SortBy":  sortBy,
+		"GroupBy": groupBy,
+		"ToJSON":  toJSON,
+		// EscapeMarkdownV2 escapes the text for the Telegram messages using the MarkdownV2 parse mode
+		"EscapeMarkdownV2": escapeMarkdownV2,
 	}
-	tplBase := template.New("").Funcs(funcs)
+}
+
+func getShoutrrrTemplate(tplString string, legacy bool) (tpl *template.Template, err error) {
+	tplBase := template.New("").Funcs(templateFuncs())
 
 	if builtin, found := commonTemplates[tplString]; found {
 		log.WithField(`template`, tplString).Debug(`Using common template`)
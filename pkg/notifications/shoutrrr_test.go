@@ -1,12 +1,18 @@
 package notifications
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/containrrr/shoutrrr/pkg/types"
+	"github.com/containrrr/watchtower/internal/actions"
 	"github.com/containrrr/watchtower/internal/actions/mocks"
 	"github.com/containrrr/watchtower/internal/flags"
+	"github.com/containrrr/watchtower/pkg/container"
 	s "github.com/containrrr/watchtower/pkg/session"
+	t "github.com/containrrr/watchtower/pkg/types"
+	dockerContainer "github.com/docker/docker/api/types/container"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
@@ -81,6 +87,82 @@ updt1 (mock/updt1:latest): Updated
 			data := mockDataFromStates(s.UpdatedState)
 			Expect(getTemplatedResult(`porcelain.v1.summary-no-log`, false, data)).To(Equal(expected))
 		})
+		It("should include the reasons of the skipped containers", func() {
+			expected := `
+skip1 (mock/skip1:latest): Skipped Reason: check-failed Error: unpossible
+`[1:]
+			data := mockDataFromStates(s.SkippedState)
+			Expect(getTemplatedResult(`porcelain.v1.summary-no-log`, false, data)).To(Equal(expected))
+		})
+	})
+
+	When("rendering the notification as JSON", func() {
+		It("should include the report and the entries", func() {
+			data := mockDataFromStates(s.UpdatedState, s.FailedState)
+			var rendered struct {
+				Host    string
+				Entries []map[string]interface{}
+				Report  struct {
+					Updated []map[string]interface{}
+					Failed  []map[string]interface{}
+				}
+			}
+			Expect(json.Unmarshal([]byte(getTemplatedResult(`json.v1`, false, data)), &rendered)).To(Succeed())
+			Expect(rendered.Host).To(Equal("Mock"))
+			Expect(rendered.Entries).To(HaveLen(len(legacyMockData.Entries)))
+			Expect(rendered.Report.Updated).To(HaveLen(1))
+			Expect(rendered.Report.Updated[0]).To(HaveKeyWithValue("name", "updt1"))
+			Expect(rendered.Report.Updated[0]).To(HaveKeyWithValue("state", "updated"))
+			Expect(rendered.Report.Failed[0]).To(HaveKeyWithValue("error", "accidentally the whole container"))
+		})
+	})
+
+	When("using the report groups and sort helpers", func() {
+		It("should group the containers by state, failed first", func() {
+			data := mockDataFromStates(s.FreshState, s.UpdatedState, s.FailedState, s.UpdatedState)
+			expected := `
+Failed: fail1
+Updated: updt1 updt2
+Fresh: frsh1
+`[1:]
+			tpl := `{{range .ByState}}{{.Key}}:{{range .Containers}} {{.Name}}{{end}}{{println}}{{end}}`
+			Expect(getTemplatedResult(tpl, false, data)).To(Equal(expected))
+		})
+		It("should group the containers by compose project, standalone ones last", func() {
+			progress := s.Progress{}
+			for i, project := range []string{"", "web", "db", "web"} {
+				config := &dockerContainer.Config{Labels: map[string]string{}}
+				if project != "" {
+					config.Labels["com.docker.compose.project"] = project
+				}
+				c := mocks.CreateMockContainerWithConfig(fmt.Sprintf("c%d", i), fmt.Sprintf("/app%d", i), "image", true, false, time.Now(), config)
+				progress.AddScanned(c, c.ImageID())
+			}
+			data := Data{Report: progress.Report()}
+			expected := `
+db: /app2
+web: /app1 /app3
+: /app0
+`[1:]
+			tpl := `{{range .ByProject}}{{.Key}}:{{range .Containers}} {{.Name}}{{end}}{{println}}{{end}}`
+			Expect(getTemplatedResult(tpl, false, data)).To(Equal(expected))
+		})
+		It("should group all the containers under the host", func() {
+			data := mockDataFromStates(s.UpdatedState, s.FreshState)
+			tpl := `{{range .ByHost}}{{.Key}}: {{len .Containers}}{{end}}`
+			Expect(getTemplatedResult(tpl, false, data)).To(Equal("Mock: 2"))
+		})
+		It("should sort and group any list of containers", func() {
+			data := mockDataFromStates(s.UpdatedState, s.UpdatedState, s.FreshState)
+			tpl := `{{range SortBy "-name" .Report.All}}{{.Name}} {{end}}|{{range GroupBy "state" .Report.All}} {{.Key}}={{len .Containers}}{{end}}`
+			Expect(getTemplatedResult(tpl, false, data)).To(Equal("updt2 updt1 frsh1 | Updated=2 Fresh=1"))
+		})
+		It("should fail on unknown fields", func() {
+			notifier, err := createNotifierWithTemplate(`{{SortBy "color" .Report.All}}`, false)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = notifier.buildMessage(mockDataFromStates(s.UpdatedState))
+			Expect(err).To(HaveOccurred())
+		})
 	})
 
 	When("using legacy templates", func() {
@@ -184,7 +266,7 @@ updt1 (mock/updt1:latest): Updated
 - updt1 (mock/updt1:latest): 01d110000000 updated to d0a110000000
 - updt2 (mock/updt2:latest): 01d120000000 updated to d0a120000000
 - frsh1 (mock/frsh1:latest): Fresh
-- skip1 (mock/skip1:latest): Skipped: unpossible
+- skip1 (mock/skip1:latest): Skipped (check-failed): unpossible
 - fail1 (mock/fail1:latest): Failed: accidentally the whole container`
 				data := mockDataFromStates(s.UpdatedState, s.FreshState, s.FailedState, s.SkippedState, s.UpdatedState)
 				Expect(getTemplatedResult(``, false, data)).To(Equal(expected))
@@ -222,6 +304,20 @@ updt1 (mock/updt1:latest): Updated
 					Expect(getTemplatedResult(``, false, data)).To(Equal(expected))
 				})
 			})
+			When("the references of the images are known", func() {
+				It("should list them along with the link to the registry", func() {
+					c, newImage := mocks.CreateContainerForProgress(0, 11, "updt%d")
+					progress := s.Progress{}
+					progress.AddScanned(c, newImage)
+					progress.MarkForUpdate(c.ID())
+					progress.SetReferences(c.ID(), "mock/updt1:latest@sha256:01d1", "mock/updt1:latest@sha256:d0a1",
+						"https://hub.docker.com/r/mock/updt1/tags?name=latest")
+					expected := `1 Scanned, 1 Updated, 0 Failed
+- updt1 (mock/updt1:latest): 01d110000000 updated to d0a110000000
+  mock/updt1:latest@sha256:01d1 → mock/updt1:latest@sha256:d0a1 (https://hub.docker.com/r/mock/updt1/tags?name=latest)`
+					Expect(getTemplatedResult(``, false, Data{Report: progress.Report()})).To(Equal(expected))
+				})
+			})
 			When("at least one container failed to update", func() {
 				It("should send a report", func() {
 					expected := `1 Scanned, 0 Updated, 1 Failed
@@ -245,15 +341,35 @@ Turns out everything is on fire
 	When("batching notifications", func() {
 		When("no messages are queued", func() {
 			It("should not send any notification", func() {
-				shoutrrr := newShoutrrrNotifier("", allButTrace, true, StaticData{}, time.Duration(0), false, "logger://")
+				shoutrrr := newShoutrrrNotifier("", nil, nil, nil, allButTrace, true, StaticData{}, time.Duration(0), false, false, nil, "logger://")
 				shoutrrr.StartNotification()
 				shoutrrr.SendNotification(nil)
 				Consistently(logBuffer).ShouldNot(gbytes.Say(`Shoutrrr:`))
 			})
 		})
+		When("the only failed checks are within the error budget", func() {
+			It("should not send any notification", func() {
+				testData := &mocks.TestData{
+					Containers: []container.Container{
+						mocks.CreateMockContainer("test-container-01", "test-container-01", "fake-image:latest", time.Now()),
+					},
+					CheckFailures: map[string]int{"test-container-01": 1},
+				}
+				// the notifiers of the previous tests would send the logs of the session right away
+				hooks := logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+				defer logrus.StandardLogger().ReplaceHooks(hooks)
+				shoutrrr := newShoutrrrNotifier("", nil, nil, nil, allButTrace, false, StaticData{}, time.Duration(0), false, false, nil, "logger://")
+				shoutrrr.StartNotification()
+				report, err := actions.Update(mocks.CreateMockClient(testData, false, false), t.UpdateParams{ErrorBudget: s.NewErrorBudget(2, nil)})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(reportSeverity(report)).To(Equal(severityNone))
+				shoutrrr.SendNotification(report)
+				Consistently(logBuffer).ShouldNot(gbytes.Say(`Shoutrrr:`))
+			})
+		})
 		When("at least one message is queued", func() {
 			It("should send a notification", func() {
-				shoutrrr := newShoutrrrNotifier("", allButTrace, true, StaticData{}, time.Duration(0), false, "logger://")
+				shoutrrr := newShoutrrrNotifier("", nil, nil, nil, allButTrace, true, StaticData{}, time.Duration(0), false, false, nil, "logger://")
 				shoutrrr.StartNotification()
 				logrus.Info("This log message is sponsored by ContainrrrVPN")
 				shoutrrr.SendNotification(nil)
@@ -291,8 +407,122 @@ Turns out everything is on fire
 			Eventually(blockingRouter.sent).Should(Receive(BeTrue()))
 		})
 	})
+	When("notifying of available updates", func() {
+		It("should send the session reports and the available updates to the selected URLs", func() {
+			applied, available, err := splitPhases([]string{
+				"logger://",
+				"generic://example.com/hook?watchtower-phases=available&title=foo",
+				"generic://example.com/report?watchtower-phases=applied",
+			}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applied).To(Equal([]string{"logger://", "generic://example.com/report"}))
+			Expect(available).To(Equal([]string{"generic://example.com/hook?title=foo"}))
+
+			applied, available, err = splitPhases([]string{"logger://"}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applied).To(Equal([]string{"logger://"}))
+			Expect(available).To(Equal([]string{"logger://"}))
+		})
+		It("should reject unknown phases", func() {
+			_, _, err := splitPhases([]string{"logger://?watchtower-phases=sometime"}, false)
+			Expect(err).To(HaveOccurred())
+		})
+		It("should only send the available updates to their URLs", func() {
+			tpl, err := getShoutrrrTemplate("", false)
+			Expect(err).NotTo(HaveOccurred())
+			reports := &recordingRouter{}
+			available := &recordingRouter{}
+			shoutrrr := &shoutrrrTypeNotifier{
+				template:        tpl,
+				messages:        make(chan message, 1),
+				done:            make(chan bool),
+				Router:          reports,
+				AvailableRouter: available,
+				params:          &types.Params{},
+			}
+			go sendNotifications(shoutrrr, time.Duration(0))
+
+			applyAt := time.Date(2022, 10, 14, 4, 0, 0, 0, time.UTC)
+			shoutrrr.SendUpdatesAvailable(t.UpdatesAvailable{
+				Updates: mocks.CreateMockProgressReport(s.StaleState).Stale(),
+				ApplyAt: applyAt,
+			})
+			shoutrrr.Close()
+
+			Expect(reports.messages).To(BeEmpty())
+			Expect(available.messages).To(Equal([]string{`stal1 (mock/stal1:latest): 01d510000000 can be updated to d0a510000000
+The updates are scheduled to be applied at 2022-10-14 04:00:00 +0000 UTC.`}))
+		})
+		It("should not send anything without URLs receiving the available updates", func() {
+			shoutrrr := createNotifier([]string{"logger://"}, allButTrace, "", false, StaticData{}, false)
+			shoutrrr.SendUpdatesAvailable(t.UpdatesAvailable{
+				Updates: mocks.CreateMockProgressReport(s.StaleState).Stale(),
+			})
+			Expect(shoutrrr.messages).To(BeEmpty())
+		})
+	})
+	When("using a template per URL", func() {
+		It("should group the URLs by the template they name", func() {
+			global, named, names, err := splitTemplates([]string{
+				"logger://",
+				"generic://example.com/a?watchtower-template=brief&title=foo",
+				"generic://example.com/b?watchtower-template=porcelain.v1.summary-no-log",
+				"generic://example.com/c?watchtower-template=brief",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(global).To(Equal([]string{"logger://"}))
+			Expect(names).To(Equal([]string{"brief", "porcelain.v1.summary-no-log"}))
+			Expect(named["brief"]).To(Equal([]string{"generic://example.com/a?title=foo", "generic://example.com/c"}))
+		})
+		It("should look the templates up in the config file, then in the common templates", func() {
+			templates := map[string]string{"brief": `{{len .Entries}} entries`}
+			tpl, err := getNamedTemplate("brief", templates, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tpl.Execute(gbytes.NewBuffer(), Data{})).To(Succeed())
+
+			_, err = getNamedTemplate("porcelain.v1.summary-no-log", templates, false)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = getNamedTemplate("verbose", templates, false)
+			Expect(err).To(HaveOccurred())
+		})
+		It("should send the reports built using each template to its URLs", func() {
+			tpl, err := getShoutrrrTemplate("", false)
+			Expect(err).NotTo(HaveOccurred())
+			brief, err := getNamedTemplate("brief", map[string]string{"brief": `{{len .Report.Updated}} updated`}, false)
+			Expect(err).NotTo(HaveOccurred())
+			reports := &recordingRouter{}
+			briefReports := &recordingRouter{}
+			shoutrrr := &shoutrrrTypeNotifier{
+				template:  tpl,
+				messages:  make(chan message, 1),
+				done:      make(chan bool),
+				Urls:      []string{"logger://"},
+				Router:    reports,
+				templated: []templatedTarget{{name: "brief", urls: []string{"logger://"}, router: briefReports, template: brief}},
+				params:    &types.Params{},
+			}
+			go sendNotifications(shoutrrr, time.Duration(0))
+
+			shoutrrr.SendNotification(mocks.CreateMockProgressReport(s.UpdatedState))
+			shoutrrr.Close()
+
+			Expect(reports.messages).To(HaveLen(1))
+			Expect(reports.messages[0]).To(ContainSubstring("updt1"))
+			Expect(briefReports.messages).To(Equal([]string{"1 updated"}))
+		})
+	})
 })
 
+type recordingRouter struct {
+	messages []string
+}
+
+func (r *recordingRouter) Send(message string, _ *types.Params) []error {
+	r.messages = append(r.messages, message)
+	return nil
+}
+
 type blockingRouter struct {
 	unlock chan bool
 	sent   chan bool
@@ -316,7 +546,7 @@ func sendNotificationsWithBlockingRouter(legacy bool) (*shoutrrrTypeNotifier, *b
 
 	shoutrrr := &shoutrrrTypeNotifier{
 		template:       tpl,
-		messages:       make(chan string, 1),
+		messages:       make(chan message, 1),
 		done:           make(chan bool),
 		Router:         router,
 		legacyTemplate: legacy,
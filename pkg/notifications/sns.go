@@ -0,0 +1,239 @@
+package notifications
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/containrrr/shoutrrr/pkg/types"
+	t "github.com/containrrr/watchtower/pkg/types"
+)
+
+const (
+	snsScheme = "sns"
+	sqsScheme = "sqs"
+)
+
+// snsMaxSubject is the maximum length of the subject of an SNS message, in characters
+const snsMaxSubject = 100
+
+// awsResource is the resource of an sns:// or sqs:// URL, like sns://us-east-1/123456789012/watchtower, where the
+// host is the region, defaulting to the one of the environment, and the path is the account and the name of the
+// resource. The query may set the profile of the shared configuration, the endpoint of the service, and the message
+// group of FIFO topics and queues.
+type awsResource struct {
+	region  string
+	account string
+	name    string
+	group   string
+	// endpoint is the endpoint of the service set by the URL, if any, which the SDK resolves otherwise
+	endpoint string
+	config   aws.Config
+}
+
+// newAWSResource returns the resource of the URL, along with the configuration of the clients of the services
+func newAWSResource(u *url.URL, service string) (*awsResource, error) {
+	account, name, _ := strings.Cut(strings.Trim(u.Path, "/"), "/")
+	if account == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("expected a %s URL like %s://region/account/name", service, service)
+	}
+	if _, err := strconv.ParseUint(account, 10, 64); err != nil || len(account) != 12 {
+		return nil, fmt.Errorf("invalid AWS account %q, expected 12 digits", account)
+	}
+
+	query := u.Query()
+	for key := range query {
+		if key != "endpoint" && key != "profile" && key != "group" {
+			return nil, fmt.Errorf("unknown %s parameter %s", service, key)
+		}
+	}
+	cfg, err := loadAWSConfig(u.Hostname(), query.Get("profile"))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" {
+		return nil, errors.New("the AWS region is neither in the URL nor set by AWS_REGION")
+	}
+	group := query.Get("group")
+	if group == "" {
+		group = "watchtower"
+	}
+	return &awsResource{
+		region:   cfg.Region,
+		account:  account,
+		name:     name,
+		group:    group,
+		endpoint: query.Get("endpoint"),
+		config:   cfg,
+	}, nil
+}
+
+// baseEndpoint returns the endpoint of the service set by the URL, or nil to leave it to the SDK
+func (r *awsResource) baseEndpoint() *string {
+	if r.endpoint == "" {
+		return nil
+	}
+	return aws.String(r.endpoint)
+}
+
+// fifo returns whether the resource is a FIFO topic or queue, which needs the group and the deduplication ID of the
+// messages
+func (r *awsResource) fifo() bool {
+	return strings.HasSuffix(r.name, ".fifo")
+}
+
+// fifoMessage returns the group and the deduplication ID of the message for FIFO resources, and nils otherwise
+func (r *awsResource) fifoMessage(message string) (group *string, deduplicationID *string) {
+	if !r.fifo() {
+		return nil, nil
+	}
+	// the same notification may be sent again later, and should not be dropped as a duplicate
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d\n%s", time.Now().UnixNano(), message)))
+	return aws.String(r.group), aws.String(hex.EncodeToString(hash[:]))
+}
+
+// messageAttributes returns the attributes of the messages published to SNS and SQS, which subscriptions and
+// consumers can filter the notifications by
+func messageAttributes(report t.Report, params *types.Params) map[string]string {
+	attributes := map[string]string{"severity": "none"}
+	outcome := reportSeverity(report)
+	for name, sev := range severityNames {
+		if sev == outcome {
+			attributes["severity"] = name
+		}
+	}
+	if params != nil {
+		attributes["title"], _ = params.Title()
+	}
+	return attributes
+}
+
+// snsService publishes the notifications to an SNS topic, like sns://us-east-1/123456789012/watchtower, so that they
+// fan out to its subscriptions. The messages have the severity of the session as an attribute, for filter policies.
+type snsService struct {
+	*awsResource
+	client *sns.Client
+}
+
+// newSNSService returns the service publishing to the topic of the URL
+func newSNSService(u *url.URL) (service, error) {
+	resource, err := newAWSResource(u, snsScheme)
+	if err != nil {
+		return nil, err
+	}
+	client := sns.NewFromConfig(resource.config, func(o *sns.Options) {
+		o.BaseEndpoint = resource.baseEndpoint()
+	})
+	return &snsService{resource, client}, nil
+}
+
+// topicARN returns the ARN of the topic
+func (s *snsService) topicARN() string {
+	return fmt.Sprintf("arn:%s:sns:%s:%s:%s", awsPartition(s.region), s.region, s.account, s.name)
+}
+
+// Send implements service, publishing the message to the topic
+func (s *snsService) Send(message string, report t.Report, params *types.Params) error {
+	attributes := messageAttributes(report, params)
+	input := &sns.PublishInput{
+		TopicArn:          aws.String(s.topicARN()),
+		Message:           aws.String(message),
+		MessageAttributes: map[string]snstypes.MessageAttributeValue{},
+	}
+	if subject := snsSubject(attributes["title"]); subject != "" {
+		input.Subject = aws.String(subject)
+	}
+	for name, value := range attributes {
+		if value != "" {
+			input.MessageAttributes[name] = snstypes.MessageAttributeValue{
+				DataType:    aws.String("String"),
+				StringValue: aws.String(value),
+			}
+		}
+	}
+	input.MessageGroupId, input.MessageDeduplicationId = s.fifoMessage(message)
+
+	ctx, cancel := context.WithTimeout(context.Background(), awsTimeout)
+	defer cancel()
+	_, err := s.client.Publish(ctx, input)
+	return err
+}
+
+// snsSubject returns the title as the subject of an SNS message, which is used by the email subscriptions and must
+// be a single line of printable ASCII characters, of at most 100 of them
+func snsSubject(title string) string {
+	subject := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, title)
+	subject = strings.TrimSpace(subject)
+	if len(subject) > snsMaxSubject {
+		subject = subject[:snsMaxSubject]
+	}
+	return subject
+}
+
+// sqsService sends the notifications to an SQS queue, like sqs://us-east-1/123456789012/watchtower. The messages have
+// the severity of the session and the title of the notification as attributes.
+type sqsService struct {
+	*awsResource
+	client *sqs.Client
+}
+
+// newSQSService returns the service sending to the queue of the URL
+func newSQSService(u *url.URL) (service, error) {
+	resource, err := newAWSResource(u, sqsScheme)
+	if err != nil {
+		return nil, err
+	}
+	client := sqs.NewFromConfig(resource.config, func(o *sqs.Options) {
+		o.BaseEndpoint = resource.baseEndpoint()
+	})
+	return &sqsService{resource, client}, nil
+}
+
+// queueURL returns the URL of the queue
+func (s *sqsService) queueURL() string {
+	endpoint := s.endpoint
+	if endpoint == "" {
+		endpoint = awsEndpoint(sqsScheme, s.region)
+	}
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), s.account, s.name)
+}
+
+// Send implements service, sending the message to the queue
+func (s *sqsService) Send(message string, report t.Report, params *types.Params) error {
+	input := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(s.queueURL()),
+		MessageBody:       aws.String(message),
+		MessageAttributes: map[string]sqstypes.MessageAttributeValue{},
+	}
+	for name, value := range messageAttributes(report, params) {
+		if value != "" {
+			input.MessageAttributes[name] = sqstypes.MessageAttributeValue{
+				DataType:    aws.String("String"),
+				StringValue: aws.String(value),
+			}
+		}
+	}
+	input.MessageGroupId, input.MessageDeduplicationId = s.fifoMessage(message)
+
+	ctx, cancel := context.WithTimeout(context.Background(), awsTimeout)
+	defer cancel()
+	_, err := s.client.SendMessage(ctx, input)
+	return err
+}
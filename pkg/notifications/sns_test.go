@@ -0,0 +1,154 @@
+package notifications
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/containrrr/shoutrrr/pkg/types"
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	s "github.com/containrrr/watchtower/pkg/session"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the SNS and SQS services", func() {
+	var server *httptest.Server
+	var paths []string
+	var sent []url.Values
+	var restore func()
+
+	BeforeEach(func() {
+		paths = nil
+		sent = nil
+		restore = setAWSEnvironment(map[string]string{
+			"AWS_ACCESS_KEY_ID":         "id",
+			"AWS_SECRET_ACCESS_KEY":     "secret",
+			"AWS_CONFIG_FILE":           os.DevNull,
+			"AWS_EC2_METADATA_DISABLED": "true",
+		})
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Authorization")).To(HavePrefix("AWS4-HMAC-SHA256 Credential=id/"))
+			Expect(r.ParseForm()).To(Succeed())
+			if strings.HasSuffix(r.PostForm.Get("QueueUrl"), "missing") || r.PostForm.Get("TopicArn") == "arn:aws:sns:eu-west-1:123456789012:missing" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>NotFound</Code>`+
+					`<Message>Topic does not exist</Message></Error></ErrorResponse>`)
+				return
+			}
+			paths = append(paths, r.URL.Path)
+			sent = append(sent, r.PostForm)
+			action := r.PostForm.Get("Action")
+			_, _ = fmt.Fprintf(w, `<%sResponse><%sResult><MessageId>1</MessageId></%sResult></%sResponse>`,
+				action, action, action, action)
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+		restore()
+	})
+
+	newTestService := func(rawURL string) service {
+		svc, err := newService(rawURL + "?endpoint=" + url.QueryEscape(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		return svc
+	}
+	// attributes returns the values of the message attributes of the form, by their name
+	attributes := func(form url.Values, prefix string) map[string]string {
+		values := map[string]string{}
+		for i := 1; form.Get(fmt.Sprintf("%s.%d.Name", prefix, i)) != ""; i++ {
+			entry := fmt.Sprintf("%s.%d.", prefix, i)
+			Expect(form.Get(entry + "Value.DataType")).To(Equal("String"))
+			values[form.Get(entry+"Name")] = form.Get(entry + "Value.StringValue")
+		}
+		return values
+	}
+	params := func(title string) *types.Params {
+		p := &types.Params{}
+		p.SetTitle(title)
+		return p
+	}
+
+	It("should publish the notifications to the topic, along with their severity", func() {
+		svc := newTestService("sns://eu-west-1/123456789012/watchtower")
+		report := mocks.CreateMockProgressReport(s.UpdatedState, s.FailedState)
+		Expect(svc.Send("failed to update", report, params("Watchtower updates on\nhost"))).To(Succeed())
+		Expect(svc.Send("started", nil, nil)).To(Succeed())
+
+		Expect(paths).To(Equal([]string{"/", "/"}))
+		Expect(sent[0].Get("Action")).To(Equal("Publish"))
+		Expect(sent[0].Get("TopicArn")).To(Equal("arn:aws:sns:eu-west-1:123456789012:watchtower"))
+		Expect(sent[0].Get("Message")).To(Equal("failed to update"))
+		Expect(sent[0].Get("Subject")).To(Equal("Watchtower updates onhost"))
+		Expect(attributes(sent[0], "MessageAttributes.entry")).To(Equal(map[string]string{
+			"severity": "failed",
+			"title":    "Watchtower updates on\nhost",
+		}))
+		Expect(sent[1]).NotTo(HaveKey("Subject"))
+		Expect(attributes(sent[1], "MessageAttributes.entry")).To(Equal(map[string]string{"severity": "none"}))
+	})
+
+	It("should send the notifications to the queue", func() {
+		svc := newTestService("sqs://eu-west-1/123456789012/watchtower")
+		Expect(svc.Send("updated", mocks.CreateMockProgressReport(s.UpdatedState), params("Updates"))).To(Succeed())
+
+		Expect(sent[0].Get("Action")).To(Equal("SendMessage"))
+		Expect(sent[0].Get("QueueUrl")).To(Equal(server.URL + "/123456789012/watchtower"))
+		Expect(sent[0].Get("MessageBody")).To(Equal("updated"))
+		Expect(attributes(sent[0], "MessageAttribute")).To(Equal(map[string]string{
+			"severity": "updated",
+			"title":    "Updates",
+		}))
+		Expect(sent[0]).NotTo(HaveKey("MessageGroupId"))
+	})
+
+	It("should set the group and a deduplication ID of the FIFO queues", func() {
+		svc := newTestService("sqs://eu-west-1/123456789012/watchtower.fifo")
+		Expect(svc.Send("updated", nil, nil)).To(Succeed())
+		Expect(svc.Send("updated", nil, nil)).To(Succeed())
+
+		Expect(sent[0].Get("MessageGroupId")).To(Equal("watchtower"))
+		Expect(sent[0].Get("MessageDeduplicationId")).NotTo(BeEmpty())
+		Expect(sent[1].Get("MessageDeduplicationId")).NotTo(Equal(sent[0].Get("MessageDeduplicationId")))
+	})
+
+	It("should return the errors of AWS", func() {
+		err := newTestService("sns://eu-west-1/123456789012/missing").Send("updated", nil, nil)
+		Expect(err).To(MatchError(ContainSubstring("SNS: Publish")))
+		Expect(err).To(MatchError(ContainSubstring("NotFound: Topic does not exist")))
+		err = newTestService("sqs://eu-west-1/123456789012/missing").Send("updated", nil, nil)
+		Expect(err).To(MatchError(ContainSubstring("SQS: SendMessage")))
+		Expect(err).To(MatchError(ContainSubstring("NotFound: Topic does not exist")))
+	})
+
+	It("should use the regional endpoints and the ARNs of the partition of the region", func() {
+		svc, err := newService("sns://cn-north-1/123456789012/watchtower")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(svc.(*snsService).topicARN()).To(Equal("arn:aws-cn:sns:cn-north-1:123456789012:watchtower"))
+		svc, err = newService("sqs://cn-north-1/123456789012/watchtower")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(svc.(*sqsService).queueURL()).To(Equal("https://sqs.cn-north-1.amazonaws.com.cn/123456789012/watchtower"))
+	})
+
+	It("should use the region of the environment", func() {
+		Expect(os.Setenv("AWS_REGION", "ap-south-1")).To(Succeed())
+		svc, err := newService("sns:///123456789012/watchtower")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(svc.(*snsService).topicARN()).To(Equal("arn:aws:sns:ap-south-1:123456789012:watchtower"))
+	})
+
+	It("should refuse invalid URLs", func() {
+		for _, rawURL := range []string{
+			"sns://eu-west-1/watchtower",
+			"sns://eu-west-1/1234/watchtower",
+			"sqs://eu-west-1/123456789012/queue/extra",
+			"sqs://eu-west-1/123456789012/watchtower?delay=5",
+		} {
+			_, err := newService(rawURL)
+			Expect(err).To(HaveOccurred(), rawURL)
+		}
+	})
+})
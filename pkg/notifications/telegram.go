@@ -0,0 +1,231 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/containrrr/shoutrrr/pkg/services/telegram"
+	"github.com/containrrr/shoutrrr/pkg/types"
+	t "github.com/containrrr/watchtower/pkg/types"
+)
+
+// telegramAPI is the address of the Bot API of Telegram
+const telegramAPI = "https://api.telegram.org"
+
+// telegramMaxLength is the maximum length of the text of a Telegram message, in characters
+const telegramMaxLength = 4096
+
+// telegramTimeout limits how long sending a message to Telegram may take
+const telegramTimeout = 30 * time.Second
+
+// telegramMaxRetryAfter is the longest watchtower waits before sending a message again once Telegram asked to slow down
+const telegramMaxRetryAfter = 30 * time.Second
+
+// telegramService sends the notifications to Telegram chats, rather than shoutrrr, to post to the topics of forums,
+// and to split the notifications longer than a message into several ones. It uses the same URLs as shoutrrr, like
+// telegram://token@telegram?chats=@channel,-1001234567890:42, where the number after the colon is the topic of a chat.
+type telegramService struct {
+	config *telegram.Config
+	api    string
+	client *http.Client
+	sleep  func(time.Duration)
+}
+
+// telegramPayload is the payload of the sendMessage method of the Bot API
+type telegramPayload struct {
+	ChatID              string `json:"chat_id"`
+	MessageThreadID     int64  `json:"message_thread_id,omitempty"`
+	Text                string `json:"text"`
+	ParseMode           string `json:"parse_mode,omitempty"`
+	DisablePreview      bool   `json:"disable_web_page_preview"`
+	DisableNotification bool   `json:"disable_notification"`
+}
+
+// telegramResponse is the answer of the Bot API
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// newTelegramService returns the service sending to the chats of the URL
+func newTelegramService(u *url.URL) (service, error) {
+	config := &telegram.Config{Preview: true, Notification: true}
+	if err := config.SetURL(u); err != nil {
+		return nil, err
+	}
+	for _, chat := range config.Chats {
+		if _, _, err := splitTelegramChat(chat); err != nil {
+			return nil, err
+		}
+	}
+	return &telegramService{
+		config: config,
+		api:    telegramAPI,
+		client: &http.Client{Timeout: telegramTimeout},
+		sleep:  time.Sleep,
+	}, nil
+}
+
+// splitTelegramChat returns the ID of the chat, along with the ID of its topic if it is followed by one
+func splitTelegramChat(chat string) (string, int64, error) {
+	id, topic, found := strings.Cut(chat, ":")
+	if !found {
+		return chat, 0, nil
+	}
+	thread, err := strconv.ParseInt(topic, 10, 64)
+	if err != nil || thread <= 0 {
+		return "", 0, fmt.Errorf("invalid topic %q of telegram chat %s", topic, id)
+	}
+	return id, thread, nil
+}
+
+// Send implements service, sending the message to each of the chats
+func (s *telegramService) Send(message string, _ t.Report, params *types.Params) error {
+	title := s.config.Title
+	if title == "" && params != nil {
+		title, _ = params.Title()
+	}
+	parseMode, messages := telegramMessages(message, title, s.config.ParseMode.String())
+
+	for _, chat := range s.config.Chats {
+		id, thread, _ := splitTelegramChat(chat)
+		for _, text := range messages {
+			err := s.sendMessage(telegramPayload{
+				ChatID:              id,
+				MessageThreadID:     thread,
+				Text:                text,
+				ParseMode:           parseMode,
+				DisablePreview:      !s.config.Preview,
+				DisableNotification: !s.config.Notification,
+			})
+			if err != nil {
+				return fmt.Errorf("could not send the message to telegram chat %s: %w", chat, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sendMessage sends the message, sending it again once if Telegram asks to wait a little
+func (s *telegramService) sendMessage(payload telegramPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	for retried := false; ; retried = true {
+		res, err := s.client.Post(fmt.Sprintf("%s/bot%s/sendMessage", s.api, s.config.Token), "application/json",
+			bytes.NewReader(body))
+		if err != nil {
+			// the error includes the URL, which includes the token
+			return fmt.Errorf("could not reach the telegram API: %w", stripToken(err, s.config.Token))
+		}
+		var answer telegramResponse
+		err = json.NewDecoder(res.Body).Decode(&answer)
+		_ = res.Body.Close()
+		if err == nil && answer.OK {
+			return nil
+		}
+		retryAfter := time.Duration(answer.Parameters.RetryAfter) * time.Second
+		if res.StatusCode == http.StatusTooManyRequests && !retried && retryAfter <= telegramMaxRetryAfter {
+			s.sleep(retryAfter)
+			continue
+		}
+		if answer.Description != "" {
+			return fmt.Errorf("%s: %s", res.Status, answer.Description)
+		}
+		return fmt.Errorf("unexpected answer of the telegram API: %s", res.Status)
+	}
+}
+
+// stripToken returns the error, with the token of the bot masked
+func stripToken(err error, token string) error {
+	return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), token, "<token>"))
+}
+
+// telegramMessages returns the parse mode and the texts of the messages to send, being the message split into parts
+// that fit in a Telegram message, with the title before the first one. Like shoutrrr, messages without a parse mode
+// are sent as HTML when they have a title, so that it can be bold.
+func telegramMessages(message string, title string, parseMode string) (string, []string) {
+	if parseMode == "None" {
+		parseMode = ""
+		if title != "" {
+			parseMode = "HTML"
+			message = html.EscapeString(message)
+		}
+	}
+
+	switch {
+	case title == "":
+	case parseMode == "HTML":
+		title = "<b>" + html.EscapeString(title) + "</b>"
+	case parseMode == "MarkdownV2":
+		title = "*" + escapeMarkdownV2(title) + "*"
+	default:
+		// the legacy Markdown has no escaping, so the title is left out, like shoutrrr does
+		title = ""
+	}
+	if title != "" {
+		message = title + "\n" + message
+	}
+	return parseMode, splitMessage(message, telegramMaxLength)
+}
+
+// splitMessage splits the message into parts of at most max characters, between its lines when possible. Lines
+// longer than that are split between characters, but never right after an escaping backslash.
+func splitMessage(message string, max int) []string {
+	if utf8.RuneCountInString(message) <= max {
+		return []string{message}
+	}
+	var parts []string
+	var part strings.Builder
+	partLength := 0
+	flush := func() {
+		if text := strings.TrimRight(part.String(), "\n"); text != "" {
+			parts = append(parts, text)
+		}
+		part.Reset()
+		partLength = 0
+	}
+	for _, line := range strings.SplitAfter(message, "\n") {
+		length := utf8.RuneCountInString(line)
+		if partLength+length > max {
+			flush()
+		}
+		for length > max {
+			runes := []rune(line)
+			cut := max
+			for cut > 1 && runes[cut-1] == '\\' {
+				cut--
+			}
+			parts = append(parts, string(runes[:cut]))
+			line = string(runes[cut:])
+			length -= cut
+		}
+		part.WriteString(line)
+		partLength += length
+	}
+	flush()
+	return parts
+}
+
+// markdownV2Escaper escapes the characters that have a meaning in the MarkdownV2 of Telegram
+var markdownV2Escaper = strings.NewReplacer(
+	`\`, `\\`, `_`, `\_`, `*`, `\*`, `[`, `\[`, `]`, `\]`, `(`, `\(`, `)`, `\)`, `~`, `\~`, "`", "\\`", `>`, `\>`,
+	`#`, `\#`, `+`, `\+`, `-`, `\-`, `=`, `\=`, `|`, `\|`, `{`, `\{`, `}`, `\}`, `.`, `\.`, `!`, `\!`,
+)
+
+// escapeMarkdownV2 escapes the text to be shown as is in a Telegram message using the MarkdownV2 parse mode
+func escapeMarkdownV2(text string) string {
+	return markdownV2Escaper.Replace(text)
+}
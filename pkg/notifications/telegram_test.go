@@ -0,0 +1,117 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/containrrr/shoutrrr/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the Telegram service", func() {
+	const token = "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw"
+	var server *httptest.Server
+	var sent []telegramPayload
+	var answers []int
+
+	BeforeEach(func() {
+		sent = nil
+		answers = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/bot" + token + "/sendMessage"))
+			var payload telegramPayload
+			Expect(json.NewDecoder(r.Body).Decode(&payload)).To(Succeed())
+			if len(answers) > 0 {
+				status := answers[0]
+				answers = answers[1:]
+				w.WriteHeader(status)
+				_, _ = fmt.Fprint(w, `{"ok":false,"description":"Too Many Requests: retry after 2","parameters":{"retry_after":2}}`)
+				return
+			}
+			sent = append(sent, payload)
+			_, _ = fmt.Fprint(w, `{"ok":true,"result":{"message_id":1}}`)
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newTestService := func(query string) *telegramService {
+		u, err := url.Parse("telegram://" + token + "@telegram?" + query)
+		Expect(err).NotTo(HaveOccurred())
+		svc, err := newTelegramService(u)
+		Expect(err).NotTo(HaveOccurred())
+		s := svc.(*telegramService)
+		s.api = server.URL
+		s.sleep = func(time.Duration) {}
+		return s
+	}
+	params := func(title string) *types.Params {
+		p := &types.Params{}
+		p.SetTitle(title)
+		return p
+	}
+
+	It("should post to the topic of a forum", func() {
+		Expect(newTestService("chats=-1001234567890:42,@channel").Send("updated", nil, nil)).To(Succeed())
+		Expect(sent).To(Equal([]telegramPayload{
+			{ChatID: "-1001234567890", MessageThreadID: 42, Text: "updated"},
+			{ChatID: "@channel", Text: "updated"},
+		}))
+	})
+
+	It("should refuse invalid topics", func() {
+		u, _ := url.Parse("telegram://" + token + "@telegram?chats=-100123:general")
+		_, err := newTelegramService(u)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should split the messages longer than the limit between their lines", func() {
+		line := strings.Repeat("a", 99) + "\n"
+		message := strings.Repeat(line, 50)
+		Expect(newTestService("chats=@channel").Send(message, nil, nil)).To(Succeed())
+		Expect(sent).To(HaveLen(2))
+		Expect(sent[0].Text).To(Equal(strings.TrimSuffix(strings.Repeat(line, 40), "\n")))
+		Expect(sent[1].Text).To(Equal(strings.TrimSuffix(strings.Repeat(line, 10), "\n")))
+	})
+
+	It("should escape the title using MarkdownV2", func() {
+		Expect(newTestService("chats=@channel&parsemode=MarkdownV2").Send("*bold*", nil, params("Updates on host-1.lan"))).To(Succeed())
+		Expect(sent).To(HaveLen(1))
+		Expect(sent[0].ParseMode).To(Equal("MarkdownV2"))
+		Expect(sent[0].Text).To(Equal("*Updates on host\\-1\\.lan*\n*bold*"))
+	})
+
+	It("should send the messages with a title as escaped HTML without a parse mode", func() {
+		Expect(newTestService("chats=@channel").Send("<none>", nil, params("Updates"))).To(Succeed())
+		Expect(sent[0].ParseMode).To(Equal("HTML"))
+		Expect(sent[0].Text).To(Equal("<b>Updates</b>\n&lt;none&gt;"))
+	})
+
+	It("should send the message again once Telegram asked to wait", func() {
+		answers = []int{http.StatusTooManyRequests}
+		Expect(newTestService("chats=@channel").Send("updated", nil, nil)).To(Succeed())
+		Expect(sent).To(HaveLen(1))
+
+		answers = []int{http.StatusTooManyRequests, http.StatusTooManyRequests}
+		Expect(newTestService("chats=@channel").Send("updated", nil, nil)).To(MatchError(ContainSubstring("Too Many Requests")))
+	})
+
+	It("should split lines longer than the limit without breaking escapes", func() {
+		Expect(splitMessage(`abc\.def`, 4)).To(Equal([]string{"abc", `\.de`, "f"}))
+	})
+
+	It("should escape the image names in templates", func() {
+		tpl, err := getShoutrrrTemplate(`{{ "ghcr.io/org/app:1.0-beta" | EscapeMarkdownV2 }}`, false)
+		Expect(err).NotTo(HaveOccurred())
+		var body strings.Builder
+		Expect(tpl.Execute(&body, nil)).To(Succeed())
+		Expect(body.String()).To(Equal(`ghcr\.io/org/app:1\.0\-beta`))
+	})
+})
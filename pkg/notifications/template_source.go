@@ -0,0 +1,129 @@
+package notifications
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// templateRecheckInterval limits how often a template served over HTTP is requested again to check whether it changed
+const templateRecheckInterval = time.Minute
+
+// templateFetchTimeout limits how long requesting a template served over HTTP may take
+const templateFetchTimeout = 30 * time.Second
+
+// templateSource reads the notification template from a file or an http(s) URL, reading it again once it has changed
+type templateSource struct {
+	location string
+	client   *http.Client
+	now      func() time.Time
+
+	mu sync.Mutex
+	// modTime is the modification time of the file when it was last read
+	modTime time.Time
+	// etag and lastModified identify the version of the template last served over HTTP, and checkedAt is when
+	etag         string
+	lastModified string
+	checkedAt    time.Time
+	// text is the template last loaded, if any
+	text   string
+	loaded bool
+}
+
+// newTemplateSource returns the source of the template at the location, being either a path or an http(s) URL
+func newTemplateSource(location string) *templateSource {
+	return &templateSource{
+		location: location,
+		client:   &http.Client{Timeout: templateFetchTimeout},
+		now:      time.Now,
+	}
+}
+
+// isURL returns whether the template is served over HTTP
+func (s *templateSource) isURL() bool {
+	return strings.HasPrefix(s.location, "http://") || strings.HasPrefix(s.location, "https://")
+}
+
+// load returns the text of the template if it has changed since it was last loaded, and whether it has. The template
+// is always loaded the first time.
+func (s *templateSource) load() (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wasLoaded := s.loaded
+	var text string
+	var read bool
+	var err error
+	if s.isURL() {
+		text, read, err = s.fetch()
+	} else {
+		text, read, err = s.read()
+	}
+	if err != nil || !read || (wasLoaded && text == s.text) {
+		return "", false, err
+	}
+	s.text = text
+	return text, true, nil
+}
+
+// read reads the template file, unless its modification time is the same as when it was last read
+func (s *templateSource) read() (string, bool, error) {
+	info, err := os.Stat(s.location)
+	if err != nil {
+		return "", false, fmt.Errorf("could not read the notification template file: %w", err)
+	}
+	if s.loaded && info.ModTime().Equal(s.modTime) {
+		return "", false, nil
+	}
+	content, err := os.ReadFile(s.location)
+	if err != nil {
+		return "", false, fmt.Errorf("could not read the notification template file: %w", err)
+	}
+	s.modTime = info.ModTime()
+	s.loaded = true
+	return string(content), true, nil
+}
+
+// fetch requests the template, at most once per recheck interval, asking the server to only send it if it has changed
+func (s *templateSource) fetch() (string, bool, error) {
+	now := s.now()
+	if s.loaded && now.Sub(s.checkedAt) < templateRecheckInterval {
+		return "", false, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, s.location, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid notification template URL: %w", err)
+	}
+	if s.loaded {
+		if s.etag != "" {
+			req.Header.Set("If-None-Match", s.etag)
+		}
+		if s.lastModified != "" {
+			req.Header.Set("If-Modified-Since", s.lastModified)
+		}
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("could not fetch the notification template: %w", err)
+	}
+	defer res.Body.Close()
+	s.checkedAt = now
+
+	if s.loaded && res.StatusCode == http.StatusNotModified {
+		return "", false, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("could not fetch the notification template: %s", res.Status)
+	}
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", false, fmt.Errorf("could not fetch the notification template: %w", err)
+	}
+	s.etag = res.Header.Get("ETag")
+	s.lastModified = res.Header.Get("Last-Modified")
+	s.loaded = true
+	return string(content), true, nil
+}
@@ -0,0 +1,142 @@
+package notifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("the notification template source", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-templates")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+	// write writes the template file, setting its modification time to the time given
+	write := func(text string, modTime time.Time) string {
+		path := filepath.Join(dir, "slack.tmpl")
+		Expect(os.WriteFile(path, []byte(text), 0o644)).To(Succeed())
+		Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+		return path
+	}
+
+	When("reading a file", func() {
+		It("should only read it again once it was modified", func() {
+			modTime := time.Now().Add(-time.Hour)
+			source := newTemplateSource(write("first", modTime))
+			text, changed, err := source.load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+			Expect(text).To(Equal("first"))
+
+			_, changed, _ = source.load()
+			Expect(changed).To(BeFalse())
+
+			write("second", modTime.Add(time.Minute))
+			text, changed, err = source.load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+			Expect(text).To(Equal("second"))
+		})
+		It("should not consider a file that was touched changed", func() {
+			modTime := time.Now().Add(-time.Hour)
+			source := newTemplateSource(write("first", modTime))
+			_, _, _ = source.load()
+			write("first", modTime.Add(time.Minute))
+			_, changed, err := source.load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeFalse())
+		})
+		It("should return an error for a missing file", func() {
+			_, _, err := newTemplateSource(filepath.Join(dir, "missing.tmpl")).load()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("fetching a URL", func() {
+		var server *httptest.Server
+		var requests int
+		var text string
+
+		BeforeEach(func() {
+			requests = 0
+			text = "first"
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				etag := `"` + text + `"`
+				if r.Header.Get("If-None-Match") == etag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", etag)
+				_, _ = w.Write([]byte(text))
+			}))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should only request it again once the recheck interval has passed", func() {
+			now := time.Now()
+			source := newTemplateSource(server.URL + "/slack.tmpl")
+			source.now = func() time.Time { return now }
+			loaded, changed, err := source.load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+			Expect(loaded).To(Equal("first"))
+
+			text = "second"
+			_, changed, _ = source.load()
+			Expect(changed).To(BeFalse())
+			Expect(requests).To(Equal(1))
+
+			now = now.Add(templateRecheckInterval)
+			loaded, changed, err = source.load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+			Expect(loaded).To(Equal("second"))
+
+			now = now.Add(templateRecheckInterval)
+			_, changed, err = source.load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeFalse())
+			Expect(requests).To(Equal(3))
+		})
+		It("should return an error for failed requests", func() {
+			server.Config.Handler = http.NotFoundHandler()
+			_, _, err := newTemplateSource(server.URL + "/missing").load()
+			Expect(err).To(MatchError(ContainSubstring("404")))
+		})
+	})
+
+	When("used by the notifier", func() {
+		It("should reload the global template once it has changed, keeping it if the new one is invalid", func() {
+			modTime := time.Now().Add(-time.Hour)
+			source := newTemplateSource(write("{{len .Entries}} entries", modTime))
+			text, _, err := source.load()
+			Expect(err).NotTo(HaveOccurred())
+			notifier := createNotifier([]string{"logger://"}, allButTrace, text, false, StaticData{}, false)
+			notifier.templateSource = source
+			data := Data{Entries: []*logrus.Entry{{Message: "foo"}}}
+
+			Expect(notifier.buildMessage(data)).To(Equal("1 entries"))
+
+			write("{{len .Entries}} entry", modTime.Add(time.Minute))
+			Expect(notifier.buildMessage(data)).To(Equal("1 entry"))
+
+			write("{{len .Entries", modTime.Add(2*time.Minute))
+			Expect(notifier.buildMessage(data)).To(Equal("1 entry"))
+		})
+	})
+})
@@ -0,0 +1,86 @@
+package notifications
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// templateParam is the query parameter of the notification URLs naming the template of the session reports sent to
+// them, in place of the global one. It is removed from the URLs before they are passed to shoutrrr.
+const templateParam = "watchtower-template"
+
+// templatedTarget are the URLs receiving the session reports built using their own template
+type templatedTarget struct {
+	name     string
+	urls     []string
+	router   router
+	template *template.Template
+}
+
+// splitTemplates removes the template parameter from the URLs, returning the ones using the global template, and the
+// ones naming their own template grouped by its name, in the order the names first appear in
+func splitTemplates(urls []string) (global []string, named map[string][]string, names []string, err error) {
+	named = map[string][]string{}
+	for _, rawURL := range urls {
+		clean, name, err := parseTemplateName(rawURL)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if name == "" {
+			global = append(global, clean)
+			continue
+		}
+		if _, found := named[name]; !found {
+			names = append(names, name)
+		}
+		named[name] = append(named[name], clean)
+	}
+	return global, named, names, nil
+}
+
+// parseTemplateName removes the template parameter from the URL, returning the name of the template it selects, or
+// an empty name if it has none
+func parseTemplateName(rawURL string) (string, string, error) {
+	if !strings.Contains(rawURL, templateParam) {
+		return rawURL, "", nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid notification URL for %s: %w", GetScheme(rawURL), err)
+	}
+	query := parsed.Query()
+	if _, found := query[templateParam]; !found {
+		return rawURL, "", nil
+	}
+	name := strings.TrimSpace(query.Get(templateParam))
+	query.Del(templateParam)
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), name, nil
+}
+
+// stripTemplateNames removes the template parameter from the URLs, for the notifications that do not use it
+func stripTemplateNames(urls []string) ([]string, error) {
+	stripped := make([]string, 0, len(urls))
+	for _, rawURL := range urls {
+		clean, _, err := parseTemplateName(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		stripped = append(stripped, clean)
+	}
+	return stripped, nil
+}
+
+// getNamedTemplate returns the template with the given name, being either one of the templates of the config file or
+// one of the common templates
+func getNamedTemplate(name string, templates map[string]string, legacy bool) (*template.Template, error) {
+	if text, found := templates[name]; found {
+		return getShoutrrrTemplate(text, legacy)
+	}
+	if _, found := commonTemplates[name]; found {
+		return getShoutrrrTemplate(name, legacy)
+	}
+	return nil, fmt.Errorf("unknown notification template %q", name)
+}
@@ -0,0 +1,31 @@
+package notifications
+
+import (
+	"github.com/containrrr/shoutrrr/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// NewWatchdogAlerter returns the function sending the alerts of the watchdog to the dedicated watchdog URLs, or nil
+// if there are none. The alerts are sent right away, rather than being queued like the session reports, so that
+// they go out even while a session is stuck.
+func NewWatchdogAlerter(c *cobra.Command) func(message string) {
+	f := c.PersistentFlags()
+	urls, _ := f.GetStringArray("notification-watchdog-url")
+	if len(urls) == 0 {
+		return nil
+	}
+	stdout, _ := f.GetBool("notification-log-stdout")
+	r := newSender(stdout)(urls)
+
+	params := &types.Params{}
+	if title := GetTemplateData(c).Title; title != "" {
+		params.SetTitle(title)
+	}
+	return func(message string) {
+		for i, err := range r.Send(message, params) {
+			if err != nil {
+				LocalLog.WithError(err).Errorf("Failed to send the watchdog alert using %s", GetScheme(urls[i]))
+			}
+		}
+	}
+}
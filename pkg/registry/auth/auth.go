@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/containrrr/watchtower/pkg/registry/helpers"
+	"github.com/containrrr/watchtower/pkg/registry/transport"
 	"github.com/containrrr/watchtower/pkg/types"
 	"github.com/docker/distribution/reference"
 	"github.com/sirupsen/logrus"
@@ -33,7 +34,7 @@ func GetToken(container types.Container, registryAuth string) (string, error) {
 		return "", err
 	}
 
-	client := &http.Client{}
+	client := transport.NewClient(false)
 	var res *http.Response
 	if res, err = client.Do(req); err != nil {
 		return "", err
@@ -74,7 +75,7 @@ func GetChallengeRequest(URL url.URL) (*http.Request, error) {
 
 // GetBearerHeader tries to fetch a bearer token from the registry based on the challenge instructions
 func GetBearerHeader(challenge string, img string, registryAuth string) (string, error) {
-	client := http.Client{}
+	client := transport.NewClient(false)
 	if strings.Contains(img, ":") {
 		img = strings.Split(img, ":")[0]
 	}
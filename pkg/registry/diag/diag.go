@@ -0,0 +1,256 @@
+package diag
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/registry"
+	"github.com/containrrr/watchtower/pkg/registry/auth"
+	"github.com/containrrr/watchtower/pkg/registry/digest"
+	"github.com/containrrr/watchtower/pkg/registry/helpers"
+	"github.com/containrrr/watchtower/pkg/registry/manifest"
+	"github.com/containrrr/watchtower/pkg/registry/transport"
+	"github.com/docker/distribution/reference"
+)
+
+const dialTimeout = 10 * time.Second
+
+// Step is the result of a single diagnostic step
+type Step struct {
+	Name     string
+	Detail   string
+	Err      error
+	Skipped  bool
+	Duration time.Duration
+}
+
+// Failed returns whether any of the steps failed
+func Failed(steps []Step) bool {
+	for _, step := range steps {
+		if step.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Target is the registry (and optionally the image) to run the diagnostics for
+type Target struct {
+	// Host is the registry host, including the port if it is not the default one
+	Host string
+	// Image is the full image reference, or empty if only the host should be checked
+	Image string
+}
+
+// ParseTarget creates a target from either a registry host name (e.g. ghcr.io) or an image reference
+// (e.g. ghcr.io/containrrr/watchtower:latest). Only references containing a slash are treated as images.
+func ParseTarget(target string) (Target, error) {
+	if !strings.Contains(target, "/") {
+		host, err := helpers.NormalizeRegistry(target)
+		return Target{Host: host}, err
+	}
+
+	named, err := reference.ParseNormalizedNamed(target)
+	if err != nil {
+		return Target{}, err
+	}
+	host, err := helpers.NormalizeRegistry(named.String())
+	if err != nil {
+		return Target{}, err
+	}
+	return Target{Host: host, Image: target}, nil
+}
+
+type diagnosis struct {
+	target Target
+	addrs  []string
+	conn   net.Conn
+	token  string
+}
+
+// Run checks each of the steps needed to retrieve the digest of an image from the registry: resolving the host,
+// connecting to it, the TLS handshake, authentication and finally the manifest HEAD request. Once a step has failed,
+// the remaining steps are skipped.
+func Run(ctx context.Context, target Target) []Step {
+	d := &diagnosis{target: target}
+	defer func() {
+		if d.conn != nil {
+			_ = d.conn.Close()
+		}
+	}()
+
+	checks := []struct {
+		name string
+		run  func(ctx context.Context) (string, error)
+	}{
+		{"DNS", d.resolve},
+		{"TCP", d.connect},
+		{"TLS", d.handshake},
+		{"Auth", d.authenticate},
+		{"HEAD", d.head},
+	}
+
+	steps := make([]Step, 0, len(checks))
+	failed := false
+	for _, check := range checks {
+		if failed {
+			steps = append(steps, Step{Name: check.name, Skipped: true})
+			continue
+		}
+		start := time.Now()
+		detail, err := check.run(ctx)
+		steps = append(steps, Step{Name: check.name, Detail: detail, Err: err, Duration: time.Since(start)})
+		failed = err != nil
+	}
+	return steps
+}
+
+func (d *diagnosis) hostAndPort() (string, string) {
+	host, port, err := net.SplitHostPort(d.target.Host)
+	if err != nil {
+		return d.target.Host, "443"
+	}
+	return host, port
+}
+
+func (d *diagnosis) resolve(ctx context.Context) (string, error) {
+	host, _ := d.hostAndPort()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	d.addrs = addrs
+	return fmt.Sprintf("%s resolved to %s", host, strings.Join(addrs, ", ")), nil
+}
+
+func (d *diagnosis) connect(ctx context.Context) (string, error) {
+	_, port := d.hostAndPort()
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var err error
+	for _, addr := range d.addrs {
+		address := net.JoinHostPort(addr, port)
+		if d.conn, err = dialer.DialContext(ctx, "tcp", address); err == nil {
+			return fmt.Sprintf("connected to %s", address), nil
+		}
+	}
+	return "", err
+}
+
+func (d *diagnosis) handshake(ctx context.Context) (string, error) {
+	host, _ := d.hostAndPort()
+	conn := tls.Client(d.conn, &tls.Config{ServerName: host})
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return "", err
+	}
+	d.conn = conn
+
+	state := conn.ConnectionState()
+	detail := tlsVersionName(state.Version)
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		detail = fmt.Sprintf("%s, certificate for %q issued by %q, valid until %s", detail,
+			cert.Subject.CommonName, cert.Issuer.CommonName, cert.NotAfter.Format(time.RFC3339))
+	}
+	return detail, nil
+}
+
+func (d *diagnosis) authenticate(_ context.Context) (string, error) {
+	challengeURL := url.URL{Scheme: "https", Host: d.target.Host, Path: "/v2/"}
+	if d.target.Image != "" {
+		var err error
+		if challengeURL, err = auth.GetChallengeURL(d.target.Image); err != nil {
+			return "", err
+		}
+	}
+
+	req, err := auth.GetChallengeRequest(challengeURL)
+	if err != nil {
+		return "", err
+	}
+	client := transport.NewClient(false)
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	_ = res.Body.Close()
+
+	challenge := res.Header.Get(auth.ChallengeHeader)
+	if challenge == "" {
+		return fmt.Sprintf("%s responded with %q without an auth challenge", challengeURL.String(), res.Status), nil
+	}
+	challengeType := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	if d.target.Image == "" {
+		return fmt.Sprintf("%s challenge received, pass an image reference to request a token", challengeType), nil
+	}
+
+	credentials := ""
+	if encoded, err := registry.EncodedAuth(d.target.Image); err == nil && encoded != "" {
+		credentials = digest.TransformAuth(encoded)
+	}
+	using := "anonymously"
+	if credentials != "" {
+		using = "using the configured credentials"
+	}
+
+	switch challengeType {
+	case "basic":
+		if credentials == "" {
+			return "", errors.New("basic challenge received, but no credentials are configured for the registry")
+		}
+		d.token = fmt.Sprintf("Basic %s", credentials)
+		return "basic challenge received, using the configured credentials", nil
+	case "bearer":
+		if d.token, err = auth.GetBearerHeader(strings.ToLower(challenge), d.target.Image, credentials); err != nil {
+			return "", err
+		}
+		if d.token == "Bearer " {
+			return "", fmt.Errorf("bearer challenge received, but no token was returned when requesting it %s", using)
+		}
+		return fmt.Sprintf("bearer challenge received, token requested %s", using), nil
+	default:
+		return "", fmt.Errorf("unsupported challenge type %q", challengeType)
+	}
+}
+
+func (d *diagnosis) head(_ context.Context) (string, error) {
+	if d.target.Image == "" {
+		return "skipped, pass an image reference to check its manifest", nil
+	}
+	if d.token == "" {
+		return "skipped, the registry did not ask for authentication", nil
+	}
+
+	manifestURL, err := manifest.BuildManifestURLForImage(d.target.Image)
+	if err != nil {
+		return "", err
+	}
+	remoteDigest, err := digest.GetDigest(manifestURL, d.token)
+	if err != nil {
+		return "", err
+	}
+	if remoteDigest == "" {
+		return "", fmt.Errorf("the registry did not return a %s header", digest.ContentDigestHeader)
+	}
+	return fmt.Sprintf("digest %s", remoteDigest), nil
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("TLS (0x%04x)", version)
+	}
+}
@@ -0,0 +1,63 @@
+package diag
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDiag(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Registry Diagnostics Suite")
+}
+
+var _ = Describe("the registry diagnostics", func() {
+	Describe("ParseTarget", func() {
+		It("should treat names without a slash as registry hosts", func() {
+			target, err := ParseTarget("docker.io")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal(Target{Host: "index.docker.io"}))
+		})
+		It("should keep the port of registry hosts", func() {
+			target, err := ParseTarget("localhost:5000")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal(Target{Host: "localhost:5000"}))
+		})
+		It("should derive the registry host from image references", func() {
+			target, err := ParseTarget("ghcr.io/containrrr/watchtower:latest")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal(Target{Host: "ghcr.io", Image: "ghcr.io/containrrr/watchtower:latest"}))
+		})
+	})
+
+	When("a step fails", func() {
+		It("should report the failure and skip the remaining steps", func() {
+			// a plain TCP server will make the TLS handshake fail
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+			go func() {
+				conn, err := listener.Accept()
+				if err == nil {
+					_, _ = conn.Write([]byte("not tls\n"))
+					_ = conn.Close()
+				}
+			}()
+
+			steps := Run(context.Background(), Target{Host: listener.Addr().String()})
+			Expect(steps).To(HaveLen(5))
+			Expect(steps[0].Name).To(Equal("DNS"))
+			Expect(steps[0].Err).NotTo(HaveOccurred())
+			Expect(steps[1].Name).To(Equal("TCP"))
+			Expect(steps[1].Err).NotTo(HaveOccurred())
+			Expect(steps[2].Name).To(Equal("TLS"))
+			Expect(steps[2].Err).To(HaveOccurred())
+			Expect(steps[3].Skipped).To(BeTrue())
+			Expect(steps[4].Skipped).To(BeTrue())
+			Expect(Failed(steps)).To(BeTrue())
+		})
+	})
+})
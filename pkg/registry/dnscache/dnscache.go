@@ -0,0 +1,147 @@
+package dnscache
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxNegativeTTL is the longest time that a failed lookup is cached for. Failures are cached for a shorter time than
+// successful lookups, so that registries become reachable again soon after a network issue has been resolved.
+const maxNegativeTTL = 10 * time.Second
+
+// LookupFunc resolves a host name to its addresses
+type LookupFunc func(ctx context.Context, host string) ([]string, error)
+
+// Resolver is a DNS resolver caching both successful and failed lookups of registry hosts
+type Resolver struct {
+	lookup      LookupFunc
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+var (
+	defaultResolver  *Resolver
+	defaultTransport http.RoundTripper = http.DefaultTransport
+)
+
+// NewResolver creates a resolver caching lookups for the given TTL, using lookup to resolve hosts that are not cached
+func NewResolver(ttl time.Duration, lookup LookupFunc) *Resolver {
+	negativeTTL := ttl
+	if negativeTTL > maxNegativeTTL {
+		negativeTTL = maxNegativeTTL
+	}
+	return &Resolver{
+		lookup:      lookup,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     make(map[string]entry),
+	}
+}
+
+// Configure sets up the resolver cache used for the requests made directly to registries. A TTL of zero or less
+// disables caching, leaving the resolution to the system resolver.
+func Configure(ttl time.Duration) {
+	if ttl <= 0 {
+		defaultResolver = nil
+		defaultTransport = http.DefaultTransport
+		return
+	}
+
+	defaultResolver = NewResolver(ttl, net.DefaultResolver.LookupHost)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = DialContext(&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	})
+	defaultTransport = transport
+	logrus.WithField("ttl", ttl).Debug("Caching DNS lookups of registry hosts")
+}
+
+// Transport returns the transport to use for the requests made directly to registries
+func Transport() http.RoundTripper {
+	return defaultTransport
+}
+
+// DialContext returns a dial function that resolves the host using the configured resolver cache before dialing
+// using the supplied dialer. If caching is disabled, the dialer is used as is.
+func DialContext(dialer *net.Dialer) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	resolver := defaultResolver
+	if resolver == nil {
+		return dialer.DialContext
+	}
+	return resolver.DialContext(dialer)
+}
+
+// LookupHost returns the addresses of host, using the cached result if it has not yet expired
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	r.lock.Lock()
+	cached, found := r.entries[host]
+	r.lock.Unlock()
+	if found && r.now().Before(cached.expires) {
+		logrus.WithField("host", host).Trace("Using cached DNS lookup")
+		return cached.addrs, cached.err
+	}
+
+	addrs, err := r.lookup(ctx, host)
+	if ctx.Err() != nil {
+		// Lookups aborted by the caller do not say anything about the host
+		return addrs, err
+	}
+
+	ttl := r.ttl
+	if err != nil {
+		ttl = r.negativeTTL
+	}
+	r.lock.Lock()
+	r.entries[host] = entry{addrs: addrs, err: err, expires: r.now().Add(ttl)}
+	r.lock.Unlock()
+
+	return addrs, err
+}
+
+// DialContext returns a dial function that resolves the host using the resolver, and then tries each of its
+// addresses in turn using the supplied dialer
+func (r *Resolver) DialContext(dialer *net.Dialer) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var conn net.Conn
+		for _, addr := range addrs {
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr, port)); err == nil {
+				return conn, nil
+			}
+		}
+		if err == nil {
+			err = &net.DNSError{Err: "no addresses found", Name: host, IsNotFound: true}
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,82 @@
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDNSCache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DNS Cache Suite")
+}
+
+var _ = Describe("the registry DNS cache", func() {
+	var lookups int
+	var lookupErr error
+	var now time.Time
+	var resolver *Resolver
+
+	BeforeEach(func() {
+		lookups = 0
+		lookupErr = nil
+		now = time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+		resolver = NewResolver(time.Minute, func(_ context.Context, host string) ([]string, error) {
+			lookups++
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			return []string{"192.0.2.10"}, nil
+		})
+		resolver.now = func() time.Time { return now }
+	})
+
+	It("should cache successful lookups until they expire", func() {
+		for i := 0; i < 3; i++ {
+			addrs, err := resolver.LookupHost(context.Background(), "ghcr.io")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(addrs).To(ConsistOf("192.0.2.10"))
+		}
+		Expect(lookups).To(Equal(1))
+
+		now = now.Add(time.Minute)
+		_, _ = resolver.LookupHost(context.Background(), "ghcr.io")
+		Expect(lookups).To(Equal(2))
+	})
+
+	It("should cache failed lookups for a shorter time", func() {
+		lookupErr = errors.New("no such host")
+		_, err := resolver.LookupHost(context.Background(), "ghcr.io")
+		Expect(err).To(MatchError("no such host"))
+		_, err = resolver.LookupHost(context.Background(), "ghcr.io")
+		Expect(err).To(MatchError("no such host"))
+		Expect(lookups).To(Equal(1))
+
+		lookupErr = nil
+		now = now.Add(maxNegativeTTL)
+		addrs, err := resolver.LookupHost(context.Background(), "ghcr.io")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(ConsistOf("192.0.2.10"))
+		Expect(lookups).To(Equal(2))
+	})
+
+	It("should not look up IP addresses", func() {
+		addrs, err := resolver.LookupHost(context.Background(), "192.0.2.20")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(ConsistOf("192.0.2.20"))
+		Expect(lookups).To(Equal(0))
+	})
+
+	It("should not use a cache unless configured", func() {
+		Configure(0)
+		Expect(Transport()).To(BeIdenticalTo(http.DefaultTransport))
+		Configure(time.Minute)
+		Expect(Transport()).NotTo(BeIdenticalTo(http.DefaultTransport))
+		Configure(0)
+	})
+})
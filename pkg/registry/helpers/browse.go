@@ -0,0 +1,40 @@
+package helpers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+)
+
+// BrowseURL returns the URL of the page showing the tags of the image on the website of its registry, for Docker Hub,
+// GitHub Container Registry and Quay, or an empty string for other registries
+func BrowseURL(imageName string) string {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return ""
+	}
+	tag := "latest"
+	if tagged, ok := named.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	}
+	path := reference.Path(named)
+
+	switch reference.Domain(named) {
+	case "docker.io":
+		if strings.HasPrefix(path, "library/") {
+			return fmt.Sprintf("https://hub.docker.com/_/%s/tags?name=%s", strings.TrimPrefix(path, "library/"), url.QueryEscape(tag))
+		}
+		return fmt.Sprintf("https://hub.docker.com/r/%s/tags?name=%s", path, url.QueryEscape(tag))
+	case "ghcr.io":
+		owner, name, found := strings.Cut(path, "/")
+		if !found {
+			return ""
+		}
+		return fmt.Sprintf("https://github.com/users/%s/packages/container/package/%s", owner, url.PathEscape(name))
+	case "quay.io":
+		return fmt.Sprintf("https://quay.io/repository/%s?tab=tags", path)
+	}
+	return ""
+}
@@ -21,6 +21,25 @@ var _ = Describe("the helpers", func() {
 			Expect(port).To(BeEmpty())
 		})
 	})
+	When("linking to the page of an image", func() {
+		It("should link to the tags of official images on Docker Hub", func() {
+			Expect(BrowseURL("nginx:1.25")).To(Equal("https://hub.docker.com/_/nginx/tags?name=1.25"))
+		})
+		It("should link to the tags of other images on Docker Hub", func() {
+			Expect(BrowseURL("containrrr/watchtower")).To(Equal("https://hub.docker.com/r/containrrr/watchtower/tags?name=latest"))
+		})
+		It("should link to the package of images on the GitHub Container Registry", func() {
+			Expect(BrowseURL("ghcr.io/home-assistant/home-assistant:stable")).To(
+				Equal("https://github.com/users/home-assistant/packages/container/package/home-assistant"))
+		})
+		It("should link to the repository of images on Quay", func() {
+			Expect(BrowseURL("quay.io/prometheus/node-exporter:v1.7.0")).To(
+				Equal("https://quay.io/repository/prometheus/node-exporter?tab=tags"))
+		})
+		It("should not link to the pages of other registries", func() {
+			Expect(BrowseURL("registry.example.com/app:1.0")).To(BeEmpty())
+		})
+	})
 	When("normalizing the registry information", func() {
 		It("should return index.docker.io given docker.io", func() {
 			out, err := NormalizeRegistry("docker.io/containrrr/watchtower:latest")
@@ -0,0 +1,140 @@
+package hosts
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/registry/helpers"
+	"github.com/sirupsen/logrus"
+)
+
+// Config holds the settings of a single registry, which apply to the requests made directly to it in place of the
+// global ones
+type Config struct {
+	// Username and Password are the credentials used for the registry, taking precedence over REPO_USER, REPO_PASS
+	// and the docker config
+	Username string
+	Password string
+	// Mirror is the URL of a registry mirror to check for updates instead of the registry itself
+	Mirror string
+	// Timeout is how long to wait for the connection to the registry and for each of its responses
+	Timeout time.Duration
+	// RateLimit is the maximum number of requests per minute made to the registry, 0 for no limit
+	RateLimit int `mapstructure:"rate_limit"`
+	// Insecure skips verifying the certificate of the registry
+	Insecure bool
+	// CABundle is the path of a PEM file with the certificate authorities to verify the registry with, in addition to
+	// the ones of the system
+	CABundle string `mapstructure:"ca_bundle"`
+
+	mirrorURL *url.URL
+	rootCAs   *x509.CertPool
+}
+
+var (
+	configs = map[string]Config{}
+	lock    sync.RWMutex
+)
+
+// Configure sets the settings of the registries, keyed by their host. Docker Hub may be referred to as docker.io.
+func Configure(registries map[string]Config) error {
+	normalized := make(map[string]Config, len(registries))
+	for host, config := range registries {
+		key, err := helpers.NormalizeRegistry(host)
+		if err != nil || key == "" {
+			return fmt.Errorf("invalid registry host %q", host)
+		}
+		if err := config.load(); err != nil {
+			return fmt.Errorf("invalid settings for the registry %s: %w", host, err)
+		}
+		normalized[key] = config
+	}
+
+	lock.Lock()
+	configs = normalized
+	lock.Unlock()
+	for host, config := range normalized {
+		logrus.WithFields(logrus.Fields{
+			"registry":    host,
+			"credentials": config.HasCredentials(),
+			"mirror":      config.Mirror,
+			"timeout":     config.Timeout,
+			"rate_limit":  config.RateLimit,
+			"insecure":    config.Insecure,
+			"ca_bundle":   config.CABundle,
+		}).Debug("Configured the registry settings")
+	}
+	return nil
+}
+
+// Lookup returns the settings of the registry with the given host, and whether there are any
+func Lookup(host string) (Config, bool) {
+	key, err := helpers.NormalizeRegistry(host)
+	if err != nil {
+		return Config{}, false
+	}
+	lock.RLock()
+	defer lock.RUnlock()
+	config, found := configs[key]
+	return config, found
+}
+
+// HasCredentials returns whether credentials have been set for the registry
+func (config Config) HasCredentials() bool {
+	return config.Username != "" && config.Password != ""
+}
+
+// MirrorURL returns the parsed URL of the mirror, or nil if none has been set
+func (config Config) MirrorURL() *url.URL {
+	return config.mirrorURL
+}
+
+// RootCAs returns the certificate authorities to verify the registry with, or nil to use the ones of the system
+func (config Config) RootCAs() *x509.CertPool {
+	return config.rootCAs
+}
+
+// load validates the settings, parsing the mirror URL and reading the CA bundle
+func (config *Config) load() error {
+	if (config.Username == "") != (config.Password == "") {
+		return errors.New("both a username and a password are needed")
+	}
+	if config.Timeout < 0 {
+		return errors.New("the timeout can not be negative")
+	}
+	if config.RateLimit < 0 {
+		return errors.New("the rate limit can not be negative")
+	}
+
+	if config.Mirror != "" {
+		mirror, err := url.Parse(config.Mirror)
+		if err != nil {
+			return fmt.Errorf("invalid mirror: %w", err)
+		}
+		if mirror.Scheme != "https" && mirror.Scheme != "http" || mirror.Host == "" {
+			return fmt.Errorf("the mirror %q is not an http or https URL", config.Mirror)
+		}
+		config.mirrorURL = mirror
+	}
+
+	if config.CABundle != "" {
+		pem, err := os.ReadFile(config.CABundle)
+		if err != nil {
+			return fmt.Errorf("could not read the CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("the CA bundle %s contains no certificates", config.CABundle)
+		}
+		config.rootCAs = pool
+	}
+	return nil
+}
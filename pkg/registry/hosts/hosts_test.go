@@ -0,0 +1,84 @@
+package hosts
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHosts(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Registry Hosts Suite")
+}
+
+var _ = Describe("the registry settings", func() {
+	AfterEach(func() {
+		Expect(Configure(nil)).To(Succeed())
+	})
+
+	It("should look up the settings by the normalized host", func() {
+		Expect(Configure(map[string]Config{
+			"docker.io":               {RateLimit: 100},
+			"harbor.example.com:8443": {Timeout: time.Minute, Insecure: true},
+		})).To(Succeed())
+
+		config, found := Lookup("registry-1.docker.io")
+		Expect(found).To(BeTrue())
+		Expect(config.RateLimit).To(Equal(100))
+
+		config, found = Lookup("harbor.example.com:8443")
+		Expect(found).To(BeTrue())
+		Expect(config.Insecure).To(BeTrue())
+
+		_, found = Lookup("harbor.example.com")
+		Expect(found).To(BeFalse())
+	})
+
+	It("should parse the mirror URL", func() {
+		Expect(Configure(map[string]Config{"docker.io": {Mirror: "https://mirror.example.com"}})).To(Succeed())
+		config, _ := Lookup("index.docker.io")
+		Expect(config.MirrorURL().Host).To(Equal("mirror.example.com"))
+	})
+
+	It("should load the CA bundle", func() {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		defer server.Close()
+		certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		dir, err := os.MkdirTemp("", "watchtower-hosts")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "ca.pem")
+		Expect(os.WriteFile(path, certificate, 0600)).To(Succeed())
+		Expect(Configure(map[string]Config{"harbor.example.com": {CABundle: path}})).To(Succeed())
+		config, _ := Lookup("harbor.example.com")
+		Expect(config.RootCAs()).NotTo(BeNil())
+	})
+
+	invalid := map[string]Config{
+		"a username without a password": {Username: "user"},
+		"a negative timeout":            {Timeout: -time.Second},
+		"a negative rate limit":         {RateLimit: -1},
+		"a mirror that is not a URL":    {Mirror: "mirror.example.com"},
+		"a missing CA bundle":           {CABundle: "/does/not/exist.pem"},
+	}
+	for description, config := range invalid {
+		config := config
+		It("should reject "+description, func() {
+			Expect(Configure(map[string]Config{"harbor.example.com": config})).NotTo(Succeed())
+		})
+	}
+
+	It("should keep the previous settings when rejecting new ones", func() {
+		Expect(Configure(map[string]Config{"harbor.example.com": {RateLimit: 10}})).To(Succeed())
+		Expect(Configure(map[string]Config{"harbor.example.com": {RateLimit: -1}})).NotTo(Succeed())
+		_, found := Lookup("harbor.example.com")
+		Expect(found).To(BeTrue())
+	})
+})
@@ -13,14 +13,47 @@ import (
 
 // BuildManifestURL from raw image data
 func BuildManifestURL(container types.Container) (string, error) {
+	return BuildManifestURLForImage(container.ImageName())
+}
 
-	normalizedName, err := ref.ParseNormalizedNamed(container.ImageName())
+// BuildManifestURLForImage returns the URL of the manifest of the supplied image reference
+func BuildManifestURLForImage(imageName string) (string, error) {
+	host, img, tag, err := parseRepository(imageName)
 	if err != nil {
 		return "", err
 	}
+	url := url2.URL{
+		Scheme: "https",
+		Host:   host,
+		Path:   fmt.Sprintf("/v2/%s/manifests/%s", img, tag),
+	}
+	return url.String(), nil
+}
 
-	host, err := helpers.NormalizeRegistry(normalizedName.String())
-	img, tag := ExtractImageAndTag(strings.TrimPrefix(container.ImageName(), host+"/"))
+// BuildTagsURL returns the URL listing the tags of the repository of the supplied image reference
+func BuildTagsURL(imageName string) (string, error) {
+	host, img, _, err := parseRepository(imageName)
+	if err != nil {
+		return "", err
+	}
+	url := url2.URL{
+		Scheme: "https",
+		Host:   host,
+		Path:   fmt.Sprintf("/v2/%s/tags/list", img),
+	}
+	return url.String(), nil
+}
+
+// parseRepository returns the registry host, repository path and tag of the supplied image reference
+func parseRepository(imageName string) (host string, img string, tag string, err error) {
+
+	normalizedName, err := ref.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	host, err = helpers.NormalizeRegistry(normalizedName.String())
+	img, tag = ExtractImageAndTag(strings.TrimPrefix(imageName, host+"/"))
 
 	logrus.WithFields(logrus.Fields{
 		"image":      img,
@@ -30,19 +63,14 @@ func BuildManifestURL(container types.Container) (string, error) {
 	}).Debug("Parsing image ref")
 
 	if err != nil {
-		return "", err
+		return "", "", "", err
 	}
 	img = auth.GetScopeFromImageName(img, host)
 
 	if !strings.Contains(img, "/") {
 		img = "library/" + img
 	}
-	url := url2.URL{
-		Scheme: "https",
-		Host:   host,
-		Path:   fmt.Sprintf("/v2/%s/manifests/%s", img, tag),
-	}
-	return url.String(), nil
+	return host, img, tag, nil
 }
 
 // ExtractImageAndTag from a concatenated string
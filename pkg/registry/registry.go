@@ -27,6 +27,22 @@ func GetPullOptions(imageName string) (types.ImagePullOptions, error) {
 	}, nil
 }
 
+// GetCredentialSetPullOptions creates a struct with all options needed for pulling images from a registry, using the
+// credentials of the named credential set in the credentials directory instead of the global ones
+func GetCredentialSetPullOptions(imageName string, credentialsDir string, set string) (types.ImagePullOptions, error) {
+	auth, err := EncodedCredentialSetAuth(imageName, credentialsDir, set)
+	if err != nil {
+		return types.ImagePullOptions{}, err
+	}
+	log.Debugf("Using the credential set %s for image %s", set, imageName)
+	log.Tracef("Got auth value: %s", auth)
+
+	return types.ImagePullOptions{
+		RegistryAuth:  auth,
+		PrivilegeFunc: DefaultAuthHandler,
+	}, nil
+}
+
 // DefaultAuthHandler will be invoked if an AuthConfig is rejected
 // It could be used to return a new value for the "X-Registry-Auth" authentication header,
 // but there's no point trying again with the same value as used in AuthConfig
@@ -0,0 +1,194 @@
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Mode determines what happens with the requests made directly to registries
+type Mode int
+
+const (
+	// Passthrough sends requests to the registry without recording them
+	Passthrough Mode = iota
+	// Record sends requests to the registry and saves the responses as fixtures
+	Record
+	// Replay answers requests using previously recorded fixtures, without contacting the registry
+	Replay
+)
+
+// redacted replaces secrets found in recorded responses
+const redacted = "REDACTED"
+
+var (
+	mode Mode
+	dir  string
+)
+
+// Configure sets up recording or replaying of registry traffic using the fixtures in the supplied directories.
+// At most one of the directories may be set. If none is set, requests are passed through as is.
+func Configure(recordDir string, replayDir string) error {
+	switch {
+	case recordDir != "" && replayDir != "":
+		return errors.New("registry traffic can not be both recorded and replayed")
+	case recordDir != "":
+		if err := os.MkdirAll(recordDir, 0755); err != nil {
+			return err
+		}
+		mode, dir = Record, recordDir
+		logrus.WithField("dir", recordDir).Warn("Recording registry traffic")
+	case replayDir != "":
+		if _, err := os.Stat(replayDir); err != nil {
+			return err
+		}
+		mode, dir = Replay, replayDir
+		logrus.WithField("dir", replayDir).Warn("Replaying recorded registry traffic")
+	default:
+		mode, dir = Passthrough, ""
+	}
+	return nil
+}
+
+// Wrap returns a round tripper that records or replays the requests made using next, depending on the configured
+// mode. If neither mode has been configured, next is returned unchanged.
+func Wrap(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	switch mode {
+	case Record:
+		return &recorder{dir: dir, next: next}
+	case Replay:
+		return &replayer{dir: dir}
+	default:
+		return next
+	}
+}
+
+// Fixture is a recorded registry response
+type Fixture struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+// FixturePath returns the path of the fixture file for the given request method and URL
+func FixturePath(dir string, method string, url string) string {
+	sum := sha256.Sum256([]byte(method + " " + url))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])[:16]+".json")
+}
+
+type recorder struct {
+	dir  string
+	next http.RoundTripper
+	lock sync.Mutex
+}
+
+// RoundTrip implements http.RoundTripper.RoundTrip
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	fixture := Fixture{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Status:  res.StatusCode,
+		Headers: res.Header.Clone(),
+		Body:    redactBody(body),
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := writeFixture(FixturePath(r.dir, fixture.Method, fixture.URL), fixture); err != nil {
+		logrus.WithError(err).Warn("Failed to record registry response")
+	} else {
+		logrus.WithField("url", fixture.URL).Debug("Recorded registry response")
+	}
+
+	return res, nil
+}
+
+type replayer struct {
+	dir string
+}
+
+// RoundTrip implements http.RoundTripper.RoundTrip
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := FixturePath(r.dir, req.Method, req.URL.String())
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response for %s %s: %w", req.Method, req.URL, err)
+	}
+
+	fixture := Fixture{}
+	if err := json.Unmarshal(raw, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	logrus.WithField("url", fixture.URL).Debug("Replaying recorded registry response")
+
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode: fixture.Status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     fixture.Headers,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(fixture.Body)),
+		Request:    req,
+	}, nil
+}
+
+func writeFixture(path string, fixture Fixture) error {
+	raw, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
+
+// redactBody removes the tokens from registry auth responses, so that the fixtures can be shared safely
+func redactBody(body []byte) string {
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(body, &values); err != nil {
+		return string(body)
+	}
+
+	changed := false
+	for _, key := range []string{"token", "access_token", "refresh_token"} {
+		if _, found := values[key]; found {
+			values[key] = redacted
+			changed = true
+		}
+	}
+	if !changed {
+		return string(body)
+	}
+
+	redactedBody, err := json.Marshal(values)
+	if err != nil {
+		return string(body)
+	}
+	return string(redactedBody)
+}
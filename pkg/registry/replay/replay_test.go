@@ -0,0 +1,83 @@
+package replay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func TestReplay(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Replay Suite")
+}
+
+var _ = Describe("the registry traffic record/replay", func() {
+	var server *ghttp.Server
+	var fixtureDir string
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		var err error
+		fixtureDir, err = ioutil.TempDir("", "watchtower-replay")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		_ = os.RemoveAll(fixtureDir)
+		Expect(Configure("", "")).To(Succeed())
+	})
+
+	get := func(url string) (*http.Response, string) {
+		client := http.Client{Transport: Wrap(nil)}
+		res, err := client.Get(url)
+		Expect(err).NotTo(HaveOccurred())
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return res, string(body)
+	}
+
+	It("should refuse to both record and replay", func() {
+		Expect(Configure(fixtureDir, fixtureDir)).NotTo(Succeed())
+	})
+
+	It("should not wrap the transport unless configured", func() {
+		Expect(Configure("", "")).To(Succeed())
+		Expect(Wrap(http.DefaultTransport)).To(BeIdenticalTo(http.DefaultTransport))
+	})
+
+	When("recording and then replaying a request", func() {
+		It("should return the recorded response without contacting the registry", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"token": "secret"}`, http.Header{
+				"Docker-Content-Digest": []string{"sha256:abc"},
+			}))
+
+			Expect(Configure(fixtureDir, "")).To(Succeed())
+			_, recordedBody := get(server.URL() + "/token?scope=repository:foo:pull")
+			Expect(recordedBody).To(Equal(`{"token": "secret"}`))
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+
+			Expect(Configure("", fixtureDir)).To(Succeed())
+			res, replayedBody := get(server.URL() + "/token?scope=repository:foo:pull")
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("Docker-Content-Digest")).To(Equal("sha256:abc"))
+			Expect(replayedBody).To(Equal(`{"token":"REDACTED"}`))
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+
+	When("replaying a request that was not recorded", func() {
+		It("should return an error", func() {
+			Expect(Configure("", fixtureDir)).To(Succeed())
+			client := http.Client{Transport: Wrap(nil)}
+			_, err := client.Get(server.URL() + "/v2/")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
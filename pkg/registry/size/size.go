@@ -0,0 +1,153 @@
+package size
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/containrrr/watchtower/internal/meta"
+	"github.com/containrrr/watchtower/pkg/registry/auth"
+	"github.com/containrrr/watchtower/pkg/registry/digest"
+	"github.com/containrrr/watchtower/pkg/registry/manifest"
+	"github.com/containrrr/watchtower/pkg/registry/transport"
+	"github.com/containrrr/watchtower/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	mediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeImageIndex   = "application/vnd.oci.image.index.v1+json"
+)
+
+var acceptedMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	mediaTypeManifestList,
+	mediaTypeImageIndex,
+}
+
+// Platform identifies the manifest to use from a manifest list
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+type descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *Platform `json:"platform,omitempty"`
+}
+
+type imageManifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+// Estimate returns the compressed size of the image that the container image name currently refers to in the
+// registry, which is roughly the amount of data a pull will download. The platform of the current image of the
+// container is used to pick the image from multi-platform manifests.
+func Estimate(container types.Container, registryAuth string) (int64, error) {
+	if !container.HasImageInfo() {
+		return 0, errors.New("container image info missing")
+	}
+
+	token, err := auth.GetToken(container, digest.TransformAuth(registryAuth))
+	if err != nil {
+		return 0, err
+	}
+
+	manifestURL, err := manifest.BuildManifestURL(container)
+	if err != nil {
+		return 0, err
+	}
+
+	imageInfo := container.ImageInfo()
+	platform := Platform{OS: imageInfo.Os, Architecture: imageInfo.Architecture, Variant: imageInfo.Variant}
+	client := transport.NewClient(false)
+	return estimate(client, manifestURL, token, platform)
+}
+
+func estimate(client *http.Client, manifestURL string, token string, platform Platform) (int64, error) {
+	image, err := getManifest(client, manifestURL, token)
+	if err != nil {
+		return 0, err
+	}
+
+	if image.MediaType == mediaTypeManifestList || image.MediaType == mediaTypeImageIndex {
+		match, err := matchPlatform(image.Manifests, platform)
+		if err != nil {
+			return 0, err
+		}
+		// Manifests are referenced by digest in the same repository
+		manifestURL = manifestURL[:strings.LastIndex(manifestURL, "/")+1] + match.Digest
+		if image, err = getManifest(client, manifestURL, token); err != nil {
+			return 0, err
+		}
+	}
+
+	total := image.Config.Size
+	for _, layer := range image.Layers {
+		total += layer.Size
+	}
+	return total, nil
+}
+
+func getManifest(client *http.Client, manifestURL string, token string) (*imageManifest, error) {
+	req, err := http.NewRequest("GET", manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", meta.UserAgent)
+	for _, mediaType := range acceptedMediaTypes {
+		req.Header.Add("Accept", mediaType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	logrus.WithField("url", manifestURL).Debug("Retrieving manifest to estimate the image size")
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry responded to manifest request with %q", res.Status)
+	}
+
+	image := &imageManifest{}
+	if err := json.NewDecoder(res.Body).Decode(image); err != nil {
+		return nil, err
+	}
+	if image.MediaType == "" {
+		image.MediaType = res.Header.Get("Content-Type")
+	}
+	return image, nil
+}
+
+// matchPlatform returns the manifest for the platform, ignoring the variant if no manifest matches it exactly
+func matchPlatform(manifests []descriptor, platform Platform) (descriptor, error) {
+	var fallback *descriptor
+	for i, m := range manifests {
+		if m.Platform == nil || m.Platform.OS != platform.OS || m.Platform.Architecture != platform.Architecture {
+			continue
+		}
+		if m.Platform.Variant == platform.Variant {
+			return m, nil
+		}
+		if fallback == nil {
+			fallback = &manifests[i]
+		}
+	}
+	if fallback == nil {
+		return descriptor{}, fmt.Errorf("no manifest found for platform %s/%s", platform.OS, platform.Architecture)
+	}
+	return *fallback, nil
+}
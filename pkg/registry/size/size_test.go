@@ -0,0 +1,94 @@
+package size
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func TestSize(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Size Suite")
+}
+
+var _ = Describe("the image size estimate", func() {
+	var server *ghttp.Server
+	amd64 := Platform{OS: "linux", Architecture: "amd64"}
+	imageManifest := map[string]interface{}{
+		"mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+		"config":    map[string]interface{}{"digest": "sha256:c0", "size": 1000},
+		"layers": []map[string]interface{}{
+			{"digest": "sha256:l1", "size": 30000},
+			{"digest": "sha256:l2", "size": 500},
+		},
+	}
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should add up the sizes of the config and layers", func() {
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v2/library/nginx/manifests/latest"),
+				ghttp.VerifyHeaderKV("Authorization", "Bearer token"),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, imageManifest),
+			),
+		)
+		size, err := estimate(http.DefaultClient, server.URL()+"/v2/library/nginx/manifests/latest", "Bearer token", amd64)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(BeEquivalentTo(31500))
+	})
+
+	It("should use the manifest of the platform from a manifest list", func() {
+		server.AppendHandlers(
+			ghttp.RespondWithJSONEncoded(http.StatusOK, map[string]interface{}{
+				"mediaType": mediaTypeManifestList,
+				"manifests": []map[string]interface{}{
+					{"digest": "sha256:arm", "platform": map[string]string{"os": "linux", "architecture": "arm64"}},
+					{"digest": "sha256:amd", "platform": map[string]string{"os": "linux", "architecture": "amd64"}},
+				},
+			}),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v2/library/nginx/manifests/sha256:amd"),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, imageManifest),
+			),
+		)
+		size, err := estimate(http.DefaultClient, server.URL()+"/v2/library/nginx/manifests/latest", "", amd64)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(BeEquivalentTo(31500))
+	})
+
+	It("should return an error if the manifest list has no manifest for the platform", func() {
+		server.AppendHandlers(
+			ghttp.RespondWithJSONEncoded(http.StatusOK, map[string]interface{}{
+				"mediaType": mediaTypeImageIndex,
+				"manifests": []map[string]interface{}{
+					{"digest": "sha256:arm", "platform": map[string]string{"os": "linux", "architecture": "arm64"}},
+				},
+			}),
+		)
+		_, err := estimate(http.DefaultClient, server.URL()+"/v2/library/nginx/manifests/latest", "", amd64)
+		Expect(err).To(MatchError(ContainSubstring("linux/amd64")))
+	})
+
+	It("should prefer the manifest matching the platform variant", func() {
+		manifests := []descriptor{
+			{Digest: "sha256:v6", Platform: &Platform{OS: "linux", Architecture: "arm", Variant: "v6"}},
+			{Digest: "sha256:v7", Platform: &Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+		}
+		match, err := matchPlatform(manifests, Platform{OS: "linux", Architecture: "arm", Variant: "v7"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(match.Digest).To(Equal("sha256:v7"))
+
+		match, err = matchPlatform(manifests, Platform{OS: "linux", Architecture: "arm"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(match.Digest).To(Equal("sha256:v6"))
+	})
+})
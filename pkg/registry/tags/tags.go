@@ -0,0 +1,137 @@
+package tags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/containrrr/watchtower/internal/meta"
+	"github.com/containrrr/watchtower/pkg/registry/auth"
+	"github.com/containrrr/watchtower/pkg/registry/digest"
+	"github.com/containrrr/watchtower/pkg/registry/manifest"
+	"github.com/containrrr/watchtower/pkg/registry/transport"
+	"github.com/containrrr/watchtower/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+// maxPages limits the number of tag list pages that are retrieved for a single repository
+const maxPages = 50
+
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="?next"?`)
+
+type tagList struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// ListTags returns all tags of the repository of the container image
+func ListTags(container types.Container, registryAuth string) ([]string, error) {
+	token, err := auth.GetToken(container, digest.TransformAuth(registryAuth))
+	if err != nil {
+		return nil, err
+	}
+
+	tagsURL, err := manifest.BuildTagsURL(container.ImageName())
+	if err != nil {
+		return nil, err
+	}
+
+	client := transport.NewClient(false)
+	var tags []string
+	for page := 0; tagsURL != "" && page < maxPages; page++ {
+		var pageTags []string
+		if pageTags, tagsURL, err = getTagsPage(client, tagsURL, token); err != nil {
+			return nil, err
+		}
+		tags = append(tags, pageTags...)
+	}
+
+	return tags, nil
+}
+
+func getTagsPage(client *http.Client, pageURL string, token string) (tags []string, next string, err error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", meta.UserAgent)
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	logrus.WithField("url", pageURL).Debug("Listing repository tags")
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("registry responded to tag list request with %q", res.Status)
+	}
+
+	list := tagList{}
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		return nil, "", err
+	}
+
+	return list.Tags, nextPageURL(pageURL, res.Header.Get("Link")), nil
+}
+
+// nextPageURL returns the absolute URL of the next page referenced by the Link header, or an empty string
+func nextPageURL(pageURL string, link string) string {
+	match := nextLinkPattern.FindStringSubmatch(link)
+	if match == nil {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	next, err := base.Parse(match[1])
+	if err != nil {
+		return ""
+	}
+	return next.String()
+}
+
+// ParseConstraint parses a semantic version constraint, like "~1.24" or ">=2.0, <3"
+func ParseConstraint(constraint string) (*semver.Constraints, error) {
+	return semver.NewConstraint(constraint)
+}
+
+// Newest returns the tag with the highest version satisfying the constraint. Tags that are not versions are ignored.
+// If several tags refer to the same version, like "1.24" and "1.24.0", the current tag is preferred, followed by
+// the most specific one.
+func Newest(tags []string, constraint *semver.Constraints, current string) (string, error) {
+	var newest string
+	var newestVersion *semver.Version
+
+	for _, tag := range tags {
+		version, err := semver.NewVersion(tag)
+		if err != nil || !constraint.Check(version) {
+			continue
+		}
+
+		if newestVersion == nil || version.GreaterThan(newestVersion) {
+			newest, newestVersion = tag, version
+			continue
+		}
+		if version.Equal(newestVersion) && newest != current {
+			if tag == current || strings.Count(tag, ".") > strings.Count(newest, ".") {
+				newest = tag
+			}
+		}
+	}
+
+	if newestVersion == nil {
+		return "", errors.New("no tag satisfies the constraint")
+	}
+	return newest, nil
+}
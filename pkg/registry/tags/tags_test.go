@@ -0,0 +1,77 @@
+package tags
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func TestTags(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tags Suite")
+}
+
+var _ = Describe("the tags module", func() {
+	Describe("Newest", func() {
+		available := []string{"latest", "1.23.4", "1.24", "1.24.0", "1.24.3", "1.24.4-alpine", "1.25.0", "2.0.0-rc1", "alpine"}
+
+		It("should return the newest tag satisfying the constraint", func() {
+			constraint, err := ParseConstraint("~1.24")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Newest(available, constraint, "1.24.0")).To(Equal("1.24.3"))
+		})
+		It("should ignore pre-releases and tags that are not versions", func() {
+			constraint, err := ParseConstraint(">=1.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Newest(available, constraint, "latest")).To(Equal("1.25.0"))
+		})
+		It("should prefer the current tag over equal versions", func() {
+			constraint, err := ParseConstraint("1.24.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Newest(available, constraint, "1.24")).To(Equal("1.24"))
+			Expect(Newest(available, constraint, "1.23.4")).To(Equal("1.24.0"))
+		})
+		It("should return an error if no tag satisfies the constraint", func() {
+			constraint, err := ParseConstraint("^3")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = Newest(available, constraint, "1.24")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("listing tags", func() {
+		var server *ghttp.Server
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should follow the link to the next page", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v2/library/nginx/tags/list"),
+					ghttp.VerifyHeaderKV("Authorization", "Bearer token"),
+					ghttp.RespondWith(http.StatusOK, `{"name":"library/nginx","tags":["1.24.0","1.24.1"]}`, http.Header{
+						"Link": []string{`</v2/library/nginx/tags/list?last=1.24.1&n=2>; rel="next"`},
+					}),
+				),
+			)
+
+			tags, next, err := getTagsPage(http.DefaultClient, server.URL()+"/v2/library/nginx/tags/list", "Bearer token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tags).To(Equal([]string{"1.24.0", "1.24.1"}))
+			Expect(next).To(Equal(server.URL() + "/v2/library/nginx/tags/list?last=1.24.1&n=2"))
+		})
+
+		It("should return an error if the registry denies the request", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusUnauthorized, nil))
+			_, _, err := getTagsPage(http.DefaultClient, server.URL()+"/v2/library/nginx/tags/list", "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
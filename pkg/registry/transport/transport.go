@@ -0,0 +1,272 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/registry/dnscache"
+	"github.com/containrrr/watchtower/pkg/registry/hosts"
+	"github.com/containrrr/watchtower/pkg/registry/replay"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Options configure the HTTP client used for the requests made directly to registries, separately from the docker
+// client, which pulls the images using the proxy configuration of the daemon
+type Options struct {
+	// Proxy is the URL of the proxy to send the requests through. If empty, the HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+	// environment variables are used.
+	Proxy string
+	// NoProxy lists the hosts, domains and networks that are reached without the proxy, replacing NO_PROXY
+	NoProxy string
+	// ConnectTimeout is how long to wait for the connection to the registry, including the TLS handshake
+	ConnectTimeout time.Duration
+	// ReadTimeout is how long to wait for the response headers after sending the request, 0 to wait indefinitely
+	ReadTimeout time.Duration
+	// RequestTimeout is the deadline of each of the requests as a whole, including the retries, 0 for none
+	RequestTimeout time.Duration
+	// MaxRetries is how many times to retry a request that failed to connect, timed out or got a server error
+	MaxRetries int
+}
+
+// DefaultConnectTimeout is used when no connect timeout has been configured
+const DefaultConnectTimeout = 30 * time.Second
+
+// retryBackoff is the delay before the first retry, which doubles with every further retry
+var retryBackoff = 500 * time.Millisecond
+
+var (
+	options = Options{ConnectTimeout: DefaultConnectTimeout}
+	// transports are shared by the clients to reuse the connections
+	transports = map[transportKey]http.RoundTripper{}
+	// limiters space the requests made to the registries having a rate limit, by their host
+	limiters = map[string]*limiter{}
+	lock     sync.Mutex
+)
+
+// transportKey identifies a shared transport by the registry whose settings it uses, empty for the registries without
+// any, and whether it verifies the certificates
+type transportKey struct {
+	registry string
+	insecure bool
+}
+
+// Configure sets the options of the registry clients. It has to be called after the DNS cache and the settings of the
+// registries have been configured, which the clients resolve and reach the registry hosts with.
+func Configure(opts Options) error {
+	if opts.Proxy != "" {
+		if _, err := url.Parse(opts.Proxy); err != nil {
+			return fmt.Errorf("invalid registry proxy: %w", err)
+		}
+	}
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = DefaultConnectTimeout
+	}
+	if opts.MaxRetries < 0 {
+		return errors.New("the maximum number of registry request retries can not be negative")
+	}
+	lock.Lock()
+	options, transports, limiters = opts, map[transportKey]http.RoundTripper{}, map[string]*limiter{}
+	lock.Unlock()
+	logrus.WithFields(logrus.Fields{
+		"proxy":           opts.Proxy != "",
+		"connect_timeout": opts.ConnectTimeout,
+		"read_timeout":    opts.ReadTimeout,
+		"request_timeout": opts.RequestTimeout,
+		"max_retries":     opts.MaxRetries,
+	}).Debug("Configured the registry client")
+	return nil
+}
+
+// NewClient returns a client for requests made directly to registries using the configured options and the settings
+// of each registry, which records or replays the requests if enabled. Verifying the certificates of the registries is
+// skipped if insecure is set.
+func NewClient(insecure bool) *http.Client {
+	lock.Lock()
+	defer lock.Unlock()
+	return &http.Client{Transport: replay.Wrap(&router{insecure: insecure}), Timeout: options.RequestTimeout}
+}
+
+// router sends the requests using the transport of the registry they are made to, redirecting them to its mirror
+type router struct {
+	insecure bool
+}
+
+func (r *router) RoundTrip(req *http.Request) (*http.Response, error) {
+	registry, found := hosts.Lookup(req.URL.Host)
+	if !found {
+		return transportFor(transportKey{insecure: r.insecure}, registry).RoundTrip(req)
+	}
+
+	key := transportKey{registry: req.URL.Host, insecure: r.insecure || registry.Insecure}
+	if mirror := registry.MirrorURL(); mirror != nil {
+		req = req.Clone(req.Context())
+		req.URL.Scheme, req.URL.Host, req.Host = mirror.Scheme, mirror.Host, ""
+	}
+	return transportFor(key, registry).RoundTrip(req)
+}
+
+// transportFor returns the shared transport for the key, creating it using the settings of the registry if needed
+func transportFor(key transportKey, registry hosts.Config) http.RoundTripper {
+	lock.Lock()
+	defer lock.Unlock()
+	if transport, found := transports[key]; found {
+		return transport
+	}
+
+	var rateLimit *limiter
+	if registry.RateLimit > 0 {
+		if rateLimit = limiters[key.registry]; rateLimit == nil {
+			rateLimit = &limiter{interval: time.Minute / time.Duration(registry.RateLimit)}
+			limiters[key.registry] = rateLimit
+		}
+	}
+	transport := newTransport(options, registry, rateLimit, key.insecure)
+	transports[key] = transport
+	return transport
+}
+
+func newTransport(opts Options, registry hosts.Config, rateLimit *limiter, insecure bool) http.RoundTripper {
+	if registry.Timeout > 0 {
+		opts.ConnectTimeout, opts.ReadTimeout = registry.Timeout, registry.Timeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(opts)
+	transport.DialContext = dnscache.DialContext(&net.Dialer{
+		Timeout:   opts.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	})
+	transport.TLSHandshakeTimeout = opts.ConnectTimeout
+	transport.ResponseHeaderTimeout = opts.ReadTimeout
+	if insecure || registry.RootCAs() != nil {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecure, RootCAs: registry.RootCAs()}
+	}
+
+	var next http.RoundTripper = transport
+	if rateLimit != nil {
+		next = &limited{next: next, limiter: rateLimit}
+	}
+	if opts.MaxRetries > 0 {
+		next = &retrier{next: next, maxRetries: opts.MaxRetries}
+	}
+	return next
+}
+
+// proxyFunc returns the proxy selection of the options, falling back to the environment variables. Unlike
+// http.ProxyFromEnvironment, the environment is read each time, so that changes made after the first request apply.
+func proxyFunc(opts Options) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		config := httpproxy.FromEnvironment()
+		if opts.Proxy != "" {
+			config.HTTPProxy, config.HTTPSProxy = opts.Proxy, opts.Proxy
+		}
+		if opts.NoProxy != "" {
+			config.NoProxy = opts.NoProxy
+		}
+		return config.ProxyFunc()(req.URL)
+	}
+}
+
+// retrier retries the requests without a body that failed to connect, timed out or got a server error
+type retrier struct {
+	next       http.RoundTripper
+	maxRetries int
+}
+
+func (r *retrier) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		res, err := r.next.RoundTrip(req)
+		if attempt >= r.maxRetries || req.Body != nil && req.Body != http.NoBody || !retryable(req.Context(), res, err) {
+			return res, err
+		}
+		if res != nil {
+			_ = res.Body.Close()
+		}
+		logrus.WithFields(logrus.Fields{
+			"url":     req.URL.Redacted(),
+			"attempt": attempt + 1,
+		}).WithError(describe(res, err)).Debug("Retrying the registry request")
+
+		select {
+		case <-time.After(backoff):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		backoff *= 2
+	}
+}
+
+// retryable returns whether the request may succeed when retried. Rate limited requests are not retried, as doing so
+// would only consume more of the limit.
+func retryable(ctx context.Context, res *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func describe(res *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	return errors.New(res.Status)
+}
+
+// limiter spaces the requests made to a registry evenly to stay within its rate limit
+type limiter struct {
+	interval time.Duration
+	next     time.Time
+	lock     sync.Mutex
+}
+
+// wait blocks until the next request may be made, or the context is done
+func (l *limiter) wait(ctx context.Context) error {
+	l.lock.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.lock.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limited sends the requests once its limiter allows them
+type limited struct {
+	next    http.RoundTripper
+	limiter *limiter
+}
+
+func (l *limited) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := l.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return l.next.RoundTrip(req)
+}
@@ -0,0 +1,166 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/registry/hosts"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestTransport(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Registry Transport Suite")
+}
+
+var _ = Describe("the registry client", func() {
+	var requests int
+	var statuses []int
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		retryBackoff = time.Millisecond
+		requests = 0
+		statuses = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			status := http.StatusOK
+			if requests < len(statuses) {
+				status = statuses[requests]
+			}
+			requests++
+			w.WriteHeader(status)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(Configure(Options{})).To(Succeed())
+	})
+
+	When("retries are enabled", func() {
+		It("should retry server errors", func() {
+			Expect(Configure(Options{MaxRetries: 2})).To(Succeed())
+			statuses = []int{http.StatusBadGateway, http.StatusServiceUnavailable}
+			res, err := NewClient(false).Head(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(requests).To(Equal(3))
+		})
+
+		It("should give up after the maximum number of retries", func() {
+			Expect(Configure(Options{MaxRetries: 1})).To(Succeed())
+			statuses = []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}
+			res, err := NewClient(false).Head(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.StatusCode).To(Equal(http.StatusBadGateway))
+			Expect(requests).To(Equal(2))
+		})
+
+		It("should not retry rate limited requests", func() {
+			Expect(Configure(Options{MaxRetries: 2})).To(Succeed())
+			statuses = []int{http.StatusTooManyRequests}
+			res, err := NewClient(false).Head(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(requests).To(Equal(1))
+		})
+	})
+
+	When("retries are disabled", func() {
+		It("should return the first response", func() {
+			statuses = []int{http.StatusBadGateway}
+			res, err := NewClient(false).Head(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.StatusCode).To(Equal(http.StatusBadGateway))
+			Expect(requests).To(Equal(1))
+		})
+	})
+
+	It("should reject a negative number of retries", func() {
+		Expect(Configure(Options{MaxRetries: -1})).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("the registry settings", func() {
+	var mirrorRequests []string
+	var mirror *httptest.Server
+
+	BeforeEach(func() {
+		mirrorRequests = nil
+		mirror = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mirrorRequests = append(mirrorRequests, r.URL.Path)
+		}))
+	})
+
+	AfterEach(func() {
+		mirror.Close()
+		Expect(hosts.Configure(nil)).To(Succeed())
+		Expect(Configure(Options{})).To(Succeed())
+	})
+
+	It("should send the requests to the mirror of the registry", func() {
+		Expect(hosts.Configure(map[string]hosts.Config{"registry.example.com": {Mirror: mirror.URL}})).To(Succeed())
+		Expect(Configure(Options{})).To(Succeed())
+		res, err := NewClient(false).Head("https://registry.example.com/v2/library/nginx/manifests/latest")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(mirrorRequests).To(ConsistOf("/v2/library/nginx/manifests/latest"))
+	})
+
+	It("should space the requests to stay within the rate limit", func() {
+		host := strings.TrimPrefix(mirror.URL, "http://")
+		Expect(hosts.Configure(map[string]hosts.Config{host: {RateLimit: 600}})).To(Succeed())
+		Expect(Configure(Options{})).To(Succeed())
+		client := NewClient(false)
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := client.Head(mirror.URL + "/v2/")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+		Expect(mirrorRequests).To(HaveLen(3))
+	})
+})
+
+var _ = Describe("the registry proxy selection", func() {
+	target := func(rawURL string) *http.Request {
+		u, _ := url.Parse(rawURL)
+		return &http.Request{URL: u}
+	}
+
+	It("should use the configured proxy", func() {
+		proxy, err := proxyFunc(Options{Proxy: "http://proxy.example.com:3128"})(target("https://registry.example.com/v2/"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proxy.String()).To(Equal("http://proxy.example.com:3128"))
+	})
+
+	It("should reach the excluded hosts directly", func() {
+		opts := Options{Proxy: "http://proxy.example.com:3128", NoProxy: ".internal.example.com"}
+		proxy, err := proxyFunc(opts)(target("https://registry.internal.example.com/v2/"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proxy).To(BeNil())
+	})
+
+	It("should fall back to the environment variables", func() {
+		for key, value := range map[string]string{"HTTPS_PROXY": "http://env-proxy.example.com:8080", "NO_PROXY": ""} {
+			previous, found := os.LookupEnv(key)
+			_ = os.Setenv(key, value)
+			defer func(key string) {
+				if found {
+					_ = os.Setenv(key, previous)
+				} else {
+					_ = os.Unsetenv(key)
+				}
+			}(key)
+		}
+		proxy, err := proxyFunc(Options{})(target("https://registry.example.com/v2/"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proxy.String()).To(Equal("http://env-proxy.example.com:8080"))
+	})
+})
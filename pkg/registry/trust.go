@@ -4,9 +4,12 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/containrrr/watchtower/pkg/registry/hosts"
 	cliconfig "github.com/docker/cli/cli/config"
 	"github.com/docker/cli/cli/config/configfile"
 	"github.com/docker/cli/cli/config/credentials"
@@ -16,9 +19,12 @@ import (
 )
 
 // EncodedAuth returns an encoded auth config for the given registry
-// loaded from environment variables or docker config
+// loaded from the registry settings, environment variables or docker config
 // as available in that order
 func EncodedAuth(ref string) (string, error) {
+	if auth, found := encodedHostAuth(ref); found {
+		return EncodeAuth(auth)
+	}
 	auth, err := EncodedEnvAuth(ref)
 	if err != nil {
 		auth, err = EncodedConfigAuth(ref)
@@ -44,6 +50,21 @@ func EncodedEnvAuth(ref string) (string, error) {
 	return "", errors.New("registry auth environment variables (REPO_USER, REPO_PASS) not set")
 }
 
+// encodedHostAuth returns the credentials set in the settings of the registry of the given image, if any
+func encodedHostAuth(ref string) (types.AuthConfig, bool) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return types.AuthConfig{}, false
+	}
+	registry, found := hosts.Lookup(reference.Domain(named))
+	if !found || !registry.HasCredentials() {
+		return types.AuthConfig{}, false
+	}
+	log.Debugf("Loaded auth credentials for user %s on registry %s from the registry settings", registry.Username, ref)
+	log.Tracef("Using auth password %s", registry.Password)
+	return types.AuthConfig{Username: registry.Username, Password: registry.Password}, true
+}
+
 // EncodedConfigAuth returns an encoded auth config for the given registry
 // loaded from the docker config
 // Returns an empty string if credentials cannot be found for the referenced server
@@ -58,6 +79,34 @@ func EncodedConfigAuth(ref string) (string, error) {
 	if configDir == "" {
 		configDir = "/"
 	}
+	return encodedConfigDirAuth(ref, server, configDir)
+}
+
+// EncodedCredentialSetAuth returns an encoded auth config for the given registry loaded from the docker config of the
+// named credential set, being the config.json file in the sub directory of the credentials directory with its name.
+// Returns an error if the credential set does not exist or has no credentials for the referenced server.
+func EncodedCredentialSetAuth(ref string, credentialsDir string, set string) (string, error) {
+	if set == "." || set == ".." || strings.ContainsAny(set, `/\`) {
+		return "", fmt.Errorf("invalid credential set name %q", set)
+	}
+	server, err := ParseServerAddress(ref)
+	if err != nil {
+		log.Errorf("Unable to parse the image ref %s", err)
+		return "", err
+	}
+	configDir := filepath.Join(credentialsDir, set)
+	if _, err := os.Stat(filepath.Join(configDir, cliconfig.ConfigFileName)); err != nil {
+		return "", fmt.Errorf("unable to load the credential set %q: %w", set, err)
+	}
+
+	auth, err := encodedConfigDirAuth(ref, server, configDir)
+	if err == nil && auth == "" {
+		err = fmt.Errorf("the credential set %q has no credentials for %s", set, server)
+	}
+	return auth, err
+}
+
+func encodedConfigDirAuth(ref string, server string, configDir string) (string, error) {
 	configFile, err := cliconfig.Load(configDir)
 	if err != nil {
 		log.Errorf("Unable to find default config file %s", err)
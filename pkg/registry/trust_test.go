@@ -1,9 +1,13 @@
 package registry
 
 import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+
+	"github.com/containrrr/watchtower/pkg/registry/hosts"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"os"
 )
 
 var _ = Describe("Testing with Ginkgo", func() {
@@ -38,6 +42,58 @@ var _ = Describe("Testing with Ginkgo", func() {
 		Expect(err).To(HaveOccurred())
 
 	})
+	It("encoded auth_ should prefer the credentials of the registry settings", func() {
+		Expect(os.Setenv("REPO_USER", "containrrr-user")).To(Succeed())
+		Expect(os.Setenv("REPO_PASS", "containrrr-pass")).To(Succeed())
+		Expect(hosts.Configure(map[string]hosts.Config{
+			"ghcr.io": {Username: "registry-user", Password: "registry-pass"},
+		})).To(Succeed())
+		defer func() { _ = hosts.Configure(nil) }()
+
+		auth, err := EncodedAuth("ghcr.io/containrrr/watchtower:latest")
+		Expect(err).NotTo(HaveOccurred())
+		decoded, _ := base64.URLEncoding.DecodeString(auth)
+		Expect(string(decoded)).To(ContainSubstring(`"username":"registry-user"`))
+
+		auth, err = EncodedAuth("containrrr/watchtower:latest")
+		Expect(err).NotTo(HaveOccurred())
+		decoded, _ = base64.URLEncoding.DecodeString(auth)
+		Expect(string(decoded)).To(ContainSubstring(`"username":"containrrr-user"`))
+	})
+	When("a credential set is used", func() {
+		var credentialsDir string
+		BeforeEach(func() {
+			var err error
+			credentialsDir, err = os.MkdirTemp("", "watchtower-credentials")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Mkdir(filepath.Join(credentialsDir, "harbor-prod"), 0700)).To(Succeed())
+			config := `{"auths": {"harbor.example.com": {"auth": "cm9ib3Q6c2VjcmV0"}}}`
+			Expect(os.WriteFile(filepath.Join(credentialsDir, "harbor-prod", "config.json"), []byte(config), 0600)).To(Succeed())
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(credentialsDir)
+		})
+		It("should return the credentials of the set", func() {
+			auth, err := EncodedCredentialSetAuth("harbor.example.com/prod/app:latest", credentialsDir, "harbor-prod")
+			Expect(err).NotTo(HaveOccurred())
+			decoded, err := base64.URLEncoding.DecodeString(auth)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(decoded)).To(ContainSubstring(`"username":"robot"`))
+			Expect(string(decoded)).To(ContainSubstring(`"password":"secret"`))
+		})
+		It("should return an error if the set has no credentials for the registry", func() {
+			_, err := EncodedCredentialSetAuth("ghcr.io/containrrr/watchtower:latest", credentialsDir, "harbor-prod")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should return an error if the set does not exist", func() {
+			_, err := EncodedCredentialSetAuth("harbor.example.com/prod/app:latest", credentialsDir, "harbor-staging")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should reject set names outside of the credentials directory", func() {
+			_, err := EncodedCredentialSetAuth("harbor.example.com/prod/app:latest", credentialsDir, "../harbor-prod")
+			Expect(err).To(HaveOccurred())
+		})
+	})
 	/*
 	 * TODO:
 	 * This part only confirms that it still works in the same way as it did
@@ -0,0 +1,153 @@
+package releases
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/internal/meta"
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// SourceLabel is the OCI annotation containing the URL of the source code of the image
+	SourceLabel = "org.opencontainers.image.source"
+	// URLLabel is the OCI annotation containing the URL with information about the image
+	URLLabel = "org.opencontainers.image.url"
+	// VersionLabel is the OCI annotation containing the version of the packaged software
+	VersionLabel = "org.opencontainers.image.version"
+
+	// maxNotesLength limits the length of the release notes included in notifications
+	maxNotesLength = 2000
+	defaultAPIURL  = "https://api.github.com"
+)
+
+// Resolver determines the release information of images from their OCI labels. Links to GitHub releases are derived
+// from GitHub source URLs, and their notes are retrieved using the GitHub API if enabled.
+type Resolver struct {
+	fetchNotes bool
+	token      string
+	apiURL     string
+	client     *http.Client
+}
+
+// NewResolver creates a resolver, which retrieves the release notes from GitHub if fetchNotes is set, optionally
+// authenticating using token to avoid the rate limits of anonymous requests
+func NewResolver(fetchNotes bool, token string) *Resolver {
+	return &Resolver{
+		fetchNotes: fetchNotes,
+		token:      token,
+		apiURL:     defaultAPIURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Resolve returns the release information for an image with the supplied labels
+func (r *Resolver) Resolve(labels map[string]string) types.Release {
+	release := types.Release{Version: labels[VersionLabel]}
+
+	source := labels[SourceLabel]
+	if source == "" {
+		source = labels[URLLabel]
+	}
+	if source == "" {
+		return release
+	}
+	release.URL = source
+
+	owner, repo, ok := gitHubRepository(source)
+	if !ok {
+		return release
+	}
+	release.URL = fmt.Sprintf("https://github.com/%s/%s/releases", owner, repo)
+	if release.Version == "" {
+		return release
+	}
+	release.URL = fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", owner, repo, url.PathEscape(release.Version))
+
+	if r.fetchNotes {
+		tag, notes, err := r.fetchRelease(owner, repo, release.Version)
+		if err != nil {
+			log.WithError(err).Debugf("Could not retrieve the release notes of %s/%s %s", owner, repo, release.Version)
+		} else {
+			release.URL = fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", owner, repo, url.PathEscape(tag))
+			release.Notes = truncate(notes, maxNotesLength)
+		}
+	}
+	return release
+}
+
+// gitHubRepository returns the owner and name of the repository referred to by a GitHub URL
+func gitHubRepository(source string) (string, string, bool) {
+	source = strings.TrimPrefix(source, "git+")
+	parsed, err := url.Parse(source)
+	if err != nil || !strings.EqualFold(parsed.Host, "github.com") {
+		return "", "", false
+	}
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), true
+}
+
+// fetchRelease retrieves the release notes for the version, trying both the version itself and the version
+// prefixed with a "v" as the tag name
+func (r *Resolver) fetchRelease(owner string, repo string, version string) (string, string, error) {
+	tags := []string{version}
+	if !strings.HasPrefix(version, "v") {
+		tags = append(tags, "v"+version)
+	}
+
+	var err error
+	for _, tag := range tags {
+		var body string
+		if body, err = r.fetchReleaseBody(owner, repo, tag); err == nil {
+			return tag, body, nil
+		}
+	}
+	return "", "", err
+}
+
+func (r *Resolver) fetchReleaseBody(owner string, repo string, tag string) (string, error) {
+	releaseURL := fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", r.apiURL, owner, repo, url.PathEscape(tag))
+	req, err := http.NewRequest("GET", releaseURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", meta.UserAgent)
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub responded with %q", res.Status)
+	}
+
+	release := struct {
+		Body string `json:"body"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&release); err != nil {
+		return "", err
+	}
+	return release.Body, nil
+}
+
+func truncate(text string, length int) string {
+	text = strings.TrimSpace(text)
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:length])) + "…"
+}
@@ -0,0 +1,101 @@
+package releases
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func TestReleases(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Releases Suite")
+}
+
+var _ = Describe("the release resolver", func() {
+	githubLabels := map[string]string{
+		SourceLabel:  "https://github.com/containrrr/watchtower.git",
+		VersionLabel: "1.5.0",
+	}
+
+	When("the image has no source labels", func() {
+		It("should only return the version", func() {
+			release := NewResolver(false, "").Resolve(map[string]string{VersionLabel: "1.5.0"})
+			Expect(release.Version).To(Equal("1.5.0"))
+			Expect(release.URL).To(BeEmpty())
+		})
+	})
+
+	When("the source is not hosted on GitHub", func() {
+		It("should link to the source", func() {
+			release := NewResolver(true, "").Resolve(map[string]string{
+				URLLabel:     "https://gitlab.com/group/project",
+				VersionLabel: "1.5.0",
+			})
+			Expect(release.URL).To(Equal("https://gitlab.com/group/project"))
+			Expect(release.Notes).To(BeEmpty())
+		})
+	})
+
+	When("the source is hosted on GitHub", func() {
+		It("should link to the release of the version", func() {
+			release := NewResolver(false, "").Resolve(githubLabels)
+			Expect(release.URL).To(Equal("https://github.com/containrrr/watchtower/releases/tag/1.5.0"))
+		})
+		It("should link to the releases if the version is unknown", func() {
+			release := NewResolver(false, "").Resolve(map[string]string{SourceLabel: githubLabels[SourceLabel]})
+			Expect(release.URL).To(Equal("https://github.com/containrrr/watchtower/releases"))
+		})
+	})
+
+	When("retrieving the release notes", func() {
+		var server *ghttp.Server
+		var resolver *Resolver
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			resolver = NewResolver(true, "secret")
+			resolver.apiURL = server.URL()
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should fall back to the tag prefixed with a v", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/repos/containrrr/watchtower/releases/tags/1.5.0"),
+					ghttp.RespondWith(http.StatusNotFound, nil),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/repos/containrrr/watchtower/releases/tags/v1.5.0"),
+					ghttp.VerifyHeaderKV("Authorization", "Bearer secret"),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, map[string]string{"body": "Fixed all the bugs\n"}),
+				),
+			)
+			release := resolver.Resolve(githubLabels)
+			Expect(release.URL).To(Equal("https://github.com/containrrr/watchtower/releases/tag/v1.5.0"))
+			Expect(release.Notes).To(Equal("Fixed all the bugs"))
+		})
+		It("should keep the link if the notes cannot be retrieved", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusForbidden, nil),
+				ghttp.RespondWith(http.StatusForbidden, nil),
+			)
+			release := resolver.Resolve(githubLabels)
+			Expect(release.URL).To(Equal("https://github.com/containrrr/watchtower/releases/tag/1.5.0"))
+			Expect(release.Notes).To(BeEmpty())
+		})
+		It("should truncate long release notes", func() {
+			server.AppendHandlers(
+				ghttp.RespondWithJSONEncoded(http.StatusOK, map[string]string{"body": strings.Repeat("a", maxNotesLength+10)}),
+			)
+			release := resolver.Resolve(githubLabels)
+			Expect([]rune(release.Notes)).To(HaveLen(maxNotesLength + 1))
+			Expect(release.Notes).To(HaveSuffix("…"))
+		})
+	})
+})
@@ -0,0 +1,138 @@
+package rotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// File is a file that is rotated once writing to it would exceed the maximum size, keeping the configured number of
+// previous files with the suffixes .1, .2 and so on. It is safe for concurrent use.
+type File struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mutex  sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// Open opens the file at the path, appending to it if it exists, and creates its directory if needed. A max size of 0
+// disables the rotation by size.
+func Open(path string, maxSize int64, maxFiles int) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("could not create the directory: %w", err)
+	}
+	f := &File{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file, f.size, f.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+// Write writes the data to the file, rotating it first if the data would make it exceed the maximum size. The data is
+// written even if the rotation failed, as long as the file could be opened again. Writing to a closed file returns
+// os.ErrClosed.
+func (f *File) Write(data []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	var rotateErr error
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(data)) > f.maxSize {
+		if rotateErr = f.rotate(); rotateErr != nil && f.file == nil {
+			return 0, rotateErr
+		}
+	}
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+// Rotate rotates the file regardless of the maximum size, like once it has been written to for too long. An empty file
+// is kept as it is.
+func (f *File) Rotate() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	if f.size == 0 {
+		return nil
+	}
+	return f.rotate()
+}
+
+// rotate shifts the previous files by one, dropping the oldest, and starts a new file. When shifting the files fails,
+// the current file is opened again, so that writing to it can go on.
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("could not rotate %s: %w", f.path, err)
+	}
+	f.file = nil
+	shiftErr := f.shift()
+	if err := f.open(); err != nil {
+		return fmt.Errorf("could not rotate %s: %w", f.path, err)
+	}
+	if shiftErr != nil {
+		return fmt.Errorf("could not rotate %s: %w", f.path, shiftErr)
+	}
+	return nil
+}
+
+func (f *File) shift() error {
+	if f.maxFiles <= 0 {
+		return os.Remove(f.path)
+	}
+	for i := f.maxFiles - 1; i > 0; i-- {
+		from := fmt.Sprintf("%s.%d", f.path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", f.path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(f.path, f.path+".1")
+}
+
+// Opened returns when the current file was opened, which is when it was started unless it already existed
+func (f *File) Opened() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.opened
+}
+
+// Close closes the file, after which writing to it fails with os.ErrClosed
+func (f *File) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,102 @@
+package rotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRotate(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Rotate Suite")
+}
+
+var _ = Describe("the rotated file", func() {
+	var dir, path string
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-rotate")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "logs", "file.log")
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	read := func(path string) string {
+		content, err := os.ReadFile(path)
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+		return string(content)
+	}
+
+	It("should append to the existing file", func() {
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte("first\n"), 0o640)).To(Succeed())
+		f, err := Open(path, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Write([]byte("second\n"))).To(Equal(7))
+		Expect(f.Close()).To(Succeed())
+		Expect(read(path)).To(Equal("first\nsecond\n"))
+	})
+
+	It("should rotate the file once it would exceed the max size, keeping the max files", func() {
+		f, err := Open(path, 10, 2)
+		Expect(err).NotTo(HaveOccurred())
+		for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n", "six\n"} {
+			_, err := f.Write([]byte(line))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(f.Close()).To(Succeed())
+
+		Expect(read(path)).To(Equal("six\n"))
+		Expect(read(path + ".1")).To(Equal("four\nfive\n"))
+		Expect(read(path + ".2")).To(Equal("three\n"))
+		Expect(path + ".3").NotTo(BeAnExistingFile())
+	})
+
+	It("should start over without previous files if none are kept", func() {
+		f, err := Open(path, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, _ = f.Write([]byte("one\n"))
+		Expect(f.Rotate()).To(Succeed())
+		_, _ = f.Write([]byte("two\n"))
+		Expect(f.Close()).To(Succeed())
+
+		Expect(read(path)).To(Equal("two\n"))
+		Expect(path + ".1").NotTo(BeAnExistingFile())
+	})
+
+	It("should not rotate an empty file", func() {
+		f, err := Open(path, 0, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Rotate()).To(Succeed())
+		Expect(f.Close()).To(Succeed())
+		Expect(path + ".1").NotTo(BeAnExistingFile())
+	})
+
+	It("should keep writing to the file if it could not be rotated", func() {
+		f, err := Open(path, 0, 1)
+		Expect(err).NotTo(HaveOccurred())
+		_, _ = f.Write([]byte("one\n"))
+		// a directory in place of the previous file makes shifting the files fail
+		Expect(os.MkdirAll(filepath.Join(path+".1", "blocked"), 0o755)).To(Succeed())
+		Expect(f.Rotate()).To(MatchError(ContainSubstring("could not rotate")))
+		_, err = f.Write([]byte("two\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		Expect(read(path)).To(Equal("one\ntwo\n"))
+	})
+
+	It("should fail writing once closed", func() {
+		f, err := Open(path, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		_, err = f.Write([]byte("late\n"))
+		Expect(err).To(MatchError(os.ErrClosed))
+		Expect(f.Rotate()).To(MatchError(os.ErrClosed))
+		Expect(f.Close()).To(Succeed())
+	})
+})
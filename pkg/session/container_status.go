@@ -1,6 +1,10 @@
 package session
 
-import wt "github.com/containrrr/watchtower/pkg/types"
+import (
+	"time"
+
+	wt "github.com/containrrr/watchtower/pkg/types"
+)
 
 // State indicates what the current state is of the container
 type State int
@@ -15,6 +19,7 @@ const (
 	FailedState
 	FreshState
 	StaleState
+	QuarantinedState
 )
 
 // ContainerStatus contains the container state during a session
@@ -25,7 +30,21 @@ type ContainerStatus struct {
 	containerName string
 	imageName     string
 	error
-	state State
+	state        State
+	release      wt.Release
+	imageChanges []string
+	pulledBytes  int64
+	retrying     bool
+	skipReason   wt.SkipReason
+	project      string
+	logs         string
+	// pullDuration is the time spent checking for the latest image, when it was pulled
+	pullDuration time.Duration
+	// recreateDuration is the time spent stopping and starting the container
+	recreateDuration time.Duration
+	currentRef       string
+	latestRef        string
+	compareURL       string
 }
 
 // ID returns the container ID
@@ -61,8 +80,66 @@ func (u *ContainerStatus) Error() string {
 	return u.error.Error()
 }
 
+// Release returns the release information of the latest image, if it was resolved
+func (u *ContainerStatus) Release() wt.Release {
+	return u.release
+}
+
+// ImageChanges returns the differences between the configurations of the current and latest image
+func (u *ContainerStatus) ImageChanges() []string {
+	return u.imageChanges
+}
+
+// PulledBytes returns the number of bytes downloaded while pulling the latest image
+func (u *ContainerStatus) PulledBytes() int64 {
+	return u.pulledBytes
+}
+
+// PullDuration returns the time spent checking for and pulling the latest image, if anything was downloaded
+func (u *ContainerStatus) PullDuration() time.Duration {
+	return u.pullDuration
+}
+
+// RecreateDuration returns the time spent stopping the container and starting it again, if it was recreated
+func (u *ContainerStatus) RecreateDuration() time.Duration {
+	return u.recreateDuration
+}
+
+// SkipReason returns why the container was skipped, or left as it was despite a new image, if it was
+func (u *ContainerStatus) SkipReason() wt.SkipReason {
+	return u.skipReason
+}
+
+// CurrentReference returns the name and digest of the current image, if the container is stale
+func (u *ContainerStatus) CurrentReference() string {
+	return u.currentRef
+}
+
+// LatestReference returns the name and digest of the latest image, if the container is stale
+func (u *ContainerStatus) LatestReference() string {
+	return u.latestRef
+}
+
+// CompareURL returns the link to the page of the latest image on the website of its registry, if it is known
+func (u *ContainerStatus) CompareURL() string {
+	return u.compareURL
+}
+
+// Project returns the name of the compose project of the container, if it belongs to one
+func (u *ContainerStatus) Project() string {
+	return u.project
+}
+
+// Logs returns the tail of the logs of the container, if it failed after being recreated
+func (u *ContainerStatus) Logs() string {
+	return u.logs
+}
+
 // State returns the current State that the container is in
 func (u *ContainerStatus) State() string {
+	if u.retrying {
+		return "Retrying"
+	}
 	switch u.state {
 	case SkippedState:
 		return "Skipped"
@@ -76,6 +153,8 @@ func (u *ContainerStatus) State() string {
 		return "Fresh"
 	case StaleState:
 		return "Stale"
+	case QuarantinedState:
+		return "Quarantined"
 	default:
 		return "Unknown"
 	}
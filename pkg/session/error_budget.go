@@ -0,0 +1,86 @@
+package session
+
+import (
+	"sync"
+
+	wt "github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// errorBudgetKey is the state store key of the error budgets of the images
+const errorBudgetKey = "error-budget"
+
+// imageBudget is how much of the error budget of an image has been spent
+type imageBudget struct {
+	Spent int `json:"spent"`
+}
+
+// ErrorBudget tolerates a number of sessions in a row in which checking an image failed before escalating the
+// failures. Each session with a failed check spends the budget of the image, while a session in which it was checked
+// successfully restores it fully. The budgets are kept in the state store, if any, so that they survive restarts.
+type ErrorBudget struct {
+	threshold int
+	state     wt.StateStore
+
+	lock    sync.Mutex
+	budgets map[string]imageBudget
+}
+
+// NewErrorBudget creates an error budget escalating failures once an image has failed threshold checks in a row,
+// loading the budgets spent during the previous sessions from the state store, if set
+func NewErrorBudget(threshold int, state wt.StateStore) *ErrorBudget {
+	if threshold < 1 {
+		threshold = 1
+	}
+	budgets := map[string]imageBudget{}
+	if state != nil {
+		if _, err := state.Get(errorBudgetKey, &budgets); err != nil {
+			log.WithError(err).Warn("Could not load the error budgets of the previous sessions, starting over")
+			budgets = map[string]imageBudget{}
+		}
+	}
+	return &ErrorBudget{
+		threshold: threshold,
+		state:     state,
+		budgets:   budgets,
+	}
+}
+
+// Spend records a session in which checking the image failed, and returns whether the failure should be escalated
+func (b *ErrorBudget) Spend(imageName string) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	budget := b.budgets[imageName]
+	if budget.Spent < b.threshold {
+		budget.Spent++
+	}
+	b.budgets[imageName] = budget
+	b.save()
+	return budget.Spent >= b.threshold
+}
+
+// Restore records a session in which the image was checked successfully, which ends the failures in a row
+func (b *ErrorBudget) Restore(imageName string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if _, found := b.budgets[imageName]; !found {
+		return
+	}
+	delete(b.budgets, imageName)
+	b.save()
+}
+
+func (b *ErrorBudget) save() {
+	if b.state == nil {
+		return
+	}
+	var value interface{} = b.budgets
+	if len(b.budgets) == 0 {
+		value = nil
+	}
+	if err := b.state.Set(errorBudgetKey, value); err != nil {
+		log.WithError(err).Error("Could not save the error budgets")
+	}
+}
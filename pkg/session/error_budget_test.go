@@ -0,0 +1,57 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/containrrr/watchtower/pkg/state"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSession(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Session Suite")
+}
+
+var _ = Describe("the error budget", func() {
+	const image = "containrrr/watchtower:latest"
+
+	It("should only escalate once the image has failed the threshold number of checks in a row", func() {
+		budget := NewErrorBudget(3, nil)
+		Expect(budget.Spend(image)).To(BeFalse())
+		Expect(budget.Spend(image)).To(BeFalse())
+		Expect(budget.Spend(image)).To(BeTrue())
+	})
+	It("should keep track of each image separately", func() {
+		budget := NewErrorBudget(2, nil)
+		Expect(budget.Spend(image)).To(BeFalse())
+		Expect(budget.Spend("containrrr/other:latest")).To(BeFalse())
+		Expect(budget.Spend(image)).To(BeTrue())
+	})
+	It("should start over once the image has been checked successfully", func() {
+		budget := NewErrorBudget(3, nil)
+		budget.Spend(image)
+		budget.Spend(image)
+		budget.Restore(image)
+		Expect(budget.Spend(image)).To(BeFalse())
+		Expect(budget.Spend(image)).To(BeFalse())
+		Expect(budget.Spend(image)).To(BeTrue())
+	})
+	It("should stop escalating once an escalated image has been checked successfully", func() {
+		budget := NewErrorBudget(2, nil)
+		budget.Spend(image)
+		Expect(budget.Spend(image)).To(BeTrue())
+		Expect(budget.Spend(image)).To(BeTrue())
+		budget.Restore(image)
+		Expect(budget.Spend(image)).To(BeFalse())
+	})
+	It("should keep the budgets in the state store", func() {
+		store, err := state.New("")
+		Expect(err).NotTo(HaveOccurred())
+		NewErrorBudget(2, store).Spend(image)
+		Expect(NewErrorBudget(2, store).Spend(image)).To(BeTrue())
+	})
+	It("should escalate every failure when the threshold is zero", func() {
+		Expect(NewErrorBudget(0, nil).Spend(image)).To(BeTrue())
+	})
+})
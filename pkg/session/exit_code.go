@@ -0,0 +1,63 @@
+package session
+
+import "github.com/containrrr/watchtower/pkg/types"
+
+// The exit codes of a one time session, by its outcome. When a session has several outcomes, the one listed first
+// after ExitOK takes precedence.
+const (
+	// ExitOK is used when there was nothing to update, or when all the updates were applied if that is not considered
+	// a failure
+	ExitOK = 0
+	// ExitError is used when the session could not run at all
+	ExitError = 1
+	// ExitUpdateFailed is used when any of the containers failed to update
+	ExitUpdateFailed = 2
+	// ExitScanFailed is used when checking or updating any of the containers failed before stopping it, rather than
+	// being skipped on purpose
+	ExitScanFailed = 3
+	// ExitUpdatesFound is used when any of the containers were updated or have a new image, if that is considered a
+	// failure
+	ExitUpdatesFound = 4
+)
+
+// intentionalSkips are the reasons for skipping containers that were not meant to be checked or updated, as decided by
+// their configuration, the missing image info policy, a hook or the update strategy, rather than failing to be. The containers left as they were due
+// to another container of their transactional group, or the shutdown, are included as well, since the failure is
+// reported on its own. Containers skipped for any other reason fail the session with ExitScanFailed.
+var intentionalSkips = map[types.SkipReason]bool{
+	SkipWithinErrorBudget:  true,
+	SkipImageMissing:       true,
+	SkipPinnedDigest:       true,
+	SkipVetoedByHook:       true,
+	SkipMonitorOnly:        true,
+	SkipStageOnly:          true,
+	SkipAwaitingApproval:   true,
+	SkipImageTooNew:        true,
+	SkipCheckNotDue:        true,
+	SkipNoPull:             true,
+	SkipShuttingDown:       true,
+	SkipGroupIncomplete:    true,
+	SkipRolledBack:         true,
+	SkipVetoedByStrategy:   true,
+	SkipDeferredByStrategy: true,
+}
+
+// ExitCode returns the exit code describing the outcome of a one time session. If failOnUpdate is set, updated
+// containers and containers with a new image fail the session, for pipelines that check for drift.
+func ExitCode(report types.Report, err error, failOnUpdate bool) int {
+	if err != nil || report == nil {
+		return ExitError
+	}
+	if len(report.Failed()) > 0 {
+		return ExitUpdateFailed
+	}
+	for _, skipped := range report.Skipped() {
+		if !intentionalSkips[skipped.SkipReason()] {
+			return ExitScanFailed
+		}
+	}
+	if failOnUpdate && len(report.Updated())+len(report.Stale()) > 0 {
+		return ExitUpdatesFound
+	}
+	return ExitOK
+}
@@ -0,0 +1,92 @@
+package session_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the exit code", func() {
+	It("should be zero if there was nothing to update", func() {
+		report := mocks.CreateMockProgressReport(session.FreshState, session.FreshState)
+		Expect(session.ExitCode(report, nil, true)).To(Equal(session.ExitOK))
+	})
+	It("should only be non-zero for updates if they fail the session", func() {
+		report := mocks.CreateMockProgressReport(session.FreshState, session.UpdatedState)
+		Expect(session.ExitCode(report, nil, false)).To(Equal(session.ExitOK))
+		Expect(session.ExitCode(report, nil, true)).To(Equal(session.ExitUpdatesFound))
+	})
+	It("should count new images that were not applied as updates", func() {
+		report := mocks.CreateMockProgressReport(session.StaleState)
+		Expect(session.ExitCode(report, nil, true)).To(Equal(session.ExitUpdatesFound))
+	})
+	It("should report failed updates over scan errors and updates", func() {
+		report := mocks.CreateMockProgressReport(session.UpdatedState, session.SkippedState, session.FailedState)
+		Expect(session.ExitCode(report, nil, true)).To(Equal(session.ExitUpdateFailed))
+	})
+	It("should report scan errors over updates", func() {
+		report := mocks.CreateMockProgressReport(session.UpdatedState, session.SkippedState)
+		Expect(session.ExitCode(report, nil, true)).To(Equal(session.ExitScanFailed))
+	})
+	It("should only report the containers that could not be checked as scan errors", func() {
+		progress := session.Progress{}
+		c, _ := mocks.CreateContainerForProgress(0, 41, "skip%d")
+		progress.AddSkipped(c, session.WithSkipReason(session.SkipCheckNotDue, errors.New("not due")))
+		Expect(session.ExitCode(progress.Report(), nil, true)).To(Equal(session.ExitOK))
+	})
+	It("should report containers skipped without a reason as scan errors", func() {
+		progress := session.Progress{}
+		c, _ := mocks.CreateContainerForProgress(0, 41, "skip%d")
+		progress.AddSkipped(c, errors.New("no registry"))
+		Expect(session.ExitCode(progress.Report(), nil, false)).To(Equal(session.ExitScanFailed))
+	})
+	for _, reason := range []types.SkipReason{
+		session.SkipCheckFailed,
+		session.SkipRateLimited,
+		session.SkipInsufficientDiskSpace,
+		session.SkipInvalidConfig,
+		session.SkipNotPermitted,
+	} {
+		reason := reason
+		It(fmt.Sprintf("should report containers skipped as %s as scan errors", reason), func() {
+			progress := session.Progress{}
+			c, _ := mocks.CreateContainerForProgress(0, 41, "skip%d")
+			progress.AddSkipped(c, session.WithSkipReason(reason, errors.New("skipped")))
+			Expect(session.ExitCode(progress.Report(), nil, false)).To(Equal(session.ExitScanFailed))
+		})
+	}
+	for _, reason := range []types.SkipReason{
+		session.SkipWithinErrorBudget,
+		session.SkipImageMissing,
+		session.SkipPinnedDigest,
+		session.SkipVetoedByHook,
+		session.SkipMonitorOnly,
+		session.SkipStageOnly,
+		session.SkipAwaitingApproval,
+		session.SkipImageTooNew,
+		session.SkipCheckNotDue,
+		session.SkipNoPull,
+		session.SkipShuttingDown,
+		session.SkipGroupIncomplete,
+		session.SkipRolledBack,
+		session.SkipVetoedByStrategy,
+		session.SkipDeferredByStrategy,
+	} {
+		reason := reason
+		It(fmt.Sprintf("should not report containers skipped as %s as scan errors", reason), func() {
+			progress := session.Progress{}
+			c, _ := mocks.CreateContainerForProgress(0, 41, "skip%d")
+			progress.AddSkipped(c, session.WithSkipReason(reason, errors.New("skipped")))
+			Expect(session.ExitCode(progress.Report(), nil, false)).To(Equal(session.ExitOK))
+		})
+	}
+	It("should report an error if the session could not run", func() {
+		Expect(session.ExitCode(nil, errors.New("no docker"), false)).To(Equal(session.ExitError))
+	})
+})
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/containrrr/watchtower/pkg/types"
+)
+
+// reportJSON is the JSON representation of a report
+type reportJSON struct {
+	Scanned             []types.ContainerReport `json:"scanned"`
+	Updated             []types.ContainerReport `json:"updated"`
+	Failed              []types.ContainerReport `json:"failed"`
+	Skipped             []types.ContainerReport `json:"skipped"`
+	Stale               []types.ContainerReport `json:"stale"`
+	Fresh               []types.ContainerReport `json:"fresh"`
+	Retrying            []types.ContainerReport `json:"retrying"`
+	Quarantined         []types.ContainerReport `json:"quarantined"`
+	PulledBytes         int64                   `json:"pulledBytes"`
+	DurationSeconds     float64                 `json:"durationSeconds"`
+	ScanDurationSeconds float64                 `json:"scanDurationSeconds"`
+}
+
+// containerJSON is the JSON representation of the status of a container in a report
+type containerJSON struct {
+	ID               types.ContainerID `json:"id"`
+	Name             string            `json:"name"`
+	ImageName        string            `json:"imageName"`
+	CurrentImageID   types.ImageID     `json:"currentImageId"`
+	LatestImageID    types.ImageID     `json:"latestImageId"`
+	CurrentReference string            `json:"currentReference,omitempty"`
+	LatestReference  string            `json:"latestReference,omitempty"`
+	CompareURL       string            `json:"compareUrl,omitempty"`
+	State            string            `json:"state"`
+	Error            string            `json:"error,omitempty"`
+	SkipReason       types.SkipReason  `json:"skipReason,omitempty"`
+	Project          string            `json:"project,omitempty"`
+	ReleaseVersion   string            `json:"releaseVersion,omitempty"`
+	ReleaseURL       string            `json:"releaseUrl,omitempty"`
+	ReleaseNotes     string            `json:"releaseNotes,omitempty"`
+	ImageChanges     []string          `json:"imageChanges,omitempty"`
+	PulledBytes      int64             `json:"pulledBytes,omitempty"`
+	Logs             string            `json:"logs,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, listing the containers by their state
+func (r *report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(reportJSON{
+		Scanned:             r.scanned,
+		Updated:             r.updated,
+		Failed:              r.failed,
+		Skipped:             r.skipped,
+		Stale:               r.stale,
+		Fresh:               r.fresh,
+		Retrying:            r.retries,
+		Quarantined:         r.quarantined,
+		PulledBytes:         r.pulled,
+		DurationSeconds:     r.duration.Seconds(),
+		ScanDurationSeconds: r.scanDuration.Seconds(),
+	})
+}
+
+// MarshalJSON implements json.Marshaler
+func (u *ContainerStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(containerJSON{
+		ID:               u.containerID,
+		Name:             strings.TrimPrefix(u.containerName, "/"),
+		ImageName:        u.imageName,
+		CurrentImageID:   u.oldImage,
+		LatestImageID:    u.newImage,
+		CurrentReference: u.currentRef,
+		LatestReference:  u.latestRef,
+		CompareURL:       u.compareURL,
+		State:            strings.ToLower(u.State()),
+		Error:            u.Error(),
+		SkipReason:       u.skipReason,
+		Project:          u.project,
+		ReleaseVersion:   u.release.Version,
+		ReleaseURL:       u.release.URL,
+		ReleaseNotes:     u.release.Notes,
+		ImageChanges:     u.imageChanges,
+		PulledBytes:      u.pulledBytes,
+		Logs:             u.logs,
+	})
+}
@@ -0,0 +1,35 @@
+package session
+
+import "errors"
+
+// logsError is an error with the tail of the logs of the container it occurred for
+type logsError struct {
+	logs string
+	err  error
+}
+
+func (e logsError) Error() string {
+	return e.err.Error()
+}
+
+func (e logsError) Unwrap() error {
+	return e.err
+}
+
+// WithLogs annotates the error with the tail of the logs of the container, which is added to its report. The error
+// message is kept as it is.
+func WithLogs(logs string, err error) error {
+	if err == nil || logs == "" {
+		return err
+	}
+	return logsError{logs: logs, err: err}
+}
+
+// LogsOf returns the logs the error was annotated with using WithLogs, or an empty string if it was not
+func LogsOf(err error) string {
+	var withLogs logsError
+	if errors.As(err, &withLogs) {
+		return withLogs.logs
+	}
+	return ""
+}
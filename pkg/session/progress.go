@@ -1,15 +1,20 @@
 package session
 
 import (
+	"time"
+
 	"github.com/containrrr/watchtower/pkg/types"
 )
 
+// composeProjectLabel is set by docker compose to the name of the project of the container
+const composeProjectLabel = "com.docker.compose.project"
+
 // Progress contains the current session container status
 type Progress map[types.ContainerID]*ContainerStatus
 
 // UpdateFromContainer sets various status fields from their corresponding container equivalents
 func UpdateFromContainer(cont types.Container, newImage types.ImageID, state State) *ContainerStatus {
-	return &ContainerStatus{
+	status := &ContainerStatus{
 		containerID:   cont.ID(),
 		containerName: cont.Name(),
 		imageName:     cont.ImageName(),
@@ -17,12 +22,27 @@ func UpdateFromContainer(cont types.Container, newImage types.ImageID, state Sta
 		newImage:      newImage,
 		state:         state,
 	}
+	if info := cont.ContainerInfo(); info != nil && info.Config != nil {
+		status.project = info.Config.Labels[composeProjectLabel]
+	}
+	return status
 }
 
 // AddSkipped adds a container to the Progress with the state set as skipped
 func (m Progress) AddSkipped(cont types.Container, err error) {
 	update := UpdateFromContainer(cont, cont.SafeImageID(), SkippedState)
 	update.error = err
+	update.skipReason = SkipReasonOf(err)
+	if update.skipReason == "" {
+		update.skipReason = SkipCheckFailed
+	}
+	m.Add(update)
+}
+
+// AddQuarantined adds a container to the Progress with the state set as quarantined
+func (m Progress) AddQuarantined(cont types.Container, reason error) {
+	update := UpdateFromContainer(cont, cont.SafeImageID(), QuarantinedState)
+	update.error = reason
 	m.Add(update)
 }
 
@@ -31,12 +51,25 @@ func (m Progress) AddScanned(cont types.Container, newImage types.ImageID) {
 	m.Add(UpdateFromContainer(cont, newImage, ScannedState))
 }
 
-// UpdateFailed updates the containers passed, setting their state as failed with the supplied error
+// UpdateFailed updates the containers passed, setting their state as failed with the supplied error. Containers whose
+// error has a skip reason are set as skipped instead.
 func (m Progress) UpdateFailed(failures map[types.ContainerID]error) {
 	for id, err := range failures {
 		update := m[id]
 		update.error = err
 		update.state = FailedState
+		update.logs = LogsOf(err)
+		if reason := SkipReasonOf(err); reason != "" {
+			update.state = SkippedState
+			update.skipReason = reason
+		}
+	}
+}
+
+// SetSkipReason sets why the container identified by containerID was left as it was
+func (m Progress) SetSkipReason(containerID types.ContainerID, reason types.SkipReason) {
+	if update, found := m[containerID]; found {
+		update.skipReason = reason
 	}
 }
 
@@ -45,12 +78,85 @@ func (m Progress) Add(update *ContainerStatus) {
 	m[update.containerID] = update
 }
 
-// MarkForUpdate marks the container identified by containerID for update
+// SetRelease sets the release information of the latest image of the container identified by containerID
+func (m Progress) SetRelease(containerID types.ContainerID, release types.Release) {
+	if update, found := m[containerID]; found {
+		update.release = release
+	}
+}
+
+// SetImageChanges sets the differences between the current and latest image of the container identified by containerID
+func (m Progress) SetImageChanges(containerID types.ContainerID, changes []string) {
+	if update, found := m[containerID]; found {
+		update.imageChanges = changes
+	}
+}
+
+// SetReferences sets the name and digest of the current and latest image of the container identified by containerID,
+// and the link to the page of the latest image on the website of its registry
+func (m Progress) SetReferences(containerID types.ContainerID, current string, latest string, compareURL string) {
+	if update, found := m[containerID]; found {
+		update.currentRef = current
+		update.latestRef = latest
+		update.compareURL = compareURL
+	}
+}
+
+// AddPulledBytes adds the number of bytes downloaded for the container identified by containerID
+func (m Progress) AddPulledBytes(containerID types.ContainerID, bytes int64) {
+	if update, found := m[containerID]; found {
+		update.pulledBytes += bytes
+	}
+}
+
+// SetPullDuration sets the time spent pulling the latest image of the container identified by containerID
+func (m Progress) SetPullDuration(containerID types.ContainerID, duration time.Duration) {
+	if update, found := m[containerID]; found {
+		update.pullDuration = duration
+	}
+}
+
+// AddRecreateDuration adds to the time spent recreating the container identified by containerID
+func (m Progress) AddRecreateDuration(containerID types.ContainerID, duration time.Duration) {
+	if update, found := m[containerID]; found {
+		update.recreateDuration += duration
+	}
+}
+
+// MarkRetrying marks the failed container identified by containerID as being retried during the next session
+func (m Progress) MarkRetrying(containerID types.ContainerID) {
+	if update, found := m[containerID]; found {
+		update.retrying = true
+	}
+}
+
+// MarkForUpdate marks the container identified by containerID for update. Skipped and quarantined containers keep
+// their state.
 func (m Progress) MarkForUpdate(containerID types.ContainerID) {
-	m[containerID].state = UpdatedState
+	if state := m[containerID].state; state != SkippedState && state != QuarantinedState {
+		m[containerID].state = UpdatedState
+	}
+}
+
+// MarkDeferred resets the state of the container identified by containerID after it was marked for update, as it was
+// left as it was, so that it is reported as stale rather than updated
+func (m Progress) MarkDeferred(containerID types.ContainerID) {
+	if update, found := m[containerID]; found && update.state == UpdatedState {
+		update.state = ScannedState
+		update.skipReason = SkipShuttingDown
+	}
 }
 
 // Report creates a new Report from a Progress instance
 func (m Progress) Report() types.Report {
 	return NewReport(m)
 }
+
+// TimedReport creates a new Report from a Progress instance, along with the time the session took, and the time spent
+// checking the containers for new images
+func (m Progress) TimedReport(duration time.Duration, scanDuration time.Duration) types.Report {
+	report := NewReport(m).(*report)
+	report.duration = duration
+	report.scanDuration = scanDuration
+	return report
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"sort"
+	"time"
 
 	"github.com/containrrr/watchtower/pkg/types"
 )
@@ -13,6 +14,13 @@ type report struct {
 	skipped []types.ContainerReport
 	stale   []types.ContainerReport
 	fresh   []types.ContainerReport
+	retries []types.ContainerReport
+	pulled  int64
+	// quarantined are the containers that were not checked, as they failed during too many sessions
+	quarantined []types.ContainerReport
+	// duration and scanDuration are the time the session took, and the part of it spent checking the containers
+	duration     time.Duration
+	scanDuration time.Duration
 }
 
 func (r *report) Scanned() []types.ContainerReport {
@@ -33,8 +41,23 @@ func (r *report) Stale() []types.ContainerReport {
 func (r *report) Fresh() []types.ContainerReport {
 	return r.fresh
 }
+func (r *report) Retrying() []types.ContainerReport {
+	return r.retries
+}
+func (r *report) Quarantined() []types.ContainerReport {
+	return r.quarantined
+}
+func (r *report) PulledBytes() int64 {
+	return r.pulled
+}
+func (r *report) Duration() time.Duration {
+	return r.duration
+}
+func (r *report) ScanDuration() time.Duration {
+	return r.scanDuration
+}
 func (r *report) All() []types.ContainerReport {
-	allLen := len(r.scanned) + len(r.updated) + len(r.failed) + len(r.skipped) + len(r.stale) + len(r.fresh)
+	allLen := len(r.scanned) + len(r.updated) + len(r.failed) + len(r.skipped) + len(r.stale) + len(r.fresh) + len(r.retries) + len(r.quarantined)
 	all := make([]types.ContainerReport, 0, allLen)
 
 	presentIds := map[types.ContainerID][]string{}
@@ -51,7 +74,9 @@ func (r *report) All() []types.ContainerReport {
 
 	appendUnique(r.updated)
 	appendUnique(r.failed)
+	appendUnique(r.retries)
 	appendUnique(r.skipped)
+	appendUnique(r.quarantined)
 	appendUnique(r.stale)
 	appendUnique(r.fresh)
 	appendUnique(r.scanned)
@@ -70,11 +95,23 @@ func NewReport(progress Progress) types.Report {
 		skipped: []types.ContainerReport{},
 		stale:   []types.ContainerReport{},
 		fresh:   []types.ContainerReport{},
+		retries: []types.ContainerReport{},
+
+		quarantined: []types.ContainerReport{},
 	}
 
 	for _, update := range progress {
+		report.pulled += update.pulledBytes
+		if update.state == QuarantinedState {
+			report.quarantined = append(report.quarantined, update)
+			continue
+		}
 		if update.state == SkippedState {
-			report.skipped = append(report.skipped, update)
+			if update.retrying {
+				report.retries = append(report.retries, update)
+			} else {
+				report.skipped = append(report.skipped, update)
+			}
 			continue
 		}
 
@@ -89,7 +126,11 @@ func NewReport(progress Progress) types.Report {
 		case UpdatedState:
 			report.updated = append(report.updated, update)
 		case FailedState:
-			report.failed = append(report.failed, update)
+			if update.retrying {
+				report.retries = append(report.retries, update)
+			} else {
+				report.failed = append(report.failed, update)
+			}
 		default:
 			update.state = StaleState
 			report.stale = append(report.stale, update)
@@ -102,6 +143,8 @@ func NewReport(progress Progress) types.Report {
 	sort.Sort(sortableContainers(report.skipped))
 	sort.Sort(sortableContainers(report.stale))
 	sort.Sort(sortableContainers(report.fresh))
+	sort.Sort(sortableContainers(report.retries))
+	sort.Sort(sortableContainers(report.quarantined))
 
 	return report
 }
@@ -0,0 +1,91 @@
+package session
+
+import (
+	"errors"
+
+	wt "github.com/containrrr/watchtower/pkg/types"
+)
+
+// The reasons for skipping containers, or for leaving them as they were
+const (
+	// SkipCheckFailed is used for the containers that could not be checked for any other reason
+	SkipCheckFailed wt.SkipReason = "check-failed"
+	// SkipWithinErrorBudget is used for the containers that could not be checked, while the error budget of their
+	// image has not been exhausted yet
+	SkipWithinErrorBudget wt.SkipReason = "within-error-budget"
+	// SkipImageMissing is used when the image of the container is no longer available locally
+	SkipImageMissing wt.SkipReason = "image-missing"
+	// SkipPinnedDigest is used when the container refers to its image by ID or digest, rather than by a tag
+	SkipPinnedDigest wt.SkipReason = "pinned-digest"
+	// SkipRateLimited is used when the registry refused to serve the image due to its rate limit
+	SkipRateLimited wt.SkipReason = "rate-limited"
+	// SkipInsufficientDiskSpace is used when pulling the image would leave less free disk space than the configured
+	// minimum
+	SkipInsufficientDiskSpace wt.SkipReason = "insufficient-disk-space"
+	// SkipInvalidConfig is used when the container could not be recreated using its configuration
+	SkipInvalidConfig wt.SkipReason = "invalid-config"
+	// SkipVetoedByHook is used when the pre-update lifecycle hook asked to skip the update using exit code 75
+	SkipVetoedByHook wt.SkipReason = "vetoed-by-hook"
+	// SkipMonitorOnly is used for stale containers that are only monitored, by flag or label
+	SkipMonitorOnly wt.SkipReason = "monitor-only"
+	// SkipStageOnly is used for stale containers whose new image was only staged
+	SkipStageOnly wt.SkipReason = "stage-only"
+	// SkipAwaitingApproval is used for stale containers whose update is waiting for approval
+	SkipAwaitingApproval wt.SkipReason = "awaiting-approval"
+	// SkipImageTooNew is used for stale containers whose new image has not reached the minimum image age yet
+	SkipImageTooNew wt.SkipReason = "image-too-new"
+	// SkipCheckNotDue is used for containers that were not checked, as less time than their check interval has passed
+	// since their previous check
+	SkipCheckNotDue wt.SkipReason = "check-not-due"
+	// SkipNoPull is used for containers whose image was not pulled due to the no-pull label, only being compared to
+	// the local image
+	SkipNoPull wt.SkipReason = "no-pull"
+	// SkipShuttingDown is used for stale containers that were left for the next session, as watchtower shut down
+	SkipShuttingDown wt.SkipReason = "shutting-down"
+	// SkipNotPermitted is used when recreating the container requires a part of the docker API that watchtower is not
+	// permitted to use
+	SkipNotPermitted wt.SkipReason = "not-permitted"
+	// SkipGroupIncomplete is used for stale containers that were left as they were, as another container of their
+	// transactional group could not be checked
+	SkipGroupIncomplete wt.SkipReason = "group-incomplete"
+	// SkipRolledBack is used for containers that were recreated using their previous image again, as another container
+	// of their transactional group failed to update
+	SkipRolledBack wt.SkipReason = "rolled-back"
+	// SkipVetoedByStrategy is used for stale containers the update strategy decided not to update to the new image
+	SkipVetoedByStrategy wt.SkipReason = "vetoed-by-strategy"
+	// SkipDeferredByStrategy is used for stale containers the update strategy decided to update later, or could not
+	// decide about
+	SkipDeferredByStrategy wt.SkipReason = "deferred-by-strategy"
+)
+
+// skipError is an error with the reason for skipping the container it occurred for
+type skipError struct {
+	reason wt.SkipReason
+	err    error
+}
+
+func (e skipError) Error() string {
+	return e.err.Error()
+}
+
+func (e skipError) Unwrap() error {
+	return e.err
+}
+
+// WithSkipReason annotates the error with the reason for skipping the container, which is reported in place of the
+// generic SkipCheckFailed. The error message is kept as it is.
+func WithSkipReason(reason wt.SkipReason, err error) error {
+	if err == nil {
+		return nil
+	}
+	return skipError{reason: reason, err: err}
+}
+
+// SkipReasonOf returns the reason the error was annotated with using WithSkipReason, or an empty reason if it was not
+func SkipReasonOf(err error) wt.SkipReason {
+	var skip skipError
+	if errors.As(err, &skip) {
+		return skip.reason
+	}
+	return ""
+}
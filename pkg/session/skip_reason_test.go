@@ -0,0 +1,49 @@
+package session_test
+
+import (
+	"errors"
+
+	"github.com/containrrr/watchtower/internal/actions/mocks"
+	"github.com/containrrr/watchtower/pkg/session"
+	"github.com/containrrr/watchtower/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the skip reasons", func() {
+	It("should default to a failed check for errors without a reason", func() {
+		report := mocks.CreateMockProgressReport(session.SkippedState)
+		Expect(report.Skipped()).To(HaveLen(1))
+		Expect(report.Skipped()[0].SkipReason()).To(Equal(session.SkipCheckFailed))
+	})
+	It("should keep the reason and the message of annotated errors", func() {
+		c, _ := mocks.CreateContainerForProgress(0, 41, "skip%d")
+		cause := errors.New("toomanyrequests: slow down")
+		progress := session.Progress{}
+		progress.AddSkipped(c, session.WithSkipReason(session.SkipRateLimited, cause))
+
+		skipped := progress.Report().Skipped()
+		Expect(skipped).To(HaveLen(1))
+		Expect(skipped[0].SkipReason()).To(Equal(session.SkipRateLimited))
+		Expect(skipped[0].Error()).To(Equal(cause.Error()))
+	})
+	It("should report the failed updates with a reason as skipped", func() {
+		c, newImage := mocks.CreateContainerForProgress(0, 11, "updt%d")
+		progress := session.Progress{}
+		progress.AddScanned(c, newImage)
+		progress.MarkForUpdate(c.ID())
+		progress.UpdateFailed(map[types.ContainerID]error{
+			c.ID(): session.WithSkipReason(session.SkipVetoedByHook, errors.New("exit code 75")),
+		})
+
+		report := progress.Report()
+		Expect(report.Failed()).To(BeEmpty())
+		Expect(report.Skipped()).To(HaveLen(1))
+		Expect(report.Skipped()[0].SkipReason()).To(Equal(session.SkipVetoedByHook))
+	})
+	It("should not annotate missing errors", func() {
+		Expect(session.WithSkipReason(session.SkipInvalidConfig, nil)).To(BeNil())
+		Expect(session.SkipReasonOf(errors.New("plain"))).To(BeEmpty())
+	})
+})
@@ -0,0 +1,98 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store is a key-value store for the state that watchtower keeps between sessions. Values are stored JSON encoded
+// and, if the store was created with a path, written to that file on every change so that they survive restarts.
+type Store struct {
+	path string
+
+	lock   sync.Mutex
+	values map[string]json.RawMessage
+}
+
+// New creates a store, loading its values from the file at path if it exists. If path is empty, the state is only
+// kept in memory.
+func New(path string) (*Store, error) {
+	store := &Store{path: path, values: map[string]json.RawMessage{}}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.values); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Get decodes the value stored for key into value, and returns whether the key was found
+func (s *Store) Get(key string, value interface{}) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	raw, found := s.values[key]
+	if !found {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, value)
+}
+
+// Set stores the value for key, replacing any previous value. A nil value removes the key.
+func (s *Store) Set(key string, value interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if value == nil {
+		delete(s.values, key)
+	} else {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		s.values[key] = raw
+	}
+	return s.save()
+}
+
+// save writes the values to the state file, replacing it atomically so that it is never left half written
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.values, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
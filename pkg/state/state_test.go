@@ -0,0 +1,69 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestState(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "State Suite")
+}
+
+type entry struct {
+	Name  string
+	Count int
+}
+
+var _ = Describe("the state store", func() {
+	var dir string
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-state")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	It("should return the stored values", func() {
+		store, err := New("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Set("entry", entry{Name: "test", Count: 2})).To(Succeed())
+
+		value := entry{}
+		found, err := store.Get("entry", &value)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(value).To(Equal(entry{Name: "test", Count: 2}))
+
+		found, err = store.Get("missing", &value)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+	It("should keep the values across restarts when using a file", func() {
+		path := filepath.Join(dir, "nested", "state.json")
+		store, err := New(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Set("entry", entry{Name: "test", Count: 2})).To(Succeed())
+		Expect(store.Set("removed", entry{})).To(Succeed())
+		Expect(store.Set("removed", nil)).To(Succeed())
+
+		reloaded, err := New(path)
+		Expect(err).NotTo(HaveOccurred())
+		value := entry{}
+		Expect(reloaded.Get("entry", &value)).To(BeTrue())
+		Expect(value.Count).To(Equal(2))
+		Expect(reloaded.Get("removed", &value)).To(BeFalse())
+	})
+	It("should fail to load a corrupt state file", func() {
+		path := filepath.Join(dir, "state.json")
+		Expect(os.WriteFile(path, []byte("{"), 0600)).To(Succeed())
+		_, err := New(path)
+		Expect(err).To(HaveOccurred())
+	})
+})
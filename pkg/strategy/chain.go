@@ -0,0 +1,37 @@
+package strategy
+
+import "github.com/containrrr/watchtower/pkg/types"
+
+type chain []types.UpdateStrategy
+
+// Chain returns the strategy asking each of the strategies in turn, until one of them decides about the container or
+// updates it. It returns the only strategy as it is, and nil if there are none.
+func Chain(strategies ...types.UpdateStrategy) types.UpdateStrategy {
+	switch len(strategies) {
+	case 0:
+		return nil
+	case 1:
+		return strategies[0]
+	}
+	return chain(strategies)
+}
+
+// ShouldUpdate returns the first decision of the strategies other than the default one
+func (c chain) ShouldUpdate(candidate types.UpdateCandidate) (types.UpdateDecision, error) {
+	for _, s := range c {
+		if decision, err := s.ShouldUpdate(candidate); err != nil || decision != Default {
+			return decision, err
+		}
+	}
+	return Default, nil
+}
+
+// PerformUpdate lets the first strategy that handles the update update the container
+func (c chain) PerformUpdate(candidate types.UpdateCandidate) (bool, error) {
+	for _, s := range c {
+		if handled, err := s.PerformUpdate(candidate); handled {
+			return true, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,131 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// The phases of an update the plugin is run for, passed to it as its only argument
+const (
+	PhaseShouldUpdate  = "should-update"
+	PhasePerformUpdate = "perform-update"
+)
+
+// outputLines limits how much of the error output of a failed plugin is included in its error
+const outputLines = 5
+
+// decisionResponse is what the plugin answers in the should-update phase
+type decisionResponse struct {
+	Decision string `json:"decision"`
+	Reason   string `json:"reason"`
+}
+
+// updateResponse is what the plugin answers in the perform-update phase
+type updateResponse struct {
+	Handled bool   `json:"handled"`
+	Error   string `json:"error"`
+}
+
+type plugin struct {
+	path    string
+	timeout time.Duration
+}
+
+// NewPlugin returns the strategy running the executable at the path for each phase, passing it the stale container as
+// JSON on its standard input, and reading its answer as JSON from its standard output. The time each run may take is
+// limited by the timeout, if set.
+func NewPlugin(path string, timeout time.Duration) types.UpdateStrategy {
+	return plugin{path: path, timeout: timeout}
+}
+
+// ShouldUpdate runs the plugin in the should-update phase, which answers with the decision and the reason for it
+func (p plugin) ShouldUpdate(candidate types.UpdateCandidate) (types.UpdateDecision, error) {
+	var response decisionResponse
+	if err := p.run(PhaseShouldUpdate, candidate, &response); err != nil {
+		return Default, err
+	}
+	decision, err := ParseDecision(response.Decision)
+	if err != nil {
+		return Default, fmt.Errorf("the update strategy plugin answered with an %w", err)
+	}
+	if decision != Default {
+		entry := log.WithField("container", candidate.Name)
+		if response.Reason != "" {
+			entry = entry.WithField("reason", response.Reason)
+		}
+		entry.Debugf("The update strategy plugin decided to %s the container", decision)
+	}
+	return decision, nil
+}
+
+// PerformUpdate runs the plugin in the perform-update phase, which answers whether it updated the container, and the
+// error if it failed to. A plugin exiting with a non-zero code has failed to update the container.
+func (p plugin) PerformUpdate(candidate types.UpdateCandidate) (bool, error) {
+	var response updateResponse
+	if err := p.run(PhasePerformUpdate, candidate, &response); err != nil {
+		return true, err
+	}
+	if response.Error != "" {
+		return true, fmt.Errorf("the update strategy plugin failed to update %s: %s", candidate.Name, response.Error)
+	}
+	return response.Handled, nil
+}
+
+// run runs the plugin for the phase, reading its answer into response. An empty answer leaves response as it is.
+func (p plugin) run(phase string, candidate types.UpdateCandidate, response interface{}) error {
+	request, err := json.Marshal(candidate)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.path, phase)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("the update strategy plugin did not finish the %s phase of %s within %s", phase, candidate.Name, p.timeout)
+		}
+		return fmt.Errorf("the update strategy plugin failed in the %s phase of %s: %w%s", phase, candidate.Name, err, tail(stderr.String()))
+	}
+	if out := strings.TrimSpace(stderr.String()); out != "" {
+		log.WithField("container", candidate.Name).Debug(out)
+	}
+
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return fmt.Errorf("could not read the answer of the update strategy plugin in the %s phase of %s: %w", phase, candidate.Name, err)
+	}
+	return nil
+}
+
+// tail returns the last lines of the output, to explain why the plugin failed
+func tail(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > outputLines {
+		lines = lines[len(lines)-outputLines:]
+	}
+	if joined := strings.Join(lines, "\n"); joined != "" {
+		return ": " + joined
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package strategy
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/types"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	log "github.com/sirupsen/logrus"
+)
+
+type script struct {
+	program *vm.Program
+}
+
+// LoadScript returns the strategy deciding about the stale containers using the expression in the file at the path
+func LoadScript(path string) (types.UpdateStrategy, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the update strategy script: %w", err)
+	}
+	return NewScript(string(source))
+}
+
+// NewScript returns the strategy deciding about each stale container by evaluating the expression, written in the
+// expr language, with the container, its images and the current time as its variables. The expression results in
+// either a decision, true to update the container, false to skip it, or nil to leave the decision to watchtower.
+func NewScript(source string) (types.UpdateStrategy, error) {
+	program, err := expr.Compile(source, expr.Env(scriptEnv(types.UpdateCandidate{}, time.Time{})))
+	if err != nil {
+		return nil, fmt.Errorf("could not compile the update strategy script: %w", err)
+	}
+	return script{program: program}, nil
+}
+
+// scriptEnv returns the variables the script is evaluated with for the container
+func scriptEnv(candidate types.UpdateCandidate, now time.Time) map[string]interface{} {
+	var imageAge time.Duration
+	if !candidate.LatestImageCreated.IsZero() {
+		imageAge = now.Sub(candidate.LatestImageCreated)
+	}
+	labels := candidate.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return map[string]interface{}{
+		"id":           string(candidate.ID),
+		"name":         candidate.Name,
+		"image":        candidate.ImageName,
+		"currentImage": string(candidate.CurrentImageID),
+		"latestImage":  string(candidate.LatestImageID),
+		"imageCreated": candidate.LatestImageCreated,
+		"imageAge":     imageAge,
+		"labels":       labels,
+		"project":      candidate.Project,
+		"now":          now,
+	}
+}
+
+// ShouldUpdate evaluates the script for the container
+func (s script) ShouldUpdate(candidate types.UpdateCandidate) (types.UpdateDecision, error) {
+	result, err := expr.Run(s.program, scriptEnv(candidate, time.Now()))
+	if err != nil {
+		return Default, fmt.Errorf("could not evaluate the update strategy script for %s: %w", candidate.Name, err)
+	}
+
+	var decision types.UpdateDecision
+	switch value := result.(type) {
+	case nil:
+		decision = Default
+	case bool:
+		decision = Skip
+		if value {
+			decision = Update
+		}
+	case string:
+		if decision, err = ParseDecision(value); err != nil {
+			return Default, fmt.Errorf("the update strategy script resulted in an %w", err)
+		}
+	default:
+		return Default, fmt.Errorf("the update strategy script resulted in %v, expected a decision or a boolean", result)
+	}
+	if decision != Default {
+		log.WithField("container", candidate.Name).Debugf("The update strategy script decided to %s the container", decision)
+	}
+	return decision, nil
+}
+
+// PerformUpdate leaves the update to watchtower, as scripts only decide about the containers
+func (s script) PerformUpdate(types.UpdateCandidate) (bool, error) {
+	return false, nil
+}
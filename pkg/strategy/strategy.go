@@ -0,0 +1,30 @@
+// Package strategy provides the update strategies that override how watchtower decides whether to update the stale
+// containers, and how it updates them
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/containrrr/watchtower/pkg/types"
+)
+
+// The decisions of an update strategy about a stale container
+const (
+	// Default leaves the decision to watchtower
+	Default types.UpdateDecision = ""
+	// Update updates the container, unless watchtower leaves it as it is for any other reason
+	Update types.UpdateDecision = "update"
+	// Skip leaves the container as it is, as it should not be updated to the new image
+	Skip types.UpdateDecision = "skip"
+	// Defer leaves the container as it is for now, to decide again during the next session
+	Defer types.UpdateDecision = "defer"
+)
+
+// ParseDecision returns the decision with the name, which is empty for the default decision
+func ParseDecision(name string) (types.UpdateDecision, error) {
+	switch decision := types.UpdateDecision(name); decision {
+	case Default, Update, Skip, Defer:
+		return decision, nil
+	}
+	return Default, fmt.Errorf("unknown decision %q, expected update, skip or defer", name)
+}
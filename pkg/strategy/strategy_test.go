@@ -0,0 +1,168 @@
+package strategy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/strategy"
+	"github.com/containrrr/watchtower/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestStrategy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Strategy Suite")
+}
+
+var _ = Describe("the update strategy plugin", func() {
+	var dir string
+	candidate := types.UpdateCandidate{
+		ID:                 "abc",
+		Name:               "shop-web",
+		ImageName:          "shop/web:latest",
+		CurrentImageID:     "sha256:01",
+		LatestImageID:      "sha256:02",
+		LatestImageCreated: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Labels:             map[string]string{"tier": "frontend"},
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "watchtower-strategy")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+	// plugin writes the script to an executable plugin, returning the strategy running it
+	plugin := func(script string, timeout time.Duration) types.UpdateStrategy {
+		path := filepath.Join(dir, "plugin")
+		Expect(os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755)).To(Succeed())
+		return strategy.NewPlugin(path, timeout)
+	}
+
+	It("should pass the phase and the container to the plugin", func() {
+		p := plugin(`echo "$1" > `+filepath.Join(dir, "phase")+`; cat > `+filepath.Join(dir, "request"), time.Minute)
+		decision, err := p.ShouldUpdate(candidate)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decision).To(Equal(strategy.Default))
+
+		phase, _ := os.ReadFile(filepath.Join(dir, "phase"))
+		Expect(string(phase)).To(Equal("should-update\n"))
+		request, _ := os.ReadFile(filepath.Join(dir, "request"))
+		Expect(string(request)).To(MatchJSON(`{
+			"id": "abc", "name": "shop-web", "imageName": "shop/web:latest", "currentImageId": "sha256:01",
+			"latestImageId": "sha256:02", "latestImageCreated": "2024-05-01T12:00:00Z", "labels": {"tier": "frontend"}
+		}`))
+	})
+
+	It("should return the decision of the plugin", func() {
+		p := plugin(`echo '{"decision": "defer", "reason": "business hours"}'`, time.Minute)
+		Expect(p.ShouldUpdate(candidate)).To(Equal(strategy.Defer))
+	})
+
+	It("should reject unknown decisions", func() {
+		p := plugin(`echo '{"decision": "maybe"}'`, time.Minute)
+		_, err := p.ShouldUpdate(candidate)
+		Expect(err).To(MatchError(ContainSubstring(`unknown decision "maybe"`)))
+	})
+
+	It("should return the error output of a failed plugin", func() {
+		p := plugin(`echo "no inventory entry" >&2; exit 2`, time.Minute)
+		_, err := p.ShouldUpdate(candidate)
+		Expect(err).To(MatchError(ContainSubstring("no inventory entry")))
+	})
+
+	It("should stop plugins running longer than the timeout", func() {
+		p := plugin(`exec sleep 5`, 50*time.Millisecond)
+		_, err := p.ShouldUpdate(candidate)
+		Expect(err).To(MatchError(ContainSubstring("did not finish")))
+	})
+
+	It("should leave the update to watchtower unless the plugin handled it", func() {
+		handled, err := plugin(`echo '{"handled": false}'`, time.Minute).PerformUpdate(candidate)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(handled).To(BeFalse())
+
+		handled, err = plugin(`echo '{"handled": true}'`, time.Minute).PerformUpdate(candidate)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(handled).To(BeTrue())
+	})
+
+	It("should consider failed updates handled by the plugin", func() {
+		handled, err := plugin(`echo '{"handled": true, "error": "canary unhealthy"}'`, time.Minute).PerformUpdate(candidate)
+		Expect(handled).To(BeTrue())
+		Expect(err).To(MatchError(ContainSubstring("canary unhealthy")))
+
+		handled, err = plugin(`exit 1`, time.Minute).PerformUpdate(candidate)
+		Expect(handled).To(BeTrue())
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("the update strategy script", func() {
+	candidate := types.UpdateCandidate{
+		Name:               "shop-web",
+		ImageName:          "shop/web:latest",
+		LatestImageCreated: time.Now().Add(-48 * time.Hour),
+		Labels:             map[string]string{"tier": "frontend"},
+		Project:            "shop",
+	}
+	decide := func(source string) (types.UpdateDecision, error) {
+		script, err := strategy.NewScript(source)
+		Expect(err).NotTo(HaveOccurred())
+		return script.ShouldUpdate(candidate)
+	}
+
+	It("should decide using the container, its images and the time", func() {
+		Expect(decide(`labels.tier == "frontend" && imageAge > duration("24h") ? "update" : "defer"`)).To(Equal(strategy.Update))
+		Expect(decide(`project == "shop" && imageCreated.After(now) ? "update" : "defer"`)).To(Equal(strategy.Defer))
+		Expect(decide(`name startsWith "shop-" ? "skip" : nil`)).To(Equal(strategy.Skip))
+	})
+
+	It("should update or skip the container for boolean results", func() {
+		Expect(decide(`image endsWith ":latest"`)).To(Equal(strategy.Update))
+		Expect(decide(`labels.tier == "backend"`)).To(Equal(strategy.Skip))
+	})
+
+	It("should leave the decision to watchtower for nil results", func() {
+		Expect(decide(`nil`)).To(Equal(strategy.Default))
+	})
+
+	It("should reject unknown results", func() {
+		_, err := decide(`"maybe"`)
+		Expect(err).To(MatchError(ContainSubstring(`unknown decision "maybe"`)))
+		_, err = decide(`42`)
+		Expect(err).To(MatchError(ContainSubstring("expected a decision or a boolean")))
+	})
+
+	It("should reject invalid scripts", func() {
+		_, err := strategy.NewScript(`labels.tier ==`)
+		Expect(err).To(MatchError(ContainSubstring("could not compile")))
+		_, err = strategy.NewScript(`unknownVariable == 1`)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should never update the containers itself", func() {
+		script, _ := strategy.NewScript(`true`)
+		Expect(script.PerformUpdate(candidate)).To(BeFalse())
+	})
+})
+
+var _ = Describe("the chain of update strategies", func() {
+	It("should use the first decision other than the default one", func() {
+		first, _ := strategy.NewScript(`nil`)
+		second, _ := strategy.NewScript(`"defer"`)
+		third, _ := strategy.NewScript(`"update"`)
+		Expect(strategy.Chain(first, second, third).ShouldUpdate(types.UpdateCandidate{})).To(Equal(strategy.Defer))
+		Expect(strategy.Chain(first).ShouldUpdate(types.UpdateCandidate{})).To(Equal(strategy.Default))
+	})
+
+	It("should be nil without strategies", func() {
+		Expect(strategy.Chain()).To(BeNil())
+	})
+})
@@ -0,0 +1,15 @@
+package types
+
+// ComposeProject describes the docker compose project a container was created by
+type ComposeProject struct {
+	Name        string
+	WorkingDir  string
+	ConfigFiles []string
+	// Services are the services of the project to re-deploy, or all of them if empty
+	Services []string
+}
+
+// ComposeDeployer re-deploys docker compose projects, leaving it to compose to recreate their containers
+type ComposeDeployer interface {
+	Up(project ComposeProject) error
+}
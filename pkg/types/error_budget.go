@@ -0,0 +1,10 @@
+package types
+
+// ErrorBudget keeps track of the failed update checks of images across sessions, deciding which failures to escalate
+type ErrorBudget interface {
+	// Spend records a session in which checking the image failed, and returns whether the failures have exhausted its
+	// budget
+	Spend(imageName string) bool
+	// Restore records a session in which the image was checked successfully
+	Restore(imageName string)
+}
@@ -0,0 +1,19 @@
+package types
+
+import "time"
+
+// Event is a structured record of an action taken by watchtower, as written to the event log
+type Event struct {
+	Time      time.Time              `json:"time"`
+	Type      string                 `json:"type"`
+	Session   string                 `json:"session,omitempty"`
+	Container string                 `json:"container,omitempty"`
+	Image     string                 `json:"image,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// EventRecorder records the events of the actions taken by watchtower
+type EventRecorder interface {
+	Record(Event)
+}
@@ -0,0 +1,28 @@
+package types
+
+import "time"
+
+// ImageRetention is the policy deciding which of the previous images of a repository to keep when cleaning up
+type ImageRetention struct {
+	// Keep is the number of previous images to keep for each repository
+	Keep int
+	// KeepYoungerThan keeps the previous images that were created less than this long ago
+	KeepYoungerThan time.Duration
+}
+
+// Enabled returns whether any previous images are retained, rather than removing them right after updating
+func (r ImageRetention) Enabled() bool {
+	return r.Keep > 0 || r.KeepYoungerThan > 0
+}
+
+// Retains returns whether the policy keeps an image, given its position among the previous images of the repository
+// (counting from the newest one, starting at zero) and the time it was created
+func (r ImageRetention) Retains(position int, created time.Time, now time.Time) bool {
+	return position < r.Keep || (r.KeepYoungerThan > 0 && now.Sub(created) < r.KeepYoungerThan)
+}
+
+// ImageSummary describes a local image considered for removal by the image retention policy
+type ImageSummary struct {
+	ID      ImageID
+	Created time.Time
+}
@@ -0,0 +1,11 @@
+package types
+
+import (
+	"io"
+	"time"
+)
+
+// LogArchiver keeps the logs of the containers that are removed to be recreated
+type LogArchiver interface {
+	Archive(container string, image string, removed time.Time, logs io.Reader) error
+}
@@ -1,9 +1,21 @@
 package types
 
+import "time"
+
 // Notifier is the interface that all notification services have in common
 type Notifier interface {
 	StartNotification()
 	SendNotification(Report)
+	SendUpdatesAvailable(UpdatesAvailable)
 	GetNames() []string
 	Close()
 }
+
+// UpdatesAvailable are the new images found for the first time, sent ahead of the report of the session applying them
+type UpdatesAvailable struct {
+	Updates []ContainerReport
+	// ApplyAt is when the next session applying the updates is scheduled, or zero if it is not
+	ApplyAt time.Time
+	// AwaitingApproval is set when the updates are only applied once they are approved
+	AwaitingApproval bool
+}
@@ -0,0 +1,11 @@
+package types
+
+import "time"
+
+// QuarantinedContainer is a container that is no longer updated after failing during too many consecutive sessions
+type QuarantinedContainer struct {
+	Name          string    `json:"name"`
+	Sessions      int       `json:"sessions"`
+	LastError     string    `json:"lastError"`
+	QuarantinedAt time.Time `json:"quarantinedAt"`
+}
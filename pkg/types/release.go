@@ -0,0 +1,16 @@
+package types
+
+// Release describes the version of an image that a container was updated to
+type Release struct {
+	// Version is the version of the image, as declared in its labels
+	Version string
+	// URL links to the release notes of the version if they could be determined, or to the source of the image
+	URL string
+	// Notes are the release notes of the version, if they were retrieved
+	Notes string
+}
+
+// ReleaseResolver looks up the release information of an image using its labels
+type ReleaseResolver interface {
+	Resolve(imageLabels map[string]string) Release
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // Report contains reports for all the containers processed during a session
 type Report interface {
 	Scanned() []ContainerReport
@@ -8,7 +10,14 @@ type Report interface {
 	Skipped() []ContainerReport
 	Stale() []ContainerReport
 	Fresh() []ContainerReport
+	Retrying() []ContainerReport
+	Quarantined() []ContainerReport
 	All() []ContainerReport
+	PulledBytes() int64
+	// Duration is the time the session took
+	Duration() time.Duration
+	// ScanDuration is the time spent checking the containers for new images
+	ScanDuration() time.Duration
 }
 
 // ContainerReport represents a container that was included in watchtower session
@@ -20,4 +29,23 @@ type ContainerReport interface {
 	ImageName() string
 	Error() string
 	State() string
+	Release() Release
+	ImageChanges() []string
+	PulledBytes() int64
+	// PullDuration is the time spent checking for and pulling the latest image, if anything was downloaded
+	PullDuration() time.Duration
+	// RecreateDuration is the time spent stopping the container and starting it again, if it was recreated
+	RecreateDuration() time.Duration
+	SkipReason() SkipReason
+	// CurrentReference and LatestReference are the name and digest of the current and latest image, like
+	// nginx:1.25@sha256:..., if the container is stale
+	CurrentReference() string
+	LatestReference() string
+	// CompareURL links to the page of the latest image on the website of its registry, if it is a known one
+	CompareURL() string
+	Project() string
+	Logs() string
 }
+
+// SkipReason is a machine-readable code for why a container was skipped, or left as it was despite a new image
+type SkipReason string
@@ -0,0 +1,9 @@
+package types
+
+// StateStore keeps state between sessions and, if configured to use a file, across restarts
+type StateStore interface {
+	// Get decodes the value stored for key into value, and returns whether the key was found
+	Get(key string, value interface{}) (bool, error)
+	// Set stores the value for key, replacing any previous value. A nil value removes the key.
+	Set(key string, value interface{}) error
+}
@@ -13,4 +13,64 @@ type UpdateParams struct {
 	MonitorOnly    bool
 	LifecycleHooks bool
 	RollingRestart bool
+	// ImageRetention decides which previous images to keep when cleaning up. If disabled, they are removed right away.
+	ImageRetention ImageRetention
+	// CleanupDelay is how long to wait before removing the previous images, which requires State to be set
+	CleanupDelay time.Duration
+	// State keeps track of the state between sessions, like the images that are due to be removed
+	State StateStore
+	// SelfUpdateTimeout is how long to wait for a new watchtower instance to take over before aborting a self-update
+	SelfUpdateTimeout time.Duration
+	// HealthStartPeriodMultiplier scales the health check start period, during which failing health checks of a new
+	// watchtower instance or a recreated container are not held against it
+	HealthStartPeriodMultiplier float64
+	// ErrorBudget decides which failed checks to escalate, if set. Otherwise every failure is logged at info level.
+	ErrorBudget ErrorBudget
+	// SessionID identifies the update session in the labels of the recreated containers. No labels are added if empty.
+	SessionID string
+	// Releases resolves the release information of the new images of stale containers for the report, if set
+	Releases ReleaseResolver
+	// Retries is how many times to retry failed update checks and container recreations within the session
+	Retries int
+	// RetryBackoff is the delay before the first retry, which doubles with every further retry
+	RetryBackoff time.Duration
+	// RetrySessions is for how many consecutive sessions failed containers are reported as retrying, requiring State
+	RetrySessions int
+	// QuarantineAfter is after how many consecutive failed sessions a container is quarantined, requiring State
+	QuarantineAfter int
+	// PendingUpdates coordinates separate check and update sessions through a queue of pending updates, requiring
+	// State. Monitor only sessions queue the containers with a new image, and the other sessions only update those.
+	PendingUpdates bool
+	// CleanupScheduled defers removing the previous images to the scheduled cleanup sessions, requiring State
+	CleanupScheduled bool
+	// StageOnly leaves the containers with a new image running, recording the pulled images as staged in State
+	StageOnly bool
+	// RequireApproval stages the new images like StageOnly, until their updates are approved
+	RequireApproval bool
+	// ApprovalLink returns a link approving the update of the container to the image, included in the log entry, if set
+	ApprovalLink func(container string, image ImageID) string
+	// MinImageAge is how old a new image has to be before the containers are updated to it, unless overridden by label
+	MinImageAge time.Duration
+	// FailureLogLines reports the recreated containers that have stopped or are unhealthy once settled as failed,
+	// including this many lines from the end of their logs, if set
+	FailureLogLines int
+	// ApplyStaged only updates the containers with a staged image, including the ones with the stage-only label
+	ApplyStaged bool
+	// Compose re-deploys the compose projects of the containers created by docker compose, rather than recreating
+	// them, if set
+	Compose ComposeDeployer
+	// Strategy decides whether to update the stale containers, and may update them in place of watchtower, if set
+	Strategy UpdateStrategy
+	// Events records the checks, pulls and recreated containers of the session, if set
+	Events EventRecorder
+	// NetworksDenied skips the containers attached to several networks, as the docker API does not permit connecting
+	// the recreated containers to the networks besides their primary one
+	NetworksDenied bool
+	// TransactionalGroups updates the containers of each group, set using the group label or else the compose project,
+	// all or nothing. Their stale containers are left as they are if any of them could not be checked, and are
+	// recreated using their previous images again if any of them failed to update.
+	TransactionalGroups bool
+	// Shutdown is closed when watchtower has been asked to shut down, which is how a new instance signals that it
+	// is ready to take over after a self-update
+	Shutdown <-chan struct{}
 }
@@ -0,0 +1,28 @@
+package types
+
+import "time"
+
+// UpdateDecision is what an update strategy decided to do with a stale container
+type UpdateDecision string
+
+// UpdateCandidate describes a stale container, and the image it would be updated to, to an update strategy. The
+// creation time of the latest image is the zero time if it is unknown.
+type UpdateCandidate struct {
+	ID                 ContainerID       `json:"id"`
+	Name               string            `json:"name"`
+	ImageName          string            `json:"imageName"`
+	CurrentImageID     ImageID           `json:"currentImageId"`
+	LatestImageID      ImageID           `json:"latestImageId"`
+	LatestImageCreated time.Time         `json:"latestImageCreated"`
+	Labels             map[string]string `json:"labels"`
+	Project            string            `json:"project,omitempty"`
+}
+
+// UpdateStrategy overrides deciding whether to update the stale containers, and updating them
+type UpdateStrategy interface {
+	// ShouldUpdate decides what to do with the stale container, returning an empty decision to leave it to watchtower
+	ShouldUpdate(candidate UpdateCandidate) (UpdateDecision, error)
+	// PerformUpdate updates the container in place of watchtower, returning whether it did, rather than leaving it to
+	// watchtower to recreate the container
+	PerformUpdate(candidate UpdateCandidate) (bool, error)
+}
@@ -0,0 +1,98 @@
+package watchdog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+const timeFormat = "2006-01-02 15:04:05 -0700 MST"
+
+// Watchdog alerts when the scheduled update sessions stop completing, like when the docker socket stops responding
+// or the scheduler has stalled, which would otherwise only be noticed once the containers are out of date. The alert
+// is sent once a grace factor of the interval of the schedule has passed since the last completed session, and a
+// second message is sent once the sessions complete again.
+type Watchdog struct {
+	schedule cron.Schedule
+	grace    float64
+	alert    func(message string)
+
+	mutex     sync.Mutex
+	completed time.Time
+	alerted   bool
+}
+
+// New creates a watchdog for the update sessions running on the schedule, treating since as the last completion
+func New(schedule cron.Schedule, grace float64, alert func(message string), since time.Time) *Watchdog {
+	return &Watchdog{
+		schedule:  schedule,
+		grace:     grace,
+		alert:     alert,
+		completed: since,
+	}
+}
+
+// Completed records that a scheduled session has completed, or was deliberately skipped, at the time
+func (w *Watchdog) Completed(at time.Time) {
+	if w == nil {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if at.After(w.completed) {
+		w.completed = at
+	}
+}
+
+// Deadline returns the time after which the watchdog alerts, if no session has completed by then
+func (w *Watchdog) Deadline() time.Time {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.deadline()
+}
+
+func (w *Watchdog) deadline() time.Time {
+	expected := w.schedule.Next(w.completed)
+	interval := w.schedule.Next(expected).Sub(expected)
+	return w.completed.Add(time.Duration(w.grace * float64(interval)))
+}
+
+// Check sends the alert if the deadline has passed, or the recovery message if a session has completed since
+func (w *Watchdog) Check(now time.Time) {
+	w.mutex.Lock()
+	deadline := w.deadline()
+	completed := w.completed
+	var message string
+	switch {
+	case !w.alerted && now.After(deadline):
+		w.alerted = true
+		message = fmt.Sprintf("No scheduled update session has completed since %s, the session expected at %s has not "+
+			"finished. Watchtower may be stuck, like on an unresponsive docker socket.",
+			completed.Format(timeFormat), w.schedule.Next(completed).Format(timeFormat))
+	case w.alerted && !now.After(deadline):
+		w.alerted = false
+		message = fmt.Sprintf("The scheduled update sessions are running again, the last one completed at %s.",
+			completed.Format(timeFormat))
+	}
+	w.mutex.Unlock()
+
+	if message != "" {
+		w.alert(message)
+	}
+}
+
+// Run checks the deadline at the interval until done is closed
+func (w *Watchdog) Run(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			w.Check(now)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package watchdog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/containrrr/watchtower/pkg/watchdog"
+	"github.com/robfig/cron"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWatchdog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Watchdog Suite")
+}
+
+var _ = Describe("the watchdog", func() {
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	var alerts []string
+	var dog *watchdog.Watchdog
+
+	BeforeEach(func() {
+		alerts = nil
+		dog = watchdog.New(cron.Every(time.Hour), 2, func(message string) {
+			alerts = append(alerts, message)
+		}, start)
+	})
+
+	It("should allow a grace factor of the interval for the sessions to complete", func() {
+		Expect(dog.Deadline()).To(Equal(start.Add(2 * time.Hour)))
+		dog.Check(start.Add(119 * time.Minute))
+		Expect(alerts).To(BeEmpty())
+	})
+
+	It("should alert once when no session has completed in time", func() {
+		dog.Check(start.Add(121 * time.Minute))
+		dog.Check(start.Add(180 * time.Minute))
+		Expect(alerts).To(HaveLen(1))
+		Expect(alerts[0]).To(ContainSubstring("No scheduled update session has completed since 2022-10-01 12:00:00"))
+	})
+
+	It("should move the deadline when the sessions complete", func() {
+		dog.Completed(start.Add(time.Hour))
+		dog.Check(start.Add(150 * time.Minute))
+		Expect(alerts).To(BeEmpty())
+		Expect(dog.Deadline()).To(Equal(start.Add(3 * time.Hour)))
+	})
+
+	It("should tell when the sessions are running again", func() {
+		dog.Check(start.Add(3 * time.Hour))
+		dog.Completed(start.Add(3*time.Hour + time.Minute))
+		dog.Check(start.Add(3*time.Hour + 2*time.Minute))
+		Expect(alerts).To(HaveLen(2))
+		Expect(alerts[1]).To(HavePrefix("The scheduled update sessions are running again"))
+	})
+
+	It("should ignore the completions of a watchdog that is not enabled", func() {
+		var disabled *watchdog.Watchdog
+		disabled.Completed(start)
+	})
+})